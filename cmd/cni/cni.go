@@ -82,7 +82,7 @@ func cmdAdd(args *skel.CmdArgs) error {
 		ContainerID:  args.ContainerID,
 		NetNs:        args.Netns})
 	if err != nil {
-		return err
+		return wrapDaemonError(err)
 	}
 
 	result, err := generateCNIResult(cniVersion, response, args.IfName, netNs)
@@ -187,11 +187,20 @@ func cmdDel(args *skel.CmdArgs) error {
 		return err
 	}
 
-	return client.Del(request.PodRequest{
+	return wrapDaemonError(client.Del(request.PodRequest{
 		PodName:      podName,
 		PodNamespace: podNamespace,
 		ContainerID:  args.ContainerID,
-		NetNs:        args.Netns})
+		NetNs:        args.Netns}))
+}
+
+// wrapDaemonError makes runtime retry later if daemon is busy
+func wrapDaemonError(err error) error {
+	var tryAgainLaterErr *request.TryAgainLaterError
+	if errors.As(err, &tryAgainLaterErr) {
+		return types.NewError(types.ErrTryAgainLater, "hybridnet daemon is busy, try again later", tryAgainLaterErr.Message)
+	}
+	return err
 }
 
 type netConf struct {
@@ -0,0 +1,40 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package main
+
+import (
+	// time zones of subnet allocation freeze windows are loaded without tzdata in images
+	_ "time/tzdata"
+
+	"github.com/alibaba/hybridnet/pkg/cmd"
+	"github.com/alibaba/hybridnet/pkg/cmd/daemon"
+	"github.com/alibaba/hybridnet/pkg/cmd/manager"
+	"github.com/alibaba/hybridnet/pkg/cmd/supportbundle"
+	"github.com/alibaba/hybridnet/pkg/cmd/webhook"
+)
+
+// hybridnet runs all components and tools as its subcommands, e.g., "hybridnet manager --controllers=*,-SubnetDrain"
+// and "hybridnet daemon doctor"
+func main() {
+	cmd.Main(cmd.NewRootCommand(
+		manager.NewCommand(),
+		daemon.NewCommand(),
+		webhook.NewCommand(),
+		supportbundle.NewCommand(),
+		cmd.NewVersionCommand(),
+	))
+}
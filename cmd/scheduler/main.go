@@ -0,0 +1,44 @@
+//go:build scheduler
+
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Command scheduler is kube-scheduler with the network-aware plugin of hybridnet registered,
+// it is built with "go build -tags scheduler ./cmd/scheduler" and the plugin still needs to
+// be enabled in scheduler profiles.
+package main
+
+import (
+	"os"
+
+	"k8s.io/component-base/logs"
+	"k8s.io/kubernetes/cmd/kube-scheduler/app"
+
+	"github.com/alibaba/hybridnet/pkg/scheduler/networkaware"
+)
+
+func main() {
+	command := app.NewSchedulerCommand(
+		app.WithPlugin(networkaware.Name, networkaware.NewFrameworkPlugin),
+	)
+
+	logs.InitLogs()
+	defer logs.FlushLogs()
+
+	if err := command.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
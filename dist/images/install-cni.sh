@@ -8,13 +8,23 @@ CNI_BIN_DST=/opt/cni/bin/hybridnet
 COMMUNITY_CNI_PLUGINS_SRC_DIR=/cni-plugins
 COMMUNITY_CNI_PLUGINS_DST_DIR=/opt/cni/bin
 
+# install binary by renaming and verify it against the source
+install_binary() {
+  cp -f "$1" "$2".tmp
+  mv -f "$2".tmp "$2"
+  if [ "$(sha256sum < "$1")" != "$(sha256sum < "$2")" ]; then
+    echo "checksum of installed $2 does not match $1" >&2
+    exit 1
+  fi
+}
+
 PLUGINS=${NEEDED_COMMUNITY_CNI_PLUGINS-"loopback"}
 for plugin in ${PLUGINS//,/ }
 do
-  cp -f $COMMUNITY_CNI_PLUGINS_SRC_DIR/"$plugin" $COMMUNITY_CNI_PLUGINS_DST_DIR/"$plugin"
+  install_binary $COMMUNITY_CNI_PLUGINS_SRC_DIR/"$plugin" $COMMUNITY_CNI_PLUGINS_DST_DIR/"$plugin"
 done
 
-cp -f $CNI_BIN_SRC $CNI_BIN_DST
+install_binary $CNI_BIN_SRC $CNI_BIN_DST
 
 # clean the out-of-date configuration
 rm -rf /etc/cni/net.d/*-hybridnet.conflist
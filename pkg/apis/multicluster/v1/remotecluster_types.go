@@ -39,11 +39,45 @@ type RemoteClusterSpec struct {
 	// KeyData takes precedence over KeyFile
 	// +kubuilder:validation:Optional
 	KeyData []byte `json:"keyData,omitempty"`
+	// EncryptedKeyData holds KeyData sealed by envelope encryption with an external KMS,
+	// it is set by manager and takes the place of KeyData
+	// +kubuilder:validation:Optional
+	EncryptedKeyData *EncryptedData `json:"encryptedKeyData,omitempty"`
+	// ServiceAccountToken makes manager authenticate to member cluster with bound tokens of its
+	// own service account instead of client certificate, member cluster is supposed to trust the
+	// service account issuer of local cluster as an OIDC provider
+	// +kubuilder:validation:Optional
+	ServiceAccountToken *ServiceAccountTokenAuth `json:"serviceAccountToken,omitempty"`
 	// Timeout is the maximum length of time to wait before giving up on a server request.
 	// A value of zero means no timeout.
 	Timeout int32 `json:"timeout,omitempty"`
 }
 
+// EncryptedData is data sealed by envelope encryption. The data is encrypted by a random
+// data encryption key, and the data encryption key is encrypted by a key of external KMS.
+type EncryptedData struct {
+	// Ciphertext is the data encrypted by AES-GCM with data encryption key.
+	Ciphertext []byte `json:"ciphertext"`
+	// EncryptedKey is the data encryption key encrypted by KMS.
+	EncryptedKey []byte `json:"encryptedKey"`
+	// KeyID is the ID of KMS key which encrypts the data encryption key.
+	KeyID string `json:"keyID"`
+	// Annotations are returned by KMS on encryption and required on decryption.
+	// +kubuilder:validation:Optional
+	Annotations map[string][]byte `json:"annotations,omitempty"`
+}
+
+// ServiceAccountTokenAuth is the configuration of bound tokens requested from local cluster.
+type ServiceAccountTokenAuth struct {
+	// Audience is the intended audience of token, which must be accepted by the OIDC
+	// authenticator of member cluster.
+	Audience string `json:"audience"`
+	// ExpirationSeconds is the requested lifetime of token, tokens are refreshed before expiration.
+	// Default to 3600.
+	// +kubuilder:validation:Optional
+	ExpirationSeconds *int64 `json:"expirationSeconds,omitempty"`
+}
+
 // RemoteClusterStatus defines the observed state of RemoteCluster
 type RemoteClusterStatus struct {
 	// UUID is the unique in time and space value for this object.
@@ -27,6 +27,46 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EncryptedData) DeepCopyInto(out *EncryptedData) {
+	*out = *in
+	if in.Ciphertext != nil {
+		in, out := &in.Ciphertext, &out.Ciphertext
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+	if in.EncryptedKey != nil {
+		in, out := &in.EncryptedKey, &out.EncryptedKey
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string][]byte, len(*in))
+		for key, val := range *in {
+			var outVal []byte
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make([]byte, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EncryptedData.
+func (in *EncryptedData) DeepCopy() *EncryptedData {
+	if in == nil {
+		return nil
+	}
+	out := new(EncryptedData)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RemoteCluster) DeepCopyInto(out *RemoteCluster) {
 	*out = *in
@@ -104,6 +144,16 @@ func (in *RemoteClusterSpec) DeepCopyInto(out *RemoteClusterSpec) {
 		*out = make([]byte, len(*in))
 		copy(*out, *in)
 	}
+	if in.EncryptedKeyData != nil {
+		in, out := &in.EncryptedKeyData, &out.EncryptedKeyData
+		*out = new(EncryptedData)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ServiceAccountToken != nil {
+		in, out := &in.ServiceAccountToken, &out.ServiceAccountToken
+		*out = new(ServiceAccountTokenAuth)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemoteClusterSpec.
@@ -444,3 +494,23 @@ func (in *RemoteVtepStatus) DeepCopy() *RemoteVtepStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceAccountTokenAuth) DeepCopyInto(out *ServiceAccountTokenAuth) {
+	*out = *in
+	if in.ExpirationSeconds != nil {
+		in, out := &in.ExpirationSeconds, &out.ExpirationSeconds
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceAccountTokenAuth.
+func (in *ServiceAccountTokenAuth) DeepCopy() *ServiceAccountTokenAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceAccountTokenAuth)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,112 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type ChaosAction string
+
+const (
+	// ChaosActionDrop drops a percentage of pod traffic toward a cidr.
+	ChaosActionDrop = ChaosAction("Drop")
+	// ChaosActionDelay delays all the tunnel traffic leaving vxlan devices.
+	ChaosActionDelay = ChaosAction("Delay")
+	// ChaosActionBlackholeVTEP drops all the tunnel traffic from and to a peer VTEP.
+	ChaosActionBlackholeVTEP = ChaosAction("BlackholeVTEP")
+)
+
+// ChaosRuleSpec defines the desired state of ChaosRule
+type ChaosRuleSpec struct {
+	// NodeSelector selects the nodes whose daemons inject the fault, all nodes are selected if it's nil.
+	// +kubebuilder:validation:Optional
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+	// Action is the kind of fault, the field of the same name describes it.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=Drop;Delay;BlackholeVTEP
+	Action ChaosAction `json:"action"`
+	// +kubebuilder:validation:Optional
+	Drop *ChaosDrop `json:"drop,omitempty"`
+	// +kubebuilder:validation:Optional
+	Delay *ChaosDelay `json:"delay,omitempty"`
+	// +kubebuilder:validation:Optional
+	BlackholeVTEP *ChaosBlackholeVTEP `json:"blackholeVTEP,omitempty"`
+	// ExpireTime is the time after which the fault is removed, the fault lasts until the rule
+	// is deleted if it's nil.
+	// +kubebuilder:validation:Optional
+	ExpireTime *metav1.Time `json:"expireTime,omitempty"`
+}
+
+type ChaosDrop struct {
+	// Destination is the cidr which the dropped pod traffic is toward.
+	// +kubebuilder:validation:Required
+	Destination string `json:"destination"`
+	// Percent is the percentage of packets to drop.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	Percent int32 `json:"percent"`
+}
+
+type ChaosDelay struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=1
+	LatencyMilliseconds int32 `json:"latencyMilliseconds"`
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	JitterMilliseconds int32 `json:"jitterMilliseconds,omitempty"`
+}
+
+// ChaosBlackholeVTEP identifies the peer VTEP either by the name of node or by the address.
+type ChaosBlackholeVTEP struct {
+	// +kubebuilder:validation:Optional
+	NodeName string `json:"nodeName,omitempty"`
+	// +kubebuilder:validation:Optional
+	VTEPIP string `json:"vtepIP,omitempty"`
+}
+
+// +k8s:openapi-gen=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +genclient
+// +genclient:nonNamespaced
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Action",type=string,JSONPath=`.spec.action`
+// +kubebuilder:printcolumn:name="ExpireTime",type=string,JSONPath=`.spec.expireTime`
+
+// ChaosRule is the Schema for the ChaosRules API, it injects faults into the dataplane of
+// selected nodes for resilience testing, and only takes effect with DataplaneChaos feature gate.
+type ChaosRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ChaosRuleSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ChaosRuleList contains a list of ChaosRule
+type ChaosRuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ChaosRule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ChaosRule{}, &ChaosRuleList{})
+}
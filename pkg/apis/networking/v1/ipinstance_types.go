@@ -31,6 +31,15 @@ type IPInstanceSpec struct {
 	Address Address `json:"address"`
 	// +kubebuilder:validation:Optional
 	Binding Binding `json:"binding,omitempty"`
+	// +kubebuilder:validation:Optional
+	Rebind *RebindTarget `json:"rebind,omitempty"`
+}
+
+// RebindTarget requests to move an IPInstance to another pod in the same namespace, e.g.
+// for live migration. It will be cleared after binding is switched.
+type RebindTarget struct {
+	// +kubebuilder:validation:Required
+	PodName string `json:"podName"`
 }
 
 // Binding defines a binding object with necessary info of an IPInstance
@@ -78,6 +87,10 @@ type IPInstanceStatus struct {
 	SandboxID string `json:"sandboxID,omitempty"`
 	// +kubebuilder:validation:Optional
 	UpdateTimestamp metav1.Time `json:"updateTimestamp,omitempty"`
+	// Topology is the node topology by which the subnet of this IP was selected,
+	// empty if the subnet was not selected by topology.
+	// +kubebuilder:validation:Optional
+	Topology string `json:"topology,omitempty"`
 }
 
 // +k8s:openapi-gen=true
@@ -91,6 +104,9 @@ type IPInstanceStatus struct {
 // +kubebuilder:printcolumn:name="Node",type=string,JSONPath=`.spec.binding.nodeName`
 // +kubebuilder:printcolumn:name="Subnet",type=string,JSONPath=`.spec.subnet`
 // +kubebuilder:printcolumn:name="Network",type=string,JSONPath=`.spec.network`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.metadata.labels.networking\.alibaba\.com/phase`
+// +kubebuilder:printcolumn:name="MAC",type=string,JSONPath=`.spec.address.mac`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // IPInstance is the Schema for the ipinstances API
 type IPInstance struct {
@@ -24,6 +24,10 @@ import (
 type NetworkSpec struct {
 	// +kubebuilder:validation:Optional
 	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// NamespaceSelector limits the namespaces whose pods are able to use this network,
+	// a nil selector means network is visible to all namespaces.
+	// +kubebuilder:validation:Optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
 	// +kubebuilder:validation:Optional
 	NetID *int32 `json:"netID"`
 	// +kubebuilder:validation:Optional
@@ -34,6 +38,26 @@ type NetworkSpec struct {
 	Mode NetworkMode `json:"mode,omitempty"`
 	// +kubebuilder:validation:Optional
 	Config *NetworkConfig `json:"config,omitempty"`
+	// Tenant isolates the address space of an overlay network, subnets of networks of
+	// different tenants are allowed to have overlapping cidrs. Empty means no tenant.
+	// +kubebuilder:validation:Optional
+	Tenant string `json:"tenant,omitempty"`
+	// Default makes network a candidate of the default network of its type for pods which
+	// specify no network. Networks without it are only selected when no network of the same
+	// type has a default policy available to pod.
+	// +kubebuilder:validation:Optional
+	Default *DefaultNetworkPolicy `json:"default,omitempty"`
+}
+
+// DefaultNetworkPolicy is the policy of electing a network as the default one of its type
+type DefaultNetworkPolicy struct {
+	// Priority orders the candidates, only the available candidates of the highest priority are elected.
+	// +kubebuilder:validation:Optional
+	Priority int32 `json:"priority,omitempty"`
+	// Weight spreads pods across the elected candidates in proportion, default to 1.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	Weight *int32 `json:"weight,omitempty"`
 }
 
 // NetworkStatus defines the observed state of Network
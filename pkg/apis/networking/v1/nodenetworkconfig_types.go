@@ -0,0 +1,166 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// NodeNetworkConfigSpec defines the desired state of NodeNetworkConfig
+type NodeNetworkConfigSpec struct {
+	// NodeSelector selects the nodes (a node pool) this config applies to, a NodeNetworkConfig
+	// with the same name of node always applies to that node even if the selector is nil.
+	// +kubebuilder:validation:Optional
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+	// Priority decides which one applies if a node is selected by multiple configs, the one
+	// with larger priority wins and names are compared if priorities are equal.
+	// +kubebuilder:validation:Optional
+	Priority int32 `json:"priority,omitempty"`
+	// VlanInterfaces are the preferred vlan parent interfaces, in the same format of
+	// "--prefer-vlan-interfaces" flag of daemon.
+	// +kubebuilder:validation:Optional
+	VlanInterfaces string `json:"vlanInterfaces,omitempty"`
+	// VxlanInterfaces are the preferred vxlan parent interfaces, in the same format of
+	// "--prefer-vxlan-interfaces" flag of daemon.
+	// +kubebuilder:validation:Optional
+	VxlanInterfaces string `json:"vxlanInterfaces,omitempty"`
+	// BGPInterfaces are the preferred bgp interfaces, in the same format of
+	// "--prefer-bgp-interfaces" flag of daemon.
+	// +kubebuilder:validation:Optional
+	BGPInterfaces string `json:"bgpInterfaces,omitempty"`
+	// VtepAddressCIDRs are the cidrs to select VTEP address of node.
+	// +kubebuilder:validation:Optional
+	VtepAddressCIDRs []string `json:"vtepAddressCIDRs,omitempty"`
+	// MTU of pod interfaces for each network type, limited by MTU of parent interfaces.
+	// +kubebuilder:validation:Optional
+	MTU *NodeMTUConfig `json:"mtu,omitempty"`
+	// Rollout rolls out changes of this config to canary nodes first, changes apply to all
+	// nodes at once (after daemons restart) if it's nil.
+	// +kubebuilder:validation:Optional
+	Rollout *NodeNetworkConfigRollout `json:"rollout,omitempty"`
+}
+
+type NodeMTUConfig struct {
+	// +kubebuilder:validation:Optional
+	Vlan *int32 `json:"vlan,omitempty"`
+	// +kubebuilder:validation:Optional
+	Vxlan *int32 `json:"vxlan,omitempty"`
+	// +kubebuilder:validation:Optional
+	BGP *int32 `json:"bgp,omitempty"`
+}
+
+// NodeNetworkConfigRollout decides how changes of NodeNetworkConfig are rolled out. Daemons on
+// canary nodes apply a change first, and the other nodes keep the last promoted spec until all
+// canary nodes pass the self-check of daemon with the change. Then the change is promoted to the
+// other nodes in batches limited by MaxUnavailable.
+type NodeNetworkConfigRollout struct {
+	// CanaryNodeSelector selects the canary nodes among the ones this config applies to.
+	// +kubebuilder:validation:Required
+	CanaryNodeSelector *metav1.LabelSelector `json:"canaryNodeSelector"`
+	// ProgressDeadlineSeconds is the max duration for canary nodes to pass the self-check,
+	// a change is never promoted to other nodes if the deadline is exceeded.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=600
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+	// MaxUnavailable is the max number or percentage of the nodes other than canary ones whose
+	// daemons are restarting to apply a promoted change at the same time.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:XIntOrString
+	// +kubebuilder:default=1
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+type NodeNetworkConfigRolloutPhase string
+
+const (
+	NodeNetworkConfigRolloutCanary    = NodeNetworkConfigRolloutPhase("Canary")
+	NodeNetworkConfigRolloutPromoting = NodeNetworkConfigRolloutPhase("Promoting")
+	NodeNetworkConfigRolloutPromoted  = NodeNetworkConfigRolloutPhase("Promoted")
+	NodeNetworkConfigRolloutFailed    = NodeNetworkConfigRolloutPhase("Failed")
+)
+
+// NodeNetworkConfigStatus defines the observed state of NodeNetworkConfig
+type NodeNetworkConfigStatus struct {
+	// ObservedGeneration is the generation of spec which is being or has been rolled out.
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Phase is the phase of rollout for the observed generation.
+	// +kubebuilder:validation:Optional
+	Phase NodeNetworkConfigRolloutPhase `json:"phase,omitempty"`
+	// CanaryStartTime is the time when the observed generation started to roll out to canary nodes.
+	// +kubebuilder:validation:Optional
+	CanaryStartTime *metav1.Time `json:"canaryStartTime,omitempty"`
+	// CanaryNodes is the count of canary nodes.
+	// +kubebuilder:validation:Optional
+	CanaryNodes int32 `json:"canaryNodes,omitempty"`
+	// VerifiedCanaryNodes is the count of canary nodes which pass the self-check with the observed generation.
+	// +kubebuilder:validation:Optional
+	VerifiedCanaryNodes int32 `json:"verifiedCanaryNodes,omitempty"`
+	// PromotedGeneration is the generation of spec which is applied by all nodes.
+	// +kubebuilder:validation:Optional
+	PromotedGeneration int64 `json:"promotedGeneration,omitempty"`
+	// PromotedSpec is the spec of promoted generation, which is applied by the nodes other than canary ones.
+	// +kubebuilder:validation:Optional
+	PromotedSpec *NodeNetworkConfigSpec `json:"promotedSpec,omitempty"`
+	// Nodes is the count of nodes other than canary ones which the promoted generation applies to.
+	// +kubebuilder:validation:Optional
+	Nodes int32 `json:"nodes,omitempty"`
+	// UpdatedNodes is the count of nodes other than canary ones which pass the self-check with the
+	// promoted generation.
+	// +kubebuilder:validation:Optional
+	UpdatedNodes int32 `json:"updatedNodes,omitempty"`
+	// Message is the human-readable detail of phase.
+	// +kubebuilder:validation:Optional
+	Message string `json:"message,omitempty"`
+}
+
+// +k8s:openapi-gen=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +genclient
+// +genclient:nonNamespaced
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Priority",type=integer,JSONPath=`.spec.priority`
+// +kubebuilder:printcolumn:name="VlanInterfaces",type=string,JSONPath=`.spec.vlanInterfaces`
+// +kubebuilder:printcolumn:name="VxlanInterfaces",type=string,JSONPath=`.spec.vxlanInterfaces`
+// +kubebuilder:printcolumn:name="BGPInterfaces",type=string,JSONPath=`.spec.bgpInterfaces`
+// +kubebuilder:printcolumn:name="Rollout",type=string,JSONPath=`.status.phase`
+
+// NodeNetworkConfig is the Schema for the NodeNetworkConfigs API
+type NodeNetworkConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NodeNetworkConfigSpec   `json:"spec,omitempty"`
+	Status NodeNetworkConfigStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// NodeNetworkConfigList contains a list of NodeNetworkConfig
+type NodeNetworkConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NodeNetworkConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NodeNetworkConfig{}, &NodeNetworkConfigList{})
+}
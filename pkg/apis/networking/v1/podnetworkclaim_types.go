@@ -0,0 +1,109 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type IPFamily string
+
+const (
+	IPFamilyIPv4Only  = IPFamily("IPv4Only")
+	IPFamilyIPv6Only  = IPFamily("IPv6Only")
+	IPFamilyDualStack = IPFamily("DualStack")
+)
+
+// PodNetworkClaimSpec defines the network intent of pods referring to the claim, empty fields
+// are left to the defaults of namespace and cluster.
+type PodNetworkClaimSpec struct {
+	// Network is the name of specified network.
+	// +kubebuilder:validation:Optional
+	Network string `json:"network,omitempty"`
+	// Subnets are names of specified subnets, the ipv4 one goes first if both ipv4 and ipv6
+	// subnets are specified.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxItems=2
+	Subnets []string `json:"subnets,omitempty"`
+	// NetworkType is the type of network to allocate addresses from.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Underlay;Overlay;GlobalBGP
+	NetworkType NetworkType `json:"networkType,omitempty"`
+	// IPFamily is the family of addresses to allocate.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=IPv4Only;IPv6Only;DualStack
+	IPFamily IPFamily `json:"ipFamily,omitempty"`
+	// IPPool are addresses to assign, one for each replica of a stateful workload, and an
+	// address of dual stack is in the format of "<ipv4>/<ipv6>".
+	// +kubebuilder:validation:Optional
+	IPPool []string `json:"ipPool,omitempty"`
+	// MACPool are mac addresses to assign, one for each replica of a stateful workload.
+	// +kubebuilder:validation:Optional
+	MACPool []string `json:"macPool,omitempty"`
+	// Retain is whether addresses are kept for the next pod of a stateful workload.
+	// +kubebuilder:validation:Optional
+	Retain *bool `json:"retain,omitempty"`
+	// StaticRoutes are extra routes programmed inside the network namespace of pods, for destinations
+	// which are not reached through the default gateway.
+	// +kubebuilder:validation:Optional
+	StaticRoutes []StaticRoute `json:"staticRoutes,omitempty"`
+}
+
+type StaticRoute struct {
+	// Destination is the CIDR of destination.
+	// +kubebuilder:validation:Required
+	Destination string `json:"destination"`
+	// NextHop is the gateway address of destination, the route is a direct one through device
+	// if empty.
+	// +kubebuilder:validation:Optional
+	NextHop string `json:"nextHop,omitempty"`
+	// Device is the interface of route in the network namespace of pods, default to eth0.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxLength=15
+	Device string `json:"device,omitempty"`
+}
+
+// +k8s:openapi-gen=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +genclient
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Network",type=string,JSONPath=`.spec.network`
+// +kubebuilder:printcolumn:name="NetworkType",type=string,JSONPath=`.spec.networkType`
+// +kubebuilder:printcolumn:name="IPFamily",type=string,JSONPath=`.spec.ipFamily`
+// +kubebuilder:printcolumn:name="Retain",type=boolean,JSONPath=`.spec.retain`
+
+// PodNetworkClaim is the Schema for the PodNetworkClaims API, pods refer to a claim in the same
+// namespace by the annotation of "networking.alibaba.com/network-claim".
+type PodNetworkClaim struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec PodNetworkClaimSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// PodNetworkClaimList contains a list of PodNetworkClaim
+type PodNetworkClaimList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PodNetworkClaim `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PodNetworkClaim{}, &PodNetworkClaimList{})
+}
@@ -31,6 +31,14 @@ type SubnetSpec struct {
 	NetID *int32 `json:"netID"`
 	// +kubebuilder:validation:Required
 	Network string `json:"network"`
+	// NamespaceSelector limits the namespaces whose pods are able to use this subnet,
+	// a nil selector means subnet is visible to all namespaces which are able to use its network.
+	// +kubebuilder:validation:Optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// Topology is the node labels (e.g. topology.kubernetes.io/zone) which this subnet serves,
+	// IPAM prefers the subnet whose topology is matched by labels of the node where pod is scheduled.
+	// +kubebuilder:validation:Optional
+	Topology map[string]string `json:"topology,omitempty"`
 	// +kubebuilder:validation:Optional
 	Config *SubnetConfig `json:"config"`
 }
@@ -39,8 +47,34 @@ type SubnetSpec struct {
 type SubnetStatus struct {
 	// +kubebuilder:validation:Optional
 	Count `json:",inline"`
+	// Reserved is the count of reserved IPs and addresses in reserved ranges, which are excluded from total
+	// +kubebuilder:validation:Optional
+	Reserved int32 `json:"reserved,omitempty"`
 	// +kubebuilder:validation:Optional
 	LastAllocatedIP string `json:"lastAllocatedIP"`
+	// +kubebuilder:validation:Optional
+	DrainingPods int32 `json:"drainingPods,omitempty"`
+	// +kubebuilder:validation:Optional
+	Forecast *SubnetUsageForecast `json:"forecast,omitempty"`
+}
+
+// SubnetUsageForecast is the trend of address usage of a subnet, observed by manager
+type SubnetUsageForecast struct {
+	// +kubebuilder:validation:Optional
+	Windows []SubnetUsageWindow `json:"windows,omitempty"`
+	// ExhaustionTime is the estimated time when all addresses are used at the fastest
+	// allocation rate of windows, empty means usage is not growing or will not be exhausted in ten years
+	// +kubebuilder:validation:Optional
+	ExhaustionTime *metav1.Time `json:"exhaustionTime,omitempty"`
+}
+
+// SubnetUsageWindow is the change of used addresses over a sliding window
+type SubnetUsageWindow struct {
+	// +kubebuilder:validation:Required
+	Duration metav1.Duration `json:"duration"`
+	// UsedDelta is the count of used addresses at present minus the one at the start of window
+	// +kubebuilder:validation:Required
+	UsedDelta int32 `json:"usedDelta"`
 }
 
 // +k8s:openapi-gen=true
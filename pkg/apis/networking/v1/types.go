@@ -33,6 +33,14 @@ const (
 	NetworkModeGlobalBGP = NetworkMode("GlobalBGP")
 )
 
+type RPFilterMode string
+
+const (
+	RPFilterDisabled = RPFilterMode("Disabled")
+	RPFilterStrict   = RPFilterMode("Strict")
+	RPFilterLoose    = RPFilterMode("Loose")
+)
+
 type Count struct {
 	// +kubebuilder:validation:Optional
 	Total int32 `json:"total"`
@@ -62,10 +70,25 @@ type AddressRange struct {
 	Gateway string `json:"gateway"`
 	// +kubebuilder:validation:Optional
 	ReservedIPs []string `json:"reservedIPs,omitempty"`
+	// ReservedRanges are ranges of addresses taken by consumers other than pods, e.g., hardware load
+	// balancers or bare-metal hosts, which are never allocated but still routed as part of subnet
+	// +kubebuilder:validation:Optional
+	ReservedRanges []ReservedRange `json:"reservedRanges,omitempty"`
 	// +kubebuilder:validation:Optional
 	ExcludeIPs []string `json:"excludeIPs,omitempty"`
 }
 
+// ReservedRange is an inclusive range of addresses reserved in subnet
+type ReservedRange struct {
+	// +kubebuilder:validation:Required
+	Start string `json:"start"`
+	// +kubebuilder:validation:Required
+	End string `json:"end"`
+	// Consumer describes what takes the addresses of range, only for reading by users
+	// +kubebuilder:validation:Optional
+	Consumer string `json:"consumer,omitempty"`
+}
+
 type SubnetConfig struct {
 	// +kubebuilder:validation:Optional
 	GatewayType string `json:"gatewayType"`
@@ -77,11 +100,139 @@ type SubnetConfig struct {
 	Private *bool `json:"private"`
 	// +kubebuilder:validation:Optional
 	AllowSubnets []string `json:"allowSubnets"`
+	// +kubebuilder:validation:Optional
+	Cordon *bool `json:"cordon,omitempty"`
+	// +kubebuilder:validation:Optional
+	Drain *bool `json:"drain,omitempty"`
+	// NodeInterface is the node NIC which carries traffic of a vlan subnet, vlan sub-interfaces and policy
+	// routes of subnet are created on it, the global vlan interface of daemon is used if empty
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:MaxLength=15
+	NodeInterface string `json:"nodeInterface,omitempty"`
+	// EgressRoutes selects the next hop gateway of traffic from pods of an underlay subnet by destination,
+	// the gateway of the most specific destination is used, and default route of subnet is kept if no
+	// default destination is specified
+	// +kubebuilder:validation:Optional
+	EgressRoutes []EgressRoute `json:"egressRoutes,omitempty"`
+	// AddressAnnouncement makes daemon resend gratuitous ARP or unsolicited NA for addresses of pods in vlan
+	// subnet after pods start and addresses are rebound, so that upstream switches and routers converge
+	// immediately after pods are rescheduled
+	// +kubebuilder:validation:Optional
+	AddressAnnouncement *AddressAnnouncement `json:"addressAnnouncement,omitempty"`
+	// AllocationFreezeWindows are recurring windows during which no new addresses are allocated from subnet,
+	// e.g., during maintenance of upstream network when new pods would be blackholed, while retained
+	// addresses are still reused
+	// +kubebuilder:validation:Optional
+	AllocationFreezeWindows []AllocationFreezeWindow `json:"allocationFreezeWindows,omitempty"`
+	// NoTrackFlows are flows from pods of subnet exempted from connection tracking, besides the ones of
+	// network, which must not be set for subnets depending on nat outgoing
+	// +kubebuilder:validation:Optional
+	NoTrackFlows []NoTrackFlow `json:"noTrackFlows,omitempty"`
+}
+
+// AllocationFreezeWindow is a recurring window during which new allocations from subnet are rejected
+type AllocationFreezeWindow struct {
+	// Schedule is a cron expression in the standard five fields at which windows start, e.g., "0 2 * * sat"
+	// +kubebuilder:validation:Required
+	Schedule string `json:"schedule"`
+	// DurationMinutes is how long every window lasts
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=1
+	DurationMinutes int32 `json:"durationMinutes"`
+	// TimeZone is the IANA time zone of schedule, e.g., "Asia/Shanghai", default to UTC
+	// +kubebuilder:validation:Optional
+	TimeZone string `json:"timeZone,omitempty"`
+	// Reason is shown in the messages of rejected allocations
+	// +kubebuilder:validation:Optional
+	Reason string `json:"reason,omitempty"`
+}
+
+type AddressAnnouncement struct {
+	// Retries is the number of announcements resent after the first one, default to 3
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10
+	Retries *int32 `json:"retries,omitempty"`
+	// IntervalMilliseconds is the wait before the first retry, which is doubled for every following
+	// retry, default to 1000
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=100
+	IntervalMilliseconds *int32 `json:"intervalMilliseconds,omitempty"`
+}
+
+type EgressRoute struct {
+	// Destination is the CIDR of destination, "0.0.0.0/0" or "::/0" means default route
+	// +kubebuilder:validation:Required
+	Destination string `json:"destination"`
+	// Gateway is the next hop address of destination
+	// +kubebuilder:validation:Required
+	Gateway string `json:"gateway"`
 }
 
 type NetworkConfig struct {
 	// +kubebuilder:validation:Optional
 	BGPPeers []BGPPeer `json:"bgpPeers,omitempty"`
+	// +kubebuilder:validation:Optional
+	Cordon *bool `json:"cordon,omitempty"`
+	// MACSpoofProtection makes pods of network only able to send frames with the MAC of their IPInstances
+	// +kubebuilder:validation:Optional
+	MACSpoofProtection *bool `json:"macSpoofProtection,omitempty"`
+	// NeighborRateLimit limits ARP packets and IPv6 neighbor solicitations sent by every pod of network
+	// +kubebuilder:validation:Optional
+	NeighborRateLimit *NeighborRateLimit `json:"neighborRateLimit,omitempty"`
+	// RPFilter is the reverse path filtering of host veths of pods and node forward interfaces of
+	// network, default to Disabled
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Disabled;Strict;Loose
+	RPFilter RPFilterMode `json:"rpFilter,omitempty"`
+	// Routes are the options of routes which daemon installs for subnets of network
+	// +kubebuilder:validation:Optional
+	Routes *RouteOptions `json:"routes,omitempty"`
+	// NoTrackFlows are flows from pods of network exempted from connection tracking, e.g., storage or metrics
+	// traffic of high packet rate, which are ignored for subnets depending on nat outgoing
+	// +kubebuilder:validation:Optional
+	NoTrackFlows []NoTrackFlow `json:"noTrackFlows,omitempty"`
+}
+
+// NoTrackFlow is a flow from pods to a destination whose packets in both directions are not tracked by
+// conntrack, so that connection tracking table of busy nodes is not exhausted. Destination must not be
+// a Service address, which depends on DNAT of connections.
+type NoTrackFlow struct {
+	// Destination is the CIDR of destination, only applied to subnets of the same family
+	// +kubebuilder:validation:Required
+	Destination string `json:"destination"`
+	// Protocol is the transport protocol of flow
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=TCP;UDP
+	Protocol string `json:"protocol"`
+	// Port is the destination port of flow, all the ports of destination if not specified
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port *int32 `json:"port,omitempty"`
+}
+
+// RouteOptions makes routes of hybridnet yield to or override routes of other agents on shared nodes
+type RouteOptions struct {
+	// Metric is the metric (priority) of routes, default to the one of kernel
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	Metric *int32 `json:"metric,omitempty"`
+	// Realm is the realm of IPv4 routes, for classifying and accounting traffic by route realms
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=255
+	Realm *int32 `json:"realm,omitempty"`
+}
+
+type NeighborRateLimit struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=1
+	PacketsPerSecond int32 `json:"packetsPerSecond"`
+	// Burst is the max number of packets sent at once, default to PacketsPerSecond
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	Burst *int32 `json:"burst,omitempty"`
 }
 
 type Address struct {
@@ -124,8 +275,16 @@ type VTEPInfo struct {
 	LocalIPs []string `json:"localIPs,omitempty"`
 }
 
-// The conversion process from IPInstance v1.1 to v1.2 has been removed after hybridnet v0.6.0.
+// The conversion process from IPInstance v1.1 to v1.2 has been removed after hybridnet v0.6.0. Models are
+// versioned by label within the only served version v1, a model breaking the v1 schema needs a new served
+// version of CRD with a conversion webhook, which is not introduced yet.
 const (
 	IPInstanceV12           = "v1.2"
 	IPInstanceLatestVersion = IPInstanceV12
 )
+
+// Phases of IPInstance, which are kept in the phase label for listing by label selector
+const (
+	IPInstancePhaseAllocated = "Allocated"
+	IPInstancePhaseReserved  = "Reserved"
+)
@@ -17,16 +17,26 @@
 package v1
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
 	"math/big"
 	"net"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gogf/gf/container/gset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
 
+	"github.com/alibaba/hybridnet/pkg/constants"
 	"github.com/alibaba/hybridnet/pkg/utils"
+	"github.com/alibaba/hybridnet/pkg/utils/cron"
 )
 
 // TODO: unit tests
@@ -39,6 +49,594 @@ func IsPrivateSubnet(subnet *Subnet) bool {
 	return *subnet.Spec.Config.Private
 }
 
+// IsCordonedSubnet means no more addresses should be allocated from subnet, even if it is
+// specified, a drained subnet is always cordoned
+func IsCordonedSubnet(subnet *Subnet) bool {
+	if subnet == nil || subnet.Spec.Config == nil {
+		return false
+	}
+
+	return (subnet.Spec.Config.Cordon != nil && *subnet.Spec.Config.Cordon) || IsDrainedSubnet(subnet)
+}
+
+// IsDrainedSubnet means pods using addresses of subnet should be moved off
+func IsDrainedSubnet(subnet *Subnet) bool {
+	if subnet == nil || subnet.Spec.Config == nil || subnet.Spec.Config.Drain == nil {
+		return false
+	}
+
+	return *subnet.Spec.Config.Drain
+}
+
+// IsCordonedNetwork means no more addresses should be allocated from any subnet of
+// network, while existing allocations are untouched
+func IsCordonedNetwork(network *Network) bool {
+	if network == nil || network.Spec.Config == nil || network.Spec.Config.Cordon == nil {
+		return false
+	}
+
+	return *network.Spec.Config.Cordon
+}
+
+// IsMACSpoofProtectedNetwork means pods of network can only send frames with the MAC of their ip instances
+func IsMACSpoofProtectedNetwork(network *Network) bool {
+	if network == nil || network.Spec.Config == nil || network.Spec.Config.MACSpoofProtection == nil {
+		return false
+	}
+
+	return *network.Spec.Config.MACSpoofProtection
+}
+
+// IsNamespaceRestrictedSubnet means subnet is only visible to namespaces selected by its namespace selector
+func IsNamespaceRestrictedSubnet(subnet *Subnet) bool {
+	return subnet != nil && subnet.Spec.NamespaceSelector != nil
+}
+
+// IsNetworkVisibleToNamespace means pods of namespace are allowed to use network
+func IsNetworkVisibleToNamespace(network *Network, namespaceLabels map[string]string) (bool, error) {
+	if network == nil {
+		return false, nil
+	}
+
+	return matchNamespaceSelector(network.Spec.NamespaceSelector, namespaceLabels)
+}
+
+// GetNetworkTenant returns the tenant which network belongs to, empty if network belongs to no tenant
+func GetNetworkTenant(network *Network) string {
+	if network == nil {
+		return ""
+	}
+
+	return network.Spec.Tenant
+}
+
+// IsTenantNetwork means network has an isolated address space of its tenant
+func IsTenantNetwork(network *Network) bool {
+	return len(GetNetworkTenant(network)) > 0
+}
+
+// IsSubnetVisibleToNamespace means pods of namespace are allowed to use subnet, the visibility
+// of its network should be checked separately
+func IsSubnetVisibleToNamespace(subnet *Subnet, namespaceLabels map[string]string) (bool, error) {
+	if subnet == nil {
+		return false, nil
+	}
+
+	return matchNamespaceSelector(subnet.Spec.NamespaceSelector, namespaceLabels)
+}
+
+// GetSubnetOfAddress returns the one of subnets whose cidr contains address, nil if there is none
+func GetSubnetOfAddress(subnets []Subnet, address string) *Subnet {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return nil
+	}
+
+	for i := range subnets {
+		if _, cidr, err := net.ParseCIDR(subnets[i].Spec.Range.CIDR); err == nil && cidr.Contains(ip) {
+			return &subnets[i]
+		}
+	}
+	return nil
+}
+
+func matchNamespaceSelector(namespaceSelector *metav1.LabelSelector, namespaceLabels map[string]string) (bool, error) {
+	if namespaceSelector == nil {
+		return true, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(namespaceSelector)
+	if err != nil {
+		return false, fmt.Errorf("invalid namespace selector: %v", err)
+	}
+
+	return selector.Matches(labels.Set(namespaceLabels)), nil
+}
+
+// AreNamespaceSelectorsDisjoint means no namespace can be selected by both selectors, which is only
+// proved by contradictory requirements on the same label key, e.g., different values of matchLabels
+func AreNamespaceSelectorsDisjoint(a, b *metav1.LabelSelector) (bool, error) {
+	// nil selector selects all the namespaces
+	if a == nil || b == nil {
+		return false, nil
+	}
+
+	type keyRequirement struct {
+		values      sets.String
+		notValues   sets.String
+		exists      bool
+		doesntExist bool
+	}
+
+	keyRequirements := map[string]*keyRequirement{}
+	for _, labelSelector := range []*metav1.LabelSelector{a, b} {
+		selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+		if err != nil {
+			return false, fmt.Errorf("invalid namespace selector: %v", err)
+		}
+
+		requirements, _ := selector.Requirements()
+		for _, requirement := range requirements {
+			keyReq, exist := keyRequirements[requirement.Key()]
+			if !exist {
+				keyReq = &keyRequirement{notValues: sets.NewString()}
+				keyRequirements[requirement.Key()] = keyReq
+			}
+
+			switch requirement.Operator() {
+			case selection.Equals, selection.DoubleEquals, selection.In:
+				keyReq.exists = true
+				if keyReq.values == nil {
+					keyReq.values = requirement.Values()
+				} else {
+					keyReq.values = keyReq.values.Intersection(requirement.Values())
+				}
+			case selection.NotEquals, selection.NotIn:
+				keyReq.notValues = keyReq.notValues.Union(requirement.Values())
+			case selection.Exists:
+				keyReq.exists = true
+			case selection.DoesNotExist:
+				keyReq.doesntExist = true
+			}
+		}
+	}
+
+	for _, keyReq := range keyRequirements {
+		if keyReq.exists && keyReq.doesntExist {
+			return true, nil
+		}
+		if keyReq.values != nil && keyReq.values.Difference(keyReq.notValues).Len() == 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetNeighborRateLimit returns the packets per second and burst of ARP/ND packets sent by every pod
+// of network, limited is false if no rate limit is set
+func GetNeighborRateLimit(network *Network) (packetsPerSecond, burst uint32, limited bool) {
+	if network == nil || network.Spec.Config == nil || network.Spec.Config.NeighborRateLimit == nil {
+		return 0, 0, false
+	}
+
+	rateLimit := network.Spec.Config.NeighborRateLimit
+	if rateLimit.PacketsPerSecond <= 0 {
+		return 0, 0, false
+	}
+
+	packetsPerSecond, burst = uint32(rateLimit.PacketsPerSecond), uint32(rateLimit.PacketsPerSecond)
+	if rateLimit.Burst != nil && *rateLimit.Burst > 0 {
+		burst = uint32(*rateLimit.Burst)
+	}
+	return packetsPerSecond, burst, true
+}
+
+// GetNetworkRPFilter returns the reverse path filtering mode of network, default to Disabled
+func GetNetworkRPFilter(network *Network) RPFilterMode {
+	if network == nil || network.Spec.Config == nil || len(network.Spec.Config.RPFilter) == 0 {
+		return RPFilterDisabled
+	}
+
+	return network.Spec.Config.RPFilter
+}
+
+// GetNetworkRouteOptions returns the metric and realm of routes for subnets of network, zero means not specified
+func GetNetworkRouteOptions(network *Network) (metric, realm int) {
+	if network == nil || network.Spec.Config == nil || network.Spec.Config.Routes == nil {
+		return 0, 0
+	}
+
+	routes := network.Spec.Config.Routes
+	if routes.Metric != nil && *routes.Metric > 0 {
+		metric = int(*routes.Metric)
+	}
+	if routes.Realm != nil && *routes.Realm > 0 {
+		realm = int(*routes.Realm)
+	}
+	return metric, realm
+}
+
+// SelectNodeNetworkConfig returns the NodeNetworkConfig applying to node, a config with the same name of node
+// is preferred, then the selecting one with the largest priority. Nil is returned if no config applies.
+func SelectNodeNetworkConfig(configs []NodeNetworkConfig, nodeName string, nodeLabels map[string]string) (*NodeNetworkConfig, error) {
+	var selected *NodeNetworkConfig
+	for i := range configs {
+		config := &configs[i]
+		if config.Name == nodeName {
+			return config, nil
+		}
+
+		if config.Spec.NodeSelector == nil {
+			continue
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(config.Spec.NodeSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid node selector of node network config %v: %v", config.Name, err)
+		}
+		if !selector.Matches(labels.Set(nodeLabels)) {
+			continue
+		}
+
+		if selected == nil || config.Spec.Priority > selected.Spec.Priority ||
+			config.Spec.Priority == selected.Spec.Priority && config.Name < selected.Name {
+			selected = config
+		}
+	}
+	return selected, nil
+}
+
+// IsNodeNetworkConfigCanary checks if a node is one of the canary nodes of NodeNetworkConfig, which
+// apply changes of config before the other nodes
+func IsNodeNetworkConfigCanary(config *NodeNetworkConfig, nodeLabels map[string]string) (bool, error) {
+	if config.Spec.Rollout == nil || config.Spec.Rollout.CanaryNodeSelector == nil {
+		return false, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(config.Spec.Rollout.CanaryNodeSelector)
+	if err != nil {
+		return false, fmt.Errorf("invalid canary node selector of node network config %v: %v", config.Name, err)
+	}
+	return selector.Matches(labels.Set(nodeLabels)), nil
+}
+
+// GetEffectiveNodeNetworkConfigSpec returns the spec of NodeNetworkConfig which a node applies with its
+// revision. Canary nodes and all nodes of a config without rollout apply the latest spec, and canary nodes
+// roll back to the promoted spec if the latest generation fails. The other nodes apply the promoted spec
+// once it's released to them or promoted to all nodes, otherwise they keep the spec applied by daemon on
+// annotations of node, and a nil spec with empty revision is returned if nothing has been applied yet.
+func GetEffectiveNodeNetworkConfigSpec(config *NodeNetworkConfig, nodeLabels, nodeAnnotations map[string]string) (
+	*NodeNetworkConfigSpec, string, error) {
+	if config.Spec.Rollout == nil {
+		return &config.Spec, NodeNetworkConfigRevision(config.Name, config.Generation), nil
+	}
+
+	canary, err := IsNodeNetworkConfigCanary(config, nodeLabels)
+	if err != nil {
+		return nil, "", err
+	}
+
+	promotedRevision := NodeNetworkConfigRevision(config.Name, config.Status.PromotedGeneration)
+	if canary {
+		if config.Status.Phase != NodeNetworkConfigRolloutFailed || config.Status.ObservedGeneration != config.Generation {
+			return &config.Spec, NodeNetworkConfigRevision(config.Name, config.Generation), nil
+		}
+		if config.Status.PromotedSpec == nil {
+			// nothing of this config has been verified, roll back to the state before it
+			return nil, "", nil
+		}
+		return config.Status.PromotedSpec, promotedRevision, nil
+	}
+
+	if config.Status.PromotedSpec != nil && (config.Status.Phase == NodeNetworkConfigRolloutPromoted ||
+		nodeAnnotations[constants.AnnotationNodeNetworkConfigReleasedRevision] == promotedRevision ||
+		nodeAnnotations[constants.AnnotationNodeNetworkConfigRevision] == promotedRevision) {
+		return config.Status.PromotedSpec, promotedRevision, nil
+	}
+	return GetAppliedNodeNetworkConfigSpec(nodeAnnotations)
+}
+
+// GetAppliedNodeNetworkConfigSpec returns the spec and revision of NodeNetworkConfig which daemon applies
+// and passes the self-check with, from annotations of node
+func GetAppliedNodeNetworkConfigSpec(nodeAnnotations map[string]string) (*NodeNetworkConfigSpec, string, error) {
+	revision := nodeAnnotations[constants.AnnotationNodeNetworkConfigRevision]
+	appliedSpec := nodeAnnotations[constants.AnnotationNodeNetworkConfigAppliedSpec]
+	if len(revision) == 0 || len(appliedSpec) == 0 {
+		return nil, "", nil
+	}
+
+	spec := &NodeNetworkConfigSpec{}
+	if err := json.Unmarshal([]byte(appliedSpec), spec); err != nil {
+		return nil, "", fmt.Errorf("failed to unmarshal applied node network config spec of revision %v: %v", revision, err)
+	}
+	return spec, revision, nil
+}
+
+// GetNodeNetworkConfigMaxUnavailable returns how many of the nodes other than canary ones are allowed to
+// restart daemons for a promoted change at the same time, a percentage is rounded up and at least one
+// node is allowed so that the rollout always makes progress
+func GetNodeNetworkConfigMaxUnavailable(rollout *NodeNetworkConfigRollout, nodes int) (int, error) {
+	if rollout == nil || rollout.MaxUnavailable == nil {
+		return 1, nil
+	}
+
+	maxUnavailable, err := intstr.GetScaledValueFromIntOrPercent(rollout.MaxUnavailable, nodes, true)
+	if err != nil {
+		return 0, fmt.Errorf("invalid max unavailable %v: %v", rollout.MaxUnavailable, err)
+	}
+	if maxUnavailable < 0 {
+		return 0, fmt.Errorf("invalid max unavailable %v, should not be negative", rollout.MaxUnavailable)
+	}
+	if maxUnavailable == 0 {
+		return 1, nil
+	}
+	return maxUnavailable, nil
+}
+
+// NodeNetworkConfigRevision returns the revision of NodeNetworkConfig reported by daemons which apply it
+func NodeNetworkConfigRevision(name string, generation int64) string {
+	return fmt.Sprintf("%s/%d", name, generation)
+}
+
+// ValidateNodeNetworkConfigSpec checks fields of NodeNetworkConfig which can not be validated by schema
+func ValidateNodeNetworkConfigSpec(spec *NodeNetworkConfigSpec) error {
+	if spec.NodeSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(spec.NodeSelector); err != nil {
+			return fmt.Errorf("invalid node selector: %v", err)
+		}
+	}
+
+	if spec.Rollout != nil {
+		if spec.Rollout.CanaryNodeSelector == nil {
+			return fmt.Errorf("canary node selector of rollout must be specified")
+		}
+		if _, err := metav1.LabelSelectorAsSelector(spec.Rollout.CanaryNodeSelector); err != nil {
+			return fmt.Errorf("invalid canary node selector: %v", err)
+		}
+		if _, err := GetNodeNetworkConfigMaxUnavailable(spec.Rollout, 0); err != nil {
+			return err
+		}
+	}
+
+	for _, cidr := range spec.VtepAddressCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("invalid vtep address cidr %v: %v", cidr, err)
+		}
+	}
+
+	if spec.MTU != nil {
+		for _, mtu := range []*int32{spec.MTU.Vlan, spec.MTU.Vxlan, spec.MTU.BGP} {
+			// 1280 is the minimum mtu of ipv6
+			if mtu != nil && (*mtu < 1280 || *mtu > 65535) {
+				return fmt.Errorf("invalid mtu %v, should be in range [1280, 65535]", *mtu)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidatePodNetworkClaimSpec checks fields of PodNetworkClaim which can not be validated by schema,
+// the existence of network and subnets is not checked
+func ValidatePodNetworkClaimSpec(spec *PodNetworkClaimSpec) error {
+	if len(spec.Subnets) > 2 {
+		return fmt.Errorf("cannot have more than two specified subnets")
+	}
+	for _, subnet := range spec.Subnets {
+		if len(subnet) == 0 || strings.Contains(subnet, "/") {
+			return fmt.Errorf("invalid subnet name %q", subnet)
+		}
+	}
+
+	switch spec.NetworkType {
+	case "", NetworkTypeUnderlay, NetworkTypeOverlay, NetworkTypeGlobalBGP:
+	default:
+		return fmt.Errorf("unrecognized network type %v", spec.NetworkType)
+	}
+
+	switch spec.IPFamily {
+	case "", IPFamilyIPv4Only, IPFamilyIPv6Only, IPFamilyDualStack:
+	default:
+		return fmt.Errorf("unrecognized ip family %v", spec.IPFamily)
+	}
+
+	for idx, ips := range spec.IPPool {
+		for _, ip := range strings.Split(ips, "/") {
+			if len(ip) == 0 || utils.NormalizedIP(ip) != ip {
+				return fmt.Errorf("the %d ip %q in ip pool is not valid", idx, ips)
+			}
+		}
+	}
+
+	for idx, mac := range spec.MACPool {
+		if _, err := net.ParseMAC(mac); err != nil {
+			return fmt.Errorf("the %d mac address %q in mac pool is not valid", idx, mac)
+		}
+	}
+
+	return ValidateStaticRoutes(spec.StaticRoutes)
+}
+
+// ValidateChaosRuleSpec checks fields of ChaosRule which can not be validated by schema, only the
+// field of action should be specified
+func ValidateChaosRuleSpec(spec *ChaosRuleSpec) error {
+	if spec.NodeSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(spec.NodeSelector); err != nil {
+			return fmt.Errorf("invalid node selector: %v", err)
+		}
+	}
+
+	switch spec.Action {
+	case ChaosActionDrop:
+		if spec.Drop == nil || spec.Delay != nil || spec.BlackholeVTEP != nil {
+			return fmt.Errorf("only drop should be specified for action %v", spec.Action)
+		}
+		if _, _, err := net.ParseCIDR(spec.Drop.Destination); err != nil {
+			return fmt.Errorf("invalid drop destination %v: %v", spec.Drop.Destination, err)
+		}
+		if spec.Drop.Percent < 1 || spec.Drop.Percent > 100 {
+			return fmt.Errorf("invalid drop percent %v, should be in range [1, 100]", spec.Drop.Percent)
+		}
+	case ChaosActionDelay:
+		if spec.Delay == nil || spec.Drop != nil || spec.BlackholeVTEP != nil {
+			return fmt.Errorf("only delay should be specified for action %v", spec.Action)
+		}
+		if spec.Delay.LatencyMilliseconds < 1 || spec.Delay.JitterMilliseconds < 0 {
+			return fmt.Errorf("invalid delay latency %vms with jitter %vms",
+				spec.Delay.LatencyMilliseconds, spec.Delay.JitterMilliseconds)
+		}
+	case ChaosActionBlackholeVTEP:
+		if spec.BlackholeVTEP == nil || spec.Drop != nil || spec.Delay != nil {
+			return fmt.Errorf("only blackholeVTEP should be specified for action %v", spec.Action)
+		}
+		if (len(spec.BlackholeVTEP.NodeName) == 0) == (len(spec.BlackholeVTEP.VTEPIP) == 0) {
+			return fmt.Errorf("exactly one of node name and vtep ip should be specified for blackholeVTEP")
+		}
+		if len(spec.BlackholeVTEP.VTEPIP) != 0 && net.ParseIP(spec.BlackholeVTEP.VTEPIP) == nil {
+			return fmt.Errorf("invalid vtep ip %v", spec.BlackholeVTEP.VTEPIP)
+		}
+	default:
+		return fmt.Errorf("unrecognized chaos action %v", spec.Action)
+	}
+	return nil
+}
+
+// IsChaosRuleExpired checks if the fault of ChaosRule should have been removed at the time
+func IsChaosRuleExpired(rule *ChaosRule, now time.Time) bool {
+	return rule.Spec.ExpireTime != nil && !now.Before(rule.Spec.ExpireTime.Time)
+}
+
+// ValidateStaticRoutes checks if static routes have valid destinations, next hops of the same family
+// with destinations and valid devices, and no destination is duplicated
+func ValidateStaticRoutes(staticRoutes []StaticRoute) error {
+	destinations := map[string]bool{}
+
+	for _, staticRoute := range staticRoutes {
+		ipOfDst, dst, err := net.ParseCIDR(staticRoute.Destination)
+		if err != nil {
+			return fmt.Errorf("invalid static route destination %s", staticRoute.Destination)
+		}
+		if !dst.IP.Equal(ipOfDst) {
+			return fmt.Errorf("static route destination %s is not standard, should start from %s", staticRoute.Destination, dst.IP)
+		}
+		if destinations[dst.String()] {
+			return fmt.Errorf("static route destination %s is duplicated", staticRoute.Destination)
+		}
+		destinations[dst.String()] = true
+
+		if len(staticRoute.NextHop) > 0 {
+			nextHop := net.ParseIP(staticRoute.NextHop)
+			if nextHop == nil {
+				return fmt.Errorf("invalid static route next hop %s", staticRoute.NextHop)
+			}
+			if (nextHop.To4() == nil) != (dst.IP.To4() == nil) {
+				return fmt.Errorf("static route next hop %s is not of the same family with destination %s",
+					staticRoute.NextHop, staticRoute.Destination)
+			}
+		}
+
+		if len(staticRoute.Device) > 0 {
+			if err = ValidateNodeInterfaceName(staticRoute.Device); err != nil {
+				return fmt.Errorf("invalid device of static route to %s: %v", staticRoute.Destination, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ParseStaticRoutes parses static routes from the json value of annotation
+func ParseStaticRoutes(value string) ([]StaticRoute, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	var staticRoutes []StaticRoute
+	if err := json.Unmarshal([]byte(value), &staticRoutes); err != nil {
+		return nil, fmt.Errorf("invalid static routes %q: %v", value, err)
+	}
+	return staticRoutes, nil
+}
+
+// PodNetworkClaimSpecToAnnotations returns the legacy pod annotations equal to spec, which are what
+// hybridnet components finally read network intent of pods from
+func PodNetworkClaimSpecToAnnotations(spec *PodNetworkClaimSpec) map[string]string {
+	annotations := map[string]string{}
+	setIfNotEmpty := func(key, value string) {
+		if len(value) > 0 {
+			annotations[key] = value
+		}
+	}
+
+	setIfNotEmpty(constants.AnnotationSpecifiedNetwork, spec.Network)
+	setIfNotEmpty(constants.AnnotationSpecifiedSubnet, strings.Join(spec.Subnets, "/"))
+	setIfNotEmpty(constants.AnnotationNetworkType, string(spec.NetworkType))
+	setIfNotEmpty(constants.AnnotationIPFamily, string(spec.IPFamily))
+	setIfNotEmpty(constants.AnnotationIPPool, strings.Join(spec.IPPool, ","))
+	setIfNotEmpty(constants.AnnotationMACPool, strings.Join(spec.MACPool, ","))
+	if spec.Retain != nil {
+		annotations[constants.AnnotationIPRetain] = strconv.FormatBool(*spec.Retain)
+	}
+	if len(spec.StaticRoutes) > 0 {
+		// marshaling a slice of plain structs never fails
+		staticRoutes, _ := json.Marshal(spec.StaticRoutes)
+		annotations[constants.AnnotationStaticRoutes] = string(staticRoutes)
+	}
+	return annotations
+}
+
+// PodNetworkClaimSpecFromAnnotations translates legacy network annotations of pod into a typed spec,
+// network type and ip family are matched case-insensitively as before, unrecognized values are kept
+// for validation to report.
+func PodNetworkClaimSpecFromAnnotations(annotations map[string]string) (*PodNetworkClaimSpec, error) {
+	spec := &PodNetworkClaimSpec{
+		Network: annotations[constants.AnnotationSpecifiedNetwork],
+	}
+
+	if subnets := annotations[constants.AnnotationSpecifiedSubnet]; len(subnets) > 0 {
+		spec.Subnets = strings.Split(subnets, "/")
+	}
+
+	networkType := NetworkType(annotations[constants.AnnotationNetworkType])
+	for _, knownType := range []NetworkType{NetworkTypeUnderlay, NetworkTypeOverlay, NetworkTypeGlobalBGP} {
+		if strings.EqualFold(string(networkType), string(knownType)) {
+			networkType = knownType
+		}
+	}
+	spec.NetworkType = networkType
+
+	ipFamily := IPFamily(annotations[constants.AnnotationIPFamily])
+	switch strings.ToLower(string(ipFamily)) {
+	case "ipv4", "ipv4only":
+		ipFamily = IPFamilyIPv4Only
+	case "ipv6", "ipv6only":
+		ipFamily = IPFamilyIPv6Only
+	case "dualstack":
+		ipFamily = IPFamilyDualStack
+	}
+	spec.IPFamily = ipFamily
+
+	if ipPool := annotations[constants.AnnotationIPPool]; len(ipPool) > 0 {
+		spec.IPPool = strings.Split(ipPool, ",")
+	}
+	if macPool := annotations[constants.AnnotationMACPool]; len(macPool) > 0 {
+		spec.MACPool = strings.Split(macPool, ",")
+	}
+
+	if retain, exist := annotations[constants.AnnotationIPRetain]; exist {
+		parsed, err := strconv.ParseBool(retain)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ip retain %q, must be a bool", retain)
+		}
+		spec.Retain = &parsed
+	}
+
+	staticRoutes, err := ParseStaticRoutes(annotations[constants.AnnotationStaticRoutes])
+	if err != nil {
+		return nil, err
+	}
+	spec.StaticRoutes = staticRoutes
+	return spec, nil
+}
+
 func IsIPv6Subnet(subnet *Subnet) bool {
 	if subnet == nil {
 		return false
@@ -105,6 +703,10 @@ func IsIPv6IPInstance(ip *IPInstance) bool {
 	return false
 }
 
+// MaxReservedRangeAddresses limits the total addresses of reserved ranges in an address range, which are
+// expanded to single addresses by IPAM and capacity preview
+const MaxReservedRangeAddresses = 65536
+
 func ValidateAddressRange(ar *AddressRange) (err error) {
 	var (
 		isIPv6   bool
@@ -171,6 +773,25 @@ func ValidateAddressRange(ar *AddressRange) (err error) {
 		}
 	}
 
+	reservedRangeAddresses := big.NewInt(0)
+	for _, rr := range ar.ReservedRanges {
+		rangeStart, rangeEnd := net.ParseIP(rr.Start), net.ParseIP(rr.End)
+		if rangeStart == nil || rangeEnd == nil {
+			return fmt.Errorf("invalid reserved range %s-%s", rr.Start, rr.End)
+		}
+		if !cidr.Contains(rangeStart) || !cidr.Contains(rangeEnd) {
+			return fmt.Errorf("reserved range %s-%s is not in CIDR %s", rr.Start, rr.End, ar.CIDR)
+		}
+		if utils.Cmp(rangeStart, rangeEnd) > 0 {
+			return fmt.Errorf("start of reserved range %s-%s is after end", rr.Start, rr.End)
+		}
+		reservedRangeAddresses.Add(reservedRangeAddresses, utils.Capacity(rangeStart, rangeEnd))
+	}
+	if reservedRangeAddresses.Cmp(big.NewInt(MaxReservedRangeAddresses)) > 0 {
+		return fmt.Errorf("reserved ranges have %s addresses, more than the limit %d",
+			reservedRangeAddresses, MaxReservedRangeAddresses)
+	}
+
 	for _, eip := range ar.ExcludeIPs {
 		if tempIP = net.ParseIP(eip); tempIP == nil {
 			return fmt.Errorf("invalid excluded ip %s", eip)
@@ -182,6 +803,154 @@ func ValidateAddressRange(ar *AddressRange) (err error) {
 	return nil
 }
 
+// ValidateNodeInterfaceName checks if name is a valid name of network interface on node
+func ValidateNodeInterfaceName(name string) error {
+	if len(name) == 0 || len(name) > 15 || name == "." || name == ".." {
+		return fmt.Errorf("invalid node interface name %q", name)
+	}
+	if strings.ContainsAny(name, "/: \t\n") {
+		return fmt.Errorf("invalid node interface name %q, must not contain '/', ':' or spaces", name)
+	}
+	return nil
+}
+
+// GetSubnetNodeInterface returns the node NIC specified for subnet, empty if not specified
+func GetSubnetNodeInterface(subnetSpec *SubnetSpec) string {
+	if subnetSpec == nil || subnetSpec.Config == nil {
+		return ""
+	}
+
+	return subnetSpec.Config.NodeInterface
+}
+
+// GetSubnetEgressRoutes returns the egress routes specified for subnet
+func GetSubnetEgressRoutes(subnetSpec *SubnetSpec) []EgressRoute {
+	if subnetSpec == nil || subnetSpec.Config == nil {
+		return nil
+	}
+
+	return subnetSpec.Config.EgressRoutes
+}
+
+const (
+	defaultAddressAnnouncementRetries  = 3
+	defaultAddressAnnouncementInterval = time.Second
+)
+
+// GetSubnetAddressAnnouncement returns how many times the announcements of pod addresses in subnet are resent
+// and the wait before the first retry, announced is false if address announcement is not specified for subnet
+func GetSubnetAddressAnnouncement(subnetSpec *SubnetSpec) (retries int, interval time.Duration, announced bool) {
+	if subnetSpec == nil || subnetSpec.Config == nil || subnetSpec.Config.AddressAnnouncement == nil {
+		return 0, 0, false
+	}
+
+	announcement := subnetSpec.Config.AddressAnnouncement
+	retries, interval = defaultAddressAnnouncementRetries, defaultAddressAnnouncementInterval
+	if announcement.Retries != nil && *announcement.Retries > 0 {
+		retries = int(*announcement.Retries)
+	}
+	if announcement.IntervalMilliseconds != nil && *announcement.IntervalMilliseconds > 0 {
+		interval = time.Duration(*announcement.IntervalMilliseconds) * time.Millisecond
+	}
+	return retries, interval, true
+}
+
+// GetSubnetAllocationFreezeWindows returns the allocation freeze windows specified for subnet
+func GetSubnetAllocationFreezeWindows(subnetSpec *SubnetSpec) []AllocationFreezeWindow {
+	if subnetSpec == nil || subnetSpec.Config == nil {
+		return nil
+	}
+
+	return subnetSpec.Config.AllocationFreezeWindows
+}
+
+// ParseAllocationFreezeWindow returns the parsed schedule, the duration and the time zone of an allocation
+// freeze window, time zone is UTC if not specified
+func ParseAllocationFreezeWindow(window *AllocationFreezeWindow) (*cron.Schedule, time.Duration, *time.Location, error) {
+	schedule, err := cron.Parse(window.Schedule)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("invalid schedule %q: %v", window.Schedule, err)
+	}
+
+	if window.DurationMinutes <= 0 {
+		return nil, 0, nil, fmt.Errorf("invalid duration minutes %d, must be positive", window.DurationMinutes)
+	}
+
+	location := time.UTC
+	if len(window.TimeZone) > 0 {
+		if location, err = time.LoadLocation(window.TimeZone); err != nil {
+			return nil, 0, nil, fmt.Errorf("invalid time zone %q: %v", window.TimeZone, err)
+		}
+	}
+
+	return schedule, time.Duration(window.DurationMinutes) * time.Minute, location, nil
+}
+
+// ValidateSubnetAllocationFreezeWindows checks if allocation freeze windows of subnet have valid schedules,
+// durations and time zones
+func ValidateSubnetAllocationFreezeWindows(subnetSpec *SubnetSpec) error {
+	windows := GetSubnetAllocationFreezeWindows(subnetSpec)
+	for i := range windows {
+		if _, _, _, err := ParseAllocationFreezeWindow(&windows[i]); err != nil {
+			return fmt.Errorf("invalid allocation freeze window %d: %v", i, err)
+		}
+	}
+
+	return nil
+}
+
+// ActiveAllocationFreezeWindow returns the allocation freeze window of subnet which t is in and when it ends,
+// invalid windows are ignored
+func ActiveAllocationFreezeWindow(subnetSpec *SubnetSpec, t time.Time) (window *AllocationFreezeWindow, end time.Time, frozen bool) {
+	windows := GetSubnetAllocationFreezeWindows(subnetSpec)
+	for i := range windows {
+		schedule, duration, location, err := ParseAllocationFreezeWindow(&windows[i])
+		if err != nil {
+			continue
+		}
+		if start, ok := schedule.ActiveWindow(t.In(location), duration); ok {
+			return &windows[i], start.Add(duration), true
+		}
+	}
+
+	return nil, time.Time{}, false
+}
+
+// ValidateSubnetEgressRoutes checks if egress routes of subnet have valid destinations and gateways
+// of the same family with subnet, and no destination is duplicated
+func ValidateSubnetEgressRoutes(subnetSpec *SubnetSpec) error {
+	isIPv6 := subnetSpec.Range.Version == IPv6
+	destinations := map[string]bool{}
+
+	for _, egressRoute := range GetSubnetEgressRoutes(subnetSpec) {
+		ipOfDst, dst, err := net.ParseCIDR(egressRoute.Destination)
+		if err != nil {
+			return fmt.Errorf("invalid egress route destination %s", egressRoute.Destination)
+		}
+		if !dst.IP.Equal(ipOfDst) {
+			return fmt.Errorf("egress route destination %s is not standard, should start from %s", egressRoute.Destination, dst.IP)
+		}
+		if (dst.IP.To4() == nil) != isIPv6 {
+			return fmt.Errorf("egress route destination %s is not of subnet family", egressRoute.Destination)
+		}
+
+		gateway := net.ParseIP(egressRoute.Gateway)
+		if gateway == nil {
+			return fmt.Errorf("invalid egress route gateway %s", egressRoute.Gateway)
+		}
+		if (gateway.To4() == nil) != isIPv6 {
+			return fmt.Errorf("egress route gateway %s is not of subnet family", egressRoute.Gateway)
+		}
+
+		if destinations[dst.String()] {
+			return fmt.Errorf("duplicated egress route destination %s", egressRoute.Destination)
+		}
+		destinations[dst.String()] = true
+	}
+
+	return nil
+}
+
 func IsSubnetAutoNatOutgoing(subnetSpec *SubnetSpec) bool {
 	if subnetSpec == nil || subnetSpec.Config == nil || subnetSpec.Config.AutoNatOutgoing == nil {
 		return true
@@ -190,6 +959,77 @@ func IsSubnetAutoNatOutgoing(subnetSpec *SubnetSpec) bool {
 	return *subnetSpec.Config.AutoNatOutgoing
 }
 
+// IsSubnetNATDependent means traffic from pods of subnet to outside of cluster is masqueraded, which
+// is broken if connections are not tracked
+func IsSubnetNATDependent(subnetSpec *SubnetSpec, network *Network) bool {
+	return GetNetworkMode(network) == NetworkModeVxlan && IsSubnetAutoNatOutgoing(subnetSpec)
+}
+
+// ValidateNoTrackFlows checks if notrack flows have valid destinations, protocols and ports,
+// and no flow is duplicated
+func ValidateNoTrackFlows(flows []NoTrackFlow) error {
+	seen := map[string]bool{}
+	for _, flow := range flows {
+		ipOfDst, dst, err := net.ParseCIDR(flow.Destination)
+		if err != nil {
+			return fmt.Errorf("invalid notrack flow destination %s", flow.Destination)
+		}
+		if !dst.IP.Equal(ipOfDst) {
+			return fmt.Errorf("notrack flow destination %s is not standard, should start from %s", flow.Destination, dst.IP)
+		}
+		if ones, _ := dst.Mask.Size(); ones == 0 {
+			return fmt.Errorf("notrack flow destination %s must not be default destination", flow.Destination)
+		}
+
+		if flow.Protocol != "TCP" && flow.Protocol != "UDP" {
+			return fmt.Errorf("invalid notrack flow protocol %s, must be TCP or UDP", flow.Protocol)
+		}
+
+		port := int32(0)
+		if flow.Port != nil {
+			if *flow.Port < 1 || *flow.Port > 65535 {
+				return fmt.Errorf("invalid notrack flow port %d", *flow.Port)
+			}
+			port = *flow.Port
+		}
+
+		key := fmt.Sprintf("%s/%s/%d", dst.String(), flow.Protocol, port)
+		if seen[key] {
+			return fmt.Errorf("duplicated notrack flow to %s of %s port %d", flow.Destination, flow.Protocol, port)
+		}
+		seen[key] = true
+	}
+
+	return nil
+}
+
+// GetSubnetNoTrackFlows returns the notrack flows of network and subnet with destinations of the same
+// family with subnet, nothing is returned for a subnet depending on nat outgoing
+func GetSubnetNoTrackFlows(subnetSpec *SubnetSpec, network *Network) []NoTrackFlow {
+	if subnetSpec == nil || IsSubnetNATDependent(subnetSpec, network) {
+		return nil
+	}
+
+	var flows []NoTrackFlow
+	if network != nil && network.Spec.Config != nil {
+		flows = append(flows, network.Spec.Config.NoTrackFlows...)
+	}
+	if subnetSpec.Config != nil {
+		flows = append(flows, subnetSpec.Config.NoTrackFlows...)
+	}
+
+	isIPv6 := subnetSpec.Range.Version == IPv6
+	var familyFlows []NoTrackFlow
+	for _, flow := range flows {
+		_, dst, err := net.ParseCIDR(flow.Destination)
+		if err != nil || (dst.IP.To4() == nil) != isIPv6 {
+			continue
+		}
+		familyFlows = append(familyFlows, flow)
+	}
+	return familyFlows
+}
+
 func CalculateCapacity(ar *AddressRange) *big.Int {
 	var (
 		cidr       *net.IPNet
@@ -218,6 +1058,134 @@ func CalculateCapacity(ar *AddressRange) *big.Int {
 	return big.NewInt(0).Sub(utils.Capacity(start, end), big.NewInt(int64(len(ar.ExcludeIPs))))
 }
 
+// CalculateUsableCapacity returns how many addresses of an address range can be
+// allocated automatically, together with how many addresses are held back as reserved
+// IPs. Only distinct addresses inside [start, end] are taken into account, and the
+// gateway is never counted as usable.
+func CalculateUsableCapacity(ar *AddressRange) (usable, reserved *big.Int) {
+	var (
+		cidr       *net.IPNet
+		start, end net.IP
+		err        error
+	)
+
+	if _, cidr, err = net.ParseCIDR(ar.CIDR); err != nil {
+		return big.NewInt(0), big.NewInt(0)
+	}
+
+	if len(ar.Start) > 0 {
+		start = net.ParseIP(ar.Start)
+	}
+	if start == nil {
+		start = utils.NextIP(cidr.IP)
+	}
+
+	if len(ar.End) > 0 {
+		end = net.ParseIP(ar.End)
+	}
+	if end == nil {
+		end = utils.LastIP(cidr)
+	}
+
+	if utils.Cmp(start, end) > 0 {
+		return big.NewInt(0), big.NewInt(0)
+	}
+
+	inRange := func(ipStr string) (string, bool) {
+		ip := net.ParseIP(ipStr)
+		if ip == nil || utils.Cmp(ip, start) < 0 || utils.Cmp(ip, end) > 0 {
+			return "", false
+		}
+		return ip.String(), true
+	}
+
+	unavailable := gset.NewStrSet()
+	for _, excludeIP := range ar.ExcludeIPs {
+		if ip, ok := inRange(excludeIP); ok {
+			unavailable.Add(ip)
+		}
+	}
+	if ip, ok := inRange(ar.Gateway); ok {
+		unavailable.Add(ip)
+	}
+
+	reservedSet := gset.NewStrSet()
+	for _, reservedIP := range GetReservedIPsOfRange(ar) {
+		if ip, ok := inRange(reservedIP); ok && !unavailable.Contains(ip) {
+			reservedSet.Add(ip)
+		}
+	}
+
+	usable = big.NewInt(0).Sub(utils.Capacity(start, end), big.NewInt(int64(unavailable.Size()+reservedSet.Size())))
+	return usable, big.NewInt(int64(reservedSet.Size()))
+}
+
+// GetReservedIPsOfRange returns reserved IPs of an address range together with the addresses of
+// reserved ranges. Addresses of reserved ranges out of [start, end] are skipped, which will never
+// be allocated anyway. Validation limits reserved ranges to MaxReservedRangeAddresses in total, and
+// addresses beyond the limit are not expanded either, in case of ranges admitted before the limit.
+func GetReservedIPsOfRange(ar *AddressRange) []string {
+	if len(ar.ReservedRanges) == 0 {
+		return ar.ReservedIPs
+	}
+
+	_, cidr, err := net.ParseCIDR(ar.CIDR)
+	if err != nil {
+		return ar.ReservedIPs
+	}
+
+	start, end := net.ParseIP(ar.Start), net.ParseIP(ar.End)
+	if start == nil {
+		start = utils.NextIP(cidr.IP)
+	}
+	if end == nil {
+		end = utils.LastIP(cidr)
+	}
+
+	reservedIPs := append([]string{}, ar.ReservedIPs...)
+	expanded := 0
+	for _, rr := range ar.ReservedRanges {
+		rangeStart, rangeEnd := net.ParseIP(rr.Start), net.ParseIP(rr.End)
+		if rangeStart == nil || rangeEnd == nil {
+			continue
+		}
+		if utils.Cmp(rangeStart, start) == -1 {
+			rangeStart = start
+		}
+		if utils.Cmp(rangeEnd, end) == 1 {
+			rangeEnd = end
+		}
+		// skip ranges of mismatched families or out of [start, end]
+		if c := utils.Cmp(rangeStart, rangeEnd); c != -1 && c != 0 {
+			continue
+		}
+		for ip := rangeStart; expanded < MaxReservedRangeAddresses; ip = utils.NextIP(ip) {
+			reservedIPs = append(reservedIPs, ip.String())
+			expanded++
+			if ip.Equal(rangeEnd) {
+				break
+			}
+		}
+	}
+	return reservedIPs
+}
+
+// IsInReservedRanges checks if address is in any reserved range of address range, which is never
+// allocated to pods even if specified
+func IsInReservedRanges(ar *AddressRange, address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+
+	for _, rr := range ar.ReservedRanges {
+		if utils.Cmp(ip, net.ParseIP(rr.Start)) >= 0 && utils.Cmp(ip, net.ParseIP(rr.End)) <= 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func IsAvailable(statistics *Count) bool {
 	if statistics == nil {
 		return false
@@ -284,6 +1252,139 @@ func IsReserved(ipInstance *IPInstance) bool {
 	return len(ipInstance.Spec.Binding.NodeName) == 0
 }
 
+// IsAdoptedIPInstance means the address of ip instance was configured out-of-band before ip instance was created
+func IsAdoptedIPInstance(ipInstance *IPInstance) bool {
+	return ipInstance != nil && utils.ParseBoolOrDefault(ipInstance.Labels[constants.LabelIPAdoption], false)
+}
+
+// ValidateAdoptedIPInstance checks an ip instance created by users for an address configured out-of-band,
+// which must be the same as the one allocated by IPAM from subnet, and reserved for a pod to take over
+func ValidateAdoptedIPInstance(ipInstance *IPInstance, subnet *Subnet, network *Network) error {
+	if ipInstance.Spec.Network != network.Name || subnet.Spec.Network != network.Name {
+		return fmt.Errorf("subnet %s does not belong to network %s", subnet.Name, ipInstance.Spec.Network)
+	}
+
+	binding := &ipInstance.Spec.Binding
+	if len(binding.NodeName) > 0 {
+		return fmt.Errorf("adopted ip instance must not be bound to node, which is bound when taken over by pod")
+	}
+	if len(binding.PodName) == 0 || len(binding.ReferredObject.Name) == 0 {
+		return fmt.Errorf("pod name and referred object of binding must be specified")
+	}
+	// only pods of StatefulSets keep their names after recreation and reuse reserved addresses
+	if binding.ReferredObject.Kind != "StatefulSet" {
+		return fmt.Errorf("referred object of binding must be a StatefulSet, but got %q", binding.ReferredObject.Kind)
+	}
+
+	address := &ipInstance.Spec.Address
+	if _, err := net.ParseMAC(address.MAC); err != nil {
+		return fmt.Errorf("invalid mac %s, which must be the one of out-of-band interface: %v", address.MAC, err)
+	}
+
+	ip, ipNet, err := net.ParseCIDR(address.IP)
+	if err != nil {
+		return fmt.Errorf("invalid address %s, which must be in cidr notation: %v", address.IP, err)
+	}
+	if name := utils.ToDNSFormat(ip); ipInstance.Name != name {
+		return fmt.Errorf("name of ip instance must be %s for address %s", name, address.IP)
+	}
+	if address.Version != subnet.Spec.Range.Version {
+		return fmt.Errorf("version %s of address mismatches version %s of subnet %s", address.Version,
+			subnet.Spec.Range.Version, subnet.Name)
+	}
+
+	if ipNet.String() != subnet.Spec.Range.CIDR {
+		return fmt.Errorf("address %s is not in cidr %s of subnet %s", address.IP, subnet.Spec.Range.CIDR, subnet.Name)
+	}
+	if start := net.ParseIP(subnet.Spec.Range.Start); start != nil && utils.Cmp(ip, start) < 0 {
+		return fmt.Errorf("address %s is before start %s of subnet %s", address.IP, subnet.Spec.Range.Start, subnet.Name)
+	}
+	if end := net.ParseIP(subnet.Spec.Range.End); end != nil && utils.Cmp(ip, end) > 0 {
+		return fmt.Errorf("address %s is after end %s of subnet %s", address.IP, subnet.Spec.Range.End, subnet.Name)
+	}
+	for _, excluded := range append([]string{subnet.Spec.Range.Gateway}, subnet.Spec.Range.ExcludeIPs...) {
+		if ip.Equal(net.ParseIP(excluded)) {
+			return fmt.Errorf("address %s is excluded from subnet %s", address.IP, subnet.Name)
+		}
+	}
+
+	if !net.ParseIP(address.Gateway).Equal(net.ParseIP(subnet.Spec.Range.Gateway)) {
+		return fmt.Errorf("gateway %s of address mismatches gateway %s of subnet %s", address.Gateway,
+			subnet.Spec.Range.Gateway, subnet.Name)
+	}
+
+	expectedNetID := subnet.Spec.NetID
+	if expectedNetID == nil {
+		expectedNetID = network.Spec.NetID
+	}
+	if (address.NetID == nil) != (expectedNetID == nil) || (address.NetID != nil && *address.NetID != *expectedNetID) {
+		return fmt.Errorf("net id of address mismatches the one of subnet %s", subnet.Name)
+	}
+
+	return nil
+}
+
+// ValidateRebindTarget checks a rebind request of ip instance, the target pod must be another pod in the
+// same namespace and a terminating ip instance can not be rebound
+func ValidateRebindTarget(ipInstance *IPInstance, target *RebindTarget) error {
+	if ipInstance.DeletionTimestamp != nil {
+		return fmt.Errorf("ip instance %s is terminating", ipInstance.Name)
+	}
+	if errs := validation.IsDNS1123Subdomain(target.PodName); len(errs) > 0 {
+		return fmt.Errorf("invalid target pod name %q: %s", target.PodName, strings.Join(errs, ", "))
+	}
+	if target.PodName == ipInstance.Spec.Binding.PodName {
+		return fmt.Errorf("ip instance %s is already bound to pod %s", ipInstance.Name, target.PodName)
+	}
+	return nil
+}
+
+// GetIPInstancePhase returns Reserved if ip instance is not bound to any node, otherwise Allocated
+func GetIPInstancePhase(ipInstance *IPInstance) string {
+	if IsReserved(ipInstance) {
+		return IPInstancePhaseReserved
+	}
+	return IPInstancePhaseAllocated
+}
+
+// SyncIPInstanceLabels makes the node, subnet, network and phase labels consistent with spec
+// of ip instance, changed is true if any label is changed
+func SyncIPInstanceLabels(ipInstance *IPInstance) (changed bool) {
+	expected := map[string]string{
+		constants.LabelNode:    ipInstance.Spec.Binding.NodeName,
+		constants.LabelSubnet:  ipInstance.Spec.Subnet,
+		constants.LabelNetwork: ipInstance.Spec.Network,
+		constants.LabelPhase:   GetIPInstancePhase(ipInstance),
+	}
+
+	for key, value := range expected {
+		current, exist := ipInstance.Labels[key]
+		switch {
+		case len(value) == 0 && exist:
+			delete(ipInstance.Labels, key)
+		case len(value) > 0 && current != value:
+			if ipInstance.Labels == nil {
+				ipInstance.Labels = map[string]string{}
+			}
+			ipInstance.Labels[key] = value
+		default:
+			continue
+		}
+		changed = true
+	}
+
+	// ip instances created before the version label are on the latest model as long as they are valid,
+	// the label is the source of the report of model versions
+	if IsValidIPInstance(ipInstance) && ipInstance.Labels[constants.LabelVersion] != IPInstanceLatestVersion {
+		if ipInstance.Labels == nil {
+			ipInstance.Labels = map[string]string{}
+		}
+		ipInstance.Labels[constants.LabelVersion] = IPInstanceLatestVersion
+		changed = true
+	}
+	return changed
+}
+
 func FetchBindingPodName(ipInstance *IPInstance) string {
 	return ipInstance.Spec.Binding.PodName
 }
@@ -301,6 +1402,15 @@ func IsValidIPInstance(ipInstance *IPInstance) bool {
 	return len(ipInstance.Spec.Binding.ReferredObject.Kind) > 0
 }
 
+// GetIPInstanceModelVersion returns the model version of IPInstance, legacy
+// IPInstance without version label will be considered as "unknown"
+func GetIPInstanceModelVersion(ipInstance *IPInstance) string {
+	if ipInstance == nil || len(ipInstance.Labels[constants.LabelVersion]) == 0 {
+		return "unknown"
+	}
+	return ipInstance.Labels[constants.LabelVersion]
+}
+
 func GetIndexFromName(name string) int {
 	nameSlice := strings.Split(name, "-")
 	indexStr := nameSlice[len(nameSlice)-1]
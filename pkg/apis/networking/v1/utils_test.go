@@ -21,8 +21,12 @@ import (
 	"math"
 	"math/big"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/alibaba/hybridnet/pkg/constants"
 )
 
 func TestValidateAddressRange(t *testing.T) {
@@ -154,6 +158,43 @@ func TestValidateAddressRange(t *testing.T) {
 			},
 			fmt.Errorf("reserved ip 192.168.9.100 is not in CIDR 192.168.8.0/24"),
 		},
+		{
+			"reserved range is not in range",
+			&AddressRange{
+				Version: IPv4,
+				CIDR:    "192.168.8.0/24",
+				Gateway: "192.168.8.254",
+				ReservedRanges: []ReservedRange{
+					{Start: "192.168.8.200", End: "192.168.9.10"},
+				},
+			},
+			fmt.Errorf("reserved range 192.168.8.200-192.168.9.10 is not in CIDR 192.168.8.0/24"),
+		},
+		{
+			"reserved range is reversed",
+			&AddressRange{
+				Version: IPv4,
+				CIDR:    "192.168.8.0/24",
+				Gateway: "192.168.8.254",
+				ReservedRanges: []ReservedRange{
+					{Start: "192.168.8.20", End: "192.168.8.10"},
+				},
+			},
+			fmt.Errorf("start of reserved range 192.168.8.20-192.168.8.10 is after end"),
+		},
+		{
+			"reserved ranges are too wide",
+			&AddressRange{
+				Version: IPv6,
+				CIDR:    "fe80::/64",
+				Gateway: "fe80::1",
+				ReservedRanges: []ReservedRange{
+					{Start: "fe80::100", End: "fe80::1:ff"},
+					{Start: "fe80::2:0", End: "fe80::ffff:ffff:ffff:ffff"},
+				},
+			},
+			fmt.Errorf("reserved ranges have 18446744073709486080 addresses, more than the limit 65536"),
+		},
 		{
 			"wrong excluded ip",
 			&AddressRange{
@@ -296,6 +337,139 @@ func TestCalculateCapacity(t *testing.T) {
 	}
 }
 
+func TestCalculateUsableCapacity(t *testing.T) {
+	tests := []struct {
+		name             string
+		addressRange     *AddressRange
+		expectedUsable   int64
+		expectedReserved int64
+	}{
+		{
+			"invalid cidr",
+			&AddressRange{
+				CIDR: "fake",
+			},
+			0,
+			0,
+		},
+		{
+			"only cidr with gateway",
+			&AddressRange{
+				CIDR:    "192.168.0.0/24",
+				Gateway: "192.168.0.1",
+			},
+			253,
+			0,
+		},
+		{
+			"gateway out of range",
+			&AddressRange{
+				Start:   "192.168.0.100",
+				End:     "192.168.0.200",
+				CIDR:    "192.168.0.0/24",
+				Gateway: "192.168.0.1",
+			},
+			101,
+			0,
+		},
+		{
+			"duplicated and out-of-range excluded ips",
+			&AddressRange{
+				Start:   "192.168.0.100",
+				End:     "192.168.0.200",
+				CIDR:    "192.168.0.0/24",
+				Gateway: "192.168.0.1",
+				ExcludeIPs: []string{
+					"192.168.0.105",
+					"192.168.0.105",
+					"192.168.0.50",
+				},
+			},
+			100,
+			0,
+		},
+		{
+			"all set",
+			&AddressRange{
+				Start:   "192.168.0.100",
+				End:     "192.168.0.200",
+				CIDR:    "192.168.0.0/24",
+				Gateway: "192.168.0.100",
+				ExcludeIPs: []string{
+					"192.168.0.105",
+					"192.168.0.107",
+				},
+				ReservedIPs: []string{
+					"192.168.0.100",
+					"192.168.0.105",
+					"192.168.0.110",
+					"192.168.0.111",
+				},
+			},
+			96,
+			2,
+		},
+		{
+			"start after end",
+			&AddressRange{
+				Start: "192.168.0.200",
+				End:   "192.168.0.100",
+				CIDR:  "192.168.0.0/24",
+			},
+			0,
+			0,
+		},
+		{
+			"ipv6",
+			&AddressRange{
+				Version: IPv6,
+				Start:   "fe80::10",
+				End:     "fe80::1f",
+				CIDR:    "fe80::/64",
+				Gateway: "fe80::10",
+				ReservedIPs: []string{
+					"fe80:0::11",
+				},
+			},
+			14,
+			1,
+		},
+		{
+			"reserved ranges",
+			&AddressRange{
+				Start:   "192.168.0.100",
+				End:     "192.168.0.200",
+				CIDR:    "192.168.0.0/24",
+				Gateway: "192.168.0.1",
+				ExcludeIPs: []string{
+					"192.168.0.195",
+				},
+				ReservedIPs: []string{
+					"192.168.0.110",
+				},
+				ReservedRanges: []ReservedRange{
+					{Start: "192.168.0.50", End: "192.168.0.104", Consumer: "load balancers"},
+					{Start: "192.168.0.110", End: "192.168.0.119"},
+					{Start: "192.168.0.190", End: "192.168.0.250"},
+				},
+			},
+			75,
+			25,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			usable, reserved := CalculateUsableCapacity(test.addressRange)
+			if usable.Cmp(big.NewInt(test.expectedUsable)) != 0 {
+				t.Fatalf("test %s fails, expected usable %d but calculated %d", test.name, test.expectedUsable, usable)
+			}
+			if reserved.Cmp(big.NewInt(test.expectedReserved)) != 0 {
+				t.Fatalf("test %s fails, expected reserved %d but calculated %d", test.name, test.expectedReserved, reserved)
+			}
+		})
+	}
+}
+
 func TestGetNetworkType(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -414,6 +588,1529 @@ func TestIsIPv6IPInstance(t *testing.T) {
 	}
 }
 
+func TestIsCordonedAndDrainedSubnet(t *testing.T) {
+	var (
+		trueValue  = true
+		falseValue = false
+	)
+
+	tests := []struct {
+		name          string
+		subnet        *Subnet
+		expectCordon  bool
+		expectDrained bool
+	}{
+		{
+			name:   "nil",
+			subnet: nil,
+		},
+		{
+			name:   "empty config",
+			subnet: &Subnet{},
+		},
+		{
+			name: "cordoned",
+			subnet: &Subnet{
+				Spec: SubnetSpec{
+					Config: &SubnetConfig{
+						Cordon: &trueValue,
+					},
+				},
+			},
+			expectCordon: true,
+		},
+		{
+			name: "drained without cordon",
+			subnet: &Subnet{
+				Spec: SubnetSpec{
+					Config: &SubnetConfig{
+						Cordon: &falseValue,
+						Drain:  &trueValue,
+					},
+				},
+			},
+			expectCordon:  true,
+			expectDrained: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := IsCordonedSubnet(test.subnet); result != test.expectCordon {
+				t.Errorf("test %s fail, expect cordoned %t but got %t", test.name, test.expectCordon, result)
+			}
+			if result := IsDrainedSubnet(test.subnet); result != test.expectDrained {
+				t.Errorf("test %s fail, expect drained %t but got %t", test.name, test.expectDrained, result)
+			}
+		})
+	}
+}
+
+func TestIsCordonedNetwork(t *testing.T) {
+	var (
+		trueValue  = true
+		falseValue = false
+	)
+
+	tests := []struct {
+		name    string
+		network *Network
+		expect  bool
+	}{
+		{
+			name:    "nil",
+			network: nil,
+		},
+		{
+			name:    "empty config",
+			network: &Network{},
+		},
+		{
+			name: "not cordoned",
+			network: &Network{
+				Spec: NetworkSpec{
+					Config: &NetworkConfig{
+						Cordon: &falseValue,
+					},
+				},
+			},
+		},
+		{
+			name: "cordoned",
+			network: &Network{
+				Spec: NetworkSpec{
+					Config: &NetworkConfig{
+						Cordon: &trueValue,
+					},
+				},
+			},
+			expect: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := IsCordonedNetwork(test.network); result != test.expect {
+				t.Errorf("test %s fail, expect cordoned %t but got %t", test.name, test.expect, result)
+			}
+		})
+	}
+}
+
+func TestIsNetworkVisibleToNamespace(t *testing.T) {
+	tests := []struct {
+		name            string
+		network         *Network
+		namespaceLabels map[string]string
+		expect          bool
+		expectErr       bool
+	}{
+		{
+			name:    "nil",
+			network: nil,
+		},
+		{
+			name:    "no selector",
+			network: &Network{},
+			expect:  true,
+		},
+		{
+			name: "namespace selected",
+			network: &Network{
+				Spec: NetworkSpec{
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"team": "a"},
+					},
+				},
+			},
+			namespaceLabels: map[string]string{"team": "a", "env": "prod"},
+			expect:          true,
+		},
+		{
+			name: "namespace not selected",
+			network: &Network{
+				Spec: NetworkSpec{
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"team": "a"},
+					},
+				},
+			},
+			namespaceLabels: map[string]string{"team": "b"},
+		},
+		{
+			name: "invalid selector",
+			network: &Network{
+				Spec: NetworkSpec{
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{
+							{Key: "team", Operator: "Unknown"},
+						},
+					},
+				},
+			},
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := IsNetworkVisibleToNamespace(test.network, test.namespaceLabels)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("test %s fail, expect error %t but got %v", test.name, test.expectErr, err)
+			}
+			if result != test.expect {
+				t.Errorf("test %s fail, expect visible %t but got %t", test.name, test.expect, result)
+			}
+		})
+	}
+}
+
+func TestGetSubnetOfAddress(t *testing.T) {
+	subnets := []Subnet{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "subnet1"},
+			Spec:       SubnetSpec{Range: AddressRange{CIDR: "192.168.0.0/24"}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "subnet2"},
+			Spec:       SubnetSpec{Range: AddressRange{CIDR: "fd00::/120"}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		address string
+		expect  string
+	}{
+		{
+			name:    "ipv4 address",
+			address: "192.168.0.10",
+			expect:  "subnet1",
+		},
+		{
+			name:    "ipv6 address",
+			address: "fd00::10",
+			expect:  "subnet2",
+		},
+		{
+			name:    "address out of subnets",
+			address: "192.168.1.10",
+		},
+		{
+			name:    "invalid address",
+			address: "192.168.0",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var result string
+			if subnet := GetSubnetOfAddress(subnets, test.address); subnet != nil {
+				result = subnet.Name
+			}
+			if result != test.expect {
+				t.Errorf("test %s fail, expect subnet %q but got %q", test.name, test.expect, result)
+			}
+		})
+	}
+}
+
+func TestIsInReservedRanges(t *testing.T) {
+	ar := &AddressRange{
+		CIDR: "192.168.0.0/24",
+		ReservedRanges: []ReservedRange{
+			{Start: "192.168.0.50", End: "192.168.0.59"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		address string
+		expect  bool
+	}{
+		{
+			name:    "start of range",
+			address: "192.168.0.50",
+			expect:  true,
+		},
+		{
+			name:    "end of range",
+			address: "192.168.0.59",
+			expect:  true,
+		},
+		{
+			name:    "address out of range",
+			address: "192.168.0.60",
+		},
+		{
+			name:    "address of another family",
+			address: "fd00::50",
+		},
+		{
+			name:    "invalid address",
+			address: "192.168.0",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := IsInReservedRanges(ar, test.address); result != test.expect {
+				t.Errorf("test %s fail, expect %t but got %t", test.name, test.expect, result)
+			}
+		})
+	}
+}
+
+func TestIsTenantNetwork(t *testing.T) {
+	tests := []struct {
+		name         string
+		network      *Network
+		expectTenant string
+	}{
+		{
+			name:    "nil",
+			network: nil,
+		},
+		{
+			name:    "no tenant",
+			network: &Network{},
+		},
+		{
+			name: "tenant",
+			network: &Network{
+				Spec: NetworkSpec{
+					Tenant: "a",
+				},
+			},
+			expectTenant: "a",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if tenant := GetNetworkTenant(test.network); tenant != test.expectTenant {
+				t.Errorf("test %s fail, expect tenant %q but got %q", test.name, test.expectTenant, tenant)
+			}
+			if isTenant := IsTenantNetwork(test.network); isTenant != (len(test.expectTenant) > 0) {
+				t.Errorf("test %s fail, expect tenant network %t but got %t", test.name, len(test.expectTenant) > 0, isTenant)
+			}
+		})
+	}
+}
+
+func TestAreNamespaceSelectorsDisjoint(t *testing.T) {
+	tests := []struct {
+		name      string
+		a         *metav1.LabelSelector
+		b         *metav1.LabelSelector
+		expect    bool
+		expectErr bool
+	}{
+		{
+			name: "nil selects all",
+			a:    nil,
+			b: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"tenant": "a"},
+			},
+		},
+		{
+			name: "different values",
+			a: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"tenant": "a"},
+			},
+			b: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"tenant": "b", "env": "prod"},
+			},
+			expect: true,
+		},
+		{
+			name: "different keys",
+			a: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"tenant": "a"},
+			},
+			b: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"team": "b"},
+			},
+		},
+		{
+			name: "overlapped values",
+			a: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "tenant", Operator: metav1.LabelSelectorOpIn, Values: []string{"a", "b"}},
+				},
+			},
+			b: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"tenant": "b"},
+			},
+		},
+		{
+			name: "excluded values",
+			a: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "tenant", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"a", "b"}},
+				},
+			},
+			b: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "tenant", Operator: metav1.LabelSelectorOpIn, Values: []string{"a", "b"}},
+				},
+			},
+			expect: true,
+		},
+		{
+			name: "exists and does not exist",
+			a: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "tenant", Operator: metav1.LabelSelectorOpExists},
+				},
+			},
+			b: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "tenant", Operator: metav1.LabelSelectorOpDoesNotExist},
+				},
+			},
+			expect: true,
+		},
+		{
+			name: "invalid selector",
+			a: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "tenant", Operator: "Unknown"},
+				},
+			},
+			b:         &metav1.LabelSelector{},
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := AreNamespaceSelectorsDisjoint(test.a, test.b)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("test %s fail, expect error %t but got %v", test.name, test.expectErr, err)
+			}
+			if result != test.expect {
+				t.Errorf("test %s fail, expect disjoint %t but got %t", test.name, test.expect, result)
+			}
+		})
+	}
+}
+
+func TestGetNeighborRateLimit(t *testing.T) {
+	var (
+		zero  int32 = 0
+		burst int32 = 50
+	)
+
+	tests := []struct {
+		name                   string
+		network                *Network
+		expectPacketsPerSecond uint32
+		expectBurst            uint32
+		expectLimited          bool
+	}{
+		{
+			name:    "nil",
+			network: nil,
+		},
+		{
+			name:    "no rate limit",
+			network: &Network{Spec: NetworkSpec{Config: &NetworkConfig{}}},
+		},
+		{
+			name: "default burst",
+			network: &Network{Spec: NetworkSpec{Config: &NetworkConfig{
+				NeighborRateLimit: &NeighborRateLimit{PacketsPerSecond: 10, Burst: &zero},
+			}}},
+			expectPacketsPerSecond: 10,
+			expectBurst:            10,
+			expectLimited:          true,
+		},
+		{
+			name: "specified burst",
+			network: &Network{Spec: NetworkSpec{Config: &NetworkConfig{
+				NeighborRateLimit: &NeighborRateLimit{PacketsPerSecond: 10, Burst: &burst},
+			}}},
+			expectPacketsPerSecond: 10,
+			expectBurst:            50,
+			expectLimited:          true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			packetsPerSecond, burst, limited := GetNeighborRateLimit(test.network)
+			if packetsPerSecond != test.expectPacketsPerSecond || burst != test.expectBurst || limited != test.expectLimited {
+				t.Errorf("test %s fail, expect (%d, %d, %t) but got (%d, %d, %t)", test.name,
+					test.expectPacketsPerSecond, test.expectBurst, test.expectLimited, packetsPerSecond, burst, limited)
+			}
+		})
+	}
+}
+
+func TestGetNetworkRPFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		network *Network
+		expect  RPFilterMode
+	}{
+		{
+			name:    "nil",
+			network: nil,
+			expect:  RPFilterDisabled,
+		},
+		{
+			name:    "not specified",
+			network: &Network{Spec: NetworkSpec{Config: &NetworkConfig{}}},
+			expect:  RPFilterDisabled,
+		},
+		{
+			name:    "loose",
+			network: &Network{Spec: NetworkSpec{Config: &NetworkConfig{RPFilter: RPFilterLoose}}},
+			expect:  RPFilterLoose,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if mode := GetNetworkRPFilter(test.network); mode != test.expect {
+				t.Errorf("test %s fail, expect %s but got %s", test.name, test.expect, mode)
+			}
+		})
+	}
+}
+
+func TestGetNetworkRouteOptions(t *testing.T) {
+	int32p := func(i int32) *int32 { return &i }
+	tests := []struct {
+		name         string
+		network      *Network
+		expectMetric int
+		expectRealm  int
+	}{
+		{
+			name:    "nil",
+			network: nil,
+		},
+		{
+			name:    "not specified",
+			network: &Network{Spec: NetworkSpec{Config: &NetworkConfig{Routes: &RouteOptions{}}}},
+		},
+		{
+			name: "metric and realm",
+			network: &Network{Spec: NetworkSpec{Config: &NetworkConfig{Routes: &RouteOptions{
+				Metric: int32p(50),
+				Realm:  int32p(10),
+			}}}},
+			expectMetric: 50,
+			expectRealm:  10,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			metric, realm := GetNetworkRouteOptions(test.network)
+			if metric != test.expectMetric || realm != test.expectRealm {
+				t.Errorf("test %s fail, expect %d/%d but got %d/%d", test.name, test.expectMetric, test.expectRealm, metric, realm)
+			}
+		})
+	}
+}
+
+func TestGetSubnetAddressAnnouncement(t *testing.T) {
+	var retries, intervalMilliseconds int32 = 5, 200
+	tests := []struct {
+		name           string
+		subnetSpec     *SubnetSpec
+		expectRetries  int
+		expectInterval time.Duration
+		expectAnnounce bool
+	}{
+		{
+			name:       "nil",
+			subnetSpec: nil,
+		},
+		{
+			name:       "not specified",
+			subnetSpec: &SubnetSpec{Config: &SubnetConfig{}},
+		},
+		{
+			name:           "default",
+			subnetSpec:     &SubnetSpec{Config: &SubnetConfig{AddressAnnouncement: &AddressAnnouncement{}}},
+			expectRetries:  3,
+			expectInterval: time.Second,
+			expectAnnounce: true,
+		},
+		{
+			name: "specified",
+			subnetSpec: &SubnetSpec{Config: &SubnetConfig{AddressAnnouncement: &AddressAnnouncement{
+				Retries:              &retries,
+				IntervalMilliseconds: &intervalMilliseconds,
+			}}},
+			expectRetries:  5,
+			expectInterval: 200 * time.Millisecond,
+			expectAnnounce: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			retries, interval, announced := GetSubnetAddressAnnouncement(test.subnetSpec)
+			if retries != test.expectRetries || interval != test.expectInterval || announced != test.expectAnnounce {
+				t.Errorf("test %s fail, expect %v %v %v but got %v %v %v", test.name,
+					test.expectRetries, test.expectInterval, test.expectAnnounce, retries, interval, announced)
+			}
+		})
+	}
+}
+
+func TestSelectNodeNetworkConfig(t *testing.T) {
+	poolSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "a"}}
+	configs := []NodeNetworkConfig{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pool-a"},
+			Spec:       NodeNetworkConfigSpec{NodeSelector: poolSelector, VlanInterfaces: "eth1"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pool-a-high"},
+			Spec:       NodeNetworkConfigSpec{NodeSelector: poolSelector, Priority: 10, VlanInterfaces: "eth2"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pool-a-another"},
+			Spec:       NodeNetworkConfigSpec{NodeSelector: poolSelector, Priority: 10, VlanInterfaces: "eth3"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+			Spec:       NodeNetworkConfigSpec{VlanInterfaces: "eth4"},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		nodeName   string
+		nodeLabels map[string]string
+		expect     string
+	}{
+		{
+			name:       "same name",
+			nodeName:   "node1",
+			nodeLabels: map[string]string{"pool": "a"},
+			expect:     "node1",
+		},
+		{
+			name:       "largest priority",
+			nodeName:   "node2",
+			nodeLabels: map[string]string{"pool": "a"},
+			expect:     "pool-a-another",
+		},
+		{
+			name:       "not selected",
+			nodeName:   "node3",
+			nodeLabels: map[string]string{"pool": "b"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selected, err := SelectNodeNetworkConfig(configs, test.nodeName, test.nodeLabels)
+			if err != nil {
+				t.Fatalf("test %s fail, unexpected error %v", test.name, err)
+			}
+
+			var name string
+			if selected != nil {
+				name = selected.Name
+			}
+			if name != test.expect {
+				t.Errorf("test %s fail, expect %q but got %q", test.name, test.expect, name)
+			}
+		})
+	}
+}
+
+func TestGetEffectiveNodeNetworkConfigSpec(t *testing.T) {
+	rollout := &NodeNetworkConfigRollout{
+		CanaryNodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"canary": "true"}},
+	}
+	promotedSpec := &NodeNetworkConfigSpec{VlanInterfaces: "eth1"}
+	applied := map[string]string{
+		constants.AnnotationNodeNetworkConfigRevision:    "pool-a/1",
+		constants.AnnotationNodeNetworkConfigAppliedSpec: `{"vlanInterfaces":"eth0"}`,
+	}
+
+	tests := []struct {
+		name             string
+		config           *NodeNetworkConfig
+		nodeLabels       map[string]string
+		nodeAnnotations  map[string]string
+		expectInterfaces string
+		expectRevision   string
+		expectNil        bool
+	}{
+		{
+			name: "without rollout",
+			config: &NodeNetworkConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "pool-a", Generation: 2},
+				Spec:       NodeNetworkConfigSpec{VlanInterfaces: "eth2"},
+			},
+			expectInterfaces: "eth2",
+			expectRevision:   "pool-a/2",
+		},
+		{
+			name: "canary node",
+			config: &NodeNetworkConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "pool-a", Generation: 3},
+				Spec:       NodeNetworkConfigSpec{VlanInterfaces: "eth2", Rollout: rollout},
+				Status: NodeNetworkConfigStatus{Phase: NodeNetworkConfigRolloutCanary, ObservedGeneration: 3,
+					PromotedGeneration: 2, PromotedSpec: promotedSpec},
+			},
+			nodeLabels:       map[string]string{"canary": "true"},
+			expectInterfaces: "eth2",
+			expectRevision:   "pool-a/3",
+		},
+		{
+			name: "canary node rolls back failed generation",
+			config: &NodeNetworkConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "pool-a", Generation: 3},
+				Spec:       NodeNetworkConfigSpec{VlanInterfaces: "eth2", Rollout: rollout},
+				Status: NodeNetworkConfigStatus{Phase: NodeNetworkConfigRolloutFailed, ObservedGeneration: 3,
+					PromotedGeneration: 2, PromotedSpec: promotedSpec},
+			},
+			nodeLabels:       map[string]string{"canary": "true"},
+			expectInterfaces: "eth1",
+			expectRevision:   "pool-a/2",
+		},
+		{
+			name: "canary node rolls back failed generation before any promotion",
+			config: &NodeNetworkConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "pool-a", Generation: 1},
+				Spec:       NodeNetworkConfigSpec{VlanInterfaces: "eth2", Rollout: rollout},
+				Status:     NodeNetworkConfigStatus{Phase: NodeNetworkConfigRolloutFailed, ObservedGeneration: 1},
+			},
+			nodeLabels: map[string]string{"canary": "true"},
+			expectNil:  true,
+		},
+		{
+			name: "canary node applies generation after the failed one",
+			config: &NodeNetworkConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "pool-a", Generation: 4},
+				Spec:       NodeNetworkConfigSpec{VlanInterfaces: "eth3", Rollout: rollout},
+				Status: NodeNetworkConfigStatus{Phase: NodeNetworkConfigRolloutFailed, ObservedGeneration: 3,
+					PromotedGeneration: 2, PromotedSpec: promotedSpec},
+			},
+			nodeLabels:       map[string]string{"canary": "true"},
+			expectInterfaces: "eth3",
+			expectRevision:   "pool-a/4",
+		},
+		{
+			name: "other node of promoted config",
+			config: &NodeNetworkConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "pool-a", Generation: 2},
+				Spec:       NodeNetworkConfigSpec{VlanInterfaces: "eth1", Rollout: rollout},
+				Status: NodeNetworkConfigStatus{Phase: NodeNetworkConfigRolloutPromoted, ObservedGeneration: 2,
+					PromotedGeneration: 2, PromotedSpec: promotedSpec},
+			},
+			expectInterfaces: "eth1",
+			expectRevision:   "pool-a/2",
+		},
+		{
+			name: "other node released",
+			config: &NodeNetworkConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "pool-a", Generation: 2},
+				Spec:       NodeNetworkConfigSpec{VlanInterfaces: "eth1", Rollout: rollout},
+				Status: NodeNetworkConfigStatus{Phase: NodeNetworkConfigRolloutPromoting, ObservedGeneration: 2,
+					PromotedGeneration: 2, PromotedSpec: promotedSpec},
+			},
+			nodeAnnotations: map[string]string{
+				constants.AnnotationNodeNetworkConfigRevision:         "pool-a/1",
+				constants.AnnotationNodeNetworkConfigAppliedSpec:      `{"vlanInterfaces":"eth0"}`,
+				constants.AnnotationNodeNetworkConfigReleasedRevision: "pool-a/2",
+			},
+			expectInterfaces: "eth1",
+			expectRevision:   "pool-a/2",
+		},
+		{
+			name: "other node keeps applied spec before released",
+			config: &NodeNetworkConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "pool-a", Generation: 2},
+				Spec:       NodeNetworkConfigSpec{VlanInterfaces: "eth1", Rollout: rollout},
+				Status: NodeNetworkConfigStatus{Phase: NodeNetworkConfigRolloutPromoting, ObservedGeneration: 2,
+					PromotedGeneration: 2, PromotedSpec: promotedSpec},
+			},
+			nodeAnnotations:  applied,
+			expectInterfaces: "eth0",
+			expectRevision:   "pool-a/1",
+		},
+		{
+			name: "other node keeps applied spec before promoted",
+			config: &NodeNetworkConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "pool-a", Generation: 1},
+				Spec:       NodeNetworkConfigSpec{VlanInterfaces: "eth2", Rollout: rollout},
+			},
+			nodeAnnotations:  applied,
+			expectInterfaces: "eth0",
+			expectRevision:   "pool-a/1",
+		},
+		{
+			name: "other node without applied spec before promoted",
+			config: &NodeNetworkConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "pool-a", Generation: 1},
+				Spec:       NodeNetworkConfigSpec{VlanInterfaces: "eth2", Rollout: rollout},
+			},
+			expectNil: true,
+		},
+	}
+	for _, test := range tests {
+		spec, revision, err := GetEffectiveNodeNetworkConfigSpec(test.config, test.nodeLabels, test.nodeAnnotations)
+		if err != nil {
+			t.Fatalf("test %s fail, unexpected error %v", test.name, err)
+		}
+		if test.expectNil {
+			if spec != nil || len(revision) != 0 {
+				t.Errorf("test %s fail, expect nil spec but got %v of revision %q", test.name, spec, revision)
+			}
+			continue
+		}
+		if spec == nil || spec.VlanInterfaces != test.expectInterfaces || revision != test.expectRevision {
+			t.Errorf("test %s fail, expect %s of revision %s but got %v of revision %s",
+				test.name, test.expectInterfaces, test.expectRevision, spec, revision)
+		}
+	}
+}
+
+func TestSyncIPInstanceLabels(t *testing.T) {
+	tests := []struct {
+		name          string
+		ipInstance    *IPInstance
+		expectChanged bool
+		expectLabels  map[string]string
+	}{
+		{
+			name: "fill labels of allocated",
+			ipInstance: &IPInstance{
+				Spec: IPInstanceSpec{
+					Network: "network1",
+					Subnet:  "subnet1",
+					Binding: Binding{NodeName: "node1"},
+				},
+			},
+			expectChanged: true,
+			expectLabels: map[string]string{
+				constants.LabelNode:    "node1",
+				constants.LabelSubnet:  "subnet1",
+				constants.LabelNetwork: "network1",
+				constants.LabelPhase:   IPInstancePhaseAllocated,
+			},
+		},
+		{
+			name: "remove node label of reserved",
+			ipInstance: &IPInstance{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						constants.LabelNode:    "node1",
+						constants.LabelSubnet:  "subnet1",
+						constants.LabelNetwork: "network1",
+						constants.LabelPhase:   IPInstancePhaseAllocated,
+						constants.LabelPod:     "pod1",
+					},
+				},
+				Spec: IPInstanceSpec{
+					Network: "network1",
+					Subnet:  "subnet1",
+				},
+			},
+			expectChanged: true,
+			expectLabels: map[string]string{
+				constants.LabelSubnet:  "subnet1",
+				constants.LabelNetwork: "network1",
+				constants.LabelPhase:   IPInstancePhaseReserved,
+				constants.LabelPod:     "pod1",
+			},
+		},
+		{
+			name: "upgrade model version of valid",
+			ipInstance: &IPInstance{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						constants.LabelSubnet:  "subnet1",
+						constants.LabelNetwork: "network1",
+						constants.LabelPhase:   IPInstancePhaseReserved,
+						constants.LabelVersion: "v1.1",
+					},
+				},
+				Spec: IPInstanceSpec{
+					Network: "network1",
+					Subnet:  "subnet1",
+					Binding: Binding{ReferredObject: ObjectMeta{Kind: "StatefulSet", Name: "sts"}},
+				},
+			},
+			expectChanged: true,
+			expectLabels: map[string]string{
+				constants.LabelSubnet:  "subnet1",
+				constants.LabelNetwork: "network1",
+				constants.LabelPhase:   IPInstancePhaseReserved,
+				constants.LabelVersion: IPInstanceLatestVersion,
+			},
+		},
+		{
+			name: "in sync",
+			ipInstance: &IPInstance{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						constants.LabelSubnet:  "subnet1",
+						constants.LabelNetwork: "network1",
+						constants.LabelPhase:   IPInstancePhaseReserved,
+					},
+				},
+				Spec: IPInstanceSpec{
+					Network: "network1",
+					Subnet:  "subnet1",
+				},
+			},
+			expectLabels: map[string]string{
+				constants.LabelSubnet:  "subnet1",
+				constants.LabelNetwork: "network1",
+				constants.LabelPhase:   IPInstancePhaseReserved,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changed := SyncIPInstanceLabels(test.ipInstance)
+			assert.Equal(t, test.expectChanged, changed)
+			assert.Equal(t, test.expectLabels, test.ipInstance.Labels)
+		})
+	}
+}
+
+func TestValidatePodNetworkClaimSpec(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      *PodNetworkClaimSpec
+		expectErr bool
+	}{
+		{
+			name: "valid",
+			spec: &PodNetworkClaimSpec{
+				Network:     "network1",
+				Subnets:     []string{"subnet-v4", "subnet-v6"},
+				NetworkType: NetworkTypeUnderlay,
+				IPFamily:    IPFamilyDualStack,
+				IPPool:      []string{"192.168.0.10/fd00::10"},
+				MACPool:     []string{"aa:bb:cc:dd:ee:01"},
+			},
+		},
+		{
+			name:      "too many subnets",
+			spec:      &PodNetworkClaimSpec{Subnets: []string{"a", "b", "c"}},
+			expectErr: true,
+		},
+		{
+			name:      "subnet name with slash",
+			spec:      &PodNetworkClaimSpec{Subnets: []string{"a/b"}},
+			expectErr: true,
+		},
+		{
+			name:      "unrecognized ip family",
+			spec:      &PodNetworkClaimSpec{IPFamily: "ipv4"},
+			expectErr: true,
+		},
+		{
+			name:      "invalid ip",
+			spec:      &PodNetworkClaimSpec{IPPool: []string{"fd00::zz"}},
+			expectErr: true,
+		},
+		{
+			name:      "empty ip of dual stack",
+			spec:      &PodNetworkClaimSpec{IPPool: []string{"192.168.0.10/"}},
+			expectErr: true,
+		},
+		{
+			name:      "invalid mac",
+			spec:      &PodNetworkClaimSpec{MACPool: []string{"aa:bb"}},
+			expectErr: true,
+		},
+		{
+			name:      "invalid static route",
+			spec:      &PodNetworkClaimSpec{StaticRoutes: []StaticRoute{{Destination: "10.0.0.0"}}},
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidatePodNetworkClaimSpec(test.spec)
+			if test.expectErr != (err != nil) {
+				t.Errorf("test %s fail, expect error %v but got %v", test.name, test.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateChaosRuleSpec(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      *ChaosRuleSpec
+		expectErr bool
+	}{
+		{
+			name: "valid drop",
+			spec: &ChaosRuleSpec{
+				Action: ChaosActionDrop,
+				Drop:   &ChaosDrop{Destination: "10.0.0.0/8", Percent: 30},
+			},
+		},
+		{
+			name: "valid delay",
+			spec: &ChaosRuleSpec{
+				Action: ChaosActionDelay,
+				Delay:  &ChaosDelay{LatencyMilliseconds: 100, JitterMilliseconds: 10},
+			},
+		},
+		{
+			name: "valid blackhole vtep",
+			spec: &ChaosRuleSpec{
+				Action:        ChaosActionBlackholeVTEP,
+				BlackholeVTEP: &ChaosBlackholeVTEP{VTEPIP: "fd00::10"},
+			},
+		},
+		{
+			name:      "unrecognized action",
+			spec:      &ChaosRuleSpec{Action: "Corrupt"},
+			expectErr: true,
+		},
+		{
+			name:      "missing field of action",
+			spec:      &ChaosRuleSpec{Action: ChaosActionDrop},
+			expectErr: true,
+		},
+		{
+			name: "field of another action",
+			spec: &ChaosRuleSpec{
+				Action: ChaosActionDelay,
+				Delay:  &ChaosDelay{LatencyMilliseconds: 100},
+				Drop:   &ChaosDrop{Destination: "10.0.0.0/8", Percent: 30},
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid drop destination",
+			spec: &ChaosRuleSpec{
+				Action: ChaosActionDrop,
+				Drop:   &ChaosDrop{Destination: "10.0.0.1", Percent: 30},
+			},
+			expectErr: true,
+		},
+		{
+			name: "drop percent out of range",
+			spec: &ChaosRuleSpec{
+				Action: ChaosActionDrop,
+				Drop:   &ChaosDrop{Destination: "10.0.0.0/8", Percent: 101},
+			},
+			expectErr: true,
+		},
+		{
+			name: "both node name and vtep ip",
+			spec: &ChaosRuleSpec{
+				Action:        ChaosActionBlackholeVTEP,
+				BlackholeVTEP: &ChaosBlackholeVTEP{NodeName: "node1", VTEPIP: "192.168.0.10"},
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid vtep ip",
+			spec: &ChaosRuleSpec{
+				Action:        ChaosActionBlackholeVTEP,
+				BlackholeVTEP: &ChaosBlackholeVTEP{VTEPIP: "192.168.0"},
+			},
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateChaosRuleSpec(test.spec)
+			if test.expectErr != (err != nil) {
+				t.Errorf("test %s fail, expect error %v but got %v", test.name, test.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateAdoptedIPInstance(t *testing.T) {
+	var netID int32 = 100
+	network := &Network{
+		ObjectMeta: metav1.ObjectMeta{Name: "network1"},
+		Spec:       NetworkSpec{NetID: &netID},
+	}
+	subnet := &Subnet{
+		ObjectMeta: metav1.ObjectMeta{Name: "subnet1"},
+		Spec: SubnetSpec{
+			Network: "network1",
+			Range: AddressRange{
+				Version:    IPv4,
+				CIDR:       "192.168.0.0/24",
+				Start:      "192.168.0.10",
+				Gateway:    "192.168.0.1",
+				ExcludeIPs: []string{"192.168.0.20"},
+			},
+		},
+	}
+	newIPInstance := func(mutate func(*IPInstance)) *IPInstance {
+		ipInstance := &IPInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "192-168-0-100"},
+			Spec: IPInstanceSpec{
+				Network: "network1",
+				Subnet:  "subnet1",
+				Address: Address{
+					Version: IPv4,
+					IP:      "192.168.0.100/24",
+					Gateway: "192.168.0.1",
+					NetID:   &netID,
+					MAC:     "0a:1b:2c:3d:4e:5f",
+				},
+				Binding: Binding{
+					ReferredObject: ObjectMeta{Kind: "StatefulSet", Name: "vm"},
+					PodName:        "vm-0",
+				},
+			},
+		}
+		if mutate != nil {
+			mutate(ipInstance)
+		}
+		return ipInstance
+	}
+
+	tests := []struct {
+		name       string
+		ipInstance *IPInstance
+		expectErr  bool
+	}{
+		{
+			name:       "valid",
+			ipInstance: newIPInstance(nil),
+		},
+		{
+			name: "bound to node",
+			ipInstance: newIPInstance(func(ipInstance *IPInstance) {
+				ipInstance.Spec.Binding.NodeName = "node1"
+			}),
+			expectErr: true,
+		},
+		{
+			name: "no pod name",
+			ipInstance: newIPInstance(func(ipInstance *IPInstance) {
+				ipInstance.Spec.Binding.PodName = ""
+			}),
+			expectErr: true,
+		},
+		{
+			name: "referred object is not stateful set",
+			ipInstance: newIPInstance(func(ipInstance *IPInstance) {
+				ipInstance.Spec.Binding.ReferredObject.Kind = "ReplicaSet"
+			}),
+			expectErr: true,
+		},
+		{
+			name: "no referred object kind",
+			ipInstance: newIPInstance(func(ipInstance *IPInstance) {
+				ipInstance.Spec.Binding.ReferredObject.Kind = ""
+			}),
+			expectErr: true,
+		},
+		{
+			name: "no mac",
+			ipInstance: newIPInstance(func(ipInstance *IPInstance) {
+				ipInstance.Spec.Address.MAC = ""
+			}),
+			expectErr: true,
+		},
+		{
+			name: "mismatched name",
+			ipInstance: newIPInstance(func(ipInstance *IPInstance) {
+				ipInstance.Name = "192-168-0-101"
+			}),
+			expectErr: true,
+		},
+		{
+			name: "out of cidr",
+			ipInstance: newIPInstance(func(ipInstance *IPInstance) {
+				ipInstance.Name = "192-168-1-100"
+				ipInstance.Spec.Address.IP = "192.168.1.100/24"
+			}),
+			expectErr: true,
+		},
+		{
+			name: "before start",
+			ipInstance: newIPInstance(func(ipInstance *IPInstance) {
+				ipInstance.Name = "192-168-0-5"
+				ipInstance.Spec.Address.IP = "192.168.0.5/24"
+			}),
+			expectErr: true,
+		},
+		{
+			name: "excluded",
+			ipInstance: newIPInstance(func(ipInstance *IPInstance) {
+				ipInstance.Name = "192-168-0-20"
+				ipInstance.Spec.Address.IP = "192.168.0.20/24"
+			}),
+			expectErr: true,
+		},
+		{
+			name: "mismatched gateway",
+			ipInstance: newIPInstance(func(ipInstance *IPInstance) {
+				ipInstance.Spec.Address.Gateway = "192.168.0.254"
+			}),
+			expectErr: true,
+		},
+		{
+			name: "mismatched net id",
+			ipInstance: newIPInstance(func(ipInstance *IPInstance) {
+				ipInstance.Spec.Address.NetID = nil
+			}),
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateAdoptedIPInstance(test.ipInstance, subnet, network)
+			if test.expectErr != (err != nil) {
+				t.Errorf("test %s fail, expect error %v but got %v", test.name, test.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateRebindTarget(t *testing.T) {
+	newIPInstance := func(terminating bool) *IPInstance {
+		ipInstance := &IPInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "192-168-0-100"},
+			Spec: IPInstanceSpec{
+				Binding: Binding{PodName: "source"},
+			},
+		}
+		if terminating {
+			ipInstance.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		}
+		return ipInstance
+	}
+
+	tests := []struct {
+		name       string
+		ipInstance *IPInstance
+		target     string
+		expectErr  bool
+	}{
+		{
+			name:       "valid",
+			ipInstance: newIPInstance(false),
+			target:     "target",
+		},
+		{
+			name:       "terminating",
+			ipInstance: newIPInstance(true),
+			target:     "target",
+			expectErr:  true,
+		},
+		{
+			name:       "invalid pod name",
+			ipInstance: newIPInstance(false),
+			target:     "Target_Pod",
+			expectErr:  true,
+		},
+		{
+			name:       "bound to target already",
+			ipInstance: newIPInstance(false),
+			target:     "source",
+			expectErr:  true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateRebindTarget(test.ipInstance, &RebindTarget{PodName: test.target})
+			if test.expectErr != (err != nil) {
+				t.Errorf("test %s fail, expect error %v but got %v", test.name, test.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateNodeInterfaceName(t *testing.T) {
+	tests := []struct {
+		name      string
+		ifName    string
+		expectErr bool
+	}{
+		{"valid", "eth1", false},
+		{"valid bond", "bond0.100", false},
+		{"empty", "", true},
+		{"too long", "eth0123456789012", true},
+		{"dot", ".", true},
+		{"slash", "eth/1", true},
+		{"space", "eth 1", true},
+	}
+	for _, test := range tests {
+		if err := ValidateNodeInterfaceName(test.ifName); test.expectErr != (err != nil) {
+			t.Errorf("test %s fail, expect error %v but got %v", test.name, test.expectErr, err)
+		}
+	}
+}
+
+func TestValidateSubnetEgressRoutes(t *testing.T) {
+	tests := []struct {
+		name         string
+		version      IPVersion
+		egressRoutes []EgressRoute
+		expectErr    bool
+	}{
+		{"no egress routes", IPv4, nil, false},
+		{"valid", IPv4, []EgressRoute{
+			{Destination: "10.0.0.0/8", Gateway: "192.168.0.253"},
+			{Destination: "0.0.0.0/0", Gateway: "192.168.0.254"},
+		}, false},
+		{"valid ipv6", IPv6, []EgressRoute{
+			{Destination: "::/0", Gateway: "fe80::1"},
+		}, false},
+		{"invalid destination", IPv4, []EgressRoute{
+			{Destination: "10.0.0.0", Gateway: "192.168.0.253"},
+		}, true},
+		{"non-standard destination", IPv4, []EgressRoute{
+			{Destination: "10.0.0.1/8", Gateway: "192.168.0.253"},
+		}, true},
+		{"invalid gateway", IPv4, []EgressRoute{
+			{Destination: "10.0.0.0/8", Gateway: "192.168.0"},
+		}, true},
+		{"mismatched family", IPv4, []EgressRoute{
+			{Destination: "::/0", Gateway: "192.168.0.253"},
+		}, true},
+		{"mismatched gateway family", IPv6, []EgressRoute{
+			{Destination: "::/0", Gateway: "192.168.0.253"},
+		}, true},
+		{"duplicated destination", IPv4, []EgressRoute{
+			{Destination: "10.0.0.0/8", Gateway: "192.168.0.253"},
+			{Destination: "10.0.0.0/8", Gateway: "192.168.0.254"},
+		}, true},
+	}
+	for _, test := range tests {
+		subnetSpec := &SubnetSpec{
+			Range:  AddressRange{Version: test.version},
+			Config: &SubnetConfig{EgressRoutes: test.egressRoutes},
+		}
+		if err := ValidateSubnetEgressRoutes(subnetSpec); test.expectErr != (err != nil) {
+			t.Errorf("test %s fail, expect error %v but got %v", test.name, test.expectErr, err)
+		}
+	}
+}
+
+func TestValidateNoTrackFlows(t *testing.T) {
+	port, invalidPort := int32(3260), int32(65536)
+	tests := []struct {
+		name      string
+		flows     []NoTrackFlow
+		expectErr bool
+	}{
+		{"no flows", nil, false},
+		{"valid", []NoTrackFlow{
+			{Destination: "10.100.0.0/24", Protocol: "TCP", Port: &port},
+			{Destination: "10.100.0.0/24", Protocol: "UDP"},
+			{Destination: "fd00:100::/64", Protocol: "TCP"},
+		}, false},
+		{"invalid destination", []NoTrackFlow{
+			{Destination: "10.100.0.0", Protocol: "TCP"},
+		}, true},
+		{"non-standard destination", []NoTrackFlow{
+			{Destination: "10.100.0.1/24", Protocol: "TCP"},
+		}, true},
+		{"default destination", []NoTrackFlow{
+			{Destination: "0.0.0.0/0", Protocol: "TCP"},
+		}, true},
+		{"invalid protocol", []NoTrackFlow{
+			{Destination: "10.100.0.0/24", Protocol: "ICMP"},
+		}, true},
+		{"invalid port", []NoTrackFlow{
+			{Destination: "10.100.0.0/24", Protocol: "TCP", Port: &invalidPort},
+		}, true},
+		{"duplicated flow", []NoTrackFlow{
+			{Destination: "10.100.0.0/24", Protocol: "TCP", Port: &port},
+			{Destination: "10.100.0.0/24", Protocol: "TCP", Port: &port},
+		}, true},
+	}
+	for _, test := range tests {
+		if err := ValidateNoTrackFlows(test.flows); test.expectErr != (err != nil) {
+			t.Errorf("test %s fail, expect error %v but got %v", test.name, test.expectErr, err)
+		}
+	}
+}
+
+func TestGetSubnetNoTrackFlows(t *testing.T) {
+	autoNatOutgoing := false
+	networkFlows := []NoTrackFlow{
+		{Destination: "10.100.0.0/24", Protocol: "TCP"},
+		{Destination: "fd00:100::/64", Protocol: "TCP"},
+	}
+	subnetFlows := []NoTrackFlow{
+		{Destination: "10.200.0.0/24", Protocol: "UDP"},
+	}
+
+	tests := []struct {
+		name        string
+		mode        NetworkMode
+		subnetSpec  *SubnetSpec
+		expectFlows int
+	}{
+		{"flows of network and subnet", NetworkModeVlan, &SubnetSpec{
+			Range:  AddressRange{Version: IPv4},
+			Config: &SubnetConfig{NoTrackFlows: subnetFlows},
+		}, 2},
+		{"flows of another family are ignored", NetworkModeVlan, &SubnetSpec{
+			Range: AddressRange{Version: IPv6},
+		}, 1},
+		{"overlay subnet depending on nat", NetworkModeVxlan, &SubnetSpec{
+			Range:  AddressRange{Version: IPv4},
+			Config: &SubnetConfig{NoTrackFlows: subnetFlows},
+		}, 0},
+		{"overlay subnet without nat outgoing", NetworkModeVxlan, &SubnetSpec{
+			Range:  AddressRange{Version: IPv4},
+			Config: &SubnetConfig{AutoNatOutgoing: &autoNatOutgoing},
+		}, 1},
+	}
+	for _, test := range tests {
+		network := &Network{
+			Spec: NetworkSpec{
+				Mode:   test.mode,
+				Config: &NetworkConfig{NoTrackFlows: networkFlows},
+			},
+		}
+		if flows := GetSubnetNoTrackFlows(test.subnetSpec, network); len(flows) != test.expectFlows {
+			t.Errorf("test %s fail, expect %d flows but got %v", test.name, test.expectFlows, flows)
+		}
+	}
+}
+
+func TestValidateSubnetAllocationFreezeWindows(t *testing.T) {
+	tests := []struct {
+		name      string
+		windows   []AllocationFreezeWindow
+		expectErr bool
+	}{
+		{"no windows", nil, false},
+		{"valid", []AllocationFreezeWindow{
+			{Schedule: "0 2 * * sat", DurationMinutes: 120, TimeZone: "Asia/Shanghai", Reason: "maintenance"},
+			{Schedule: "@daily", DurationMinutes: 10},
+		}, false},
+		{"invalid schedule", []AllocationFreezeWindow{
+			{Schedule: "0 25 * * *", DurationMinutes: 10},
+		}, true},
+		{"invalid duration", []AllocationFreezeWindow{
+			{Schedule: "0 2 * * *", DurationMinutes: 0},
+		}, true},
+		{"invalid time zone", []AllocationFreezeWindow{
+			{Schedule: "0 2 * * *", DurationMinutes: 10, TimeZone: "Mars/Olympus"},
+		}, true},
+	}
+	for _, test := range tests {
+		subnetSpec := &SubnetSpec{Config: &SubnetConfig{AllocationFreezeWindows: test.windows}}
+		if err := ValidateSubnetAllocationFreezeWindows(subnetSpec); test.expectErr != (err != nil) {
+			t.Errorf("test %s fail, expect error %v but got %v", test.name, test.expectErr, err)
+		}
+	}
+}
+
+func TestActiveAllocationFreezeWindow(t *testing.T) {
+	subnetSpec := &SubnetSpec{Config: &SubnetConfig{AllocationFreezeWindows: []AllocationFreezeWindow{
+		{Schedule: "invalid", DurationMinutes: 10},
+		{Schedule: "0 2 * * *", DurationMinutes: 120, TimeZone: "Asia/Shanghai", Reason: "maintenance"},
+	}}}
+
+	// 02:30 in Asia/Shanghai
+	window, end, frozen := ActiveAllocationFreezeWindow(subnetSpec, time.Date(2022, 3, 1, 18, 30, 0, 0, time.UTC))
+	assert.True(t, frozen)
+	assert.Equal(t, "maintenance", window.Reason)
+	assert.True(t, end.Equal(time.Date(2022, 3, 1, 20, 0, 0, 0, time.UTC)))
+
+	// 04:00 in Asia/Shanghai, when the window just ends
+	_, _, frozen = ActiveAllocationFreezeWindow(subnetSpec, time.Date(2022, 3, 1, 20, 0, 0, 0, time.UTC))
+	assert.False(t, frozen)
+
+	_, _, frozen = ActiveAllocationFreezeWindow(&SubnetSpec{}, time.Now())
+	assert.False(t, frozen)
+}
+
+func TestValidateStaticRoutes(t *testing.T) {
+	tests := []struct {
+		name         string
+		staticRoutes []StaticRoute
+		expectErr    bool
+	}{
+		{"no static routes", nil, false},
+		{"valid", []StaticRoute{
+			{Destination: "10.0.0.0/8", NextHop: "192.168.0.253"},
+			{Destination: "172.16.0.0/12", Device: "eth0"},
+			{Destination: "fd00:100::/64", NextHop: "fe80::1", Device: "eth0"},
+		}, false},
+		{"invalid destination", []StaticRoute{
+			{Destination: "10.0.0.0", NextHop: "192.168.0.253"},
+		}, true},
+		{"non-standard destination", []StaticRoute{
+			{Destination: "10.0.0.1/8", NextHop: "192.168.0.253"},
+		}, true},
+		{"invalid next hop", []StaticRoute{
+			{Destination: "10.0.0.0/8", NextHop: "192.168.0"},
+		}, true},
+		{"mismatched next hop family", []StaticRoute{
+			{Destination: "fd00:100::/64", NextHop: "192.168.0.253"},
+		}, true},
+		{"invalid device", []StaticRoute{
+			{Destination: "10.0.0.0/8", Device: "eth/0"},
+		}, true},
+		{"duplicated destination", []StaticRoute{
+			{Destination: "10.0.0.0/8", NextHop: "192.168.0.253"},
+			{Destination: "10.0.0.0/8", NextHop: "192.168.0.254"},
+		}, true},
+	}
+	for _, test := range tests {
+		if err := ValidateStaticRoutes(test.staticRoutes); test.expectErr != (err != nil) {
+			t.Errorf("test %s fail, expect error %v but got %v", test.name, test.expectErr, err)
+		}
+	}
+}
+
+func TestPodNetworkClaimSpecAnnotations(t *testing.T) {
+	retain := true
+	spec := &PodNetworkClaimSpec{
+		Network:     "network1",
+		Subnets:     []string{"subnet-v4", "subnet-v6"},
+		NetworkType: NetworkTypeUnderlay,
+		IPFamily:    IPFamilyDualStack,
+		IPPool:      []string{"192.168.0.10/fd00::10", "192.168.0.11/fd00::11"},
+		MACPool:     []string{"aa:bb:cc:dd:ee:01", "aa:bb:cc:dd:ee:02"},
+		Retain:      &retain,
+		StaticRoutes: []StaticRoute{
+			{Destination: "10.0.0.0/8", NextHop: "192.168.0.253"},
+		},
+	}
+
+	annotations := PodNetworkClaimSpecToAnnotations(spec)
+	assert.Equal(t, map[string]string{
+		constants.AnnotationSpecifiedNetwork: "network1",
+		constants.AnnotationSpecifiedSubnet:  "subnet-v4/subnet-v6",
+		constants.AnnotationNetworkType:      "Underlay",
+		constants.AnnotationIPFamily:         "DualStack",
+		constants.AnnotationIPPool:           "192.168.0.10/fd00::10,192.168.0.11/fd00::11",
+		constants.AnnotationMACPool:          "aa:bb:cc:dd:ee:01,aa:bb:cc:dd:ee:02",
+		constants.AnnotationIPRetain:         "true",
+		constants.AnnotationStaticRoutes:     `[{"destination":"10.0.0.0/8","nextHop":"192.168.0.253"}]`,
+	}, annotations)
+
+	translated, err := PodNetworkClaimSpecFromAnnotations(annotations)
+	assert.NoError(t, err)
+	assert.Equal(t, spec, translated)
+
+	// legacy values are matched case-insensitively
+	translated, err = PodNetworkClaimSpecFromAnnotations(map[string]string{
+		constants.AnnotationNetworkType: "overlay",
+		constants.AnnotationIPFamily:    "IPv4",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &PodNetworkClaimSpec{NetworkType: NetworkTypeOverlay, IPFamily: IPFamilyIPv4Only}, translated)
+
+	_, err = PodNetworkClaimSpecFromAnnotations(map[string]string{
+		constants.AnnotationIPRetain: "yes",
+	})
+	assert.Error(t, err)
+
+	_, err = PodNetworkClaimSpecFromAnnotations(map[string]string{
+		constants.AnnotationStaticRoutes: "10.0.0.0/8",
+	})
+	assert.Error(t, err)
+}
+
 func TestIntersect(t *testing.T) {
 	testCase := []struct {
 		name     string
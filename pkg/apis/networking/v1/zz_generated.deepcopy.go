@@ -22,7 +22,9 @@
 package v1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -45,6 +47,31 @@ func (in *Address) DeepCopy() *Address {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AddressAnnouncement) DeepCopyInto(out *AddressAnnouncement) {
+	*out = *in
+	if in.Retries != nil {
+		in, out := &in.Retries, &out.Retries
+		*out = new(int32)
+		**out = **in
+	}
+	if in.IntervalMilliseconds != nil {
+		in, out := &in.IntervalMilliseconds, &out.IntervalMilliseconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AddressAnnouncement.
+func (in *AddressAnnouncement) DeepCopy() *AddressAnnouncement {
+	if in == nil {
+		return nil
+	}
+	out := new(AddressAnnouncement)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AddressRange) DeepCopyInto(out *AddressRange) {
 	*out = *in
@@ -53,6 +80,11 @@ func (in *AddressRange) DeepCopyInto(out *AddressRange) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ReservedRanges != nil {
+		in, out := &in.ReservedRanges, &out.ReservedRanges
+		*out = make([]ReservedRange, len(*in))
+		copy(*out, *in)
+	}
 	if in.ExcludeIPs != nil {
 		in, out := &in.ExcludeIPs, &out.ExcludeIPs
 		*out = make([]string, len(*in))
@@ -70,6 +102,21 @@ func (in *AddressRange) DeepCopy() *AddressRange {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AllocationFreezeWindow) DeepCopyInto(out *AllocationFreezeWindow) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AllocationFreezeWindow.
+func (in *AllocationFreezeWindow) DeepCopy() *AllocationFreezeWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(AllocationFreezeWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BGPPeer) DeepCopyInto(out *BGPPeer) {
 	*out = *in
@@ -106,6 +153,148 @@ func (in *Binding) DeepCopy() *Binding {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChaosBlackholeVTEP) DeepCopyInto(out *ChaosBlackholeVTEP) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChaosBlackholeVTEP.
+func (in *ChaosBlackholeVTEP) DeepCopy() *ChaosBlackholeVTEP {
+	if in == nil {
+		return nil
+	}
+	out := new(ChaosBlackholeVTEP)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChaosDelay) DeepCopyInto(out *ChaosDelay) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChaosDelay.
+func (in *ChaosDelay) DeepCopy() *ChaosDelay {
+	if in == nil {
+		return nil
+	}
+	out := new(ChaosDelay)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChaosDrop) DeepCopyInto(out *ChaosDrop) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChaosDrop.
+func (in *ChaosDrop) DeepCopy() *ChaosDrop {
+	if in == nil {
+		return nil
+	}
+	out := new(ChaosDrop)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChaosRule) DeepCopyInto(out *ChaosRule) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChaosRule.
+func (in *ChaosRule) DeepCopy() *ChaosRule {
+	if in == nil {
+		return nil
+	}
+	out := new(ChaosRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ChaosRule) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChaosRuleList) DeepCopyInto(out *ChaosRuleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ChaosRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChaosRuleList.
+func (in *ChaosRuleList) DeepCopy() *ChaosRuleList {
+	if in == nil {
+		return nil
+	}
+	out := new(ChaosRuleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ChaosRuleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChaosRuleSpec) DeepCopyInto(out *ChaosRuleSpec) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Drop != nil {
+		in, out := &in.Drop, &out.Drop
+		*out = new(ChaosDrop)
+		**out = **in
+	}
+	if in.Delay != nil {
+		in, out := &in.Delay, &out.Delay
+		*out = new(ChaosDelay)
+		**out = **in
+	}
+	if in.BlackholeVTEP != nil {
+		in, out := &in.BlackholeVTEP, &out.BlackholeVTEP
+		*out = new(ChaosBlackholeVTEP)
+		**out = **in
+	}
+	if in.ExpireTime != nil {
+		in, out := &in.ExpireTime, &out.ExpireTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChaosRuleSpec.
+func (in *ChaosRuleSpec) DeepCopy() *ChaosRuleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ChaosRuleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Count) DeepCopyInto(out *Count) {
 	*out = *in
@@ -121,6 +310,41 @@ func (in *Count) DeepCopy() *Count {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DefaultNetworkPolicy) DeepCopyInto(out *DefaultNetworkPolicy) {
+	*out = *in
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DefaultNetworkPolicy.
+func (in *DefaultNetworkPolicy) DeepCopy() *DefaultNetworkPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(DefaultNetworkPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EgressRoute) DeepCopyInto(out *EgressRoute) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EgressRoute.
+func (in *EgressRoute) DeepCopy() *EgressRoute {
+	if in == nil {
+		return nil
+	}
+	out := new(EgressRoute)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IPInstance) DeepCopyInto(out *IPInstance) {
 	*out = *in
@@ -210,6 +434,11 @@ func (in *IPInstanceSpec) DeepCopyInto(out *IPInstanceSpec) {
 	*out = *in
 	in.Address.DeepCopyInto(&out.Address)
 	in.Binding.DeepCopyInto(&out.Binding)
+	if in.Rebind != nil {
+		in, out := &in.Rebind, &out.Rebind
+		*out = new(RebindTarget)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPInstanceSpec.
@@ -238,6 +467,26 @@ func (in *IPInstanceStatus) DeepCopy() *IPInstanceStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NeighborRateLimit) DeepCopyInto(out *NeighborRateLimit) {
+	*out = *in
+	if in.Burst != nil {
+		in, out := &in.Burst, &out.Burst
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NeighborRateLimit.
+func (in *NeighborRateLimit) DeepCopy() *NeighborRateLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(NeighborRateLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Network) DeepCopyInto(out *Network) {
 	*out = *in
@@ -273,6 +522,33 @@ func (in *NetworkConfig) DeepCopyInto(out *NetworkConfig) {
 		*out = make([]BGPPeer, len(*in))
 		copy(*out, *in)
 	}
+	if in.Cordon != nil {
+		in, out := &in.Cordon, &out.Cordon
+		*out = new(bool)
+		**out = **in
+	}
+	if in.MACSpoofProtection != nil {
+		in, out := &in.MACSpoofProtection, &out.MACSpoofProtection
+		*out = new(bool)
+		**out = **in
+	}
+	if in.NeighborRateLimit != nil {
+		in, out := &in.NeighborRateLimit, &out.NeighborRateLimit
+		*out = new(NeighborRateLimit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Routes != nil {
+		in, out := &in.Routes, &out.Routes
+		*out = new(RouteOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NoTrackFlows != nil {
+		in, out := &in.NoTrackFlows, &out.NoTrackFlows
+		*out = make([]NoTrackFlow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkConfig.
@@ -327,6 +603,11 @@ func (in *NetworkSpec) DeepCopyInto(out *NetworkSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.NetID != nil {
 		in, out := &in.NetID, &out.NetID
 		*out = new(int32)
@@ -337,6 +618,11 @@ func (in *NetworkSpec) DeepCopyInto(out *NetworkSpec) {
 		*out = new(NetworkConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Default != nil {
+		in, out := &in.Default, &out.Default
+		*out = new(DefaultNetworkPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkSpec.
@@ -389,6 +675,26 @@ func (in *NetworkStatus) DeepCopy() *NetworkStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NoTrackFlow) DeepCopyInto(out *NoTrackFlow) {
+	*out = *in
+	if in.Port != nil {
+		in, out := &in.Port, &out.Port
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NoTrackFlow.
+func (in *NoTrackFlow) DeepCopy() *NoTrackFlow {
+	if in == nil {
+		return nil
+	}
+	out := new(NoTrackFlow)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeInfo) DeepCopyInto(out *NodeInfo) {
 	*out = *in
@@ -484,6 +790,184 @@ func (in *NodeInfoStatus) DeepCopy() *NodeInfoStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeMTUConfig) DeepCopyInto(out *NodeMTUConfig) {
+	*out = *in
+	if in.Vlan != nil {
+		in, out := &in.Vlan, &out.Vlan
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Vxlan != nil {
+		in, out := &in.Vxlan, &out.Vxlan
+		*out = new(int32)
+		**out = **in
+	}
+	if in.BGP != nil {
+		in, out := &in.BGP, &out.BGP
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeMTUConfig.
+func (in *NodeMTUConfig) DeepCopy() *NodeMTUConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeMTUConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeNetworkConfig) DeepCopyInto(out *NodeNetworkConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeNetworkConfig.
+func (in *NodeNetworkConfig) DeepCopy() *NodeNetworkConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeNetworkConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NodeNetworkConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeNetworkConfigList) DeepCopyInto(out *NodeNetworkConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]NodeNetworkConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeNetworkConfigList.
+func (in *NodeNetworkConfigList) DeepCopy() *NodeNetworkConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeNetworkConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NodeNetworkConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeNetworkConfigRollout) DeepCopyInto(out *NodeNetworkConfigRollout) {
+	*out = *in
+	if in.CanaryNodeSelector != nil {
+		in, out := &in.CanaryNodeSelector, &out.CanaryNodeSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProgressDeadlineSeconds != nil {
+		in, out := &in.ProgressDeadlineSeconds, &out.ProgressDeadlineSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeNetworkConfigRollout.
+func (in *NodeNetworkConfigRollout) DeepCopy() *NodeNetworkConfigRollout {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeNetworkConfigRollout)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeNetworkConfigSpec) DeepCopyInto(out *NodeNetworkConfigSpec) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VtepAddressCIDRs != nil {
+		in, out := &in.VtepAddressCIDRs, &out.VtepAddressCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MTU != nil {
+		in, out := &in.MTU, &out.MTU
+		*out = new(NodeMTUConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(NodeNetworkConfigRollout)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeNetworkConfigSpec.
+func (in *NodeNetworkConfigSpec) DeepCopy() *NodeNetworkConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeNetworkConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeNetworkConfigStatus) DeepCopyInto(out *NodeNetworkConfigStatus) {
+	*out = *in
+	if in.CanaryStartTime != nil {
+		in, out := &in.CanaryStartTime, &out.CanaryStartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.PromotedSpec != nil {
+		in, out := &in.PromotedSpec, &out.PromotedSpec
+		*out = new(NodeNetworkConfigSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeNetworkConfigStatus.
+func (in *NodeNetworkConfigStatus) DeepCopy() *NodeNetworkConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeNetworkConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObjectMeta) DeepCopyInto(out *ObjectMeta) {
 	*out = *in
@@ -499,6 +983,159 @@ func (in *ObjectMeta) DeepCopy() *ObjectMeta {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodNetworkClaim) DeepCopyInto(out *PodNetworkClaim) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodNetworkClaim.
+func (in *PodNetworkClaim) DeepCopy() *PodNetworkClaim {
+	if in == nil {
+		return nil
+	}
+	out := new(PodNetworkClaim)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PodNetworkClaim) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodNetworkClaimList) DeepCopyInto(out *PodNetworkClaimList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PodNetworkClaim, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodNetworkClaimList.
+func (in *PodNetworkClaimList) DeepCopy() *PodNetworkClaimList {
+	if in == nil {
+		return nil
+	}
+	out := new(PodNetworkClaimList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PodNetworkClaimList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodNetworkClaimSpec) DeepCopyInto(out *PodNetworkClaimSpec) {
+	*out = *in
+	if in.Subnets != nil {
+		in, out := &in.Subnets, &out.Subnets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IPPool != nil {
+		in, out := &in.IPPool, &out.IPPool
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MACPool != nil {
+		in, out := &in.MACPool, &out.MACPool
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Retain != nil {
+		in, out := &in.Retain, &out.Retain
+		*out = new(bool)
+		**out = **in
+	}
+	if in.StaticRoutes != nil {
+		in, out := &in.StaticRoutes, &out.StaticRoutes
+		*out = make([]StaticRoute, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodNetworkClaimSpec.
+func (in *PodNetworkClaimSpec) DeepCopy() *PodNetworkClaimSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PodNetworkClaimSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RebindTarget) DeepCopyInto(out *RebindTarget) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RebindTarget.
+func (in *RebindTarget) DeepCopy() *RebindTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(RebindTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReservedRange) DeepCopyInto(out *ReservedRange) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedRange.
+func (in *ReservedRange) DeepCopy() *ReservedRange {
+	if in == nil {
+		return nil
+	}
+	out := new(ReservedRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouteOptions) DeepCopyInto(out *RouteOptions) {
+	*out = *in
+	if in.Metric != nil {
+		in, out := &in.Metric, &out.Metric
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Realm != nil {
+		in, out := &in.Realm, &out.Realm
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouteOptions.
+func (in *RouteOptions) DeepCopy() *RouteOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(RouteOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StatefulInfo) DeepCopyInto(out *StatefulInfo) {
 	*out = *in
@@ -519,6 +1156,21 @@ func (in *StatefulInfo) DeepCopy() *StatefulInfo {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StaticRoute) DeepCopyInto(out *StaticRoute) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StaticRoute.
+func (in *StaticRoute) DeepCopy() *StaticRoute {
+	if in == nil {
+		return nil
+	}
+	out := new(StaticRoute)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Subnet) DeepCopyInto(out *Subnet) {
 	*out = *in
@@ -564,6 +1216,38 @@ func (in *SubnetConfig) DeepCopyInto(out *SubnetConfig) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Cordon != nil {
+		in, out := &in.Cordon, &out.Cordon
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Drain != nil {
+		in, out := &in.Drain, &out.Drain
+		*out = new(bool)
+		**out = **in
+	}
+	if in.EgressRoutes != nil {
+		in, out := &in.EgressRoutes, &out.EgressRoutes
+		*out = make([]EgressRoute, len(*in))
+		copy(*out, *in)
+	}
+	if in.AddressAnnouncement != nil {
+		in, out := &in.AddressAnnouncement, &out.AddressAnnouncement
+		*out = new(AddressAnnouncement)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AllocationFreezeWindows != nil {
+		in, out := &in.AllocationFreezeWindows, &out.AllocationFreezeWindows
+		*out = make([]AllocationFreezeWindow, len(*in))
+		copy(*out, *in)
+	}
+	if in.NoTrackFlows != nil {
+		in, out := &in.NoTrackFlows, &out.NoTrackFlows
+		*out = make([]NoTrackFlow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SubnetConfig.
@@ -617,6 +1301,18 @@ func (in *SubnetSpec) DeepCopyInto(out *SubnetSpec) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Topology != nil {
+		in, out := &in.Topology, &out.Topology
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.Config != nil {
 		in, out := &in.Config, &out.Config
 		*out = new(SubnetConfig)
@@ -638,6 +1334,11 @@ func (in *SubnetSpec) DeepCopy() *SubnetSpec {
 func (in *SubnetStatus) DeepCopyInto(out *SubnetStatus) {
 	*out = *in
 	out.Count = in.Count
+	if in.Forecast != nil {
+		in, out := &in.Forecast, &out.Forecast
+		*out = new(SubnetUsageForecast)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SubnetStatus.
@@ -650,6 +1351,46 @@ func (in *SubnetStatus) DeepCopy() *SubnetStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SubnetUsageForecast) DeepCopyInto(out *SubnetUsageForecast) {
+	*out = *in
+	if in.Windows != nil {
+		in, out := &in.Windows, &out.Windows
+		*out = make([]SubnetUsageWindow, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExhaustionTime != nil {
+		in, out := &in.ExhaustionTime, &out.ExhaustionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SubnetUsageForecast.
+func (in *SubnetUsageForecast) DeepCopy() *SubnetUsageForecast {
+	if in == nil {
+		return nil
+	}
+	out := new(SubnetUsageForecast)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SubnetUsageWindow) DeepCopyInto(out *SubnetUsageWindow) {
+	*out = *in
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SubnetUsageWindow.
+func (in *SubnetUsageWindow) DeepCopy() *SubnetUsageWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(SubnetUsageWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VTEPInfo) DeepCopyInto(out *VTEPInfo) {
 	*out = *in
@@ -27,6 +27,10 @@ type FakeNetworkingV1 struct {
 	*testing.Fake
 }
 
+func (c *FakeNetworkingV1) ChaosRules() v1.ChaosRuleInterface {
+	return &FakeChaosRules{c}
+}
+
 func (c *FakeNetworkingV1) IPInstances(namespace string) v1.IPInstanceInterface {
 	return &FakeIPInstances{c, namespace}
 }
@@ -39,6 +43,14 @@ func (c *FakeNetworkingV1) NodeInfos() v1.NodeInfoInterface {
 	return &FakeNodeInfos{c}
 }
 
+func (c *FakeNetworkingV1) NodeNetworkConfigs() v1.NodeNetworkConfigInterface {
+	return &FakeNodeNetworkConfigs{c}
+}
+
+func (c *FakeNetworkingV1) PodNetworkClaims(namespace string) v1.PodNetworkClaimInterface {
+	return &FakePodNetworkClaims{c, namespace}
+}
+
 func (c *FakeNetworkingV1) Subnets() v1.SubnetInterface {
 	return &FakeSubnets{c}
 }
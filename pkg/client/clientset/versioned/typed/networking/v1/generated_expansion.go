@@ -17,10 +17,16 @@ limitations under the License.
 
 package v1
 
+type ChaosRuleExpansion interface{}
+
 type IPInstanceExpansion interface{}
 
 type NetworkExpansion interface{}
 
 type NodeInfoExpansion interface{}
 
+type NodeNetworkConfigExpansion interface{}
+
+type PodNetworkClaimExpansion interface{}
+
 type SubnetExpansion interface{}
@@ -27,9 +27,12 @@ import (
 
 type NetworkingV1Interface interface {
 	RESTClient() rest.Interface
+	ChaosRulesGetter
 	IPInstancesGetter
 	NetworksGetter
 	NodeInfosGetter
+	NodeNetworkConfigsGetter
+	PodNetworkClaimsGetter
 	SubnetsGetter
 }
 
@@ -38,6 +41,10 @@ type NetworkingV1Client struct {
 	restClient rest.Interface
 }
 
+func (c *NetworkingV1Client) ChaosRules() ChaosRuleInterface {
+	return newChaosRules(c)
+}
+
 func (c *NetworkingV1Client) IPInstances(namespace string) IPInstanceInterface {
 	return newIPInstances(c, namespace)
 }
@@ -50,6 +57,14 @@ func (c *NetworkingV1Client) NodeInfos() NodeInfoInterface {
 	return newNodeInfos(c)
 }
 
+func (c *NetworkingV1Client) NodeNetworkConfigs() NodeNetworkConfigInterface {
+	return newNodeNetworkConfigs(c)
+}
+
+func (c *NetworkingV1Client) PodNetworkClaims(namespace string) PodNetworkClaimInterface {
+	return newPodNetworkClaims(c, namespace)
+}
+
 func (c *NetworkingV1Client) Subnets() SubnetInterface {
 	return newSubnets(c)
 }
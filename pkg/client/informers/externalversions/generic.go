@@ -63,12 +63,18 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Multicluster().V1().RemoteVteps().Informer()}, nil
 
 		// Group=networking, Version=v1
+	case networkingv1.SchemeGroupVersion.WithResource("chaosrules"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Networking().V1().ChaosRules().Informer()}, nil
 	case networkingv1.SchemeGroupVersion.WithResource("ipinstances"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Networking().V1().IPInstances().Informer()}, nil
 	case networkingv1.SchemeGroupVersion.WithResource("networks"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Networking().V1().Networks().Informer()}, nil
 	case networkingv1.SchemeGroupVersion.WithResource("nodeinfos"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Networking().V1().NodeInfos().Informer()}, nil
+	case networkingv1.SchemeGroupVersion.WithResource("nodenetworkconfigs"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Networking().V1().NodeNetworkConfigs().Informer()}, nil
+	case networkingv1.SchemeGroupVersion.WithResource("podnetworkclaims"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Networking().V1().PodNetworkClaims().Informer()}, nil
 	case networkingv1.SchemeGroupVersion.WithResource("subnets"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Networking().V1().Subnets().Informer()}, nil
 
@@ -23,12 +23,18 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// ChaosRules returns a ChaosRuleInformer.
+	ChaosRules() ChaosRuleInformer
 	// IPInstances returns a IPInstanceInformer.
 	IPInstances() IPInstanceInformer
 	// Networks returns a NetworkInformer.
 	Networks() NetworkInformer
 	// NodeInfos returns a NodeInfoInformer.
 	NodeInfos() NodeInfoInformer
+	// NodeNetworkConfigs returns a NodeNetworkConfigInformer.
+	NodeNetworkConfigs() NodeNetworkConfigInformer
+	// PodNetworkClaims returns a PodNetworkClaimInformer.
+	PodNetworkClaims() PodNetworkClaimInformer
 	// Subnets returns a SubnetInformer.
 	Subnets() SubnetInformer
 }
@@ -44,6 +50,11 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// ChaosRules returns a ChaosRuleInformer.
+func (v *version) ChaosRules() ChaosRuleInformer {
+	return &chaosRuleInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // IPInstances returns a IPInstanceInformer.
 func (v *version) IPInstances() IPInstanceInformer {
 	return &iPInstanceInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
@@ -59,6 +70,16 @@ func (v *version) NodeInfos() NodeInfoInformer {
 	return &nodeInfoInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
 }
 
+// NodeNetworkConfigs returns a NodeNetworkConfigInformer.
+func (v *version) NodeNetworkConfigs() NodeNetworkConfigInformer {
+	return &nodeNetworkConfigInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
+// PodNetworkClaims returns a PodNetworkClaimInformer.
+func (v *version) PodNetworkClaims() PodNetworkClaimInformer {
+	return &podNetworkClaimInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // Subnets returns a SubnetInformer.
 func (v *version) Subnets() SubnetInformer {
 	return &subnetInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
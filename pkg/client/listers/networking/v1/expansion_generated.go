@@ -17,6 +17,10 @@ limitations under the License.
 
 package v1
 
+// ChaosRuleListerExpansion allows custom methods to be added to
+// ChaosRuleLister.
+type ChaosRuleListerExpansion interface{}
+
 // IPInstanceListerExpansion allows custom methods to be added to
 // IPInstanceLister.
 type IPInstanceListerExpansion interface{}
@@ -33,6 +37,18 @@ type NetworkListerExpansion interface{}
 // NodeInfoLister.
 type NodeInfoListerExpansion interface{}
 
+// NodeNetworkConfigListerExpansion allows custom methods to be added to
+// NodeNetworkConfigLister.
+type NodeNetworkConfigListerExpansion interface{}
+
+// PodNetworkClaimListerExpansion allows custom methods to be added to
+// PodNetworkClaimLister.
+type PodNetworkClaimListerExpansion interface{}
+
+// PodNetworkClaimNamespaceListerExpansion allows custom methods to be added to
+// PodNetworkClaimNamespaceLister.
+type PodNetworkClaimNamespaceListerExpansion interface{}
+
 // SubnetListerExpansion allows custom methods to be added to
 // SubnetLister.
 type SubnetListerExpansion interface{}
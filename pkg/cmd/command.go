@@ -0,0 +1,52 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"flag"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// NewRootCommand returns the command of hybridnet binary, which runs components and tools as its subcommands.
+func NewRootCommand(subcommands ...*cobra.Command) *cobra.Command {
+	root := &cobra.Command{
+		Use:   "hybridnet",
+		Short: "Hybridnet is a container networking solution for hybrid underlay and overlay networks",
+		Args:  cobra.NoArgs,
+	}
+	root.AddCommand(subcommands...)
+	return root
+}
+
+// AddGlobalFlags adds the flags shared by all components to flags of a command, including flags of
+// loggers, kubeconfig and feature gates, which are registered on the global flag sets.
+func AddGlobalFlags(flags *pflag.FlagSet) {
+	flags.AddGoFlagSet(flag.CommandLine)
+	flags.AddFlagSet(pflag.CommandLine)
+}
+
+// Main executes command with the command line arguments, and exits if it fails.
+func Main(command *cobra.Command) {
+	// usage is only printed for errors of command line, not for failures of running components
+	command.SilenceUsage = true
+	if err := command.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestRootCommand(t *testing.T) {
+	var ran string
+	var port int
+	newCommand := func(name string) *cobra.Command {
+		command := &cobra.Command{
+			Use:  name,
+			Args: cobra.NoArgs,
+			RunE: func(_ *cobra.Command, _ []string) error {
+				ran = name
+				return nil
+			},
+		}
+		// components register flags of the same names without conflicts
+		command.Flags().IntVar(&port, "metrics-port", 0, "The port of metrics.")
+		AddGlobalFlags(command.Flags())
+		return command
+	}
+
+	tests := []struct {
+		name         string
+		args         []string
+		expectRun    string
+		expectPort   int
+		expectOutput string
+		expectErr    bool
+	}{
+		{
+			name:       "subcommand with flags",
+			args:       []string{"manager", "--metrics-port=9899"},
+			expectRun:  "manager",
+			expectPort: 9899,
+		},
+		{
+			name:       "subcommand with global flags",
+			args:       []string{"daemon", "--feature-gates=MultiCluster=true", "--zap-log-level=debug"},
+			expectRun:  "daemon",
+			expectPort: 0,
+		},
+		{
+			name:         "help of root command",
+			args:         []string{"--help"},
+			expectOutput: "Available Commands:",
+		},
+		{
+			name:         "help of subcommand",
+			args:         []string{"manager", "--help"},
+			expectOutput: "--metrics-port",
+		},
+		{
+			name:         "version",
+			args:         []string{"version"},
+			expectOutput: "commit-id",
+		},
+		{
+			name:      "unknown flag",
+			args:      []string{"manager", "--unknown"},
+			expectErr: true,
+		},
+		{
+			name:      "unexpected arguments",
+			args:      []string{"daemon", "unknown"},
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		ran, port = "", 0
+		output := &bytes.Buffer{}
+		root := NewRootCommand(newCommand("manager"), newCommand("daemon"), NewVersionCommand())
+		root.SetOut(output)
+		root.SetErr(output)
+		root.SetArgs(test.args)
+
+		err := root.Execute()
+		if test.expectErr != (err != nil) {
+			t.Errorf("test %s fail, expect error %v but got %v", test.name, test.expectErr, err)
+			continue
+		}
+		if ran != test.expectRun || port != test.expectPort {
+			t.Errorf("test %s fail, expect running %q with port %d but got %q with %d",
+				test.name, test.expectRun, test.expectPort, ran, port)
+		}
+		if !strings.Contains(output.String(), test.expectOutput) {
+			t.Errorf("test %s fail, expect output containing %q but got %q", test.name, test.expectOutput, output.String())
+		}
+	}
+}
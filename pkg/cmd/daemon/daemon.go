@@ -0,0 +1,275 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/alibaba/hybridnet/pkg/constants"
+	daemonutils "github.com/alibaba/hybridnet/pkg/daemon/utils"
+	"github.com/spf13/cobra"
+	"github.com/vishvananda/netlink"
+
+	"github.com/alibaba/hybridnet/pkg/cmd"
+	zapinit "github.com/alibaba/hybridnet/pkg/zap"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	multiclusterv1 "github.com/alibaba/hybridnet/pkg/apis/multicluster/v1"
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/daemon/cnibin"
+	daemonconfig "github.com/alibaba/hybridnet/pkg/daemon/config"
+	"github.com/alibaba/hybridnet/pkg/daemon/controller"
+	"github.com/alibaba/hybridnet/pkg/daemon/lease"
+	"github.com/alibaba/hybridnet/pkg/daemon/server"
+	"github.com/alibaba/hybridnet/pkg/daemon/statedump"
+	"github.com/alibaba/hybridnet/pkg/feature"
+)
+
+// NewCommand returns the command running hybridnet daemon.
+func NewCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "daemon",
+		Short: "Run hybridnet daemon, which serves cni requests and configures the network of node",
+		Args:  cobra.NoArgs,
+	}
+
+	flags := command.Flags()
+	newConfig := daemonconfig.AddFlags(flags)
+	cmd.AddGlobalFlags(flags)
+
+	command.RunE = func(_ *cobra.Command, _ []string) error {
+		return run(newConfig)
+	}
+	command.AddCommand(newDoctorCommand())
+	return command
+}
+
+// run runs hybridnet daemon with the configuration initialized from parsed flags.
+func run(newConfig func() (*daemonconfig.Configuration, error)) error {
+	entryLog := cmd.SetupLogger("daemon")
+
+	config, err := newConfig()
+	if err != nil {
+		return fmt.Errorf("failed to parse config: %v", err)
+	}
+
+	if config.ValidateOnly {
+		entryLog.Info("daemon config is valid", "config-file", config.ConfigFile)
+		return nil
+	}
+
+	// log level might be set in config file, which is loaded after logger is created
+	if config.LogLevel != "" {
+		if err = zapinit.SetLevel(config.LogLevel); err != nil {
+			entryLog.Error(err, "failed to set log level")
+			os.Exit(1)
+		}
+	}
+
+	if err := cmd.ValidateFIPS(entryLog); err != nil {
+		return err
+	}
+
+	if config.DryDataplane {
+		entryLog.Info("dry dataplane enabled, host network will never be changed")
+	} else if err := initSysctl(); err != nil {
+		entryLog.Error(err, "failed to init sysctl")
+		os.Exit(1)
+	}
+
+	// setup manager
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+		MetricsBindAddress: config.MetricsServerAddress,
+		// only pods on this node are cached, pods of other nodes must be read from api server
+		NewCache: cache.BuilderWithOptions(cache.Options{
+			SelectorsByObject: cache.SelectorsByObject{
+				&corev1.Pod{}: {Field: fields.OneTermEqualSelector("spec.nodeName", config.NodeName)},
+			},
+		}),
+	})
+	if err != nil {
+		entryLog.Error(err, "unable to start daemon manager")
+		os.Exit(1)
+	}
+
+	if err := clientgoscheme.AddToScheme(mgr.GetScheme()); err != nil {
+		entryLog.Error(err, "failed to add client-go to manager scheme")
+		os.Exit(1)
+	}
+
+	if err := networkingv1.AddToScheme(mgr.GetScheme()); err != nil {
+		entryLog.Error(err, "failed to add networking v1 to manager scheme")
+		os.Exit(1)
+	}
+
+	if err := multiclusterv1.AddToScheme(mgr.GetScheme()); err != nil {
+		entryLog.Error(err, "failed to add multicluster v1 to manager scheme")
+		os.Exit(1)
+	}
+
+	ctx := ctrl.SetupSignalHandler()
+
+	nodeNetworkConfig, err := config.InitNodeNetworkConfig(ctx, mgr.GetAPIReader())
+	if err != nil {
+		entryLog.Error(err, "failed to init node network config")
+		os.Exit(1)
+	}
+	if nodeNetworkConfig != nil {
+		entryLog.Info("node network config applied", "node-network-config", nodeNetworkConfig.Name)
+	}
+	entryLog.Info("generate daemon config", "config", *config)
+
+	ctl, err := controller.NewCtrlHub(config, mgr, log.Log.WithName("ctrl-hub"))
+	if err != nil {
+		entryLog.Error(err, "failed to create controller")
+		os.Exit(1)
+	}
+
+	if config.EnableStateDump {
+		if err = mgr.AddMetricsExtraHandler(statedump.Path, statedump.Handler(config)); err != nil {
+			entryLog.Error(err, "failed to add state dump handler")
+			os.Exit(1)
+		}
+	}
+
+	if config.CNIBinIntegrityCheckInterval > 0 {
+		if err = mgr.Add(&cnibin.Verifier{
+			Binaries: cnibin.Binaries(config.CNIBinDir, config.CommunityCNIPlugins),
+			Interval: config.CNIBinIntegrityCheckInterval,
+			NodeName: config.NodeName,
+			Recorder: mgr.GetEventRecorderFor("hybridnet-daemon"),
+			Logger:   log.Log.WithName("cni-binary-verifier"),
+		}); err != nil {
+			entryLog.Error(err, "failed to add cni binary verifier")
+			os.Exit(1)
+		}
+	}
+
+	if feature.IPInstanceLeaseEnabled() {
+		if err = mgr.Add(&lease.Renewer{
+			Client:   mgr.GetClient(),
+			NodeName: config.NodeName,
+			Duration: config.IPLeaseDuration,
+			Logger:   log.Log.WithName("ip-lease-renewer"),
+		}); err != nil {
+			entryLog.Error(err, "failed to add ip instance lease renewer")
+			os.Exit(1)
+		}
+	}
+
+	if config.ConfigFile != "" {
+		configFileLog := log.Log.WithName("config-file-watcher")
+		if err = mgr.Add(&daemonconfig.FileWatcher{
+			Config:   config,
+			Interval: daemonconfig.DefaultConfigFileCheckInterval,
+			Logger:   configFileLog,
+			OnReload: func(reloadable *daemonconfig.ReloadableConfiguration) {
+				if err := zapinit.SetLevel(reloadable.LogLevel); err != nil {
+					configFileLog.Error(err, "failed to set log level")
+				}
+				ctl.ApplyReloadableConfiguration(reloadable)
+			},
+		}); err != nil {
+			entryLog.Error(err, "failed to add config file watcher")
+			os.Exit(1)
+		}
+	}
+
+	selfCheck := func(ctx context.Context) error {
+		if !ctl.CacheSynced(ctx) {
+			return fmt.Errorf("failed to wait for caches to sync")
+		}
+		return config.SelfCheck()
+	}
+
+	if err = mgr.Add(&daemonconfig.NodeNetworkConfigWatcher{
+		Config:    config,
+		Client:    mgr.GetClient(),
+		Reader:    mgr.GetClient(),
+		Interval:  daemonconfig.DefaultNodeNetworkConfigCheckInterval,
+		Logger:    log.Log.WithName("node-network-config-watcher"),
+		SelfCheck: selfCheck,
+		OnRolloutChange: func(revision string) {
+			// node network config is only applied on start
+			entryLog.Info("exit to apply node network config", "revision", revision)
+			os.Exit(0)
+		},
+	}); err != nil {
+		entryLog.Error(err, "failed to add node network config watcher")
+		os.Exit(1)
+	}
+
+	if err = mgr.Add(&daemonconfig.HealthReporter{
+		NodeName:          config.NodeName,
+		Version:           cmd.GitCommit,
+		Client:            mgr.GetClient(),
+		Interval:          daemonconfig.DefaultHealthReportInterval,
+		HeartbeatInterval: daemonconfig.DefaultHealthHeartbeatInterval,
+		Logger:            log.Log.WithName("health-reporter"),
+		SelfCheck:         selfCheck,
+	}); err != nil {
+		entryLog.Error(err, "failed to add health reporter")
+		os.Exit(1)
+	}
+
+	go func() {
+		if err = ctl.Run(ctx); err != nil {
+			entryLog.Error(err, "CtrlHub exit unusually")
+			os.Exit(1)
+		}
+	}()
+
+	server.RunServer(ctx, config, ctl, log.Log.WithName("cni-server"))
+	return nil
+}
+
+func initSysctl() error {
+	if err := daemonutils.EnableIPForward(netlink.FAMILY_V4); err != nil {
+		return fmt.Errorf("failed to enable ipv4 forwarding: %v", err)
+	}
+
+	globalDisabled, err := daemonutils.CheckIPv6GlobalDisabled()
+	if err != nil {
+		return fmt.Errorf("failed to check ipv6 global disabled: %v", err)
+	}
+
+	if !globalDisabled {
+		if err := daemonutils.EnableIPForward(netlink.FAMILY_V6); err != nil {
+			return fmt.Errorf("failed to enable ipv6 forwarding: %v", err)
+		}
+	}
+
+	if err := daemonutils.EnsureRpFilter(); err != nil {
+		return fmt.Errorf("failed to ensure rp_filter sysctl config: %v", err)
+	}
+
+	sysctlPath := fmt.Sprintf(constants.ArpFilterSysctl, "all")
+	if err = daemonutils.SetSysctl(sysctlPath, 0); err != nil {
+		return fmt.Errorf("failed to set %s sysctl path to 0, error: %v", sysctlPath, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/cmd"
+	"github.com/alibaba/hybridnet/pkg/daemon/cnibin"
+	daemonconfig "github.com/alibaba/hybridnet/pkg/daemon/config"
+)
+
+// doctorTimeout is the timeout of reading node network configs from apiserver
+const doctorTimeout = 30 * time.Second
+
+// doctorCheck is a check of doctor, which fails with a non-nil error
+type doctorCheck struct {
+	name  string
+	check func() error
+}
+
+// newDoctorCommand returns the command checking the node of daemon, e.g., "hybridnet daemon doctor".
+// It takes the same flags as daemon, and only reads host and apiserver without changing them.
+func newDoctorCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the config, node interfaces and cni binaries of hybridnet daemon on this node without changing them",
+		Args:  cobra.NoArgs,
+	}
+
+	flags := command.Flags()
+	newConfig := daemonconfig.AddFlags(flags)
+	cmd.AddGlobalFlags(flags)
+
+	command.RunE = func(c *cobra.Command, _ []string) error {
+		config, err := newConfig()
+		if err != nil {
+			return fmt.Errorf("failed to parse config: %v", err)
+		}
+		return runDoctorChecks(c.OutOrStdout(), newDoctorChecks(config))
+	}
+	return command
+}
+
+// newDoctorChecks returns the checks of daemon with config, node interfaces are checked after
+// node network config is applied to config as daemon does on start
+func newDoctorChecks(config *daemonconfig.Configuration) []doctorCheck {
+	return []doctorCheck{
+		{
+			name: "node-network-config",
+			check: func() error {
+				reader, err := newDoctorReader()
+				if err != nil {
+					return err
+				}
+
+				ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+				defer cancel()
+
+				_, err = config.InitNodeNetworkConfig(ctx, reader)
+				return err
+			},
+		},
+		{
+			name:  "node-interfaces",
+			check: config.SelfCheck,
+		},
+		{
+			name: "cni-binaries",
+			check: func() error {
+				mismatches, err := cnibin.Mismatches(cnibin.Binaries(config.CNIBinDir, config.CommunityCNIPlugins))
+				if err != nil {
+					return err
+				}
+				if len(mismatches) > 0 {
+					return fmt.Errorf("cni binaries do not match the ones inside image: %s", strings.Join(mismatches, ", "))
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// runDoctorChecks runs all the checks and writes their results to out, it fails if any check fails.
+func runDoctorChecks(out io.Writer, checks []doctorCheck) error {
+	var failed int
+	for _, c := range checks {
+		if err := c.check(); err != nil {
+			failed++
+			_, _ = fmt.Fprintf(out, "[FAIL] %s: %v\n", c.name, err)
+			continue
+		}
+		_, _ = fmt.Fprintf(out, "[PASS] %s\n", c.name)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+func newDoctorReader() (client.Reader, error) {
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubeconfig: %v", err)
+	}
+
+	scheme := runtime.NewScheme()
+	if err = clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("failed to add client-go to scheme: %v", err)
+	}
+	if err = networkingv1.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("failed to add networking v1 to scheme: %v", err)
+	}
+
+	return client.New(restConfig, client.Options{Scheme: scheme})
+}
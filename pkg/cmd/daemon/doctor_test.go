@@ -0,0 +1,68 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestRunDoctorChecks(t *testing.T) {
+	pass := doctorCheck{name: "pass", check: func() error { return nil }}
+	fail := doctorCheck{name: "fail", check: func() error { return fmt.Errorf("interface eth0 is down") }}
+
+	tests := []struct {
+		name         string
+		checks       []doctorCheck
+		expectErr    bool
+		expectOutput string
+	}{
+		{
+			name:         "all checks pass",
+			checks:       []doctorCheck{pass},
+			expectOutput: "[PASS] pass\n",
+		},
+		{
+			name:         "checks after failure still run",
+			checks:       []doctorCheck{fail, pass},
+			expectErr:    true,
+			expectOutput: "[FAIL] fail: interface eth0 is down\n[PASS] pass\n",
+		},
+	}
+
+	for _, test := range tests {
+		output := &bytes.Buffer{}
+		err := runDoctorChecks(output, test.checks)
+		if test.expectErr != (err != nil) {
+			t.Errorf("test %s fail, expect error %v but got %v", test.name, test.expectErr, err)
+		}
+		if output.String() != test.expectOutput {
+			t.Errorf("test %s fail, expect output %q but got %q", test.name, test.expectOutput, output.String())
+		}
+	}
+}
+
+func TestDoctorCommand(t *testing.T) {
+	command, args, err := NewCommand().Find([]string{"doctor", "--cni-bin-dir=/opt/cni/bin"})
+	if err != nil || command.Name() != "doctor" {
+		t.Fatalf("expect doctor subcommand but got %v: %v", command.Name(), err)
+	}
+	if err = command.ParseFlags(args); err != nil {
+		t.Errorf("expect doctor to take flags of daemon but got %v", err)
+	}
+}
@@ -0,0 +1,364 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package manager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	multiclusterv1 "github.com/alibaba/hybridnet/pkg/apis/multicluster/v1"
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/cmd"
+	"github.com/alibaba/hybridnet/pkg/controllers"
+	"github.com/alibaba/hybridnet/pkg/controllers/multicluster"
+	"github.com/alibaba/hybridnet/pkg/controllers/multicluster/envelope"
+	"github.com/alibaba/hybridnet/pkg/controllers/multicluster/satoken"
+	"github.com/alibaba/hybridnet/pkg/controllers/networking"
+	"github.com/alibaba/hybridnet/pkg/controllers/utils"
+	"github.com/alibaba/hybridnet/pkg/crds"
+	"github.com/alibaba/hybridnet/pkg/feature"
+	"github.com/alibaba/hybridnet/pkg/ipam/notifier"
+	ipamservice "github.com/alibaba/hybridnet/pkg/ipam/service"
+	"github.com/alibaba/hybridnet/pkg/managerconfig"
+	"github.com/alibaba/hybridnet/pkg/utils/mtls"
+	webhookserver "github.com/alibaba/hybridnet/pkg/webhook/server"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(multiclusterv1.AddToScheme(scheme))
+	utilruntime.Must(networkingv1.AddToScheme(scheme))
+	utilruntime.Must(kubevirtv1.AddToScheme(scheme))
+}
+
+// options are the flags of manager command
+type options struct {
+	controllerConcurrency map[string]int
+	clientQPS             float32
+	clientBurst           int
+	metricsPort           int
+	selectorStr           string
+	subnetDrainBatchSize  int
+	subnetDrainInterval   time.Duration
+	retrofitBatchSize     int
+	retrofitInterval      time.Duration
+	cleanupTimeout        time.Duration
+	gcInterval            time.Duration
+	podStartupTimeout     time.Duration
+	configMapName         string
+	multiClusterQPS       float32
+	multiClusterBurst     int
+	kmsEndpoint           string
+	kmsTimeout            time.Duration
+	kmsRotationInterval   time.Duration
+	tokenServiceAccount   string
+	ipamServiceAddress    string
+	ipamServiceTLS        mtls.Config
+	ipamServiceLease      time.Duration
+	ipamServiceMaxLease   time.Duration
+	ipamNotifierOptions   notifier.Options
+	installCRDs           bool
+	crdEstablishedTimeout time.Duration
+	cacheHostNetworkPods  bool
+	ipLeaseDuration       time.Duration
+	forecastWindows       []time.Duration
+	enableWebhook         bool
+	webhookPort           int
+	daemonRollout         networking.DaemonRolloutOptions
+	controllers           []string
+}
+
+// NewCommand returns the command running hybridnet manager.
+func NewCommand() *cobra.Command {
+	o := &options{}
+	command := &cobra.Command{
+		Use:   "manager",
+		Short: "Run hybridnet manager, which allocates addresses and manages the resources of hybridnet",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return run(o)
+		},
+	}
+
+	// register flags
+	flags := command.Flags()
+	flags.StringSliceVar(&o.controllers, "controllers", []string{"*"}, "The optional controllers to run, '*' enables all of them, 'foo' enables controller foo and '-foo' disables it, e.g., '*,-SubnetDrain'. The controllers allocating and releasing addresses are always running.")
+	flags.StringToIntVar(&o.controllerConcurrency, "controller-concurrency", map[string]int{}, "The specified concurrency of different controllers, which takes effect after restart and can not be changed by ConfigMap.")
+	flags.Float32Var(&o.clientQPS, "kube-client-qps", 300, "The QPS limit of apiserver client.")
+	flags.IntVar(&o.clientBurst, "kube-client-burst", 600, "The Burst limit of apiserver client.")
+	flags.Float32Var(&o.multiClusterQPS, "multicluster-kube-client-qps", 100, "The QPS limit of apiserver client used by multi-cluster controllers.")
+	flags.IntVar(&o.multiClusterBurst, "multicluster-kube-client-burst", 200, "The Burst limit of apiserver client used by multi-cluster controllers.")
+	flags.IntVar(&o.metricsPort, "metrics-port", 9899, "The port to listen on for prometheus metrics.")
+	flags.StringVar(&o.selectorStr, "pod-label-selector", "", "The label selector to select specified pods for IPAM.")
+	flags.IntVar(&o.subnetDrainBatchSize, "subnet-drain-batch-size", 1, "The max count of pods evicted in one batch when draining a subnet.")
+	flags.DurationVar(&o.subnetDrainInterval, "subnet-drain-interval", 30*time.Second, "The interval between two batches of eviction when draining a subnet.")
+	flags.IntVar(&o.retrofitBatchSize, "dualstack-retrofit-batch-size", 1, "The max count of pods restarted in one batch when retrofitting a namespace to dual-stack.")
+	flags.DurationVar(&o.retrofitInterval, "dualstack-retrofit-interval", time.Minute, "The interval between two batches of restart when retrofitting a namespace to dual-stack.")
+	flags.DurationVar(&o.cleanupTimeout, "dataplane-cleanup-timeout", 5*time.Minute, "The max duration for subnet deletion to wait for dataplane cleanup on nodes.")
+	flags.DurationVar(&o.gcInterval, "garbage-collection-interval", time.Minute, "The interval of garbage collection for remote objects.")
+	flags.DurationVar(&o.podStartupTimeout, "pod-startup-timeout", 5*time.Minute, "The max duration for scheduled pods to get ip instances before being flagged as stuck, zero means disabled.")
+	flags.StringVar(&o.kmsEndpoint, "remote-cluster-kms-endpoint", "", "The unix socket of KMS v2 plugin to encrypt key data of remote clusters, e.g., unix:///var/run/kms-plugin/socket.sock, empty means disabled.")
+	flags.DurationVar(&o.kmsTimeout, "remote-cluster-kms-timeout", 3*time.Second, "The timeout of calls to KMS plugin.")
+	flags.DurationVar(&o.kmsRotationInterval, "remote-cluster-kms-rotation-check-interval", 10*time.Minute, "The interval to check whether key of KMS is rotated and re-encrypt key data of remote clusters.")
+	flags.StringVar(&o.tokenServiceAccount, "remote-cluster-token-service-account", "", "The service account in the same namespace whose bound tokens authenticate manager to remote clusters configured with serviceAccountToken, empty means disabled.")
+	flags.StringVar(&o.ipamServiceAddress, "ipam-service-address", "", "The address for gRPC IPAM service to listen on, which serves workloads out of cluster, e.g., :9900, empty means disabled.")
+	flags.StringVar(&o.ipamServiceTLS.CertFile, "ipam-service-cert-file", "", "The certificate file of gRPC IPAM service.")
+	flags.StringVar(&o.ipamServiceTLS.KeyFile, "ipam-service-key-file", "", "The key file of gRPC IPAM service.")
+	flags.StringVar(&o.ipamServiceTLS.CAFile, "ipam-service-ca-file", "", "The CA bundle to verify client certificates of gRPC IPAM service.")
+	flags.StringVar(&o.ipamServiceTLS.TrustDomain, "ipam-service-trust-domain", "cluster.local", "The SPIFFE trust domain of gRPC IPAM service and its clients.")
+	flags.DurationVar(&o.ipamServiceLease, "ipam-service-default-lease-duration", time.Hour, "The default duration of leases of gRPC IPAM service.")
+	flags.DurationVar(&o.ipamServiceMaxLease, "ipam-service-max-lease-duration", 30*24*time.Hour, "The max duration of leases of gRPC IPAM service.")
+	flags.StringVar(&o.ipamNotifierOptions.URL, "ipam-notification-url", "", "The webhook url which events of address allocation and release are posted to, for third-party systems to track pod addresses, empty means disabled.")
+	flags.StringVar(&o.ipamNotifierOptions.BearerTokenFile, "ipam-notification-bearer-token-file", "", "The file of bearer token for authenticating to ipam notification webhook, which is read on every request.")
+	flags.StringVar(&o.ipamNotifierOptions.CAFile, "ipam-notification-ca-file", "", "The CA bundle to verify the certificate of ipam notification webhook, empty means system roots.")
+	flags.StringVar(&o.ipamNotifierOptions.CertFile, "ipam-notification-cert-file", "", "The client certificate file presented to ipam notification webhook.")
+	flags.StringVar(&o.ipamNotifierOptions.KeyFile, "ipam-notification-key-file", "", "The client key file presented to ipam notification webhook.")
+	flags.DurationVar(&o.ipamNotifierOptions.Timeout, "ipam-notification-timeout", 10*time.Second, "The timeout of every request to ipam notification webhook.")
+	flags.IntVar(&o.ipamNotifierOptions.QueueSize, "ipam-notification-queue-size", 10000, "The max count of events waiting to be posted to ipam notification webhook, events beyond are dropped.")
+	flags.IntVar(&o.ipamNotifierOptions.MaxRetries, "ipam-notification-max-retries", 5, "The max count of retries of a failed request to ipam notification webhook, before its events are dropped.")
+	flags.BoolVar(&o.installCRDs, "install-crds", false, "Whether to create or update CRDs of hybridnet and wait for them to be established on start.")
+	flags.DurationVar(&o.crdEstablishedTimeout, "crd-established-timeout", time.Minute, "The max duration to wait for installed CRDs to be established.")
+	flags.BoolVar(&o.cacheHostNetworkPods, "cache-host-network-pods", false, "Whether to cache host networking pods, which are never processed by manager, it should be true only if apiserver does not support the field selector of spec.hostNetwork.")
+	flags.DurationVar(&o.ipLeaseDuration, "ip-lease-duration", 5*time.Minute, "The duration of leases of ip instances, which must be the same as daemon, only used when IPInstanceLease feature is enabled.")
+	flags.DurationSliceVar(&o.forecastWindows, "subnet-usage-forecast-windows", []time.Duration{time.Hour, 24 * time.Hour}, "The sliding windows of allocation rate to forecast exhaustion of subnets in status and metrics, zero means disabled.")
+	flags.BoolVar(&o.enableWebhook, "enable-webhook", false, "Whether to serve validating and mutating webhooks in manager, which share informer caches with controllers instead of running hybridnet webhook separately.")
+	flags.IntVar(&o.webhookPort, "webhook-port", 9898, "The port webhook listen on, only used when webhook is enabled.")
+	flags.StringVar(&o.daemonRollout.ConfigMapName, "daemon-rollout-config-map-name", "", "The name of ConfigMap in the same namespace which readiness of daemons in every node pool is written to for gating daemon rollout, empty means disabled.")
+	flags.StringVar(&o.daemonRollout.NodePoolLabel, "daemon-rollout-node-pool-label", "", "The node label whose values group nodes into pools for daemon rollout, empty means all nodes are in the default pool.")
+	flags.IntVar(&o.daemonRollout.MaxUnhealthyNodes, "daemon-rollout-max-unhealthy-nodes", 0, "The max count of nodes in a pool failing self-checks with the same daemon version before the pool is not ready for daemon rollout.")
+	flags.DurationVar(&o.daemonRollout.HeartbeatTimeout, "daemon-rollout-heartbeat-timeout", 15*time.Minute, "The duration after the last heartbeat of daemon before it's stale, stale daemons are unhealthy once daemons of another version report in the same pool, zero means heartbeats are not checked.")
+	flags.StringVar(&o.configMapName, "config-map-name", "hybridnet-manager-config", "The name of ConfigMap in the same namespace whose data overrides flags at runtime, empty means disabled.")
+	cmd.AddGlobalFlags(flags)
+
+	return command
+}
+
+// run runs hybridnet manager with the parsed flags.
+func run(o *options) error {
+	entryLog := cmd.SetupLogger("manager", "controller-concurrency", o.controllerConcurrency, "controllers", o.controllers)
+
+	if err := cmd.ValidateFIPS(entryLog); err != nil {
+		return err
+	}
+
+	if err := networking.ControllerSelector(o.controllers).Validate(); err != nil {
+		return fmt.Errorf("invalid --controllers: %v", err)
+	}
+
+	globalContext := ctrl.SetupSignalHandler()
+
+	configStore := managerconfig.NewStore(managerconfig.Configuration{
+		KubeClientQPS:              o.clientQPS,
+		KubeClientBurst:            o.clientBurst,
+		SubnetDrainBatchSize:       o.subnetDrainBatchSize,
+		SubnetDrainInterval:        o.subnetDrainInterval,
+		DualStackRetrofitBatchSize: o.retrofitBatchSize,
+		DualStackRetrofitInterval:  o.retrofitInterval,
+		DataplaneCleanupTimeout:    o.cleanupTimeout,
+		GarbageCollectionInterval:  o.gcInterval,
+		PodStartupTimeout:          o.podStartupTimeout,
+	})
+
+	// client rate limiter can be adjusted by configuration at runtime
+	clientRateLimiter := managerconfig.NewAdjustableRateLimiter(o.clientQPS, o.clientBurst)
+	configStore.OnChange(func(config *managerconfig.Configuration) {
+		clientRateLimiter.SetRate(config.KubeClientQPS, config.KubeClientBurst)
+	})
+
+	// runtime feature gates must be updated before other handlers which depend on them
+	configStore.OnChange(func(config *managerconfig.Configuration) {
+		if err := feature.SetRuntimeFeatureGates(config.FeatureGates); err != nil {
+			entryLog.Error(err, "unable to set runtime feature gates")
+		}
+	})
+
+	clientConfig := ctrl.GetConfigOrDie()
+	clientConfig.QPS = o.clientQPS
+	clientConfig.Burst = o.clientBurst
+	clientConfig.RateLimiter = clientRateLimiter
+	clientConfig.UserAgent = rest.DefaultKubernetesUserAgent() + " controller-group/ipam"
+
+	if len(o.configMapName) > 0 {
+		go configStore.Watch(globalContext, kubernetes.NewForConfigOrDie(clientConfig), os.Getenv("NAMESPACE"),
+			o.configMapName, ctrllog.Log.WithName("config"))
+	}
+
+	// crds must be upgraded before controllers watch them
+	if o.installCRDs {
+		if err := installHybridnetCRDs(globalContext, clientConfig, o.crdEstablishedTimeout); err != nil {
+			entryLog.Error(err, "unable to install crds")
+			os.Exit(1)
+		}
+	}
+
+	// initialize objects from flags
+	// if selector string is empty, it means select everything
+	labelSelector, err := metav1.ParseToLabelSelector(o.selectorStr)
+	if err != nil {
+		entryLog.Error(err, "unable to parse label selector")
+		os.Exit(1)
+	}
+
+	var podSelector utils.PodSelector
+	if podSelector, err = utils.LabelSelectorAsPodSelector(labelSelector); err != nil {
+		entryLog.Error(err, "unable to create pod selector")
+		os.Exit(1)
+	}
+
+	mgr, err := ctrl.NewManager(clientConfig, ctrl.Options{
+		Scheme:                  scheme,
+		Logger:                  ctrl.Log.WithName("manager"),
+		MetricsBindAddress:      fmt.Sprintf(":%d", o.metricsPort),
+		LeaderElection:          true,
+		LeaderElectionID:        "hybridnet-manager-election",
+		LeaderElectionNamespace: os.Getenv("NAMESPACE"),
+		NewCache:                newCache(o.cacheHostNetworkPods),
+		Port:                    o.webhookPort,
+		TLSOpts:                 webhookserver.TLSOpts(),
+	})
+	if err != nil {
+		entryLog.Error(err, "unable to start manager")
+		os.Exit(1)
+	}
+
+	// webhook server does not need leader election, so admission requests are served by all manager
+	// pods, and objects are looked up from the informer caches of controllers instead of apiserver
+	if o.enableWebhook {
+		webhookserver.Register(mgr.GetWebhookServer())
+	}
+
+	var ipamServiceOptions *ipamservice.Options
+	if len(o.ipamServiceAddress) > 0 {
+		ipamServiceOptions = &ipamservice.Options{
+			Address:                 o.ipamServiceAddress,
+			TLS:                     o.ipamServiceTLS,
+			Namespace:               os.Getenv("NAMESPACE"),
+			DefaultLeaseDuration:    o.ipamServiceLease,
+			MaxLeaseDuration:        o.ipamServiceMaxLease,
+			ExpirationCheckInterval: 30 * time.Second,
+		}
+	}
+
+	var ipamNotifier *notifier.Options
+	if len(o.ipamNotifierOptions.URL) > 0 {
+		ipamNotifier = &o.ipamNotifierOptions
+	}
+
+	var daemonRolloutOptions *networking.DaemonRolloutOptions
+	if len(o.daemonRollout.ConfigMapName) > 0 {
+		o.daemonRollout.Namespace = os.Getenv("NAMESPACE")
+		daemonRolloutOptions = &o.daemonRollout
+	}
+
+	if err = controllers.SetupAll(mgr, controllers.Options{
+		Networking: networking.RegisterOptions{
+			ConcurrencyMap:             o.controllerConcurrency,
+			PodSelector:                podSelector,
+			Config:                     configStore,
+			IPAMService:                ipamServiceOptions,
+			IPAMNotifier:               ipamNotifier,
+			IPLeaseDuration:            o.ipLeaseDuration,
+			SubnetUsageForecastWindows: o.forecastWindows,
+			DaemonRollout:              daemonRolloutOptions,
+			Controllers:                o.controllers,
+		},
+		MultiCluster: func() (multicluster.RegisterOptions, error) {
+			// multi-cluster controllers mirror objects in bulk, so they use a separate client
+			// to avoid starving the writes of IPAM
+			multiClusterClient, err := utils.NewDelegatingClientFromManager(
+				utils.NewRestConfigForControllerGroup(clientConfig, "multicluster", o.multiClusterQPS, o.multiClusterBurst), mgr)
+			if err != nil {
+				return multicluster.RegisterOptions{}, fmt.Errorf("unable to create client for multi-cluster controllers: %v", err)
+			}
+
+			var remoteClusterEnvelope *envelope.Envelope
+			if len(o.kmsEndpoint) > 0 {
+				if remoteClusterEnvelope, err = envelope.NewFromEndpoint(o.kmsEndpoint, o.kmsTimeout); err != nil {
+					return multicluster.RegisterOptions{}, fmt.Errorf("unable to create envelope for remote cluster credentials: %v", err)
+				}
+			}
+
+			var remoteClusterTokenIssuer *satoken.Issuer
+			if len(o.tokenServiceAccount) > 0 {
+				remoteClusterTokenIssuer = satoken.New(kubernetes.NewForConfigOrDie(clientConfig), os.Getenv("NAMESPACE"), o.tokenServiceAccount)
+			}
+
+			return multicluster.RegisterOptions{
+				ConcurrencyMap:           o.controllerConcurrency,
+				Config:                   configStore,
+				Client:                   multiClusterClient,
+				Envelope:                 remoteClusterEnvelope,
+				KeyRotationCheckInterval: o.kmsRotationInterval,
+				TokenIssuer:              remoteClusterTokenIssuer,
+			}, nil
+		},
+		Logger: ctrllog.Log.WithName("controllers"),
+	}); err != nil {
+		entryLog.Error(err, "unable to set up controllers")
+		os.Exit(1)
+	}
+
+	if err = mgr.Start(globalContext); err != nil {
+		entryLog.Error(err, "manager exit unexpectedly")
+		os.Exit(1)
+	}
+
+	return nil
+}
+
+func installHybridnetCRDs(ctx context.Context, config *rest.Config, timeout time.Duration) error {
+	installer := &crds.Installer{
+		Client:             apiextensionsclient.NewForConfigOrDie(config),
+		EstablishedTimeout: timeout,
+		Logger:             ctrllog.Log.WithName("crd-installer"),
+	}
+
+	return installer.Install(ctx)
+}
+
+// newCache returns the cache builder of manager, host networking pods are filtered out by field selector
+// so that they are never listed, watched and enqueued
+func newCache(cacheHostNetworkPods bool) cache.NewCacheFunc {
+	if cacheHostNetworkPods {
+		return cache.New
+	}
+	return cache.BuilderWithOptions(cache.Options{
+		SelectorsByObject: cache.SelectorsByObject{
+			&corev1.Pod{}: {Field: fields.OneTermEqualSelector("spec.hostNetwork", "false")},
+		},
+	})
+}
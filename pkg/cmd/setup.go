@@ -0,0 +1,50 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/alibaba/hybridnet/pkg/feature"
+	"github.com/alibaba/hybridnet/pkg/utils/fips"
+	zapinit "github.com/alibaba/hybridnet/pkg/zap"
+)
+
+// SetupLogger sets the global logger and returns the entry logger which has logged the start of
+// component, it should be called after parsing flags.
+func SetupLogger(component string, keysAndValues ...interface{}) logr.Logger {
+	ctrllog.SetLogger(zapinit.NewZapLogger())
+
+	entryLog := ctrllog.Log.WithName("entry")
+	entryLog.Info("starting hybridnet "+component, append([]interface{}{
+		"known-features", feature.KnownFeatures(),
+		"commit-id", GitCommit,
+	}, keysAndValues...)...)
+	return entryLog
+}
+
+// ValidateFIPS validates the fips mode of binary and logs the result.
+func ValidateFIPS(entryLog logr.Logger) error {
+	if err := fips.Validate(); err != nil {
+		return fmt.Errorf("unable to run in fips mode: %v", err)
+	}
+	entryLog.Info("fips mode", "enabled", fips.Enabled(), "validated-module", fips.ValidatedModule())
+	return nil
+}
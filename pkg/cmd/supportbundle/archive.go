@@ -0,0 +1,74 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package supportbundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"time"
+)
+
+// Archive writes the files of a support bundle into a gzipped tarball, all of them are put
+// under a top-level directory
+type Archive struct {
+	dir        string
+	modTime    time.Time
+	gzipWriter *gzip.Writer
+	tarWriter  *tar.Writer
+}
+
+// NewArchive returns an archive writing to w, files are put under dir and stamped with modTime
+func NewArchive(w io.Writer, dir string, modTime time.Time) *Archive {
+	gzipWriter := gzip.NewWriter(w)
+	return &Archive{
+		dir:        dir,
+		modTime:    modTime,
+		gzipWriter: gzipWriter,
+		tarWriter:  tar.NewWriter(gzipWriter),
+	}
+}
+
+// Add writes a file into archive, name is relative to the top-level directory
+func (a *Archive) Add(name string, data []byte) error {
+	if err := a.tarWriter.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     path.Join(a.dir, name),
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  a.modTime,
+	}); err != nil {
+		return fmt.Errorf("failed to write header of %v: %v", name, err)
+	}
+	if _, err := a.tarWriter.Write(data); err != nil {
+		return fmt.Errorf("failed to write %v: %v", name, err)
+	}
+	return nil
+}
+
+// Close flushes the archive, it does not close the underlying writer
+func (a *Archive) Close() error {
+	if err := a.tarWriter.Close(); err != nil {
+		return fmt.Errorf("failed to close tar writer: %v", err)
+	}
+	if err := a.gzipWriter.Close(); err != nil {
+		return fmt.Errorf("failed to close gzip writer: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package supportbundle
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	multiclusterv1 "github.com/alibaba/hybridnet/pkg/apis/multicluster/v1"
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/cmd"
+	"github.com/alibaba/hybridnet/pkg/daemon/statedump"
+)
+
+const (
+	componentLabel    = "component"
+	componentManager  = "manager"
+	componentWebhook  = "webhook"
+	componentDaemon   = "daemon"
+	configMapPrefix   = "hybridnet"
+	errorsFile        = "errors.txt"
+	metricsPath       = "metrics"
+	hybridnetAppLabel = "hybridnet"
+)
+
+// Collector collects the objects, events, metrics and daemon states of a hybridnet cluster into
+// a support bundle. Failures of collecting are recorded in the bundle instead of aborting it.
+type Collector struct {
+	Client     client.Reader
+	KubeClient kubernetes.Interface
+	Logger     logr.Logger
+
+	// Namespace which hybridnet components are deployed in
+	Namespace string
+	// Metrics of a component are not collected if its port is zero
+	ManagerMetricsPort int
+	WebhookMetricsPort int
+	DaemonMetricsPort  int
+	// Only events observed after EventsSince are collected
+	EventsSince time.Time
+
+	failures []string
+}
+
+// Collect writes the support bundle into archive, an error is only returned if archive fails
+func (c *Collector) Collect(ctx context.Context, archive *Archive) error {
+	c.failures = nil
+
+	if err := archive.Add("version.txt", []byte(cmd.Version()+"\n")); err != nil {
+		return err
+	}
+
+	for _, collect := range []func(context.Context, *Archive) error{
+		c.collectResources,
+		c.collectEvents,
+		c.collectComponents,
+	} {
+		if err := collect(ctx, archive); err != nil {
+			return err
+		}
+	}
+
+	if len(c.failures) == 0 {
+		return nil
+	}
+	return archive.Add(errorsFile, []byte(strings.Join(c.failures, "\n")+"\n"))
+}
+
+func (c *Collector) fail(err error) {
+	c.Logger.Error(err, "failed to collect")
+	c.failures = append(c.failures, err.Error())
+}
+
+func (c *Collector) collectResources(ctx context.Context, archive *Archive) error {
+	for _, resource := range []struct {
+		name string
+		list client.ObjectList
+		opts []client.ListOption
+	}{
+		{name: "networks", list: &networkingv1.NetworkList{}},
+		{name: "subnets", list: &networkingv1.SubnetList{}},
+		{name: "ipinstances", list: &networkingv1.IPInstanceList{}},
+		{name: "podnetworkclaims", list: &networkingv1.PodNetworkClaimList{}},
+		{name: "nodenetworkconfigs", list: &networkingv1.NodeNetworkConfigList{}},
+		{name: "chaosrules", list: &networkingv1.ChaosRuleList{}},
+		{name: "nodeinfos", list: &networkingv1.NodeInfoList{}},
+		{name: "remoteclusters", list: &multiclusterv1.RemoteClusterList{}},
+		{name: "remotesubnets", list: &multiclusterv1.RemoteSubnetList{}},
+		{name: "remotevteps", list: &multiclusterv1.RemoteVtepList{}},
+		{name: "remoteendpointslices", list: &multiclusterv1.RemoteEndpointSliceList{}},
+		{name: "nodes", list: &corev1.NodeList{}},
+		{name: "pods", list: &corev1.PodList{}, opts: []client.ListOption{
+			client.InNamespace(c.Namespace), client.MatchingLabels{"app": hybridnetAppLabel},
+		}},
+		{name: "configmaps", list: &corev1.ConfigMapList{}, opts: []client.ListOption{client.InNamespace(c.Namespace)}},
+	} {
+		if err := c.Client.List(ctx, resource.list, resource.opts...); err != nil {
+			// crds of multicluster are not installed if the feature is disabled
+			if !meta.IsNoMatchError(err) {
+				c.fail(fmt.Errorf("failed to list %v: %v", resource.name, err))
+			}
+			continue
+		}
+
+		if configMaps, ok := resource.list.(*corev1.ConfigMapList); ok {
+			var items []corev1.ConfigMap
+			for _, configMap := range configMaps.Items {
+				if strings.HasPrefix(configMap.Name, configMapPrefix) {
+					items = append(items, configMap)
+				}
+			}
+			configMaps.Items = items
+		}
+
+		if err := meta.EachListItem(resource.list, func(obj runtime.Object) error {
+			Redact(obj.(client.Object))
+			return nil
+		}); err != nil {
+			c.fail(fmt.Errorf("failed to redact %v: %v", resource.name, err))
+			continue
+		}
+
+		if err := c.addYAML(archive, "resources/"+resource.name+".yaml", resource.list); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Collector) collectEvents(ctx context.Context, archive *Archive) error {
+	eventList := &corev1.EventList{}
+	if err := c.Client.List(ctx, eventList); err != nil {
+		c.fail(fmt.Errorf("failed to list events: %v", err))
+		return nil
+	}
+
+	var events []corev1.Event
+	for i := range eventList.Items {
+		if IsHybridnetEvent(&eventList.Items[i], c.EventsSince) {
+			events = append(events, eventList.Items[i])
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return eventTime(&events[i]).Before(eventTime(&events[j]))
+	})
+	for i := range events {
+		Redact(&events[i])
+	}
+
+	return c.addYAML(archive, "events.yaml", &corev1.EventList{Items: events})
+}
+
+// collectComponents collects metrics of components and states of daemons through the pod proxy of
+// api server, daemon states are only served if daemons run with --enable-state-dump
+func (c *Collector) collectComponents(ctx context.Context, archive *Archive) error {
+	podList := &corev1.PodList{}
+	if err := c.Client.List(ctx, podList, client.InNamespace(c.Namespace),
+		client.MatchingLabels{"app": hybridnetAppLabel}); err != nil {
+		c.fail(fmt.Errorf("failed to list pods of hybridnet: %v", err))
+		return nil
+	}
+
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+
+		var port int
+		switch pod.Labels[componentLabel] {
+		case componentManager:
+			port = c.ManagerMetricsPort
+		case componentWebhook:
+			port = c.WebhookMetricsPort
+		case componentDaemon:
+			port = c.DaemonMetricsPort
+		}
+		if port == 0 {
+			continue
+		}
+
+		if pod.Status.Phase != corev1.PodRunning {
+			c.fail(fmt.Errorf("skip pod %v which is %v", pod.Name, pod.Status.Phase))
+			continue
+		}
+
+		if data, err := c.proxyGet(ctx, pod, port, metricsPath); err != nil {
+			c.fail(fmt.Errorf("failed to get metrics of pod %v: %v", pod.Name, err))
+		} else if err = archive.Add("metrics/"+pod.Name+".txt", data); err != nil {
+			return err
+		}
+
+		if pod.Labels[componentLabel] != componentDaemon {
+			continue
+		}
+
+		if data, err := c.proxyGet(ctx, pod, port, statedump.Path); err != nil {
+			c.fail(fmt.Errorf("failed to get state of daemon %v on node %v: %v", pod.Name, pod.Spec.NodeName, err))
+		} else if err = archive.Add("daemons/"+pod.Spec.NodeName+".json", data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Collector) proxyGet(ctx context.Context, pod *corev1.Pod, port int, path string) ([]byte, error) {
+	return c.KubeClient.CoreV1().Pods(pod.Namespace).
+		ProxyGet("http", pod.Name, strconv.Itoa(port), path, nil).
+		DoRaw(ctx)
+}
+
+func (c *Collector) addYAML(archive *Archive, name string, obj interface{}) error {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		c.fail(fmt.Errorf("failed to marshal %v: %v", name, err))
+		return nil
+	}
+	return archive.Add(name, data)
+}
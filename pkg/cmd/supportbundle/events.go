@@ -0,0 +1,70 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package supportbundle
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	multiclusterv1 "github.com/alibaba/hybridnet/pkg/apis/multicluster/v1"
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+)
+
+// DaemonEventSource is the component of events recorded by hybridnet daemon
+const DaemonEventSource = "hybridnet-daemon"
+
+// IsHybridnetEvent tells whether an event is about a hybridnet object, or recorded by hybridnet.
+// Recorders of manager are named in camel case, e.g., "PodController" and "RemoteClusterStatusChecker",
+// which are distinguished from the ones of kubernetes components, e.g., "deployment-controller".
+func IsHybridnetEvent(event *corev1.Event, since time.Time) bool {
+	if eventTime(event).Before(since) {
+		return false
+	}
+
+	switch schema.FromAPIVersionAndKind(event.InvolvedObject.APIVersion, event.InvolvedObject.Kind).Group {
+	case networkingv1.GroupVersion.Group, multiclusterv1.GroupVersion.Group:
+		return true
+	}
+
+	component := event.Source.Component
+	if component == "" {
+		component = event.ReportingController
+	}
+	if component == DaemonEventSource {
+		return true
+	}
+	return len(component) > 0 && unicode.IsUpper(rune(component[0])) &&
+		(strings.HasSuffix(component, "Controller") || strings.HasSuffix(component, "Checker"))
+}
+
+// eventTime returns the time when an event is observed lastly
+func eventTime(event *corev1.Event) time.Time {
+	switch {
+	case event.Series != nil:
+		return event.Series.LastObservedTime.Time
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
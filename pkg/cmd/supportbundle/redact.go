@@ -0,0 +1,54 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package supportbundle
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	multiclusterv1 "github.com/alibaba/hybridnet/pkg/apis/multicluster/v1"
+)
+
+var redactedData = []byte("REDACTED")
+
+// Redact removes the sensitive fields of an object before it is put into support bundle. Managed
+// fields and the last applied configuration are dropped too, the latter may contain sensitive fields.
+func Redact(obj client.Object) {
+	obj.SetManagedFields(nil)
+	if annotations := obj.GetAnnotations(); len(annotations) > 0 {
+		delete(annotations, corev1.LastAppliedConfigAnnotation)
+		obj.SetAnnotations(annotations)
+	}
+
+	if remoteCluster, ok := obj.(*multiclusterv1.RemoteCluster); ok {
+		redactBytes(&remoteCluster.Spec.CAData)
+		redactBytes(&remoteCluster.Spec.CertData)
+		redactBytes(&remoteCluster.Spec.KeyData)
+		// id of kms key is kept for diagnosing decryption failures
+		if encrypted := remoteCluster.Spec.EncryptedKeyData; encrypted != nil {
+			redactBytes(&encrypted.Ciphertext)
+			redactBytes(&encrypted.EncryptedKey)
+			encrypted.Annotations = nil
+		}
+	}
+}
+
+func redactBytes(data *[]byte) {
+	if len(*data) > 0 {
+		*data = redactedData
+	}
+}
@@ -0,0 +1,142 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package supportbundle
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+
+	multiclusterv1 "github.com/alibaba/hybridnet/pkg/apis/multicluster/v1"
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/cmd"
+	zapinit "github.com/alibaba/hybridnet/pkg/zap"
+)
+
+// options are the flags of support-bundle command
+type options struct {
+	output             string
+	namespace          string
+	managerMetricsPort int
+	webhookMetricsPort int
+	daemonMetricsPort  int
+	eventsSince        time.Duration
+	timeout            time.Duration
+}
+
+// NewCommand returns the command collecting a support bundle of hybridnet.
+func NewCommand() *cobra.Command {
+	o := &options{}
+	command := &cobra.Command{
+		Use:   "support-bundle",
+		Short: "Collect objects, events, metrics and daemon states of hybridnet into an archive for attaching to issues",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return run(o)
+		},
+	}
+
+	flags := command.Flags()
+	flags.StringVar(&o.output, "output", "", "The path of support bundle, default: hybridnet-support-bundle-<time>.tar.gz in current directory")
+	flags.StringVar(&o.namespace, "namespace", "kube-system", "The namespace which hybridnet components are deployed in")
+	flags.IntVar(&o.managerMetricsPort, "manager-metrics-port", 9899, "The metrics port of manager, 0 means metrics of manager are not collected")
+	flags.IntVar(&o.webhookMetricsPort, "webhook-metrics-port", 0, "The metrics port of webhook, 0 means metrics of webhook are not collected")
+	flags.IntVar(&o.daemonMetricsPort, "daemon-metrics-port", 8091, "The metrics port of daemon, which also serves daemon states if daemon runs with --enable-state-dump, 0 means neither is collected")
+	flags.DurationVar(&o.eventsSince, "events-since", time.Hour, "Only events observed within this duration are collected")
+	flags.DurationVar(&o.timeout, "timeout", 5*time.Minute, "The timeout of collecting support bundle")
+	cmd.AddGlobalFlags(flags)
+
+	return command
+}
+
+// run collects a support bundle with the parsed flags.
+func run(o *options) error {
+	ctrllog.SetLogger(zapinit.NewZapLogger())
+
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get kubeconfig: %v", err)
+	}
+
+	scheme := runtime.NewScheme()
+	for _, addToScheme := range []func(*runtime.Scheme) error{
+		clientgoscheme.AddToScheme,
+		networkingv1.AddToScheme,
+		multiclusterv1.AddToScheme,
+	} {
+		if err = addToScheme(scheme); err != nil {
+			return fmt.Errorf("failed to build scheme: %v", err)
+		}
+	}
+
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return fmt.Errorf("failed to create client: %v", err)
+	}
+	kubeClient, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create kube client: %v", err)
+	}
+
+	now := time.Now()
+	bundleName := "hybridnet-support-bundle-" + now.Format("20060102-150405")
+	if o.output == "" {
+		o.output = bundleName + ".tar.gz"
+	}
+
+	file, err := os.Create(o.output)
+	if err != nil {
+		return fmt.Errorf("failed to create support bundle: %v", err)
+	}
+	defer file.Close()
+
+	ctx, cancel := context.WithTimeout(ctrl.SetupSignalHandler(), o.timeout)
+	defer cancel()
+
+	archive := NewArchive(file, bundleName, now)
+	collector := &Collector{
+		Client:             c,
+		KubeClient:         kubeClient,
+		Logger:             ctrllog.Log.WithName("support-bundle"),
+		Namespace:          o.namespace,
+		ManagerMetricsPort: o.managerMetricsPort,
+		WebhookMetricsPort: o.webhookMetricsPort,
+		DaemonMetricsPort:  o.daemonMetricsPort,
+		EventsSince:        now.Add(-o.eventsSince),
+	}
+	if err = collector.Collect(ctx, archive); err != nil {
+		return fmt.Errorf("failed to write support bundle: %v", err)
+	}
+	if err = archive.Close(); err != nil {
+		return fmt.Errorf("failed to write support bundle: %v", err)
+	}
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("failed to close support bundle: %v", err)
+	}
+
+	fmt.Printf("support bundle is written to %v\n", o.output)
+	return nil
+}
@@ -0,0 +1,205 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package supportbundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	multiclusterv1 "github.com/alibaba/hybridnet/pkg/apis/multicluster/v1"
+)
+
+func TestRedact(t *testing.T) {
+	remoteCluster := &multiclusterv1.RemoteCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "cluster1",
+			Annotations: map[string]string{
+				corev1.LastAppliedConfigAnnotation: `{"spec":{"keyData":"a2V5"}}`,
+				"foo":                              "bar",
+			},
+			ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+		},
+		Spec: multiclusterv1.RemoteClusterSpec{
+			APIEndpoint: "https://10.0.0.1:6443",
+			CAData:      []byte("ca"),
+			KeyData:     []byte("key"),
+			EncryptedKeyData: &multiclusterv1.EncryptedData{
+				Ciphertext:   []byte("ciphertext"),
+				EncryptedKey: []byte("encrypted-key"),
+				KeyID:        "kms-key",
+				Annotations:  map[string][]byte{"foo": []byte("bar")},
+			},
+		},
+	}
+
+	Redact(remoteCluster)
+
+	if _, exist := remoteCluster.Annotations[corev1.LastAppliedConfigAnnotation]; exist {
+		t.Errorf("last applied configuration is not dropped")
+	}
+	if remoteCluster.Annotations["foo"] != "bar" || len(remoteCluster.ManagedFields) != 0 {
+		t.Errorf("unexpected metadata %+v", remoteCluster.ObjectMeta)
+	}
+
+	spec := remoteCluster.Spec
+	if string(spec.CAData) != "REDACTED" || string(spec.KeyData) != "REDACTED" || len(spec.CertData) != 0 {
+		t.Errorf("pem data is not redacted %+v", spec)
+	}
+	if string(spec.EncryptedKeyData.Ciphertext) != "REDACTED" || string(spec.EncryptedKeyData.EncryptedKey) != "REDACTED" ||
+		spec.EncryptedKeyData.Annotations != nil || spec.EncryptedKeyData.KeyID != "kms-key" {
+		t.Errorf("encrypted key data is not redacted %+v", spec.EncryptedKeyData)
+	}
+	if spec.APIEndpoint != "https://10.0.0.1:6443" {
+		t.Errorf("api endpoint should be kept")
+	}
+}
+
+func TestIsHybridnetEvent(t *testing.T) {
+	now := time.Now()
+	since := now.Add(-time.Hour)
+
+	tests := []struct {
+		name     string
+		event    *corev1.Event
+		expected bool
+	}{
+		{
+			name: "subnet event",
+			event: &corev1.Event{
+				InvolvedObject: corev1.ObjectReference{APIVersion: "networking.alibaba.com/v1", Kind: "Subnet"},
+				LastTimestamp:  metav1.NewTime(now),
+			},
+			expected: true,
+		},
+		{
+			name: "remote cluster event",
+			event: &corev1.Event{
+				InvolvedObject: corev1.ObjectReference{APIVersion: "multicluster.alibaba.com/v1", Kind: "RemoteCluster"},
+				LastTimestamp:  metav1.NewTime(now),
+			},
+			expected: true,
+		},
+		{
+			name: "pod event of manager",
+			event: &corev1.Event{
+				InvolvedObject: corev1.ObjectReference{APIVersion: "v1", Kind: "Pod"},
+				Source:         corev1.EventSource{Component: "PodController"},
+				LastTimestamp:  metav1.NewTime(now),
+			},
+			expected: true,
+		},
+		{
+			name: "node event of daemon",
+			event: &corev1.Event{
+				InvolvedObject: corev1.ObjectReference{APIVersion: "v1", Kind: "Node"},
+				Source:         corev1.EventSource{Component: DaemonEventSource},
+				EventTime:      metav1.NewMicroTime(now),
+			},
+			expected: true,
+		},
+		{
+			name: "pod event of kubernetes",
+			event: &corev1.Event{
+				InvolvedObject: corev1.ObjectReference{APIVersion: "v1", Kind: "Pod"},
+				Source:         corev1.EventSource{Component: "replicaset-controller"},
+				LastTimestamp:  metav1.NewTime(now),
+			},
+			expected: false,
+		},
+		{
+			name: "expired subnet event",
+			event: &corev1.Event{
+				InvolvedObject: corev1.ObjectReference{APIVersion: "networking.alibaba.com/v1", Kind: "Subnet"},
+				LastTimestamp:  metav1.NewTime(now.Add(-2 * time.Hour)),
+			},
+			expected: false,
+		},
+		{
+			name: "recurring event of series",
+			event: &corev1.Event{
+				InvolvedObject: corev1.ObjectReference{APIVersion: "v1", Kind: "Pod"},
+				Source:         corev1.EventSource{Component: "PodStartupWatchdogController"},
+				EventTime:      metav1.NewMicroTime(now.Add(-2 * time.Hour)),
+				Series:         &corev1.EventSeries{LastObservedTime: metav1.NewMicroTime(now)},
+			},
+			expected: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := IsHybridnetEvent(test.event, since); result != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestArchive(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	archive := NewArchive(buffer, "bundle", time.Now())
+	files := map[string]string{
+		"version.txt":            "commit-id: abc\n",
+		"resources/subnets.yaml": "items: []\n",
+	}
+	for _, name := range []string{"version.txt", "resources/subnets.yaml"} {
+		if err := archive.Add(name, []byte(files[name])); err != nil {
+			t.Fatalf("failed to add %v: %v", name, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+
+	gzipReader, err := gzip.NewReader(buffer)
+	if err != nil {
+		t.Fatalf("failed to read gzip: %v", err)
+	}
+	tarReader := tar.NewReader(gzipReader)
+
+	count := 0
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read tar: %v", err)
+		}
+		data, err := io.ReadAll(tarReader)
+		if err != nil {
+			t.Fatalf("failed to read %v: %v", header.Name, err)
+		}
+
+		expected, exist := files[strings.TrimPrefix(header.Name, "bundle/")]
+		if !exist || string(data) != expected {
+			t.Errorf("unexpected file %v with content %q", header.Name, data)
+		}
+		count++
+	}
+	if count != len(files) {
+		t.Errorf("expected %v files, got %v", len(files), count)
+	}
+}
@@ -0,0 +1,46 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/spf13/cobra"
+)
+
+// GitCommit is the commit id which binaries are built from, it's set by
+// -ldflags "-X github.com/alibaba/hybridnet/pkg/cmd.GitCommit=<commit id>"
+var GitCommit string
+
+// Version returns the version information of binary.
+func Version() string {
+	return fmt.Sprintf("commit-id: %s, go-version: %s, platform: %s/%s",
+		GitCommit, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
+// NewVersionCommand returns the command printing version information.
+func NewVersionCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Print the version information of hybridnet",
+		Args:  cobra.NoArgs,
+		Run: func(command *cobra.Command, args []string) {
+			_, _ = fmt.Fprintln(command.OutOrStdout(), Version())
+		},
+	}
+}
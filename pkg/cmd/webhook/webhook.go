@@ -0,0 +1,125 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package webhook
+
+import (
+	"os"
+
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/spf13/cobra"
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	multiclusterv1 "github.com/alibaba/hybridnet/pkg/apis/multicluster/v1"
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/cmd"
+	"github.com/alibaba/hybridnet/pkg/feature"
+	"github.com/alibaba/hybridnet/pkg/managerconfig"
+	webhookserver "github.com/alibaba/hybridnet/pkg/webhook/server"
+)
+
+var (
+	scheme             = runtime.NewScheme()
+	port               int
+	metricsBindAddress string
+	configMapName      string
+)
+
+func init() {
+	_ = corev1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+	_ = authorizationv1.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+	_ = multiclusterv1.AddToScheme(scheme)
+	_ = admissionv1beta1.AddToScheme(scheme)
+	_ = admissionv1.AddToScheme(scheme)
+	_ = kubevirtv1.AddToScheme(scheme)
+}
+
+// NewCommand returns the command running hybridnet webhook.
+func NewCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "webhook",
+		Short: "Run hybridnet webhook, which validates and mutates the objects of hybridnet and pods",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return run()
+		},
+	}
+
+	// register flags
+	flags := command.Flags()
+	flags.IntVar(&port, "port", 9898, "The port webhook listen on")
+	flags.StringVar(&metricsBindAddress, "metrics-bind-address", "0", "The bind address for metrics, eg :8080")
+	flags.StringVar(&configMapName, "config-map-name", "hybridnet-manager-config", "The name of ConfigMap in the same namespace whose feature gates override flags at runtime, empty means disabled.")
+	cmd.AddGlobalFlags(flags)
+
+	return command
+}
+
+// run runs hybridnet webhook with the parsed flags.
+func run() error {
+	entryLog := cmd.SetupLogger("webhook")
+
+	if err := cmd.ValidateFIPS(entryLog); err != nil {
+		return err
+	}
+
+	clientConfig := ctrl.GetConfigOrDie()
+	globalContext := ctrl.SetupSignalHandler()
+
+	// webhook only cares about runtime feature gates of manager configuration
+	if len(configMapName) > 0 {
+		go managerconfig.WatchConfigMap(globalContext, kubernetes.NewForConfigOrDie(clientConfig), os.Getenv("NAMESPACE"),
+			configMapName, func(data map[string]string) {
+				if err := feature.SetRuntimeFeatureGates(data[managerconfig.KeyFeatureGates]); err != nil {
+					entryLog.Error(err, "unable to set runtime feature gates")
+					return
+				}
+				entryLog.Info("runtime feature gates applied", "feature-gates", data[managerconfig.KeyFeatureGates])
+			})
+	}
+
+	// create manager
+	mgr, err := ctrl.NewManager(clientConfig, ctrl.Options{
+		Scheme:             scheme,
+		LeaderElection:     false,
+		Port:               port,
+		MetricsBindAddress: metricsBindAddress,
+		TLSOpts:            webhookserver.TLSOpts(),
+	})
+	if err != nil {
+		entryLog.Error(err, "unable to start manager")
+		os.Exit(1)
+	}
+
+	// create webhooks
+	webhookserver.Register(mgr.GetWebhookServer())
+
+	if err = mgr.Start(globalContext); err != nil {
+		entryLog.Error(err, "manager exit unexpectedly")
+		os.Exit(1)
+	}
+	return nil
+}
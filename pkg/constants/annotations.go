@@ -24,6 +24,12 @@ const (
 
 	AnnotationIPRetain = "networking.alibaba.com/ip-retain"
 
+	// AnnotationIPRetainTTL on an ip-retained Job pod is how long its IPs are reserved after it fails, e.g., "30m"
+	AnnotationIPRetainTTL = "networking.alibaba.com/ip-retain-ttl"
+
+	// AnnotationIPRetainExpireTime on a reserved IPInstance of Job is the time (RFC3339) it will be released
+	AnnotationIPRetainExpireTime = "networking.alibaba.com/ip-retain-expire-time"
+
 	AnnotationGlobalService = "networking.alibaba.com/global-service"
 
 	AnnotationSpecifiedNetwork = "networking.alibaba.com/specified-network"
@@ -31,7 +37,64 @@ const (
 
 	AnnotationNetworkType = "networking.alibaba.com/network-type"
 
+	// AnnotationNodeInterface selects the node NIC which carries underlay traffic of pod, addresses
+	// of pod are allocated from the vlan subnets whose node interface is the same one
+	AnnotationNodeInterface = "networking.alibaba.com/node-interface"
+
+	// AnnotationNetworkClaim refers to a PodNetworkClaim in the same namespace of pod, the claim is
+	// translated into the network annotations above when pod is created
+	AnnotationNetworkClaim = "networking.alibaba.com/network-claim"
+
+	// AnnotationStaticRoutes is a json list of extra routes programmed inside the network namespace of pod,
+	// e.g., [{"destination":"10.0.0.0/8","nextHop":"192.168.1.254"}]
+	AnnotationStaticRoutes = "networking.alibaba.com/static-routes"
+
 	AnnotationHandledByWebhook = "networking.alibaba.com/handled-by-webhook"
 
+	// AnnotationEgressAllowlist works on pods and namespaces, annotation of pod takes precedence
+	AnnotationEgressAllowlist = "networking.alibaba.com/egress-allowlist"
+
+	// AnnotationIPSourceGuard set to "false" on pod opts out of source ip guard, which is enabled for underlay pods by default
+	AnnotationIPSourceGuard = "networking.alibaba.com/ip-source-guard"
+
+	// AnnotationIPLeaseWorkload describes the workload using addresses of a lease of IPAM service, e.g., name of VM
+	AnnotationIPLeaseWorkload = "networking.alibaba.com/ip-lease-workload"
+
+	// AnnotationLeaseExpireTime on a bound IPInstance is the time (RFC3339) its lease expires, which is
+	// renewed by the daemon of node while pod exists
+	AnnotationLeaseExpireTime = "networking.alibaba.com/lease-expire-time"
+
+	// AnnotationDualStackRetrofit set to "true" on a namespace restarts its running IPv4-only pods progressively
+	// after IPv6 subnets are added to their networks, so that the recreated pods are dual-stack
+	AnnotationDualStackRetrofit = "networking.alibaba.com/dualstack-retrofit"
+
+	// AnnotationDualStackRetrofitProgress on a retrofitted namespace is the progress in json, which is updated by manager
+	AnnotationDualStackRetrofitProgress = "networking.alibaba.com/dualstack-retrofit-progress"
+
+	AnnotationDataplaneCleanedSubnets = "networking.alibaba.com/dataplane-cleaned-subnets"
+
+	// AnnotationNodeNetworkConfigRevision on a node is the revision ("<name>/<generation>") of NodeNetworkConfig
+	// which daemon applies and passes the self-check with, it's updated by daemon and empty if no config applies
+	AnnotationNodeNetworkConfigRevision = "networking.alibaba.com/node-network-config-revision"
+
+	// AnnotationNodeNetworkConfigAppliedSpec on a node is the spec in json of the revision on
+	// AnnotationNodeNetworkConfigRevision, which nodes keep until another revision is released to them
+	AnnotationNodeNetworkConfigAppliedSpec = "networking.alibaba.com/node-network-config-applied-spec"
+
+	// AnnotationNodeNetworkConfigReleasedRevision on a node is the promoted revision of NodeNetworkConfig which
+	// daemon is allowed to restart for, it's updated by manager to promote a change to nodes in batches
+	AnnotationNodeNetworkConfigReleasedRevision = "networking.alibaba.com/node-network-config-released-revision"
+
+	// AnnotationDaemonVersion on a node is the commit id of daemon running on it, AnnotationDaemonHealthy
+	// is whether the daemon passes its latest self-check, and AnnotationDaemonHeartbeat is the time (RFC3339)
+	// of its latest report, all of them are updated by daemon periodically
+	AnnotationDaemonVersion   = "networking.alibaba.com/daemon-version"
+	AnnotationDaemonHealthy   = "networking.alibaba.com/daemon-healthy"
+	AnnotationDaemonHeartbeat = "networking.alibaba.com/daemon-heartbeat"
+
 	AnnotationCalicoPodIPs = "cni.projectcalico.org/podIPs"
+
+	// AnnotationNetworkStatus on a pod summarizes its network in the format defined by Kubernetes
+	// Network Plumbing Working Group, it's updated by daemon after the network of pod is created
+	AnnotationNetworkStatus = "k8s.v1.cni.cncf.io/network-status"
 )
@@ -22,4 +22,6 @@ const (
 	FinalizerManagerRuntimeRegistered = "multicluster.alibaba.com/manager-runtime-registered"
 
 	FinalizerMetricsRegistered = "networking.alibaba.com/metrics-registered"
+
+	FinalizerDataplaneCleanup = "networking.alibaba.com/dataplane-cleanup"
 )
@@ -25,6 +25,7 @@ const (
 	LabelPod     = "networking.alibaba.com/pod"
 	LabelPodUID  = "networking.alibaba.com/pod-uid"
 	LabelVersion = "networking.alibaba.com/version"
+	LabelPhase   = "networking.alibaba.com/phase"
 
 	LabelSpecifiedNetwork = "networking.alibaba.com/specified-network"
 	LabelSpecifiedSubnet  = "networking.alibaba.com/specified-subnet"
@@ -36,11 +37,34 @@ const (
 
 	LabelNetworkType = "networking.alibaba.com/network-type"
 
+	// LabelIPFamily on a Deployment or StatefulSet is the ip family preference of its pods, which only
+	// takes effect if ip family is not specified by pod or namespace
+	LabelIPFamily = "networking.alibaba.com/ip-family"
+
 	LabelUnderlayNetworkAttachment = "networking.alibaba.com/underlay-network-attachment"
 	LabelOverlayNetworkAttachment  = "networking.alibaba.com/overlay-network-attachment"
 	LabelBGPNetworkAttachment      = "networking.alibaba.com/bgp-network-attachment"
 
 	LabelRemoteCluster = "networking.alibaba.com/remote-cluster"
+
+	// LabelRebindSourceNode marks an IPInstance which has been rebound away from a node,
+	// it is removed by the daemon of new node after the data plane is ready
+	LabelRebindSourceNode = "networking.alibaba.com/rebind-source-node"
+
+	// LabelIPLease is the name of lease of IPAM service, on both the lease and its IPInstances
+	LabelIPLease = "networking.alibaba.com/ip-lease"
+
+	// LabelJobOwner is the uid of Job or CronJob which the retained IPInstances of its pods belong to
+	LabelJobOwner = "networking.alibaba.com/job-owner-uid"
+
+	// LabelStatefulOwner is the uid of stateful workload without ordinal pod names (e.g., Argo Rollouts)
+	// which the retained IPInstances of its pods belong to
+	LabelStatefulOwner = "networking.alibaba.com/stateful-owner-uid"
+
+	// LabelIPAdoption set to "true" on an IPInstance created by users means its address has been configured
+	// out-of-band, e.g., on a migrated VM, the existing owner of address with the same MAC is taken over by pod
+	// instead of being a conflict
+	LabelIPAdoption = "networking.alibaba.com/ip-adoption"
 )
 
 const (
@@ -5,4 +5,5 @@ const (
 	ContainerHostLinkMac    = "ee:ee:ee:ee:ee:ee"
 	VxlanLinkInfix          = ".vxlan"
 	ContainerNicName        = "eth0"
+	TenantVRFLinkPrefix     = "hvrf"
 )
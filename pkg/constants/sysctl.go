@@ -42,6 +42,9 @@ const (
 	AcceptDADSysctl = "/proc/sys/net/ipv6/conf/%s/accept_dad"
 	AcceptRASysctl  = "/proc/sys/net/ipv6/conf/%s/accept_ra"
 
+	UseTempAddrSysctl = "/proc/sys/net/ipv6/conf/%s/use_tempaddr"
+	AutoconfSysctl    = "/proc/sys/net/ipv6/conf/%s/autoconf"
+
 	IPv4BaseReachableTimeMSSysctl = "/proc/sys/net/ipv4/neigh/%s/base_reachable_time_ms"
 	IPv6BaseReachableTimeMSSysctl = "/proc/sys/net/ipv6/neigh/%s/base_reachable_time_ms"
 
@@ -0,0 +1,167 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package envelope
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	kmsapi "k8s.io/apiserver/pkg/storage/value/encrypt/envelope/v2alpha1"
+
+	multiclusterv1 "github.com/alibaba/hybridnet/pkg/apis/multicluster/v1"
+)
+
+// dataKeySize makes AES-256 used for data encryption
+const dataKeySize = 32
+
+// Envelope seals data with a random data encryption key for every sealing, and only the
+// data encryption key is sent to KMS, which follows the KMS v2 plugin API of kubernetes.
+type Envelope struct {
+	client      kmsapi.KeyManagementServiceClient
+	callTimeout time.Duration
+}
+
+func New(client kmsapi.KeyManagementServiceClient, callTimeout time.Duration) *Envelope {
+	return &Envelope{
+		client:      client,
+		callTimeout: callTimeout,
+	}
+}
+
+// NewFromEndpoint connects to a KMS v2 plugin listening on unix socket, e.g.,
+// unix:///var/run/kms-plugin/socket.sock
+func NewFromEndpoint(endpoint string, callTimeout time.Duration) (*Envelope, error) {
+	socket := strings.TrimPrefix(endpoint, "unix://")
+	if len(socket) == 0 || socket == endpoint && strings.Contains(endpoint, "://") {
+		return nil, fmt.Errorf("invalid kms endpoint %s, only unix socket is supported", endpoint)
+	}
+
+	conn, err := grpc.Dial(socket,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.WaitForReady(true)),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", addr)
+		}))
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to kms plugin %s: %v", endpoint, err)
+	}
+
+	return New(kmsapi.NewKeyManagementServiceClient(conn), callTimeout), nil
+}
+
+// Seal encrypts plaintext, uid is only used by KMS for tracing
+func (e *Envelope) Seal(ctx context.Context, uid string, plaintext []byte) (*multiclusterv1.EncryptedData, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("unable to generate data encryption key: %v", err)
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("unable to generate nonce: %v", err)
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, e.callTimeout)
+	defer cancel()
+
+	resp, err := e.client.Encrypt(callCtx, &kmsapi.EncryptRequest{Plaintext: dataKey, Uid: uid})
+	if err != nil {
+		return nil, fmt.Errorf("unable to encrypt data encryption key by kms: %v", err)
+	}
+	if len(resp.KeyId) == 0 {
+		return nil, fmt.Errorf("kms returns empty key id")
+	}
+
+	return &multiclusterv1.EncryptedData{
+		// nonce is prefixed to ciphertext
+		Ciphertext:   aead.Seal(nonce, nonce, plaintext, nil),
+		EncryptedKey: resp.Ciphertext,
+		KeyID:        resp.KeyId,
+		Annotations:  resp.Annotations,
+	}, nil
+}
+
+// Open decrypts the sealed data
+func (e *Envelope) Open(ctx context.Context, uid string, data *multiclusterv1.EncryptedData) ([]byte, error) {
+	callCtx, cancel := context.WithTimeout(ctx, e.callTimeout)
+	defer cancel()
+
+	resp, err := e.client.Decrypt(callCtx, &kmsapi.DecryptRequest{
+		Ciphertext:  data.EncryptedKey,
+		Uid:         uid,
+		KeyId:       data.KeyID,
+		Annotations: data.Annotations,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt data encryption key by kms: %v", err)
+	}
+
+	aead, err := newAEAD(resp.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data.Ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+
+	plaintext, err := aead.Open(nil, data.Ciphertext[:aead.NonceSize()], data.Ciphertext[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt data: %v", err)
+	}
+	return plaintext, nil
+}
+
+// Rotated checks whether the sealed data is encrypted by a key which is not current key of KMS
+func (e *Envelope) Rotated(ctx context.Context, data *multiclusterv1.EncryptedData) (bool, error) {
+	callCtx, cancel := context.WithTimeout(ctx, e.callTimeout)
+	defer cancel()
+
+	status, err := e.client.Status(callCtx, &kmsapi.StatusRequest{})
+	if err != nil {
+		return false, fmt.Errorf("unable to get status of kms: %v", err)
+	}
+	if len(status.KeyId) == 0 {
+		return false, fmt.Errorf("kms returns empty key id")
+	}
+	return status.KeyId != data.KeyID, nil
+}
+
+func newAEAD(dataKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create cipher: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create gcm: %v", err)
+	}
+	return aead, nil
+}
@@ -24,7 +24,6 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -32,8 +31,11 @@ import (
 	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
 	"github.com/alibaba/hybridnet/pkg/constants"
 	"github.com/alibaba/hybridnet/pkg/controllers/utils"
+	"github.com/alibaba/hybridnet/pkg/managerconfig"
 )
 
+const defaultGarbageCollectionInterval = time.Minute
+
 var _ manager.Runnable = &RemoteSubnetGarbageCollection{}
 var _ manager.Runnable = &RemoteVTEPGarbageCollection{}
 
@@ -46,7 +48,7 @@ type RemoteSubnetGarbageCollection struct {
 func (r *RemoteSubnetGarbageCollection) Start(ctx context.Context) error {
 	r.logger.Info("remote subnet garbage collection is starting")
 
-	wait.UntilWithContext(ctx, func(c context.Context) {
+	untilWithInterval(ctx, func(c context.Context) {
 		subnetList, err := utils.ListSubnets(ctx, r.reconciler.Client)
 		if err != nil {
 			r.logger.Error(err, "unable to list subnets")
@@ -81,7 +83,9 @@ func (r *RemoteSubnetGarbageCollection) Start(ctx context.Context) error {
 				},
 			}
 		}
-	}, time.Minute)
+	}, func() time.Duration {
+		return garbageCollectionInterval(r.reconciler.Config)
+	})
 
 	r.logger.Info("remote subnet garbage collection is stopping")
 	return nil
@@ -108,7 +112,7 @@ type RemoteVTEPGarbageCollection struct {
 func (r *RemoteVTEPGarbageCollection) Start(ctx context.Context) error {
 	r.logger.Info("remote vtep garbage collection is starting")
 
-	wait.UntilWithContext(ctx, func(c context.Context) {
+	untilWithInterval(ctx, func(c context.Context) {
 		nodeNames, err := utils.ListActiveNodesToNames(ctx, r.reconciler.Client)
 		if err != nil {
 			r.logger.Error(err, "unable to list nodes")
@@ -143,7 +147,9 @@ func (r *RemoteVTEPGarbageCollection) Start(ctx context.Context) error {
 				},
 			}
 		}
-	}, time.Minute)
+	}, func() time.Duration {
+		return garbageCollectionInterval(r.reconciler.Config)
+	})
 
 	r.logger.Info("remote vtep garbage collection is stopping")
 	return nil
@@ -156,3 +162,32 @@ func NewRemoteVTEPGarbageCollection(logger logr.Logger, eventChan chan<- event.G
 		reconciler: reconciler,
 	}
 }
+
+func garbageCollectionInterval(config *managerconfig.Store) time.Duration {
+	if c := config.Get(); c != nil && c.GarbageCollectionInterval > 0 {
+		return c.GarbageCollectionInterval
+	}
+	return defaultGarbageCollectionInterval
+}
+
+// untilWithInterval runs f periodically until context is done, the interval is
+// fetched before every waiting so that it can be changed at runtime
+func untilWithInterval(ctx context.Context, f func(context.Context), interval func() time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		f(ctx)
+
+		timer := time.NewTimer(interval())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
@@ -21,14 +21,44 @@ import (
 	"fmt"
 	"time"
 
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	"github.com/alibaba/hybridnet/pkg/controllers/concurrency"
+	"github.com/alibaba/hybridnet/pkg/controllers/multicluster/envelope"
+	"github.com/alibaba/hybridnet/pkg/controllers/multicluster/satoken"
+	"github.com/alibaba/hybridnet/pkg/managerconfig"
 	"github.com/alibaba/hybridnet/pkg/managerruntime"
 )
 
 type RegisterOptions struct {
 	ConcurrencyMap map[string]int
+	Config         *managerconfig.Store
+
+	// Client is used by multi-cluster controllers to access local cluster, so that
+	// bulk mirroring will not share the rate limit of IPAM, client of manager is used
+	// if it is nil
+	Client client.Client
+
+	// Envelope encrypts key data of remote clusters with KMS, encryption is disabled if it is nil
+	Envelope *envelope.Envelope
+	// KeyRotationCheckInterval is the interval to check whether the key of KMS is rotated
+	KeyRotationCheckInterval time.Duration
+
+	// TokenIssuer issues bound service account tokens for remote clusters which are authenticated
+	// without client certificate, these remote clusters are unavailable if it is nil
+	TokenIssuer *satoken.Issuer
+}
+
+// clientOverriddenManager replaces the client of manager, caches and others are
+// still shared
+type clientOverriddenManager struct {
+	manager.Manager
+	client client.Client
+}
+
+func (m *clientOverriddenManager) GetClient() client.Client {
+	return m.client
 }
 
 func RegisterToManager(ctx context.Context, mgr manager.Manager, options RegisterOptions) error {
@@ -36,6 +66,13 @@ func RegisterToManager(ctx context.Context, mgr manager.Manager, options Registe
 		options.ConcurrencyMap = map[string]int{}
 	}
 
+	if options.Client != nil {
+		mgr = &clientOverriddenManager{
+			Manager: mgr,
+			client:  options.Client,
+		}
+	}
+
 	clusterStatusCheckChan := make(chan string, 10)
 
 	uuidMutex, err := NewUUIDMutexFromClient(ctx, mgr.GetClient())
@@ -54,6 +91,8 @@ func RegisterToManager(ctx context.Context, mgr manager.Manager, options Registe
 		Client:                mgr.GetClient(),
 		Recorder:              mgr.GetEventRecorderFor(ControllerRemoteClusterUUID + "Controller"),
 		UUIDMutex:             uuidMutex,
+		Envelope:              options.Envelope,
+		TokenIssuer:           options.TokenIssuer,
 		ControllerConcurrency: concurrency.ControllerConcurrency(options.ConcurrencyMap[ControllerRemoteClusterUUID]),
 	}).SetupWithManager(mgr); err != nil {
 		return fmt.Errorf("unable to inject controller %s: %v", ControllerRemoteClusterUUID, err)
@@ -67,11 +106,26 @@ func RegisterToManager(ctx context.Context, mgr manager.Manager, options Registe
 		DaemonHub:              daemonHub,
 		LocalManager:           mgr,
 		ClusterStatusCheckChan: clusterStatusCheckChan,
+		Config:                 options.Config,
+		Envelope:               options.Envelope,
+		TokenIssuer:            options.TokenIssuer,
 		ControllerConcurrency:  concurrency.ControllerConcurrency(options.ConcurrencyMap[ControllerRemoteCluster]),
 	}).SetupWithManager(mgr); err != nil {
 		return fmt.Errorf("unable to inject controller %s: %v", ControllerRemoteCluster, err)
 	}
 
+	if options.Envelope != nil {
+		if err = (&RemoteClusterEncryptionReconciler{
+			Client:                mgr.GetClient(),
+			Recorder:              mgr.GetEventRecorderFor(ControllerRemoteClusterEncryption + "Controller"),
+			Envelope:              options.Envelope,
+			RotationCheckInterval: options.KeyRotationCheckInterval,
+			ControllerConcurrency: concurrency.ControllerConcurrency(options.ConcurrencyMap[ControllerRemoteClusterEncryption]),
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to inject controller %s: %v", ControllerRemoteClusterEncryption, err)
+		}
+	}
+
 	if err = mgr.Add(&RemoteClusterStatusChecker{
 		Client:                 mgr.GetClient(),
 		Logger:                 mgr.GetLogger().WithName("checker").WithName(CheckerRemoteClusterStatus),
@@ -37,8 +37,11 @@ import (
 	multiclusterv1 "github.com/alibaba/hybridnet/pkg/apis/multicluster/v1"
 	"github.com/alibaba/hybridnet/pkg/constants"
 	"github.com/alibaba/hybridnet/pkg/controllers/concurrency"
+	"github.com/alibaba/hybridnet/pkg/controllers/multicluster/envelope"
+	"github.com/alibaba/hybridnet/pkg/controllers/multicluster/satoken"
 	"github.com/alibaba/hybridnet/pkg/controllers/utils"
 	"github.com/alibaba/hybridnet/pkg/controllers/utils/sets"
+	"github.com/alibaba/hybridnet/pkg/managerconfig"
 	"github.com/alibaba/hybridnet/pkg/managerruntime"
 )
 
@@ -59,6 +62,14 @@ type RemoteClusterReconciler struct {
 
 	LocalManager manager.Manager
 
+	Config *managerconfig.Store
+
+	// Envelope decrypts key data of remote clusters, nil if kms is not configured
+	Envelope *envelope.Envelope
+
+	// TokenIssuer issues bound tokens for remote clusters authenticated by service account token
+	TokenIssuer *satoken.Issuer
+
 	concurrency.ControllerConcurrency
 }
 
@@ -119,7 +130,7 @@ func (r *RemoteClusterReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 
 	// generate rest config and manager runtime
 	var restConfig *rest.Config
-	if restConfig, err = utils.NewRestConfigFromRemoteCluster(remoteCluster); err != nil {
+	if restConfig, err = restConfigOfRemoteCluster(ctx, r.Envelope, r.TokenIssuer, remoteCluster); err != nil {
 		return ctrl.Result{}, wrapError("unable to get rest config", err)
 	}
 	var managerRuntime managerruntime.ManagerRuntime
@@ -227,6 +238,7 @@ func (r *RemoteClusterReconciler) constructClusterManagerRuntime(remoteCluster *
 				ParentCluster:       r.LocalManager,
 				ParentClusterObject: shadowRemoteCluster,
 				SubnetSet:           subnetSet,
+				Config:              r.Config,
 			}).SetupWithManager(mgr); err != nil {
 				return wrapError("unable to inject remote subnet reconciler", err)
 			}
@@ -240,6 +252,7 @@ func (r *RemoteClusterReconciler) constructClusterManagerRuntime(remoteCluster *
 				ParentClusterObject: shadowRemoteCluster,
 				SubnetSet:           subnetSet,
 				EventTrigger:        make(chan event.GenericEvent, 100),
+				Config:              r.Config,
 			}).SetupWithManager(mgr); err != nil {
 				return wrapError("unable to inject remote vtep reconciler", err)
 			}
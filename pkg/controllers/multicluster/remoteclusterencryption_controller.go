@@ -0,0 +1,172 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package multicluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	multiclusterv1 "github.com/alibaba/hybridnet/pkg/apis/multicluster/v1"
+	"github.com/alibaba/hybridnet/pkg/controllers/concurrency"
+	"github.com/alibaba/hybridnet/pkg/controllers/multicluster/envelope"
+	"github.com/alibaba/hybridnet/pkg/controllers/multicluster/satoken"
+	"github.com/alibaba/hybridnet/pkg/controllers/utils"
+)
+
+const ControllerRemoteClusterEncryption = "RemoteClusterEncryption"
+
+const (
+	ReasonCredentialEncrypted   = "CredentialEncrypted"
+	ReasonCredentialReEncrypted = "CredentialReEncrypted"
+	ReasonCredentialEncryptFail = "CredentialEncryptFail"
+)
+
+// RemoteClusterEncryptionReconciler replaces plaintext key data of remote clusters with the
+// envelope-encrypted one, and re-encrypts it after the key of KMS is rotated
+type RemoteClusterEncryptionReconciler struct {
+	client.Client
+
+	Recorder record.EventRecorder
+
+	Envelope *envelope.Envelope
+
+	// RotationCheckInterval is the interval to check whether key of KMS is rotated
+	RotationCheckInterval time.Duration
+
+	concurrency.ControllerConcurrency
+}
+
+//+kubebuilder:rbac:groups=multicluster.alibaba.com,resources=remoteclusters,verbs=get;list;watch;update;patch
+
+func (r *RemoteClusterEncryptionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	log := ctrllog.FromContext(ctx)
+
+	var remoteCluster = &multiclusterv1.RemoteCluster{}
+
+	defer func() {
+		if err != nil {
+			log.Error(err, "reconciliation fails")
+			if len(remoteCluster.UID) > 0 {
+				r.Recorder.Event(remoteCluster, corev1.EventTypeWarning, ReasonCredentialEncryptFail, err.Error())
+			}
+		}
+	}()
+
+	if err = r.Get(ctx, req.NamespacedName, remoteCluster); err != nil {
+		return ctrl.Result{}, wrapError("unable to fetch remote cluster", client.IgnoreNotFound(err))
+	}
+
+	if !remoteCluster.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	var keyData []byte
+	var reason string
+	switch {
+	case len(remoteCluster.Spec.KeyData) > 0:
+		keyData, reason = remoteCluster.Spec.KeyData, ReasonCredentialEncrypted
+	case remoteCluster.Spec.EncryptedKeyData != nil:
+		var rotated bool
+		if rotated, err = r.Envelope.Rotated(ctx, remoteCluster.Spec.EncryptedKeyData); err != nil {
+			return ctrl.Result{}, wrapError("unable to check key rotation", err)
+		}
+		if !rotated {
+			return ctrl.Result{RequeueAfter: r.RotationCheckInterval}, nil
+		}
+		if keyData, err = r.Envelope.Open(ctx, remoteCluster.Name, remoteCluster.Spec.EncryptedKeyData); err != nil {
+			return ctrl.Result{}, wrapError("unable to decrypt key data", err)
+		}
+		reason = ReasonCredentialReEncrypted
+	default:
+		return ctrl.Result{}, nil
+	}
+
+	encrypted := remoteCluster.DeepCopy()
+	if encrypted.Spec.EncryptedKeyData, err = r.Envelope.Seal(ctx, remoteCluster.Name, keyData); err != nil {
+		return ctrl.Result{}, wrapError("unable to encrypt key data", err)
+	}
+	encrypted.Spec.KeyData = nil
+
+	if err = r.Patch(ctx, encrypted, client.MergeFromWithOptions(remoteCluster, client.MergeFromWithOptimisticLock{})); err != nil {
+		return ctrl.Result{}, wrapError("unable to update encrypted key data", err)
+	}
+
+	r.Recorder.Eventf(encrypted, corev1.EventTypeNormal, reason, "key data is encrypted by kms key %s",
+		encrypted.Spec.EncryptedKeyData.KeyID)
+	return ctrl.Result{RequeueAfter: r.RotationCheckInterval}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *RemoteClusterEncryptionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(ControllerRemoteClusterEncryption).
+		For(&multiclusterv1.RemoteCluster{},
+			builder.WithPredicates(
+				&predicate.GenerationChangedPredicate{},
+			),
+		).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.Max(),
+			RecoverPanic:            true,
+		}).
+		Complete(r)
+}
+
+// restConfigOfRemoteCluster builds rest config of remote cluster whose key data may be encrypted,
+// or which is authenticated with bound service account tokens
+func restConfigOfRemoteCluster(ctx context.Context, e *envelope.Envelope, issuer *satoken.Issuer,
+	remoteCluster *multiclusterv1.RemoteCluster) (*rest.Config, error) {
+	if remoteCluster.Spec.ServiceAccountToken != nil {
+		if issuer == nil {
+			return nil, fmt.Errorf("remote cluster %s uses service account token but token issuer is not configured", remoteCluster.Name)
+		}
+
+		restConfig, err := utils.NewRestConfigFromRemoteCluster(remoteCluster)
+		if err != nil {
+			return nil, err
+		}
+		issuer.ConfigureRestConfig(restConfig, remoteCluster.Spec.ServiceAccountToken)
+		return restConfig, nil
+	}
+
+	if len(remoteCluster.Spec.KeyData) == 0 && remoteCluster.Spec.EncryptedKeyData != nil {
+		if e == nil {
+			return nil, fmt.Errorf("key data of remote cluster %s is encrypted but kms is not configured", remoteCluster.Name)
+		}
+
+		keyData, err := e.Open(ctx, remoteCluster.Name, remoteCluster.Spec.EncryptedKeyData)
+		if err != nil {
+			return nil, err
+		}
+
+		remoteCluster = remoteCluster.DeepCopy()
+		remoteCluster.Spec.KeyData = keyData
+	}
+
+	return utils.NewRestConfigFromRemoteCluster(remoteCluster)
+}
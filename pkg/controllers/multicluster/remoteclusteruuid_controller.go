@@ -34,6 +34,8 @@ import (
 
 	multiclusterv1 "github.com/alibaba/hybridnet/pkg/apis/multicluster/v1"
 	"github.com/alibaba/hybridnet/pkg/controllers/concurrency"
+	"github.com/alibaba/hybridnet/pkg/controllers/multicluster/envelope"
+	"github.com/alibaba/hybridnet/pkg/controllers/multicluster/satoken"
 	"github.com/alibaba/hybridnet/pkg/controllers/utils"
 )
 
@@ -46,6 +48,12 @@ type RemoteClusterUUIDReconciler struct {
 
 	UUIDMutex UUIDMutex
 
+	// Envelope decrypts key data of remote clusters, nil if kms is not configured
+	Envelope *envelope.Envelope
+
+	// TokenIssuer issues bound tokens for remote clusters authenticated by service account token
+	TokenIssuer *satoken.Issuer
+
 	concurrency.ControllerConcurrency
 }
 
@@ -69,7 +77,7 @@ func (r *RemoteClusterUUIDReconciler) Reconcile(ctx context.Context, req ctrl.Re
 	}
 
 	var restConfig *rest.Config
-	if restConfig, err = utils.NewRestConfigFromRemoteCluster(remoteCluster); err != nil {
+	if restConfig, err = restConfigOfRemoteCluster(ctx, r.Envelope, r.TokenIssuer, remoteCluster); err != nil {
 		// clean uuid and set offline
 		_ = retry.RetryOnConflict(retry.DefaultRetry, func() error {
 			return r.Status().Patch(ctx, remoteCluster, client.RawPatch(types.MergePatchType, []byte(fmt.Sprintf(`{"status":{"uuid":"","state":%q,"conditions":null}}`, multiclusterv1.ClusterOffline))))
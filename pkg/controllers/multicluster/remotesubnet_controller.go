@@ -39,6 +39,7 @@ import (
 	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
 	"github.com/alibaba/hybridnet/pkg/constants"
 	"github.com/alibaba/hybridnet/pkg/controllers/utils/sets"
+	"github.com/alibaba/hybridnet/pkg/managerconfig"
 )
 
 const ControllerRemoteSubnet = "RemoteSubnet"
@@ -52,6 +53,9 @@ type RemoteSubnetReconciler struct {
 	ParentClusterObject *multiclusterv1.RemoteCluster
 
 	SubnetSet sets.CallbackSet
+
+	// Config provides the interval of garbage collection
+	Config *managerconfig.Store
 }
 
 func (r *RemoteSubnetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
@@ -45,6 +45,7 @@ import (
 	"github.com/alibaba/hybridnet/pkg/constants"
 	"github.com/alibaba/hybridnet/pkg/controllers/utils"
 	"github.com/alibaba/hybridnet/pkg/controllers/utils/sets"
+	"github.com/alibaba/hybridnet/pkg/managerconfig"
 )
 
 const ControllerRemoteVTEP = "RemoteVTEP"
@@ -65,6 +66,9 @@ type RemoteVtepReconciler struct {
 
 	SubnetSet    sets.CallbackSet
 	EventTrigger chan event.GenericEvent
+
+	// Config provides the interval of garbage collection
+	Config *managerconfig.Store
 }
 
 func (r *RemoteVtepReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
@@ -0,0 +1,139 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package satoken
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+
+	multiclusterv1 "github.com/alibaba/hybridnet/pkg/apis/multicluster/v1"
+)
+
+const (
+	DefaultExpirationSeconds int64 = 3600
+
+	// minExpirationSeconds is the min lifetime of bound token accepted by apiserver
+	minExpirationSeconds int64 = 600
+
+	requestTimeout = 10 * time.Second
+)
+
+// Issuer requests bound tokens of a service account from local cluster, member clusters
+// which trust the service account issuer of local cluster as an OIDC provider can validate
+// these tokens, so that no long-lived credential of member cluster needs to be stored.
+type Issuer struct {
+	client         kubernetes.Interface
+	namespace      string
+	serviceAccount string
+
+	mu      sync.Mutex
+	sources map[tokenKey]transport.ResettableTokenSource
+}
+
+type tokenKey struct {
+	audience          string
+	expirationSeconds int64
+}
+
+func New(client kubernetes.Interface, namespace, serviceAccount string) *Issuer {
+	return &Issuer{
+		client:         client,
+		namespace:      namespace,
+		serviceAccount: serviceAccount,
+		sources:        map[tokenKey]transport.ResettableTokenSource{},
+	}
+}
+
+// TokenSource returns a cached token source which refreshes token after 80% of its lifetime,
+// token sources of the same audience and expiration are shared by all remote clusters
+func (i *Issuer) TokenSource(audience string, expirationSeconds int64) transport.ResettableTokenSource {
+	if expirationSeconds <= 0 {
+		expirationSeconds = DefaultExpirationSeconds
+	}
+	if expirationSeconds < minExpirationSeconds {
+		expirationSeconds = minExpirationSeconds
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	key := tokenKey{audience: audience, expirationSeconds: expirationSeconds}
+	if source, exist := i.sources[key]; exist {
+		return source
+	}
+
+	source := transport.NewCachedTokenSource(&tokenRequester{
+		issuer:            i,
+		audience:          audience,
+		expirationSeconds: expirationSeconds,
+	})
+	i.sources[key] = source
+	return source
+}
+
+// ConfigureRestConfig replaces the client certificate of rest config with bound tokens
+func (i *Issuer) ConfigureRestConfig(config *rest.Config, auth *multiclusterv1.ServiceAccountTokenAuth) {
+	var expirationSeconds int64
+	if auth.ExpirationSeconds != nil {
+		expirationSeconds = *auth.ExpirationSeconds
+	}
+
+	config.CertData, config.KeyData = nil, nil
+	config.Wrap(transport.ResettableTokenSourceWrapTransport(i.TokenSource(auth.Audience, expirationSeconds)))
+}
+
+type tokenRequester struct {
+	issuer            *Issuer
+	audience          string
+	expirationSeconds int64
+}
+
+func (t *tokenRequester) Token() (*oauth2.Token, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	expirationSeconds := t.expirationSeconds
+	issuedAt := time.Now()
+	tokenRequest, err := t.issuer.client.CoreV1().ServiceAccounts(t.issuer.namespace).CreateToken(ctx,
+		t.issuer.serviceAccount, &authenticationv1.TokenRequest{
+			Spec: authenticationv1.TokenRequestSpec{
+				Audiences:         []string{t.audience},
+				ExpirationSeconds: &expirationSeconds,
+			},
+		}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to request token of service account %s/%s for audience %s: %v",
+			t.issuer.namespace, t.issuer.serviceAccount, t.audience, err)
+	}
+
+	// apiserver may issue a token with different lifetime from the requested one
+	lifetime := tokenRequest.Status.ExpirationTimestamp.Sub(issuedAt)
+	return &oauth2.Token{
+		AccessToken: tokenRequest.Status.Token,
+		TokenType:   "Bearer",
+		Expiry:      issuedAt.Add(lifetime * 4 / 5),
+	}, nil
+}
@@ -0,0 +1,88 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package satoken
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestTokenSource(t *testing.T) {
+	var requested int
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		createAction := action.(k8stesting.CreateAction)
+		if createAction.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		if createAction.GetNamespace() != "kube-system" {
+			return true, nil, fmt.Errorf("unexpected namespace %s", createAction.GetNamespace())
+		}
+
+		tokenRequest := createAction.GetObject().(*authenticationv1.TokenRequest)
+		if len(tokenRequest.Spec.Audiences) != 1 || tokenRequest.Spec.Audiences[0] != "hybridnet" {
+			return true, nil, fmt.Errorf("unexpected audiences %v", tokenRequest.Spec.Audiences)
+		}
+		if *tokenRequest.Spec.ExpirationSeconds != DefaultExpirationSeconds {
+			return true, nil, fmt.Errorf("unexpected expiration seconds %d", *tokenRequest.Spec.ExpirationSeconds)
+		}
+
+		requested++
+		tokenRequest = tokenRequest.DeepCopy()
+		tokenRequest.Status = authenticationv1.TokenRequestStatus{
+			Token:               fmt.Sprintf("token-%d", requested),
+			ExpirationTimestamp: metav1.NewTime(time.Now().Add(time.Hour)),
+		}
+		return true, tokenRequest, nil
+	})
+
+	issuer := New(client, "kube-system", "hybridnet")
+	source := issuer.TokenSource("hybridnet", 0)
+	if issuer.TokenSource("hybridnet", DefaultExpirationSeconds) != source {
+		t.Fatalf("expect token source to be shared")
+	}
+
+	for i := 0; i < 2; i++ {
+		token, err := source.Token()
+		if err != nil {
+			t.Fatalf("unable to get token: %v", err)
+		}
+		if token.AccessToken != "token-1" {
+			t.Errorf("expect cached token-1 but got %s", token.AccessToken)
+		}
+		if token.Expiry.After(time.Now().Add(50 * time.Minute)) {
+			t.Errorf("expect token to be refreshed before expiration, but expiry is %v", token.Expiry)
+		}
+	}
+
+	// token is requested again after reset
+	source.ResetTokenOlderThan(time.Now().Add(time.Second))
+	token, err := source.Token()
+	if err != nil {
+		t.Fatalf("unable to get token: %v", err)
+	}
+	if token.AccessToken != "token-2" {
+		t.Errorf("expect token-2 but got %s", token.AccessToken)
+	}
+}
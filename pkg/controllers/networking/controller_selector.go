@@ -0,0 +1,99 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// RequiredControllers are always registered, because addresses are allocated by them, IPAM controller
+// blocks on the status updates of networks and subnets, and the finalizers of dataplane cleanup are
+// only removed by cleanup controllers
+var RequiredControllers = sets.NewString(
+	ControllerIPAM,
+	ControllerIPInstance,
+	ControllerPod,
+	ControllerNetworkStatus,
+	ControllerSubnetStatus,
+	ControllerSubnetCleanup,
+	ControllerIPInstanceCleanup,
+	ControllerNetworkCleanup,
+)
+
+// OptionalControllers can be disabled by ControllerSelector, the ones depending on feature gates or
+// options are still registered only if those are enabled
+var OptionalControllers = sets.NewString(
+	ControllerIPInstanceRebind,
+	ControllerNode,
+	ControllerQuota,
+	ControllerNodeCleanup,
+	ControllerJobIPRetain,
+	ControllerIPInstanceLease,
+	ControllerNodeDrain,
+	ControllerStatefulSetPreAllocate,
+	ControllerSubnetDrain,
+	ControllerDualStackRetrofit,
+	ControllerPodStartupWatchdog,
+	ControllerNodeNetworkConfigRollout,
+	ControllerDaemonRollout,
+)
+
+// ControllerSelector selects the optional controllers to register in the same way of kube-controller-manager,
+// "*" enables all of them, "foo" enables controller foo and "-foo" disables it, e.g., "*,-SubnetDrain".
+// An empty selector is the same as "*".
+type ControllerSelector []string
+
+// Validate checks that all the items of selector are "*" or names of optional controllers.
+func (s ControllerSelector) Validate() error {
+	for _, item := range s {
+		if item == "*" {
+			continue
+		}
+
+		name := strings.TrimPrefix(item, "-")
+		if RequiredControllers.Has(name) {
+			return fmt.Errorf("controller %s is required and can not be selected", name)
+		}
+		if !OptionalControllers.Has(name) {
+			return fmt.Errorf("unknown controller %q, supported controllers are %v", name, OptionalControllers.List())
+		}
+	}
+	return nil
+}
+
+// Enabled returns whether controller of name is selected, required controllers are always enabled.
+func (s ControllerSelector) Enabled(name string) bool {
+	if len(s) == 0 || RequiredControllers.Has(name) {
+		return true
+	}
+
+	var star bool
+	for _, item := range s {
+		switch item {
+		case name:
+			return true
+		case "-" + name:
+			return false
+		case "*":
+			star = true
+		}
+	}
+	return star
+}
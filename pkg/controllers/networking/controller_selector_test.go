@@ -0,0 +1,74 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import "testing"
+
+func TestControllerSelector(t *testing.T) {
+	tests := []struct {
+		name      string
+		selector  ControllerSelector
+		expectErr bool
+		enabled   []string
+		disabled  []string
+	}{
+		{
+			name:     "empty selector enables all",
+			selector: nil,
+			enabled:  []string{ControllerIPAM, ControllerSubnetDrain, ControllerNodeDrain},
+		},
+		{
+			name:     "star with disabled controller",
+			selector: ControllerSelector{"*", "-SubnetDrain"},
+			enabled:  []string{ControllerIPAM, ControllerNodeDrain},
+			disabled: []string{ControllerSubnetDrain},
+		},
+		{
+			name:     "only named controllers",
+			selector: ControllerSelector{"NodeDrain"},
+			enabled:  []string{ControllerIPAM, ControllerPod, ControllerNodeDrain},
+			disabled: []string{ControllerSubnetDrain, ControllerQuota},
+		},
+		{
+			name:      "required controller",
+			selector:  ControllerSelector{"*", "-Pod"},
+			expectErr: true,
+		},
+		{
+			name:      "unknown controller",
+			selector:  ControllerSelector{"-Unknown"},
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		if err := test.selector.Validate(); test.expectErr != (err != nil) {
+			t.Errorf("test %s fail, expect error %v but got %v", test.name, test.expectErr, err)
+			continue
+		}
+		for _, name := range test.enabled {
+			if !test.selector.Enabled(name) {
+				t.Errorf("test %s fail, expect controller %s enabled", test.name, name)
+			}
+		}
+		for _, name := range test.disabled {
+			if test.selector.Enabled(name) {
+				t.Errorf("test %s fail, expect controller %s disabled", test.name, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,305 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/controllers/concurrency"
+	"github.com/alibaba/hybridnet/pkg/controllers/utils"
+	"github.com/alibaba/hybridnet/pkg/metrics"
+	globalutils "github.com/alibaba/hybridnet/pkg/utils"
+)
+
+const ControllerDaemonRollout = "DaemonRollout"
+
+const (
+	// defaultNodePool is the pool of nodes without the label of node pool
+	defaultNodePool = "default"
+
+	// unreportedDaemonVersion is the version of daemons which never report health, e.g., of old versions
+	unreportedDaemonVersion = "unreported"
+
+	// maxUnhealthyNodesInStatus limits the length of status for large node pools
+	maxUnhealthyNodesInStatus = 10
+
+	// daemonHeartbeatCheckInterval is the interval to summarize pools again for stale heartbeats,
+	// which never trigger events of nodes
+	daemonHeartbeatCheckInterval = time.Minute
+)
+
+// DaemonRolloutOptions configures the coordinator of daemon rollout
+type DaemonRolloutOptions struct {
+	// Namespace and ConfigMapName locate the ConfigMap which readiness of node pools is written to
+	Namespace     string
+	ConfigMapName string
+
+	// NodePoolLabel is the node label whose values group nodes into pools, all nodes are in
+	// the default pool if empty
+	NodePoolLabel string
+
+	// MaxUnhealthyNodes is the max count of nodes of a pool failing self-checks with the same
+	// daemon version before the pool is not ready
+	MaxUnhealthyNodes int
+
+	// HeartbeatTimeout is the duration after the last heartbeat reported by daemon before the
+	// daemon is stale, stale and unreported daemons are unhealthy once another version of daemon
+	// reports heartbeats in the same pool
+	HeartbeatTimeout time.Duration
+}
+
+// DaemonPoolStatus is the readiness of daemons in a node pool, written to the ConfigMap in json
+type DaemonPoolStatus struct {
+	Ready    bool                  `json:"ready"`
+	Message  string                `json:"message,omitempty"`
+	Versions []DaemonVersionStatus `json:"versions"`
+}
+
+// DaemonVersionStatus is the health of daemons of a version in a node pool
+type DaemonVersionStatus struct {
+	Version            string   `json:"version"`
+	Nodes              int      `json:"nodes"`
+	StaleNodes         int      `json:"staleNodes,omitempty"`
+	UnhealthyNodes     int      `json:"unhealthyNodes"`
+	UnhealthyNodeNames []string `json:"unhealthyNodeNames,omitempty"`
+}
+
+// DaemonRolloutReconciler coordinates the rollout of daemon DaemonSet. It watches the version and
+// self-check result of daemon reported on every node, and writes the readiness of every node pool to a
+// ConfigMap, so that upgrade tooling can pause the rollout once daemons of a new version start failing
+// self-checks or stop reporting heartbeats. All pools are summarized together by a single key of work queue.
+type DaemonRolloutReconciler struct {
+	client.Client
+
+	// APIReader reads the ConfigMap which is not cached by manager
+	APIReader client.Reader
+
+	Options DaemonRolloutOptions
+
+	concurrency.ControllerConcurrency
+}
+
+//+kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;create;update
+
+func (r *DaemonRolloutReconciler) Reconcile(ctx context.Context, _ ctrl.Request) (result ctrl.Result, err error) {
+	log := ctrllog.FromContext(ctx)
+
+	defer func() {
+		if err != nil {
+			log.Error(err, "reconciliation fails")
+		}
+	}()
+
+	var nodeList = &corev1.NodeList{}
+	if err = r.List(ctx, nodeList); err != nil {
+		return ctrl.Result{}, wrapError("unable to list nodes", err)
+	}
+
+	var data = map[string]string{}
+	for pool, status := range summarizeDaemonPools(nodeList.Items, r.Options.NodePoolLabel, r.Options.MaxUnhealthyNodes,
+		r.Options.HeartbeatTimeout, time.Now()) {
+		statusBytes, err := json.Marshal(status)
+		if err != nil {
+			return ctrl.Result{}, wrapError("unable to marshal status of node pool", err)
+		}
+		data[pool] = string(statusBytes)
+
+		if status.Ready {
+			metrics.DaemonPoolReadyGauge.WithLabelValues(pool).Set(1)
+		} else {
+			metrics.DaemonPoolReadyGauge.WithLabelValues(pool).Set(0)
+		}
+	}
+
+	var configMap = &corev1.ConfigMap{}
+	if err = r.APIReader.Get(ctx, types.NamespacedName{Namespace: r.Options.Namespace, Name: r.Options.ConfigMapName}, configMap); err != nil {
+		if !errors.IsNotFound(err) {
+			return ctrl.Result{}, wrapError("unable to fetch ConfigMap", err)
+		}
+		return ctrl.Result{RequeueAfter: daemonHeartbeatCheckInterval}, wrapError("unable to create ConfigMap", r.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: r.Options.Namespace,
+				Name:      r.Options.ConfigMapName,
+			},
+			Data: data,
+		}))
+	}
+
+	// pools without nodes are removed
+	for pool := range configMap.Data {
+		if _, exist := data[pool]; !exist {
+			_ = metrics.DaemonPoolReadyGauge.DeleteLabelValues(pool)
+		}
+	}
+
+	if reflect.DeepEqual(configMap.Data, data) {
+		return ctrl.Result{RequeueAfter: daemonHeartbeatCheckInterval}, nil
+	}
+
+	configMap.Data = data
+	return ctrl.Result{RequeueAfter: daemonHeartbeatCheckInterval}, wrapError("unable to update ConfigMap", r.Update(ctx, configMap))
+}
+
+// summarizeDaemonPools groups nodes into pools by label and summarizes the health of daemons of every
+// version, a pool is not ready if daemons of any version fail self-checks on more than maxUnhealthy nodes.
+// Daemons whose heartbeats are older than heartbeatTimeout are stale, and stale or unreported daemons are
+// counted as unhealthy once daemons of another version report heartbeats in the same pool, e.g., daemons
+// crash after upgraded. Heartbeats are never checked if heartbeatTimeout is zero.
+func summarizeDaemonPools(nodes []corev1.Node, poolLabel string, maxUnhealthy int, heartbeatTimeout time.Duration,
+	now time.Time) map[string]*DaemonPoolStatus {
+	var (
+		versionsOfPools     = map[string]map[string]*DaemonVersionStatus{}
+		liveVersionsOfPools = map[string]sets.String{}
+		staleNodesOfPools   = map[string][]*corev1.Node{}
+	)
+	for i := range nodes {
+		node := &nodes[i]
+
+		pool := defaultNodePool
+		if len(poolLabel) > 0 && len(node.Labels[poolLabel]) > 0 {
+			pool = node.Labels[poolLabel]
+		}
+		if versionsOfPools[pool] == nil {
+			versionsOfPools[pool] = map[string]*DaemonVersionStatus{}
+			liveVersionsOfPools[pool] = sets.NewString()
+		}
+
+		version := globalutils.PickFirstNonEmptyString(node.Annotations[constants.AnnotationDaemonVersion], unreportedDaemonVersion)
+		versionStatus := versionsOfPools[pool][version]
+		if versionStatus == nil {
+			versionStatus = &DaemonVersionStatus{Version: version}
+			versionsOfPools[pool][version] = versionStatus
+		}
+
+		versionStatus.Nodes++
+		if !globalutils.ParseBoolOrDefault(node.Annotations[constants.AnnotationDaemonHealthy], true) {
+			versionStatus.UnhealthyNodes++
+			versionStatus.UnhealthyNodeNames = append(versionStatus.UnhealthyNodeNames, node.Name)
+			continue
+		}
+
+		if heartbeatTimeout <= 0 {
+			continue
+		}
+		if isDaemonHeartbeatStale(node, heartbeatTimeout, now) {
+			staleNodesOfPools[pool] = append(staleNodesOfPools[pool], node)
+		} else {
+			liveVersionsOfPools[pool].Insert(version)
+		}
+	}
+
+	for pool, staleNodes := range staleNodesOfPools {
+		for _, node := range staleNodes {
+			version := globalutils.PickFirstNonEmptyString(node.Annotations[constants.AnnotationDaemonVersion], unreportedDaemonVersion)
+			versionStatus := versionsOfPools[pool][version]
+			versionStatus.StaleNodes++
+
+			// no daemon of another version is running, e.g., daemons of old versions never report
+			liveVersions := liveVersionsOfPools[pool]
+			if liveVersions.Len() == 0 || liveVersions.Len() == 1 && liveVersions.Has(version) {
+				continue
+			}
+
+			versionStatus.UnhealthyNodes++
+			versionStatus.UnhealthyNodeNames = append(versionStatus.UnhealthyNodeNames, node.Name)
+		}
+	}
+
+	var pools = map[string]*DaemonPoolStatus{}
+	for pool, versions := range versionsOfPools {
+		status := &DaemonPoolStatus{Ready: true}
+		for _, versionStatus := range versions {
+			sort.Strings(versionStatus.UnhealthyNodeNames)
+			if len(versionStatus.UnhealthyNodeNames) > maxUnhealthyNodesInStatus {
+				versionStatus.UnhealthyNodeNames = versionStatus.UnhealthyNodeNames[:maxUnhealthyNodesInStatus]
+			}
+			status.Versions = append(status.Versions, *versionStatus)
+		}
+		sort.Slice(status.Versions, func(i, j int) bool {
+			return status.Versions[i].Version < status.Versions[j].Version
+		})
+
+		for _, versionStatus := range status.Versions {
+			if versionStatus.UnhealthyNodes > maxUnhealthy {
+				status.Ready = false
+				status.Message = fmt.Sprintf("daemons of version %s fail self-checks or stop reporting on %d nodes, more than %d",
+					versionStatus.Version, versionStatus.UnhealthyNodes, maxUnhealthy)
+				break
+			}
+		}
+		pools[pool] = status
+	}
+	return pools
+}
+
+// isDaemonHeartbeatStale checks if daemon on node never reports a heartbeat, or its last heartbeat is
+// older than timeout
+func isDaemonHeartbeatStale(node *corev1.Node, timeout time.Duration, now time.Time) bool {
+	heartbeat, err := time.Parse(time.RFC3339, node.Annotations[constants.AnnotationDaemonHeartbeat])
+	if err != nil {
+		return true
+	}
+	return now.Sub(heartbeat) > timeout
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DaemonRolloutReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	annotationKeys := []string{constants.AnnotationDaemonVersion, constants.AnnotationDaemonHealthy}
+	var nodeChangedPredicate predicate.Predicate = &utils.SpecifiedAnnotationChangedPredicate{AnnotationKeys: annotationKeys}
+	if len(r.Options.NodePoolLabel) > 0 {
+		nodeChangedPredicate = predicate.Or(nodeChangedPredicate,
+			&utils.SpecifiedLabelChangedPredicate{LabelKeys: []string{r.Options.NodePoolLabel}})
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(ControllerDaemonRollout).
+		Watches(&source.Kind{Type: &corev1.Node{}},
+			handler.EnqueueRequestsFromMapFunc(func(client.Object) []reconcile.Request {
+				return []reconcile.Request{
+					{NamespacedName: types.NamespacedName{Name: ControllerDaemonRollout}},
+				}
+			}),
+			builder.WithPredicates(nodeChangedPredicate),
+		).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.Max(),
+			RecoverPanic:            true,
+		}).
+		Complete(r)
+}
@@ -0,0 +1,188 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/alibaba/hybridnet/pkg/constants"
+)
+
+func TestSummarizeDaemonPools(t *testing.T) {
+	now := time.Now()
+	newNode := func(name, pool, version, healthy string) corev1.Node {
+		node := corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Labels:      map[string]string{},
+				Annotations: map[string]string{},
+			},
+		}
+		if len(pool) > 0 {
+			node.Labels["pool"] = pool
+		}
+		if len(version) > 0 {
+			node.Annotations[constants.AnnotationDaemonVersion] = version
+			node.Annotations[constants.AnnotationDaemonHealthy] = healthy
+			node.Annotations[constants.AnnotationDaemonHeartbeat] = now.Add(-time.Minute).Format(time.RFC3339)
+		}
+		return node
+	}
+	newStaleNode := func(name, pool, version string) corev1.Node {
+		node := newNode(name, pool, version, "true")
+		node.Annotations[constants.AnnotationDaemonHeartbeat] = now.Add(-time.Hour).Format(time.RFC3339)
+		return node
+	}
+
+	tests := []struct {
+		name             string
+		nodes            []corev1.Node
+		poolLabel        string
+		maxUnhealthy     int
+		heartbeatTimeout time.Duration
+		expected         map[string]*DaemonPoolStatus
+	}{
+		{
+			name: "all healthy in default pool",
+			nodes: []corev1.Node{
+				newNode("node1", "a", "v1", "true"),
+				newNode("node2", "b", "v2", "true"),
+				newNode("node3", "", "", ""),
+			},
+			expected: map[string]*DaemonPoolStatus{
+				defaultNodePool: {
+					Ready: true,
+					Versions: []DaemonVersionStatus{
+						{Version: unreportedDaemonVersion, Nodes: 1},
+						{Version: "v1", Nodes: 1},
+						{Version: "v2", Nodes: 1},
+					},
+				},
+			},
+		},
+		{
+			name: "new version fails in one pool",
+			nodes: []corev1.Node{
+				newNode("node1", "a", "v1", "true"),
+				newNode("node2", "a", "v2", "false"),
+				newNode("node3", "b", "v1", "true"),
+				newNode("node4", "", "v2", "true"),
+			},
+			poolLabel: "pool",
+			expected: map[string]*DaemonPoolStatus{
+				"a": {
+					Ready:   false,
+					Message: "daemons of version v2 fail self-checks or stop reporting on 1 nodes, more than 0",
+					Versions: []DaemonVersionStatus{
+						{Version: "v1", Nodes: 1},
+						{Version: "v2", Nodes: 1, UnhealthyNodes: 1, UnhealthyNodeNames: []string{"node2"}},
+					},
+				},
+				"b": {
+					Ready: true,
+					Versions: []DaemonVersionStatus{
+						{Version: "v1", Nodes: 1},
+					},
+				},
+				defaultNodePool: {
+					Ready: true,
+					Versions: []DaemonVersionStatus{
+						{Version: "v2", Nodes: 1},
+					},
+				},
+			},
+		},
+		{
+			name: "unhealthy nodes within tolerance",
+			nodes: []corev1.Node{
+				newNode("node2", "a", "v2", "false"),
+				newNode("node1", "a", "v2", "false"),
+				newNode("node3", "a", "v2", "true"),
+			},
+			poolLabel:    "pool",
+			maxUnhealthy: 2,
+			expected: map[string]*DaemonPoolStatus{
+				"a": {
+					Ready: true,
+					Versions: []DaemonVersionStatus{
+						{Version: "v2", Nodes: 3, UnhealthyNodes: 2, UnhealthyNodeNames: []string{"node1", "node2"}},
+					},
+				},
+			},
+		},
+		{
+			name: "stale daemons of old version after new version reports",
+			nodes: []corev1.Node{
+				newStaleNode("node1", "a", "v1"),
+				newNode("node2", "a", "v2", "true"),
+				newNode("node3", "a", "", ""),
+				newStaleNode("node4", "b", "v1"),
+				newNode("node5", "b", "v1", "true"),
+			},
+			poolLabel:        "pool",
+			heartbeatTimeout: 15 * time.Minute,
+			expected: map[string]*DaemonPoolStatus{
+				"a": {
+					Ready:   false,
+					Message: "daemons of version unreported fail self-checks or stop reporting on 1 nodes, more than 0",
+					Versions: []DaemonVersionStatus{
+						{Version: unreportedDaemonVersion, Nodes: 1, StaleNodes: 1, UnhealthyNodes: 1, UnhealthyNodeNames: []string{"node3"}},
+						{Version: "v1", Nodes: 1, StaleNodes: 1, UnhealthyNodes: 1, UnhealthyNodeNames: []string{"node1"}},
+						{Version: "v2", Nodes: 1},
+					},
+				},
+				"b": {
+					Ready: true,
+					Versions: []DaemonVersionStatus{
+						{Version: "v1", Nodes: 2, StaleNodes: 1},
+					},
+				},
+			},
+		},
+		{
+			name: "stale daemons without another version",
+			nodes: []corev1.Node{
+				newStaleNode("node1", "", "v1"),
+				newNode("node2", "", "", ""),
+			},
+			heartbeatTimeout: 15 * time.Minute,
+			expected: map[string]*DaemonPoolStatus{
+				defaultNodePool: {
+					Ready: true,
+					Versions: []DaemonVersionStatus{
+						{Version: unreportedDaemonVersion, Nodes: 1, StaleNodes: 1},
+						{Version: "v1", Nodes: 1, StaleNodes: 1},
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pools := summarizeDaemonPools(test.nodes, test.poolLabel, test.maxUnhealthy, test.heartbeatTimeout, now)
+			if !reflect.DeepEqual(pools, test.expected) {
+				t.Errorf("test %s fails, expected %+v but got %+v", test.name, test.expected, pools)
+			}
+		})
+	}
+}
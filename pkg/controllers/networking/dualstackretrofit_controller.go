@@ -0,0 +1,380 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/controllers/concurrency"
+	"github.com/alibaba/hybridnet/pkg/controllers/utils"
+	ipamtypes "github.com/alibaba/hybridnet/pkg/ipam/types"
+	"github.com/alibaba/hybridnet/pkg/managerconfig"
+	globalutils "github.com/alibaba/hybridnet/pkg/utils"
+)
+
+const ControllerDualStackRetrofit = "DualStackRetrofit"
+
+const (
+	defaultDualStackRetrofitBatchSize = 1
+	defaultDualStackRetrofitInterval  = time.Minute
+)
+
+// DualStackRetrofitProgress is the progress of a retrofitted namespace, only pods on the networks
+// with IPv6 subnets are counted
+type DualStackRetrofitProgress struct {
+	// DualStack is the count of pods which have both IPv4 and IPv6 addresses
+	DualStack int `json:"dualStack"`
+	// Pending is the count of IPv4-only pods which will be restarted
+	Pending int `json:"pending"`
+	// Skipped is the count of IPv4-only pods which will not be dual-stack after restart, e.g., pods
+	// of StatefulSets retaining IPs, or pods whose workloads specify IPv4 family
+	Skipped int `json:"skipped"`
+}
+
+// DualStackRetrofitReconciler restarts the running IPv4-only pods of retrofitted namespaces by eviction
+// at a controlled rate after IPv6 subnets are added to their networks, so that the pods recreated by
+// their workloads are allocated with dual-stack addresses. CNI can not add addresses to running
+// containers, so restart is the only way to retrofit.
+type DualStackRetrofitReconciler struct {
+	client.Client
+
+	// APIReader fetches the pod templates of workloads, which are not cached by manager
+	APIReader client.Reader
+
+	// KubeClient is used for eviction, which is a sub-resource of pod
+	KubeClient kubernetes.Interface
+	Recorder   record.EventRecorder
+
+	// Config provides the max count of pods restarted in one batch and the
+	// interval between two batches, which can be changed at runtime
+	Config *managerconfig.Store
+
+	// lastBatchTime records the time of last restart batch for each namespace, because
+	// ip instance events will trigger reconciliation before next batch is due
+	lastBatchTime sync.Map
+
+	concurrency.ControllerConcurrency
+}
+
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=core,resources=pods/eviction,verbs=create
+//+kubebuilder:rbac:groups=apps,resources=replicasets;daemonsets,verbs=get
+
+func (r *DualStackRetrofitReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	log := ctrllog.FromContext(ctx)
+
+	var ns = &corev1.Namespace{}
+
+	defer func() {
+		if err != nil {
+			log.Error(err, "reconciliation fails")
+			if len(ns.UID) > 0 {
+				r.Recorder.Event(ns, corev1.EventTypeWarning, "DualStackRetrofitFail", err.Error())
+			}
+		}
+	}()
+
+	if err = r.Get(ctx, req.NamespacedName, ns); err != nil {
+		return ctrl.Result{}, wrapError("unable to fetch Namespace", client.IgnoreNotFound(err))
+	}
+
+	if ns.DeletionTimestamp != nil || !globalutils.ParseBoolOrDefault(ns.Annotations[constants.AnnotationDualStackRetrofit], false) {
+		r.lastBatchTime.Delete(ns.Name)
+		return ctrl.Result{}, nil
+	}
+
+	progress, pendingPods, err := r.inspect(ctx, ns.Name)
+	if err != nil {
+		return ctrl.Result{}, wrapError("unable to inspect pods of namespace", err)
+	}
+
+	if err = r.updateProgress(ctx, ns, progress); err != nil {
+		return ctrl.Result{}, wrapError("unable to update retrofit progress of namespace", err)
+	}
+
+	if len(pendingPods) == 0 {
+		r.lastBatchTime.Delete(ns.Name)
+		return ctrl.Result{}, nil
+	}
+
+	if last, ok := r.lastBatchTime.Load(ns.Name); ok {
+		if wait := r.interval() - time.Since(last.(time.Time)); wait > 0 {
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+	}
+	r.lastBatchTime.Store(ns.Name, time.Now())
+
+	var restarted int
+	for _, pod := range pendingPods {
+		if restarted >= r.batchSize() {
+			break
+		}
+
+		if err = r.KubeClient.CoreV1().Pods(pod.Namespace).EvictV1(ctx, &policyv1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      pod.Name,
+				Namespace: pod.Namespace,
+			},
+		}); err != nil {
+			switch {
+			case apierrors.IsNotFound(err):
+				continue
+			case apierrors.IsTooManyRequests(err):
+				// eviction is blocked by pod disruption budget, try again in next batch
+				log.V(1).Info("eviction is blocked by disruption budget", "pod", pod.Name)
+				continue
+			default:
+				return ctrl.Result{}, wrapError(fmt.Sprintf("unable to evict pod %s", pod.Name), err)
+			}
+		}
+
+		r.Recorder.Eventf(ns, corev1.EventTypeNormal, "RestartPod", "restart pod %s for dual-stack retrofit", pod.Name)
+		restarted++
+	}
+
+	log.V(1).Info(fmt.Sprintf("%d pods are pending for dual-stack retrofit, %d are restarted in this batch",
+		len(pendingPods), restarted))
+	return ctrl.Result{RequeueAfter: r.interval()}, nil
+}
+
+// inspect counts the pods of namespace on networks with IPv6 subnets, and returns the running
+// IPv4-only pods which will be dual-stack after restart
+func (r *DualStackRetrofitReconciler) inspect(ctx context.Context, namespace string) (*DualStackRetrofitProgress, []*corev1.Pod, error) {
+	ipInstances, err := utils.ListAllocatedIPInstances(ctx, r, client.InNamespace(namespace))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		podIPv6     = map[string]bool{}
+		podNetworks = map[string]string{}
+	)
+	for _, ipInstance := range ipInstances {
+		podName := ipInstance.Spec.Binding.PodName
+		if len(podName) == 0 {
+			continue
+		}
+		podNetworks[podName] = ipInstance.Spec.Network
+		podIPv6[podName] = podIPv6[podName] || networkingv1.IsIPv6IPInstance(ipInstance)
+	}
+
+	podNames := make([]string, 0, len(podNetworks))
+	for podName := range podNetworks {
+		podNames = append(podNames, podName)
+	}
+	sort.Strings(podNames)
+
+	var (
+		progress      = &DualStackRetrofitProgress{}
+		pendingPods   []*corev1.Pod
+		networkHasV6  = map[string]bool{}
+		networkLoaded = map[string]bool{}
+	)
+	for _, podName := range podNames {
+		networkName := podNetworks[podName]
+		if !networkLoaded[networkName] {
+			var network = &networkingv1.Network{}
+			if err = r.Get(ctx, types.NamespacedName{Name: networkName}, network); client.IgnoreNotFound(err) != nil {
+				return nil, nil, fmt.Errorf("unable to get network %s: %v", networkName, err)
+			}
+			networkHasV6[networkName] = err == nil && network.Status.IPv6Statistics != nil && network.Status.IPv6Statistics.Total > 0
+			networkLoaded[networkName] = true
+		}
+
+		if !networkHasV6[networkName] {
+			continue
+		}
+
+		if podIPv6[podName] {
+			progress.DualStack++
+			continue
+		}
+
+		var pod = &corev1.Pod{}
+		if err = r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: podName}, pod); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, nil, fmt.Errorf("unable to get pod %s: %v", podName, err)
+		}
+
+		retrofittable, err := r.retrofittable(ctx, pod)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to check if pod %s can be retrofitted: %v", podName, err)
+		}
+
+		if !retrofittable {
+			progress.Skipped++
+			continue
+		}
+
+		progress.Pending++
+		// terminating pods are counted as pending until recreated, but never evicted again
+		if pod.DeletionTimestamp == nil && pod.Status.Phase == corev1.PodRunning {
+			pendingPods = append(pendingPods, pod)
+		}
+	}
+
+	return progress, pendingPods, nil
+}
+
+// retrofittable checks if the pod will be recreated with a new identity and allocated with dual-stack
+// addresses after restart, which is resolved in the same way as webhook from its pod template
+func (r *DualStackRetrofitReconciler) retrofittable(ctx context.Context, pod *corev1.Pod) (bool, error) {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil || !strings.HasPrefix(ref.APIVersion, appsv1.GroupName+"/") {
+		return false, nil
+	}
+
+	var template *corev1.PodTemplateSpec
+	switch ref.Kind {
+	case "ReplicaSet":
+		var replicaSet = &appsv1.ReplicaSet{}
+		if err := r.APIReader.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: ref.Name}, replicaSet); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		if replicaSet.UID != ref.UID {
+			return false, nil
+		}
+		template = &replicaSet.Spec.Template
+	case "DaemonSet":
+		var daemonSet = &appsv1.DaemonSet{}
+		if err := r.APIReader.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: ref.Name}, daemonSet); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		if daemonSet.UID != ref.UID {
+			return false, nil
+		}
+		template = &daemonSet.Spec.Template
+	default:
+		// pods of StatefulSets reuse their retained addresses after restart
+		return false, nil
+	}
+
+	// a pod to be created by workload, it never has the annotations patched by webhook
+	var probe = &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            pod.Name,
+			Namespace:       pod.Namespace,
+			Labels:          template.Labels,
+			Annotations:     template.Annotations,
+			OwnerReferences: pod.OwnerReferences,
+		},
+	}
+
+	_, subnetNameStr, _, ipFamily, _, _, err := utils.ParseNetworkConfigOfPodByPriority(ctx, r, probe)
+	if err != nil {
+		return false, err
+	}
+
+	// a single specified subnet can not provide dual-stack addresses
+	if len(subnetNameStr) > 0 && !strings.Contains(subnetNameStr, "/") {
+		return false, nil
+	}
+	return ipFamily == ipamtypes.DualStack, nil
+}
+
+func (r *DualStackRetrofitReconciler) updateProgress(ctx context.Context, ns *corev1.Namespace, progress *DualStackRetrofitProgress) error {
+	progressBytes, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+
+	if ns.Annotations[constants.AnnotationDualStackRetrofitProgress] == string(progressBytes) {
+		return nil
+	}
+
+	nsPatch := client.MergeFrom(ns.DeepCopy())
+	ns.Annotations[constants.AnnotationDualStackRetrofitProgress] = string(progressBytes)
+	return r.Patch(ctx, ns, nsPatch)
+}
+
+func (r *DualStackRetrofitReconciler) batchSize() int {
+	if config := r.Config.Get(); config != nil && config.DualStackRetrofitBatchSize > 0 {
+		return config.DualStackRetrofitBatchSize
+	}
+	return defaultDualStackRetrofitBatchSize
+}
+
+func (r *DualStackRetrofitReconciler) interval() time.Duration {
+	if config := r.Config.Get(); config != nil && config.DualStackRetrofitInterval > 0 {
+		return config.DualStackRetrofitInterval
+	}
+	return defaultDualStackRetrofitInterval
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DualStackRetrofitReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(ControllerDualStackRetrofit).
+		For(&corev1.Namespace{},
+			builder.WithPredicates(
+				&utils.IgnoreDeletePredicate{},
+				predicate.NewPredicateFuncs(func(obj client.Object) bool {
+					return len(obj.GetAnnotations()[constants.AnnotationDualStackRetrofit]) > 0
+				}),
+				predicate.Or(
+					&predicate.GenerationChangedPredicate{},
+					&predicate.AnnotationChangedPredicate{},
+				),
+			)).
+		Watches(&source.Kind{Type: &networkingv1.IPInstance{}},
+			handler.EnqueueRequestsFromMapFunc(func(object client.Object) []reconcile.Request {
+				return []reconcile.Request{
+					{
+						NamespacedName: types.NamespacedName{
+							Name: object.GetNamespace(),
+						},
+					},
+				}
+			}),
+			builder.WithPredicates(
+				&utils.IgnoreUpdatePredicate{},
+			),
+		).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.Max(),
+			RecoverPanic:            true,
+		}).
+		Complete(r)
+}
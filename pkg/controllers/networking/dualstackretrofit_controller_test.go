@@ -0,0 +1,351 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/managerconfig"
+)
+
+const retrofitNamespace = "retrofit"
+
+func newRetrofitReplicaSet(name string, annotations map[string]string) *appsv1.ReplicaSet {
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: retrofitNamespace,
+			UID:       types.UID(name + "-uid"),
+		},
+		Spec: appsv1.ReplicaSetSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Annotations: annotations},
+			},
+		},
+	}
+}
+
+func newRetrofitPod(name string, owner metav1.OwnerReference) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       retrofitNamespace,
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func ownerRefOf(kind, name string) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion: "apps/v1",
+		Kind:       kind,
+		Name:       name,
+		UID:        types.UID(name + "-uid"),
+		Controller: pointer.Bool(true),
+	}
+}
+
+func TestDualStackRetrofitRetrofittable(t *testing.T) {
+	dualStack := map[string]string{constants.AnnotationIPFamily: "DualStack"}
+
+	r := &DualStackRetrofitReconciler{
+		Client: newFakeClient(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: retrofitNamespace}},
+			&networkingv1.Network{ObjectMeta: metav1.ObjectMeta{Name: "dual"}},
+			&networkingv1.Subnet{
+				ObjectMeta: metav1.ObjectMeta{Name: "subnet-v4"},
+				Spec: networkingv1.SubnetSpec{
+					Network: "dual",
+					Range:   networkingv1.AddressRange{Version: networkingv1.IPv4, CIDR: "10.0.0.0/24"},
+				},
+			},
+		),
+		APIReader: newFakeClient(
+			newRetrofitReplicaSet("rs-dual", dualStack),
+			newRetrofitReplicaSet("rs-v4", map[string]string{constants.AnnotationIPFamily: "IPv4Only"}),
+			newRetrofitReplicaSet("rs-single-subnet", map[string]string{
+				constants.AnnotationIPFamily:        "DualStack",
+				constants.AnnotationSpecifiedSubnet: "subnet-v4",
+			}),
+		),
+	}
+
+	recreatedOwner := ownerRefOf("ReplicaSet", "rs-dual")
+	recreatedOwner.UID = "old-uid"
+
+	tests := []struct {
+		name   string
+		pod    *corev1.Pod
+		expect bool
+	}{
+		{
+			name:   "pod of dual-stack replica set",
+			pod:    newRetrofitPod("dual", ownerRefOf("ReplicaSet", "rs-dual")),
+			expect: true,
+		},
+		{
+			name: "pod of ipv4 replica set",
+			pod:  newRetrofitPod("v4", ownerRefOf("ReplicaSet", "rs-v4")),
+		},
+		{
+			name: "pod specifying a single subnet",
+			pod:  newRetrofitPod("single-subnet", ownerRefOf("ReplicaSet", "rs-single-subnet")),
+		},
+		{
+			name: "pod of recreated replica set",
+			pod:  newRetrofitPod("recreated", recreatedOwner),
+		},
+		{
+			name: "pod of missing replica set",
+			pod:  newRetrofitPod("missing", ownerRefOf("ReplicaSet", "rs-missing")),
+		},
+		{
+			name: "pod of stateful set",
+			pod:  newRetrofitPod("sts-0", ownerRefOf("StatefulSet", "sts")),
+		},
+		{
+			name: "pod without owner",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "bare", Namespace: retrofitNamespace},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := r.retrofittable(context.Background(), test.pod)
+			if err != nil {
+				t.Fatalf("test %s fails, unexpected error: %v", test.name, err)
+			}
+			if result != test.expect {
+				t.Errorf("test %s fails, expected %v but got %v", test.name, test.expect, result)
+			}
+		})
+	}
+}
+
+func TestDualStackRetrofitInspect(t *testing.T) {
+	newIPInstance := func(name, podName, network string, version networkingv1.IPVersion) *networkingv1.IPInstance {
+		return &networkingv1.IPInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: retrofitNamespace},
+			Spec: networkingv1.IPInstanceSpec{
+				Network: network,
+				Address: networkingv1.Address{Version: version},
+				Binding: networkingv1.Binding{PodName: podName},
+			},
+		}
+	}
+
+	stoppedPod := newRetrofitPod("pending-stopped", ownerRefOf("ReplicaSet", "rs-dual"))
+	stoppedPod.Status.Phase = corev1.PodPending
+
+	r := &DualStackRetrofitReconciler{
+		Client: newFakeClient(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: retrofitNamespace}},
+			&networkingv1.Network{
+				ObjectMeta: metav1.ObjectMeta{Name: "dual"},
+				Status:     networkingv1.NetworkStatus{IPv6Statistics: &networkingv1.Count{Total: 10}},
+			},
+			&networkingv1.Network{ObjectMeta: metav1.ObjectMeta{Name: "v4"}},
+			newRetrofitPod("dual-a", ownerRefOf("ReplicaSet", "rs-dual")),
+			newIPInstance("10-0-0-1", "dual-a", "dual", networkingv1.IPv4),
+			newIPInstance("fd00--1", "dual-a", "dual", networkingv1.IPv6),
+			newRetrofitPod("pending-a", ownerRefOf("ReplicaSet", "rs-dual")),
+			newIPInstance("10-0-0-2", "pending-a", "dual", networkingv1.IPv4),
+			stoppedPod,
+			newIPInstance("10-0-0-3", "pending-stopped", "dual", networkingv1.IPv4),
+			newRetrofitPod("sts-0", ownerRefOf("StatefulSet", "sts")),
+			newIPInstance("10-0-0-4", "sts-0", "dual", networkingv1.IPv4),
+			newRetrofitPod("v4-a", ownerRefOf("ReplicaSet", "rs-dual")),
+			newIPInstance("10-0-1-1", "v4-a", "v4", networkingv1.IPv4),
+			newIPInstance("10-0-0-5", "gone", "dual", networkingv1.IPv4),
+		),
+		APIReader: newFakeClient(
+			newRetrofitReplicaSet("rs-dual", map[string]string{constants.AnnotationIPFamily: "DualStack"}),
+		),
+	}
+
+	progress, pendingPods, err := r.inspect(context.Background(), retrofitNamespace)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectProgress := &DualStackRetrofitProgress{DualStack: 1, Pending: 2, Skipped: 1}
+	if !reflect.DeepEqual(progress, expectProgress) {
+		t.Errorf("expected progress %+v but got %+v", expectProgress, progress)
+	}
+
+	var pendingPodNames []string
+	for _, pod := range pendingPods {
+		pendingPodNames = append(pendingPodNames, pod.Name)
+	}
+	// pods not running are counted as pending but never evicted
+	if expect := []string{"pending-a"}; !reflect.DeepEqual(pendingPodNames, expect) {
+		t.Errorf("expected pending pods %v but got %v", expect, pendingPodNames)
+	}
+}
+
+func TestDualStackRetrofitBatch(t *testing.T) {
+	newObjects := func() []client.Object {
+		objects := []client.Object{
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:        retrofitNamespace,
+				Annotations: map[string]string{constants.AnnotationDualStackRetrofit: "true"},
+			}},
+			&networkingv1.Network{
+				ObjectMeta: metav1.ObjectMeta{Name: "dual"},
+				Status:     networkingv1.NetworkStatus{IPv6Statistics: &networkingv1.Count{Total: 10}},
+			},
+		}
+		for _, name := range []string{"pod-a", "pod-b", "pod-c"} {
+			objects = append(objects,
+				newRetrofitPod(name, ownerRefOf("ReplicaSet", "rs-dual")),
+				&networkingv1.IPInstance{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: retrofitNamespace},
+					Spec: networkingv1.IPInstanceSpec{
+						Network: "dual",
+						Address: networkingv1.Address{Version: networkingv1.IPv4},
+						Binding: networkingv1.Binding{PodName: name},
+					},
+				})
+		}
+		return objects
+	}
+
+	// evicted pods are removed at once, as if they were recreated later
+	newKubeClient := func(c client.Client, blocked map[string]bool) (*kubefake.Clientset, *[]string) {
+		var evicted []string
+		kubeClient := kubefake.NewSimpleClientset()
+		kubeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			eviction, ok := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+			if !ok {
+				return false, nil, nil
+			}
+			if blocked[eviction.Name] {
+				return true, nil, apierrors.NewTooManyRequests("disruption budget", 0)
+			}
+			evicted = append(evicted, eviction.Name)
+			return true, nil, c.Delete(context.Background(), &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Namespace: eviction.Namespace, Name: eviction.Name},
+			})
+		})
+		return kubeClient, &evicted
+	}
+
+	newReconciler := func(batchSize int, blocked map[string]bool) (*DualStackRetrofitReconciler, *[]string) {
+		c := newFakeClient(newObjects()...)
+		kubeClient, evicted := newKubeClient(c, blocked)
+		return &DualStackRetrofitReconciler{
+			Client: c,
+			APIReader: newFakeClient(
+				newRetrofitReplicaSet("rs-dual", map[string]string{constants.AnnotationIPFamily: "DualStack"}),
+			),
+			KubeClient: kubeClient,
+			Recorder:   record.NewFakeRecorder(10),
+			Config: managerconfig.NewStore(managerconfig.Configuration{
+				DualStackRetrofitBatchSize: batchSize,
+				DualStackRetrofitInterval:  time.Minute,
+			}),
+		}, evicted
+	}
+
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Name: retrofitNamespace}}
+
+	t.Run("batches are paced by interval", func(t *testing.T) {
+		r, evicted := newReconciler(2, nil)
+
+		result, err := r.Reconcile(context.Background(), request)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if expect := []string{"pod-a", "pod-b"}; !reflect.DeepEqual(*evicted, expect) {
+			t.Errorf("expected evicted pods %v but got %v", expect, *evicted)
+		}
+		if result.RequeueAfter != time.Minute {
+			t.Errorf("expected requeue after %v but got %v", time.Minute, result.RequeueAfter)
+		}
+
+		ns := &corev1.Namespace{}
+		if err = r.Get(context.Background(), types.NamespacedName{Name: retrofitNamespace}, ns); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if expect := `{"dualStack":0,"pending":3,"skipped":0}`; ns.Annotations[constants.AnnotationDualStackRetrofitProgress] != expect {
+			t.Errorf("expected progress %s but got %s", expect, ns.Annotations[constants.AnnotationDualStackRetrofitProgress])
+		}
+
+		// next batch is not due yet
+		if result, err = r.Reconcile(context.Background(), request); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(*evicted) != 2 {
+			t.Errorf("expected no eviction before next batch but got %v", *evicted)
+		}
+		if result.RequeueAfter <= 0 || result.RequeueAfter > time.Minute {
+			t.Errorf("expected requeue before next batch but got %v", result.RequeueAfter)
+		}
+
+		r.lastBatchTime.Store(retrofitNamespace, time.Now().Add(-time.Minute))
+		if _, err = r.Reconcile(context.Background(), request); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if expect := []string{"pod-a", "pod-b", "pod-c"}; !reflect.DeepEqual(*evicted, expect) {
+			t.Errorf("expected evicted pods %v but got %v", expect, *evicted)
+		}
+
+		// all pods are retrofitted
+		if result, err = r.Reconcile(context.Background(), request); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.RequeueAfter != 0 {
+			t.Errorf("expected no requeue but got %v", result.RequeueAfter)
+		}
+		if _, exist := r.lastBatchTime.Load(retrofitNamespace); exist {
+			t.Errorf("expected last batch time to be cleaned")
+		}
+	})
+
+	t.Run("pods blocked by disruption budget are skipped", func(t *testing.T) {
+		r, evicted := newReconciler(1, map[string]bool{"pod-a": true})
+
+		if _, err := r.Reconcile(context.Background(), request); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if expect := []string{"pod-b"}; !reflect.DeepEqual(*evicted, expect) {
+			t.Errorf("expected evicted pods %v but got %v", expect, *evicted)
+		}
+	})
+}
@@ -20,11 +20,14 @@ import (
 	"context"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
 	"github.com/alibaba/hybridnet/pkg/constants"
 	"github.com/alibaba/hybridnet/pkg/controllers/utils"
 	"github.com/alibaba/hybridnet/pkg/ipam"
 	"github.com/alibaba/hybridnet/pkg/ipam/manager"
+	"github.com/alibaba/hybridnet/pkg/ipam/notifier"
 	"github.com/alibaba/hybridnet/pkg/ipam/store"
 	"github.com/alibaba/hybridnet/pkg/ipam/types"
 	ipamtypes "github.com/alibaba/hybridnet/pkg/ipam/types"
@@ -93,6 +96,10 @@ func IPSetGetter(ctx context.Context, c client.Reader) manager.IPSetGetter {
 		ipSet := ipamtypes.NewIPSet()
 		for i := range ipList.Items {
 			ip := &ipList.Items[i]
+			// addresses of ip instances created by users are only in use after being adopted
+			if networkingv1.IsAdoptedIPInstance(ip) && !controllerutil.ContainsFinalizer(ip, constants.FinalizerIPAllocated) {
+				continue
+			}
 			ipSet.Add(utils.ToIPFormat(ip.Name), transform.TransferIPInstanceForIPAM(ip))
 		}
 		return ipSet, nil
@@ -106,3 +113,8 @@ type IPAMStore interface {
 func NewIPAMStore(c client.Client) IPAMStore {
 	return store.NewCRDStore(c)
 }
+
+// IPAMNotifier sends address allocation events to third-party systems
+type IPAMNotifier interface {
+	Notify(events ...notifier.Event)
+}
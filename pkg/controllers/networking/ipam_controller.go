@@ -18,9 +18,11 @@ package networking
 
 import (
 	"context"
+	"sync"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -49,6 +51,14 @@ type IPAMReconciler struct {
 	NetworkStatusUpdateChan chan<- event.GenericEvent
 	SubnetStatusUpdateChan  chan<- event.GenericEvent
 
+	// dirtySubnets records changed subnets of each network, so that only these
+	// subnets will be refreshed if network itself is unchanged
+	dirtySubnetsLock sync.Mutex
+	dirtySubnets     map[string]sets.String
+
+	// refreshedGenerations records the generation of each network in last full refresh
+	refreshedGenerations sync.Map
+
 	concurrency.ControllerConcurrency
 }
 
@@ -59,17 +69,70 @@ type IPAMReconciler struct {
 func (r *IPAMReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := ctrllog.FromContext(ctx)
 
-	if err := r.IPAMManager.Refresh(ipamtypes.RefreshNetworks{req.Name}); err != nil {
+	var (
+		refreshOptions      = []ipamtypes.RefreshOption{ipamtypes.RefreshNetworks{req.Name}}
+		subnetNames         = r.popDirtySubnets(req.Name)
+		generation, changed = r.networkChanged(ctx, req.Name)
+	)
+	if !changed && len(subnetNames) > 0 {
+		refreshOptions = append(refreshOptions, ipamtypes.RefreshSubnets(subnetNames))
+	}
+
+	if err := r.IPAMManager.Refresh(refreshOptions...); err != nil {
 		log.Error(err, "unable to refresh IPAM Manager", "network", req.Name)
+		// make sure that the retry is a full refresh
+		r.refreshedGenerations.Delete(req.Name)
 		return ctrl.Result{}, err
 	}
 
+	if changed {
+		if generation > 0 {
+			r.refreshedGenerations.Store(req.Name, generation)
+		} else {
+			r.refreshedGenerations.Delete(req.Name)
+		}
+	}
+
 	// update statuses after refreshing network asynchronously
 	go r.updateStatus(ctx, req.Name)
 
 	return ctrl.Result{}, nil
 }
 
+// networkChanged returns the current generation of network, and whether it differs from
+// the one of last full refresh, missing network always needs a full refresh
+func (r *IPAMReconciler) networkChanged(ctx context.Context, networkName string) (int64, bool) {
+	network, err := utils.GetNetwork(ctx, r, networkName)
+	if err != nil {
+		return 0, true
+	}
+
+	last, ok := r.refreshedGenerations.Load(networkName)
+	return network.Generation, !ok || last.(int64) != network.Generation
+}
+
+func (r *IPAMReconciler) markDirtySubnet(networkName, subnetName string) {
+	r.dirtySubnetsLock.Lock()
+	defer r.dirtySubnetsLock.Unlock()
+
+	if r.dirtySubnets == nil {
+		r.dirtySubnets = map[string]sets.String{}
+	}
+	if _, exist := r.dirtySubnets[networkName]; !exist {
+		r.dirtySubnets[networkName] = sets.NewString()
+	}
+	r.dirtySubnets[networkName].Insert(subnetName)
+}
+
+func (r *IPAMReconciler) popDirtySubnets(networkName string) []string {
+	r.dirtySubnetsLock.Lock()
+	defer r.dirtySubnetsLock.Unlock()
+
+	subnetNames := r.dirtySubnets[networkName].List()
+	delete(r.dirtySubnets, networkName)
+	return subnetNames
+}
+
 // updateStatus will trigger some status reconciliations related to a network
 func (r *IPAMReconciler) updateStatus(ctx context.Context, networkName string) {
 	r.updateNetworkStatus(networkName)
@@ -124,6 +187,7 @@ func (r *IPAMReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				if !ok {
 					return nil
 				}
+				r.markDirtySubnet(subnet.Spec.Network, subnet.Name)
 				return []reconcile.Request{
 					{
 						NamespacedName: types.NamespacedName{
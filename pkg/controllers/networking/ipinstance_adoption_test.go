@@ -0,0 +1,116 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	ipamtypes "github.com/alibaba/hybridnet/pkg/ipam/types"
+)
+
+func TestIPInstanceAdopt(t *testing.T) {
+	newIPInstance := func(nodeName string) *networkingv1.IPInstance {
+		return &networkingv1.IPInstance{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "192-168-0-10",
+				Namespace: "default",
+				Labels:    map[string]string{constants.LabelIPAdoption: "false"},
+			},
+			Spec: networkingv1.IPInstanceSpec{
+				Network: "network1",
+				Subnet:  "subnet1",
+				Address: networkingv1.Address{IP: "192.168.0.10/24", Version: networkingv1.IPv4},
+				Binding: networkingv1.Binding{
+					PodName:  "pod1",
+					NodeName: nodeName,
+				},
+			},
+		}
+	}
+	adopted := func(ipInstance *networkingv1.IPInstance) *networkingv1.IPInstance {
+		ipInstance.Labels[constants.LabelIPAdoption] = "true"
+		return ipInstance
+	}
+
+	tests := []struct {
+		name            string
+		ipInstance      *networkingv1.IPInstance
+		trackErr        error
+		expectErr       bool
+		expectTracked   []string
+		expectFinalizer bool
+	}{
+		{
+			name:            "adopted ip instance",
+			ipInstance:      adopted(newIPInstance("")),
+			expectTracked:   []string{"192.168.0.10"},
+			expectFinalizer: true,
+		},
+		{
+			name:       "adopted ip instance of address in use",
+			ipInstance: adopted(newIPInstance("")),
+			trackErr:   ipamtypes.ErrNotAvailableAssignedIP,
+			expectErr:  true,
+		},
+		{
+			name:          "allocated ip instance",
+			ipInstance:    newIPInstance("node1"),
+			expectTracked: []string{"192.168.0.10"},
+		},
+		{
+			name:       "allocated ip instance being rebound",
+			ipInstance: newIPInstance("node1"),
+			trackErr:   ipamtypes.ErrNotAvailableAssignedIP,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ipamManager := &fakeIPAMManager{trackErr: test.trackErr}
+			r := &IPInstanceReconciler{
+				Client:      newFakeClient(test.ipInstance),
+				IPAMManager: ipamManager,
+			}
+
+			request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: test.ipInstance.Name}}
+			if _, err := r.Reconcile(context.Background(), request); (err != nil) != test.expectErr {
+				t.Fatalf("test %s fails, expected error %t but got %v", test.name, test.expectErr, err)
+			}
+
+			if !reflect.DeepEqual(ipamManager.tracked, test.expectTracked) {
+				t.Errorf("test %s fails, expected tracked %v but got %v", test.name, test.expectTracked, ipamManager.tracked)
+			}
+
+			ipInstance := &networkingv1.IPInstance{}
+			if err := r.Get(context.Background(), request.NamespacedName, ipInstance); err != nil {
+				t.Fatalf("test %s fails, unable to get ip instance: %v", test.name, err)
+			}
+			if finalizer := controllerutil.ContainsFinalizer(ipInstance, constants.FinalizerIPAllocated); finalizer != test.expectFinalizer {
+				t.Errorf("test %s fails, expected finalizer %t but got %t", test.name, test.expectFinalizer, finalizer)
+			}
+		})
+	}
+}
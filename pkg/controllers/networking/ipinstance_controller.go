@@ -18,17 +18,24 @@ package networking
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
 	"github.com/alibaba/hybridnet/pkg/controllers/concurrency"
 	"github.com/alibaba/hybridnet/pkg/controllers/utils"
+	"github.com/alibaba/hybridnet/pkg/ipam/notifier"
 	"github.com/alibaba/hybridnet/pkg/ipam/types"
+	"github.com/alibaba/hybridnet/pkg/utils/transform"
 )
 
 const ControllerIPInstance = "IPInstance"
@@ -42,6 +49,9 @@ type IPInstanceReconciler struct {
 	IPAMManager IPAMManager
 	IPAMStore   IPAMStore
 
+	// IPAMNotifier is notified of released addresses if not nil
+	IPAMNotifier IPAMNotifier
+
 	concurrency.ControllerConcurrency
 }
 
@@ -59,12 +69,98 @@ func (r *IPInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	if !ip.DeletionTimestamp.IsZero() {
 		r.PodIPCache.ReleaseIP(ip.Name, ip.Namespace)
 
+		// address must not be reallocated until the dataplane of it is cleaned on node, removal of
+		// the finalizer will trigger reconciliation again
+		if controllerutil.ContainsFinalizer(&ip, constants.FinalizerDataplaneCleanup) {
+			return ctrl.Result{}, nil
+		}
+
 		if err = r.releaseIP(ctx, &ip); err != nil {
 			return ctrl.Result{}, wrapError("unable to release IPInstance", err)
 		}
+		return ctrl.Result{}, nil
+	}
+
+	if networkingv1.IsAdoptedIPInstance(&ip) && networkingv1.IsReserved(&ip) {
+		return ctrl.Result{}, wrapError("unable to adopt IPInstance", r.adopt(ctx, &ip))
+	}
+
+	if err = r.track(&ip); err != nil {
+		return ctrl.Result{}, wrapError("unable to track IPInstance in IPAM", err)
+	}
+
+	return ctrl.Result{}, wrapError("unable to sync labels of IPInstance", r.syncLabels(ctx, &ip))
+}
+
+// track records the address of ip instance in IPAM if it is missing, which keeps IPAM consistent with
+// ip instances incrementally, addresses are released by the finalizer of ip instances on deletion
+func (r *IPInstanceReconciler) track(ipInstance *networkingv1.IPInstance) error {
+	// ip instances with empty binding are still being created by IPAM store
+	if len(networkingv1.FetchBindingPodName(ipInstance)) == 0 {
+		return nil
+	}
+
+	err := r.IPAMManager.Track(ipInstance.Spec.Network, transform.TransferIPInstanceForIPAM(ipInstance))
+	if errors.Is(err, types.ErrNotAvailableAssignedIP) {
+		// address is being rebound to another pod in IPAM before the ip instance is updated
+		return nil
+	}
+	return err
+}
+
+// adopt completes the metadata of an ip instance created by users for an address configured out-of-band,
+// which is then reserved in IPAM for the bound pod, and taken over by pod just like a retained one
+func (r *IPInstanceReconciler) adopt(ctx context.Context, ipInstance *networkingv1.IPInstance) error {
+	// the address is taken in IPAM under its lock before anything else, webhook only checks conflicts with
+	// cached ip instances, which misses the addresses allocated but not coupled yet
+	if err := r.IPAMManager.Track(ipInstance.Spec.Network, transform.TransferIPInstanceForIPAM(ipInstance)); err != nil {
+		return wrapError(fmt.Sprintf("unable to take address %s in IPAM", ipInstance.Spec.Address.IP), err)
+	}
+
+	patch := client.MergeFromWithOptions(ipInstance.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	changed := networkingv1.SyncIPInstanceLabels(ipInstance)
+
+	expectedLabels := map[string]string{
+		constants.LabelVersion: networkingv1.IPInstanceLatestVersion,
+		constants.LabelPod:     transform.TransferPodNameForLabelValue(ipInstance.Spec.Binding.PodName),
+	}
+	for key, value := range expectedLabels {
+		if ipInstance.Labels[key] != value {
+			ipInstance.Labels[key] = value
+			changed = true
+		}
 	}
 
-	return ctrl.Result{}, nil
+	// finalizer will block deletion until address is released in IPAM
+	if !controllerutil.ContainsFinalizer(ipInstance, constants.FinalizerIPAllocated) {
+		controllerutil.AddFinalizer(ipInstance, constants.FinalizerIPAllocated)
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	if err := r.Patch(ctx, ipInstance, patch); err != nil {
+		if apierrors.IsNotFound(err) {
+			// ip instance is gone without finalizer, so nothing else will release the address
+			return r.IPAMManager.Release(ipInstance.Spec.Network, []types.SubnetIPSuite{
+				types.ReleaseIPOfSubnet(ipInstance.Spec.Subnet, utils.ToIPFormat(ipInstance.Name)),
+			})
+		}
+		return err
+	}
+	return nil
+}
+
+// syncLabels keeps the labels for listing by node, subnet, network and phase consistent with
+// spec, which also fills labels of ip instances created by older versions
+func (r *IPInstanceReconciler) syncLabels(ctx context.Context, ipInstance *networkingv1.IPInstance) error {
+	patch := client.MergeFromWithOptions(ipInstance.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	if !networkingv1.SyncIPInstanceLabels(ipInstance) {
+		return nil
+	}
+	return client.IgnoreNotFound(r.Patch(ctx, ipInstance, patch))
 }
 
 func (r *IPInstanceReconciler) releaseIP(ctx context.Context, ipInstance *networkingv1.IPInstance) (err error) {
@@ -76,7 +172,13 @@ func (r *IPInstanceReconciler) releaseIP(ctx context.Context, ipInstance *networ
 		return
 	}
 
-	err = r.IPAMStore.IPUnBind(ctx, ipInstance.Namespace, ipInstance.Name)
+	if err = r.IPAMStore.IPUnBind(ctx, ipInstance.Namespace, ipInstance.Name); err != nil {
+		return
+	}
+
+	if r.IPAMNotifier != nil {
+		r.IPAMNotifier.Notify(notifier.ReleaseEvent(ipInstance))
+	}
 	return
 }
 
@@ -0,0 +1,152 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/controllers/concurrency"
+	"github.com/alibaba/hybridnet/pkg/controllers/utils"
+	"github.com/alibaba/hybridnet/pkg/managerconfig"
+)
+
+const ControllerIPInstanceCleanup = "IPInstanceCleanup"
+
+// IPInstanceCleanupReconciler holds the deletion of ip instance until the daemon on its node
+// removes the dataplane of its address, e.g., proxy neighs and bgp paths, and removes the
+// finalizer. The finalizer is removed here instead if no daemon will ever do it.
+type IPInstanceCleanupReconciler struct {
+	client.Client
+
+	Recorder record.EventRecorder
+
+	// Config provides the max duration to wait for daemons, the finalizer will be removed
+	// after timeout to avoid blocking deletion forever
+	Config *managerconfig.Store
+
+	concurrency.ControllerConcurrency
+}
+
+//+kubebuilder:rbac:groups=networking.alibaba.com,resources=ipinstances,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=networking.alibaba.com,resources=ipinstances/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+
+func (r *IPInstanceCleanupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	log := ctrllog.FromContext(ctx)
+
+	var ipInstance = &networkingv1.IPInstance{}
+
+	defer func() {
+		if err != nil {
+			log.Error(err, "reconciliation fails")
+			if len(ipInstance.UID) > 0 {
+				r.Recorder.Event(ipInstance, corev1.EventTypeWarning, "CleanupFail", err.Error())
+			}
+		}
+	}()
+
+	if err = r.Get(ctx, req.NamespacedName, ipInstance); err != nil {
+		return ctrl.Result{}, wrapError("unable to fetch IPInstance", client.IgnoreNotFound(err))
+	}
+
+	// ip instances created by older versions have no finalizer of dataplane cleanup
+	if ipInstance.DeletionTimestamp == nil {
+		return ctrl.Result{}, wrapError("unable to add finalizer to ip instance",
+			utils.AddFinalizer(ctx, r, ipInstance, constants.FinalizerDataplaneCleanup))
+	}
+
+	if !controllerutil.ContainsFinalizer(ipInstance, constants.FinalizerDataplaneCleanup) {
+		return ctrl.Result{}, nil
+	}
+
+	var pending bool
+	if pending, err = r.waitingForDaemon(ctx, ipInstance); err != nil {
+		return ctrl.Result{}, wrapError("unable to check daemon of node", err)
+	}
+
+	if pending {
+		if waited := time.Since(ipInstance.DeletionTimestamp.Time); waited < r.cleanupTimeout() {
+			log.V(1).Info("waiting for dataplane cleanup", "node", ipInstance.Spec.Binding.NodeName)
+			return ctrl.Result{RequeueAfter: dataplaneCleanupCheckInterval}, nil
+		}
+
+		r.Recorder.Eventf(ipInstance, corev1.EventTypeWarning, "CleanupTimeout",
+			"dataplane cleanup is not acknowledged by node %s after %v", ipInstance.Spec.Binding.NodeName, r.cleanupTimeout())
+	}
+
+	return ctrl.Result{}, wrapError("unable to remove finalizer from ip instance",
+		utils.RemoveFinalizer(ctx, r, ipInstance, constants.FinalizerDataplaneCleanup))
+}
+
+// waitingForDaemon checks if the finalizer is expected to be removed by daemon, which only
+// happens on a ready node running daemon, and reserved ip instances have no dataplane at all
+func (r *IPInstanceCleanupReconciler) waitingForDaemon(ctx context.Context, ipInstance *networkingv1.IPInstance) (bool, error) {
+	if networkingv1.IsReserved(ipInstance) {
+		return false, nil
+	}
+
+	var node = &corev1.Node{}
+	if err := r.Get(ctx, types.NamespacedName{Name: ipInstance.Spec.Binding.NodeName}, node); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return nodeIsReady(node) && nodeRunsDaemon(node, time.Now()), nil
+}
+
+func (r *IPInstanceCleanupReconciler) cleanupTimeout() time.Duration {
+	if config := r.Config.Get(); config != nil && config.DataplaneCleanupTimeout > 0 {
+		return config.DataplaneCleanupTimeout
+	}
+	return defaultDataplaneCleanupTimeout
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *IPInstanceCleanupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(ControllerIPInstanceCleanup).
+		For(&networkingv1.IPInstance{},
+			builder.WithPredicates(
+				&utils.IgnoreDeletePredicate{},
+				predicate.Or(
+					&predicate.GenerationChangedPredicate{},
+					&utils.TerminatingPredicate{},
+				),
+			)).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.Max(),
+			RecoverPanic:            true,
+		}).
+		Complete(r)
+}
@@ -0,0 +1,138 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/managerconfig"
+)
+
+func TestIPInstanceCleanupReconcile(t *testing.T) {
+	now := time.Now()
+
+	newIPInstance := func(nodeName string, deletedAt time.Time) *networkingv1.IPInstance {
+		ipInstance := &networkingv1.IPInstance{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "10-0-0-1",
+				Namespace:  "default",
+				Finalizers: []string{constants.FinalizerIPAllocated},
+			},
+			Spec: networkingv1.IPInstanceSpec{
+				Network: "network1",
+				Subnet:  "subnet1",
+				Binding: networkingv1.Binding{PodName: "pod1", NodeName: nodeName},
+			},
+		}
+		if !deletedAt.IsZero() {
+			ipInstance.DeletionTimestamp = &metav1.Time{Time: deletedAt}
+			ipInstance.Finalizers = append(ipInstance.Finalizers, constants.FinalizerDataplaneCleanup)
+		}
+		return ipInstance
+	}
+
+	tests := []struct {
+		name            string
+		objects         []client.Object
+		expectFinalizer bool
+		expectRequeue   bool
+	}{
+		{
+			name:            "finalizer added to live ip instance",
+			objects:         []client.Object{newIPInstance("node1", time.Time{})},
+			expectFinalizer: true,
+		},
+		{
+			name: "waiting for daemon on node",
+			objects: []client.Object{
+				newIPInstance("node1", now),
+				newCleanupNode("node1", true, now, ""),
+			},
+			expectFinalizer: true,
+			expectRequeue:   true,
+		},
+		{
+			name:            "reserved ip instance",
+			objects:         []client.Object{newIPInstance("", now)},
+			expectFinalizer: false,
+		},
+		{
+			name:            "node not found",
+			objects:         []client.Object{newIPInstance("node1", now)},
+			expectFinalizer: false,
+		},
+		{
+			name: "node not ready",
+			objects: []client.Object{
+				newIPInstance("node1", now),
+				newCleanupNode("node1", false, now, ""),
+			},
+			expectFinalizer: false,
+		},
+		{
+			name: "node not running daemon",
+			objects: []client.Object{
+				newIPInstance("node1", now),
+				newCleanupNode("node1", true, time.Time{}, ""),
+			},
+			expectFinalizer: false,
+		},
+		{
+			name: "cleanup timeout",
+			objects: []client.Object{
+				newIPInstance("node1", now.Add(-2*time.Minute)),
+				newCleanupNode("node1", true, now, ""),
+			},
+			expectFinalizer: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := &IPInstanceCleanupReconciler{
+				Client:   newFakeClient(test.objects...),
+				Recorder: record.NewFakeRecorder(10),
+				Config: managerconfig.NewStore(managerconfig.Configuration{
+					DataplaneCleanupTimeout: time.Minute,
+				}),
+			}
+
+			key := types.NamespacedName{Name: "10-0-0-1", Namespace: "default"}
+			result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if finalizer := hasCleanupFinalizer(t, r.Client, key, &networkingv1.IPInstance{}); finalizer != test.expectFinalizer {
+				t.Errorf("expected finalizer %v but got %v", test.expectFinalizer, finalizer)
+			}
+			if requeue := result.RequeueAfter > 0; requeue != test.expectRequeue {
+				t.Errorf("expected requeue %v but got %v", test.expectRequeue, result.RequeueAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/controllers/concurrency"
+)
+
+const ControllerIPInstanceLease = "IPInstanceLease"
+
+// IPInstanceLeaseReconciler reclaims ip instances whose leases are not renewed by daemon, which protects
+// against leaked allocations when the deletion events of pods are lost
+type IPInstanceLeaseReconciler struct {
+	client.Client
+	APIReader client.Reader
+
+	IPAMStore IPAMStore
+	Duration  time.Duration
+
+	concurrency.ControllerConcurrency
+}
+
+//+kubebuilder:rbac:groups=networking.alibaba.com,resources=ipinstances,verbs=get;list;watch;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get
+
+func (r *IPInstanceLeaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	log := ctrllog.FromContext(ctx)
+
+	defer func() {
+		if err != nil {
+			log.Error(err, "reconciliation fails")
+		}
+	}()
+
+	var ipInstance = &networkingv1.IPInstance{}
+	if err = r.Get(ctx, req.NamespacedName, ipInstance); err != nil {
+		return ctrl.Result{}, wrapError("unable to fetch IPInstance", client.IgnoreNotFound(err))
+	}
+
+	// reserved ip instances are not bound to any pod, so their leases will never be renewed, and ip instances
+	// of IPAM service are bound to its leases instead of pods
+	if !ipInstance.DeletionTimestamp.IsZero() || networkingv1.IsReserved(ipInstance) || isLeasedByIPAMService(ipInstance) {
+		return ctrl.Result{}, nil
+	}
+
+	expireTime, err := time.Parse(time.RFC3339, ipInstance.Annotations[constants.AnnotationLeaseExpireTime])
+	if err != nil {
+		// ip instances allocated before lease mode is enabled carry no lease, grant one
+		// to give daemon a chance to renew it
+		patch := client.MergeFrom(ipInstance.DeepCopy())
+		if ipInstance.Annotations == nil {
+			ipInstance.Annotations = map[string]string{}
+		}
+		ipInstance.Annotations[constants.AnnotationLeaseExpireTime] = time.Now().Add(r.Duration).Format(time.RFC3339)
+		if err = r.Patch(ctx, ipInstance, patch); err != nil {
+			return ctrl.Result{}, wrapError("unable to grant lease to ip instance", client.IgnoreNotFound(err))
+		}
+		return ctrl.Result{RequeueAfter: r.Duration}, nil
+	}
+
+	if remaining := time.Until(expireTime); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	// lease expires, but it may be caused by an unavailable daemon, so only ip instances
+	// whose pods are really gone will be reclaimed
+	podName := types.NamespacedName{
+		Namespace: ipInstance.Namespace,
+		Name:      networkingv1.FetchBindingPodName(ipInstance),
+	}
+	if len(podName.Name) > 0 {
+		var pod = &corev1.Pod{}
+		if err = r.APIReader.Get(ctx, podName, pod); err == nil {
+			if isBoundToPod(ipInstance, pod) || retainedAfterNodeDeletion(ipInstance) {
+				// a recreated stateful pod will take over the retained address
+				return ctrl.Result{RequeueAfter: r.Duration}, nil
+			}
+
+			// pod is recreated with the same name, only the stale ip instance is released, and the ones
+			// of the new pod are untouched
+			if err = client.IgnoreNotFound(r.Delete(ctx, ipInstance)); err != nil {
+				return ctrl.Result{}, wrapError("unable to release stale ip instance", err)
+			}
+			log.Info("reclaim stale ip instance with expired lease", "pod", podName.String(), "uid", ipInstance.Spec.Binding.PodUID)
+			return ctrl.Result{}, nil
+		} else if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, wrapError("unable to fetch pod of ip instance", err)
+		}
+	}
+
+	reserved, err := releaseIPInstancesOfGonePod(ctx, r, r.IPAMStore, ipInstance, podName)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("reclaim ip instance with expired lease", "pod", podName.String(), "reserved", reserved)
+	return ctrl.Result{}, nil
+}
+
+// isBoundToPod checks if ip instance is bound to pod by uid, ip instances without pod uid are created by
+// older versions, which are always considered bound
+func isBoundToPod(ipInstance *networkingv1.IPInstance, pod *corev1.Pod) bool {
+	return len(ipInstance.Spec.Binding.PodUID) == 0 || ipInstance.Spec.Binding.PodUID == pod.UID
+}
+
+// isLeasedByIPAMService checks if ip instance is allocated for a lease of IPAM service
+func isLeasedByIPAMService(ipInstance client.Object) bool {
+	_, leased := ipInstance.GetLabels()[constants.LabelIPLease]
+	return leased
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *IPInstanceLeaseReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(ControllerIPInstanceLease).
+		For(&networkingv1.IPInstance{},
+			builder.WithPredicates(
+				&predicate.ResourceVersionChangedPredicate{},
+				predicate.NewPredicateFuncs(func(obj client.Object) bool {
+					return !isLeasedByIPAMService(obj)
+				}),
+			)).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.Max(),
+			RecoverPanic:            true,
+		}).
+		Complete(r)
+}
@@ -0,0 +1,188 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+)
+
+func TestIPInstanceLeaseReconcile(t *testing.T) {
+	const (
+		namespace = "default"
+		duration  = 5 * time.Minute
+	)
+
+	now := time.Now()
+	newIPInstance := func(podName, nodeName, ownerKind string, expireTime *time.Time) *networkingv1.IPInstance {
+		ipInstance := &networkingv1.IPInstance{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "192-168-0-10",
+				Namespace: namespace,
+				OwnerReferences: []metav1.OwnerReference{
+					{APIVersion: "v1", Kind: ownerKind, Name: "owner", UID: "owner-uid", Controller: pointer.Bool(true)},
+				},
+			},
+			Spec: networkingv1.IPInstanceSpec{
+				Binding: networkingv1.Binding{
+					PodName:  podName,
+					PodUID:   "pod-uid",
+					NodeName: nodeName,
+				},
+			},
+		}
+		if expireTime != nil {
+			ipInstance.Annotations = map[string]string{constants.AnnotationLeaseExpireTime: expireTime.Format(time.RFC3339)}
+		}
+		return ipInstance
+	}
+
+	expired := now.Add(-time.Minute)
+	valid := now.Add(2 * time.Minute)
+
+	tests := []struct {
+		name             string
+		ipInstance       *networkingv1.IPInstance
+		podExist         bool
+		podUID           types.UID
+		expectResult     ctrl.Result
+		expectGranted    bool
+		expectDeleted    bool
+		expectDecoupled  []string
+		expectIPReserved []string
+	}{
+		{
+			name:          "ip instance without lease",
+			ipInstance:    newIPInstance("pod1", "node1", "Pod", nil),
+			expectResult:  ctrl.Result{RequeueAfter: duration},
+			expectGranted: true,
+		},
+		{
+			name:         "valid lease",
+			ipInstance:   newIPInstance("pod1", "node1", "Pod", &valid),
+			expectResult: ctrl.Result{RequeueAfter: 2 * time.Minute},
+		},
+		{
+			name:       "reserved ip instance",
+			ipInstance: newIPInstance("pod1", "", "StatefulSet", &expired),
+		},
+		{
+			name:         "expired lease of existing pod",
+			ipInstance:   newIPInstance("pod1", "node1", "Pod", &expired),
+			podExist:     true,
+			expectResult: ctrl.Result{RequeueAfter: duration},
+		},
+		{
+			name:          "expired lease of recreated pod",
+			ipInstance:    newIPInstance("pod1", "node1", "Pod", &expired),
+			podExist:      true,
+			podUID:        "new-pod-uid",
+			expectDeleted: true,
+		},
+		{
+			name:         "expired lease of recreated stateful pod",
+			ipInstance:   newIPInstance("pod1", "node1", "StatefulSet", &expired),
+			podExist:     true,
+			podUID:       "new-pod-uid",
+			expectResult: ctrl.Result{RequeueAfter: duration},
+		},
+		{
+			name: "expired ip instance of IPAM service lease",
+			ipInstance: func() *networkingv1.IPInstance {
+				ipInstance := newIPInstance("lease1", "", "Lease", &expired)
+				ipInstance.Labels = map[string]string{constants.LabelIPLease: "lease1"}
+				return ipInstance
+			}(),
+		},
+		{
+			name:            "expired lease of gone pod",
+			ipInstance:      newIPInstance("pod1", "node1", "Pod", &expired),
+			expectDecoupled: []string{"default/pod1"},
+		},
+		{
+			name:             "expired lease of gone stateful pod",
+			ipInstance:       newIPInstance("pod1", "node1", "StatefulSet", &expired),
+			expectIPReserved: []string{"default/pod1"},
+		},
+		{
+			name:          "expired lease without pod",
+			ipInstance:    newIPInstance("", "node1", "Pod", &expired),
+			expectDeleted: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: namespace, UID: "pod-uid"}}
+			if len(test.podUID) > 0 {
+				pod.UID = test.podUID
+			}
+			apiReader := newFakeClient()
+			if test.podExist {
+				apiReader = newFakeClient(pod)
+			}
+
+			ipamStore := &fakeIPAMStore{}
+			r := &IPInstanceLeaseReconciler{
+				Client:    newFakeClient(test.ipInstance),
+				APIReader: apiReader,
+				IPAMStore: ipamStore,
+				Duration:  duration,
+			}
+
+			request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: test.ipInstance.Name}}
+			result, err := r.Reconcile(context.Background(), request)
+			if err != nil {
+				t.Fatalf("test %s fails, unexpected error: %v", test.name, err)
+			}
+			// requeue duration is rounded since lease is recorded in seconds
+			if result.RequeueAfter.Round(time.Minute) != test.expectResult.RequeueAfter {
+				t.Errorf("test %s fails, expected result %v but got %v", test.name, test.expectResult, result)
+			}
+
+			ipInstance := &networkingv1.IPInstance{}
+			err = r.Get(context.Background(), request.NamespacedName, ipInstance)
+			if deleted := apierrors.IsNotFound(err); deleted != test.expectDeleted {
+				t.Fatalf("test %s fails, expected deleted %v but got error %v", test.name, test.expectDeleted, err)
+			}
+
+			_, granted := ipInstance.Annotations[constants.AnnotationLeaseExpireTime]
+			if !test.expectDeleted && test.ipInstance.Annotations == nil && granted != test.expectGranted {
+				t.Errorf("test %s fails, expected lease granted %v but got %v", test.name, test.expectGranted, granted)
+			}
+
+			if !reflect.DeepEqual(ipamStore.decoupled, test.expectDecoupled) {
+				t.Errorf("test %s fails, expected decoupled pods %v but got %v", test.name, test.expectDecoupled, ipamStore.decoupled)
+			}
+			if !reflect.DeepEqual(ipamStore.ipReserved, test.expectIPReserved) {
+				t.Errorf("test %s fails, expected reserved pods %v but got %v", test.name, test.expectIPReserved, ipamStore.ipReserved)
+			}
+		})
+	}
+}
@@ -0,0 +1,406 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/controllers/concurrency"
+	"github.com/alibaba/hybridnet/pkg/controllers/utils"
+	"github.com/alibaba/hybridnet/pkg/ipam/strategy"
+	ipamtypes "github.com/alibaba/hybridnet/pkg/ipam/types"
+	ipamutils "github.com/alibaba/hybridnet/pkg/ipam/utils"
+	"github.com/alibaba/hybridnet/pkg/utils/transform"
+)
+
+const ControllerIPInstanceRebind = "IPInstanceRebind"
+
+const IndexerFieldRebindPod = "rebindPod"
+
+const ReasonIPRebindSucceed = "IPRebindSucceed"
+
+// IPInstanceRebindReconciler moves IPInstances to the pods requested in spec.rebind. The
+// binding is switched in a single update, and the old node is recorded in a label so that
+// its daemon keeps forwarding to the address until the daemon of new node is ready.
+type IPInstanceRebindReconciler struct {
+	client.Client
+
+	// APIReader resolves the owner chain of target pod, whose owners are not cached
+	APIReader client.Reader
+
+	Recorder    record.EventRecorder
+	PodIPCache  PodIPCache
+	IPAMManager IPAMManager
+
+	concurrency.ControllerConcurrency
+}
+
+//+kubebuilder:rbac:groups=networking.alibaba.com,resources=ipinstances,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+
+func (r *IPInstanceRebindReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	log := ctrllog.FromContext(ctx)
+
+	var ipInstance = &networkingv1.IPInstance{}
+
+	defer func() {
+		if err != nil {
+			log.Error(err, "reconciliation fails")
+		}
+	}()
+
+	if err = r.Get(ctx, req.NamespacedName, ipInstance); err != nil {
+		return ctrl.Result{}, wrapError("unable to fetch IPInstance", client.IgnoreNotFound(err))
+	}
+
+	if !ipInstance.DeletionTimestamp.IsZero() || ipInstance.Spec.Rebind == nil {
+		return ctrl.Result{}, nil
+	}
+
+	var pod = &corev1.Pod{}
+	if err = r.Get(ctx, types.NamespacedName{Namespace: ipInstance.Namespace, Name: ipInstance.Spec.Rebind.PodName}, pod); err != nil {
+		if apierrors.IsNotFound(err) {
+			// wait for target pod, its creation will trigger reconciliation
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, wrapError("unable to fetch target pod", err)
+	}
+
+	if !pod.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, fmt.Errorf("target pod %s is terminating", pod.Name)
+	}
+
+	// wait for scheduling, the binding needs node of target pod
+	if !utils.PodIsScheduled(pod) {
+		return ctrl.Result{}, nil
+	}
+
+	// addresses of all families bound to source pod are moved together, otherwise a dual-stack
+	// pod would be split into two single-stack pods
+	var group []*networkingv1.IPInstance
+	if group, err = r.listRebindGroup(ctx, ipInstance); err != nil {
+		return ctrl.Result{}, wrapError("unable to list ip instances of source pod", err)
+	}
+
+	if err = r.checkTargetPod(ctx, group, pod); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var (
+		sourcePodName   = ipInstance.Spec.Binding.PodName
+		ipInstanceNames []string
+		addresses       []string
+	)
+	if exist, uid, recorded := r.PodIPCache.Get(pod.Name, pod.Namespace); exist && uid == pod.UID {
+		ipInstanceNames = recorded
+	}
+
+	// the requested ip instance is rebound at last, the request is kept for retrying until
+	// all of the group are rebound
+	for _, member := range group {
+		if err = r.rebind(ctx, member, pod); err != nil {
+			return ctrl.Result{}, wrapError(fmt.Sprintf("unable to rebind IPInstance %s", member.Name), err)
+		}
+
+		r.PodIPCache.ReleaseIP(member.Name, member.Namespace)
+		ipInstanceNames = append(ipInstanceNames, member.Name)
+		addresses = append(addresses, member.Spec.Address.IP)
+	}
+	r.PodIPCache.Record(pod.UID, pod.Name, pod.Namespace, ipInstanceNames)
+
+	r.Recorder.Eventf(pod, corev1.EventTypeNormal, ReasonIPRebindSucceed, "rebind IP %s from pod %s successfully",
+		strings.Join(addresses, ","), sourcePodName)
+	return ctrl.Result{}, nil
+}
+
+// listRebindGroup returns the ip instances bound to the same source pod with the requested one,
+// and the requested one is always the last
+func (r *IPInstanceRebindReconciler) listRebindGroup(ctx context.Context, ipInstance *networkingv1.IPInstance) ([]*networkingv1.IPInstance, error) {
+	var binding = ipInstance.Spec.Binding
+	if len(binding.PodName) == 0 {
+		return []*networkingv1.IPInstance{ipInstance}, nil
+	}
+
+	var ipInstanceList = &networkingv1.IPInstanceList{}
+	if err := r.List(ctx, ipInstanceList,
+		client.InNamespace(ipInstance.Namespace),
+		client.MatchingLabels{constants.LabelPod: transform.TransferPodNameForLabelValue(binding.PodName)},
+	); err != nil {
+		return nil, err
+	}
+
+	var group []*networkingv1.IPInstance
+	for i := range ipInstanceList.Items {
+		var sibling = &ipInstanceList.Items[i]
+		if sibling.Name == ipInstance.Name || !sibling.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		if sibling.Spec.Binding.PodName == binding.PodName && sibling.Spec.Binding.PodUID == binding.PodUID {
+			group = append(group, sibling)
+		}
+	}
+	return append(group, ipInstance), nil
+}
+
+// checkTargetPod makes sure the node of target pod belongs to the networks of addresses, and target
+// pod will not get two addresses of the same family
+func (r *IPInstanceRebindReconciler) checkTargetPod(ctx context.Context, group []*networkingv1.IPInstance, pod *corev1.Pod) error {
+	var (
+		node       *corev1.Node
+		groupNames = map[string]bool{}
+	)
+
+	for _, ipInstance := range group {
+		groupNames[ipInstance.Name] = true
+
+		if ipInstance.Spec.Rebind != nil && ipInstance.Spec.Rebind.PodName != pod.Name {
+			return fmt.Errorf("ip instance %s of the same pod is requested to be rebound to another pod %s",
+				ipInstance.Name, ipInstance.Spec.Rebind.PodName)
+		}
+
+		network, err := utils.GetNetwork(ctx, r, ipInstance.Spec.Network)
+		if err != nil {
+			return wrapError("unable to fetch network", err)
+		}
+
+		// underlay addresses are only reachable on the nodes selected by network
+		if networkingv1.GetNetworkType(network) != networkingv1.NetworkTypeUnderlay {
+			continue
+		}
+
+		if node == nil {
+			node = &corev1.Node{}
+			if err = r.Get(ctx, types.NamespacedName{Name: pod.Spec.NodeName}, node); err != nil {
+				return wrapError("unable to fetch node of target pod", err)
+			}
+		}
+
+		if !labels.SelectorFromSet(network.Spec.NodeSelector).Matches(labels.Set(node.Labels)) {
+			return fmt.Errorf("node %s of target pod %s does not belong to network %s", node.Name, pod.Name, network.Name)
+		}
+	}
+
+	allocatedIPInstances, err := utils.ListAllocatedIPInstancesOfPod(ctx, r, pod)
+	if err != nil {
+		return wrapError("unable to list ip instances of target pod", err)
+	}
+
+	for _, allocated := range allocatedIPInstances {
+		if groupNames[allocated.Name] {
+			continue
+		}
+		for _, ipInstance := range group {
+			if allocated.Spec.Address.Version == ipInstance.Spec.Address.Version {
+				return fmt.Errorf("target pod %s already has address %s of the same version", pod.Name, allocated.Spec.Address.IP)
+			}
+		}
+	}
+	return nil
+}
+
+// rebind switches binding in IPAM first, then switches binding, owner and clears the request in
+// a single patch, which will be retried until succeed as the request is still there. IPAM is rolled
+// back if the patch fails, so the address never stays with a pod it is not bound to.
+func (r *IPInstanceRebindReconciler) rebind(ctx context.Context, ipInstance *networkingv1.IPInstance, pod *corev1.Pod) error {
+	var (
+		rebound    = ipInstance.DeepCopy()
+		sourceNode = ipInstance.Spec.Binding.NodeName
+		ip         = utils.ToIPFormat(ipInstance.Name)
+		ipFamily   = ipamtypes.IPv4
+	)
+
+	if networkingv1.IsIPv6IPInstance(ipInstance) {
+		ipFamily = ipamtypes.IPv6
+	}
+
+	// the address is still in use, so it is reserved and then assigned to target pod by force
+	// instead of being released, which may be allocated to others in the meantime
+	if err := r.IPAMManager.Reserve(ipInstance.Spec.Network, []ipamtypes.SubnetIPSuite{
+		ipamtypes.ReserveIPOfSubnet(ipInstance.Spec.Subnet, ip),
+	}); err != nil {
+		return wrapError("unable to reserve address in IPAM", err)
+	}
+
+	if _, err := r.IPAMManager.Assign(ipInstance.Spec.Network, ipamtypes.PodInfo{
+		NamespacedName: types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name},
+		IPFamily:       ipFamily,
+	}, []ipamtypes.SubnetIPSuite{
+		ipamtypes.AssignIPOfSubnet(ipInstance.Spec.Subnet, ip),
+	}, ipamtypes.AssignForce(true)); err != nil {
+		return wrapError("unable to assign address to target pod in IPAM", err)
+	}
+
+	if rebound.Labels == nil {
+		rebound.Labels = map[string]string{}
+	}
+	rebound.Labels[constants.LabelNode] = pod.Spec.NodeName
+	rebound.Labels[constants.LabelPod] = transform.TransferPodNameForLabelValue(pod.Name)
+	rebound.Labels[constants.LabelPodUID] = string(pod.UID)
+	rebound.Labels[constants.LabelPhase] = networkingv1.IPInstancePhaseAllocated
+	if len(sourceNode) > 0 && sourceNode != pod.Spec.NodeName {
+		rebound.Labels[constants.LabelRebindSourceNode] = sourceNode
+	} else {
+		delete(rebound.Labels, constants.LabelRebindSourceNode)
+	}
+
+	// the source pod may be the controller owner, which will garbage collect the address once it
+	// is deleted, so owner follows the same rule of allocation
+	owner, err := strategy.ResolveStatefulWorkloadOwner(ctx, pod, r.APIReader)
+	if err != nil {
+		return fmt.Errorf("unable to resolve stateful owner of target pod: %v", err)
+	}
+	if owner == nil {
+		owner = ipamutils.NewControllerRef(pod, corev1.SchemeGroupVersion.WithKind("Pod"), true, false)
+	}
+	rebound.OwnerReferences = []metav1.OwnerReference{*owner}
+
+	// retained addresses of stateful workloads without ordinal pod names are kept in pool of workload
+	if strategy.IsStatefulWorkloadReference(owner) && !strategy.IsOrdinalWorkloadReference(owner) {
+		rebound.Labels[constants.LabelStatefulOwner] = string(owner.UID)
+	} else {
+		delete(rebound.Labels, constants.LabelStatefulOwner)
+	}
+
+	rebound.Spec.Binding = networkingv1.Binding{
+		ReferredObject: networkingv1.ObjectMeta{
+			Kind: owner.Kind,
+			Name: owner.Name,
+			UID:  owner.UID,
+		},
+		NodeName: pod.Spec.NodeName,
+		PodUID:   pod.UID,
+		PodName:  pod.Name,
+	}
+	if strategy.IsStatefulWorkloadReference(owner) {
+		rebound.Spec.Binding.Stateful = &networkingv1.StatefulInfo{}
+		if strategy.IsOrdinalWorkloadReference(owner) {
+			rebound.Spec.Binding.Stateful.Index = ipamutils.IntToInt32P(ipamutils.GetIndexFromName(pod.Name))
+		}
+	}
+	rebound.Spec.Rebind = nil
+
+	if err := r.Patch(ctx, rebound, client.MergeFromWithOptions(ipInstance, client.MergeFromWithOptimisticLock{})); err != nil {
+		if rollbackErr := r.rollback(ipInstance, ipFamily); rollbackErr != nil {
+			return fmt.Errorf("%v, and unable to roll back IPAM: %v", err, rollbackErr)
+		}
+		return err
+	}
+
+	*ipInstance = *rebound
+	return nil
+}
+
+// rollback restores the binding of address in IPAM as the unchanged ip instance
+func (r *IPInstanceRebindReconciler) rollback(ipInstance *networkingv1.IPInstance, ipFamily ipamtypes.IPFamilyMode) error {
+	var ip = utils.ToIPFormat(ipInstance.Name)
+
+	if err := r.IPAMManager.Reserve(ipInstance.Spec.Network, []ipamtypes.SubnetIPSuite{
+		ipamtypes.ReserveIPOfSubnet(ipInstance.Spec.Subnet, ip),
+	}); err != nil {
+		return wrapError("unable to reserve address in IPAM", err)
+	}
+
+	if len(ipInstance.Spec.Binding.PodName) == 0 {
+		return nil
+	}
+
+	if _, err := r.IPAMManager.Assign(ipInstance.Spec.Network, ipamtypes.PodInfo{
+		NamespacedName: types.NamespacedName{Namespace: ipInstance.Namespace, Name: ipInstance.Spec.Binding.PodName},
+		IPFamily:       ipFamily,
+	}, []ipamtypes.SubnetIPSuite{
+		ipamtypes.AssignIPOfSubnet(ipInstance.Spec.Subnet, ip),
+	}, ipamtypes.AssignForce(true)); err != nil {
+		return wrapError("unable to assign address to source pod in IPAM", err)
+	}
+
+	// retained address of a pod is still reserved
+	if networkingv1.IsReserved(ipInstance) {
+		return wrapError("unable to reserve address in IPAM", r.IPAMManager.Reserve(ipInstance.Spec.Network,
+			[]ipamtypes.SubnetIPSuite{ipamtypes.ReserveIPOfSubnet(ipInstance.Spec.Subnet, ip)}))
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *IPInstanceRebindReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(ControllerIPInstanceRebind).
+		For(&networkingv1.IPInstance{},
+			builder.WithPredicates(
+				&utils.IgnoreDeletePredicate{},
+				predicate.NewPredicateFuncs(func(obj client.Object) bool {
+					ipInstance, ok := obj.(*networkingv1.IPInstance)
+					if !ok {
+						return false
+					}
+					return ipInstance.Spec.Rebind != nil
+				}),
+			)).
+		Watches(&source.Kind{Type: &corev1.Pod{}},
+			handler.EnqueueRequestsFromMapFunc(func(object client.Object) []reconcile.Request {
+				var ipInstanceList = &networkingv1.IPInstanceList{}
+				if err := r.List(context.TODO(), ipInstanceList,
+					client.MatchingFields{IndexerFieldRebindPod: object.GetName()},
+					client.InNamespace(object.GetNamespace()),
+				); err != nil {
+					return nil
+				}
+
+				var requests []reconcile.Request
+				for i := range ipInstanceList.Items {
+					requests = append(requests, reconcile.Request{
+						NamespacedName: types.NamespacedName{
+							Namespace: ipInstanceList.Items[i].Namespace,
+							Name:      ipInstanceList.Items[i].Name,
+						},
+					})
+				}
+				return requests
+			}),
+			builder.WithPredicates(
+				&utils.IgnoreDeletePredicate{},
+				&predicate.ResourceVersionChangedPredicate{},
+			),
+		).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.Max(),
+			RecoverPanic:            true,
+		}).
+		Complete(r)
+}
@@ -0,0 +1,375 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	ipamtypes "github.com/alibaba/hybridnet/pkg/ipam/types"
+)
+
+func TestIPInstanceRebind(t *testing.T) {
+	const namespace = "default"
+
+	newPod := func(name, nodeName string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				UID:       types.UID(name + "-uid"),
+			},
+			Spec: corev1.PodSpec{
+				NodeName: nodeName,
+			},
+		}
+	}
+
+	newNode := func(name string, labels map[string]string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: labels,
+			},
+		}
+	}
+
+	network := &networkingv1.Network{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "underlay",
+		},
+		Spec: networkingv1.NetworkSpec{
+			Type:         networkingv1.NetworkTypeUnderlay,
+			NodeSelector: map[string]string{"network": "underlay"},
+		},
+	}
+
+	sourcePod := newPod("source", "node0")
+
+	newIPInstance := func(target string) *networkingv1.IPInstance {
+		return &networkingv1.IPInstance{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "192-168-0-10",
+				Namespace: namespace,
+				Labels: map[string]string{
+					constants.LabelNode:   "node0",
+					constants.LabelPod:    sourcePod.Name,
+					constants.LabelPodUID: string(sourcePod.UID),
+					constants.LabelPhase:  networkingv1.IPInstancePhaseAllocated,
+				},
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         "v1",
+						Kind:               "Pod",
+						Name:               sourcePod.Name,
+						UID:                sourcePod.UID,
+						Controller:         pointer.BoolPtr(true),
+						BlockOwnerDeletion: pointer.BoolPtr(false),
+					},
+				},
+			},
+			Spec: networkingv1.IPInstanceSpec{
+				Network: network.Name,
+				Subnet:  "subnet",
+				Address: networkingv1.Address{
+					IP:      "192.168.0.10/24",
+					Version: networkingv1.IPv4,
+				},
+				Binding: networkingv1.Binding{
+					ReferredObject: networkingv1.ObjectMeta{
+						Kind: "Pod",
+						Name: sourcePod.Name,
+						UID:  sourcePod.UID,
+					},
+					NodeName: sourcePod.Spec.NodeName,
+					PodUID:   sourcePod.UID,
+					PodName:  sourcePod.Name,
+				},
+				Rebind: &networkingv1.RebindTarget{
+					PodName: target,
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		target       *corev1.Pod
+		expectError  bool
+		expectRebind bool
+	}{
+		{
+			name:         "target pod on node of network",
+			target:       newPod("target", "node1"),
+			expectRebind: true,
+		},
+		{
+			name:        "target pod on node out of network",
+			target:      newPod("target", "node2"),
+			expectError: true,
+		},
+		{
+			name:   "target pod not scheduled",
+			target: newPod("target", ""),
+		},
+		{
+			name: "target pod not found",
+		},
+	}
+
+	for _, test := range tests {
+		ctx := context.Background()
+		objects := []client.Object{
+			network,
+			newNode("node0", network.Spec.NodeSelector),
+			newNode("node1", network.Spec.NodeSelector),
+			newNode("node2", nil),
+			sourcePod,
+			newIPInstance("target"),
+		}
+		if test.target != nil {
+			objects = append(objects, test.target)
+		}
+
+		c := newFakeClient(objects...)
+		podIPCache, _ := NewPodIPCache(ctx, c, logr.Discard())
+		ipamManager := &fakeIPAMManager{}
+		r := &IPInstanceRebindReconciler{
+			Client:      c,
+			APIReader:   c,
+			Recorder:    record.NewFakeRecorder(10),
+			PodIPCache:  podIPCache,
+			IPAMManager: ipamManager,
+		}
+
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: "192-168-0-10"}})
+		if (err != nil) != test.expectError {
+			t.Errorf("test %s fails, expected error %v but got %v", test.name, test.expectError, err)
+			continue
+		}
+
+		ipInstance := &networkingv1.IPInstance{}
+		if err = c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "192-168-0-10"}, ipInstance); err != nil {
+			t.Fatalf("test %s fails, unable to get ip instance: %v", test.name, err)
+		}
+
+		if !test.expectRebind {
+			if ipInstance.Spec.Rebind == nil || ipInstance.Spec.Binding.PodName != sourcePod.Name ||
+				len(ipamManager.reserved) != 0 || len(ipamManager.assigned) != 0 {
+				t.Errorf("test %s fails, ip instance should not be rebound, got %+v", test.name, ipInstance.Spec)
+			}
+			continue
+		}
+
+		expectedBinding := networkingv1.Binding{
+			ReferredObject: networkingv1.ObjectMeta{
+				Kind: "Pod",
+				Name: test.target.Name,
+				UID:  test.target.UID,
+			},
+			NodeName: test.target.Spec.NodeName,
+			PodUID:   test.target.UID,
+			PodName:  test.target.Name,
+		}
+		if ipInstance.Spec.Rebind != nil || !reflect.DeepEqual(ipInstance.Spec.Binding, expectedBinding) {
+			t.Errorf("test %s fails, expected binding %+v but got %+v", test.name, expectedBinding, ipInstance.Spec)
+		}
+
+		if len(ipInstance.OwnerReferences) != 1 || ipInstance.OwnerReferences[0].UID != test.target.UID {
+			t.Errorf("test %s fails, expected owner %s but got %+v", test.name, test.target.Name, ipInstance.OwnerReferences)
+		}
+
+		if ipInstance.Labels[constants.LabelPod] != test.target.Name ||
+			ipInstance.Labels[constants.LabelRebindSourceNode] != sourcePod.Spec.NodeName {
+			t.Errorf("test %s fails, unexpected labels %v", test.name, ipInstance.Labels)
+		}
+
+		expectedSuites := []ipamtypes.SubnetIPSuite{ipamtypes.ReserveIPOfSubnet("subnet", "192.168.0.10")}
+		if !reflect.DeepEqual(ipamManager.reserved, expectedSuites) {
+			t.Errorf("test %s fails, expected reserved %+v but got %+v", test.name, expectedSuites, ipamManager.reserved)
+		}
+
+		expectedAssigned := map[string][]ipamtypes.SubnetIPSuite{
+			namespace + "/" + test.target.Name: {ipamtypes.AssignIPOfSubnet("subnet", "192.168.0.10")},
+		}
+		if !reflect.DeepEqual(ipamManager.assigned, expectedAssigned) {
+			t.Errorf("test %s fails, expected assigned %+v but got %+v", test.name, expectedAssigned, ipamManager.assigned)
+		}
+
+		if exist, uid, _ := podIPCache.Get(test.target.Name, namespace); !exist || uid != test.target.UID {
+			t.Errorf("test %s fails, address is not recorded for target pod", test.name)
+		}
+	}
+}
+
+// patchFailingClient fails all the patches with err
+type patchFailingClient struct {
+	client.Client
+	err error
+}
+
+func (c *patchFailingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return c.err
+}
+
+func TestIPInstanceRebindGroup(t *testing.T) {
+	const namespace = "default"
+
+	network := &networkingv1.Network{
+		ObjectMeta: metav1.ObjectMeta{Name: "overlay"},
+		Spec:       networkingv1.NetworkSpec{Type: networkingv1.NetworkTypeOverlay},
+	}
+	sourcePod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "source", Namespace: namespace, UID: "source-uid"},
+		Spec:       corev1.PodSpec{NodeName: "node0"},
+	}
+	targetPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "target", Namespace: namespace, UID: "target-uid"},
+		Spec:       corev1.PodSpec{NodeName: "node1"},
+	}
+
+	newIPInstance := func(name, ip, subnet string, version networkingv1.IPVersion, rebind bool) *networkingv1.IPInstance {
+		ipInstance := &networkingv1.IPInstance{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels: map[string]string{
+					constants.LabelNode:   sourcePod.Spec.NodeName,
+					constants.LabelPod:    sourcePod.Name,
+					constants.LabelPodUID: string(sourcePod.UID),
+					constants.LabelPhase:  networkingv1.IPInstancePhaseAllocated,
+				},
+			},
+			Spec: networkingv1.IPInstanceSpec{
+				Network: network.Name,
+				Subnet:  subnet,
+				Address: networkingv1.Address{IP: ip, Version: version},
+				Binding: networkingv1.Binding{
+					ReferredObject: networkingv1.ObjectMeta{
+						Kind: "Pod",
+						Name: sourcePod.Name,
+						UID:  sourcePod.UID,
+					},
+					NodeName: sourcePod.Spec.NodeName,
+					PodUID:   sourcePod.UID,
+					PodName:  sourcePod.Name,
+				},
+			},
+		}
+		if rebind {
+			ipInstance.Spec.Rebind = &networkingv1.RebindTarget{PodName: targetPod.Name}
+		}
+		return ipInstance
+	}
+
+	newReconciler := func(patchErr error) (*IPInstanceRebindReconciler, client.Client, *fakeIPAMManager) {
+		ctx := context.Background()
+		c := newFakeClient(network, sourcePod, targetPod,
+			newIPInstance("10-0-0-10", "10.0.0.10/24", "subnet-v4", networkingv1.IPv4, true),
+			newIPInstance("fd00-0-0-0-0-0-0-10", "fd00::10/64", "subnet-v6", networkingv1.IPv6, false),
+		)
+		podIPCache, err := NewPodIPCache(ctx, c, logr.Discard())
+		if err != nil {
+			t.Fatalf("unable to create pod ip cache: %v", err)
+		}
+		ipamManager := &fakeIPAMManager{}
+
+		var reconcilerClient client.Client = c
+		if patchErr != nil {
+			reconcilerClient = &patchFailingClient{Client: c, err: patchErr}
+		}
+		return &IPInstanceRebindReconciler{
+			Client:      reconcilerClient,
+			APIReader:   c,
+			Recorder:    record.NewFakeRecorder(10),
+			PodIPCache:  podIPCache,
+			IPAMManager: ipamManager,
+		}, c, ipamManager
+	}
+
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: "10-0-0-10"}}
+
+	t.Run("dual-stack addresses are rebound together", func(t *testing.T) {
+		r, c, ipamManager := newReconciler(nil)
+
+		if _, err := r.Reconcile(context.Background(), request); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, name := range []string{"10-0-0-10", "fd00-0-0-0-0-0-0-10"} {
+			ipInstance := &networkingv1.IPInstance{}
+			if err := c.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: name}, ipInstance); err != nil {
+				t.Fatalf("unable to get ip instance %s: %v", name, err)
+			}
+			if ipInstance.Spec.Binding.PodName != targetPod.Name || ipInstance.Spec.Rebind != nil {
+				t.Errorf("expected ip instance %s rebound to %s but got %+v", name, targetPod.Name, ipInstance.Spec)
+			}
+		}
+
+		expectedAssigned := map[string][]ipamtypes.SubnetIPSuite{
+			namespace + "/" + targetPod.Name: {
+				ipamtypes.AssignIPOfSubnet("subnet-v6", "fd00::10"),
+				ipamtypes.AssignIPOfSubnet("subnet-v4", "10.0.0.10"),
+			},
+		}
+		if !reflect.DeepEqual(ipamManager.assigned, expectedAssigned) {
+			t.Errorf("expected assigned %+v but got %+v", expectedAssigned, ipamManager.assigned)
+		}
+
+		if _, _, recorded := r.PodIPCache.Get(targetPod.Name, namespace); len(recorded) != 2 {
+			t.Errorf("expected both addresses recorded for target pod but got %v", recorded)
+		}
+	})
+
+	t.Run("IPAM is rolled back if patch fails", func(t *testing.T) {
+		r, c, ipamManager := newReconciler(fmt.Errorf("conflict"))
+
+		if _, err := r.Reconcile(context.Background(), request); err == nil {
+			t.Fatalf("expected error but got nil")
+		}
+
+		ipInstance := &networkingv1.IPInstance{}
+		if err := c.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: "fd00-0-0-0-0-0-0-10"}, ipInstance); err != nil {
+			t.Fatalf("unable to get ip instance: %v", err)
+		}
+		if ipInstance.Spec.Binding.PodName != sourcePod.Name {
+			t.Errorf("expected ip instance bound to %s but got %+v", sourcePod.Name, ipInstance.Spec.Binding)
+		}
+
+		// the first address of group is assigned to target pod and then back to source pod
+		expectedAssigned := map[string][]ipamtypes.SubnetIPSuite{
+			namespace + "/" + targetPod.Name: {ipamtypes.AssignIPOfSubnet("subnet-v6", "fd00::10")},
+			namespace + "/" + sourcePod.Name: {ipamtypes.AssignIPOfSubnet("subnet-v6", "fd00::10")},
+		}
+		if !reflect.DeepEqual(ipamManager.assigned, expectedAssigned) {
+			t.Errorf("expected assigned %+v but got %+v", expectedAssigned, ipamManager.assigned)
+		}
+	})
+}
@@ -0,0 +1,106 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/controllers/concurrency"
+	"github.com/alibaba/hybridnet/pkg/ipam/strategy"
+)
+
+const ControllerJobIPRetain = "JobIPRetain"
+
+// JobIPRetainReconciler releases the reserved ip instances of Jobs and CronJobs which are not reused
+// by any retried pod before they expire.
+type JobIPRetainReconciler struct {
+	client.Client
+
+	concurrency.ControllerConcurrency
+}
+
+//+kubebuilder:rbac:groups=networking.alibaba.com,resources=ipinstances,verbs=get;list;watch;delete
+
+func (r *JobIPRetainReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	log := ctrllog.FromContext(ctx)
+
+	defer func() {
+		if err != nil {
+			log.Error(err, "reconciliation fails")
+		}
+	}()
+
+	var ipInstance = &networkingv1.IPInstance{}
+	if err = r.Get(ctx, req.NamespacedName, ipInstance); err != nil {
+		return ctrl.Result{}, wrapError("unable to fetch IPInstance", client.IgnoreNotFound(err))
+	}
+
+	if !ipInstance.DeletionTimestamp.IsZero() || !networkingv1.IsReserved(ipInstance) {
+		return ctrl.Result{}, nil
+	}
+
+	if remaining := time.Until(jobIPRetainExpireTime(ipInstance)); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	// ip instance may be reused by a retried pod just now, resource version makes sure that
+	// only the expired reservation will be deleted
+	var resourceVersion = ipInstance.ResourceVersion
+	if err = r.Delete(ctx, ipInstance, client.Preconditions{ResourceVersion: &resourceVersion}); err != nil {
+		return ctrl.Result{}, wrapError("unable to release expired ip instance", client.IgnoreNotFound(err))
+	}
+
+	log.Info("release expired ip instance of job", "owner", ipInstance.Labels[constants.LabelJobOwner])
+	return ctrl.Result{}, nil
+}
+
+// jobIPRetainExpireTime returns the expire time of reserved ip instance, ip instances which are reserved
+// without expire time, e.g., those reserved after node deletion, expire after the default TTL
+func jobIPRetainExpireTime(ipInstance *networkingv1.IPInstance) time.Time {
+	if expireTime, err := time.Parse(time.RFC3339, ipInstance.Annotations[constants.AnnotationIPRetainExpireTime]); err == nil {
+		return expireTime
+	}
+	return ipInstance.Status.UpdateTimestamp.Add(strategy.JobIPRetainTTL)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *JobIPRetainReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(ControllerJobIPRetain).
+		For(&networkingv1.IPInstance{},
+			builder.WithPredicates(
+				&predicate.ResourceVersionChangedPredicate{},
+				predicate.NewPredicateFuncs(func(obj client.Object) bool {
+					return len(obj.GetLabels()[constants.LabelJobOwner]) > 0
+				}),
+			)).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.Max(),
+			RecoverPanic:            true,
+		}).
+		Complete(r)
+}
@@ -19,19 +19,46 @@ package networking
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	"github.com/alibaba/hybridnet/pkg/controllers/concurrency"
 	"github.com/alibaba/hybridnet/pkg/controllers/utils"
+	"github.com/alibaba/hybridnet/pkg/feature"
+	"github.com/alibaba/hybridnet/pkg/ipam/notifier"
+	ipamservice "github.com/alibaba/hybridnet/pkg/ipam/service"
+	"github.com/alibaba/hybridnet/pkg/managerconfig"
 )
 
 type RegisterOptions struct {
 	NewIPAMManager NewIPAMManagerFunction
 	ConcurrencyMap map[string]int
 	PodSelector    utils.PodSelector
+
+	// Config provides the configurations which can be changed at runtime
+	Config *managerconfig.Store
+
+	// IPAMService enables the gRPC IPAM service for workloads out of cluster if not nil
+	IPAMService *ipamservice.Options
+
+	// IPAMNotifier enables sending address allocation events to an outbound webhook if not nil
+	IPAMNotifier *notifier.Options
+
+	// IPLeaseDuration is the duration of leases of ip instances, only used when lease mode is enabled
+	IPLeaseDuration time.Duration
+
+	// SubnetUsageForecastWindows are the sliding windows to forecast exhaustion of subnets, empty means disabled
+	SubnetUsageForecastWindows []time.Duration
+
+	// DaemonRollout enables the coordinator of daemon rollout if not nil
+	DaemonRollout *DaemonRolloutOptions
+
+	// Controllers selects the optional controllers to register, all of them are registered if empty
+	Controllers ControllerSelector
 }
 
 func RegisterToManager(ctx context.Context, mgr manager.Manager, options RegisterOptions) error {
@@ -41,6 +68,9 @@ func RegisterToManager(ctx context.Context, mgr manager.Manager, options Registe
 	if len(options.ConcurrencyMap) == 0 {
 		options.ConcurrencyMap = map[string]int{}
 	}
+	if err := options.Controllers.Validate(); err != nil {
+		return fmt.Errorf("invalid controllers: %v", err)
+	}
 
 	// init IPAM manager and start
 	ipamManager, err := options.NewIPAMManager(ctx, mgr.GetClient())
@@ -55,17 +85,28 @@ func RegisterToManager(ctx context.Context, mgr manager.Manager, options Registe
 
 	ipamStore := NewIPAMStore(mgr.GetClient())
 
+	var ipamNotifier IPAMNotifier
+	if options.IPAMNotifier != nil {
+		n := notifier.New(*options.IPAMNotifier, ctrllog.Log.WithName("ipam-notifier"))
+		if err = mgr.Add(n); err != nil {
+			return fmt.Errorf("unable to inject ipam notifier: %v", err)
+		}
+		ipamNotifier = n
+		ipamStore = notifier.NewStore(ipamStore, n)
+	}
+
 	// init status update channels
 	networkStatusUpdateChan, subnetStatusUpdateChan := make(chan event.GenericEvent), make(chan event.GenericEvent)
 
 	// setup controllers
-	if err = (&IPAMReconciler{
+	ipamReconciler := &IPAMReconciler{
 		Client:                  mgr.GetClient(),
 		IPAMManager:             ipamManager,
 		NetworkStatusUpdateChan: networkStatusUpdateChan,
 		SubnetStatusUpdateChan:  subnetStatusUpdateChan,
 		ControllerConcurrency:   concurrency.ControllerConcurrency(options.ConcurrencyMap[ControllerIPAM]),
-	}).SetupWithManager(mgr); err != nil {
+	}
+	if err = ipamReconciler.SetupWithManager(mgr); err != nil {
 		return fmt.Errorf("unable to inject controller %s: %v", ControllerIPAM, err)
 	}
 
@@ -74,17 +115,33 @@ func RegisterToManager(ctx context.Context, mgr manager.Manager, options Registe
 		PodIPCache:            podIPCache,
 		IPAMManager:           ipamManager,
 		IPAMStore:             ipamStore,
+		IPAMNotifier:          ipamNotifier,
 		ControllerConcurrency: concurrency.ControllerConcurrency(options.ConcurrencyMap[ControllerIPInstance]),
 	}).SetupWithManager(mgr); err != nil {
 		return fmt.Errorf("unable to inject controller %s: %v", ControllerIPInstance, err)
 	}
 
-	if err = (&NodeReconciler{
-		Context:               ctx,
-		Client:                mgr.GetClient(),
-		ControllerConcurrency: concurrency.ControllerConcurrency(options.ConcurrencyMap[ControllerNode]),
-	}).SetupWithManager(mgr); err != nil {
-		return fmt.Errorf("unable to inject controller %s: %v", ControllerNode, err)
+	if options.Controllers.Enabled(ControllerIPInstanceRebind) {
+		if err = (&IPInstanceRebindReconciler{
+			Client:                mgr.GetClient(),
+			APIReader:             mgr.GetAPIReader(),
+			Recorder:              mgr.GetEventRecorderFor(ControllerIPInstanceRebind + "Controller"),
+			PodIPCache:            podIPCache,
+			IPAMManager:           ipamManager,
+			ControllerConcurrency: concurrency.ControllerConcurrency(options.ConcurrencyMap[ControllerIPInstanceRebind]),
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to inject controller %s: %v", ControllerIPInstanceRebind, err)
+		}
+	}
+
+	if options.Controllers.Enabled(ControllerNode) {
+		if err = (&NodeReconciler{
+			Context:               ctx,
+			Client:                mgr.GetClient(),
+			ControllerConcurrency: concurrency.ControllerConcurrency(options.ConcurrencyMap[ControllerNode]),
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to inject controller %s: %v", ControllerNode, err)
+		}
 	}
 
 	if err = (&PodReconciler{
@@ -116,17 +173,179 @@ func RegisterToManager(ctx context.Context, mgr manager.Manager, options Registe
 		IPAMManager:            ipamManager,
 		Recorder:               mgr.GetEventRecorderFor(ControllerSubnetStatus + "Controller"),
 		SubnetStatusUpdateChan: subnetStatusUpdateChan,
+		UsageForecastWindows:   options.SubnetUsageForecastWindows,
 		ControllerConcurrency:  concurrency.ControllerConcurrency(options.ConcurrencyMap[ControllerSubnetStatus]),
 	}).SetupWithManager(mgr); err != nil {
 		return fmt.Errorf("unable to inject controller %s: %v", ControllerSubnetStatus, err)
 	}
 
-	if err = (&QuotaReconciler{
-		Context:               ctx,
+	if options.Controllers.Enabled(ControllerQuota) {
+		if err = (&QuotaReconciler{
+			Context:               ctx,
+			Client:                mgr.GetClient(),
+			ControllerConcurrency: concurrency.ControllerConcurrency(options.ConcurrencyMap[ControllerQuota]),
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to inject controller %s: %v", ControllerQuota, err)
+		}
+	}
+
+	if err = (&SubnetCleanupReconciler{
+		Client:                mgr.GetClient(),
+		Recorder:              mgr.GetEventRecorderFor(ControllerSubnetCleanup + "Controller"),
+		Config:                options.Config,
+		ControllerConcurrency: concurrency.ControllerConcurrency(options.ConcurrencyMap[ControllerSubnetCleanup]),
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to inject controller %s: %v", ControllerSubnetCleanup, err)
+	}
+
+	if err = (&IPInstanceCleanupReconciler{
+		Client:                mgr.GetClient(),
+		Recorder:              mgr.GetEventRecorderFor(ControllerIPInstanceCleanup + "Controller"),
+		Config:                options.Config,
+		ControllerConcurrency: concurrency.ControllerConcurrency(options.ConcurrencyMap[ControllerIPInstanceCleanup]),
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to inject controller %s: %v", ControllerIPInstanceCleanup, err)
+	}
+
+	if err = (&NetworkCleanupReconciler{
 		Client:                mgr.GetClient(),
-		ControllerConcurrency: concurrency.ControllerConcurrency(options.ConcurrencyMap[ControllerQuota]),
+		Recorder:              mgr.GetEventRecorderFor(ControllerNetworkCleanup + "Controller"),
+		ControllerConcurrency: concurrency.ControllerConcurrency(options.ConcurrencyMap[ControllerNetworkCleanup]),
 	}).SetupWithManager(mgr); err != nil {
-		return fmt.Errorf("unable to inject controller %s: %v", ControllerQuota, err)
+		return fmt.Errorf("unable to inject controller %s: %v", ControllerNetworkCleanup, err)
+	}
+
+	if options.Controllers.Enabled(ControllerNodeCleanup) {
+		if err = (&NodeCleanupReconciler{
+			Client:                mgr.GetClient(),
+			IPAMStore:             ipamStore,
+			ControllerConcurrency: concurrency.ControllerConcurrency(options.ConcurrencyMap[ControllerNodeCleanup]),
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to inject controller %s: %v", ControllerNodeCleanup, err)
+		}
+	}
+
+	if options.Controllers.Enabled(ControllerJobIPRetain) {
+		if err = (&JobIPRetainReconciler{
+			Client:                mgr.GetClient(),
+			ControllerConcurrency: concurrency.ControllerConcurrency(options.ConcurrencyMap[ControllerJobIPRetain]),
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to inject controller %s: %v", ControllerJobIPRetain, err)
+		}
+	}
+
+	if feature.IPInstanceLeaseEnabled() && options.Controllers.Enabled(ControllerIPInstanceLease) {
+		if err = (&IPInstanceLeaseReconciler{
+			Client:                mgr.GetClient(),
+			APIReader:             mgr.GetAPIReader(),
+			IPAMStore:             ipamStore,
+			Duration:              options.IPLeaseDuration,
+			ControllerConcurrency: concurrency.ControllerConcurrency(options.ConcurrencyMap[ControllerIPInstanceLease]),
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to inject controller %s: %v", ControllerIPInstanceLease, err)
+		}
+	}
+
+	if options.Controllers.Enabled(ControllerNodeDrain) {
+		if err = (&NodeDrainReconciler{
+			Client:                mgr.GetClient(),
+			Recorder:              mgr.GetEventRecorderFor(ControllerNodeDrain + "Controller"),
+			IPAMStore:             ipamStore,
+			IPAMManager:           ipamManager,
+			ControllerConcurrency: concurrency.ControllerConcurrency(options.ConcurrencyMap[ControllerNodeDrain]),
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to inject controller %s: %v", ControllerNodeDrain, err)
+		}
+	}
+
+	if feature.StatefulSetIPPreAllocationEnabled() && options.Controllers.Enabled(ControllerStatefulSetPreAllocate) {
+		if err = (&StatefulSetPreAllocateReconciler{
+			APIReader:             mgr.GetAPIReader(),
+			Client:                mgr.GetClient(),
+			Recorder:              mgr.GetEventRecorderFor(ControllerStatefulSetPreAllocate + "Controller"),
+			IPAMStore:             ipamStore,
+			IPAMManager:           ipamManager,
+			ControllerConcurrency: concurrency.ControllerConcurrency(options.ConcurrencyMap[ControllerStatefulSetPreAllocate]),
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to inject controller %s: %v", ControllerStatefulSetPreAllocate, err)
+		}
+	}
+
+	kubeClient, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return fmt.Errorf("unable to create kubernetes client: %v", err)
+	}
+
+	if options.Controllers.Enabled(ControllerSubnetDrain) {
+		if err = (&SubnetDrainReconciler{
+			Client:                mgr.GetClient(),
+			KubeClient:            kubeClient,
+			Recorder:              mgr.GetEventRecorderFor(ControllerSubnetDrain + "Controller"),
+			Config:                options.Config,
+			ControllerConcurrency: concurrency.ControllerConcurrency(options.ConcurrencyMap[ControllerSubnetDrain]),
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to inject controller %s: %v", ControllerSubnetDrain, err)
+		}
+	}
+
+	if options.Controllers.Enabled(ControllerDualStackRetrofit) {
+		if err = (&DualStackRetrofitReconciler{
+			Client:                mgr.GetClient(),
+			APIReader:             mgr.GetAPIReader(),
+			KubeClient:            kubeClient,
+			Recorder:              mgr.GetEventRecorderFor(ControllerDualStackRetrofit + "Controller"),
+			Config:                options.Config,
+			ControllerConcurrency: concurrency.ControllerConcurrency(options.ConcurrencyMap[ControllerDualStackRetrofit]),
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to inject controller %s: %v", ControllerDualStackRetrofit, err)
+		}
+	}
+
+	if options.Controllers.Enabled(ControllerPodStartupWatchdog) {
+		if err = (&PodStartupWatchdogReconciler{
+			Client:                mgr.GetClient(),
+			APIReader:             mgr.GetAPIReader(),
+			Recorder:              mgr.GetEventRecorderFor(ControllerPodStartupWatchdog + "Controller"),
+			PodSelector:           options.PodSelector,
+			Config:                options.Config,
+			ControllerConcurrency: concurrency.ControllerConcurrency(options.ConcurrencyMap[ControllerPodStartupWatchdog]),
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to inject controller %s: %v", ControllerPodStartupWatchdog, err)
+		}
+	}
+
+	if options.Controllers.Enabled(ControllerNodeNetworkConfigRollout) {
+		if err = (&NodeNetworkConfigRolloutReconciler{
+			Client:                mgr.GetClient(),
+			Recorder:              mgr.GetEventRecorderFor(ControllerNodeNetworkConfigRollout + "Controller"),
+			ControllerConcurrency: concurrency.ControllerConcurrency(options.ConcurrencyMap[ControllerNodeNetworkConfigRollout]),
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to inject controller %s: %v", ControllerNodeNetworkConfigRollout, err)
+		}
+	}
+
+	if options.DaemonRollout != nil && options.Controllers.Enabled(ControllerDaemonRollout) {
+		if err = (&DaemonRolloutReconciler{
+			Client:                mgr.GetClient(),
+			APIReader:             mgr.GetAPIReader(),
+			Options:               *options.DaemonRollout,
+			ControllerConcurrency: concurrency.ControllerConcurrency(options.ConcurrencyMap[ControllerDaemonRollout]),
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to inject controller %s: %v", ControllerDaemonRollout, err)
+		}
+	}
+
+	if options.IPAMService != nil {
+		if err = mgr.Add(&ipamservice.Server{
+			Client:      mgr.GetClient(),
+			APIReader:   mgr.GetAPIReader(),
+			IPAMManager: ipamManager,
+			IPAMStore:   ipamStore,
+			Options:     *options.IPAMService,
+			Logger:      ctrllog.Log.WithName("ipam-service"),
+		}); err != nil {
+			return fmt.Errorf("unable to inject ipam service: %v", err)
+		}
 	}
 
 	return nil
@@ -0,0 +1,132 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/controllers/concurrency"
+	"github.com/alibaba/hybridnet/pkg/controllers/utils"
+)
+
+const ControllerNetworkCleanup = "NetworkCleanup"
+
+// NetworkCleanupReconciler holds the deletion of network until all of its subnets are removed,
+// so that daemons can always find the network of a terminating subnet to clean its dataplane
+type NetworkCleanupReconciler struct {
+	client.Client
+
+	Recorder record.EventRecorder
+
+	concurrency.ControllerConcurrency
+}
+
+//+kubebuilder:rbac:groups=networking.alibaba.com,resources=networks,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=networking.alibaba.com,resources=networks/finalizers,verbs=update
+
+func (r *NetworkCleanupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	log := ctrllog.FromContext(ctx)
+
+	var network = &networkingv1.Network{}
+
+	defer func() {
+		if err != nil {
+			log.Error(err, "reconciliation fails")
+			if len(network.UID) > 0 {
+				r.Recorder.Event(network, corev1.EventTypeWarning, "CleanupFail", err.Error())
+			}
+		}
+	}()
+
+	if err = r.Get(ctx, req.NamespacedName, network); err != nil {
+		return ctrl.Result{}, wrapError("unable to fetch Network", client.IgnoreNotFound(err))
+	}
+
+	if network.DeletionTimestamp == nil {
+		return ctrl.Result{}, wrapError("unable to add finalizer to network",
+			utils.AddFinalizer(ctx, r, network, constants.FinalizerDataplaneCleanup))
+	}
+
+	var subnetList *networkingv1.SubnetList
+	if subnetList, err = utils.ListSubnets(ctx, r); err != nil {
+		return ctrl.Result{}, wrapError("unable to list subnets", err)
+	}
+
+	for i := range subnetList.Items {
+		if subnetList.Items[i].Spec.Network == network.Name {
+			// subnet removal will trigger reconciliation again
+			log.V(1).Info(fmt.Sprintf("waiting for subnet %s to be removed", subnetList.Items[i].Name))
+			return ctrl.Result{}, nil
+		}
+	}
+
+	return ctrl.Result{}, wrapError("unable to remove finalizer from network",
+		utils.RemoveFinalizer(ctx, r, network, constants.FinalizerDataplaneCleanup))
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *NetworkCleanupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(ControllerNetworkCleanup).
+		For(&networkingv1.Network{},
+			builder.WithPredicates(
+				&utils.IgnoreDeletePredicate{},
+				predicate.Or(
+					&predicate.GenerationChangedPredicate{},
+					&utils.TerminatingPredicate{},
+				),
+			)).
+		Watches(&source.Kind{Type: &networkingv1.Subnet{}},
+			handler.EnqueueRequestsFromMapFunc(func(object client.Object) []reconcile.Request {
+				subnet, ok := object.(*networkingv1.Subnet)
+				if !ok {
+					return nil
+				}
+				return []reconcile.Request{
+					{
+						NamespacedName: types.NamespacedName{
+							Name: subnet.Spec.Network,
+						},
+					},
+				}
+			}),
+			builder.WithPredicates(
+				&utils.IgnoreUpdatePredicate{},
+			),
+		).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.Max(),
+			RecoverPanic:            true,
+		}).
+		Complete(r)
+}
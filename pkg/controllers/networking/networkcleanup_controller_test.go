@@ -0,0 +1,89 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+)
+
+func TestNetworkCleanupReconcile(t *testing.T) {
+	newNetwork := func(terminating bool) *networkingv1.Network {
+		network := &networkingv1.Network{ObjectMeta: metav1.ObjectMeta{Name: "network1"}}
+		if terminating {
+			network.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			network.Finalizers = []string{constants.FinalizerDataplaneCleanup}
+		}
+		return network
+	}
+	newSubnet := func(name, networkName string) *networkingv1.Subnet {
+		return &networkingv1.Subnet{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       networkingv1.SubnetSpec{Network: networkName},
+		}
+	}
+
+	tests := []struct {
+		name            string
+		objects         []client.Object
+		expectFinalizer bool
+	}{
+		{
+			name:            "finalizer added to live network",
+			objects:         []client.Object{newNetwork(false)},
+			expectFinalizer: true,
+		},
+		{
+			name:            "waiting for subnets of network",
+			objects:         []client.Object{newNetwork(true), newSubnet("subnet1", "network1")},
+			expectFinalizer: true,
+		},
+		{
+			name:            "subnets of other networks are ignored",
+			objects:         []client.Object{newNetwork(true), newSubnet("subnet2", "network2")},
+			expectFinalizer: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := &NetworkCleanupReconciler{
+				Client:   newFakeClient(test.objects...),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			key := types.NamespacedName{Name: "network1"}
+			if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if finalizer := hasCleanupFinalizer(t, r.Client, key, &networkingv1.Network{}); finalizer != test.expectFinalizer {
+				t.Errorf("expected finalizer %v but got %v", test.expectFinalizer, finalizer)
+			}
+		})
+	}
+}
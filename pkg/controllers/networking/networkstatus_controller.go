@@ -30,7 +30,6 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
@@ -88,12 +87,12 @@ func (r *NetworkStatusReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 
 	if network.DeletionTimestamp != nil {
 		cleanMetrics(network.Name)
-		return ctrl.Result{}, wrapError("unable to remove finalizer", r.removeFinalizer(ctx, network))
+		return ctrl.Result{}, wrapError("unable to remove finalizer", utils.RemoveFinalizer(ctx, r, network, constants.FinalizerMetricsRegistered))
 
 	}
 
 	// make sure metrics will be un-registered before deletion
-	if err = r.addFinalizer(ctx, network); err != nil {
+	if err = utils.AddFinalizer(ctx, r, network, constants.FinalizerMetricsRegistered); err != nil {
 		return ctrl.Result{}, wrapError("unable to add finalizer to network", err)
 	}
 
@@ -211,30 +210,6 @@ func cleanMetrics(networkName string) {
 	_ = metrics.IPUsageGauge.DeleteLabelValues(networkName, metrics.DualStack, metrics.IPAvailableUsageType)
 }
 
-func (r *NetworkStatusReconciler) addFinalizer(ctx context.Context, network *networkingv1.Network) error {
-	if controllerutil.ContainsFinalizer(network, constants.FinalizerMetricsRegistered) {
-		return nil
-	}
-
-	patch := client.MergeFrom(network.DeepCopy())
-	controllerutil.AddFinalizer(network, constants.FinalizerMetricsRegistered)
-	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		return r.Patch(ctx, network, patch)
-	})
-}
-
-func (r *NetworkStatusReconciler) removeFinalizer(ctx context.Context, network *networkingv1.Network) error {
-	if !controllerutil.ContainsFinalizer(network, constants.FinalizerMetricsRegistered) {
-		return nil
-	}
-
-	patch := client.MergeFrom(network.DeepCopy())
-	controllerutil.RemoveFinalizer(network, constants.FinalizerMetricsRegistered)
-	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		return r.Patch(ctx, network, patch)
-	})
-}
-
 // SetupWithManager sets up the controller with the Manager.
 func (r *NetworkStatusReconciler) SetupWithManager(mgr ctrl.Manager) (err error) {
 	return ctrl.NewControllerManagedBy(mgr).
@@ -0,0 +1,218 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/controllers/concurrency"
+)
+
+const ControllerNodeCleanup = "NodeCleanup"
+
+// NodeCleanupReconciler handles ip instances which are still bound to deleted nodes while
+// their pods are gone. Retained ip instances are reserved for next pods of their owners, and
+// others are released. Pods which still exist are left to Pod controller.
+type NodeCleanupReconciler struct {
+	client.Client
+
+	IPAMStore IPAMStore
+
+	concurrency.ControllerConcurrency
+}
+
+//+kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+//+kubebuilder:rbac:groups=networking.alibaba.com,resources=ipinstances,verbs=get;list;watch;update;patch;delete
+
+func (r *NodeCleanupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	log := ctrllog.FromContext(ctx)
+
+	defer func() {
+		if err != nil {
+			log.Error(err, "reconciliation fails")
+		}
+	}()
+
+	var node = &corev1.Node{}
+	if err = r.Get(ctx, req.NamespacedName, node); err == nil {
+		if node.DeletionTimestamp.IsZero() {
+			return ctrl.Result{}, nil
+		}
+	} else if !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, wrapError("unable to fetch Node", err)
+	}
+
+	var ipInstanceList = &networkingv1.IPInstanceList{}
+	if err = r.List(ctx, ipInstanceList, client.MatchingLabels{
+		constants.LabelNode: req.Name,
+	}); err != nil {
+		return ctrl.Result{}, wrapError("unable to list ip instances of node", err)
+	}
+
+	var handledPods = map[types.NamespacedName]struct{}{}
+	for i := range ipInstanceList.Items {
+		var ipInstance = &ipInstanceList.Items[i]
+		if !ipInstance.DeletionTimestamp.IsZero() || networkingv1.IsReserved(ipInstance) {
+			continue
+		}
+
+		podName := types.NamespacedName{
+			Namespace: ipInstance.Namespace,
+			Name:      networkingv1.FetchBindingPodName(ipInstance),
+		}
+		if _, handled := handledPods[podName]; handled {
+			continue
+		}
+		handledPods[podName] = struct{}{}
+
+		if len(podName.Name) > 0 {
+			var pod = &corev1.Pod{}
+			if err = r.Get(ctx, podName, pod); err == nil {
+				// pod still exists, it will be handled by Pod controller after being garbage collected
+				continue
+			} else if !apierrors.IsNotFound(err) {
+				return ctrl.Result{}, wrapError("unable to fetch Pod", err)
+			}
+		}
+
+		var reserved bool
+		if reserved, err = releaseIPInstancesOfGonePod(ctx, r.Client, r.IPAMStore, ipInstance, podName); err != nil {
+			return ctrl.Result{}, err
+		}
+		if reserved {
+			log.Info("reserve ip instances of deleted node", "pod", podName.String())
+		} else {
+			log.Info("release ip instances of deleted node", "pod", podName.String())
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// releaseIPInstancesOfGonePod releases ip instances of a pod which has been removed, or reserves
+// them if they are retained, reserved is true if ip instances are reserved
+func releaseIPInstancesOfGonePod(ctx context.Context, c client.Client, ipamStore IPAMStore,
+	ipInstance *networkingv1.IPInstance, podName types.NamespacedName) (reserved bool, err error) {
+	// pod is gone, only name and namespace are used to find its ip instances
+	var podOfIPInstance = &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      podName.Name,
+			Namespace: podName.Namespace,
+		},
+	}
+
+	if retainedAfterNodeDeletion(ipInstance) && len(podName.Name) > 0 {
+		if err = ipamStore.IPReserve(ctx, podOfIPInstance); err != nil {
+			return false, wrapError(fmt.Sprintf("unable to reserve ip instances of pod %s", podName.String()), err)
+		}
+		return true, nil
+	}
+
+	if len(podName.Name) > 0 {
+		if err = ipamStore.DeCouple(ctx, podOfIPInstance); err != nil {
+			return false, wrapError(fmt.Sprintf("unable to release ip instances of pod %s", podName.String()), err)
+		}
+	} else if err = client.IgnoreNotFound(c.Delete(ctx, ipInstance)); err != nil {
+		return false, wrapError("unable to release ip instance", err)
+	}
+	return false, nil
+}
+
+// retainedAfterNodeDeletion means the ip instance is owned by a stateful workload but not
+// a pod, which is the same as the reservation rule of terminating pods
+func retainedAfterNodeDeletion(ipInstance *networkingv1.IPInstance) bool {
+	owner := metav1.GetControllerOf(ipInstance)
+	return owner != nil && owner.Kind != "Pod"
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *NodeCleanupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(ControllerNodeCleanup).
+		For(&corev1.Node{},
+			builder.WithPredicates(
+				predicate.Funcs{
+					CreateFunc: func(event.CreateEvent) bool {
+						return false
+					},
+					UpdateFunc: func(updateEvent event.UpdateEvent) bool {
+						return updateEvent.ObjectOld.GetDeletionTimestamp().IsZero() &&
+							!updateEvent.ObjectNew.GetDeletionTimestamp().IsZero()
+					},
+					DeleteFunc: func(event.DeleteEvent) bool {
+						return true
+					},
+					GenericFunc: func(event.GenericEvent) bool {
+						return false
+					},
+				},
+			)).
+		// the initial list of ip instances finds nodes which are deleted when manager is down
+		Watches(&source.Kind{Type: &networkingv1.IPInstance{}},
+			handler.EnqueueRequestsFromMapFunc(func(object client.Object) []reconcile.Request {
+				nodeName := object.GetLabels()[constants.LabelNode]
+				if len(nodeName) == 0 {
+					return nil
+				}
+				return []reconcile.Request{
+					{
+						NamespacedName: types.NamespacedName{
+							Name: nodeName,
+						},
+					},
+				}
+			}),
+			builder.WithPredicates(
+				predicate.Funcs{
+					CreateFunc: func(event.CreateEvent) bool {
+						return true
+					},
+					UpdateFunc: func(event.UpdateEvent) bool {
+						return false
+					},
+					DeleteFunc: func(event.DeleteEvent) bool {
+						return false
+					},
+					GenericFunc: func(event.GenericEvent) bool {
+						return false
+					},
+				},
+			)).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.Max(),
+			RecoverPanic:            true,
+		}).
+		Complete(r)
+}
@@ -0,0 +1,348 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/controllers/concurrency"
+	"github.com/alibaba/hybridnet/pkg/controllers/utils"
+	"github.com/alibaba/hybridnet/pkg/feature"
+	ipamtypes "github.com/alibaba/hybridnet/pkg/ipam/types"
+)
+
+const ControllerNodeDrain = "NodeDrain"
+
+const (
+	ReasonNodeDrainIPRelease  = "NodeDrainIPRelease"
+	ReasonNodeDrainIPShortage = "NodeDrainIPShortage"
+)
+
+// NodeDrainReconciler releases ip instances on cordoned nodes, which are usually being drained.
+// Ip instances of evicted or terminating pods are released as soon as all containers of pods
+// stop, instead of waiting for kubelet to remove the pods and ip instances being garbage collected.
+// Ip instances of stateful workloads are left to Pod controller, which reserves them once pods stop,
+// and reserved addresses are not bound to any node, so the pods recreated on other nodes get them
+// at once. Pods which do not retain addresses need new ones after being recreated on other nodes,
+// so the networks are pre-checked for enough available addresses while the node is drained.
+// Allocations are not pre-warmed on target nodes: addresses are bound to pods by name, and the
+// names and nodes of recreated pods are unknown until they are created and scheduled. Shortage
+// is reported as events on the node instead.
+type NodeDrainReconciler struct {
+	client.Client
+
+	Recorder record.EventRecorder
+
+	IPAMStore   IPAMStore
+	IPAMManager IPAMManager
+
+	concurrency.ControllerConcurrency
+}
+
+//+kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups=networking.alibaba.com,resources=ipinstances,verbs=get;list;watch;update;patch;delete
+
+func (r *NodeDrainReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	log := ctrllog.FromContext(ctx)
+
+	defer func() {
+		if err != nil {
+			log.Error(err, "reconciliation fails")
+		}
+	}()
+
+	if !feature.NodeDrainIPReleaseEnabled() {
+		return ctrl.Result{}, nil
+	}
+
+	var node = &corev1.Node{}
+	if err = r.Get(ctx, req.NamespacedName, node); err != nil {
+		// ip instances of deleted nodes are handled by NodeCleanup controller
+		return ctrl.Result{}, wrapError("unable to fetch Node", client.IgnoreNotFound(err))
+	}
+
+	if !node.Spec.Unschedulable || !node.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	var ipInstanceList = &networkingv1.IPInstanceList{}
+	if err = r.List(ctx, ipInstanceList, client.MatchingLabels{
+		constants.LabelNode: node.Name,
+	}); err != nil {
+		return ctrl.Result{}, wrapError("unable to list ip instances of node", err)
+	}
+
+	var (
+		handledPods       = map[types.NamespacedName]struct{}{}
+		releasedPods      int
+		replacementDemand = addressDemand{}
+	)
+	for i := range ipInstanceList.Items {
+		var ipInstance = &ipInstanceList.Items[i]
+		if !ipInstance.DeletionTimestamp.IsZero() || networkingv1.IsReserved(ipInstance) {
+			continue
+		}
+
+		podName := types.NamespacedName{
+			Namespace: ipInstance.Namespace,
+			Name:      networkingv1.FetchBindingPodName(ipInstance),
+		}
+		if _, handled := handledPods[podName]; handled || len(podName.Name) == 0 {
+			continue
+		}
+		handledPods[podName] = struct{}{}
+
+		var pod = &corev1.Pod{}
+		if err = r.Get(ctx, podName, pod); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return ctrl.Result{}, wrapError("unable to fetch Pod", err)
+			}
+
+			var reserved bool
+			if reserved, err = releaseIPInstancesOfGonePod(ctx, r.Client, r.IPAMStore, ipInstance, podName); err != nil {
+				return ctrl.Result{}, err
+			}
+			if !reserved {
+				releasedPods++
+			}
+			log.Info("handle ip instances of removed pod on cordoned node", "pod", podName.String(), "reserved", reserved)
+			continue
+		}
+
+		if retainedAfterNodeDeletion(ipInstance) {
+			continue
+		}
+
+		if !podStoppedOnDrainedNode(pod, node.Name) {
+			if podToBeReplaced(pod, node.Name) {
+				replacementDemand.add(ipInstanceList.Items, podName)
+			}
+			continue
+		}
+
+		if err = r.IPAMStore.DeCouple(ctx, pod); err != nil {
+			return ctrl.Result{}, wrapError(fmt.Sprintf("unable to release ip instances of pod %s", podName.String()), err)
+		}
+		r.Recorder.Event(pod, corev1.EventTypeNormal, ReasonNodeDrainIPRelease, "release all IPs of stopped pod on cordoned node")
+		releasedPods++
+		log.Info("release ip instances of stopped pod on cordoned node", "pod", podName.String())
+	}
+
+	if releasedPods > 0 {
+		r.Recorder.Eventf(node, corev1.EventTypeNormal, ReasonNodeDrainIPRelease,
+			"release IPs of %d stopped pods on cordoned node", releasedPods)
+	}
+
+	var shortages []string
+	if shortages, err = r.checkCapacityForReplacements(replacementDemand); err != nil {
+		return ctrl.Result{}, wrapError("unable to check capacity for replacements of pods", err)
+	}
+	if len(shortages) > 0 {
+		r.Recorder.Eventf(node, corev1.EventTypeWarning, ReasonNodeDrainIPShortage,
+			"pods evicted from cordoned node may fail to get IPs on other nodes: %s", strings.Join(shortages, ", "))
+	}
+	return ctrl.Result{}, nil
+}
+
+// addressDemand counts the addresses required by each network and ip family
+type addressDemand map[string]map[ipamtypes.IPFamilyMode]uint32
+
+// add counts the addresses of a pod, which are required again once the pod is recreated
+func (d addressDemand) add(ipInstances []networkingv1.IPInstance, podName types.NamespacedName) {
+	for i := range ipInstances {
+		var ipInstance = &ipInstances[i]
+		if ipInstance.Namespace != podName.Namespace || networkingv1.FetchBindingPodName(ipInstance) != podName.Name ||
+			!ipInstance.DeletionTimestamp.IsZero() || networkingv1.IsReserved(ipInstance) {
+			continue
+		}
+
+		ipFamily := ipamtypes.IPv4
+		if ipInstance.Spec.Address.Version == networkingv1.IPv6 {
+			ipFamily = ipamtypes.IPv6
+		}
+
+		if d[ipInstance.Spec.Network] == nil {
+			d[ipInstance.Spec.Network] = map[ipamtypes.IPFamilyMode]uint32{}
+		}
+		d[ipInstance.Spec.Network][ipFamily]++
+	}
+}
+
+// checkCapacityForReplacements returns the networks which have not enough available addresses
+// for the demand, addresses of other pods being allocated at the same time are not considered
+func (r *NodeDrainReconciler) checkCapacityForReplacements(demand addressDemand) ([]string, error) {
+	var networkNames []string
+	for networkName := range demand {
+		networkNames = append(networkNames, networkName)
+	}
+	sort.Strings(networkNames)
+
+	var shortages []string
+	for _, networkName := range networkNames {
+		networkUsage, err := r.IPAMManager.GetNetworkUsage(networkName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to fetch usage of network %s: %v", networkName, err)
+		}
+
+		for _, ipFamily := range []ipamtypes.IPFamilyMode{ipamtypes.IPv4, ipamtypes.IPv6} {
+			required := demand[networkName][ipFamily]
+			if required == 0 {
+				continue
+			}
+
+			var available uint32
+			if usage := networkUsage.GetByType(ipFamily); usage != nil {
+				available = usage.Available
+			}
+			if available < required {
+				shortages = append(shortages, fmt.Sprintf("network %s requires %d %s addresses but only %d available",
+					networkName, required, ipFamily, available))
+			}
+		}
+	}
+	return shortages, nil
+}
+
+// podStoppedOnDrainedNode means pod on the node is leaving and none of its containers is running,
+// so its addresses will never be used again
+func podStoppedOnDrainedNode(pod *corev1.Pod, nodeName string) bool {
+	if pod.Spec.NodeName != nodeName {
+		return false
+	}
+
+	if pod.DeletionTimestamp.IsZero() && !utils.PodIsEvicted(pod) && !utils.PodIsCompleted(pod) {
+		return false
+	}
+
+	return utils.PodIsNotRunning(pod)
+}
+
+// podToBeReplaced means pod on the node will be evicted by drain and recreated on other nodes,
+// pods of DaemonSets are skipped by drain and never move to other nodes, and the replacements of
+// terminating pods have been created already
+func podToBeReplaced(pod *corev1.Pod, nodeName string) bool {
+	if pod.Spec.NodeName != nodeName || !pod.DeletionTimestamp.IsZero() || utils.PodIsCompleted(pod) {
+		return false
+	}
+
+	owner := metav1.GetControllerOf(pod)
+	return owner != nil && owner.Kind != "DaemonSet" && owner.Kind != "Node"
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *NodeDrainReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(ControllerNodeDrain).
+		For(&corev1.Node{},
+			builder.WithPredicates(
+				predicate.Funcs{
+					CreateFunc: func(createEvent event.CreateEvent) bool {
+						return isCordonedNode(createEvent.Object)
+					},
+					UpdateFunc: func(updateEvent event.UpdateEvent) bool {
+						return !isCordonedNode(updateEvent.ObjectOld) && isCordonedNode(updateEvent.ObjectNew)
+					},
+					DeleteFunc: func(event.DeleteEvent) bool {
+						return false
+					},
+					GenericFunc: func(event.GenericEvent) bool {
+						return false
+					},
+				},
+			)).
+		// pods being evicted from a drained node trigger releasing once they stop
+		Watches(&source.Kind{Type: &corev1.Pod{}},
+			handler.EnqueueRequestsFromMapFunc(r.cordonedNodeOfPod),
+			builder.WithPredicates(
+				predicate.Funcs{
+					CreateFunc: func(event.CreateEvent) bool {
+						return false
+					},
+					UpdateFunc: func(updateEvent event.UpdateEvent) bool {
+						pod, ok := updateEvent.ObjectNew.(*corev1.Pod)
+						if !ok {
+							return false
+						}
+						return (!pod.DeletionTimestamp.IsZero() || utils.PodIsEvicted(pod) || utils.PodIsCompleted(pod)) &&
+							utils.PodIsNotRunning(pod)
+					},
+					DeleteFunc: func(event.DeleteEvent) bool {
+						return true
+					},
+					GenericFunc: func(event.GenericEvent) bool {
+						return false
+					},
+				},
+			)).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.Max(),
+			RecoverPanic:            true,
+		}).
+		Complete(r)
+}
+
+// cordonedNodeOfPod enqueues the node of pod only if it is cordoned, pods on other nodes are
+// left to Pod controller
+func (r *NodeDrainReconciler) cordonedNodeOfPod(object client.Object) []reconcile.Request {
+	pod, ok := object.(*corev1.Pod)
+	if !ok || len(pod.Spec.NodeName) == 0 {
+		return nil
+	}
+
+	var node = &corev1.Node{}
+	if err := r.Get(context.TODO(), types.NamespacedName{Name: pod.Spec.NodeName}, node); err != nil {
+		return nil
+	}
+	if !isCordonedNode(node) {
+		return nil
+	}
+
+	return []reconcile.Request{
+		{
+			NamespacedName: types.NamespacedName{
+				Name: pod.Spec.NodeName,
+			},
+		},
+	}
+}
+
+func isCordonedNode(object client.Object) bool {
+	node, ok := object.(*corev1.Node)
+	return ok && node.Spec.Unschedulable
+}
@@ -0,0 +1,262 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/feature"
+	ipamtypes "github.com/alibaba/hybridnet/pkg/ipam/types"
+)
+
+func enableNodeDrainIPRelease(t *testing.T) {
+	if err := utilfeature.DefaultMutableFeatureGate.Set(string(feature.NodeDrainIPRelease) + "=true"); err != nil {
+		t.Fatalf("unable to enable node drain ip release: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = utilfeature.DefaultMutableFeatureGate.Set(string(feature.NodeDrainIPRelease) + "=false")
+	})
+}
+
+func TestNodeDrainReconcile(t *testing.T) {
+	enableNodeDrainIPRelease(t)
+
+	const (
+		namespace = "default"
+		nodeName  = "node0"
+	)
+
+	replicaSetRef := metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "rs", UID: "rs-uid",
+		Controller: pointer.Bool(true)}
+	statefulSetRef := *metav1.NewControllerRef(&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "sts", UID: "sts-uid"}},
+		statefulSetGVK)
+	daemonSetRef := replicaSetRef
+	daemonSetRef.Kind, daemonSetRef.Name, daemonSetRef.UID = "DaemonSet", "ds", "ds-uid"
+
+	running := corev1.PodStatus{
+		Phase:             corev1.PodRunning,
+		ContainerStatuses: []corev1.ContainerStatus{{Name: "c", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}},
+	}
+	evicted := corev1.PodStatus{
+		Phase:  corev1.PodFailed,
+		Reason: "Evicted",
+	}
+
+	newPod := func(name string, owner metav1.OwnerReference, status corev1.PodStatus) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       namespace,
+				OwnerReferences: []metav1.OwnerReference{owner},
+			},
+			Spec: corev1.PodSpec{
+				NodeName:   nodeName,
+				Containers: []corev1.Container{{Name: "c"}},
+			},
+			Status: status,
+		}
+	}
+
+	newIPInstance := func(name, podName string, version networkingv1.IPVersion, owner metav1.OwnerReference) *networkingv1.IPInstance {
+		if owner.Kind == "ReplicaSet" || owner.Kind == "DaemonSet" {
+			owner = metav1.OwnerReference{APIVersion: "v1", Kind: "Pod", Name: podName, UID: types.UID(podName + "-uid"),
+				Controller: owner.Controller}
+		}
+		return &networkingv1.IPInstance{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       namespace,
+				Labels:          map[string]string{constants.LabelNode: nodeName},
+				OwnerReferences: []metav1.OwnerReference{owner},
+			},
+			Spec: networkingv1.IPInstanceSpec{
+				Network: "overlay",
+				Address: networkingv1.Address{Version: version},
+				Binding: networkingv1.Binding{
+					PodName:  podName,
+					NodeName: nodeName,
+				},
+			},
+		}
+	}
+
+	objects := []client.Object{
+		newPod("evicted", replicaSetRef, evicted),
+		newIPInstance("10-0-0-1", "evicted", networkingv1.IPv4, replicaSetRef),
+		newPod("running", replicaSetRef, running),
+		newIPInstance("10-0-0-2", "running", networkingv1.IPv4, replicaSetRef),
+		newIPInstance("fd00--2", "running", networkingv1.IPv6, replicaSetRef),
+		newPod("stateful-0", statefulSetRef, evicted),
+		newIPInstance("10-0-0-3", "stateful-0", networkingv1.IPv4, statefulSetRef),
+		newPod("daemon", daemonSetRef, running),
+		newIPInstance("10-0-0-4", "daemon", networkingv1.IPv4, daemonSetRef),
+		newIPInstance("10-0-0-5", "gone", networkingv1.IPv4, replicaSetRef),
+		newIPInstance("10-0-0-6", "stateful-1", networkingv1.IPv4, statefulSetRef),
+	}
+
+	newUsage := func(ipv4Available, ipv6Available uint32) *ipamtypes.NetworkUsage {
+		return &ipamtypes.NetworkUsage{
+			Usages: map[ipamtypes.IPFamilyMode]*ipamtypes.Usage{
+				ipamtypes.IPv4: {Available: ipv4Available},
+				ipamtypes.IPv6: {Available: ipv6Available},
+			},
+		}
+	}
+
+	tests := []struct {
+		name             string
+		unschedulable    bool
+		usage            *ipamtypes.NetworkUsage
+		expectDecoupled  []string
+		expectIPReserved []string
+		expectShortage   string
+	}{
+		{
+			name:  "schedulable node",
+			usage: newUsage(10, 10),
+		},
+		{
+			name:             "cordoned node",
+			unschedulable:    true,
+			usage:            newUsage(10, 10),
+			expectDecoupled:  []string{"default/evicted", "default/gone"},
+			expectIPReserved: []string{"default/stateful-1"},
+		},
+		{
+			name:             "cordoned node without enough addresses for replacements",
+			unschedulable:    true,
+			usage:            newUsage(10, 0),
+			expectDecoupled:  []string{"default/evicted", "default/gone"},
+			expectIPReserved: []string{"default/stateful-1"},
+			expectShortage:   "network overlay requires 1 IPv6Only addresses but only 0 available",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+				Spec:       corev1.NodeSpec{Unschedulable: test.unschedulable},
+			}
+
+			ipamStore := &fakeIPAMStore{}
+			recorder := record.NewFakeRecorder(10)
+			r := &NodeDrainReconciler{
+				Client:      newFakeClient(append([]client.Object{node}, objects...)...),
+				Recorder:    recorder,
+				IPAMStore:   ipamStore,
+				IPAMManager: &fakeIPAMManager{usages: map[string]*ipamtypes.NetworkUsage{"overlay": test.usage}},
+			}
+
+			if _, err := r.Reconcile(context.Background(), ctrl.Request{
+				NamespacedName: types.NamespacedName{Name: nodeName},
+			}); err != nil {
+				t.Fatalf("test %s fails, unexpected error: %v", test.name, err)
+			}
+
+			sort.Strings(ipamStore.decoupled)
+			if !reflect.DeepEqual(ipamStore.decoupled, test.expectDecoupled) {
+				t.Errorf("test %s fails, expected decoupled pods %v but got %v", test.name, test.expectDecoupled, ipamStore.decoupled)
+			}
+			if !reflect.DeepEqual(ipamStore.ipReserved, test.expectIPReserved) {
+				t.Errorf("test %s fails, expected reserved pods %v but got %v", test.name, test.expectIPReserved, ipamStore.ipReserved)
+			}
+
+			var shortage string
+			close(recorder.Events)
+			for event := range recorder.Events {
+				if strings.Contains(event, ReasonNodeDrainIPShortage) {
+					shortage = event
+				}
+			}
+			if !strings.Contains(shortage, test.expectShortage) || (len(test.expectShortage) == 0) != (len(shortage) == 0) {
+				t.Errorf("test %s fails, expected shortage %q but got event %q", test.name, test.expectShortage, shortage)
+			}
+		})
+	}
+}
+
+func TestNodeDrainCordonedNodeOfPod(t *testing.T) {
+	newNode := func(name string, unschedulable bool) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       corev1.NodeSpec{Unschedulable: unschedulable},
+		}
+	}
+	newPod := func(nodeName string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+		}
+	}
+
+	r := &NodeDrainReconciler{
+		Client: newFakeClient(newNode("cordoned", true), newNode("schedulable", false)),
+	}
+
+	tests := []struct {
+		name   string
+		obj    client.Object
+		expect []ctrl.Request
+	}{
+		{
+			name:   "pod on cordoned node",
+			obj:    newPod("cordoned"),
+			expect: []ctrl.Request{{NamespacedName: types.NamespacedName{Name: "cordoned"}}},
+		},
+		{
+			name: "pod on schedulable node",
+			obj:  newPod("schedulable"),
+		},
+		{
+			name: "pod on missing node",
+			obj:  newPod("missing"),
+		},
+		{
+			name: "unscheduled pod",
+			obj:  newPod(""),
+		},
+		{
+			name: "not pod",
+			obj:  newNode("cordoned", true),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := r.cordonedNodeOfPod(test.obj); !reflect.DeepEqual(result, test.expect) {
+				t.Errorf("test %s fails, expected %v but got %v", test.name, test.expect, result)
+			}
+		})
+	}
+}
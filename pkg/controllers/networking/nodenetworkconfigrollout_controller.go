@@ -0,0 +1,361 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/controllers/concurrency"
+	"github.com/alibaba/hybridnet/pkg/controllers/utils"
+)
+
+const ControllerNodeNetworkConfigRollout = "NodeNetworkConfigRollout"
+
+const (
+	defaultRolloutProgressDeadline = 10 * time.Minute
+
+	// maxUnverifiedNodesInMessage limits the length of status message for large canary node sets
+	maxUnverifiedNodesInMessage = 5
+)
+
+// NodeNetworkConfigRolloutReconciler rolls out changes of NodeNetworkConfigs. A change of config with
+// rollout is applied by daemons on canary nodes first, and it's promoted to the other nodes after all
+// canary nodes report the revision of change on annotation, which means their daemons pass the
+// self-check with it. The other nodes are released to apply the promoted change in batches limited
+// by max unavailable. Changes of configs without rollout are promoted at once.
+type NodeNetworkConfigRolloutReconciler struct {
+	client.Client
+
+	Recorder record.EventRecorder
+
+	concurrency.ControllerConcurrency
+}
+
+//+kubebuilder:rbac:groups=networking.alibaba.com,resources=nodenetworkconfigs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=networking.alibaba.com,resources=nodenetworkconfigs/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch;patch
+
+func (r *NodeNetworkConfigRolloutReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	log := ctrllog.FromContext(ctx)
+
+	var config = &networkingv1.NodeNetworkConfig{}
+
+	defer func() {
+		if err != nil {
+			log.Error(err, "reconciliation fails")
+			if len(config.UID) > 0 {
+				r.Recorder.Event(config, corev1.EventTypeWarning, "RolloutFail", err.Error())
+			}
+		}
+	}()
+
+	if err = r.Get(ctx, req.NamespacedName, config); err != nil {
+		return ctrl.Result{}, wrapError("unable to fetch NodeNetworkConfig", client.IgnoreNotFound(err))
+	}
+
+	if config.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	status := config.Status.DeepCopy()
+
+	switch {
+	case config.Spec.Rollout == nil, status.PromotedSpec != nil && specEqualWithoutRollout(status.PromotedSpec, &config.Spec):
+		// nothing to verify by canary nodes
+		promoteNodeNetworkConfig(config, status, "promoted without verification of canary nodes")
+	case status.ObservedGeneration != config.Generation:
+		status.ObservedGeneration = config.Generation
+		status.Phase = networkingv1.NodeNetworkConfigRolloutCanary
+		status.CanaryStartTime = &metav1.Time{Time: time.Now()}
+		status.CanaryNodes, status.VerifiedCanaryNodes = 0, 0
+		status.Message = ""
+	}
+
+	if status.Phase == networkingv1.NodeNetworkConfigRolloutCanary {
+		if result, err = r.progress(ctx, config, status); err != nil {
+			return ctrl.Result{}, wrapError("unable to check progress of canary nodes", err)
+		}
+	}
+
+	if status.Phase == networkingv1.NodeNetworkConfigRolloutPromoting {
+		if err = r.release(ctx, config, status); err != nil {
+			return ctrl.Result{}, wrapError("unable to release nodes for promoted generation", err)
+		}
+	}
+
+	if reflect.DeepEqual(&config.Status, status) {
+		return result, nil
+	}
+
+	if status.Phase != config.Status.Phase {
+		switch status.Phase {
+		case networkingv1.NodeNetworkConfigRolloutPromoting:
+			r.Recorder.Eventf(config, corev1.EventTypeNormal, "RolloutPromoting", "generation %d is being promoted to other nodes",
+				status.PromotedGeneration)
+		case networkingv1.NodeNetworkConfigRolloutPromoted:
+			r.Recorder.Eventf(config, corev1.EventTypeNormal, "RolloutPromoted", "generation %d is promoted to all nodes",
+				status.PromotedGeneration)
+		case networkingv1.NodeNetworkConfigRolloutFailed:
+			r.Recorder.Eventf(config, corev1.EventTypeWarning, "RolloutFailed", "generation %d is not promoted: %s",
+				status.ObservedGeneration, status.Message)
+		}
+	}
+
+	configPatch := client.MergeFrom(config.DeepCopy())
+	config.Status = *status
+	if err = r.Status().Patch(ctx, config, configPatch); err != nil {
+		return ctrl.Result{}, wrapError("unable to update NodeNetworkConfig status", err)
+	}
+	return result, nil
+}
+
+// progress counts the canary nodes which report the revision of observed generation, and decides if
+// the observed generation is promoted, or the rollout fails for exceeding deadline
+func (r *NodeNetworkConfigRolloutReconciler) progress(ctx context.Context, config *networkingv1.NodeNetworkConfig,
+	status *networkingv1.NodeNetworkConfigStatus) (ctrl.Result, error) {
+	canaryNodes, _, err := r.listNodes(ctx, config)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	revision := networkingv1.NodeNetworkConfigRevision(config.Name, status.ObservedGeneration)
+	var unverifiedNodes []string
+	for _, node := range canaryNodes {
+		if node.Annotations[constants.AnnotationNodeNetworkConfigRevision] != revision {
+			unverifiedNodes = append(unverifiedNodes, node.Name)
+		}
+	}
+	sort.Strings(unverifiedNodes)
+
+	status.CanaryNodes = int32(len(canaryNodes))
+	status.VerifiedCanaryNodes = int32(len(canaryNodes) - len(unverifiedNodes))
+
+	if len(canaryNodes) > 0 && len(unverifiedNodes) == 0 {
+		promoteNodeNetworkConfig(config, status, fmt.Sprintf("verified by %d canary nodes", len(canaryNodes)))
+		return ctrl.Result{}, nil
+	}
+
+	deadline := defaultRolloutProgressDeadline
+	if config.Spec.Rollout.ProgressDeadlineSeconds != nil {
+		deadline = time.Duration(*config.Spec.Rollout.ProgressDeadlineSeconds) * time.Second
+	}
+
+	var waiting string
+	switch {
+	case len(canaryNodes) == 0:
+		waiting = "no canary node is selected"
+	case len(unverifiedNodes) > maxUnverifiedNodesInMessage:
+		waiting = fmt.Sprintf("canary nodes %s and %d more are not verified",
+			strings.Join(unverifiedNodes[:maxUnverifiedNodesInMessage], ", "), len(unverifiedNodes)-maxUnverifiedNodesInMessage)
+	default:
+		waiting = fmt.Sprintf("canary nodes %s are not verified", strings.Join(unverifiedNodes, ", "))
+	}
+
+	remaining := deadline - time.Since(status.CanaryStartTime.Time)
+	if remaining <= 0 {
+		status.Phase = networkingv1.NodeNetworkConfigRolloutFailed
+		status.Message = fmt.Sprintf("progress deadline %v exceeded, %s", deadline, waiting)
+		return ctrl.Result{}, nil
+	}
+
+	status.Message = waiting
+	return ctrl.Result{RequeueAfter: remaining}, nil
+}
+
+// release counts the nodes other than canary ones which report the revision of promoted generation,
+// and releases more of them by annotation until the ones restarting daemons reach max unavailable.
+// A released node is unavailable until its daemon passes the self-check and reports the revision.
+func (r *NodeNetworkConfigRolloutReconciler) release(ctx context.Context, config *networkingv1.NodeNetworkConfig,
+	status *networkingv1.NodeNetworkConfigStatus) error {
+	_, nodes, err := r.listNodes(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	maxUnavailable, err := networkingv1.GetNodeNetworkConfigMaxUnavailable(config.Spec.Rollout, len(nodes))
+	if err != nil {
+		return err
+	}
+
+	revision := networkingv1.NodeNetworkConfigRevision(config.Name, status.PromotedGeneration)
+	var (
+		updated, unavailable int
+		pendingNodes         []string
+	)
+	for _, node := range nodes {
+		switch {
+		case node.Annotations[constants.AnnotationNodeNetworkConfigRevision] == revision:
+			updated++
+		case node.Annotations[constants.AnnotationNodeNetworkConfigReleasedRevision] == revision:
+			unavailable++
+		default:
+			pendingNodes = append(pendingNodes, node.Name)
+		}
+	}
+	sort.Strings(pendingNodes)
+
+	patchBody := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, constants.AnnotationNodeNetworkConfigReleasedRevision, revision)
+	for i := 0; i < len(pendingNodes) && unavailable < maxUnavailable; i++ {
+		if err = r.Patch(ctx, &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: pendingNodes[i],
+			},
+		}, client.RawPatch(types.MergePatchType, []byte(patchBody))); err != nil {
+			return fmt.Errorf("unable to release node %s: %v", pendingNodes[i], err)
+		}
+		unavailable++
+	}
+
+	status.Nodes = int32(len(nodes))
+	status.UpdatedNodes = int32(updated)
+
+	if updated == len(nodes) {
+		status.Phase = networkingv1.NodeNetworkConfigRolloutPromoted
+		status.Message = fmt.Sprintf("promoted to %d nodes", len(nodes))
+		return nil
+	}
+
+	status.Message = fmt.Sprintf("%d of %d nodes are updated, %d nodes are applying the change", updated, len(nodes), unavailable)
+	return nil
+}
+
+// listNodes returns the canary nodes and the other nodes which the config applies to
+func (r *NodeNetworkConfigRolloutReconciler) listNodes(ctx context.Context, config *networkingv1.NodeNetworkConfig) (
+	canaryNodes, otherNodes []corev1.Node, err error) {
+	var configList = &networkingv1.NodeNetworkConfigList{}
+	if err = r.List(ctx, configList); err != nil {
+		return nil, nil, fmt.Errorf("unable to list NodeNetworkConfigs: %v", err)
+	}
+
+	var nodeList = &corev1.NodeList{}
+	if err = r.List(ctx, nodeList); err != nil {
+		return nil, nil, fmt.Errorf("unable to list nodes: %v", err)
+	}
+
+	for _, node := range nodeList.Items {
+		selected, err := networkingv1.SelectNodeNetworkConfig(configList.Items, node.Name, node.Labels)
+		if err != nil {
+			return nil, nil, err
+		}
+		if selected == nil || selected.Name != config.Name {
+			continue
+		}
+
+		canary, err := networkingv1.IsNodeNetworkConfigCanary(config, node.Labels)
+		if err != nil {
+			return nil, nil, err
+		}
+		if canary {
+			canaryNodes = append(canaryNodes, node)
+		} else {
+			otherNodes = append(otherNodes, node)
+		}
+	}
+	return canaryNodes, otherNodes, nil
+}
+
+// promoteNodeNetworkConfig promotes the latest generation, which is released to nodes other than canary
+// ones in batches if config has rollout
+func promoteNodeNetworkConfig(config *networkingv1.NodeNetworkConfig, status *networkingv1.NodeNetworkConfigStatus, message string) {
+	if (status.Phase == networkingv1.NodeNetworkConfigRolloutPromoted || status.Phase == networkingv1.NodeNetworkConfigRolloutPromoting) &&
+		status.PromotedGeneration == config.Generation {
+		return
+	}
+
+	status.ObservedGeneration = config.Generation
+	status.Phase = networkingv1.NodeNetworkConfigRolloutPromoted
+	if config.Spec.Rollout != nil {
+		status.Phase = networkingv1.NodeNetworkConfigRolloutPromoting
+	}
+	status.PromotedGeneration = config.Generation
+	status.PromotedSpec = config.Spec.DeepCopy()
+	status.Nodes, status.UpdatedNodes = 0, 0
+	status.Message = message
+}
+
+// specEqualWithoutRollout checks if two specs are the same for daemons, changes of rollout itself
+// are never rolled out by canary nodes
+func specEqualWithoutRollout(a, b *networkingv1.NodeNetworkConfigSpec) bool {
+	a, b = a.DeepCopy(), b.DeepCopy()
+	a.Rollout, b.Rollout = nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *NodeNetworkConfigRolloutReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(ControllerNodeNetworkConfigRollout).
+		For(&networkingv1.NodeNetworkConfig{},
+			builder.WithPredicates(
+				&utils.IgnoreDeletePredicate{},
+				&predicate.GenerationChangedPredicate{},
+			)).
+		Watches(&source.Kind{Type: &corev1.Node{}},
+			handler.EnqueueRequestsFromMapFunc(func(object client.Object) []reconcile.Request {
+				var configList = &networkingv1.NodeNetworkConfigList{}
+				if err := r.List(context.TODO(), configList); err != nil {
+					return nil
+				}
+
+				var requests []reconcile.Request
+				for _, config := range configList.Items {
+					if config.Status.Phase == networkingv1.NodeNetworkConfigRolloutCanary ||
+						config.Status.Phase == networkingv1.NodeNetworkConfigRolloutPromoting {
+						requests = append(requests, reconcile.Request{
+							NamespacedName: types.NamespacedName{Name: config.Name},
+						})
+					}
+				}
+				return requests
+			}),
+			builder.WithPredicates(
+				&utils.IgnoreDeletePredicate{},
+				predicate.Or(
+					&utils.SpecifiedAnnotationChangedPredicate{
+						AnnotationKeys: []string{constants.AnnotationNodeNetworkConfigRevision},
+					},
+					&predicate.LabelChangedPredicate{},
+				),
+			),
+		).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.Max(),
+			RecoverPanic:            true,
+		}).
+		Complete(r)
+}
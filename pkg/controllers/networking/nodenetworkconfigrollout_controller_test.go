@@ -0,0 +1,209 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+)
+
+func TestNodeNetworkConfigRollout(t *testing.T) {
+	const (
+		configName = "pool"
+		generation = 2
+	)
+	revision := networkingv1.NodeNetworkConfigRevision(configName, generation)
+	oldRevision := networkingv1.NodeNetworkConfigRevision(configName, generation-1)
+
+	// node is named as "<canary|other><index>", reported and released are revisions on annotations
+	newNode := func(name string, canary bool, reported, released string) *corev1.Node {
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Labels:      map[string]string{"pool": "a"},
+				Annotations: map[string]string{},
+			},
+		}
+		if canary {
+			node.Labels["canary"] = "true"
+		}
+		if len(reported) > 0 {
+			node.Annotations[constants.AnnotationNodeNetworkConfigRevision] = reported
+		}
+		if len(released) > 0 {
+			node.Annotations[constants.AnnotationNodeNetworkConfigReleasedRevision] = released
+		}
+		return node
+	}
+
+	newConfig := func(maxUnavailable *intstr.IntOrString, status networkingv1.NodeNetworkConfigStatus) *networkingv1.NodeNetworkConfig {
+		return &networkingv1.NodeNetworkConfig{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       configName,
+				Generation: generation,
+			},
+			Spec: networkingv1.NodeNetworkConfigSpec{
+				NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "a"}},
+				Rollout: &networkingv1.NodeNetworkConfigRollout{
+					CanaryNodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"canary": "true"}},
+					MaxUnavailable:     maxUnavailable,
+				},
+			},
+			Status: status,
+		}
+	}
+
+	canaryStatus := networkingv1.NodeNetworkConfigStatus{
+		ObservedGeneration: generation,
+		Phase:              networkingv1.NodeNetworkConfigRolloutCanary,
+		CanaryStartTime:    &metav1.Time{Time: time.Now()},
+		PromotedGeneration: generation - 1,
+		PromotedSpec:       &networkingv1.NodeNetworkConfigSpec{},
+	}
+	promotingStatus := networkingv1.NodeNetworkConfigStatus{
+		ObservedGeneration: generation,
+		Phase:              networkingv1.NodeNetworkConfigRolloutPromoting,
+		PromotedGeneration: generation,
+		PromotedSpec:       &networkingv1.NodeNetworkConfigSpec{},
+	}
+	percentage := intstr.FromString("50%")
+
+	tests := []struct {
+		name             string
+		config           *networkingv1.NodeNetworkConfig
+		nodes            []*corev1.Node
+		expectedPhase    networkingv1.NodeNetworkConfigRolloutPhase
+		expectedReleased []string
+		expectedUpdated  int32
+	}{
+		{
+			name:   "canary nodes are not verified",
+			config: newConfig(nil, canaryStatus),
+			nodes: []*corev1.Node{
+				newNode("canary0", true, revision, ""),
+				newNode("canary1", true, oldRevision, ""),
+				newNode("other0", false, oldRevision, ""),
+			},
+			expectedPhase: networkingv1.NodeNetworkConfigRolloutCanary,
+		},
+		{
+			name:   "canary nodes are verified and the first batch is released",
+			config: newConfig(nil, canaryStatus),
+			nodes: []*corev1.Node{
+				newNode("canary0", true, revision, ""),
+				newNode("other1", false, oldRevision, ""),
+				newNode("other0", false, oldRevision, ""),
+			},
+			expectedPhase:    networkingv1.NodeNetworkConfigRolloutPromoting,
+			expectedReleased: []string{"other0"},
+		},
+		{
+			name:   "released node is not updated yet",
+			config: newConfig(nil, promotingStatus),
+			nodes: []*corev1.Node{
+				newNode("canary0", true, revision, ""),
+				newNode("other0", false, oldRevision, revision),
+				newNode("other1", false, oldRevision, ""),
+			},
+			expectedPhase:    networkingv1.NodeNetworkConfigRolloutPromoting,
+			expectedReleased: []string{"other0"},
+		},
+		{
+			name:   "next batch is released by percentage",
+			config: newConfig(&percentage, promotingStatus),
+			nodes: []*corev1.Node{
+				newNode("canary0", true, revision, ""),
+				newNode("other0", false, revision, revision),
+				newNode("other1", false, oldRevision, ""),
+				newNode("other2", false, oldRevision, ""),
+				newNode("other3", false, oldRevision, ""),
+			},
+			expectedPhase:    networkingv1.NodeNetworkConfigRolloutPromoting,
+			expectedReleased: []string{"other0", "other1", "other2"},
+			expectedUpdated:  1,
+		},
+		{
+			name:   "all nodes are updated",
+			config: newConfig(nil, promotingStatus),
+			nodes: []*corev1.Node{
+				newNode("canary0", true, revision, ""),
+				newNode("other0", false, revision, revision),
+				newNode("other1", false, revision, ""),
+			},
+			expectedPhase:    networkingv1.NodeNetworkConfigRolloutPromoted,
+			expectedReleased: []string{"other0"},
+			expectedUpdated:  2,
+		},
+	}
+
+	for _, test := range tests {
+		ctx := context.Background()
+		objects := []client.Object{test.config}
+		for _, node := range test.nodes {
+			objects = append(objects, node)
+		}
+
+		c := newFakeClient(objects...)
+		r := &NodeNetworkConfigRolloutReconciler{
+			Client:   c,
+			Recorder: record.NewFakeRecorder(10),
+		}
+
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: configName}}); err != nil {
+			t.Errorf("test %s fails, unexpected error %v", test.name, err)
+			continue
+		}
+
+		config := &networkingv1.NodeNetworkConfig{}
+		if err := c.Get(ctx, types.NamespacedName{Name: configName}, config); err != nil {
+			t.Fatalf("test %s fails, unable to get node network config: %v", test.name, err)
+		}
+		if config.Status.Phase != test.expectedPhase || config.Status.UpdatedNodes != test.expectedUpdated {
+			t.Errorf("test %s fails, expected phase %s with %d updated nodes but got %+v", test.name,
+				test.expectedPhase, test.expectedUpdated, config.Status)
+		}
+
+		nodeList := &corev1.NodeList{}
+		if err := c.List(ctx, nodeList); err != nil {
+			t.Fatalf("test %s fails, unable to list nodes: %v", test.name, err)
+		}
+		var released []string
+		for _, node := range nodeList.Items {
+			if node.Annotations[constants.AnnotationNodeNetworkConfigReleasedRevision] == revision {
+				released = append(released, node.Name)
+			}
+		}
+		sort.Strings(released)
+		if !reflect.DeepEqual(released, test.expectedReleased) {
+			t.Errorf("test %s fails, expected released nodes %v but got %v", test.name, test.expectedReleased, released)
+		}
+	}
+}
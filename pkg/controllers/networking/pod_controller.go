@@ -19,6 +19,7 @@ package networking
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -87,6 +88,7 @@ type PodReconciler struct {
 //+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=pods/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups="",resources=pods/finalizers,verbs=update
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list
 
 func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
 	log := ctrllog.FromContext(ctx)
@@ -94,13 +96,19 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result
 	var (
 		pod         = &corev1.Pod{}
 		networkName string
+		allocating  bool
 	)
 
 	defer func() {
 		if err != nil {
-			log.Error(err, "reconciliation fails")
+			log.Error(err, "reconciliation fails", "reason", ipamtypes.FailureReasonOf(err))
 			if len(pod.UID) > 0 {
-				r.Recorder.Event(pod, corev1.EventTypeWarning, ReasonIPAllocationFail, err.Error())
+				r.Recorder.Event(pod, corev1.EventTypeWarning, ReasonIPAllocationFail, ipamtypes.DescribeAllocationFailure(err))
+			}
+			if allocating {
+				metrics.IPAllocationFailureCounter.
+					WithLabelValues(networkName, string(ipamtypes.FailureReasonOf(err))).
+					Inc()
 			}
 		}
 	}()
@@ -114,8 +122,9 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result
 
 	// We need to reserve ip for terminating, evicted and completed ip-retained pods.
 	// For evicted and completed ip-retained pods, will be not reconciled while getting terminating, because
-	// finalizer is removed.
-	if pod.DeletionTimestamp != nil || utils.PodIsEvicted(pod) || utils.PodIsCompleted(pod) {
+	// finalizer is removed. Failed ip-retained Job pods are also reserved for their retries.
+	var failedIPRetainedJobPod = strategy.OwnByIPRetainedJob(pod) && utils.PodIsFailed(pod)
+	if pod.DeletionTimestamp != nil || utils.PodIsEvicted(pod) || utils.PodIsCompleted(pod) || failedIPRetainedJobPod {
 		var ownedObj client.Object = pod
 
 		// For terminating pods with no controller owner reference, try to get
@@ -145,13 +154,19 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result
 			}
 		}
 
-		if strategy.OwnByStatefulWorkload(ownedObj) {
+		var statefulOwner *metav1.OwnerReference
+		if statefulOwner, err = r.resolveStatefulOwnerOfTerminatingPod(ctx, pod, ownedObj); err != nil {
+			return ctrl.Result{}, wrapError("unable to resolve stateful owner", err)
+		}
+
+		if statefulOwner != nil {
 			// Before pod terminated, should not reserve ip instance because of pre-stop
-			if !utils.PodIsNotRunning(pod) {
+			if !r.podIsNotRunning(ctx, pod) {
 				return ctrl.Result{}, nil
 			}
 
-			if err = r.reserve(ctx, pod); err != nil {
+			// retained IPs of workloads without ordinal pod names go back to the pool of workload
+			if err = r.reserve(ctx, pod, types.DropPodName(!strategy.IsOrdinalWorkloadReference(statefulOwner))); err != nil {
 				return ctrl.Result{}, wrapError("unable to reserve pod", err)
 			}
 			return ctrl.Result{}, wrapError("unable to remove finalizer", r.removeFinalizer(ctx, pod))
@@ -174,7 +189,7 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result
 				}
 
 				// Before pod is not running, should not reserve ip instance because of pre-stop
-				if !utils.PodIsNotRunning(pod) {
+				if !r.podIsNotRunning(ctx, pod) {
 					return ctrl.Result{}, nil
 				}
 
@@ -207,6 +222,24 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result
 			}
 		}
 
+		if strategy.OwnByIPRetainedJob(pod) {
+			// IPs of succeeded job pods will never be reused by retries
+			if utils.PodIsCompleted(pod) {
+				if err = r.decouple(ctx, pod); err != nil {
+					return ctrl.Result{}, wrapError("unable to decouple pod", err)
+				}
+				return ctrl.Result{}, wrapError("unable to remove finalizer", r.removeFinalizer(ctx, pod))
+			}
+
+			// Before pod is not running, should not reserve ip instance because of pre-stop
+			if !r.podIsNotRunning(ctx, pod) {
+				return ctrl.Result{}, nil
+			}
+
+			log.V(1).Info("reserve ip for job pod")
+			return ctrl.Result{}, wrapError("unable to reserve job pod", r.jobReserve(ctx, pod))
+		}
+
 		// For evicted and completed normal pods, pre decouple ip instances for completed or evicted pods
 		if utils.PodIsEvicted(pod) || utils.PodIsCompleted(pod) {
 			return ctrl.Result{}, wrapError("unable to decouple pod", r.decouple(ctx, pod))
@@ -220,6 +253,8 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result
 		return ctrl.Result{}, nil
 	}
 
+	allocating = true
+
 	var (
 		networkStrFromWebhook  string
 		subnetStrFromWebhook   string
@@ -244,15 +279,33 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result
 		return ctrl.Result{}, nil
 	}
 
+	// addresses will be moved to pod by rebinding, no need to allocate
+	var rebindingIPInstances = &networkingv1.IPInstanceList{}
+	if err = r.List(ctx, rebindingIPInstances,
+		client.MatchingFields{IndexerFieldRebindPod: pod.Name},
+		client.InNamespace(pod.Namespace),
+	); err != nil {
+		return ctrl.Result{}, wrapError("unable to list rebinding ip instances of pod", err)
+	}
+	if len(rebindingIPInstances.Items) > 0 {
+		log.V(1).Info("skip allocation for pod which is a rebinding target")
+		return ctrl.Result{}, nil
+	}
+
 	networkName, err = r.selectNetwork(ctx, pod, handledByWebhook, networkStrFromWebhook, networkTypeFromWebhook)
 	if err != nil {
-		return ctrl.Result{}, fmt.Errorf("unable to select network: %v", err)
+		return ctrl.Result{}, fmt.Errorf("unable to select network: %w", err)
+	}
+
+	statefulOwner, err := strategy.ResolveStatefulWorkloadOwner(ctx, pod, r.APIReader)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("unable to resolve stateful owner of pod %v/%v: %v", pod.Namespace, pod.Name, err)
 	}
 
-	if strategy.OwnByStatefulWorkload(pod) {
-		log.V(1).Info("strategic allocation for stateful pod")
+	if statefulOwner != nil {
+		log.V(1).Info("strategic allocation for stateful pod", "owner", statefulOwner.Kind+"/"+statefulOwner.Name)
 		return ctrl.Result{}, wrapError("unable to stateful allocate",
-			r.statefulAllocate(ctx, pod, networkName, subnetStrFromWebhook, handledByWebhook, ipFamily))
+			r.statefulAllocate(ctx, pod, statefulOwner, networkName, subnetStrFromWebhook, handledByWebhook, ipFamily))
 	}
 
 	if feature.VMIPRetainEnabled() {
@@ -265,6 +318,17 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result
 		}
 	}
 
+	jobOwner, err := strategy.ResolveJobIPRetainOwner(ctx, pod, r.APIReader)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("unable to resolve job owner of pod %v/%v: %v", pod.Namespace, pod.Name, err)
+	}
+
+	if jobOwner != nil {
+		log.V(1).Info("strategic allocation for job pod", "owner", jobOwner.Kind+"/"+jobOwner.Name)
+		return ctrl.Result{}, wrapError("unable to job allocate",
+			r.jobAllocate(ctx, pod, jobOwner, networkName, subnetStrFromWebhook, handledByWebhook, ipFamily))
+	}
+
 	return ctrl.Result{}, wrapError("unable to allocate", r.allocate(ctx, pod, networkName,
 		subnetStrFromWebhook, ipFamily, handledByWebhook))
 }
@@ -289,9 +353,48 @@ func (r *PodReconciler) reserve(ctx context.Context, pod *corev1.Pod, reserveOpt
 	return nil
 }
 
+// podIsNotRunning also treats pods on deleted nodes as not running, because their
+// statuses will never be updated by kubelet
+func (r *PodReconciler) podIsNotRunning(ctx context.Context, pod *corev1.Pod) bool {
+	if utils.PodIsNotRunning(pod) {
+		return true
+	}
+
+	if len(pod.Spec.NodeName) == 0 {
+		return false
+	}
+
+	err := r.Get(ctx, apitypes.NamespacedName{Name: pod.Spec.NodeName}, &corev1.Node{})
+	return apierrors.IsNotFound(err)
+}
+
+// resolveStatefulOwnerOfTerminatingPod resolves the stateful owner through controller chain of owned object,
+// intermediate owners (e.g. ReplicaSets of Argo Rollouts) may be removed before pod terminates, so the
+// stateful owner recorded in IP instances of pod will be used as a fallback.
+func (r *PodReconciler) resolveStatefulOwnerOfTerminatingPod(ctx context.Context, pod *corev1.Pod,
+	ownedObj client.Object) (*metav1.OwnerReference, error) {
+	statefulOwner, err := strategy.ResolveStatefulWorkloadOwner(ctx, ownedObj, r.APIReader)
+	if err != nil || statefulOwner != nil || strategy.OwnerReferenceMaxDepth <= 1 {
+		return statefulOwner, err
+	}
+
+	var allocatedIPs []*networkingv1.IPInstance
+	if allocatedIPs, err = utils.ListAllocatedIPInstancesOfPod(ctx, r, pod); err != nil {
+		return nil, err
+	}
+
+	for _, ipInstance := range allocatedIPs {
+		if ref := metav1.GetControllerOf(ipInstance); strategy.IsStatefulWorkloadReference(ref) {
+			return ref, nil
+		}
+	}
+	return nil, nil
+}
+
 // selectNetwork will pick the hit network by pod, taking the priority as below
 // 1. explicitly specify network in pod annotations/labels
-// 2. parse network type from pod and select a corresponding network binding on node
+// 2. parse network type from pod and elect a network of the type by default policies
+// 3. select a corresponding network binding on node
 func (r *PodReconciler) selectNetwork(ctx context.Context, pod *corev1.Pod, handledByWebhook bool,
 	networkStrFromWebhook string, networkTypeFromWebhook types.NetworkType) (string, error) {
 	var specifiedNetwork string
@@ -312,7 +415,8 @@ func (r *PodReconciler) selectNetwork(ctx context.Context, pod *corev1.Pod, hand
 				return "", fmt.Errorf("fail to check network existence: %v", err)
 			}
 			if !exist {
-				return "", fmt.Errorf("specified network %s not found, should check webhook liveness", specifiedNetwork)
+				return "", ipamtypes.NewAllocationError(ipamtypes.FailureNetworkNotFound,
+					"specified network %s not found, should check webhook liveness", specifiedNetwork)
 			}
 		}
 
@@ -327,9 +431,18 @@ func (r *PodReconciler) selectNetwork(ctx context.Context, pod *corev1.Pod, hand
 			pod.Annotations[constants.AnnotationNetworkType], pod.Labels[constants.LabelNetworkType]))
 	}
 
+	// networks elected by default policies take precedence over the ones binding on node
 	var selectedNetworkName string
+	if selectedNetworkName, err = r.electDefaultNetwork(ctx, pod, networkType); err != nil {
+		return "", fmt.Errorf("unable to elect default network: %v", err)
+	}
+
 	switch networkType {
 	case types.Underlay:
+		if len(selectedNetworkName) > 0 {
+			break
+		}
+
 		// try to get underlay network by node name
 		selectedNetworkName, err = r.getNetworkByNodeNameIndexer(ctx, pod.Spec.NodeName)
 		if err != nil {
@@ -337,9 +450,14 @@ func (r *PodReconciler) selectNetwork(ctx context.Context, pod *corev1.Pod, hand
 		}
 
 		if len(selectedNetworkName) == 0 {
-			return "", fmt.Errorf("unable to find underlay network for node %s, should check webhook liveness", pod.Spec.NodeName)
+			return "", ipamtypes.NewAllocationError(ipamtypes.FailureNetworkNotCoveringNode,
+				"unable to find underlay network for node %s, should check webhook liveness", pod.Spec.NodeName)
 		}
 	case types.Overlay:
+		if len(selectedNetworkName) > 0 {
+			break
+		}
+
 		// try to get overlay network by special node name
 		selectedNetworkName, err = r.getNetworkByNodeNameIndexer(ctx, OverlayNodeName)
 		if err != nil {
@@ -347,17 +465,19 @@ func (r *PodReconciler) selectNetwork(ctx context.Context, pod *corev1.Pod, hand
 		}
 
 		if len(selectedNetworkName) == 0 {
-			return "", fmt.Errorf("unable to find overlay network")
+			return "", ipamtypes.NewAllocationError(ipamtypes.FailureNetworkNotFound, "unable to find overlay network")
 		}
 	case types.GlobalBGP:
-		// try to get global bgp network by special node name
-		selectedNetworkName, err = r.getNetworkByNodeNameIndexer(ctx, GlobalBGPNodeName)
-		if err != nil {
-			return "", fmt.Errorf("unable to get overlay network by node name indexer: %v", err)
+		if len(selectedNetworkName) == 0 {
+			// try to get global bgp network by special node name
+			selectedNetworkName, err = r.getNetworkByNodeNameIndexer(ctx, GlobalBGPNodeName)
+			if err != nil {
+				return "", fmt.Errorf("unable to get overlay network by node name indexer: %v", err)
+			}
 		}
 
 		if len(selectedNetworkName) == 0 {
-			return "", fmt.Errorf("unable to find global bgp network")
+			return "", ipamtypes.NewAllocationError(ipamtypes.FailureNetworkNotFound, "unable to find global bgp network")
 		}
 
 		if !handledByWebhook {
@@ -367,12 +487,13 @@ func (r *PodReconciler) selectNetwork(ctx context.Context, pod *corev1.Pod, hand
 				return "", fmt.Errorf("unalbe to get node %s: %v", pod.Spec.NodeName, err)
 			}
 			if _, attached := node.Labels[constants.LabelBGPNetworkAttachment]; !attached {
-				return "", fmt.Errorf("node %s has not attached bgp network, should check webhook liveness", pod.Spec.NodeName)
+				return "", ipamtypes.NewAllocationError(ipamtypes.FailureNetworkNotCoveringNode,
+					"node %s has not attached bgp network, should check webhook liveness", pod.Spec.NodeName)
 			}
 		}
 
 	default:
-		return "", fmt.Errorf("unknown network type %s from pod", networkType)
+		return "", ipamtypes.NewAllocationError(ipamtypes.FailureInvalidRequest, "unknown network type %s from pod", networkType)
 	}
 
 	if !handledByWebhook {
@@ -382,9 +503,135 @@ func (r *PodReconciler) selectNetwork(ctx context.Context, pod *corev1.Pod, hand
 		}
 	}
 
+	// network selected by node is not validated by webhook, so visibility must be checked here
+	if err = r.checkNetworkVisibility(ctx, pod.Namespace, selectedNetworkName); err != nil {
+		return "", err
+	}
+
 	return selectedNetworkName, nil
 }
 
+// electDefaultNetwork elects a network of the type for pod by default policies, among the ones visible
+// to namespace of pod and, for underlay networks, covering node of pod. Empty means no network is elected.
+func (r *PodReconciler) electDefaultNetwork(ctx context.Context, pod *corev1.Pod, networkType types.NetworkType) (string, error) {
+	networkList, err := utils.ListNetworks(ctx, r)
+	if err != nil {
+		return "", fmt.Errorf("unable to list networks: %v", err)
+	}
+
+	var (
+		ns               *corev1.Namespace
+		eligibleNetworks = map[string]bool{}
+	)
+	for i := range networkList.Items {
+		var network = &networkList.Items[i]
+		if network.Spec.Default == nil ||
+			ipamtypes.ParseNetworkTypeFromString(string(networkingv1.GetNetworkType(network))) != networkType {
+			continue
+		}
+
+		if networkType == types.Underlay {
+			if _, covered := globalutils.StringSliceToMap(network.Status.NodeList)[pod.Spec.NodeName]; !covered {
+				continue
+			}
+		}
+
+		if network.Spec.NamespaceSelector != nil {
+			if ns == nil {
+				ns = &corev1.Namespace{}
+				if err = r.Get(ctx, apitypes.NamespacedName{Name: pod.Namespace}, ns); err != nil {
+					return "", fmt.Errorf("unable to get namespace %s: %v", pod.Namespace, err)
+				}
+			}
+
+			visible, err := networkingv1.IsNetworkVisibleToNamespace(network, ns.Labels)
+			if err != nil {
+				return "", fmt.Errorf("unable to check visibility of network %s: %v", network.Name, err)
+			}
+			if !visible {
+				continue
+			}
+		}
+
+		eligibleNetworks[network.Name] = true
+	}
+
+	if len(eligibleNetworks) == 0 {
+		return "", nil
+	}
+
+	return r.IPAMManager.ElectDefaultNetwork(networkType, ipamtypes.PodInfo{
+		NamespacedName: apitypes.NamespacedName{Namespace: pod.Namespace, Name: pod.Name},
+	}, func(networkName string) bool {
+		return eligibleNetworks[networkName]
+	}), nil
+}
+
+func (r *PodReconciler) checkNetworkVisibility(ctx context.Context, namespace, networkName string) error {
+	var network = &networkingv1.Network{}
+	if err := r.Get(ctx, apitypes.NamespacedName{Name: networkName}, network); err != nil {
+		return fmt.Errorf("unable to get network %s: %v", networkName, err)
+	}
+
+	if network.Spec.NamespaceSelector == nil {
+		return nil
+	}
+
+	var ns = &corev1.Namespace{}
+	if err := r.Get(ctx, apitypes.NamespacedName{Name: namespace}, ns); err != nil {
+		return fmt.Errorf("unable to get namespace %s: %v", namespace, err)
+	}
+
+	visible, err := networkingv1.IsNetworkVisibleToNamespace(network, ns.Labels)
+	if err != nil {
+		return fmt.Errorf("unable to check visibility of network %s: %v", networkName, err)
+	}
+	if !visible {
+		return ipamtypes.NewAllocationError(ipamtypes.FailureNetworkNotVisible,
+			"network %s is not visible to namespace %s", networkName, namespace)
+	}
+	return nil
+}
+
+// checkVisibilityOfIPCandidates checks that network and the subnets containing IP candidates
+// are all visible to namespace
+func (r *PodReconciler) checkVisibilityOfIPCandidates(ctx context.Context, namespace, networkName string,
+	ipCandidates []ipCandidate) error {
+	if err := r.checkNetworkVisibility(ctx, namespace, networkName); err != nil {
+		return err
+	}
+
+	subnetList, err := utils.ListSubnets(ctx, r, client.MatchingFields{IndexerFieldNetwork: networkName})
+	if err != nil {
+		return fmt.Errorf("unable to list subnets of network %s: %v", networkName, err)
+	}
+
+	var ns *corev1.Namespace
+	for _, candidate := range ipCandidates {
+		subnet := networkingv1.GetSubnetOfAddress(subnetList.Items, candidate.ip)
+		if subnet == nil || subnet.Spec.Network != networkName || subnet.Spec.NamespaceSelector == nil {
+			continue
+		}
+
+		if ns == nil {
+			ns = &corev1.Namespace{}
+			if err = r.Get(ctx, apitypes.NamespacedName{Name: namespace}, ns); err != nil {
+				return fmt.Errorf("unable to get namespace %s: %v", namespace, err)
+			}
+		}
+
+		visible, err := networkingv1.IsSubnetVisibleToNamespace(subnet, ns.Labels)
+		if err != nil {
+			return fmt.Errorf("unable to check visibility of subnet %s: %v", subnet.Name, err)
+		}
+		if !visible {
+			return ipamtypes.NewAllocationError(ipamtypes.FailureNetworkNotVisible,
+				"subnet %s of assigned ip %s is not visible to namespace %s", subnet.Name, candidate.ip, namespace)
+		}
+	}
+	return nil
+}
+
 func (r *PodReconciler) getNetworkByNodeNameIndexer(ctx context.Context, nodeName string) (string, error) {
 	var networkList *networkingv1.NetworkList
 	var err error
@@ -401,8 +648,8 @@ func (r *PodReconciler) getNetworkByNodeNameIndexer(ctx context.Context, nodeNam
 
 // statefulAllocate means an allocation on a stateful pod, including some
 // special features, ip retain, ip reuse or ip assignment
-func (r *PodReconciler) statefulAllocate(ctx context.Context, pod *corev1.Pod, networkName, subnetStrFromWebhook string,
-	handledByWebhook bool, ipFamily types.IPFamilyMode) (err error) {
+func (r *PodReconciler) statefulAllocate(ctx context.Context, pod *corev1.Pod, statefulOwner *metav1.OwnerReference,
+	networkName, subnetStrFromWebhook string, handledByWebhook bool, ipFamily types.IPFamilyMode) (err error) {
 	var (
 		shouldObserve = true
 		startTime     = time.Now()
@@ -437,6 +684,19 @@ func (r *PodReconciler) statefulAllocate(ctx context.Context, pod *corev1.Pod, n
 		}
 	}
 
+	// IP instances should be owned by the resolved stateful workload, which might
+	// not be the direct controller of pod
+	var ownerReference = types.OwnerReference(*statefulOwner)
+
+	// pods of stateful workloads without ordinal names never come back with the same names, so
+	// their retained IPs are kept in a pool of the workload, like the ones of Jobs
+	var poolLabels client.MatchingLabels
+	if !strategy.IsOrdinalWorkloadReference(statefulOwner) {
+		poolLabels = client.MatchingLabels{
+			constants.LabelStatefulOwner: string(statefulOwner.UID),
+		}
+	}
+
 	// expectReallocate means that ip is expected to be released and allocated again, usually
 	// this will be set true when ip is leaking
 	// 1. global retain and pod retain or unset, ip should be retained
@@ -449,6 +709,13 @@ func (r *PodReconciler) statefulAllocate(ctx context.Context, pod *corev1.Pod, n
 	// if pre-assigned through annotation, this must be false
 	var shouldReallocate = expectReallocate && !preAssign
 
+	// retained addresses in drained subnets should not be reused, so that pod can be moved off
+	if !shouldReallocate && !preAssign {
+		if shouldReallocate, err = r.retainedInDrainedSubnet(ctx, pod); err != nil {
+			return wrapError("unable to check drained subnets of retained ips", err)
+		}
+	}
+
 	if shouldReallocate {
 		var allocatedIPs []*networkingv1.IPInstance
 		if allocatedIPs, err = utils.ListAllocatedIPInstancesOfPod(ctx, r, pod); err != nil {
@@ -463,7 +730,7 @@ func (r *PodReconciler) statefulAllocate(ctx context.Context, pod *corev1.Pod, n
 		}
 
 		return wrapError("unable to reallocate", r.allocate(ctx, pod, networkName,
-			subnetStrFromWebhook, ipFamily, handledByWebhook, specifiedMACAddr))
+			subnetStrFromWebhook, ipFamily, handledByWebhook, specifiedMACAddr, ownerReference, types.AdditionalLabels(poolLabels)))
 	}
 
 	var (
@@ -493,6 +760,11 @@ func (r *PodReconciler) statefulAllocate(ctx context.Context, pod *corev1.Pod, n
 				ip: normalizedIP,
 			})
 		}
+		// subnets of pre-assigned IPs are resolved from addresses rather than validated by webhook
+		if err = r.checkVisibilityOfIPCandidates(ctx, pod.Namespace, networkName, ipCandidates); err != nil {
+			return err
+		}
+
 		// pre assignment can force using reserved IPs
 		forceAssign = true
 	} else {
@@ -512,18 +784,29 @@ func (r *PodReconciler) statefulAllocate(ctx context.Context, pod *corev1.Pod, n
 			})
 		}
 
+		// reserved IPs in pool of workload are taken by force, as the ones of Jobs
+		if len(ipCandidates) == 0 && len(poolLabels) > 0 {
+			var pooledIPInstances []*networkingv1.IPInstance
+			if pooledIPInstances, err = utils.ListAllocatedIPInstances(ctx, r, poolLabels,
+				client.InNamespace(pod.Namespace)); err != nil {
+				return fmt.Errorf("failed to list retained ip instances for %v %v: %v", statefulOwner.Kind, statefulOwner.Name, err)
+			}
+			ipCandidates = pickReservedIPCandidates(pooledIPInstances, networkName, ipFamily)
+			forceAssign = len(ipCandidates) > 0
+		}
+
 		// when no valid ip found, it means that this is the first time of pod creation
 		if len(ipCandidates) == 0 {
 			// allocate has its own observation process, so just skip
 			shouldObserve = false
 			return wrapError("unable to allocate", r.allocate(ctx, pod, networkName, subnetStrFromWebhook,
-				ipFamily, handledByWebhook, specifiedMACAddr))
+				ipFamily, handledByWebhook, specifiedMACAddr, ownerReference, types.AdditionalLabels(poolLabels)))
 		}
 	}
 
 	// assign IP candidates to pod
 	return wrapError("unable to assign", r.assign(ctx, pod, networkName, ipCandidates, forceAssign,
-		ipFamily, specifiedMACAddr))
+		ipFamily, specifiedMACAddr, ownerReference, types.AdditionalLabels(poolLabels)))
 }
 
 func (r *PodReconciler) vmAllocate(ctx context.Context, pod *corev1.Pod, vmName, networkName, subnetStrFromWebhook string,
@@ -567,6 +850,150 @@ func (r *PodReconciler) vmAllocate(ctx context.Context, pod *corev1.Pod, vmName,
 		types.AdditionalLabels(vmLabels), types.OwnerReference(*vmiOwnerReference)))
 }
 
+// jobAllocate reuses reserved IPs of the Job or CronJob of pod, so that retried pods keep the IPs
+// of failed ones, or allocates new IPs if no reserved IPs can be reused
+func (r *PodReconciler) jobAllocate(ctx context.Context, pod *corev1.Pod, jobOwner *metav1.OwnerReference,
+	networkName, subnetStrFromWebhook string, handledByWebhook bool, ipFamily types.IPFamilyMode) (err error) {
+	// finalizer need to be added before ip allocation, because terminating pod without finalizer will not be reconciled
+	if err = r.addFinalizer(ctx, pod); err != nil {
+		return wrapError("unable to add finalizer for job pod", err)
+	}
+
+	jobLabels := client.MatchingLabels{
+		constants.LabelJobOwner: string(jobOwner.UID),
+	}
+
+	var allocatedIPInstances []*networkingv1.IPInstance
+	if allocatedIPInstances, err = utils.ListAllocatedIPInstances(ctx, r, jobLabels,
+		client.InNamespace(pod.Namespace)); err != nil {
+		return fmt.Errorf("failed to list allocated ip instances for %v %v: %v", jobOwner.Kind, jobOwner.Name, err)
+	}
+
+	ipCandidates := pickReservedIPCandidates(allocatedIPInstances, networkName, ipFamily)
+	if len(ipCandidates) == 0 {
+		return wrapError("unable to allocate", r.allocate(ctx, pod, networkName, subnetStrFromWebhook, ipFamily,
+			handledByWebhook, types.AdditionalLabels(jobLabels), types.OwnerReference(*jobOwner)))
+	}
+
+	// forced assign for using reserved ips
+	return wrapError("unable to assign", r.assign(ctx, pod, networkName, ipCandidates, true, ipFamily,
+		types.AdditionalLabels(jobLabels), types.OwnerReference(*jobOwner)))
+}
+
+// pickReservedIPCandidates picks the reserved IPs of a pool (e.g., of a Job) which are not taken by
+// any pod, at most one for each ip family, nil will be returned if the ip family of pod can not be satisfied
+func pickReservedIPCandidates(ipInstances []*networkingv1.IPInstance, networkName string,
+	ipFamily types.IPFamilyMode) []ipCandidate {
+	var v4Candidate, v6Candidate *ipCandidate
+
+	sort.SliceStable(ipInstances, func(i, j int) bool {
+		return ipInstances[i].Name < ipInstances[j].Name
+	})
+	for _, ipInstance := range ipInstances {
+		if !networkingv1.IsReserved(ipInstance) || len(ipInstance.Spec.Binding.PodName) > 0 ||
+			ipInstance.Spec.Network != networkName {
+			continue
+		}
+
+		candidate := &ipCandidate{
+			subnet: ipInstance.Spec.Subnet,
+			ip:     utils.ToIPFormat(ipInstance.Name),
+		}
+		if networkingv1.IsIPv6IPInstance(ipInstance) {
+			if v6Candidate == nil {
+				v6Candidate = candidate
+			}
+		} else if v4Candidate == nil {
+			v4Candidate = candidate
+		}
+	}
+
+	switch ipFamily {
+	case types.IPv4:
+		if v4Candidate != nil {
+			return []ipCandidate{*v4Candidate}
+		}
+	case types.IPv6:
+		if v6Candidate != nil {
+			return []ipCandidate{*v6Candidate}
+		}
+	case types.DualStack:
+		if v4Candidate != nil && v6Candidate != nil {
+			return []ipCandidate{*v4Candidate, *v6Candidate}
+		}
+	}
+	return nil
+}
+
+// jobReserve reserves IPs of a failed or terminating Job pod for the next pods of its Job or CronJob,
+// reserved IPs will be released after TTL by JobIPRetain controller
+func (r *PodReconciler) jobReserve(ctx context.Context, pod *corev1.Pod) (err error) {
+	var allocatedIPInstances []*networkingv1.IPInstance
+	if allocatedIPInstances, err = utils.ListAllocatedIPInstancesOfPod(ctx, r, pod); err != nil {
+		return fmt.Errorf("failed to list allocated ip instances of pod: %v", err)
+	}
+
+	// ip instances allocated before pod asked for retention can not be reused by other pods
+	for _, ipInstance := range allocatedIPInstances {
+		if len(ipInstance.Labels[constants.LabelJobOwner]) == 0 {
+			if err = r.decouple(ctx, pod); err != nil {
+				return err
+			}
+			return wrapError("unable to remove finalizer", r.removeFinalizer(ctx, pod))
+		}
+	}
+
+	var expireTime = time.Now().Add(strategy.JobIPRetainTTLOfPod(pod)).Format(time.RFC3339)
+	for _, ipInstance := range allocatedIPInstances {
+		patch := client.MergeFrom(ipInstance.DeepCopy())
+		if ipInstance.Annotations == nil {
+			ipInstance.Annotations = map[string]string{}
+		}
+		ipInstance.Annotations[constants.AnnotationIPRetainExpireTime] = expireTime
+		if err = r.Patch(ctx, ipInstance, patch); err != nil {
+			return fmt.Errorf("failed to set expire time of ip instance %v: %v", ipInstance.Name, err)
+		}
+	}
+
+	if err = r.reserve(ctx, pod, types.DropPodName(true)); err != nil {
+		return err
+	}
+
+	for _, ipInstance := range allocatedIPInstances {
+		if err = r.IPAMManager.Reserve(ipInstance.Spec.Network, []types.SubnetIPSuite{
+			ipamtypes.ReserveIPOfSubnet(ipInstance.Spec.Subnet, utils.ToIPFormat(ipInstance.Name)),
+		}); err != nil {
+			return fmt.Errorf("failed to reserve ip %v: %v", ipInstance.Spec.Address.IP, err)
+		}
+	}
+
+	r.PodIPCache.ReleasePod(pod.Name, pod.Namespace)
+	return wrapError("unable to remove finalizer", r.removeFinalizer(ctx, pod))
+}
+
+// retainedInDrainedSubnet checks if any of allocated IPs of pod is in a drained subnet
+func (r *PodReconciler) retainedInDrainedSubnet(ctx context.Context, pod *corev1.Pod) (bool, error) {
+	allocatedIPs, err := utils.ListAllocatedIPInstancesOfPod(ctx, r, pod)
+	if err != nil {
+		return false, err
+	}
+
+	for _, ipInstance := range allocatedIPs {
+		var subnet *networkingv1.Subnet
+		if subnet, err = utils.GetSubnet(ctx, r, ipInstance.Spec.Subnet); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return false, err
+		}
+
+		if networkingv1.IsDrainedSubnet(subnet) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // recycleNonCandidateReservedIPs recycles IPs that should NO LONGER serve given pod, i.e., release
 // reserved IPs that does not appear in candidates.
 func (r *PodReconciler) recycleNonCandidateReservedIPs(ctx context.Context, pod *corev1.Pod, ipCandidates []ipCandidate) (err error) {
@@ -675,18 +1102,38 @@ func (r *PodReconciler) allocate(ctx context.Context, pod *corev1.Pod, networkNa
 			pod.Labels[constants.LabelSpecifiedSubnet])
 	}
 
+	// subnets are selected by node interface of pod if not specified
+	if nodeInterface := pod.Annotations[constants.AnnotationNodeInterface]; len(subnetNameStr) == 0 && len(nodeInterface) > 0 {
+		if subnetNameStr, err = r.selectSubnetsByNodeInterface(ctx, pod.Namespace, networkName, nodeInterface, ipFamily); err != nil {
+			return err
+		}
+	}
+
 	if len(subnetNameStr) > 0 {
 		specifiedSubnetNames = strings.Split(subnetNameStr, "/")
 	}
 
+	// labels of scheduled node are used for preferring subnets by topology
+	var nodeLabels map[string]string
+	if len(specifiedSubnetNames) == 0 && len(pod.Spec.NodeName) > 0 {
+		var node = &corev1.Node{}
+		if err = r.Get(ctx, apitypes.NamespacedName{Name: pod.Spec.NodeName}, node); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return fmt.Errorf("unable to get node %s: %v", pod.Spec.NodeName, err)
+			}
+			err = nil
+		}
+		nodeLabels = node.Labels
+	}
+
 	if allocatedIPs, err = r.IPAMManager.Allocate(networkName, ipamtypes.PodInfo{
 		NamespacedName: apitypes.NamespacedName{
 			Namespace: pod.Namespace,
 			Name:      pod.Name,
 		},
 		IPFamily: ipFamily,
-	}, ipamtypes.AllocateSubnets(specifiedSubnetNames)); err != nil {
-		return fmt.Errorf("unable to allocate IP on family %s : %v", ipFamily, err)
+	}, ipamtypes.AllocateSubnets(specifiedSubnetNames), ipamtypes.AllocateNodeLabels(nodeLabels)); err != nil {
+		return fmt.Errorf("unable to allocate IP on family %s : %w", ipFamily, err)
 	}
 
 	defer func() {
@@ -706,6 +1153,75 @@ func (r *PodReconciler) allocate(ctx context.Context, pod *corev1.Pod, networkNa
 	return nil
 }
 
+// selectSubnetsByNodeInterface picks the subnets of network on node interface for every ip family of pod,
+// subnets with available addresses are preferred, the result is in format of specified subnet string.
+// Private, cordoned and invisible subnets are never picked, as they are not checked by webhook like
+// the specified ones.
+func (r *PodReconciler) selectSubnetsByNodeInterface(ctx context.Context, namespace, networkName, nodeInterface string,
+	ipFamily types.IPFamilyMode) (string, error) {
+	subnetList, err := utils.ListSubnets(ctx, r)
+	if err != nil {
+		return "", fmt.Errorf("unable to list subnets: %v", err)
+	}
+
+	sort.SliceStable(subnetList.Items, func(i, j int) bool {
+		// subnets with available addresses go first
+		iAvailable, jAvailable := subnetList.Items[i].Status.Available > 0, subnetList.Items[j].Status.Available > 0
+		if iAvailable != jAvailable {
+			return iAvailable
+		}
+		return subnetList.Items[i].Name < subnetList.Items[j].Name
+	})
+
+	var (
+		v4Subnet, v6Subnet string
+		ns                 *corev1.Namespace
+	)
+	for i := range subnetList.Items {
+		var subnet = &subnetList.Items[i]
+		if subnet.Spec.Network != networkName || !subnet.DeletionTimestamp.IsZero() ||
+			networkingv1.IsPrivateSubnet(subnet) || networkingv1.IsCordonedSubnet(subnet) ||
+			networkingv1.GetSubnetNodeInterface(&subnet.Spec) != nodeInterface {
+			continue
+		}
+
+		if networkingv1.IsNamespaceRestrictedSubnet(subnet) {
+			if ns == nil {
+				ns = &corev1.Namespace{}
+				if err = r.Get(ctx, apitypes.NamespacedName{Name: namespace}, ns); err != nil {
+					return "", fmt.Errorf("unable to get namespace %s: %v", namespace, err)
+				}
+			}
+
+			visible, err := networkingv1.IsSubnetVisibleToNamespace(subnet, ns.Labels)
+			if err != nil {
+				return "", fmt.Errorf("unable to check visibility of subnet %s: %v", subnet.Name, err)
+			}
+			if !visible {
+				continue
+			}
+		}
+
+		if subnet.Spec.Range.Version == networkingv1.IPv6 {
+			if len(v6Subnet) == 0 {
+				v6Subnet = subnet.Name
+			}
+		} else if len(v4Subnet) == 0 {
+			v4Subnet = subnet.Name
+		}
+	}
+
+	switch {
+	case ipFamily == types.IPv4 && len(v4Subnet) > 0:
+		return v4Subnet, nil
+	case ipFamily == types.IPv6 && len(v6Subnet) > 0:
+		return v6Subnet, nil
+	case ipFamily == types.DualStack && len(v4Subnet) > 0 && len(v6Subnet) > 0:
+		return v4Subnet + "/" + v6Subnet, nil
+	}
+	return "", fmt.Errorf("no %s subnet of network %s on node interface %s", ipFamily, networkName, nodeInterface)
+}
+
 func (r *PodReconciler) addFinalizer(ctx context.Context, pod *corev1.Pod) error {
 	if controllerutil.ContainsFinalizer(pod, constants.FinalizerIPAllocated) {
 		return nil
@@ -67,6 +67,14 @@ func NewPodIPCache(ctx context.Context, c client.Reader, logger logr.Logger) (Po
 		return nil, err
 	}
 
+	// report model versions of ip instances, which shows the progress of migration
+	// and tells whether an old version of model can be dropped
+	var versionReport = map[string]int{}
+	for i := range ipList.Items {
+		versionReport[networkingv1.GetIPInstanceModelVersion(&ipList.Items[i])]++
+	}
+	logger.Info("ip instance model versions", "latest", networkingv1.IPInstanceLatestVersion, "report", versionReport)
+
 	for _, ip := range ipList.Items {
 		if !networkingv1.IsValidIPInstance(&ip) {
 			return nil, fmt.Errorf("get legacy model ip instance, " +
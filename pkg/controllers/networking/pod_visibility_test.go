@@ -0,0 +1,195 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	ipamtypes "github.com/alibaba/hybridnet/pkg/ipam/types"
+)
+
+func TestCheckVisibilityOfIPCandidates(t *testing.T) {
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}}
+	objects := []*networkingv1.Subnet{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "open"},
+			Spec: networkingv1.SubnetSpec{
+				Network: "network1",
+				Range:   networkingv1.AddressRange{CIDR: "192.168.0.0/24"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "restricted"},
+			Spec: networkingv1.SubnetSpec{
+				Network:           "network1",
+				Range:             networkingv1.AddressRange{CIDR: "192.168.1.0/24"},
+				NamespaceSelector: selector,
+			},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		namespaceLabels  map[string]string
+		networkSelector  *metav1.LabelSelector
+		ipCandidates     []ipCandidate
+		expectNotVisible bool
+	}{
+		{
+			name:         "subnet without selector",
+			ipCandidates: []ipCandidate{{ip: "192.168.0.10"}},
+		},
+		{
+			name:            "restricted subnet selecting namespace",
+			namespaceLabels: map[string]string{"team": "a"},
+			ipCandidates:    []ipCandidate{{ip: "192.168.1.10"}},
+		},
+		{
+			name:             "restricted subnet not selecting namespace",
+			namespaceLabels:  map[string]string{"team": "b"},
+			ipCandidates:     []ipCandidate{{ip: "192.168.0.10"}, {ip: "192.168.1.10"}},
+			expectNotVisible: true,
+		},
+		{
+			name:             "network not selecting namespace",
+			namespaceLabels:  map[string]string{"team": "b"},
+			networkSelector:  selector,
+			ipCandidates:     []ipCandidate{{ip: "192.168.0.10"}},
+			expectNotVisible: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := &PodReconciler{
+				Client: newFakeClient(
+					&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1", Labels: test.namespaceLabels}},
+					&networkingv1.Network{
+						ObjectMeta: metav1.ObjectMeta{Name: "network1"},
+						Spec:       networkingv1.NetworkSpec{NamespaceSelector: test.networkSelector},
+					},
+					objects[0].DeepCopy(),
+					objects[1].DeepCopy(),
+				),
+			}
+
+			err := r.checkVisibilityOfIPCandidates(context.Background(), "ns1", "network1", test.ipCandidates)
+			notVisible := errors.Is(err, ipamtypes.ErrNetworkNotVisible)
+			if err != nil && !notVisible {
+				t.Fatalf("test %s fails, unexpected error: %v", test.name, err)
+			}
+			if notVisible != test.expectNotVisible {
+				t.Errorf("test %s fails, expected not visible %t but got error %v", test.name, test.expectNotVisible, err)
+			}
+		})
+	}
+}
+
+func TestSelectSubnetsByNodeInterface(t *testing.T) {
+	newSubnet := func(name, cidr string, mutate func(config *networkingv1.SubnetConfig, spec *networkingv1.SubnetSpec)) *networkingv1.Subnet {
+		subnet := &networkingv1.Subnet{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: networkingv1.SubnetSpec{
+				Network: "network1",
+				Range:   networkingv1.AddressRange{Version: networkingv1.IPv4, CIDR: cidr},
+				Config:  &networkingv1.SubnetConfig{NodeInterface: "eth1"},
+			},
+		}
+		if mutate != nil {
+			mutate(subnet.Spec.Config, &subnet.Spec)
+		}
+		return subnet
+	}
+	enabled := true
+
+	tests := []struct {
+		name         string
+		subnets      []*networkingv1.Subnet
+		expectSubnet string
+		expectErr    bool
+	}{
+		{
+			name:         "subnet on node interface",
+			subnets:      []*networkingv1.Subnet{newSubnet("subnet1", "192.168.0.0/24", nil)},
+			expectSubnet: "subnet1",
+		},
+		{
+			name: "private subnet",
+			subnets: []*networkingv1.Subnet{
+				newSubnet("subnet1", "192.168.0.0/24", func(config *networkingv1.SubnetConfig, _ *networkingv1.SubnetSpec) {
+					config.Private = &enabled
+				}),
+			},
+			expectErr: true,
+		},
+		{
+			name: "cordoned subnet",
+			subnets: []*networkingv1.Subnet{
+				newSubnet("subnet1", "192.168.0.0/24", func(config *networkingv1.SubnetConfig, _ *networkingv1.SubnetSpec) {
+					config.Cordon = &enabled
+				}),
+				newSubnet("subnet2", "192.168.1.0/24", nil),
+			},
+			expectSubnet: "subnet2",
+		},
+		{
+			name: "subnet invisible to namespace",
+			subnets: []*networkingv1.Subnet{
+				newSubnet("subnet1", "192.168.0.0/24", func(_ *networkingv1.SubnetConfig, spec *networkingv1.SubnetSpec) {
+					spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"team": "b"}}
+				}),
+			},
+			expectErr: true,
+		},
+		{
+			name: "subnet visible to namespace",
+			subnets: []*networkingv1.Subnet{
+				newSubnet("subnet1", "192.168.0.0/24", func(_ *networkingv1.SubnetConfig, spec *networkingv1.SubnetSpec) {
+					spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}}
+				}),
+			},
+			expectSubnet: "subnet1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			objects := []client.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1", Labels: map[string]string{"team": "a"}}},
+			}
+			for _, subnet := range test.subnets {
+				objects = append(objects, subnet)
+			}
+			r := &PodReconciler{Client: newFakeClient(objects...)}
+
+			subnet, err := r.selectSubnetsByNodeInterface(context.Background(), "ns1", "network1", "eth1", ipamtypes.IPv4)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("test %s fails, expected error %t but got %v", test.name, test.expectErr, err)
+			}
+			if subnet != test.expectSubnet {
+				t.Errorf("test %s fails, expected subnet %q but got %q", test.name, test.expectSubnet, subnet)
+			}
+		})
+	}
+}
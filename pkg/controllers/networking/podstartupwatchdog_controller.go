@@ -0,0 +1,324 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/controllers/concurrency"
+	"github.com/alibaba/hybridnet/pkg/controllers/utils"
+	"github.com/alibaba/hybridnet/pkg/managerconfig"
+	"github.com/alibaba/hybridnet/pkg/metrics"
+	globalutils "github.com/alibaba/hybridnet/pkg/utils"
+)
+
+const ControllerPodStartupWatchdog = "PodStartupWatchdog"
+
+const ReasonPodStartupStuck = "PodStartupStuck"
+
+const defaultPodStartupTimeout = 5 * time.Minute
+
+// labels of hybridnet daemon pods, which are checked for diagnosis of stuck pods
+var daemonPodLabels = client.MatchingLabels{
+	"app":       "hybridnet",
+	"component": "daemon",
+}
+
+// PodStartupWatchdogReconciler flags the scheduled pods which still have no ip instance after the startup
+// timeout, every stuck pod gets a warning event aggregating the states of webhook, IPAM and daemon for it,
+// and the number of stuck pods is exported as a gauge
+type PodStartupWatchdogReconciler struct {
+	client.Client
+
+	// APIReader reads events and daemon pods which are not cached by manager
+	APIReader client.Reader
+
+	Recorder record.EventRecorder
+
+	PodSelector utils.PodSelector
+
+	// Config provides the startup timeout which can be changed at runtime
+	Config *managerconfig.Store
+
+	mu        sync.Mutex
+	stuckPods map[types.NamespacedName]types.UID
+
+	concurrency.ControllerConcurrency
+}
+
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=events,verbs=list;create;patch
+//+kubebuilder:rbac:groups=networking.alibaba.com,resources=ipinstances,verbs=get;list;watch
+
+func (r *PodStartupWatchdogReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	log := ctrllog.FromContext(ctx)
+
+	defer func() {
+		if err != nil {
+			log.Error(err, "reconciliation fails")
+		}
+	}()
+
+	var timeout = r.startupTimeout()
+	if timeout <= 0 {
+		r.forget(req.NamespacedName)
+		return ctrl.Result{}, nil
+	}
+
+	var pod = &corev1.Pod{}
+	if err = r.Get(ctx, req.NamespacedName, pod); err != nil {
+		r.forget(req.NamespacedName)
+		return ctrl.Result{}, wrapError("unable to fetch Pod", client.IgnoreNotFound(err))
+	}
+
+	if !podNeedsAddresses(pod) {
+		r.forget(req.NamespacedName)
+		return ctrl.Result{}, nil
+	}
+
+	ipInstances, err := utils.ListAllocatedIPInstancesOfPod(ctx, r, pod)
+	if err != nil {
+		return ctrl.Result{}, wrapError("unable to list ip instances of pod", err)
+	}
+	if len(ipInstances) > 0 {
+		r.forget(req.NamespacedName)
+		return ctrl.Result{}, nil
+	}
+
+	waiting := time.Since(podScheduledTime(pod))
+	if waiting < timeout {
+		return ctrl.Result{RequeueAfter: timeout - waiting}, nil
+	}
+
+	if !r.flag(req.NamespacedName, pod.UID) {
+		return ctrl.Result{}, nil
+	}
+
+	diagnosis := r.diagnose(ctx, pod, waiting)
+	r.Recorder.Event(pod, corev1.EventTypeWarning, ReasonPodStartupStuck, diagnosis)
+	log.Info("pod is stuck without ip instance", "diagnosis", diagnosis)
+	return ctrl.Result{}, nil
+}
+
+func (r *PodStartupWatchdogReconciler) startupTimeout() time.Duration {
+	if config := r.Config.Get(); config != nil {
+		return config.PodStartupTimeout
+	}
+	return defaultPodStartupTimeout
+}
+
+// flag records pod as stuck and returns true if it was not flagged before
+func (r *PodStartupWatchdogReconciler) flag(name types.NamespacedName, uid types.UID) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.stuckPods == nil {
+		r.stuckPods = map[types.NamespacedName]types.UID{}
+	}
+	if flaggedUID, exist := r.stuckPods[name]; exist && flaggedUID == uid {
+		return false
+	}
+
+	r.stuckPods[name] = uid
+	metrics.StuckPodGauge.Set(float64(len(r.stuckPods)))
+	return true
+}
+
+// forget removes pod from the stuck ones
+func (r *PodStartupWatchdogReconciler) forget(name types.NamespacedName) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exist := r.stuckPods[name]; !exist {
+		return
+	}
+
+	delete(r.stuckPods, name)
+	metrics.StuckPodGauge.Set(float64(len(r.stuckPods)))
+}
+
+// diagnose aggregates the states of webhook, IPAM and daemon for a stuck pod, failures of
+// lookups are reported in the diagnosis instead of blocking the event
+func (r *PodStartupWatchdogReconciler) diagnose(ctx context.Context, pod *corev1.Pod, waiting time.Duration) string {
+	var diagnosis = []string{
+		fmt.Sprintf("no ip instance after %v on node %s", waiting.Round(time.Second), pod.Spec.NodeName),
+	}
+
+	if globalutils.ParseBoolOrDefault(pod.Annotations[constants.AnnotationHandledByWebhook], false) {
+		diagnosis = append(diagnosis, "webhook: handled")
+	} else {
+		diagnosis = append(diagnosis, "webhook: not handled, network config is parsed by manager")
+	}
+
+	diagnosis = append(diagnosis, fmt.Sprintf("ipam: network %q, subnet %q, ip family %q, %s",
+		globalutils.PickFirstNonEmptyString(pod.Annotations[constants.AnnotationSpecifiedNetwork],
+			pod.Labels[constants.LabelSpecifiedNetwork]),
+		globalutils.PickFirstNonEmptyString(pod.Annotations[constants.AnnotationSpecifiedSubnet],
+			pod.Labels[constants.LabelSpecifiedSubnet]),
+		pod.Annotations[constants.AnnotationIPFamily],
+		r.lastAllocationFailure(ctx, pod)))
+
+	diagnosis = append(diagnosis, "daemon: "+r.daemonState(ctx, pod.Spec.NodeName))
+
+	return strings.Join(diagnosis, "; ")
+}
+
+// lastAllocationFailure returns the message of the latest allocation failure event of pod
+func (r *PodStartupWatchdogReconciler) lastAllocationFailure(ctx context.Context, pod *corev1.Pod) string {
+	var eventList = &corev1.EventList{}
+	if err := r.APIReader.List(ctx, eventList,
+		client.InNamespace(pod.Namespace),
+		client.MatchingFields{
+			"involvedObject.uid": string(pod.UID),
+			"reason":             ReasonIPAllocationFail,
+		},
+	); err != nil {
+		return fmt.Sprintf("unable to list events: %v", err)
+	}
+
+	var latest *corev1.Event
+	for i := range eventList.Items {
+		if latest == nil || latest.LastTimestamp.Before(&eventList.Items[i].LastTimestamp) {
+			latest = &eventList.Items[i]
+		}
+	}
+
+	if latest == nil {
+		return "no allocation failure recorded"
+	}
+	return fmt.Sprintf("last allocation failure: %s", latest.Message)
+}
+
+// daemonState returns whether the daemon pod on node is ready
+func (r *PodStartupWatchdogReconciler) daemonState(ctx context.Context, nodeName string) string {
+	var podList = &corev1.PodList{}
+	if err := r.APIReader.List(ctx, podList, daemonPodLabels, client.MatchingFields{
+		"spec.nodeName": nodeName,
+	}); err != nil {
+		return fmt.Sprintf("unable to list daemon pods: %v", err)
+	}
+
+	for i := range podList.Items {
+		daemonPod := &podList.Items[i]
+		for _, condition := range daemonPod.Status.Conditions {
+			if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+				return fmt.Sprintf("%s/%s is ready", daemonPod.Namespace, daemonPod.Name)
+			}
+		}
+		return fmt.Sprintf("%s/%s is not ready", daemonPod.Namespace, daemonPod.Name)
+	}
+
+	return "not found on node"
+}
+
+// podNeedsAddresses means pod is scheduled and waiting for ip instances to start
+func podNeedsAddresses(pod *corev1.Pod) bool {
+	return !pod.Spec.HostNetwork && utils.PodIsScheduled(pod) && pod.DeletionTimestamp.IsZero() &&
+		!utils.PodIsFailed(pod) && pod.Status.Phase != corev1.PodSucceeded
+}
+
+// podScheduledTime returns when pod is scheduled, or the creation time of pod if unknown
+func podScheduledTime(pod *corev1.Pod) time.Time {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionTrue &&
+			!condition.LastTransitionTime.IsZero() {
+			return condition.LastTransitionTime.Time
+		}
+	}
+	return pod.CreationTimestamp.Time
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PodStartupWatchdogReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(ControllerPodStartupWatchdog).
+		For(&corev1.Pod{},
+			builder.WithPredicates(
+				// deleted pods are forgotten by reconciliation
+				&predicate.ResourceVersionChangedPredicate{},
+				predicate.NewPredicateFuncs(func(obj client.Object) bool {
+					pod, ok := obj.(*corev1.Pod)
+					if !ok || pod.Spec.HostNetwork || !utils.PodIsScheduled(pod) {
+						return false
+					}
+					return r.PodSelector == nil || r.PodSelector.Matches(pod)
+				}),
+			)).
+		// stuck pods are unflagged once they get ip instances
+		Watches(&source.Kind{Type: &networkingv1.IPInstance{}},
+			handler.EnqueueRequestsFromMapFunc(func(object client.Object) []reconcile.Request {
+				ipInstance, ok := object.(*networkingv1.IPInstance)
+				if !ok {
+					return nil
+				}
+
+				podName := networkingv1.FetchBindingPodName(ipInstance)
+				if len(podName) == 0 {
+					return nil
+				}
+				return []reconcile.Request{
+					{
+						NamespacedName: types.NamespacedName{
+							Namespace: ipInstance.Namespace,
+							Name:      podName,
+						},
+					},
+				}
+			}),
+			builder.WithPredicates(
+				predicate.Funcs{
+					CreateFunc: func(event.CreateEvent) bool {
+						return true
+					},
+					UpdateFunc: func(event.UpdateEvent) bool {
+						return false
+					},
+					DeleteFunc: func(event.DeleteEvent) bool {
+						return false
+					},
+					GenericFunc: func(event.GenericEvent) bool {
+						return false
+					},
+				},
+			),
+		).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.Max(),
+			RecoverPanic:            true,
+		}).
+		Complete(r)
+}
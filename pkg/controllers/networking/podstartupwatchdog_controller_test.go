@@ -0,0 +1,231 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/managerconfig"
+)
+
+func TestPodStartupWatchdogReconcile(t *testing.T) {
+	const namespace = "default"
+
+	newPod := func(name string, scheduledBefore time.Duration, hostNetwork bool) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   namespace,
+				UID:         types.UID(name + "-uid"),
+				Annotations: map[string]string{constants.AnnotationHandledByWebhook: "true"},
+				Labels:      map[string]string{constants.LabelSpecifiedNetwork: "underlay"},
+			},
+			Spec: corev1.PodSpec{
+				NodeName:    "node0",
+				HostNetwork: hostNetwork,
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodPending,
+				Conditions: []corev1.PodCondition{
+					{
+						Type:               corev1.PodScheduled,
+						Status:             corev1.ConditionTrue,
+						LastTransitionTime: metav1.NewTime(time.Now().Add(-scheduledBefore)),
+					},
+				},
+			},
+		}
+	}
+
+	daemonPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "hybridnet-daemon-x",
+			Namespace: "kube-system",
+			Labels:    daemonPodLabels,
+		},
+		Spec: corev1.PodSpec{NodeName: "node0"},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}
+
+	allocationFailure := &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: "stuck.1", Namespace: namespace},
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Namespace: namespace, Name: "stuck", UID: "stuck-uid"},
+		Reason:         ReasonIPAllocationFail,
+		Message:        "no available ip in network underlay",
+		LastTimestamp:  metav1.Now(),
+	}
+
+	ipInstance := &networkingv1.IPInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "192-168-0-10",
+			Namespace: namespace,
+			Labels:    map[string]string{constants.LabelPod: "allocated"},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		pod          string
+		timeout      time.Duration
+		expectEvents []string
+		expectStuck  bool
+		expectResult ctrl.Result
+	}{
+		{
+			name:    "pod not found",
+			pod:     "missing",
+			timeout: time.Minute,
+		},
+		{
+			name:    "host network pod",
+			pod:     "host",
+			timeout: time.Minute,
+		},
+		{
+			name:    "pod with ip instance",
+			pod:     "allocated",
+			timeout: time.Minute,
+		},
+		{
+			name:         "pod waiting within timeout",
+			pod:          "waiting",
+			timeout:      time.Hour,
+			expectResult: ctrl.Result{RequeueAfter: 50 * time.Minute},
+		},
+		{
+			name:    "stuck pod",
+			pod:     "stuck",
+			timeout: time.Minute,
+			// condition times are stored with second precision, so the waiting
+			// duration in the event may be a second longer than the pod's age
+			expectEvents: []string{
+				"no ip instance after 10m",
+				"s on node node0",
+				"webhook: handled",
+				`ipam: network "underlay"`,
+				"last allocation failure: no available ip in network underlay",
+				"daemon: kube-system/hybridnet-daemon-x is ready",
+			},
+			expectStuck: true,
+		},
+		{
+			name: "watchdog disabled",
+			pod:  "stuck",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			objects := []client.Object{
+				newPod("host", 10*time.Minute, true),
+				newPod("allocated", 10*time.Minute, false),
+				newPod("waiting", 10*time.Minute, false),
+				newPod("stuck", 10*time.Minute, false),
+				ipInstance,
+			}
+			apiReader := newFakeClient(daemonPod, allocationFailure)
+			recorder := record.NewFakeRecorder(10)
+			r := &PodStartupWatchdogReconciler{
+				Client:    newFakeClient(objects...),
+				APIReader: apiReader,
+				Recorder:  recorder,
+				Config:    managerconfig.NewStore(managerconfig.Configuration{PodStartupTimeout: test.timeout}),
+			}
+
+			request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: test.pod}}
+			result, err := r.Reconcile(context.Background(), request)
+			if err != nil {
+				t.Fatalf("test %s fails, unexpected error: %v", test.name, err)
+			}
+			// requeue duration is rounded since time passes during reconciliation
+			if result.RequeueAfter.Round(time.Minute) != test.expectResult.RequeueAfter {
+				t.Errorf("test %s fails, expected result %v but got %v", test.name, test.expectResult, result)
+			}
+
+			// stuck pods are flagged only once
+			if _, err = r.Reconcile(context.Background(), request); err != nil {
+				t.Fatalf("test %s fails, unexpected error: %v", test.name, err)
+			}
+			close(recorder.Events)
+
+			var events []string
+			for event := range recorder.Events {
+				events = append(events, event)
+			}
+			if len(test.expectEvents) == 0 && len(events) > 0 {
+				t.Errorf("test %s fails, expected no event but got %v", test.name, events)
+			}
+			if len(test.expectEvents) > 0 {
+				if len(events) != 1 {
+					t.Fatalf("test %s fails, expected one event but got %v", test.name, events)
+				}
+				for _, expect := range test.expectEvents {
+					if !strings.Contains(events[0], expect) {
+						t.Errorf("test %s fails, expected event containing %q but got %q", test.name, expect, events[0])
+					}
+				}
+			}
+
+			if _, stuck := r.stuckPods[request.NamespacedName]; stuck != test.expectStuck {
+				t.Errorf("test %s fails, expected stuck %v but got %v", test.name, test.expectStuck, stuck)
+			}
+		})
+	}
+}
+
+func TestPodStartupWatchdogForget(t *testing.T) {
+	r := &PodStartupWatchdogReconciler{
+		Client:    newFakeClient(),
+		APIReader: newFakeClient(),
+		Recorder:  record.NewFakeRecorder(10),
+		Config:    managerconfig.NewStore(managerconfig.Configuration{PodStartupTimeout: time.Minute}),
+	}
+
+	name := types.NamespacedName{Namespace: "default", Name: "stuck"}
+	if !r.flag(name, "uid-1") {
+		t.Fatalf("expected pod to be flagged for the first time")
+	}
+	if r.flag(name, "uid-1") {
+		t.Errorf("expected pod not to be flagged again")
+	}
+	// a recreated pod of the same name is flagged again
+	if !r.flag(name, "uid-2") {
+		t.Errorf("expected recreated pod to be flagged")
+	}
+
+	// removed pod is forgotten by reconciliation
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: name}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(r.stuckPods) != 0 {
+		t.Errorf("expected no stuck pod but got %v", r.stuckPods)
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -35,6 +36,7 @@ import (
 	"github.com/alibaba/hybridnet/pkg/constants"
 	"github.com/alibaba/hybridnet/pkg/controllers/concurrency"
 	"github.com/alibaba/hybridnet/pkg/controllers/utils"
+	"github.com/alibaba/hybridnet/pkg/feature"
 	globalutils "github.com/alibaba/hybridnet/pkg/utils"
 )
 
@@ -80,7 +82,11 @@ func (r *QuotaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (resu
 		delete(node.Labels, constants.LabelIPv6AddressQuota)
 		delete(node.Labels, constants.LabelDualStackAddressQuota)
 
-		return ctrl.Result{}, wrapError("unable to clean node quota labels", r.Patch(ctx, node, nodePatch))
+		if err = r.Patch(ctx, node, nodePatch); err != nil {
+			return ctrl.Result{}, wrapError("unable to clean node quota labels", err)
+		}
+
+		return ctrl.Result{}, wrapError("unable to clean node address resources", r.cleanAddressResources(ctx, node))
 	}
 
 	// TODO: use the first matched underlay network until multiple network selection supported
@@ -105,7 +111,81 @@ func (r *QuotaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (resu
 	}
 
 	log.V(1).Info(fmt.Sprintf("sync quota labels to %v", node.Labels))
-	return ctrl.Result{}, nil
+
+	if !feature.AddressExtendedResourceEnabled() {
+		return ctrl.Result{}, wrapError("unable to clean node address resources", r.cleanAddressResources(ctx, node))
+	}
+
+	return ctrl.Result{}, wrapError("unable to sync node address resources", r.syncAddressResources(ctx, node, network))
+}
+
+// syncAddressResources publishes available addresses of underlay network as extended resources of node.
+// Because scheduler only accounts the requests of pods on the same node, the addresses which have been
+// used on this node need to be added to the available ones of network.
+func (r *QuotaReconciler) syncAddressResources(ctx context.Context, node *corev1.Node, network *networkingv1.Network) error {
+	ipInstances, err := utils.ListAllocatedIPInstances(ctx, r, client.MatchingLabels{
+		constants.LabelNetwork: network.Name,
+		constants.LabelNode:    node.Name,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to list allocated ip instances on node: %v", err)
+	}
+
+	var usedIPv4, usedIPv6 int64
+	for _, ipInstance := range ipInstances {
+		if networkingv1.IsIPv6IPInstance(ipInstance) {
+			usedIPv6++
+		} else {
+			usedIPv4++
+		}
+	}
+
+	availableOf := func(statistics *networkingv1.Count) int64 {
+		if statistics == nil || statistics.Available < 0 {
+			return 0
+		}
+		return int64(statistics.Available)
+	}
+
+	nodePatch := client.MergeFrom(node.DeepCopy())
+	setNodeResource(node, constants.ResourceIPv4Address, availableOf(network.Status.Statistics)+usedIPv4)
+	setNodeResource(node, constants.ResourceIPv6Address, availableOf(network.Status.IPv6Statistics)+usedIPv6)
+
+	return r.Status().Patch(ctx, node, nodePatch)
+}
+
+// cleanAddressResources removes address resources from node status if exist
+func (r *QuotaReconciler) cleanAddressResources(ctx context.Context, node *corev1.Node) error {
+	var found bool
+	for _, resourceName := range []corev1.ResourceName{constants.ResourceIPv4Address, constants.ResourceIPv6Address} {
+		_, inCapacity := node.Status.Capacity[resourceName]
+		_, inAllocatable := node.Status.Allocatable[resourceName]
+		found = found || inCapacity || inAllocatable
+	}
+	if !found {
+		return nil
+	}
+
+	nodePatch := client.MergeFrom(node.DeepCopy())
+	for _, resourceName := range []corev1.ResourceName{constants.ResourceIPv4Address, constants.ResourceIPv6Address} {
+		delete(node.Status.Capacity, resourceName)
+		delete(node.Status.Allocatable, resourceName)
+	}
+
+	return r.Status().Patch(ctx, node, nodePatch)
+}
+
+func setNodeResource(node *corev1.Node, resourceName corev1.ResourceName, value int64) {
+	if node.Status.Capacity == nil {
+		node.Status.Capacity = corev1.ResourceList{}
+	}
+	if node.Status.Allocatable == nil {
+		node.Status.Allocatable = corev1.ResourceList{}
+	}
+
+	quantity := resource.NewQuantity(value, resource.DecimalSI)
+	node.Status.Capacity[resourceName] = *quantity
+	node.Status.Allocatable[resourceName] = quantity.DeepCopy()
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -0,0 +1,141 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+)
+
+func TestSyncAddressResources(t *testing.T) {
+	newIPInstance := func(name, nodeName string, version networkingv1.IPVersion, terminating bool) *networkingv1.IPInstance {
+		ipInstance := &networkingv1.IPInstance{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Labels: map[string]string{
+					constants.LabelNetwork: "underlay",
+					constants.LabelNode:    nodeName,
+				},
+			},
+			Spec: networkingv1.IPInstanceSpec{
+				Network: "underlay",
+				Address: networkingv1.Address{Version: version},
+			},
+		}
+		if terminating {
+			ipInstance.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			ipInstance.Finalizers = []string{constants.FinalizerIPAllocated}
+		}
+		return ipInstance
+	}
+	newNetwork := func(ipv4, ipv6 *networkingv1.Count) *networkingv1.Network {
+		return &networkingv1.Network{
+			ObjectMeta: metav1.ObjectMeta{Name: "underlay"},
+			Status: networkingv1.NetworkStatus{
+				Statistics:     ipv4,
+				IPv6Statistics: ipv6,
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		network     *networkingv1.Network
+		ipInstances []client.Object
+		expectIPv4  int64
+		expectIPv6  int64
+	}{
+		{
+			name:       "no address used on node",
+			network:    newNetwork(&networkingv1.Count{Available: 10}, &networkingv1.Count{Available: 5}),
+			expectIPv4: 10,
+			expectIPv6: 5,
+		},
+		{
+			name:    "addresses used on node are added to available ones",
+			network: newNetwork(&networkingv1.Count{Available: 10}, &networkingv1.Count{Available: 5}),
+			ipInstances: []client.Object{
+				newIPInstance("10-0-0-1", "node1", networkingv1.IPv4, false),
+				newIPInstance("10-0-0-2", "node1", networkingv1.IPv4, false),
+				newIPInstance("fd00-0-0-0-0-0-0-1", "node1", networkingv1.IPv6, false),
+			},
+			expectIPv4: 12,
+			expectIPv6: 6,
+		},
+		{
+			name:    "addresses on other nodes and terminating ones are not counted",
+			network: newNetwork(&networkingv1.Count{Available: 10}, &networkingv1.Count{Available: 5}),
+			ipInstances: []client.Object{
+				newIPInstance("10-0-0-1", "node1", networkingv1.IPv4, false),
+				newIPInstance("10-0-0-2", "node2", networkingv1.IPv4, false),
+				newIPInstance("10-0-0-3", "node1", networkingv1.IPv4, true),
+			},
+			expectIPv4: 11,
+			expectIPv6: 5,
+		},
+		{
+			name:    "missing and negative statistics",
+			network: newNetwork(&networkingv1.Count{Available: -1}, nil),
+			ipInstances: []client.Object{
+				newIPInstance("10-0-0-1", "node1", networkingv1.IPv4, false),
+			},
+			expectIPv4: 1,
+			expectIPv6: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+			r := &QuotaReconciler{
+				Context: context.Background(),
+				Client:  newFakeClient(append(test.ipInstances, node)...),
+			}
+
+			if err := r.syncAddressResources(context.Background(), node, test.network); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			synced := &corev1.Node{}
+			if err := r.Get(context.Background(), types.NamespacedName{Name: "node1"}, synced); err != nil {
+				t.Fatalf("unable to get node: %v", err)
+			}
+
+			for resourceName, expect := range map[corev1.ResourceName]int64{
+				constants.ResourceIPv4Address: test.expectIPv4,
+				constants.ResourceIPv6Address: test.expectIPv6,
+			} {
+				capacity := synced.Status.Capacity[resourceName]
+				allocatable := synced.Status.Allocatable[resourceName]
+				if capacity.Value() != expect || allocatable.Value() != expect {
+					t.Errorf("expected %s %d but got capacity %s and allocatable %s", resourceName, expect,
+						capacity.String(), allocatable.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,307 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/controllers/concurrency"
+	"github.com/alibaba/hybridnet/pkg/controllers/utils"
+	"github.com/alibaba/hybridnet/pkg/ipam/strategy"
+	ipamtypes "github.com/alibaba/hybridnet/pkg/ipam/types"
+	globalutils "github.com/alibaba/hybridnet/pkg/utils"
+)
+
+const ControllerStatefulSetPreAllocate = "StatefulSetPreAllocate"
+
+const ReasonIPPreAllocateSucceed = "IPPreAllocateSucceed"
+
+var statefulSetGVK = appsv1.SchemeGroupVersion.WithKind("StatefulSet")
+
+// StatefulSetPreAllocateReconciler allocates addresses for the pods of StatefulSets which do
+// not exist yet, and keeps them reserved as if the pods had been recreated with retained IPs.
+// Pod controller will then assign the reserved addresses on pod creation instead of allocating.
+// The next pod to be created is always left to pod controller, so that the two controllers
+// never allocate for the same pod concurrently.
+type StatefulSetPreAllocateReconciler struct {
+	// APIReader is used to check pod existence, because pod created after the scale-up
+	// must not get another address allocated here
+	APIReader client.Reader
+	client.Client
+
+	Recorder record.EventRecorder
+
+	IPAMStore   IPAMStore
+	IPAMManager IPAMManager
+
+	concurrency.ControllerConcurrency
+}
+
+//+kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=networking.alibaba.com,resources=ipinstances,verbs=get;list;watch;create;update;patch
+
+func (r *StatefulSetPreAllocateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	log := ctrllog.FromContext(ctx)
+
+	var statefulSet = &appsv1.StatefulSet{}
+
+	defer func() {
+		if err != nil {
+			log.Error(err, "reconciliation fails")
+			if len(statefulSet.UID) > 0 {
+				r.Recorder.Event(statefulSet, corev1.EventTypeWarning, ReasonIPAllocationFail, err.Error())
+			}
+		}
+	}()
+
+	if err = r.Get(ctx, req.NamespacedName, statefulSet); err != nil {
+		return ctrl.Result{}, wrapError("unable to fetch StatefulSet", client.IgnoreNotFound(err))
+	}
+
+	if statefulSet.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	networkName, preAllocatable := preAllocatableStatefulSet(statefulSet)
+	if !preAllocatable {
+		return ctrl.Result{}, nil
+	}
+
+	var firstAbsent = -1
+	for index := 0; index < int(replicasOf(statefulSet)); index++ {
+		var podExists bool
+		if podExists, err = r.podExists(ctx, statefulSet.Namespace, podNameOf(statefulSet, index)); err != nil {
+			return ctrl.Result{}, wrapError(fmt.Sprintf("unable to check pod of index %d", index), err)
+		}
+		if podExists {
+			continue
+		}
+
+		// pods are created one by one in ordinal order, the first absent one may be being created
+		// by StatefulSet controller right now, so it is left to pod controller, otherwise both
+		// controllers can allocate addresses for it at the same time
+		if firstAbsent < 0 {
+			firstAbsent = index
+			continue
+		}
+
+		var pod *corev1.Pod
+		if pod, err = r.podToPreAllocate(ctx, statefulSet, index); err != nil {
+			return ctrl.Result{}, wrapError(fmt.Sprintf("unable to check pod of index %d", index), err)
+		}
+		if pod == nil {
+			continue
+		}
+
+		if err = r.preAllocate(ctx, statefulSet, pod, networkName); err != nil {
+			return ctrl.Result{}, wrapError(fmt.Sprintf("unable to pre-allocate for pod %s", pod.Name), err)
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// preAllocatableStatefulSet returns the network which addresses should be pre-allocated from.
+// Only the pods which will retain addresses and specify network explicitly in template are
+// supported, otherwise the network can only be decided in webhook or after scheduling.
+func preAllocatableStatefulSet(statefulSet *appsv1.StatefulSet) (string, bool) {
+	template := &statefulSet.Spec.Template
+
+	if !globalutils.ParseBoolOrDefault(template.Annotations[constants.AnnotationIPRetain], strategy.DefaultIPRetain) {
+		return "", false
+	}
+
+	// pods of parallel policy are created all at once, none of them can be pre-allocated
+	// without racing pod controller
+	if statefulSet.Spec.PodManagementPolicy == appsv1.ParallelPodManagement {
+		return "", false
+	}
+
+	// specified mac addresses are checked for collision on pod creation
+	if len(template.Annotations[constants.AnnotationMACPool]) > 0 {
+		return "", false
+	}
+
+	networkName := globalutils.PickFirstNonEmptyString(template.Annotations[constants.AnnotationSpecifiedNetwork],
+		template.Labels[constants.LabelSpecifiedNetwork])
+	return networkName, len(networkName) > 0
+}
+
+// podToPreAllocate returns a pod skeleton of index if addresses of the pod do not exist
+func (r *StatefulSetPreAllocateReconciler) podToPreAllocate(ctx context.Context, statefulSet *appsv1.StatefulSet,
+	index int) (*corev1.Pod, error) {
+	// a pod without node and uid makes the coupled ip instances reserved
+	var pod = &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            podNameOf(statefulSet, index),
+			Namespace:       statefulSet.Namespace,
+			Annotations:     statefulSet.Spec.Template.Annotations,
+			Labels:          statefulSet.Spec.Template.Labels,
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(statefulSet, statefulSetGVK)},
+		},
+	}
+
+	ipInstances, err := utils.ListAllocatedIPInstancesOfPod(ctx, r, pod)
+	if err != nil {
+		return nil, err
+	}
+	if len(ipInstances) > 0 {
+		return nil, nil
+	}
+
+	return pod, nil
+}
+
+// podExists checks whether the pod of name has been created
+func (r *StatefulSetPreAllocateReconciler) podExists(ctx context.Context, namespace, name string) (bool, error) {
+	if err := r.APIReader.Get(ctx, apitypes.NamespacedName{Namespace: namespace, Name: name}, &corev1.Pod{}); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	return true, nil
+}
+
+func (r *StatefulSetPreAllocateReconciler) preAllocate(ctx context.Context, statefulSet *appsv1.StatefulSet,
+	pod *corev1.Pod, networkName string) (err error) {
+	var (
+		ipFamily     = ipamtypes.ParseIPFamilyFromString(pod.Annotations[constants.AnnotationIPFamily])
+		podInfo      = ipamtypes.PodInfo{NamespacedName: apitypes.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, IPFamily: ipFamily}
+		allocatedIPs []*ipamtypes.IP
+	)
+
+	if ipPoolStr := pod.Annotations[constants.AnnotationIPPool]; len(ipPoolStr) > 0 {
+		// follow the same rule of pod controller to pick addresses from ip pool
+		ipPool := strings.Split(ipPoolStr, ",")
+		idx := utils.GetIndexFromName(pod.Name)
+		if idx >= len(ipPool) || len(ipPool[idx]) == 0 {
+			return fmt.Errorf("unable to find assigned ip in ip-pool %s by index %d", ipPoolStr, idx)
+		}
+
+		var assignSuites []ipamtypes.SubnetIPSuite
+		for _, ipStr := range strings.Split(ipPool[idx], "/") {
+			normalizedIP := globalutils.NormalizedIP(ipStr)
+			if len(normalizedIP) == 0 {
+				return fmt.Errorf("the assigned ip %s is illegal", ipStr)
+			}
+			assignSuites = append(assignSuites, ipamtypes.AssignIP(normalizedIP))
+		}
+
+		if allocatedIPs, err = r.IPAMManager.Assign(networkName, podInfo, assignSuites); err != nil {
+			return fmt.Errorf("unable to assign IP on family %s: %v", ipFamily, err)
+		}
+	} else {
+		var specifiedSubnetNames []string
+		if subnetNameStr := globalutils.PickFirstNonEmptyString(pod.Annotations[constants.AnnotationSpecifiedSubnet],
+			pod.Labels[constants.LabelSpecifiedSubnet]); len(subnetNameStr) > 0 {
+			specifiedSubnetNames = strings.Split(subnetNameStr, "/")
+		}
+
+		if allocatedIPs, err = r.IPAMManager.Allocate(networkName, podInfo,
+			ipamtypes.AllocateSubnets(specifiedSubnetNames)); err != nil {
+			return fmt.Errorf("unable to allocate IP on family %s: %v", ipFamily, err)
+		}
+	}
+
+	defer func() {
+		if err != nil {
+			_ = r.IPAMManager.Release(networkName, ipToReleaseSuite(allocatedIPs))
+		}
+	}()
+
+	if err = r.IPAMStore.Couple(ctx, pod, allocatedIPs,
+		ipamtypes.OwnerReference(pod.OwnerReferences[0])); err != nil {
+		return fmt.Errorf("unable to couple IPs %v with pod: %v", allocatedIPs, err)
+	}
+
+	if err = r.IPAMManager.Reserve(networkName, ipToReserveSuite(allocatedIPs)); err != nil {
+		return fmt.Errorf("unable to reserve IPs %v: %v", allocatedIPs, err)
+	}
+
+	r.Recorder.Eventf(statefulSet, corev1.EventTypeNormal, ReasonIPPreAllocateSucceed,
+		"pre-allocate IPs %v for pod %s successfully", ipToIPString(allocatedIPs), pod.Name)
+	return nil
+}
+
+func ipToReserveSuite(ips []*ipamtypes.IP) (ret []ipamtypes.SubnetIPSuite) {
+	for _, ip := range ips {
+		ret = append(ret, ipamtypes.ReserveIPOfSubnet(ip.Subnet, ip.Address.IP.String()))
+	}
+	return
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *StatefulSetPreAllocateReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(ControllerStatefulSetPreAllocate).
+		For(&appsv1.StatefulSet{},
+			builder.WithPredicates(
+				predicate.Funcs{
+					CreateFunc: func(event.CreateEvent) bool {
+						return true
+					},
+					UpdateFunc: func(updateEvent event.UpdateEvent) bool {
+						oldStatefulSet, ok := updateEvent.ObjectOld.(*appsv1.StatefulSet)
+						if !ok {
+							return false
+						}
+						newStatefulSet, ok := updateEvent.ObjectNew.(*appsv1.StatefulSet)
+						if !ok {
+							return false
+						}
+						// only scale-up needs pre-allocation
+						return replicasOf(newStatefulSet) > replicasOf(oldStatefulSet)
+					},
+					DeleteFunc: func(event.DeleteEvent) bool {
+						return false
+					},
+					GenericFunc: func(event.GenericEvent) bool {
+						return false
+					},
+				},
+			)).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.Max(),
+			RecoverPanic:            true,
+		}).
+		Complete(r)
+}
+
+func replicasOf(statefulSet *appsv1.StatefulSet) int32 {
+	if statefulSet.Spec.Replicas == nil {
+		return 1
+	}
+	return *statefulSet.Spec.Replicas
+}
+
+func podNameOf(statefulSet *appsv1.StatefulSet, index int) string {
+	return fmt.Sprintf("%s-%d", statefulSet.Name, index)
+}
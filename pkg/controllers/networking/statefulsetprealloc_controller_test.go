@@ -0,0 +1,271 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	ipamtypes "github.com/alibaba/hybridnet/pkg/ipam/types"
+)
+
+func TestPreAllocatableStatefulSet(t *testing.T) {
+	tests := []struct {
+		name          string
+		annotations   map[string]string
+		labels        map[string]string
+		policy        appsv1.PodManagementPolicyType
+		expectNetwork string
+		expectOK      bool
+	}{
+		{
+			name:          "network specified by annotation",
+			annotations:   map[string]string{constants.AnnotationSpecifiedNetwork: "underlay"},
+			expectNetwork: "underlay",
+			expectOK:      true,
+		},
+		{
+			name:          "network specified by label",
+			labels:        map[string]string{constants.LabelSpecifiedNetwork: "underlay"},
+			expectNetwork: "underlay",
+			expectOK:      true,
+		},
+		{
+			name: "network not specified",
+		},
+		{
+			name: "ip not retained",
+			annotations: map[string]string{
+				constants.AnnotationSpecifiedNetwork: "underlay",
+				constants.AnnotationIPRetain:         "false",
+			},
+		},
+		{
+			name:          "ordered ready policy",
+			annotations:   map[string]string{constants.AnnotationSpecifiedNetwork: "underlay"},
+			policy:        appsv1.OrderedReadyPodManagement,
+			expectNetwork: "underlay",
+			expectOK:      true,
+		},
+		{
+			name:        "parallel policy",
+			annotations: map[string]string{constants.AnnotationSpecifiedNetwork: "underlay"},
+			policy:      appsv1.ParallelPodManagement,
+		},
+		{
+			name: "mac pool specified",
+			annotations: map[string]string{
+				constants.AnnotationSpecifiedNetwork: "underlay",
+				constants.AnnotationMACPool:          "00:00:00:00:00:01",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		statefulSet := &appsv1.StatefulSet{
+			Spec: appsv1.StatefulSetSpec{
+				PodManagementPolicy: test.policy,
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Annotations: test.annotations, Labels: test.labels},
+				},
+			},
+		}
+
+		networkName, ok := preAllocatableStatefulSet(statefulSet)
+		if networkName != test.expectNetwork || ok != test.expectOK {
+			t.Errorf("test %s fails, expected %q %v but got %q %v", test.name, test.expectNetwork, test.expectOK,
+				networkName, ok)
+		}
+	}
+}
+
+func TestStatefulSetPreAllocateReconcile(t *testing.T) {
+	const namespace = "default"
+
+	newStatefulSet := func(name string, replicas int32, annotations map[string]string) *appsv1.StatefulSet {
+		return &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				UID:       types.UID(name + "-uid"),
+			},
+			Spec: appsv1.StatefulSetSpec{
+				Replicas: pointer.Int32(replicas),
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Annotations: annotations},
+				},
+			},
+		}
+	}
+	underlay := map[string]string{constants.AnnotationSpecifiedNetwork: "underlay"}
+
+	objects := []client.Object{
+		newStatefulSet("scale", 4, underlay),
+		newStatefulSet("pool", 2, map[string]string{
+			constants.AnnotationSpecifiedNetwork: "underlay",
+			constants.AnnotationIPPool:           "10.0.0.5,10.0.0.6/fd00::6",
+		}),
+		newStatefulSet("short-pool", 2, map[string]string{
+			constants.AnnotationSpecifiedNetwork: "underlay",
+			constants.AnnotationIPPool:           "10.0.0.5",
+		}),
+		newStatefulSet("unspecified", 2, nil),
+		newStatefulSet("gap", 5, underlay),
+		// scale-0 has been created and scale-2 has its addresses, scale-1 is the next to create
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "scale-0", Namespace: namespace}},
+		&networkingv1.IPInstance{ObjectMeta: metav1.ObjectMeta{
+			Name:      "10-0-0-100",
+			Namespace: namespace,
+			Labels:    map[string]string{constants.LabelPod: "scale-2"},
+		}},
+		// gap-1 is absent while gap-0 and gap-2 exist
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "gap-0", Namespace: namespace}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "gap-2", Namespace: namespace}},
+	}
+
+	tests := []struct {
+		name           string
+		statefulSet    string
+		coupleErr      error
+		expectErr      bool
+		expectCoupled  []string
+		expectAssigned map[string][]ipamtypes.SubnetIPSuite
+		expectReserved int
+		expectReleased int
+		expectEvent    string
+	}{
+		{
+			name:        "statefulset not found",
+			statefulSet: "missing",
+		},
+		{
+			name:        "network unspecified",
+			statefulSet: "unspecified",
+		},
+		{
+			name:           "pods and addresses exist",
+			statefulSet:    "scale",
+			expectCoupled:  []string{"default/scale-3"},
+			expectReserved: 1,
+			expectEvent:    "pre-allocate IPs [10.0.0.1] for pod scale-3 successfully",
+		},
+		{
+			name:           "next pod is left to pod controller",
+			statefulSet:    "gap",
+			expectCoupled:  []string{"default/gap-3", "default/gap-4"},
+			expectReserved: 2,
+		},
+		{
+			name:        "ip pool",
+			statefulSet: "pool",
+			expectAssigned: map[string][]ipamtypes.SubnetIPSuite{
+				"default/pool-1": {ipamtypes.AssignIP("10.0.0.6"), ipamtypes.AssignIP("fd00::6")},
+			},
+			expectCoupled:  []string{"default/pool-1"},
+			expectReserved: 2,
+		},
+		{
+			name:        "ip pool shorter than replicas",
+			statefulSet: "short-pool",
+			expectErr:   true,
+			expectEvent: "unable to find assigned ip in ip-pool 10.0.0.5 by index 1",
+		},
+		{
+			name:           "couple fails",
+			statefulSet:    "scale",
+			coupleErr:      fmt.Errorf("conflict"),
+			expectErr:      true,
+			expectReleased: 1,
+			expectEvent:    "unable to couple IPs",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newFakeClient(objects...)
+			recorder := record.NewFakeRecorder(10)
+			ipamManager := &fakeIPAMManager{}
+			ipamStore := &fakeIPAMStore{coupleErr: test.coupleErr}
+			r := &StatefulSetPreAllocateReconciler{
+				APIReader:   c,
+				Client:      c,
+				Recorder:    recorder,
+				IPAMStore:   ipamStore,
+				IPAMManager: ipamManager,
+			}
+
+			_, err := r.Reconcile(context.Background(), ctrl.Request{
+				NamespacedName: types.NamespacedName{Namespace: namespace, Name: test.statefulSet},
+			})
+			if (err != nil) != test.expectErr {
+				t.Fatalf("test %s fails, expected error %v but got %v", test.name, test.expectErr, err)
+			}
+
+			if !reflect.DeepEqual(ipamStore.coupled, test.expectCoupled) {
+				t.Errorf("test %s fails, expected coupled pods %v but got %v", test.name, test.expectCoupled,
+					ipamStore.coupled)
+			}
+			if len(test.expectAssigned) > 0 && !reflect.DeepEqual(ipamManager.assigned, test.expectAssigned) {
+				t.Errorf("test %s fails, expected assigned %v but got %v", test.name, test.expectAssigned,
+					ipamManager.assigned)
+			}
+			if len(ipamManager.reserved) != test.expectReserved {
+				t.Errorf("test %s fails, expected %d reserved but got %v", test.name, test.expectReserved,
+					ipamManager.reserved)
+			}
+			if len(ipamManager.released) != test.expectReleased {
+				t.Errorf("test %s fails, expected %d released but got %v", test.name, test.expectReleased,
+					ipamManager.released)
+			}
+
+			close(recorder.Events)
+			var events []string
+			for event := range recorder.Events {
+				events = append(events, event)
+			}
+			if len(test.expectEvent) == 0 {
+				for _, event := range events {
+					if !strings.Contains(event, ReasonIPPreAllocateSucceed) {
+						t.Errorf("test %s fails, expected no failure event but got %v", test.name, event)
+					}
+				}
+				return
+			}
+			var found bool
+			for _, event := range events {
+				found = found || strings.Contains(event, test.expectEvent)
+			}
+			if !found {
+				t.Errorf("test %s fails, expected event %q but got %v", test.name, test.expectEvent, events)
+			}
+		})
+	}
+}
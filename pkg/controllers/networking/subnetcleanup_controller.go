@@ -0,0 +1,192 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/controllers/concurrency"
+	"github.com/alibaba/hybridnet/pkg/controllers/utils"
+	"github.com/alibaba/hybridnet/pkg/managerconfig"
+)
+
+const ControllerSubnetCleanup = "SubnetCleanup"
+
+const (
+	defaultDataplaneCleanupTimeout = 5 * time.Minute
+	dataplaneCleanupCheckInterval  = 10 * time.Second
+
+	// daemonHeartbeatTimeout is three heartbeat intervals of daemon, a node without heartbeats
+	// in it is taken as not running daemon
+	daemonHeartbeatTimeout = 15 * time.Minute
+)
+
+// SubnetCleanupReconciler holds the deletion of subnet until all the ip instances of subnet are
+// released and the dataplane of subnet is cleaned by daemons on all ready nodes running daemon
+type SubnetCleanupReconciler struct {
+	client.Client
+
+	Recorder record.EventRecorder
+
+	// Config provides the max duration to wait for acknowledgements of daemons, the
+	// finalizer will be removed after timeout to avoid blocking deletion forever
+	Config *managerconfig.Store
+
+	concurrency.ControllerConcurrency
+}
+
+//+kubebuilder:rbac:groups=networking.alibaba.com,resources=subnets,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=networking.alibaba.com,resources=subnets/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+
+func (r *SubnetCleanupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	log := ctrllog.FromContext(ctx)
+
+	var subnet = &networkingv1.Subnet{}
+
+	defer func() {
+		if err != nil {
+			log.Error(err, "reconciliation fails")
+			if len(subnet.UID) > 0 {
+				r.Recorder.Event(subnet, corev1.EventTypeWarning, "CleanupFail", err.Error())
+			}
+		}
+	}()
+
+	if err = r.Get(ctx, req.NamespacedName, subnet); err != nil {
+		return ctrl.Result{}, wrapError("unable to fetch Subnet", client.IgnoreNotFound(err))
+	}
+
+	if subnet.DeletionTimestamp == nil {
+		return ctrl.Result{}, wrapError("unable to add finalizer to subnet",
+			utils.AddFinalizer(ctx, r, subnet, constants.FinalizerDataplaneCleanup))
+	}
+
+	// ip instances must be released before subnet
+	var ipInstances *networkingv1.IPInstanceList
+	if ipInstances, err = utils.ListIPInstances(ctx, r, client.MatchingLabels{constants.LabelSubnet: subnet.Name}); err != nil {
+		return ctrl.Result{}, wrapError("unable to list ip instances of subnet", err)
+	}
+	if len(ipInstances.Items) > 0 {
+		log.V(1).Info(fmt.Sprintf("waiting for %d ip instances to be released", len(ipInstances.Items)))
+		return ctrl.Result{RequeueAfter: dataplaneCleanupCheckInterval}, nil
+	}
+
+	var pendingNodes []string
+	if pendingNodes, err = r.listPendingNodes(ctx, subnet.Name, time.Now()); err != nil {
+		return ctrl.Result{}, wrapError("unable to list nodes pending on dataplane cleanup", err)
+	}
+
+	if len(pendingNodes) > 0 {
+		if waited := time.Since(subnet.DeletionTimestamp.Time); waited < r.cleanupTimeout() {
+			log.V(1).Info(fmt.Sprintf("waiting for dataplane cleanup on nodes %v", pendingNodes))
+			return ctrl.Result{RequeueAfter: dataplaneCleanupCheckInterval}, nil
+		}
+
+		r.Recorder.Eventf(subnet, corev1.EventTypeWarning, "CleanupTimeout",
+			"dataplane cleanup is not acknowledged by nodes %v after %v", pendingNodes, r.cleanupTimeout())
+	}
+
+	return ctrl.Result{}, wrapError("unable to remove finalizer from subnet",
+		utils.RemoveFinalizer(ctx, r, subnet, constants.FinalizerDataplaneCleanup))
+}
+
+// listPendingNodes returns the ready nodes running daemon which have not acknowledged the dataplane
+// cleanup of subnet
+func (r *SubnetCleanupReconciler) listPendingNodes(ctx context.Context, subnetName string, now time.Time) ([]string, error) {
+	var nodeList = &corev1.NodeList{}
+	if err := r.List(ctx, nodeList); err != nil {
+		return nil, err
+	}
+
+	var pendingNodes []string
+	for i := range nodeList.Items {
+		var node = &nodeList.Items[i]
+		if !nodeIsReady(node) || !nodeRunsDaemon(node, now) {
+			continue
+		}
+
+		if !dataplaneCleanedOnNode(node, subnetName) {
+			pendingNodes = append(pendingNodes, node.Name)
+		}
+	}
+	return pendingNodes, nil
+}
+
+func (r *SubnetCleanupReconciler) cleanupTimeout() time.Duration {
+	if config := r.Config.Get(); config != nil && config.DataplaneCleanupTimeout > 0 {
+		return config.DataplaneCleanupTimeout
+	}
+	return defaultDataplaneCleanupTimeout
+}
+
+func nodeIsReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// nodeRunsDaemon checks if daemon on node is reporting heartbeats, nodes out of the daemonset,
+// e.g., excluded by node selector, will never acknowledge any dataplane cleanup
+func nodeRunsDaemon(node *corev1.Node, now time.Time) bool {
+	return !isDaemonHeartbeatStale(node, daemonHeartbeatTimeout, now)
+}
+
+func dataplaneCleanedOnNode(node *corev1.Node, subnetName string) bool {
+	for _, cleaned := range strings.Split(node.Annotations[constants.AnnotationDataplaneCleanedSubnets], ",") {
+		if cleaned == subnetName {
+			return true
+		}
+	}
+	return false
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SubnetCleanupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(ControllerSubnetCleanup).
+		For(&networkingv1.Subnet{},
+			builder.WithPredicates(
+				&utils.IgnoreDeletePredicate{},
+				predicate.Or(
+					&predicate.GenerationChangedPredicate{},
+					&utils.TerminatingPredicate{},
+				),
+			)).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.Max(),
+			RecoverPanic:            true,
+		}).
+		Complete(r)
+}
@@ -0,0 +1,172 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/managerconfig"
+)
+
+// newCleanupNode returns a node with the ready condition, heartbeat of daemon and the subnets whose
+// dataplane cleanup is acknowledged, an empty heartbeat means daemon is not running on node
+func newCleanupNode(name string, ready bool, heartbeat time.Time, cleanedSubnets string) *corev1.Node {
+	var status = corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Annotations: map[string]string{constants.AnnotationDataplaneCleanedSubnets: cleanedSubnets},
+		},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+		},
+	}
+	if !heartbeat.IsZero() {
+		node.Annotations[constants.AnnotationDaemonHeartbeat] = heartbeat.Format(time.RFC3339)
+	}
+	return node
+}
+
+// hasCleanupFinalizer checks the dataplane cleanup finalizer of object, an object already removed
+// after its finalizers are gone has no finalizer either
+func hasCleanupFinalizer(t *testing.T, c client.Client, key types.NamespacedName, object client.Object) bool {
+	if err := c.Get(context.Background(), key, object); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false
+		}
+		t.Fatalf("unable to get object %v: %v", key, err)
+	}
+	return controllerutil.ContainsFinalizer(object, constants.FinalizerDataplaneCleanup)
+}
+
+func TestSubnetCleanupReconcile(t *testing.T) {
+	now := time.Now()
+
+	newSubnet := func(deletedAt time.Time) *networkingv1.Subnet {
+		subnet := &networkingv1.Subnet{
+			ObjectMeta: metav1.ObjectMeta{Name: "subnet1"},
+			Spec:       networkingv1.SubnetSpec{Network: "network1"},
+		}
+		if !deletedAt.IsZero() {
+			subnet.DeletionTimestamp = &metav1.Time{Time: deletedAt}
+			subnet.Finalizers = []string{constants.FinalizerDataplaneCleanup}
+		}
+		return subnet
+	}
+
+	// nodes without daemon, not ready or with stale heartbeat never acknowledge cleanup
+	idleNodes := []client.Object{
+		newCleanupNode("no-daemon", true, time.Time{}, ""),
+		newCleanupNode("not-ready", false, now, ""),
+		newCleanupNode("stale-daemon", true, now.Add(-2*daemonHeartbeatTimeout), ""),
+	}
+
+	tests := []struct {
+		name            string
+		objects         []client.Object
+		expectFinalizer bool
+		expectRequeue   bool
+	}{
+		{
+			name:            "finalizer added to live subnet",
+			objects:         []client.Object{newSubnet(time.Time{})},
+			expectFinalizer: true,
+		},
+		{
+			name: "waiting for ip instances",
+			objects: []client.Object{
+				newSubnet(now),
+				&networkingv1.IPInstance{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "10-0-0-1",
+						Namespace: "default",
+						Labels:    map[string]string{constants.LabelSubnet: "subnet1"},
+					},
+				},
+			},
+			expectFinalizer: true,
+			expectRequeue:   true,
+		},
+		{
+			name: "waiting for node running daemon",
+			objects: append([]client.Object{
+				newSubnet(now),
+				newCleanupNode("pending", true, now, "subnet2"),
+			}, idleNodes...),
+			expectFinalizer: true,
+			expectRequeue:   true,
+		},
+		{
+			name: "acknowledged by all nodes running daemon",
+			objects: append([]client.Object{
+				newSubnet(now),
+				newCleanupNode("cleaned", true, now, "subnet2,subnet1"),
+			}, idleNodes...),
+			expectFinalizer: false,
+		},
+		{
+			name: "cleanup timeout",
+			objects: []client.Object{
+				newSubnet(now.Add(-2 * time.Minute)),
+				newCleanupNode("pending", true, now, ""),
+			},
+			expectFinalizer: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := &SubnetCleanupReconciler{
+				Client:   newFakeClient(test.objects...),
+				Recorder: record.NewFakeRecorder(10),
+				Config: managerconfig.NewStore(managerconfig.Configuration{
+					DataplaneCleanupTimeout: time.Minute,
+				}),
+			}
+
+			key := types.NamespacedName{Name: "subnet1"}
+			result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if finalizer := hasCleanupFinalizer(t, r.Client, key, &networkingv1.Subnet{}); finalizer != test.expectFinalizer {
+				t.Errorf("expected finalizer %v but got %v", test.expectFinalizer, finalizer)
+			}
+			if requeue := result.RequeueAfter > 0; requeue != test.expectRequeue {
+				t.Errorf("expected requeue %v but got %v", test.expectRequeue, result.RequeueAfter)
+			}
+		})
+	}
+}
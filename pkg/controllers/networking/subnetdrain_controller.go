@@ -0,0 +1,268 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/controllers/concurrency"
+	"github.com/alibaba/hybridnet/pkg/controllers/utils"
+	"github.com/alibaba/hybridnet/pkg/managerconfig"
+)
+
+const ControllerSubnetDrain = "SubnetDrain"
+
+const (
+	defaultSubnetDrainBatchSize = 1
+	defaultSubnetDrainInterval  = 30 * time.Second
+)
+
+// SubnetDrainReconciler evicts pods using addresses of drained subnets at a controlled
+// rate, and reports the count of remaining pods in subnet status
+type SubnetDrainReconciler struct {
+	client.Client
+
+	// KubeClient is used for eviction, which is a sub-resource of pod
+	KubeClient kubernetes.Interface
+	Recorder   record.EventRecorder
+
+	// Config provides the max count of pods evicted in one batch and the
+	// interval between two batches, which can be changed at runtime
+	Config *managerconfig.Store
+
+	// lastBatchTime records the time of last eviction batch for each subnet, because
+	// ip instance events will trigger reconciliation before next batch is due
+	lastBatchTime sync.Map
+
+	concurrency.ControllerConcurrency
+}
+
+//+kubebuilder:rbac:groups=networking.alibaba.com,resources=subnets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=networking.alibaba.com,resources=subnets/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core,resources=pods/eviction,verbs=create
+
+func (r *SubnetDrainReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	log := ctrllog.FromContext(ctx)
+
+	var subnet = &networkingv1.Subnet{}
+
+	defer func() {
+		if err != nil {
+			log.Error(err, "reconciliation fails")
+			if len(subnet.UID) > 0 {
+				r.Recorder.Event(subnet, corev1.EventTypeWarning, "DrainFail", err.Error())
+			}
+		}
+	}()
+
+	if err = r.Get(ctx, req.NamespacedName, subnet); err != nil {
+		return ctrl.Result{}, wrapError("unable to fetch Subnet", client.IgnoreNotFound(err))
+	}
+
+	if subnet.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	var drainingPods []*corev1.Pod
+	if networkingv1.IsDrainedSubnet(subnet) {
+		if drainingPods, err = r.listDrainingPods(ctx, subnet); err != nil {
+			return ctrl.Result{}, wrapError("unable to list draining pods", err)
+		}
+	}
+
+	if err = r.updateDrainingPods(ctx, subnet, int32(len(drainingPods))); err != nil {
+		return ctrl.Result{}, wrapError("unable to update draining pods of subnet", err)
+	}
+
+	if len(drainingPods) == 0 {
+		r.lastBatchTime.Delete(subnet.Name)
+		return ctrl.Result{}, nil
+	}
+
+	if last, ok := r.lastBatchTime.Load(subnet.Name); ok {
+		if wait := r.interval() - time.Since(last.(time.Time)); wait > 0 {
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+	}
+	r.lastBatchTime.Store(subnet.Name, time.Now())
+
+	var evicted int
+	for _, pod := range drainingPods {
+		if evicted >= r.batchSize() {
+			break
+		}
+
+		podName := types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}
+		if pod.DeletionTimestamp != nil {
+			// evicted pod which is terminating is also counted into batch
+			evicted++
+			continue
+		}
+
+		if err = r.KubeClient.CoreV1().Pods(pod.Namespace).EvictV1(ctx, &policyv1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      pod.Name,
+				Namespace: pod.Namespace,
+			},
+		}); err != nil {
+			switch {
+			case apierrors.IsNotFound(err):
+				continue
+			case apierrors.IsTooManyRequests(err):
+				// eviction is blocked by pod disruption budget, try again in next batch
+				log.V(1).Info("eviction is blocked by disruption budget", "pod", podName.String())
+				continue
+			default:
+				return ctrl.Result{}, wrapError(fmt.Sprintf("unable to evict pod %s", podName.String()), err)
+			}
+		}
+
+		r.Recorder.Eventf(subnet, corev1.EventTypeNormal, "EvictPod", "evict pod %s for subnet draining", podName.String())
+		evicted++
+	}
+
+	log.V(1).Info(fmt.Sprintf("%d pods are still draining, %d are evicted in this batch", len(drainingPods), evicted))
+	return ctrl.Result{RequeueAfter: r.interval()}, nil
+}
+
+// listDrainingPods returns the distinct live pods which own allocated ip instances of subnet,
+// retained ip instances without pods or of finished pods need no eviction
+func (r *SubnetDrainReconciler) listDrainingPods(ctx context.Context, subnet *networkingv1.Subnet) ([]*corev1.Pod, error) {
+	ipInstances, err := utils.ListAllocatedIPInstances(ctx, r, client.MatchingLabels{
+		constants.LabelSubnet: subnet.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		pods     []*corev1.Pod
+		existing = map[types.NamespacedName]struct{}{}
+	)
+	for _, ipInstance := range ipInstances {
+		if len(ipInstance.Spec.Binding.PodName) == 0 {
+			continue
+		}
+
+		podName := types.NamespacedName{
+			Namespace: ipInstance.Namespace,
+			Name:      ipInstance.Spec.Binding.PodName,
+		}
+		if _, ok := existing[podName]; ok {
+			continue
+		}
+		existing[podName] = struct{}{}
+
+		var pod = &corev1.Pod{}
+		if err = r.Get(ctx, podName, pod); err != nil {
+			if apierrors.IsNotFound(err) {
+				// retained ip instance without pod, it will be reallocated when pod comes back
+				continue
+			}
+			return nil, fmt.Errorf("unable to fetch pod %s: %v", podName.String(), err)
+		}
+
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		pods = append(pods, pod)
+	}
+	return pods, nil
+}
+
+func (r *SubnetDrainReconciler) updateDrainingPods(ctx context.Context, subnet *networkingv1.Subnet, count int32) error {
+	if subnet.Status.DrainingPods == count {
+		return nil
+	}
+
+	subnetPatch := client.MergeFrom(subnet.DeepCopy())
+	subnet.Status.DrainingPods = count
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		return r.Status().Patch(ctx, subnet, subnetPatch)
+	})
+}
+
+func (r *SubnetDrainReconciler) batchSize() int {
+	if config := r.Config.Get(); config != nil && config.SubnetDrainBatchSize > 0 {
+		return config.SubnetDrainBatchSize
+	}
+	return defaultSubnetDrainBatchSize
+}
+
+func (r *SubnetDrainReconciler) interval() time.Duration {
+	if config := r.Config.Get(); config != nil && config.SubnetDrainInterval > 0 {
+		return config.SubnetDrainInterval
+	}
+	return defaultSubnetDrainInterval
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SubnetDrainReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(ControllerSubnetDrain).
+		For(&networkingv1.Subnet{},
+			builder.WithPredicates(
+				&utils.IgnoreDeletePredicate{},
+				&predicate.GenerationChangedPredicate{},
+			)).
+		Watches(&source.Kind{Type: &networkingv1.IPInstance{}},
+			handler.EnqueueRequestsFromMapFunc(func(object client.Object) []reconcile.Request {
+				ipInstance, ok := object.(*networkingv1.IPInstance)
+				if !ok {
+					return nil
+				}
+				return []reconcile.Request{
+					{
+						NamespacedName: types.NamespacedName{
+							Name: ipInstance.Spec.Subnet,
+						},
+					},
+				}
+			}),
+			builder.WithPredicates(
+				&utils.IgnoreUpdatePredicate{},
+			),
+		).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.Max(),
+			RecoverPanic:            true,
+		}).
+		Complete(r)
+}
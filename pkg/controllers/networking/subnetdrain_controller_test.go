@@ -0,0 +1,205 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/managerconfig"
+)
+
+func TestSubnetDrainReconcile(t *testing.T) {
+	const namespace = "default"
+
+	newSubnet := func(name string, drain bool, drainingPods int32) *networkingv1.Subnet {
+		return &networkingv1.Subnet{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: networkingv1.SubnetSpec{
+				Network: "underlay",
+				Config:  &networkingv1.SubnetConfig{Drain: pointer.Bool(drain)},
+			},
+			Status: networkingv1.SubnetStatus{DrainingPods: drainingPods},
+		}
+	}
+	newIPInstance := func(name, subnetName, podName string) *networkingv1.IPInstance {
+		return &networkingv1.IPInstance{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{constants.LabelSubnet: subnetName},
+			},
+			Spec: networkingv1.IPInstanceSpec{
+				Subnet:  subnetName,
+				Network: "underlay",
+				Binding: networkingv1.Binding{PodName: podName},
+			},
+		}
+	}
+	newPod := func(name string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+	}
+
+	newObjects := func() []client.Object {
+		return []client.Object{
+			newSubnet("drained", true, 0),
+			newSubnet("normal", false, 2),
+			// pod-a has two addresses in drained subnet
+			newIPInstance("10-0-0-1", "drained", "pod-a"),
+			newIPInstance("10-0-0-2", "drained", "pod-a"),
+			newIPInstance("10-0-0-3", "drained", "pod-b"),
+			// retained address without pod and address of finished pod are not draining
+			newIPInstance("10-0-0-4", "drained", "pod-gone"),
+			newIPInstance("10-0-0-5", "drained", "pod-done"),
+			newIPInstance("10-0-1-1", "normal", "pod-c"),
+			newPod("pod-a", corev1.PodRunning),
+			newPod("pod-b", corev1.PodRunning),
+			newPod("pod-c", corev1.PodRunning),
+			newPod("pod-done", corev1.PodSucceeded),
+		}
+	}
+
+	newReconciler := func(batchSize int, blocked map[string]bool) (*SubnetDrainReconciler, *[]string) {
+		c := newFakeClient(newObjects()...)
+
+		var evicted []string
+		kubeClient := kubefake.NewSimpleClientset()
+		kubeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			eviction, ok := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+			if !ok {
+				return false, nil, nil
+			}
+			if blocked[eviction.Name] {
+				return true, nil, apierrors.NewTooManyRequests("disruption budget", 0)
+			}
+			evicted = append(evicted, eviction.Name)
+			return true, nil, nil
+		})
+
+		return &SubnetDrainReconciler{
+			Client:     c,
+			KubeClient: kubeClient,
+			Recorder:   record.NewFakeRecorder(10),
+			Config: managerconfig.NewStore(managerconfig.Configuration{
+				SubnetDrainBatchSize: batchSize,
+				SubnetDrainInterval:  time.Minute,
+			}),
+		}, &evicted
+	}
+
+	drainingPodsOf := func(t *testing.T, c client.Client, name string) int32 {
+		var subnet = &networkingv1.Subnet{}
+		if err := c.Get(context.Background(), types.NamespacedName{Name: name}, subnet); err != nil {
+			t.Fatalf("unable to get subnet %s: %v", name, err)
+		}
+		return subnet.Status.DrainingPods
+	}
+
+	drained := ctrl.Request{NamespacedName: types.NamespacedName{Name: "drained"}}
+
+	t.Run("only live pods are counted and evicted", func(t *testing.T) {
+		r, evicted := newReconciler(10, nil)
+
+		result, err := r.Reconcile(context.Background(), drained)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		sort.Strings(*evicted)
+		if expect := []string{"pod-a", "pod-b"}; !reflect.DeepEqual(*evicted, expect) {
+			t.Errorf("expected evicted pods %v but got %v", expect, *evicted)
+		}
+		if count := drainingPodsOf(t, r.Client, "drained"); count != 2 {
+			t.Errorf("expected 2 draining pods but got %d", count)
+		}
+		if result.RequeueAfter != time.Minute {
+			t.Errorf("expected requeue after %v but got %v", time.Minute, result.RequeueAfter)
+		}
+	})
+
+	t.Run("batches are paced by interval", func(t *testing.T) {
+		r, evicted := newReconciler(1, nil)
+
+		if _, err := r.Reconcile(context.Background(), drained); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(*evicted) != 1 {
+			t.Fatalf("expected one pod evicted in first batch but got %v", *evicted)
+		}
+
+		result, err := r.Reconcile(context.Background(), drained)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(*evicted) != 1 {
+			t.Errorf("expected no pod evicted before next batch but got %v", *evicted)
+		}
+		if result.RequeueAfter <= 0 || result.RequeueAfter > time.Minute {
+			t.Errorf("expected requeue before next batch but got %v", result.RequeueAfter)
+		}
+	})
+
+	t.Run("eviction blocked by disruption budget", func(t *testing.T) {
+		r, evicted := newReconciler(10, map[string]bool{"pod-a": true})
+
+		if _, err := r.Reconcile(context.Background(), drained); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if expect := []string{"pod-b"}; !reflect.DeepEqual(*evicted, expect) {
+			t.Errorf("expected evicted pods %v but got %v", expect, *evicted)
+		}
+	})
+
+	t.Run("subnet not drained", func(t *testing.T) {
+		r, evicted := newReconciler(10, nil)
+
+		result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Name: "normal"}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(*evicted) != 0 {
+			t.Errorf("expected no pod evicted but got %v", *evicted)
+		}
+		if count := drainingPodsOf(t, r.Client, "normal"); count != 0 {
+			t.Errorf("expected draining pods to be reset but got %d", count)
+		}
+		if result.RequeueAfter != 0 {
+			t.Errorf("expected no requeue but got %v", result.RequeueAfter)
+		}
+	})
+}
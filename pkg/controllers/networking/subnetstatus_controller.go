@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	corev1 "k8s.io/api/core/v1"
@@ -30,7 +31,6 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
@@ -48,6 +48,9 @@ import (
 
 const ControllerSubnetStatus = "SubnetStatus"
 
+// subnetUsageForecastResyncPeriod makes forecast of idle subnets decay as windows slide
+const subnetUsageForecastResyncPeriod = 5 * time.Minute
+
 // SubnetStatusReconciler reconciles a Subnet object
 type SubnetStatusReconciler struct {
 	client.Client
@@ -57,7 +60,13 @@ type SubnetStatusReconciler struct {
 
 	SubnetStatusUpdateChan <-chan event.GenericEvent
 
+	// UsageForecastWindows are the sliding windows of allocation rate to forecast exhaustion
+	// of subnets, empty means disabled
+	UsageForecastWindows []time.Duration
+
 	concurrency.ControllerConcurrency
+
+	forecaster *subnetUsageForecaster
 }
 
 //+kubebuilder:rbac:groups=networking.alibaba.com,resources=subnets,verbs=get;list;watch;create;update;patch;delete
@@ -83,13 +92,14 @@ func (r *SubnetStatusReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	}
 
 	if subnet.DeletionTimestamp != nil {
+		r.forecaster.Forget(subnet.Name)
 		cleanSubnetMetrics(subnet.Spec.Network, subnet.Name)
-		return ctrl.Result{}, wrapError("unable to remove finalizer", r.removeFinalizer(ctx, subnet))
+		return ctrl.Result{}, wrapError("unable to remove finalizer", utils.RemoveFinalizer(ctx, r, subnet, constants.FinalizerMetricsRegistered))
 
 	}
 
 	// make sure metrics will be un-registered before deletion
-	if err = r.addFinalizer(ctx, subnet); err != nil {
+	if err = utils.AddFinalizer(ctx, r, subnet, constants.FinalizerMetricsRegistered); err != nil {
 		return ctrl.Result{}, wrapError("unable to add finalizer to subnet", err)
 	}
 
@@ -105,13 +115,23 @@ func (r *SubnetStatusReconciler) Reconcile(ctx context.Context, req ctrl.Request
 			Used:      int32(usage.Used),
 			Available: int32(usage.Available),
 		},
+		Reserved:        int32(usage.Reserved),
 		LastAllocatedIP: usage.LastAllocation,
+		// draining pods are counted by drain controller
+		DrainingPods: subnet.Status.DrainingPods,
+	}
+
+	if r.forecaster.enabled() {
+		now := time.Now()
+		subnetStatus.Forecast = r.forecaster.Observe(subnet.Name, now, subnetStatus.Used, subnetStatus.Available)
+		updateSubnetForecastMetrics(subnet.Spec.Network, subnet.Name, subnetStatus.Forecast, now)
+		result.RequeueAfter = subnetUsageForecastResyncPeriod
 	}
 
 	// diff for no-op
 	if reflect.DeepEqual(&subnet.Status, subnetStatus) {
 		log.V(1).Info("subnet status is up-to-date, skip updating")
-		return ctrl.Result{}, nil
+		return result, nil
 	}
 
 	// update metrics
@@ -129,7 +149,7 @@ func (r *SubnetStatusReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	}
 
 	log.V(1).Info(fmt.Sprintf("sync subnet status to %+v", subnetStatus))
-	return ctrl.Result{}, nil
+	return result, nil
 }
 
 func updateSubnetUsageMetrics(networkName, subnetName string, subnetStatus *networkingv1.SubnetStatus) {
@@ -157,7 +177,33 @@ func updateSubnetUsageMetrics(networkName, subnetName string, subnetStatus *netw
 				"usageType":   metrics.IPAvailableUsageType,
 			},
 		).Set(float64(subnetStatus.Available))
+
+		metrics.SubnetIPUsageGauge.With(
+			prometheus.Labels{
+				"subnetName":  subnetName,
+				"networkName": networkName,
+				"usageType":   metrics.IPReservedUsageType,
+			},
+		).Set(float64(subnetStatus.Reserved))
+	}
+}
+
+func updateSubnetForecastMetrics(networkName, subnetName string, forecast *networkingv1.SubnetUsageForecast, now time.Time) {
+	labels := prometheus.Labels{
+		"subnetName":  subnetName,
+		"networkName": networkName,
+	}
+
+	if forecast == nil || forecast.ExhaustionTime == nil {
+		_ = metrics.SubnetIPExhaustionGauge.Delete(labels)
+		return
+	}
+
+	remaining := forecast.ExhaustionTime.Sub(now).Seconds()
+	if remaining < 0 {
+		remaining = 0
 	}
+	metrics.SubnetIPExhaustionGauge.With(labels).Set(remaining)
 }
 
 func cleanSubnetMetrics(networkName, subnetName string) {
@@ -181,34 +227,25 @@ func cleanSubnetMetrics(networkName, subnetName string) {
 			"networkName": networkName,
 			"usageType":   metrics.IPAvailableUsageType,
 		})
-}
 
-func (r *SubnetStatusReconciler) addFinalizer(ctx context.Context, subnet *networkingv1.Subnet) error {
-	if controllerutil.ContainsFinalizer(subnet, constants.FinalizerMetricsRegistered) {
-		return nil
-	}
-
-	patch := client.MergeFrom(subnet.DeepCopy())
-	controllerutil.AddFinalizer(subnet, constants.FinalizerMetricsRegistered)
-	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		return r.Patch(ctx, subnet, patch)
-	})
-}
-
-func (r *SubnetStatusReconciler) removeFinalizer(ctx context.Context, subnet *networkingv1.Subnet) error {
-	if !controllerutil.ContainsFinalizer(subnet, constants.FinalizerMetricsRegistered) {
-		return nil
-	}
+	_ = metrics.SubnetIPUsageGauge.Delete(
+		prometheus.Labels{
+			"subnetName":  subnetName,
+			"networkName": networkName,
+			"usageType":   metrics.IPReservedUsageType,
+		})
 
-	patch := client.MergeFrom(subnet.DeepCopy())
-	controllerutil.RemoveFinalizer(subnet, constants.FinalizerMetricsRegistered)
-	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		return r.Patch(ctx, subnet, patch)
-	})
+	_ = metrics.SubnetIPExhaustionGauge.Delete(
+		prometheus.Labels{
+			"subnetName":  subnetName,
+			"networkName": networkName,
+		})
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *SubnetStatusReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.forecaster = newSubnetUsageForecaster(r.UsageForecastWindows)
+
 	return ctrl.NewControllerManagedBy(mgr).
 		Named(ControllerSubnetStatus).
 		For(&networkingv1.Subnet{},
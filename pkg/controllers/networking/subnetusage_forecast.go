@@ -0,0 +1,164 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+)
+
+// subnetUsageForecastHorizon is the longest duration to be forecast, subnets will not be exhausted
+// in the horizon are taken as stable
+const subnetUsageForecastHorizon = 10 * 365 * 24 * time.Hour
+
+type usageSample struct {
+	time time.Time
+	used int32
+}
+
+// subnetUsageForecaster keeps samples of used addresses of subnets in memory and
+// estimates when addresses will be exhausted from allocation rates over sliding windows,
+// samples are lost on restart and windows are covered again from then on
+type subnetUsageForecaster struct {
+	sync.Mutex
+
+	// windows are sorted from the shortest to the longest
+	windows []time.Duration
+
+	// use "name" of subnet as key
+	samples map[string][]usageSample
+}
+
+func newSubnetUsageForecaster(windows []time.Duration) *subnetUsageForecaster {
+	var validWindows []time.Duration
+	for _, window := range windows {
+		if window > 0 {
+			validWindows = append(validWindows, window)
+		}
+	}
+	sort.Slice(validWindows, func(i, j int) bool {
+		return validWindows[i] < validWindows[j]
+	})
+
+	return &subnetUsageForecaster{
+		windows: validWindows,
+		samples: map[string][]usageSample{},
+	}
+}
+
+func (f *subnetUsageForecaster) enabled() bool {
+	return f != nil && len(f.windows) > 0
+}
+
+// Observe records used addresses of a subnet at present and returns the forecast of it,
+// nil will be returned if forecasting is disabled
+func (f *subnetUsageForecaster) Observe(subnetName string, now time.Time, used, available int32) *networkingv1.SubnetUsageForecast {
+	if !f.enabled() {
+		return nil
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	samples := f.samples[subnetName]
+	// only changes of usage are recorded, the rate between two samples is deduced to be constant
+	if len(samples) == 0 || samples[len(samples)-1].used != used {
+		samples = append(samples, usageSample{time: now, used: used})
+	}
+	samples = pruneUsageSamples(samples, now.Add(-f.windows[len(f.windows)-1]))
+	f.samples[subnetName] = samples
+
+	return forecastSubnetUsage(samples, f.windows, now, used, available)
+}
+
+// Forget drops all samples of a subnet
+func (f *subnetUsageForecaster) Forget(subnetName string) {
+	if !f.enabled() {
+		return
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	delete(f.samples, subnetName)
+}
+
+// pruneUsageSamples drops samples before the start of the longest window except the
+// last one of them, which is kept as the baseline of the longest window
+func pruneUsageSamples(samples []usageSample, start time.Time) []usageSample {
+	var first = 0
+	for i := range samples {
+		if samples[i].time.After(start) {
+			break
+		}
+		first = i
+	}
+	return samples[first:]
+}
+
+func forecastSubnetUsage(samples []usageSample, windows []time.Duration, now time.Time, used, available int32) *networkingv1.SubnetUsageForecast {
+	var (
+		forecast = &networkingv1.SubnetUsageForecast{}
+		maxRate  float64
+	)
+
+	for _, window := range windows {
+		var (
+			start    = now.Add(-window)
+			baseline = samples[0]
+		)
+		for i := range samples {
+			if samples[i].time.After(start) {
+				break
+			}
+			baseline = samples[i]
+		}
+
+		usedDelta := used - baseline.used
+		forecast.Windows = append(forecast.Windows, networkingv1.SubnetUsageWindow{
+			Duration:  metav1.Duration{Duration: window},
+			UsedDelta: usedDelta,
+		})
+
+		// usage before the start of window is not accounted
+		covered := now.Sub(baseline.time)
+		if baseline.time.Before(start) {
+			covered = window
+		}
+		if usedDelta <= 0 || covered <= 0 {
+			continue
+		}
+
+		if rate := float64(usedDelta) / covered.Seconds(); rate > maxRate {
+			maxRate = rate
+		}
+	}
+
+	if maxRate > 0 {
+		if remaining := float64(available) / maxRate; remaining < subnetUsageForecastHorizon.Seconds() {
+			exhaustionTime := metav1.NewTime(now.Add(time.Duration(remaining * float64(time.Second))).Truncate(time.Minute))
+			forecast.ExhaustionTime = &exhaustionTime
+		}
+	}
+
+	return forecast
+}
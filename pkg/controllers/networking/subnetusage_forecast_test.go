@@ -0,0 +1,176 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubnetUsageForecaster(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	type observation struct {
+		after     time.Duration
+		used      int32
+		available int32
+	}
+
+	tests := []struct {
+		name           string
+		windows        []time.Duration
+		observations   []observation
+		usedDeltas     []int32
+		exhaustionTime *time.Time
+		disabled       bool
+	}{
+		{
+			name:     "disabled",
+			windows:  []time.Duration{0},
+			disabled: true,
+			observations: []observation{
+				{0, 10, 90},
+			},
+		},
+		{
+			name:    "first observation",
+			windows: []time.Duration{time.Hour},
+			observations: []observation{
+				{0, 10, 90},
+			},
+			usedDeltas: []int32{0},
+		},
+		{
+			name:    "steady growth",
+			windows: []time.Duration{time.Hour},
+			observations: []observation{
+				{0, 10, 90},
+				{30 * time.Minute, 20, 80},
+				{time.Hour, 30, 70},
+			},
+			usedDeltas: []int32{20},
+			// 20 addresses per hour, 70 addresses left
+			exhaustionTime: timePtr(start.Add(time.Hour + 210*time.Minute)),
+		},
+		{
+			name:    "fastest rate of windows",
+			windows: []time.Duration{24 * time.Hour, time.Hour},
+			observations: []observation{
+				{0, 10, 90},
+				{23 * time.Hour, 20, 80},
+				{24 * time.Hour, 40, 60},
+			},
+			usedDeltas: []int32{20, 30},
+			// 20 addresses per hour in the shorter window, 60 addresses left
+			exhaustionTime: timePtr(start.Add(27 * time.Hour)),
+		},
+		{
+			name:    "usage before window is not accounted",
+			windows: []time.Duration{time.Hour},
+			observations: []observation{
+				{0, 10, 90},
+				{time.Hour, 20, 80},
+				{3 * time.Hour, 20, 80},
+			},
+			usedDeltas: []int32{0},
+		},
+		{
+			name:    "shrinking usage",
+			windows: []time.Duration{time.Hour},
+			observations: []observation{
+				{0, 20, 80},
+				{30 * time.Minute, 10, 90},
+			},
+			usedDeltas: []int32{-10},
+		},
+		{
+			name:    "exhaustion time is rounded to minute",
+			windows: []time.Duration{time.Hour},
+			observations: []observation{
+				{0, 0, 100},
+				{time.Hour, 7, 93},
+			},
+			usedDeltas: []int32{7},
+			// 93 / 7 hours is 13h17m08s
+			exhaustionTime: timePtr(start.Add(time.Hour + 13*time.Hour + 17*time.Minute)),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newSubnetUsageForecaster(test.windows)
+
+			for _, o := range test.observations[:len(test.observations)-1] {
+				f.Observe("subnet", start.Add(o.after), o.used, o.available)
+			}
+			last := test.observations[len(test.observations)-1]
+			forecast := f.Observe("subnet", start.Add(last.after), last.used, last.available)
+
+			if test.disabled {
+				if forecast != nil {
+					t.Fatalf("expect no forecast but got %+v", forecast)
+				}
+				return
+			}
+			if forecast == nil {
+				t.Fatalf("expect forecast but got nil")
+			}
+
+			if len(forecast.Windows) != len(test.usedDeltas) {
+				t.Fatalf("expect %d windows but got %d", len(test.usedDeltas), len(forecast.Windows))
+			}
+			for i := range forecast.Windows {
+				if forecast.Windows[i].UsedDelta != test.usedDeltas[i] {
+					t.Errorf("expect used delta %d of window %s but got %d", test.usedDeltas[i],
+						forecast.Windows[i].Duration.Duration, forecast.Windows[i].UsedDelta)
+				}
+			}
+
+			switch {
+			case test.exhaustionTime == nil && forecast.ExhaustionTime != nil:
+				t.Errorf("expect no exhaustion time but got %s", forecast.ExhaustionTime.Time)
+			case test.exhaustionTime != nil && forecast.ExhaustionTime == nil:
+				t.Errorf("expect exhaustion time %s but got nil", *test.exhaustionTime)
+			case test.exhaustionTime != nil && !forecast.ExhaustionTime.Time.Equal(*test.exhaustionTime):
+				t.Errorf("expect exhaustion time %s but got %s", *test.exhaustionTime, forecast.ExhaustionTime.Time)
+			}
+		})
+	}
+}
+
+func TestPruneUsageSamples(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	samples := []usageSample{
+		{time: start, used: 1},
+		{time: start.Add(time.Hour), used: 2},
+		{time: start.Add(2 * time.Hour), used: 3},
+	}
+
+	pruned := pruneUsageSamples(samples, start.Add(90*time.Minute))
+	if len(pruned) != 2 || pruned[0].used != 2 {
+		t.Fatalf("expect the last sample before start to be kept as baseline but got %+v", pruned)
+	}
+
+	pruned = pruneUsageSamples(samples, start.Add(-time.Hour))
+	if len(pruned) != 3 {
+		t.Fatalf("expect all samples to be kept but got %+v", pruned)
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -39,6 +39,10 @@ func InitIndexers(mgr ctrl.Manager) (err error) {
 			case networkingv1.NetworkTypeUnderlay:
 				return globalutils.DeepCopyStringSlice(network.Status.NodeList)
 			case networkingv1.NetworkTypeOverlay:
+				// tenant networks are never selected by nodes
+				if networkingv1.IsTenantNetwork(network) {
+					return nil
+				}
 				return []string{OverlayNodeName}
 			case networkingv1.NetworkTypeGlobalBGP:
 				return []string{GlobalBGPNodeName}
@@ -61,6 +65,18 @@ func InitIndexers(mgr ctrl.Manager) (err error) {
 		return err
 	}
 
+	// init rebind pod indexer for IPInstances
+	if err = mgr.GetFieldIndexer().IndexField(context.TODO(), &networkingv1.IPInstance{},
+		IndexerFieldRebindPod, func(obj client.Object) []string {
+			ipInstance, ok := obj.(*networkingv1.IPInstance)
+			if !ok || ipInstance.Spec.Rebind == nil {
+				return nil
+			}
+			return []string{ipInstance.Spec.Rebind.PodName}
+		}); err != nil {
+		return err
+	}
+
 	// init network indexer for Subnets
 	return mgr.GetFieldIndexer().IndexField(context.TODO(), &networkingv1.Subnet{},
 		IndexerFieldNetwork, func(obj client.Object) []string {
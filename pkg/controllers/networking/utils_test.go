@@ -0,0 +1,168 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/ipam"
+	ipamtypes "github.com/alibaba/hybridnet/pkg/ipam/types"
+)
+
+func newFakeClient(objects ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+}
+
+// fakeIPAMManager records the allocated, released, reserved, assigned and tracked addresses and returns
+// the preset usages of networks, tracking fails with trackErr if set, calling other methods will panic
+type fakeIPAMManager struct {
+	ipam.Manager
+
+	lock      sync.Mutex
+	allocated []string
+	released  []ipamtypes.SubnetIPSuite
+	reserved  []ipamtypes.SubnetIPSuite
+	assigned  map[string][]ipamtypes.SubnetIPSuite
+	tracked   []string
+	trackErr  error
+	usages    map[string]*ipamtypes.NetworkUsage
+}
+
+func (f *fakeIPAMManager) GetNetworkUsage(networkName string) (*ipamtypes.NetworkUsage, error) {
+	usage, ok := f.usages[networkName]
+	if !ok {
+		return nil, fmt.Errorf("network %s not found", networkName)
+	}
+	return usage, nil
+}
+
+// Allocate returns addresses of subnet "subnet1" in order, one for each allocation
+func (f *fakeIPAMManager) Allocate(networkName string, podInfo ipamtypes.PodInfo,
+	options ...ipamtypes.AllocateOption) ([]*ipamtypes.IP, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.allocated = append(f.allocated, podInfo.String())
+	return []*ipamtypes.IP{{
+		Address: &net.IPNet{IP: net.IPv4(10, 0, 0, byte(len(f.allocated))), Mask: net.CIDRMask(24, 32)},
+		Subnet:  "subnet1",
+		Network: networkName,
+	}}, nil
+}
+
+func (f *fakeIPAMManager) Release(networkName string, releaseSuites []ipamtypes.SubnetIPSuite) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.released = append(f.released, releaseSuites...)
+	return nil
+}
+
+func (f *fakeIPAMManager) Reserve(networkName string, reserveSuites []ipamtypes.SubnetIPSuite) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.reserved = append(f.reserved, reserveSuites...)
+	return nil
+}
+
+func (f *fakeIPAMManager) Assign(networkName string, podInfo ipamtypes.PodInfo, assignedSuites []ipamtypes.SubnetIPSuite,
+	options ...ipamtypes.AssignOption) ([]*ipamtypes.IP, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.assigned == nil {
+		f.assigned = map[string][]ipamtypes.SubnetIPSuite{}
+	}
+	f.assigned[podInfo.String()] = append(f.assigned[podInfo.String()], assignedSuites...)
+
+	var assignedIPs []*ipamtypes.IP
+	for _, suite := range assignedSuites {
+		ip := net.ParseIP(suite.IP)
+		assignedIPs = append(assignedIPs, &ipamtypes.IP{
+			Address: &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)},
+			Subnet:  suite.Subnet,
+			Network: networkName,
+		})
+	}
+	return assignedIPs, nil
+}
+
+func (f *fakeIPAMManager) Track(networkName string, ip *ipamtypes.IP) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.trackErr != nil {
+		return f.trackErr
+	}
+	f.tracked = append(f.tracked, ip.Address.IP.String())
+	return nil
+}
+
+// fakeIPAMStore records the pods which ip instances are coupled, decoupled or reserved, coupling
+// fails with coupleErr if set, calling other methods will panic
+type fakeIPAMStore struct {
+	ipam.Store
+
+	lock       sync.Mutex
+	coupleErr  error
+	coupled    []string
+	decoupled  []string
+	ipReserved []string
+}
+
+func (f *fakeIPAMStore) Couple(ctx context.Context, pod *corev1.Pod, IPs []*ipamtypes.IP,
+	options ...ipamtypes.CoupleOption) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.coupleErr != nil {
+		return f.coupleErr
+	}
+	f.coupled = append(f.coupled, pod.Namespace+"/"+pod.Name)
+	return nil
+}
+
+func (f *fakeIPAMStore) DeCouple(ctx context.Context, pod *corev1.Pod) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.decoupled = append(f.decoupled, pod.Namespace+"/"+pod.Name)
+	return nil
+}
+
+func (f *fakeIPAMStore) IPReserve(ctx context.Context, pod *corev1.Pod, opts ...ipamtypes.ReserveOption) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.ipReserved = append(f.ipReserved, pod.Namespace+"/"+pod.Name)
+	return nil
+}
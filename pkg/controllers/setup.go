@@ -0,0 +1,157 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/go-logr/logr"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/alibaba/hybridnet/pkg/controllers/multicluster"
+	"github.com/alibaba/hybridnet/pkg/controllers/networking"
+	"github.com/alibaba/hybridnet/pkg/feature"
+	"github.com/alibaba/hybridnet/pkg/managerconfig"
+	"github.com/alibaba/hybridnet/pkg/managerruntime"
+)
+
+// Options are the options of all the controllers of hybridnet manager, for embedding them into
+// managers of downstream distributions
+type Options struct {
+	// Networking are the options of networking controllers, which are always registered
+	Networking networking.RegisterOptions
+
+	// MultiCluster builds the options of multi-cluster controllers, which are registered once the
+	// MultiCluster feature gate is enabled, multi-cluster controllers are never registered if it is nil
+	MultiCluster func() (multicluster.RegisterOptions, error)
+
+	// PreStartHooks run after caches of manager are synced and before controllers are registered,
+	// a hook waiting for caches to sync named "wait-for-cache-sync" can be depended on
+	PreStartHooks []managerruntime.PreStartHook
+
+	// Logger is the logger of registration, the logger named "controllers" is used if it is nil
+	Logger logr.Logger
+}
+
+// SetupAll injects indexers of hybridnet controllers into manager and registers controllers once
+// manager is elected as leader. It must be called before manager starts, and the failure of
+// registration fails the start of manager.
+func SetupAll(mgr manager.Manager, options Options) error {
+	if options.Logger.GetSink() == nil {
+		options.Logger = ctrllog.Log.WithName("controllers")
+	}
+
+	// indexers need to be injected before informers are running
+	if err := networking.InitIndexers(mgr); err != nil {
+		return fmt.Errorf("unable to init indexers: %v", err)
+	}
+
+	if err := mgr.Add(&registrar{mgr: mgr, options: options}); err != nil {
+		return fmt.Errorf("unable to inject controller registrar: %v", err)
+	}
+	return nil
+}
+
+// registrar registers controllers after leader election, because IPAM is initialized from
+// the objects in caches, which must not be changed by other managers
+type registrar struct {
+	mgr     manager.Manager
+	options Options
+
+	multiClusterMutex      sync.Mutex
+	multiClusterRegistered bool
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (r *registrar) NeedLeaderElection() bool {
+	return true
+}
+
+func (r *registrar) Start(ctx context.Context) error {
+	hooks := append([]managerruntime.PreStartHook{
+		{
+			// wait for manager cache client ready
+			Name: "wait-for-cache-sync",
+			Run: func(ctx context.Context) error {
+				if ok := r.mgr.GetCache().WaitForCacheSync(ctx); !ok {
+					return fmt.Errorf("failed to wait for manager cache sync")
+				}
+				return nil
+			},
+		},
+	}, r.options.PreStartHooks...)
+
+	if err := managerruntime.RunPreStartHooks(ctx, r.options.Logger.WithName("pre-start"), hooks); err != nil {
+		return fmt.Errorf("unable to run pre-start hooks: %v", err)
+	}
+
+	if err := networking.RegisterToManager(ctx, r.mgr, r.options.Networking); err != nil {
+		return fmt.Errorf("unable to register networking controllers: %v", err)
+	}
+
+	if r.options.MultiCluster != nil {
+		if err := r.registerMultiCluster(ctx); err != nil {
+			return err
+		}
+
+		// multi-cluster controllers can be registered at runtime when feature is turned on,
+		// but running controllers can not be unregistered from manager
+		if config := r.options.Networking.Config; config != nil {
+			config.OnChange(func(*managerconfig.Configuration) {
+				if err := r.registerMultiCluster(ctx); err != nil {
+					r.options.Logger.Error(err, "unable to register multi-cluster controllers at runtime")
+				}
+			})
+		}
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+// registerMultiCluster registers multi-cluster controllers once if MultiCluster feature gate
+// is enabled, it will be retried on the next change of configuration if failed
+func (r *registrar) registerMultiCluster(ctx context.Context) error {
+	r.multiClusterMutex.Lock()
+	defer r.multiClusterMutex.Unlock()
+
+	if !feature.MultiClusterEnabled() {
+		if r.multiClusterRegistered {
+			r.options.Logger.Info("multi-cluster controllers keep running until restart after feature is turned off")
+		}
+		return nil
+	}
+
+	if r.multiClusterRegistered {
+		return nil
+	}
+
+	options, err := r.options.MultiCluster()
+	if err != nil {
+		return fmt.Errorf("unable to build options of multi-cluster controllers: %v", err)
+	}
+
+	if err = multicluster.RegisterToManager(ctx, r.mgr, options); err != nil {
+		return fmt.Errorf("unable to register multi-cluster controllers: %v", err)
+	}
+
+	r.multiClusterRegistered = true
+	return nil
+}
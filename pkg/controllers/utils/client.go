@@ -201,7 +201,8 @@ func FindOverlayNetwork(ctx context.Context, client client.Reader) (overlayNetwo
 
 	for i := range networkList.Items {
 		var network = networkList.Items[i]
-		if networkingv1.GetNetworkType(&network) == networkingv1.NetworkTypeOverlay {
+		// tenant networks are only used by pods specifying them explicitly
+		if networkingv1.GetNetworkType(&network) == networkingv1.NetworkTypeOverlay && !networkingv1.IsTenantNetwork(&network) {
 			return network.Name, nil
 		}
 	}
@@ -230,7 +231,7 @@ func FindOverlayNetworkNetID(ctx context.Context, client client.Reader) (*int32,
 	}
 	for i := range networkList.Items {
 		var network = &networkList.Items[i]
-		if networkingv1.GetNetworkType(network) == networkingv1.NetworkTypeOverlay {
+		if networkingv1.GetNetworkType(network) == networkingv1.NetworkTypeOverlay && !networkingv1.IsTenantNetwork(network) {
 			if network.Spec.NetID == nil {
 				return nil, nil
 			}
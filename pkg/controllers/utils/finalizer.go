@@ -0,0 +1,68 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// AddFinalizer adds finalizer to object if it does not exist
+func AddFinalizer(ctx context.Context, c client.Client, obj client.Object, finalizer string) error {
+	return patchFinalizers(ctx, c, obj, func() bool {
+		if controllerutil.ContainsFinalizer(obj, finalizer) {
+			return false
+		}
+		controllerutil.AddFinalizer(obj, finalizer)
+		return true
+	})
+}
+
+// RemoveFinalizer removes finalizer from object if it exists
+func RemoveFinalizer(ctx context.Context, c client.Client, obj client.Object, finalizer string) error {
+	return patchFinalizers(ctx, c, obj, func() bool {
+		if !controllerutil.ContainsFinalizer(obj, finalizer) {
+			return false
+		}
+		controllerutil.RemoveFinalizer(obj, finalizer)
+		return true
+	})
+}
+
+// patchFinalizers patches finalizers of object with optimistic lock, because finalizers of
+// one object may be maintained by different controllers, and merge patch will overwrite the
+// whole finalizer list. Object will be refreshed on conflict before mutating again.
+func patchFinalizers(ctx context.Context, c client.Client, obj client.Object, mutate func() bool) error {
+	var refresh = false
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if refresh {
+			if err := c.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+				return client.IgnoreNotFound(err)
+			}
+		}
+		refresh = true
+
+		patch := client.MergeFromWithOptions(obj.DeepCopyObject().(client.Object), client.MergeFromWithOptimisticLock{})
+		if !mutate() {
+			return nil
+		}
+		return c.Patch(ctx, obj, patch)
+	})
+}
@@ -0,0 +1,72 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+)
+
+func TestFinalizers(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = networkingv1.AddToScheme(scheme)
+
+	subnet := &networkingv1.Subnet{}
+	subnet.Name = "subnet"
+	subnet.Finalizers = []string{"test/existing"}
+
+	ctx := context.Background()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(subnet).Build()
+
+	// use a stale copy to make sure finalizers of others will not be overwritten
+	stale := &networkingv1.Subnet{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(subnet), stale); err != nil {
+		t.Fatalf("unable to get subnet: %v", err)
+	}
+
+	if err := AddFinalizer(ctx, c, subnet, "test/first"); err != nil {
+		t.Fatalf("unable to add finalizer: %v", err)
+	}
+	if err := AddFinalizer(ctx, c, stale, "test/second"); err != nil {
+		t.Fatalf("unable to add finalizer with stale object: %v", err)
+	}
+	if err := RemoveFinalizer(ctx, c, stale, "test/existing"); err != nil {
+		t.Fatalf("unable to remove finalizer: %v", err)
+	}
+
+	result := &networkingv1.Subnet{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(subnet), result); err != nil {
+		t.Fatalf("unable to get subnet: %v", err)
+	}
+
+	expected := []string{"test/first", "test/second"}
+	if !reflect.DeepEqual(result.Finalizers, expected) {
+		t.Errorf("expected finalizers %v but got %v", expected, result.Finalizers)
+	}
+
+	if !controllerutil.ContainsFinalizer(stale, "test/second") || controllerutil.ContainsFinalizer(stale, "test/existing") {
+		t.Errorf("object should be updated after patch, got finalizers %v", stale.Finalizers)
+	}
+}
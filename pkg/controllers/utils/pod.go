@@ -26,6 +26,10 @@ func PodIsEvicted(pod *v1.Pod) bool {
 	return pod.Status.Phase == v1.PodFailed && pod.Status.Reason == "Evicted"
 }
 
+func PodIsFailed(pod *v1.Pod) bool {
+	return pod.Status.Phase == v1.PodFailed
+}
+
 func PodIsCompleted(pod *v1.Pod) bool {
 	var unknownContainerCount = 0
 
@@ -65,7 +65,11 @@ func (NetworkSpecChangePredicate) Update(e event.UpdateEvent) bool {
 	// change indicators
 	// 1. netID
 	// 2. node selector
-	return !reflect.DeepEqual(oldNetwork.Spec.NetID, newNetwork.Spec.NetID) || !reflect.DeepEqual(oldNetwork.Spec.NodeSelector, newNetwork.Spec.NodeSelector)
+	// 3. cordon
+	// 4. default policy
+	return !reflect.DeepEqual(oldNetwork.Spec.NetID, newNetwork.Spec.NetID) || !reflect.DeepEqual(oldNetwork.Spec.NodeSelector, newNetwork.Spec.NodeSelector) ||
+		networkingv1.IsCordonedNetwork(oldNetwork) != networkingv1.IsCordonedNetwork(newNetwork) ||
+		!reflect.DeepEqual(oldNetwork.Spec.Default, newNetwork.Spec.Default)
 }
 
 type NetworkStatusChangePredicate struct {
@@ -118,7 +122,15 @@ func (SubnetSpecChangePredicate) Update(e event.UpdateEvent) bool {
 	// change indicators
 	// 1. address range
 	// 2. private
-	return !reflect.DeepEqual(oldSubnet.Spec.Range, newSubnet.Spec.Range) || networkingv1.IsPrivateSubnet(oldSubnet) != networkingv1.IsPrivateSubnet(newSubnet)
+	// 3. cordon
+	// 4. namespace restriction
+	// 5. allocation freeze windows
+	return !reflect.DeepEqual(oldSubnet.Spec.Range, newSubnet.Spec.Range) ||
+		networkingv1.IsPrivateSubnet(oldSubnet) != networkingv1.IsPrivateSubnet(newSubnet) ||
+		networkingv1.IsCordonedSubnet(oldSubnet) != networkingv1.IsCordonedSubnet(newSubnet) ||
+		networkingv1.IsNamespaceRestrictedSubnet(oldSubnet) != networkingv1.IsNamespaceRestrictedSubnet(newSubnet) ||
+		!reflect.DeepEqual(networkingv1.GetSubnetAllocationFreezeWindows(&oldSubnet.Spec),
+			networkingv1.GetSubnetAllocationFreezeWindows(&newSubnet.Spec))
 }
 
 type NetworkOfNodeChangePredicate struct {
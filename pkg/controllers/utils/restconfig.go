@@ -21,6 +21,8 @@ import (
 
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	multiclusterv1 "github.com/alibaba/hybridnet/pkg/apis/multicluster/v1"
 )
@@ -46,3 +48,38 @@ func NewRestConfigFromRemoteCluster(remoteCluster *multiclusterv1.RemoteCluster)
 
 	return clusterConfig, nil
 }
+
+// NewRestConfigForControllerGroup copies config with a separate rate limit, and a user
+// agent suffixed with group, which can be used as a hint of APF priority levels
+func NewRestConfigForControllerGroup(config *rest.Config, group string, qps float32, burst int) *rest.Config {
+	groupConfig := rest.CopyConfig(config)
+	// rate limiter takes precedence over qps and burst, so that it must not be shared
+	groupConfig.RateLimiter = nil
+	groupConfig.QPS = qps
+	groupConfig.Burst = burst
+
+	userAgent := groupConfig.UserAgent
+	if len(userAgent) == 0 {
+		userAgent = rest.DefaultKubernetesUserAgent()
+	}
+	groupConfig.UserAgent = userAgent + " controller-group/" + group
+
+	return groupConfig
+}
+
+// NewDelegatingClientFromManager creates a client which writes with config and reads
+// from the shared cache of manager
+func NewDelegatingClientFromManager(config *rest.Config, mgr manager.Manager) (client.Client, error) {
+	c, err := client.New(config, client.Options{
+		Scheme: mgr.GetScheme(),
+		Mapper: mgr.GetRESTMapper(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return client.NewDelegatingClient(client.NewDelegatingClientInput{
+		CacheReader: mgr.GetCache(),
+		Client:      c,
+	})
+}
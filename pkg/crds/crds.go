@@ -0,0 +1,166 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package crds installs and upgrades the CRDs of hybridnet built into binary, so that new
+// controllers never run against stale CRD schemas after upgrade. Manifests are copied from
+// charts/hybridnet/crds by "make crd-yamls".
+package crds
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/go-logr/logr"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/util/retry"
+)
+
+const manifestDir = "manifests"
+
+//go:embed manifests/*.yaml
+var manifests embed.FS
+
+// Load returns the CRDs built into binary
+func Load() ([]*apiextensionsv1.CustomResourceDefinition, error) {
+	entries, err := manifests.ReadDir(manifestDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var crds []*apiextensionsv1.CustomResourceDefinition
+	for _, entry := range entries {
+		data, err := manifests.ReadFile(path.Join(manifestDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+		for {
+			crd := &apiextensionsv1.CustomResourceDefinition{}
+			if err = decoder.Decode(crd); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("unable to decode %s: %v", entry.Name(), err)
+			}
+
+			// skip empty documents
+			if len(crd.Name) > 0 {
+				crds = append(crds, crd)
+			}
+		}
+	}
+	return crds, nil
+}
+
+// Installer creates or updates CRDs, then waits for them to be established
+type Installer struct {
+	Client apiextensionsclient.Interface
+	// EstablishedTimeout is the max duration to wait for CRDs to be established
+	EstablishedTimeout time.Duration
+	Logger             logr.Logger
+}
+
+func (i *Installer) Install(ctx context.Context) error {
+	crds, err := Load()
+	if err != nil {
+		return fmt.Errorf("unable to load crds: %v", err)
+	}
+
+	for _, crd := range crds {
+		if err = i.apply(ctx, crd); err != nil {
+			return fmt.Errorf("unable to apply crd %s: %v", crd.Name, err)
+		}
+	}
+
+	return i.waitForEstablished(ctx, crds)
+}
+
+func (i *Installer) apply(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition) error {
+	crdClient := i.Client.ApiextensionsV1().CustomResourceDefinitions()
+	// every replica of manager applies crds on start, the conflicts are retried
+	return retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}, func() error {
+		existing, err := crdClient.Get(ctx, crd.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			if _, err = crdClient.Create(ctx, crd, metav1.CreateOptions{}); err != nil {
+				return err
+			}
+			i.Logger.Info("crd is created", "crd", crd.Name)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		updated := existing.DeepCopy()
+		updated.Spec = crd.Spec
+		if updated.Annotations == nil {
+			updated.Annotations = map[string]string{}
+		}
+		for key, value := range crd.Annotations {
+			updated.Annotations[key] = value
+		}
+
+		if _, err = crdClient.Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+		i.Logger.Info("crd is updated", "crd", crd.Name)
+		return nil
+	})
+}
+
+func (i *Installer) waitForEstablished(ctx context.Context, crds []*apiextensionsv1.CustomResourceDefinition) error {
+	crdClient := i.Client.ApiextensionsV1().CustomResourceDefinitions()
+	for _, crd := range crds {
+		var lastReason string
+		if err := wait.PollImmediateWithContext(ctx, time.Second, i.EstablishedTimeout, func(ctx context.Context) (bool, error) {
+			current, err := crdClient.Get(ctx, crd.Name, metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+
+			for _, condition := range current.Status.Conditions {
+				switch condition.Type {
+				case apiextensionsv1.Established:
+					if condition.Status == apiextensionsv1.ConditionTrue {
+						return true, nil
+					}
+				case apiextensionsv1.NamesAccepted:
+					if condition.Status == apiextensionsv1.ConditionFalse {
+						lastReason = condition.Message
+					}
+				}
+			}
+			return false, nil
+		}); err != nil {
+			return fmt.Errorf("crd %s is not established: %v %s", crd.Name, err, lastReason)
+		}
+	}
+
+	i.Logger.Info("crds are established", "count", len(crds))
+	return nil
+}
@@ -0,0 +1,110 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package crds
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLoad(t *testing.T) {
+	crds, err := Load()
+	if err != nil {
+		t.Fatalf("unable to load crds: %v", err)
+	}
+
+	var names = map[string]bool{}
+	for _, crd := range crds {
+		names[crd.Name] = true
+	}
+	for _, name := range []string{
+		"ipinstances.networking.alibaba.com",
+		"networks.networking.alibaba.com",
+		"subnets.networking.alibaba.com",
+		"remoteclusters.multicluster.alibaba.com",
+	} {
+		if !names[name] {
+			t.Errorf("expect crd %s to be loaded", name)
+		}
+	}
+}
+
+func TestInstall(t *testing.T) {
+	crds, err := Load()
+	if err != nil {
+		t.Fatalf("unable to load crds: %v", err)
+	}
+
+	// a stale crd of older version without printer columns
+	var ipInstanceCRD *apiextensionsv1.CustomResourceDefinition
+	for _, crd := range crds {
+		if crd.Name == "ipinstances.networking.alibaba.com" {
+			ipInstanceCRD = crd
+		}
+	}
+	stale := ipInstanceCRD.DeepCopy()
+	stale.Spec.Versions[0].AdditionalPrinterColumns = nil
+
+	ctx := context.Background()
+	client := fake.NewSimpleClientset(stale)
+	installer := &Installer{
+		Client:             client,
+		EstablishedTimeout: 2 * time.Second,
+		Logger:             logr.Discard(),
+	}
+
+	for _, crd := range crds {
+		if err = installer.apply(ctx, crd); err != nil {
+			t.Fatalf("unable to apply crd %s: %v", crd.Name, err)
+		}
+	}
+
+	updated, err := client.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, stale.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unable to get crd: %v", err)
+	}
+	if len(updated.Spec.Versions[0].AdditionalPrinterColumns) == 0 {
+		t.Errorf("expect stale crd %s to be updated", stale.Name)
+	}
+
+	if err = installer.waitForEstablished(ctx, crds); err == nil {
+		t.Fatalf("expect crds not established")
+	}
+
+	for _, crd := range crds {
+		established, err := client.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, crd.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unable to get crd: %v", err)
+		}
+		established.Status.Conditions = []apiextensionsv1.CustomResourceDefinitionCondition{{
+			Type:   apiextensionsv1.Established,
+			Status: apiextensionsv1.ConditionTrue,
+		}}
+		if _, err = client.ApiextensionsV1().CustomResourceDefinitions().UpdateStatus(ctx, established, metav1.UpdateOptions{}); err != nil {
+			t.Fatalf("unable to update status of crd: %v", err)
+		}
+	}
+	if err = installer.waitForEstablished(ctx, crds); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
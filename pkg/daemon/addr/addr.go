@@ -33,6 +33,16 @@ import (
 
 type subnetToPodMap map[string]net.IP
 
+// Interface maintains enhanced addresses on vlan forward interfaces, it's implemented by Manager
+// which configures the host and by FakeManager which keeps everything in memory.
+type Interface interface {
+	ResetInfos()
+	TryAddPodInfo(forwardNodeIfName string, subnet *net.IPNet, podIP net.IP)
+	SyncAddresses(getIPInstanceByAddress func(net.IP) (*networkingv1.IPInstance, error)) error
+}
+
+var _ Interface = &Manager{}
+
 type Manager struct {
 	family        int
 	localNodeName string
@@ -175,7 +185,8 @@ func (m *Manager) SyncAddresses(getIPInstanceByAddress func(net.IP) (*networking
 
 						if ipInstance != nil {
 							nodeName := ipInstance.Labels[constants.LabelNode]
-							if nodeName == m.localNodeName {
+							if nodeName == m.localNodeName ||
+								ipInstance.Labels[constants.LabelRebindSourceNode] == m.localNodeName {
 								// exist enhanced address is still valid, just keep it
 								continue
 							}
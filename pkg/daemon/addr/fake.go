@@ -0,0 +1,117 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package addr
+
+import (
+	"net"
+	"sync"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+)
+
+// FakeManager is an in-memory Interface which records enhanced addresses instead of
+// configuring them on host, for testing without privileges.
+type FakeManager struct {
+	mu sync.Mutex
+
+	family int
+
+	interfaceToSubnetMap       map[string]subnetToPodMap
+	syncedInterfaceToSubnetMap map[string]map[string]net.IP
+	syncCount                  int
+
+	// SyncErr will be returned by SyncAddresses if not nil.
+	SyncErr error
+
+	// OnSync will be called with the synced enhanced addresses, which are indexed by interface
+	// name and subnet, every time SyncAddresses succeeds.
+	OnSync func(family int, interfaceToSubnetAddrs map[string]map[string]net.IP)
+}
+
+var _ Interface = &FakeManager{}
+
+func NewFakeManager(family int) *FakeManager {
+	return &FakeManager{
+		family:               family,
+		interfaceToSubnetMap: map[string]subnetToPodMap{},
+	}
+}
+
+func (f *FakeManager) ResetInfos() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.interfaceToSubnetMap = map[string]subnetToPodMap{}
+}
+
+func (f *FakeManager) TryAddPodInfo(forwardNodeIfName string, subnet *net.IPNet, podIP net.IP) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.interfaceToSubnetMap[forwardNodeIfName] == nil {
+		f.interfaceToSubnetMap[forwardNodeIfName] = subnetToPodMap{}
+	}
+
+	// we only need one local pod ip for every subnet
+	if _, exist := f.interfaceToSubnetMap[forwardNodeIfName][subnet.String()]; !exist {
+		f.interfaceToSubnetMap[forwardNodeIfName][subnet.String()] = podIP
+	}
+}
+
+// SyncAddresses records the enhanced addresses, getIPInstanceByAddress is not used
+// because there is no exist address on host to be checked.
+func (f *FakeManager) SyncAddresses(_ func(net.IP) (*networkingv1.IPInstance, error)) error {
+	f.mu.Lock()
+	if f.SyncErr != nil {
+		f.mu.Unlock()
+		return f.SyncErr
+	}
+
+	synced := make(map[string]map[string]net.IP, len(f.interfaceToSubnetMap))
+	for forwardNodeIfName, subnetMap := range f.interfaceToSubnetMap {
+		synced[forwardNodeIfName] = make(map[string]net.IP, len(subnetMap))
+		for subnet, podIP := range subnetMap {
+			synced[forwardNodeIfName][subnet] = podIP
+		}
+	}
+
+	f.syncedInterfaceToSubnetMap = synced
+	f.syncCount++
+	onSync := f.OnSync
+	f.mu.Unlock()
+
+	if onSync != nil {
+		onSync(f.family, synced)
+	}
+	return nil
+}
+
+// Synced returns the enhanced addresses of the last successful SyncAddresses.
+func (f *FakeManager) Synced() map[string]map[string]net.IP {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.syncedInterfaceToSubnetMap
+}
+
+// SyncCount returns how many times SyncAddresses succeeds.
+func (f *FakeManager) SyncCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.syncCount
+}
@@ -26,7 +26,8 @@ import (
 
 // CheckWithTimeout checks vlan network environment and duplicate ip problems,
 // timeout parameter determines how long this function will exactly last.
-func CheckWithTimeout(ifi *net.Interface, srcPod, gateway net.IP, timeout time.Duration) error {
+// A duplicate ip owned by adoptedHw is adopted instead of being a conflict, if adoptedHw is not nil.
+func CheckWithTimeout(ifi *net.Interface, srcPod, gateway net.IP, adoptedHw net.HardwareAddr, timeout time.Duration) error {
 	// Resolve gateway ip for vlan check.
 	if _, err := pingOverInterface(srcPod, gateway, ifi, timeout); err != nil {
 		return fmt.Errorf("failed to resolve arp from pod %v to gateway %v: %v"+
@@ -36,14 +37,15 @@ func CheckWithTimeout(ifi *net.Interface, srcPod, gateway net.IP, timeout time.D
 
 	// Resolve src pod ip for duplicate ip check and send gratuitous arp.
 	// Src ip should be 0.0.0.0 for arp probe.
-	if duplicatedHw, err := pingOverInterface(net.ParseIP("0.0.0.0"), srcPod, ifi, timeout); err == nil {
+	if duplicatedHw, err := pingOverInterface(net.ParseIP("0.0.0.0"), srcPod, ifi, timeout); err == nil &&
+		(adoptedHw == nil || duplicatedHw.String() != adoptedHw.String()) {
 		return fmt.Errorf("pod ip %v duplicated"+
 			", please check if ip %v is occupied by other machines or containers, another hw addr is %v",
 			srcPod.String(), srcPod.String(), duplicatedHw.String())
 	}
 
 	// Send gratuitous arp to ensure remote neigh cache flushed.
-	if err := gratuitousOverInterface(srcPod, ifi); err != nil {
+	if err := Gratuitous(ifi, srcPod); err != nil {
 		return fmt.Errorf("failed to send gratuitous arp for pod %v: %v", srcPod.String(), err)
 	}
 
@@ -72,7 +74,9 @@ func pingOverInterface(srcIP, dstIP net.IP, iif *net.Interface, timeout time.Dur
 	return hw, nil
 }
 
-func gratuitousOverInterface(ip net.IP, iif *net.Interface) error {
+// Gratuitous broadcasts gratuitous arp request and reply of ip over interface,
+// remote neigh caches of ip are updated to the hw addr of interface.
+func Gratuitous(iif *net.Interface, ip net.IP) error {
 	client, err := Dial(iif, ip)
 	if err != nil {
 		return fmt.Errorf("failed to init client with ip %v interface %v: %v", ip.String(), iif.Name, err)
@@ -47,6 +47,8 @@ type Manager struct {
 	routerV6Address net.IP
 
 	bgpServer *server.BgpServer
+	// bgp server is never started in a dry dataplane, recorded peers and paths are only logged
+	dryRun bool
 
 	logger logr.Logger
 
@@ -89,7 +91,6 @@ func NewManager(peeringInterfaceName, grpcListenAddress string, logger logr.Logg
 	case 0:
 		return nil, fmt.Errorf("there is no valid address on bpg peering interface")
 	case 1:
-		manager.routerID = existLinkAddress[0].IP.String()
 		if existLinkAddress[0].IP.To4() == nil {
 			manager.routerV6Address = existLinkAddress[0].IP
 		} else {
@@ -100,12 +101,10 @@ func NewManager(peeringInterfaceName, grpcListenAddress string, logger logr.Logg
 			for _, addr := range existLinkAddress {
 				if addr.IP.To4() == nil {
 					manager.routerV6Address = addr.IP
+				} else {
+					manager.routerV4Address = addr.IP
 				}
-				manager.routerV4Address = addr.IP
 			}
-
-			// Use v4 address as routerID by default if v4/v6 addresses exist at the same time.
-			manager.routerID = manager.routerV4Address.String()
 			break
 		}
 		fallthrough
@@ -137,19 +136,33 @@ func NewManager(peeringInterfaceName, grpcListenAddress string, logger logr.Logg
 		if manager.routerV4Address == nil && manager.routerV6Address == nil {
 			return nil, fmt.Errorf("failed to find valid address for bgp router")
 		}
+	}
 
-		if manager.routerV4Address != nil {
-			// Use v4 address as routerID by default if v4/v6 addresses exist at the same time.
-			manager.routerID = manager.routerV4Address.String()
-		} else {
-			manager.routerID = manager.routerV6Address.String()
-		}
+	// Use v4 address as routerID by default if v4/v6 addresses exist at the same time, router id of
+	// bgp is always a 32-bit number, so it is generated from v6 address on ipv6-only nodes.
+	if manager.routerV4Address != nil {
+		manager.routerID = manager.routerV4Address.String()
+	} else {
+		manager.routerID = generateRouterIDFromIPv6(manager.routerV6Address)
 	}
 
 	go manager.bgpServer.Serve()
 	return manager, nil
 }
 
+// NewDryRunManager creates a Manager for a dry dataplane, which never looks up the peering
+// interface or starts bgp server, and sync operations only log recorded infos
+func NewDryRunManager(logger logr.Logger) *Manager {
+	return &Manager{
+		dryRun:     true,
+		logger:     logger,
+		peerMap:    map[string]*peerInfo{},
+		subnetMap:  map[string]*net.IPNet{},
+		ipMap:      map[string]*ipInfo{},
+		startMutex: sync.RWMutex{},
+	}
+}
+
 func (m *Manager) RecordPeer(address, password string, asn int, gracefulRestartTime int32, allowNotEstablished bool) {
 	if gracefulRestartTime == 0 {
 		gracefulRestartTime = 300
@@ -196,6 +209,11 @@ func (m *Manager) TryStart(asn uint32) error {
 	m.startMutex.Lock()
 	defer m.startMutex.Unlock()
 
+	if m.dryRun {
+		m.logger.Info("dry dataplane, skip starting bgp server", "asn", asn)
+		return nil
+	}
+
 	if m.localASN != 0 {
 		if m.localASN != asn {
 			return fmt.Errorf("can not restart bgp manager (local AS number: %v) with a different AS number %v",
@@ -314,6 +332,12 @@ func (m *Manager) SyncSubnetInfos() error {
 }
 
 func (m *Manager) SyncPeerAndSubnetInfos() error {
+	if m.dryRun {
+		m.logger.Info("dry dataplane, skip syncing bgp peers and subnet paths",
+			"peers", len(m.peerMap), "subnets", len(m.subnetMap))
+		return nil
+	}
+
 	if err := m.SyncPeerInfos(); err != nil {
 		return err
 	}
@@ -322,6 +346,11 @@ func (m *Manager) SyncPeerAndSubnetInfos() error {
 }
 
 func (m *Manager) SyncIPInfos() error {
+	if m.dryRun {
+		m.logger.Info("dry dataplane, skip syncing bgp ip paths", "ips", len(m.ipMap))
+		return nil
+	}
+
 	// If bgp manager is not started, do nothing.
 	if !m.CheckIfStart() {
 		return nil
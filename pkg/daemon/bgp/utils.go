@@ -103,6 +103,13 @@ func generatePeerConfig(p *peerInfo) *api.Peer {
 	}
 }
 
+// generateRouterIDFromIPv6 uses the last 32 bits of ipv6 address as router id, which is the
+// same as the interface identifier of most addressing plans and unique among nodes of a subnet
+func generateRouterIDFromIPv6(ip net.IP) string {
+	ip = ip.To16()
+	return net.IPv4(ip[12], ip[13], ip[14], ip[15]).String()
+}
+
 func getIPFamilyFromIP(ip net.IP) *api.Family {
 	if ip.To4() == nil {
 		return v6Family
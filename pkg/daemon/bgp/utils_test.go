@@ -0,0 +1,39 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package bgp
+
+import (
+	"net"
+	"testing"
+)
+
+func TestGenerateRouterIDFromIPv6(t *testing.T) {
+	tests := []struct {
+		ip       string
+		routerID string
+	}{
+		{"fd00:10::a0b:c0d", "10.11.12.13"},
+		{"2001:db8::1", "0.0.0.1"},
+		{"fe80::ffff:ffff", "255.255.255.255"},
+	}
+
+	for _, test := range tests {
+		if routerID := generateRouterIDFromIPv6(net.ParseIP(test.ip)); routerID != test.routerID {
+			t.Errorf("unexpected router id %v of %v, expect %v", routerID, test.ip, test.routerID)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package chaos
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	extraliptables "github.com/coreos/go-iptables/iptables"
+	"github.com/vishvananda/netlink"
+
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/daemon/iptables"
+)
+
+const (
+	ChainChaosForward = iptables.CustomChainPrefix + "CHAOS-FORWARD"
+	ChainChaosInput   = iptables.CustomChainPrefix + "CHAOS-INPUT"
+	ChainChaosOutput  = iptables.CustomChainPrefix + "CHAOS-OUTPUT"
+
+	chainInput  = "INPUT"
+	chainOutput = "OUTPUT"
+)
+
+// hookChains are the built-in chains of filter table and the chaos chains they jump to
+var hookChains = []struct {
+	hook  string
+	chain string
+}{
+	{hook: iptables.ChainForward, chain: ChainChaosForward},
+	{hook: chainInput, chain: ChainChaosInput},
+	{hook: chainOutput, chain: ChainChaosOutput},
+}
+
+// Drop drops a percentage of pod traffic toward the destination
+type Drop struct {
+	Destination *net.IPNet
+	Percent     int32
+}
+
+// Delay delays all the traffic leaving vxlan devices
+type Delay struct {
+	Latency time.Duration
+	Jitter  time.Duration
+}
+
+// Faults are all the faults injected into the dataplane of this node
+type Faults struct {
+	Drops          []Drop
+	BlackholeVTEPs []net.IP
+	// Delay is nil if tunnel traffic is not delayed
+	Delay *Delay
+}
+
+// Manager injects faults by iptables rules of dedicated chains in filter table and by netem
+// qdiscs on vxlan devices, nothing is left on host if faults are empty.
+type Manager struct {
+	vxlanUDPPort int
+
+	v4Helper *extraliptables.IPTables
+	v6Helper *extraliptables.IPTables
+
+	// appliedDelay is the delay which netem qdiscs on vxlan devices are created with
+	appliedDelay *Delay
+}
+
+func NewManager(vxlanUDPPort int) (*Manager, error) {
+	v4Helper, err := extraliptables.NewWithProtocol(extraliptables.ProtocolIPv4)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ipv4 iptables helper: %v", err)
+	}
+
+	v6Helper, err := extraliptables.NewWithProtocol(extraliptables.ProtocolIPv6)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ipv6 iptables helper: %v", err)
+	}
+
+	return &Manager{
+		vxlanUDPPort: vxlanUDPPort,
+		v4Helper:     v4Helper,
+		v6Helper:     v6Helper,
+	}, nil
+}
+
+// Sync makes the dataplane inject exactly the faults
+func (m *Manager) Sync(faults *Faults) error {
+	if err := syncRules(m.v4Helper, rulesOf(faults, iptables.ProtocolIpv4, m.vxlanUDPPort)); err != nil {
+		return fmt.Errorf("failed to sync ipv4 chaos rules: %v", err)
+	}
+
+	if err := syncRules(m.v6Helper, rulesOf(faults, iptables.ProtocolIpv6, m.vxlanUDPPort)); err != nil {
+		return fmt.Errorf("failed to sync ipv6 chaos rules: %v", err)
+	}
+
+	if err := m.syncDelay(faults.Delay); err != nil {
+		return fmt.Errorf("failed to sync delay of vxlan devices: %v", err)
+	}
+	return nil
+}
+
+// rulesOf returns the rule specs of each chaos chain for the protocol
+func rulesOf(faults *Faults, protocol iptables.Protocol, vxlanUDPPort int) map[string][][]string {
+	isIPv4 := protocol == iptables.ProtocolIpv4
+	rules := map[string][][]string{}
+
+	for _, drop := range faults.Drops {
+		if (drop.Destination.IP.To4() != nil) != isIPv4 {
+			continue
+		}
+
+		rule := []string{"-d", drop.Destination.String()}
+		if drop.Percent < 100 {
+			rule = append(rule, "-m", "statistic", "--mode", "random",
+				"--probability", strconv.FormatFloat(float64(drop.Percent)/100, 'f', 2, 64))
+		}
+		rules[ChainChaosForward] = append(rules[ChainChaosForward], append(rule, "-j", "DROP"))
+	}
+
+	port := strconv.Itoa(vxlanUDPPort)
+	for _, vtepIP := range faults.BlackholeVTEPs {
+		if (vtepIP.To4() != nil) != isIPv4 {
+			continue
+		}
+
+		rules[ChainChaosInput] = append(rules[ChainChaosInput],
+			[]string{"-s", vtepIP.String(), "-p", "udp", "--dport", port, "-j", "DROP"})
+		rules[ChainChaosOutput] = append(rules[ChainChaosOutput],
+			[]string{"-d", vtepIP.String(), "-p", "udp", "--dport", port, "-j", "DROP"})
+	}
+
+	return rules
+}
+
+func syncRules(helper *extraliptables.IPTables, rules map[string][][]string) error {
+	for _, hookChain := range hookChains {
+		jumpRule := []string{"-j", hookChain.chain}
+
+		if len(rules[hookChain.chain]) == 0 {
+			exist, err := helper.ChainExists(iptables.TableFilter, hookChain.chain)
+			if err != nil {
+				return fmt.Errorf("failed to check chain %v: %v", hookChain.chain, err)
+			}
+			if !exist {
+				continue
+			}
+
+			if err := helper.DeleteIfExists(iptables.TableFilter, hookChain.hook, jumpRule...); err != nil {
+				return fmt.Errorf("failed to delete jump rule to chain %v: %v", hookChain.chain, err)
+			}
+			if err := helper.ClearAndDeleteChain(iptables.TableFilter, hookChain.chain); err != nil {
+				return fmt.Errorf("failed to delete chain %v: %v", hookChain.chain, err)
+			}
+			continue
+		}
+
+		// the chain is created if not exist
+		if err := helper.ClearChain(iptables.TableFilter, hookChain.chain); err != nil {
+			return fmt.Errorf("failed to clear chain %v: %v", hookChain.chain, err)
+		}
+
+		for _, rule := range rules[hookChain.chain] {
+			if err := helper.Append(iptables.TableFilter, hookChain.chain, rule...); err != nil {
+				return fmt.Errorf("failed to append rule %v to chain %v: %v",
+					strings.Join(rule, " "), hookChain.chain, err)
+			}
+		}
+
+		// faults go before any other rules, so that accepted traffic is affected too
+		exist, err := helper.Exists(iptables.TableFilter, hookChain.hook, jumpRule...)
+		if err != nil {
+			return fmt.Errorf("failed to check jump rule to chain %v: %v", hookChain.chain, err)
+		}
+		if !exist {
+			if err := helper.Insert(iptables.TableFilter, hookChain.hook, 1, jumpRule...); err != nil {
+				return fmt.Errorf("failed to insert jump rule to chain %v: %v", hookChain.chain, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *Manager) syncDelay(delay *Delay) error {
+	linkList, err := netlink.LinkList()
+	if err != nil {
+		return fmt.Errorf("failed to list link: %v", err)
+	}
+
+	for _, link := range linkList {
+		if !strings.Contains(link.Attrs().Name, constants.VxlanLinkInfix) {
+			continue
+		}
+
+		qdiscList, err := netlink.QdiscList(link)
+		if err != nil {
+			return fmt.Errorf("failed to list qdisc of link %v: %v", link.Attrs().Name, err)
+		}
+
+		var rootNetem netlink.Qdisc
+		for _, qdisc := range qdiscList {
+			if qdisc.Attrs().Parent == netlink.HANDLE_ROOT && qdisc.Type() == "netem" {
+				rootNetem = qdisc
+			}
+		}
+
+		if delay == nil {
+			// vxlan devices have no netem qdiscs except the ones of delay
+			if rootNetem != nil {
+				if err := netlink.QdiscDel(rootNetem); err != nil {
+					return fmt.Errorf("failed to delete netem qdisc of link %v: %v", link.Attrs().Name, err)
+				}
+			}
+			continue
+		}
+
+		// replacing a qdisc drops the packets queued in it, so the unchanged one is kept
+		if rootNetem != nil && reflect.DeepEqual(delay, m.appliedDelay) {
+			continue
+		}
+
+		if err := netlink.QdiscReplace(netlink.NewNetem(netlink.QdiscAttrs{
+			LinkIndex: link.Attrs().Index,
+			Handle:    netlink.MakeHandle(1, 0),
+			Parent:    netlink.HANDLE_ROOT,
+		}, netlink.NetemQdiscAttrs{
+			Latency: uint32(delay.Latency.Microseconds()),
+			Jitter:  uint32(delay.Jitter.Microseconds()),
+		})); err != nil {
+			return fmt.Errorf("failed to replace netem qdisc of link %v: %v", link.Attrs().Name, err)
+		}
+	}
+
+	m.appliedDelay = delay
+	return nil
+}
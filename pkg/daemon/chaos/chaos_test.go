@@ -0,0 +1,76 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package chaos
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/alibaba/hybridnet/pkg/daemon/iptables"
+)
+
+func TestRulesOf(t *testing.T) {
+	_, privateV4, _ := net.ParseCIDR("10.0.0.0/8")
+	_, privateV6, _ := net.ParseCIDR("fd00::/64")
+
+	faults := &Faults{
+		Drops: []Drop{
+			{Destination: privateV4, Percent: 30},
+			{Destination: privateV6, Percent: 100},
+		},
+		BlackholeVTEPs: []net.IP{net.ParseIP("192.168.0.10")},
+	}
+
+	tests := []struct {
+		name     string
+		protocol iptables.Protocol
+		expect   map[string][][]string
+	}{
+		{
+			name:     "ipv4",
+			protocol: iptables.ProtocolIpv4,
+			expect: map[string][][]string{
+				ChainChaosForward: {
+					{"-d", "10.0.0.0/8", "-m", "statistic", "--mode", "random", "--probability", "0.30", "-j", "DROP"},
+				},
+				ChainChaosInput: {
+					{"-s", "192.168.0.10", "-p", "udp", "--dport", "8472", "-j", "DROP"},
+				},
+				ChainChaosOutput: {
+					{"-d", "192.168.0.10", "-p", "udp", "--dport", "8472", "-j", "DROP"},
+				},
+			},
+		},
+		{
+			name:     "ipv6",
+			protocol: iptables.ProtocolIpv6,
+			expect: map[string][][]string{
+				ChainChaosForward: {
+					{"-d", "fd00::/64", "-j", "DROP"},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if rules := rulesOf(faults, test.protocol, 8472); !reflect.DeepEqual(rules, test.expect) {
+				t.Errorf("test %s fail, expect rules %v but got %v", test.name, test.expect, rules)
+			}
+		})
+	}
+}
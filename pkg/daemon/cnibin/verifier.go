@@ -0,0 +1,204 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cnibin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// HybridnetBinarySource is the cni binary of hybridnet inside daemon image
+	HybridnetBinarySource = "/hybridnet/hybridnet"
+	// CommunityPluginsSourceDir is the directory of community cni plugins inside daemon image
+	CommunityPluginsSourceDir = "/cni-plugins"
+)
+
+const (
+	ReasonCNIBinaryTampered        = "CNIBinaryTampered"
+	ReasonCNIBinaryReinstalled     = "CNIBinaryReinstalled"
+	ReasonCNIBinaryReinstallFailed = "CNIBinaryReinstallFailed"
+)
+
+// Binary is a cni binary installed onto host
+type Binary struct {
+	Source      string
+	Destination string
+}
+
+// Binaries returns the hybridnet binary and the community plugins installed into cniBinDir
+func Binaries(cniBinDir string, communityPlugins []string) []Binary {
+	binaries := []Binary{{
+		Source:      HybridnetBinarySource,
+		Destination: filepath.Join(cniBinDir, "hybridnet"),
+	}}
+	for _, plugin := range communityPlugins {
+		binaries = append(binaries, Binary{
+			Source:      filepath.Join(CommunityPluginsSourceDir, plugin),
+			Destination: filepath.Join(cniBinDir, plugin),
+		})
+	}
+	return binaries
+}
+
+// Verifier periodically checks the cni binaries on host against the sha256 digests of the
+// ones inside image, which is trusted, and re-installs the binaries which are tampered with
+type Verifier struct {
+	Binaries []Binary
+	Interval time.Duration
+	NodeName string
+	Recorder record.EventRecorder
+	Logger   logr.Logger
+
+	expectedDigests map[string]string
+}
+
+// Start implements manager.Runnable
+func (v *Verifier) Start(ctx context.Context) error {
+	v.expectedDigests = map[string]string{}
+	for _, binary := range v.Binaries {
+		digest, err := digestOf(binary.Source)
+		if err != nil {
+			return fmt.Errorf("failed to get digest of source cni binary %v: %v", binary.Source, err)
+		}
+		v.expectedDigests[binary.Source] = digest
+	}
+
+	v.Logger.Info("cni binary verifier started", "interval", v.Interval)
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		v.verify()
+	}, v.Interval)
+	return nil
+}
+
+func (v *Verifier) verify() {
+	nodeRef := &corev1.ObjectReference{
+		Kind: "Node",
+		Name: v.NodeName,
+		// the same as kubelet, node events are referred by node name
+		UID: types.UID(v.NodeName),
+	}
+
+	for _, binary := range v.Binaries {
+		expected := v.expectedDigests[binary.Source]
+
+		actual, err := digestOf(binary.Destination)
+		if err != nil && !os.IsNotExist(err) {
+			v.Logger.Error(err, "failed to get digest of cni binary", "path", binary.Destination)
+			continue
+		}
+		if actual == expected {
+			continue
+		}
+
+		v.Logger.Info("cni binary does not match the one inside image", "path", binary.Destination,
+			"expected", expected, "actual", actual)
+		v.Recorder.Eventf(nodeRef, corev1.EventTypeWarning, ReasonCNIBinaryTampered,
+			"cni binary %s does not match the one inside image, expected sha256 %s but got %q",
+			binary.Destination, expected, actual)
+
+		if err = install(binary.Source, binary.Destination); err != nil {
+			v.Logger.Error(err, "failed to re-install cni binary", "path", binary.Destination)
+			v.Recorder.Eventf(nodeRef, corev1.EventTypeWarning, ReasonCNIBinaryReinstallFailed,
+				"failed to re-install cni binary %s: %v", binary.Destination, err)
+			continue
+		}
+
+		v.Recorder.Eventf(nodeRef, corev1.EventTypeNormal, ReasonCNIBinaryReinstalled,
+			"cni binary %s is re-installed", binary.Destination)
+	}
+}
+
+// Mismatches returns the destinations of binaries which do not match their sources inside image,
+// including the ones missing on host, without re-installing them
+func Mismatches(binaries []Binary) ([]string, error) {
+	var mismatches []string
+	for _, binary := range binaries {
+		expected, err := digestOf(binary.Source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get digest of source cni binary %v: %v", binary.Source, err)
+		}
+
+		actual, err := digestOf(binary.Destination)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to get digest of cni binary %v: %v", binary.Destination, err)
+		}
+		if actual != expected {
+			mismatches = append(mismatches, binary.Destination)
+		}
+	}
+	return mismatches, nil
+}
+
+func digestOf(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// install replaces dst by renaming, so that a running binary is never partially overwritten
+func install(src, dst string) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err = io.Copy(tmpFile, srcFile); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err = tmpFile.Chmod(0755); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err = tmpFile.Sync(); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err = tmpFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), dst)
+}
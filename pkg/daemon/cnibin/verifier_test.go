@@ -0,0 +1,103 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cnibin
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestVerify(t *testing.T) {
+	srcDir, dstDir := t.TempDir(), t.TempDir()
+	binaries := []Binary{
+		{Source: filepath.Join(srcDir, "hybridnet"), Destination: filepath.Join(dstDir, "hybridnet")},
+		{Source: filepath.Join(srcDir, "loopback"), Destination: filepath.Join(dstDir, "loopback")},
+	}
+
+	for _, binary := range binaries {
+		if err := os.WriteFile(binary.Source, []byte("binary of "+binary.Source), 0755); err != nil {
+			t.Fatalf("unable to write source: %v", err)
+		}
+		if err := install(binary.Source, binary.Destination); err != nil {
+			t.Fatalf("unable to install: %v", err)
+		}
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	v := &Verifier{
+		Binaries:        binaries,
+		NodeName:        "node1",
+		Recorder:        recorder,
+		Logger:          logr.Discard(),
+		expectedDigests: map[string]string{},
+	}
+	for _, binary := range binaries {
+		digest, err := digestOf(binary.Source)
+		if err != nil {
+			t.Fatalf("unable to get digest: %v", err)
+		}
+		v.expectedDigests[binary.Source] = digest
+	}
+
+	v.verify()
+	if len(recorder.Events) != 0 {
+		t.Fatalf("expect no event for intact binaries but got %v", <-recorder.Events)
+	}
+	if mismatches, err := Mismatches(binaries); err != nil || len(mismatches) != 0 {
+		t.Fatalf("expect no mismatch for intact binaries but got %v: %v", mismatches, err)
+	}
+
+	// tamper one binary and remove the other
+	if err := os.WriteFile(binaries[0].Destination, []byte("malicious"), 0755); err != nil {
+		t.Fatalf("unable to tamper binary: %v", err)
+	}
+	if err := os.Remove(binaries[1].Destination); err != nil {
+		t.Fatalf("unable to remove binary: %v", err)
+	}
+
+	mismatches, err := Mismatches(binaries)
+	if err != nil {
+		t.Fatalf("unable to check mismatches: %v", err)
+	}
+	if expect := []string{binaries[0].Destination, binaries[1].Destination}; !reflect.DeepEqual(mismatches, expect) {
+		t.Errorf("expect mismatches %v but got %v", expect, mismatches)
+	}
+
+	v.verify()
+	for _, binary := range binaries {
+		if event := <-recorder.Events; !strings.Contains(event, ReasonCNIBinaryTampered) {
+			t.Errorf("expect tampered event but got %s", event)
+		}
+		if event := <-recorder.Events; !strings.Contains(event, ReasonCNIBinaryReinstalled) {
+			t.Errorf("expect reinstalled event but got %s", event)
+		}
+
+		actual, err := digestOf(binary.Destination)
+		if err != nil || actual != v.expectedDigests[binary.Source] {
+			t.Errorf("binary %s is not re-installed: %v", binary.Destination, err)
+		}
+		if info, err := os.Stat(binary.Destination); err != nil || info.Mode().Perm() != 0755 {
+			t.Errorf("unexpected mode of re-installed binary %s: %v", binary.Destination, err)
+		}
+	}
+}
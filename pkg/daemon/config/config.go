@@ -17,15 +17,18 @@
 package config
 
 import (
-	"flag"
 	"fmt"
 	"net"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
 	daemonutils "github.com/alibaba/hybridnet/pkg/daemon/utils"
 	"github.com/alibaba/hybridnet/pkg/utils"
+	zapinit "github.com/alibaba/hybridnet/pkg/zap"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
@@ -39,6 +42,10 @@ const (
 
 	DefaultVxlanUDPPort = 8472
 
+	// VXLAN uses a 50-byte header over ipv4 and a 70-byte header over ipv6
+	vxlanOverheadIPv4 = 50
+	vxlanOverheadIPv6 = 70
+
 	DefaultVlanCheckTimeout                     = 3 * time.Second
 	DefaultIPtablesCheckDuration                = 5 * time.Second
 	DefaultVxlanBaseReachableTime               = 5 * time.Second
@@ -54,8 +61,40 @@ const (
 
 	DefaultIPv6RouteCacheMaxSize  = 524288
 	DefaultIPv6RouteCacheGCThresh = 65536
+
+	DefaultCNIServerAllowedUIDs = "0"
+	// CNIServerAnyBinary allows local processes of any executable to call cni server
+	CNIServerAnyBinary = "*"
+
+	DefaultCNIServerAddQPS              = 10
+	DefaultCNIServerAddBurst            = 20
+	DefaultCNIServerMaxInflightRequests = 20
+	DefaultCNIServerMaxQueuedRequests   = 100
+	DefaultCNIServerQueueTimeout        = 30 * time.Second
+
+	DefaultLogLevel = "info"
+
+	DefaultIPLeaseDuration = 5 * time.Minute
+
+	DefaultDryDataplaneIfName = "eth0"
+	DefaultDryDataplaneMTU    = 1500
 )
 
+const zapLogLevelFlag = "zap-log-level"
+
+// verbs of cni server which are authorized separately
+const (
+	CNIServerVerbAdd = "add"
+	CNIServerVerbDel = "del"
+)
+
+// CNIServerPeerRule limits the local processes which are allowed to call a verb of cni server,
+// an empty list means no limitation on that field
+type CNIServerPeerRule struct {
+	UIDs     []uint32
+	Binaries []string
+}
+
 // Configuration is the daemon conf
 type Configuration struct {
 	BindSocket string
@@ -102,109 +141,328 @@ type Configuration struct {
 
 	EnableVlanArpEnhancement     bool
 	PatchCalicoPodIPsAnnotation  bool
+	PatchNetworkStatusAnnotation bool
 	CheckPodConnectivityFromHost bool
 	UpdateIPInstanceStatus       bool
+
+	// Peer credentials of cni server requests are checked against rules of the verb
+	CNIServerPeerRules map[string]CNIServerPeerRule
+
+	// Rate of add requests and concurrency of all requests handled by cni server, zero means unlimited
+	CNIServerAddQPS              float64
+	CNIServerAddBurst            int
+	CNIServerMaxInflightRequests int
+	CNIServerMaxQueuedRequests   int
+	CNIServerQueueTimeout        time.Duration
+
+	// Controllers run against an in-memory fake dataplane and only log intended operations if
+	// DryDataplane is true, host network is never changed
+	DryDataplane bool
+
+	// State of daemon and host dataplane is served on metrics server for support bundles if
+	// EnableStateDump is true
+	EnableStateDump bool
+
+	// cni binaries installed in CNIBinDir are verified periodically if CNIBinIntegrityCheckInterval is not zero
+	CNIBinDir                    string
+	CommunityCNIPlugins          []string
+	CNIBinIntegrityCheckInterval time.Duration
+
+	// leases of ip instances bound to this node are renewed to IPLeaseDuration later periodically,
+	// which only works if IPInstanceLease feature is enabled
+	IPLeaseDuration time.Duration
+
+	// NodeNetworkConfigRevision is the revision of NodeNetworkConfig applied on start, it's empty if
+	// no config applies
+	NodeNetworkConfigRevision string
+	// NodeNetworkConfigSpec is the spec of NodeNetworkConfigRevision, which is reported with it
+	NodeNetworkConfigSpec *networkingv1.NodeNetworkConfigSpec
+
+	// Versioned config file which overrides defaults of flags, safe fields of it are reloaded at runtime
+	ConfigFile string
+	// LogLevel is empty if log level is neither set on command line nor in config file
+	LogLevel string
+	// Configuration is only validated if ValidateOnly is true
+	ValidateOnly bool
+
+	// flags set explicitly on command line, which take precedence over config file
+	commandLineFlags map[string]bool
+	fileConfig       *FileConfiguration
 }
 
-// ParseFlags will parse cmd args then init kubeClient and configuration
-func ParseFlags() (*Configuration, error) {
+// AddFlags registers flags of daemon on flagSet, and returns the function which inits configuration
+// after flagSet is parsed, interfaces and mtu are not resolved until InitNodeNetworkConfig is called
+func AddFlags(flagSet *pflag.FlagSet) func() (*Configuration, error) {
 	var (
-		argPreferInterfaces                     = pflag.String("prefer-interfaces", "", "[deprecated]The preferred vlan interfaces used to inter-host pod communication, default: the default route interface")
-		argPreferVlanInterfaces                 = pflag.String("prefer-vlan-interfaces", "", "The preferred vlan interfaces used to inter-host pod communication, default: the default route interface")
-		argPreferVxlanInterfaces                = pflag.String("prefer-vxlan-interfaces", "", "The preferred vxlan interfaces used to inter-host pod communication, default: the default route interface")
-		argPreferBGPInterfaces                  = pflag.String("prefer-bgp-interfaces", "", "The preferred bgp interfaces used to inter-host pod communication, default: the default route interface")
-		argBindSocket                           = pflag.String("bind-socket", "/var/run/hybridnet.sock", "The socket daemon bind to.")
-		argHealthyServerAddress                 = pflag.String("health-probe-addr", DefaultHealthyServerBindAddress, "The address which daemon healthy server bind")
-		argMetricsServerAddress                 = pflag.String("metrics-addr", DefaultMetricsServerBindAddress, "The address which daemon metrics server bind")
-		argBGPgRPCServerAddress                 = pflag.String("bgp-grpc-server-addr", DefaultBGPgRPCServerBindAddress, "The address which daemon bgp grpc server bind, for using gobgp command to debug")
-		argLocalDirectTableNum                  = pflag.Int("local-direct-table", DefaultLocalDirectTableNum, "The number of local-pod-direct route table")
-		argIPtablesCheckDuration                = pflag.Duration("iptables-check-duration", DefaultIPtablesCheckDuration, "The time period for iptables manager to check iptables rules")
-		argToOverlaySubnetTableNum              = pflag.Int("to-overlay-table", DefaultToOverlaySubnetTableNum, "The number of to-overlay-pod-subnet route table")
-		argOverlayMarkTableNum                  = pflag.Int("overlay-mark-table", DefaultOverlayMarkTableNum, "The number of overlay-mark routing table")
-		argVlanCheckTimeout                     = pflag.Duration("vlan-check-timeout", DefaultVlanCheckTimeout, "The timeout of vlan network environment check while pod creating")
-		argVxlanUDPPort                         = pflag.Int("vxlan-udp-port", DefaultVxlanUDPPort, "The local udp port which vxlan tunnel use")
-		argVxlanBaseReachableTime               = pflag.Duration("vxlan-base-reachable-time", DefaultVxlanBaseReachableTime, "The time for neigh caches of vxlan device to get STALE from REACHABLE")
-		argVxlanExpiredNeighCachesClearInterval = pflag.Duration("vxlan-expired-neigh-caches-clear-interval", DefaultVxlanExpiredNeighCachesClearInterval, "The interval for daemon to clear STALE and FAILED neigh caches of vxlan device")
-		argVtepAddressCIDRs                     = pflag.String("vtep-address-cidrs", "0.0.0.0/0,::/0", "The cidr list to select vtep address on each node, e.g., \\\"192.168.10.0/24,10.2.3.0/24\\\"\"")
-		argNeighGCThresh1                       = pflag.Int("neigh-gc-thresh1", DefaultNeighGCThresh1, "Value to set net.ipv4/ipv6.neigh.default.gc_thresh1")
-		argNeighGCThresh2                       = pflag.Int("neigh-gc-thresh2", DefaultNeighGCThresh2, "Value to set net.ipv4/ipv6.neigh.default.gc_thresh2")
-		argNeighGCThresh3                       = pflag.Int("neigh-gc-thresh3", DefaultNeighGCThresh3, "Value to set net.ipv4/ipv6.neigh.default.gc_thresh3")
-		argExtraNodeLocalVxlanIPCidrs           = pflag.String("extra-node-local-vxlan-ip-cidrs", "", "The cidr list to select node extra local vxlan ip, e.g., \"192.168.10.0/24,10.2.3.0/24\"")
-		argEnableVlanArpEnhancement             = pflag.Bool("enable-vlan-arp-enhancement", true, "Whether enable arp source enhancement in a vlan environment")
-		argIPv6RouteCacheMaxSize                = pflag.Int("ipv6-route-cache-max-size", DefaultIPv6RouteCacheMaxSize, "Value to set net.ipv6.route.max_size")
-		argIPv6RouteCacheGCThresh               = pflag.Int("ipv6-route-cache-gc-thresh", DefaultIPv6RouteCacheGCThresh, "Value to set net.ipv6.route.gc_thresh")
-		argPatchCalicoPodIPsAnnotation          = pflag.Bool("patch-calico-pod-ips-annotation", true, "Patch \"cni.projectcalico.org/podIPs\" annotations to pod")
-		argCheckPodConnectivityFromHost         = pflag.Bool("check-pod-connectivity-from-host", true, "Check pod's connectivity from host before start it")
-		argUpdateIPInstanceStatus               = pflag.Bool("update-ipinstance-status", true, "Update ipinstance status while creating pod sandbox")
+		argPreferInterfaces                     = flagSet.String("prefer-interfaces", "", "[deprecated]The preferred vlan interfaces used to inter-host pod communication, default: the default route interface")
+		argPreferVlanInterfaces                 = flagSet.String("prefer-vlan-interfaces", "", "The preferred vlan interfaces used to inter-host pod communication, default: the default route interface")
+		argPreferVxlanInterfaces                = flagSet.String("prefer-vxlan-interfaces", "", "The preferred vxlan interfaces used to inter-host pod communication, default: the default route interface")
+		argPreferBGPInterfaces                  = flagSet.String("prefer-bgp-interfaces", "", "The preferred bgp interfaces used to inter-host pod communication, default: the default route interface")
+		argBindSocket                           = flagSet.String("bind-socket", "/var/run/hybridnet.sock", "The socket daemon bind to.")
+		argHealthyServerAddress                 = flagSet.String("health-probe-addr", DefaultHealthyServerBindAddress, "The address which daemon healthy server bind")
+		argMetricsServerAddress                 = flagSet.String("metrics-addr", DefaultMetricsServerBindAddress, "The address which daemon metrics server bind")
+		argBGPgRPCServerAddress                 = flagSet.String("bgp-grpc-server-addr", DefaultBGPgRPCServerBindAddress, "The address which daemon bgp grpc server bind, for using gobgp command to debug")
+		argLocalDirectTableNum                  = flagSet.Int("local-direct-table", DefaultLocalDirectTableNum, "The number of local-pod-direct route table")
+		argIPtablesCheckDuration                = flagSet.Duration("iptables-check-duration", DefaultIPtablesCheckDuration, "The time period for iptables manager to check iptables rules")
+		argToOverlaySubnetTableNum              = flagSet.Int("to-overlay-table", DefaultToOverlaySubnetTableNum, "The number of to-overlay-pod-subnet route table")
+		argOverlayMarkTableNum                  = flagSet.Int("overlay-mark-table", DefaultOverlayMarkTableNum, "The number of overlay-mark routing table")
+		argVlanCheckTimeout                     = flagSet.Duration("vlan-check-timeout", DefaultVlanCheckTimeout, "The timeout of vlan network environment check while pod creating")
+		argVxlanUDPPort                         = flagSet.Int("vxlan-udp-port", DefaultVxlanUDPPort, "The local udp port which vxlan tunnel use")
+		argVxlanBaseReachableTime               = flagSet.Duration("vxlan-base-reachable-time", DefaultVxlanBaseReachableTime, "The time for neigh caches of vxlan device to get STALE from REACHABLE")
+		argVxlanExpiredNeighCachesClearInterval = flagSet.Duration("vxlan-expired-neigh-caches-clear-interval", DefaultVxlanExpiredNeighCachesClearInterval, "The interval for daemon to clear STALE and FAILED neigh caches, and neigh caches of unknown addresses of vxlan device")
+		argVtepAddressCIDRs                     = flagSet.String("vtep-address-cidrs", "0.0.0.0/0,::/0", "The cidr list to select vtep address on each node, e.g., \\\"192.168.10.0/24,10.2.3.0/24\\\"\"")
+		argNeighGCThresh1                       = flagSet.Int("neigh-gc-thresh1", DefaultNeighGCThresh1, "Value to set net.ipv4/ipv6.neigh.default.gc_thresh1")
+		argNeighGCThresh2                       = flagSet.Int("neigh-gc-thresh2", DefaultNeighGCThresh2, "Value to set net.ipv4/ipv6.neigh.default.gc_thresh2")
+		argNeighGCThresh3                       = flagSet.Int("neigh-gc-thresh3", DefaultNeighGCThresh3, "Value to set net.ipv4/ipv6.neigh.default.gc_thresh3")
+		argExtraNodeLocalVxlanIPCidrs           = flagSet.String("extra-node-local-vxlan-ip-cidrs", "", "The cidr list to select node extra local vxlan ip, e.g., \"192.168.10.0/24,10.2.3.0/24\"")
+		argEnableVlanArpEnhancement             = flagSet.Bool("enable-vlan-arp-enhancement", true, "Whether enable arp source enhancement in a vlan environment")
+		argIPv6RouteCacheMaxSize                = flagSet.Int("ipv6-route-cache-max-size", DefaultIPv6RouteCacheMaxSize, "Value to set net.ipv6.route.max_size")
+		argIPv6RouteCacheGCThresh               = flagSet.Int("ipv6-route-cache-gc-thresh", DefaultIPv6RouteCacheGCThresh, "Value to set net.ipv6.route.gc_thresh")
+		argPatchCalicoPodIPsAnnotation          = flagSet.Bool("patch-calico-pod-ips-annotation", true, "Patch \"cni.projectcalico.org/podIPs\" annotations to pod")
+		argPatchNetworkStatusAnnotation         = flagSet.Bool("patch-network-status-annotation", false, "Patch \"k8s.v1.cni.cncf.io/network-status\" annotations to pod after its network is created")
+		argCheckPodConnectivityFromHost         = flagSet.Bool("check-pod-connectivity-from-host", true, "Check pod's connectivity from host before start it")
+		argUpdateIPInstanceStatus               = flagSet.Bool("update-ipinstance-status", true, "Update ipinstance status while creating pod sandbox")
+		argCNIServerAllowedUIDs                 = flagSet.String("cni-server-allowed-uids", DefaultCNIServerAllowedUIDs, "The uid list of local processes allowed to call cni server, e.g., \"0,1000\", empty means any uid")
+		argCNIServerAllowedBinaries             = flagSet.String("cni-server-allowed-binaries", "", "The executable list of local processes allowed to call cni server, e.g., \"/opt/cni/bin/hybridnet\", executables are matched by device and inode, empty means the hybridnet cni binary in cni-bin-dir, \"*\" means any executable")
+		argCNIServerVerbAllowedUIDs             = flagSet.String("cni-server-verb-allowed-uids", "", "The uid lists overriding cni-server-allowed-uids for specified verbs, e.g., \"add=0/1000,del=0\"")
+		argCNIBinDir                            = flagSet.String("cni-bin-dir", "/opt/cni/bin", "The directory of host which cni binaries are installed into")
+		argCommunityCNIPlugins                  = flagSet.String("community-cni-plugins", "loopback", "The community cni plugins installed into cni-bin-dir, e.g., \"loopback,bandwidth\"")
+		argCNIBinIntegrityCheckInterval         = flagSet.Duration("cni-bin-integrity-check-interval", 0, "The interval to verify cni binaries installed on host against the ones inside image and re-install the tampered ones, 0 means disabled")
+		argIPLeaseDuration                      = flagSet.Duration("ip-lease-duration", DefaultIPLeaseDuration, "The duration of leases of ip instances renewed by daemon, leases are renewed in the last quarter of it, only works with IPInstanceLease feature")
+		argCNIServerAddQPS                      = flagSet.Float64("cni-server-add-qps", DefaultCNIServerAddQPS, "The qps of add requests handled by cni server, requests beyond are rejected as retryable, 0 means unlimited")
+		argCNIServerAddBurst                    = flagSet.Int("cni-server-add-burst", DefaultCNIServerAddBurst, "The burst of add requests handled by cni server")
+		argCNIServerMaxInflightRequests         = flagSet.Int("cni-server-max-inflight-requests", DefaultCNIServerMaxInflightRequests, "The max number of requests handled by cni server concurrently, 0 means unlimited")
+		argCNIServerMaxQueuedRequests           = flagSet.Int("cni-server-max-queued-requests", DefaultCNIServerMaxQueuedRequests, "The max number of requests waiting for being handled by cni server, requests beyond are rejected as retryable")
+		argCNIServerQueueTimeout                = flagSet.Duration("cni-server-queue-timeout", DefaultCNIServerQueueTimeout, "The max duration of requests waiting for being handled by cni server")
+		argDryDataplane                         = flagSet.Bool("dry-dataplane", false, "Run controllers against a fake dataplane which only logs intended operations without changing host network, for development")
+		argEnableStateDump                      = flagSet.Bool("enable-state-dump", false, "Serve the state of daemon and host dataplane on \"/debug/state\" of metrics server, for collecting support bundles")
+		argConfigFile                           = flagSet.String("config", "", "The path of versioned config file, whose fields override defaults of flags while flags set on command line take precedence")
+		argValidateConfig                       = flagSet.Bool("validate-config", false, "Validate flags and config file then exit without running daemon")
 	)
 
-	// mute info log for ipset lib
-	logrus.SetLevel(logrus.WarnLevel)
-
-	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
-	pflag.Parse()
-
-	nodeName := os.Getenv("KUBE_NODE_NAME")
-	if nodeName == "" {
-		return nil, fmt.Errorf("env KUBE_NODE_NAME not exists")
-	}
-
-	config := &Configuration{
-		BindSocket:                           *argBindSocket,
-		NodeName:                             nodeName,
-		NodeVlanIfName:                       *argPreferVlanInterfaces,
-		NodeVxlanIfName:                      *argPreferVxlanInterfaces,
-		NodeBGPIfName:                        *argPreferBGPInterfaces,
-		HealthyServerAddress:                 *argHealthyServerAddress,
-		MetricsServerAddress:                 *argMetricsServerAddress,
-		BGPgRPCServerAddress:                 *argBGPgRPCServerAddress,
-		LocalDirectTableNum:                  *argLocalDirectTableNum,
-		ToOverlaySubnetTableNum:              *argToOverlaySubnetTableNum,
-		OverlayMarkTableNum:                  *argOverlayMarkTableNum,
-		VlanCheckTimeout:                     *argVlanCheckTimeout,
-		VxlanUDPPort:                         *argVxlanUDPPort,
-		IptablesCheckDuration:                *argIPtablesCheckDuration,
-		VxlanBaseReachableTime:               *argVxlanBaseReachableTime,
-		NeighGCThresh1:                       *argNeighGCThresh1,
-		NeighGCThresh2:                       *argNeighGCThresh2,
-		NeighGCThresh3:                       *argNeighGCThresh3,
-		VxlanExpiredNeighCachesClearInterval: *argVxlanExpiredNeighCachesClearInterval,
-		EnableVlanArpEnhancement:             *argEnableVlanArpEnhancement,
-		IPv6RouteCacheMaxSize:                *argIPv6RouteCacheMaxSize,
-		IPv6RouteCacheGCThresh:               *argIPv6RouteCacheGCThresh,
-		PatchCalicoPodIPsAnnotation:          *argPatchCalicoPodIPsAnnotation,
-		CheckPodConnectivityFromHost:         *argCheckPodConnectivityFromHost,
-		UpdateIPInstanceStatus:               *argUpdateIPInstanceStatus,
-	}
-
-	if *argPreferVlanInterfaces == "" {
-		config.NodeVlanIfName = *argPreferInterfaces
-	}
-
-	if *argExtraNodeLocalVxlanIPCidrs != "" {
+	return func() (*Configuration, error) {
+		// mute info log for ipset lib
+		logrus.SetLevel(logrus.WarnLevel)
+
+		commandLineFlags := map[string]bool{}
+		flagSet.Visit(func(f *pflag.Flag) {
+			commandLineFlags[f.Name] = true
+		})
+
+		var fileConfig *FileConfiguration
+		if *argConfigFile != "" {
+			var err error
+			if fileConfig, err = LoadFileConfiguration(*argConfigFile); err != nil {
+				return nil, err
+			}
+			if err = fileConfig.ApplyToFlags(flagSet); err != nil {
+				return nil, fmt.Errorf("invalid config file %v: %v", *argConfigFile, err)
+			}
+		}
+
+		config := &Configuration{
+			BindSocket:                           *argBindSocket,
+			NodeVlanIfName:                       *argPreferVlanInterfaces,
+			NodeVxlanIfName:                      *argPreferVxlanInterfaces,
+			NodeBGPIfName:                        *argPreferBGPInterfaces,
+			HealthyServerAddress:                 *argHealthyServerAddress,
+			MetricsServerAddress:                 *argMetricsServerAddress,
+			BGPgRPCServerAddress:                 *argBGPgRPCServerAddress,
+			LocalDirectTableNum:                  *argLocalDirectTableNum,
+			ToOverlaySubnetTableNum:              *argToOverlaySubnetTableNum,
+			OverlayMarkTableNum:                  *argOverlayMarkTableNum,
+			VlanCheckTimeout:                     *argVlanCheckTimeout,
+			VxlanUDPPort:                         *argVxlanUDPPort,
+			IptablesCheckDuration:                *argIPtablesCheckDuration,
+			VxlanBaseReachableTime:               *argVxlanBaseReachableTime,
+			NeighGCThresh1:                       *argNeighGCThresh1,
+			NeighGCThresh2:                       *argNeighGCThresh2,
+			NeighGCThresh3:                       *argNeighGCThresh3,
+			VxlanExpiredNeighCachesClearInterval: *argVxlanExpiredNeighCachesClearInterval,
+			EnableVlanArpEnhancement:             *argEnableVlanArpEnhancement,
+			IPv6RouteCacheMaxSize:                *argIPv6RouteCacheMaxSize,
+			IPv6RouteCacheGCThresh:               *argIPv6RouteCacheGCThresh,
+			PatchCalicoPodIPsAnnotation:          *argPatchCalicoPodIPsAnnotation,
+			PatchNetworkStatusAnnotation:         *argPatchNetworkStatusAnnotation,
+			CheckPodConnectivityFromHost:         *argCheckPodConnectivityFromHost,
+			UpdateIPInstanceStatus:               *argUpdateIPInstanceStatus,
+			CNIServerAddQPS:                      *argCNIServerAddQPS,
+			CNIServerAddBurst:                    *argCNIServerAddBurst,
+			CNIServerMaxInflightRequests:         *argCNIServerMaxInflightRequests,
+			CNIServerMaxQueuedRequests:           *argCNIServerMaxQueuedRequests,
+			CNIServerQueueTimeout:                *argCNIServerQueueTimeout,
+			DryDataplane:                         *argDryDataplane,
+			EnableStateDump:                      *argEnableStateDump,
+			CNIBinDir:                            *argCNIBinDir,
+			CNIBinIntegrityCheckInterval:         *argCNIBinIntegrityCheckInterval,
+			IPLeaseDuration:                      *argIPLeaseDuration,
+			ConfigFile:                           *argConfigFile,
+			ValidateOnly:                         *argValidateConfig,
+			commandLineFlags:                     commandLineFlags,
+			fileConfig:                           fileConfig,
+		}
+
+		if flagSet.Changed(zapLogLevelFlag) {
+			config.LogLevel = flagSet.Lookup(zapLogLevelFlag).Value.String()
+		}
+
+		if *argCommunityCNIPlugins != "" {
+			config.CommunityCNIPlugins = strings.Split(*argCommunityCNIPlugins, ",")
+		}
+
+		if *argPreferVlanInterfaces == "" {
+			config.NodeVlanIfName = *argPreferInterfaces
+		}
+
+		if *argExtraNodeLocalVxlanIPCidrs != "" {
+			var err error
+			config.ExtraNodeLocalVxlanIPCidrs, err = parseCidrString(*argExtraNodeLocalVxlanIPCidrs)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse extra node local vxlan ip cidrs: %v", err)
+			}
+		}
+
+		if *argVtepAddressCIDRs != "" {
+			var err error
+			config.VtepAddressCIDRs, err = parseCidrString(*argVtepAddressCIDRs)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse vtep address cidrs: %v", err)
+			}
+		}
+
 		var err error
-		config.ExtraNodeLocalVxlanIPCidrs, err = parseCidrString(*argExtraNodeLocalVxlanIPCidrs)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse extra node local vxlan ip cidrs: %v", err)
+		if config.CNIServerPeerRules, err = parseCNIServerPeerRules(*argCNIServerAllowedUIDs,
+			*argCNIServerAllowedBinaries, *argCNIServerVerbAllowedUIDs, *argCNIBinDir); err != nil {
+			return nil, fmt.Errorf("failed to parse cni server peer rules: %v", err)
+		}
+
+		if err = config.validate(); err != nil {
+			return nil, fmt.Errorf("invalid config: %v", err)
 		}
+
+		if config.ValidateOnly {
+			return config, nil
+		}
+
+		if config.NodeName = os.Getenv("KUBE_NODE_NAME"); config.NodeName == "" {
+			return nil, fmt.Errorf("env KUBE_NODE_NAME not exists")
+		}
+
+		return config, nil
 	}
+}
 
-	if *argVtepAddressCIDRs != "" {
-		var err error
-		config.VtepAddressCIDRs, err = parseCidrString(*argVtepAddressCIDRs)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse vtep address cidrs: %v", err)
+func (config *Configuration) validate() error {
+	if err := validateReloadable(&ReloadableConfiguration{
+		LogLevel:                             config.LogLevel,
+		IptablesCheckDuration:                config.IptablesCheckDuration,
+		VxlanExpiredNeighCachesClearInterval: config.VxlanExpiredNeighCachesClearInterval,
+	}); err != nil {
+		return err
+	}
+
+	if config.VlanCheckTimeout <= 0 {
+		return fmt.Errorf("vlan check timeout must be positive")
+	}
+	if config.VxlanBaseReachableTime <= 0 {
+		return fmt.Errorf("vxlan base reachable time must be positive")
+	}
+	if config.VxlanUDPPort <= 0 || config.VxlanUDPPort > 65535 {
+		return fmt.Errorf("invalid vxlan udp port %v", config.VxlanUDPPort)
+	}
+
+	tableNums := map[int]bool{}
+	for _, tableNum := range []int{config.LocalDirectTableNum, config.ToOverlaySubnetTableNum, config.OverlayMarkTableNum} {
+		// 253, 254 and 255 are reserved for default, main and local tables
+		if tableNum <= 0 || tableNum >= 253 && tableNum <= 255 {
+			return fmt.Errorf("invalid route table number %v", tableNum)
+		}
+		if tableNums[tableNum] {
+			return fmt.Errorf("route table number %v is used more than once", tableNum)
 		}
+		tableNums[tableNum] = true
+	}
+
+	if config.NeighGCThresh1 < 0 || config.NeighGCThresh1 > config.NeighGCThresh2 || config.NeighGCThresh2 > config.NeighGCThresh3 {
+		return fmt.Errorf("neigh gc thresholds must satisfy 0 <= thresh1 <= thresh2 <= thresh3")
+	}
+	if config.IPv6RouteCacheMaxSize < 0 || config.IPv6RouteCacheGCThresh < 0 {
+		return fmt.Errorf("ipv6 route cache max size and gc thresh must not be negative")
+	}
+
+	if config.CNIServerAddQPS < 0 || config.CNIServerAddBurst < 0 || config.CNIServerMaxInflightRequests < 0 ||
+		config.CNIServerMaxQueuedRequests < 0 || config.CNIServerQueueTimeout < 0 {
+		return fmt.Errorf("rate and concurrency limits of cni server must not be negative")
+	}
+
+	if config.CNIBinIntegrityCheckInterval < 0 {
+		return fmt.Errorf("cni binary integrity check interval must not be negative")
+	}
+
+	if config.IPLeaseDuration <= 0 {
+		return fmt.Errorf("ip lease duration must be positive")
+	}
+
+	return nil
+}
+
+// reloadableFrom merges reloadable fields of a new config file, flags set on command line
+// still take precedence and fields removed from config file fall back to defaults
+func (config *Configuration) reloadableFrom(fileConfig *FileConfiguration) (*ReloadableConfiguration, error) {
+	reloadable := &ReloadableConfiguration{
+		LogLevel:                             DefaultLogLevel,
+		IptablesCheckDuration:                DefaultIPtablesCheckDuration,
+		VxlanExpiredNeighCachesClearInterval: DefaultVxlanExpiredNeighCachesClearInterval,
+	}
+
+	switch {
+	case config.commandLineFlags[zapLogLevelFlag]:
+		reloadable.LogLevel = config.LogLevel
+	case fileConfig.LogLevel != nil:
+		reloadable.LogLevel = *fileConfig.LogLevel
+	}
+
+	switch {
+	case config.commandLineFlags["iptables-check-duration"]:
+		reloadable.IptablesCheckDuration = config.IptablesCheckDuration
+	case fileConfig.IptablesCheckDuration != nil:
+		reloadable.IptablesCheckDuration = fileConfig.IptablesCheckDuration.Duration
+	}
+
+	switch {
+	case config.commandLineFlags["vxlan-expired-neigh-caches-clear-interval"]:
+		reloadable.VxlanExpiredNeighCachesClearInterval = config.VxlanExpiredNeighCachesClearInterval
+	case fileConfig.VxlanExpiredNeighCachesClearInterval != nil:
+		reloadable.VxlanExpiredNeighCachesClearInterval = fileConfig.VxlanExpiredNeighCachesClearInterval.Duration
 	}
 
-	if err := config.initNicConfig(); err != nil {
+	if err := validateReloadable(reloadable); err != nil {
 		return nil, err
 	}
+	return reloadable, nil
+}
 
-	return config, nil
+func validateReloadable(reloadable *ReloadableConfiguration) error {
+	if reloadable.LogLevel != "" {
+		if err := zapinit.ValidateLevel(reloadable.LogLevel); err != nil {
+			return err
+		}
+	}
+	if reloadable.IptablesCheckDuration <= 0 {
+		return fmt.Errorf("iptables check duration must be positive")
+	}
+	if reloadable.VxlanExpiredNeighCachesClearInterval <= 0 {
+		return fmt.Errorf("vxlan expired neigh caches clear interval must be positive")
+	}
+	return nil
 }
 
 func (config *Configuration) initNicConfig() error {
+	if config.DryDataplane {
+		config.initDryNicConfig()
+		return nil
+	}
+
 	defaultGatewayIf, err := daemonutils.GetDefaultInterface(netlink.FAMILY_V4)
 	if err != nil && err != daemonutils.NotExist {
 		return fmt.Errorf("failed to get ipv4 default gateway interface: %v", err)
@@ -220,7 +478,7 @@ func (config *Configuration) initNicConfig() error {
 		return fmt.Errorf("both ipv4 and ipv6 default gateway not found")
 	}
 
-	// if vlan/vxlan interface name is not provided, get the ipv4 default gateway interface
+	// if vlan/vxlan interface name is not provided, get the default gateway interface, ipv4 first
 	config.NodeVlanIfName = utils.PickFirstNonEmptyString(config.NodeVlanIfName, defaultGatewayIf.Name)
 	config.NodeVxlanIfName = utils.PickFirstNonEmptyString(config.NodeVxlanIfName, defaultGatewayIf.Name)
 	config.NodeBGPIfName = utils.PickFirstNonEmptyString(config.NodeBGPIfName, defaultGatewayIf.Name)
@@ -254,14 +512,58 @@ func (config *Configuration) initNicConfig() error {
 		config.BGPMTU = bgpNodeInterface.MTU
 	}
 
-	// VXLAN uses a 50-byte header
-	if config.VxlanMTU == 0 || config.VxlanMTU > vxlanNodeInterface.MTU-50 {
-		config.VxlanMTU = vxlanNodeInterface.MTU - 50
+	vxlanOverhead, err := config.vxlanOverheadOf(vxlanNodeInterface)
+	if err != nil {
+		return err
+	}
+
+	if config.VxlanMTU == 0 || config.VxlanMTU > vxlanNodeInterface.MTU-vxlanOverhead {
+		config.VxlanMTU = vxlanNodeInterface.MTU - vxlanOverhead
 	}
 
 	return nil
 }
 
+// vxlanOverheadOf returns the header size of vxlan by the family of vtep address, which is
+// selected in the same way as that of NodeInfo
+func (config *Configuration) vxlanOverheadOf(vxlanNodeInterface *net.Interface) (int, error) {
+	link, err := netlink.LinkByIndex(vxlanNodeInterface.Index)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get vxlan node link %v: %v", vxlanNodeInterface.Name, err)
+	}
+
+	addrList, err := daemonutils.ListAllGlobalUnicastAddress(link)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list address for vxlan node link %v: %v", vxlanNodeInterface.Name, err)
+	}
+
+	return vxlanOverheadOfVtep(daemonutils.SelectVtepAddress(addrList, config.VtepAddressCIDRs)), nil
+}
+
+func vxlanOverheadOfVtep(vtepIP net.IP) int {
+	if vtepIP != nil && vtepIP.To4() == nil {
+		return vxlanOverheadIPv6
+	}
+	return vxlanOverheadIPv4
+}
+
+// initDryNicConfig never looks up host interfaces, which might not exist in a dry dataplane
+func (config *Configuration) initDryNicConfig() {
+	config.NodeVlanIfName = utils.PickFirstNonEmptyString(config.NodeVlanIfName, DefaultDryDataplaneIfName)
+	config.NodeVxlanIfName = utils.PickFirstNonEmptyString(config.NodeVxlanIfName, DefaultDryDataplaneIfName)
+	config.NodeBGPIfName = utils.PickFirstNonEmptyString(config.NodeBGPIfName, DefaultDryDataplaneIfName)
+
+	if config.VlanMTU == 0 {
+		config.VlanMTU = DefaultDryDataplaneMTU
+	}
+	if config.BGPMTU == 0 {
+		config.BGPMTU = DefaultDryDataplaneMTU
+	}
+	if config.VxlanMTU == 0 {
+		config.VxlanMTU = DefaultDryDataplaneMTU - vxlanOverheadIPv4
+	}
+}
+
 func parseCidrString(cidrListString string) ([]*net.IPNet, error) {
 	var cidrList []*net.IPNet
 	cidrStringList := strings.Split(cidrListString, ",")
@@ -276,3 +578,65 @@ func parseCidrString(cidrListString string) ([]*net.IPNet, error) {
 
 	return cidrList, nil
 }
+
+func parseCNIServerPeerRules(allowedUIDs, allowedBinaries, verbAllowedUIDs, cniBinDir string) (map[string]CNIServerPeerRule, error) {
+	defaultUIDs, err := parseUIDList(allowedUIDs, ",")
+	if err != nil {
+		return nil, err
+	}
+
+	// any root process is allowed by default uid, so only the cni binary is allowed by default
+	var binaries []string
+	switch allowedBinaries {
+	case "":
+		binaries = []string{filepath.Join(cniBinDir, "hybridnet")}
+	case CNIServerAnyBinary:
+	default:
+		binaries = strings.Split(allowedBinaries, ",")
+	}
+
+	rules := map[string]CNIServerPeerRule{
+		CNIServerVerbAdd: {UIDs: defaultUIDs, Binaries: binaries},
+		CNIServerVerbDel: {UIDs: defaultUIDs, Binaries: binaries},
+	}
+
+	if verbAllowedUIDs == "" {
+		return rules, nil
+	}
+
+	for _, verbUIDs := range strings.Split(verbAllowedUIDs, ",") {
+		verbAndUIDs := strings.SplitN(verbUIDs, "=", 2)
+		if len(verbAndUIDs) != 2 {
+			return nil, fmt.Errorf("invalid verb uids %v, should be in format of verb=uid1/uid2", verbUIDs)
+		}
+
+		rule, exist := rules[verbAndUIDs[0]]
+		if !exist {
+			return nil, fmt.Errorf("unknown verb %v of cni server", verbAndUIDs[0])
+		}
+
+		if rule.UIDs, err = parseUIDList(verbAndUIDs[1], "/"); err != nil {
+			return nil, err
+		}
+		rules[verbAndUIDs[0]] = rule
+	}
+
+	return rules, nil
+}
+
+func parseUIDList(uidListString, sep string) ([]uint32, error) {
+	var uidList []uint32
+	if uidListString == "" {
+		return uidList, nil
+	}
+
+	for _, uidString := range strings.Split(uidListString, sep) {
+		uid, err := strconv.ParseUint(uidString, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse uid %v: %v", uidString, err)
+		}
+		uidList = append(uidList, uint32(uid))
+	}
+
+	return uidList, nil
+}
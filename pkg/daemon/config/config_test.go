@@ -0,0 +1,78 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCNIServerPeerRules(t *testing.T) {
+	tests := []struct {
+		name            string
+		allowedUIDs     string
+		allowedBinaries string
+		verbAllowedUIDs string
+		expected        map[string]CNIServerPeerRule
+		expectErr       bool
+	}{
+		{
+			name:        "default binary",
+			allowedUIDs: DefaultCNIServerAllowedUIDs,
+			expected: map[string]CNIServerPeerRule{
+				CNIServerVerbAdd: {UIDs: []uint32{0}, Binaries: []string{"/opt/cni/bin/hybridnet"}},
+				CNIServerVerbDel: {UIDs: []uint32{0}, Binaries: []string{"/opt/cni/bin/hybridnet"}},
+			},
+		},
+		{
+			name:            "any binary",
+			allowedUIDs:     DefaultCNIServerAllowedUIDs,
+			allowedBinaries: CNIServerAnyBinary,
+			expected: map[string]CNIServerPeerRule{
+				CNIServerVerbAdd: {UIDs: []uint32{0}},
+				CNIServerVerbDel: {UIDs: []uint32{0}},
+			},
+		},
+		{
+			name:            "verb uids override",
+			allowedUIDs:     "0",
+			allowedBinaries: "/usr/bin/a,/usr/bin/b",
+			verbAllowedUIDs: "del=0/1000",
+			expected: map[string]CNIServerPeerRule{
+				CNIServerVerbAdd: {UIDs: []uint32{0}, Binaries: []string{"/usr/bin/a", "/usr/bin/b"}},
+				CNIServerVerbDel: {UIDs: []uint32{0, 1000}, Binaries: []string{"/usr/bin/a", "/usr/bin/b"}},
+			},
+		},
+		{
+			name:            "unknown verb",
+			allowedUIDs:     "0",
+			verbAllowedUIDs: "get=0",
+			expectErr:       true,
+		},
+	}
+
+	for _, test := range tests {
+		rules, err := parseCNIServerPeerRules(test.allowedUIDs, test.allowedBinaries, test.verbAllowedUIDs, "/opt/cni/bin")
+		if (err != nil) != test.expectErr {
+			t.Errorf("test %s fails, expected error %v but got %v", test.name, test.expectErr, err)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(rules, test.expected) {
+			t.Errorf("test %s fails, expected %v but got %v", test.name, test.expected, rules)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	FileConfigurationAPIVersion = "daemon.hybridnet.io/v1alpha1"
+	FileConfigurationKind       = "DaemonConfiguration"
+)
+
+// FileConfiguration is the versioned config file of daemon. Every field overrides the default of
+// the flag in its "flag" tag, while flags set explicitly on command line always take precedence.
+type FileConfiguration struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+
+	// LogLevel can be changed without restarting daemon
+	LogLevel *string `json:"logLevel,omitempty" flag:"zap-log-level"`
+
+	BindSocket           *string `json:"bindSocket,omitempty" flag:"bind-socket"`
+	HealthyServerAddress *string `json:"healthProbeAddress,omitempty" flag:"health-probe-addr"`
+	MetricsServerAddress *string `json:"metricsAddress,omitempty" flag:"metrics-addr"`
+	BGPgRPCServerAddress *string `json:"bgpGRPCServerAddress,omitempty" flag:"bgp-grpc-server-addr"`
+
+	PreferVlanInterfaces  *string `json:"preferVlanInterfaces,omitempty" flag:"prefer-vlan-interfaces"`
+	PreferVxlanInterfaces *string `json:"preferVxlanInterfaces,omitempty" flag:"prefer-vxlan-interfaces"`
+	PreferBGPInterfaces   *string `json:"preferBGPInterfaces,omitempty" flag:"prefer-bgp-interfaces"`
+
+	LocalDirectTableNum     *int `json:"localDirectTable,omitempty" flag:"local-direct-table"`
+	ToOverlaySubnetTableNum *int `json:"toOverlayTable,omitempty" flag:"to-overlay-table"`
+	OverlayMarkTableNum     *int `json:"overlayMarkTable,omitempty" flag:"overlay-mark-table"`
+
+	// IptablesCheckDuration can be changed without restarting daemon
+	IptablesCheckDuration *metav1.Duration `json:"iptablesCheckDuration,omitempty" flag:"iptables-check-duration"`
+	VlanCheckTimeout      *metav1.Duration `json:"vlanCheckTimeout,omitempty" flag:"vlan-check-timeout"`
+
+	VxlanUDPPort           *int             `json:"vxlanUDPPort,omitempty" flag:"vxlan-udp-port"`
+	VxlanBaseReachableTime *metav1.Duration `json:"vxlanBaseReachableTime,omitempty" flag:"vxlan-base-reachable-time"`
+	// VxlanExpiredNeighCachesClearInterval can be changed without restarting daemon
+	VxlanExpiredNeighCachesClearInterval *metav1.Duration `json:"vxlanExpiredNeighCachesClearInterval,omitempty" flag:"vxlan-expired-neigh-caches-clear-interval"`
+	VtepAddressCIDRs                     []string         `json:"vtepAddressCIDRs,omitempty" flag:"vtep-address-cidrs"`
+	ExtraNodeLocalVxlanIPCidrs           []string         `json:"extraNodeLocalVxlanIPCIDRs,omitempty" flag:"extra-node-local-vxlan-ip-cidrs"`
+
+	NeighGCThresh1         *int `json:"neighGCThresh1,omitempty" flag:"neigh-gc-thresh1"`
+	NeighGCThresh2         *int `json:"neighGCThresh2,omitempty" flag:"neigh-gc-thresh2"`
+	NeighGCThresh3         *int `json:"neighGCThresh3,omitempty" flag:"neigh-gc-thresh3"`
+	IPv6RouteCacheMaxSize  *int `json:"ipv6RouteCacheMaxSize,omitempty" flag:"ipv6-route-cache-max-size"`
+	IPv6RouteCacheGCThresh *int `json:"ipv6RouteCacheGCThresh,omitempty" flag:"ipv6-route-cache-gc-thresh"`
+
+	EnableVlanArpEnhancement     *bool `json:"enableVlanArpEnhancement,omitempty" flag:"enable-vlan-arp-enhancement"`
+	PatchCalicoPodIPsAnnotation  *bool `json:"patchCalicoPodIPsAnnotation,omitempty" flag:"patch-calico-pod-ips-annotation"`
+	PatchNetworkStatusAnnotation *bool `json:"patchNetworkStatusAnnotation,omitempty" flag:"patch-network-status-annotation"`
+	CheckPodConnectivityFromHost *bool `json:"checkPodConnectivityFromHost,omitempty" flag:"check-pod-connectivity-from-host"`
+	UpdateIPInstanceStatus       *bool `json:"updateIPInstanceStatus,omitempty" flag:"update-ipinstance-status"`
+
+	CNIServerAllowedUIDs         []string         `json:"cniServerAllowedUIDs,omitempty" flag:"cni-server-allowed-uids"`
+	CNIServerAllowedBinaries     []string         `json:"cniServerAllowedBinaries,omitempty" flag:"cni-server-allowed-binaries"`
+	CNIServerVerbAllowedUIDs     []string         `json:"cniServerVerbAllowedUIDs,omitempty" flag:"cni-server-verb-allowed-uids"`
+	CNIServerAddQPS              *float64         `json:"cniServerAddQPS,omitempty" flag:"cni-server-add-qps"`
+	CNIServerAddBurst            *int             `json:"cniServerAddBurst,omitempty" flag:"cni-server-add-burst"`
+	CNIServerMaxInflightRequests *int             `json:"cniServerMaxInflightRequests,omitempty" flag:"cni-server-max-inflight-requests"`
+	CNIServerMaxQueuedRequests   *int             `json:"cniServerMaxQueuedRequests,omitempty" flag:"cni-server-max-queued-requests"`
+	CNIServerQueueTimeout        *metav1.Duration `json:"cniServerQueueTimeout,omitempty" flag:"cni-server-queue-timeout"`
+
+	DryDataplane    *bool `json:"dryDataplane,omitempty" flag:"dry-dataplane"`
+	EnableStateDump *bool `json:"enableStateDump,omitempty" flag:"enable-state-dump"`
+
+	CNIBinDir                    *string          `json:"cniBinDir,omitempty" flag:"cni-bin-dir"`
+	CommunityCNIPlugins          []string         `json:"communityCNIPlugins,omitempty" flag:"community-cni-plugins"`
+	CNIBinIntegrityCheckInterval *metav1.Duration `json:"cniBinIntegrityCheckInterval,omitempty" flag:"cni-bin-integrity-check-interval"`
+
+	IPLeaseDuration *metav1.Duration `json:"ipLeaseDuration,omitempty" flag:"ip-lease-duration"`
+
+	FeatureGates map[string]bool `json:"featureGates,omitempty" flag:"feature-gates"`
+}
+
+// ReloadableConfiguration is the part of configuration which is applied at runtime
+// after config file changes
+type ReloadableConfiguration struct {
+	LogLevel                             string
+	IptablesCheckDuration                time.Duration
+	VxlanExpiredNeighCachesClearInterval time.Duration
+}
+
+// LoadFileConfiguration reads config file strictly, unknown or duplicated fields are rejected
+func LoadFileConfiguration(path string) (*FileConfiguration, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %v: %v", path, err)
+	}
+
+	fileConfig, err := parseFileConfiguration(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %v: %v", path, err)
+	}
+	return fileConfig, nil
+}
+
+func parseFileConfiguration(data []byte) (*FileConfiguration, error) {
+	fileConfig := &FileConfiguration{}
+	if err := yaml.UnmarshalStrict(data, fileConfig); err != nil {
+		return nil, err
+	}
+
+	if fileConfig.APIVersion != FileConfigurationAPIVersion || fileConfig.Kind != FileConfigurationKind {
+		return nil, fmt.Errorf("unsupported config %v/%v, expect %v/%v", fileConfig.APIVersion, fileConfig.Kind,
+			FileConfigurationAPIVersion, FileConfigurationKind)
+	}
+	return fileConfig, nil
+}
+
+// ApplyToFlags sets flags which are not set on command line with values of config file, so
+// that values of config file are parsed in the same way of flags
+func (f *FileConfiguration) ApplyToFlags(flagSet *pflag.FlagSet) error {
+	return f.visitFlagValues(func(name, value string) error {
+		if flagSet.Changed(name) {
+			return nil
+		}
+		if err := flagSet.Set(name, value); err != nil {
+			return fmt.Errorf("invalid value %q of flag %v: %v", value, name, err)
+		}
+		return nil
+	})
+}
+
+// withoutReloadable returns a copy without fields which can be changed at runtime, which
+// is used to find out the changes requiring restart
+func (f *FileConfiguration) withoutReloadable() *FileConfiguration {
+	copied := *f
+	copied.LogLevel = nil
+	copied.IptablesCheckDuration = nil
+	copied.VxlanExpiredNeighCachesClearInterval = nil
+	return &copied
+}
+
+// visitFlagValues calls visit with flag name and the string of value for every field set in config file
+func (f *FileConfiguration) visitFlagValues(visit func(name, value string) error) error {
+	v := reflect.ValueOf(f).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get("flag")
+		if len(name) == 0 {
+			continue
+		}
+
+		field := v.Field(i)
+		if field.IsNil() {
+			continue
+		}
+
+		var value string
+		switch typed := field.Interface().(type) {
+		case *metav1.Duration:
+			value = typed.Duration.String()
+		case []string:
+			value = strings.Join(typed, ",")
+		case map[string]bool:
+			var pairs []string
+			for key, enabled := range typed {
+				pairs = append(pairs, key+"="+strconv.FormatBool(enabled))
+			}
+			value = strings.Join(pairs, ",")
+		default:
+			value = fmt.Sprint(field.Elem().Interface())
+		}
+
+		if err := visit(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+func TestParseFileConfiguration(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			data: `
+apiVersion: daemon.hybridnet.io/v1alpha1
+kind: DaemonConfiguration
+logLevel: debug
+vxlanUDPPort: 4789
+iptablesCheckDuration: 10s
+vtepAddressCIDRs: ["192.168.0.0/16"]
+featureGates:
+  MultiCluster: true
+`,
+		},
+		{
+			name: "unknown field",
+			data: `
+apiVersion: daemon.hybridnet.io/v1alpha1
+kind: DaemonConfiguration
+vxlanPort: 4789
+`,
+			wantErr: true,
+		},
+		{
+			name: "wrong type",
+			data: `
+apiVersion: daemon.hybridnet.io/v1alpha1
+kind: DaemonConfiguration
+vxlanUDPPort: "port"
+`,
+			wantErr: true,
+		},
+		{
+			name: "unsupported version",
+			data: `
+apiVersion: daemon.hybridnet.io/v1
+kind: DaemonConfiguration
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseFileConfiguration([]byte(test.data))
+			if (err != nil) != test.wantErr {
+				t.Errorf("expect error %v, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestApplyToFlags(t *testing.T) {
+	flagSet := pflag.NewFlagSet("", pflag.ContinueOnError)
+	port := flagSet.Int("vxlan-udp-port", DefaultVxlanUDPPort, "")
+	duration := flagSet.Duration("iptables-check-duration", DefaultIPtablesCheckDuration, "")
+	cidrs := flagSet.String("vtep-address-cidrs", "", "")
+	if err := flagSet.Parse([]string{"--iptables-check-duration=20s"}); err != nil {
+		t.Fatalf("unable to parse flags: %v", err)
+	}
+
+	fileConfig, err := parseFileConfiguration([]byte(`
+apiVersion: daemon.hybridnet.io/v1alpha1
+kind: DaemonConfiguration
+vxlanUDPPort: 4789
+iptablesCheckDuration: 10s
+vtepAddressCIDRs: ["192.168.0.0/16", "10.0.0.0/8"]
+`))
+	if err != nil {
+		t.Fatalf("unable to parse config file: %v", err)
+	}
+
+	if err = fileConfig.ApplyToFlags(flagSet); err != nil {
+		t.Fatalf("unable to apply config file: %v", err)
+	}
+
+	if *port != 4789 {
+		t.Errorf("expect vxlan udp port from config file, got %v", *port)
+	}
+	if *duration != 20*time.Second {
+		t.Errorf("expect iptables check duration from command line, got %v", *duration)
+	}
+	if *cidrs != "192.168.0.0/16,10.0.0.0/8" {
+		t.Errorf("unexpected vtep address cidrs %v", *cidrs)
+	}
+}
+
+func TestReloadableFrom(t *testing.T) {
+	config := &Configuration{
+		IptablesCheckDuration: 20 * time.Second,
+		commandLineFlags:      map[string]bool{"iptables-check-duration": true},
+	}
+
+	fileConfig, err := parseFileConfiguration([]byte(`
+apiVersion: daemon.hybridnet.io/v1alpha1
+kind: DaemonConfiguration
+logLevel: debug
+iptablesCheckDuration: 10s
+`))
+	if err != nil {
+		t.Fatalf("unable to parse config file: %v", err)
+	}
+
+	reloadable, err := config.reloadableFrom(fileConfig)
+	if err != nil {
+		t.Fatalf("unable to get reloadable configuration: %v", err)
+	}
+
+	expected := ReloadableConfiguration{
+		LogLevel:                             "debug",
+		IptablesCheckDuration:                20 * time.Second,
+		VxlanExpiredNeighCachesClearInterval: DefaultVxlanExpiredNeighCachesClearInterval,
+	}
+	if *reloadable != expected {
+		t.Errorf("expect %+v, got %+v", expected, *reloadable)
+	}
+
+	fileConfig.LogLevel = new(string)
+	*fileConfig.LogLevel = "verbose"
+	if _, err = config.reloadableFrom(fileConfig); err == nil {
+		t.Errorf("expect error of invalid log level")
+	}
+}
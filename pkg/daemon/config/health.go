@@ -0,0 +1,112 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/alibaba/hybridnet/pkg/constants"
+)
+
+// DefaultHealthReportInterval is the interval of self-checks whose results are reported to node
+const DefaultHealthReportInterval = time.Minute
+
+// DefaultHealthHeartbeatInterval is the interval to report an unchanged result again with a new heartbeat,
+// which tells manager that daemon is still running
+const DefaultHealthHeartbeatInterval = 5 * time.Minute
+
+// unknownDaemonVersion is reported by binaries built without commit id
+const unknownDaemonVersion = "unknown"
+
+// HealthReporter runs the self-check of daemon periodically and reports the result together with the
+// version of daemon on annotations of node, which are watched by manager to gate the rollout of daemon.
+// Annotations are patched with the time of report as heartbeat when the result or version changes, or
+// the last heartbeat is older than HeartbeatInterval.
+type HealthReporter struct {
+	NodeName          string
+	Version           string
+	Client            client.Client
+	Interval          time.Duration
+	HeartbeatInterval time.Duration
+	Logger            logr.Logger
+
+	// SelfCheck checks if dataplane of daemon works, e.g., caches are synced and interfaces are usable
+	SelfCheck func(ctx context.Context) error
+
+	// reported is the result and version reported last time
+	reported string
+	// lastHeartbeat is the time of the last report
+	lastHeartbeat time.Time
+}
+
+// Start implements manager.Runnable
+func (r *HealthReporter) Start(ctx context.Context) error {
+	if len(r.Version) == 0 {
+		r.Version = unknownDaemonVersion
+	}
+
+	r.Logger.Info("health reporter started", "version", r.Version, "interval", r.Interval)
+	wait.UntilWithContext(ctx, r.check, r.Interval)
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (r *HealthReporter) NeedLeaderElection() bool {
+	return false
+}
+
+func (r *HealthReporter) check(ctx context.Context) {
+	err := r.SelfCheck(ctx)
+	if err != nil {
+		r.Logger.Error(err, "daemon fails the self-check", "version", r.Version)
+	}
+
+	reported := fmt.Sprintf("%s/%t", r.Version, err == nil)
+	now := time.Now()
+	if reported == r.reported && now.Sub(r.lastHeartbeat) < r.HeartbeatInterval {
+		return
+	}
+
+	patchBody := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q,%q:%q,%q:%q}}}`,
+		constants.AnnotationDaemonVersion, r.Version,
+		constants.AnnotationDaemonHealthy, strconv.FormatBool(err == nil),
+		constants.AnnotationDaemonHeartbeat, now.UTC().Format(time.RFC3339))
+	if err = r.Client.Patch(ctx, &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: r.NodeName,
+		},
+	}, client.RawPatch(types.MergePatchType, []byte(patchBody))); err != nil {
+		r.Logger.Error(err, "failed to report daemon health", "node", r.NodeName)
+		return
+	}
+
+	if reported != r.reported {
+		r.Logger.Info("daemon health is reported", "node", r.NodeName, "patch", patchBody)
+	}
+	r.reported = reported
+	r.lastHeartbeat = now
+}
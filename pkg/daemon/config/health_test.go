@@ -0,0 +1,81 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/alibaba/hybridnet/pkg/constants"
+)
+
+func TestHealthReporterHeartbeat(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(node).Build()
+
+	r := &HealthReporter{
+		NodeName:          node.Name,
+		Version:           "v1",
+		Client:            c,
+		HeartbeatInterval: time.Hour,
+		Logger:            logr.Discard(),
+		SelfCheck:         func(ctx context.Context) error { return nil },
+	}
+
+	heartbeatOf := func() string {
+		if err := c.Get(context.Background(), types.NamespacedName{Name: node.Name}, node); err != nil {
+			t.Fatalf("failed to get node: %v", err)
+		}
+		if node.Annotations[constants.AnnotationDaemonVersion] != "v1" || node.Annotations[constants.AnnotationDaemonHealthy] != "true" {
+			t.Fatalf("unexpected annotations %v", node.Annotations)
+		}
+		return node.Annotations[constants.AnnotationDaemonHeartbeat]
+	}
+
+	r.check(context.Background())
+	heartbeat := heartbeatOf()
+	if _, err := time.Parse(time.RFC3339, heartbeat); err != nil {
+		t.Fatalf("invalid heartbeat %q: %v", heartbeat, err)
+	}
+
+	// an unchanged result is not reported again within heartbeat interval
+	lastHeartbeat := r.lastHeartbeat
+	r.check(context.Background())
+	if r.lastHeartbeat != lastHeartbeat {
+		t.Errorf("unchanged result should not be reported within heartbeat interval")
+	}
+
+	// an unchanged result is reported again once heartbeat is older than heartbeat interval
+	r.lastHeartbeat = lastHeartbeat.Add(-2 * time.Hour)
+	r.check(context.Background())
+	if !r.lastHeartbeat.After(lastHeartbeat) {
+		t.Errorf("heartbeat should be reported after heartbeat interval")
+	}
+	_ = heartbeatOf()
+}
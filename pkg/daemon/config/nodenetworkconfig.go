@@ -0,0 +1,128 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+)
+
+// InitNodeNetworkConfig overrides interfaces, vtep address cidrs and mtu from flags with the
+// NodeNetworkConfig applying to this node, then resolves the actual interfaces and mtu. It must
+// be called before configuration is used, and returns nil if no NodeNetworkConfig applies.
+// Nodes other than the canary ones apply the promoted spec if the config is being rolled out and
+// it's released to them, otherwise they keep the spec applied last time.
+func (config *Configuration) InitNodeNetworkConfig(ctx context.Context, reader client.Reader) (*networkingv1.NodeNetworkConfig, error) {
+	nodeNetworkConfig, spec, revision, err := selectEffectiveNodeNetworkConfig(ctx, reader, config.NodeName)
+	if err != nil {
+		return nil, err
+	}
+
+	if spec != nil {
+		if err = config.applyNodeNetworkConfig(spec); err != nil {
+			return nil, fmt.Errorf("failed to apply node network config %v: %v", revision, err)
+		}
+	}
+	config.NodeNetworkConfigRevision = revision
+	config.NodeNetworkConfigSpec = spec
+
+	if err = config.initNicConfig(); err != nil {
+		return nil, err
+	}
+	return nodeNetworkConfig, nil
+}
+
+// selectEffectiveNodeNetworkConfig returns the NodeNetworkConfig applying to node, with the spec node
+// applies and its revision, the spec is nil and revision is empty if no spec applies
+func selectEffectiveNodeNetworkConfig(ctx context.Context, reader client.Reader, nodeName string) (
+	*networkingv1.NodeNetworkConfig, *networkingv1.NodeNetworkConfigSpec, string, error) {
+	nodeNetworkConfigList := &networkingv1.NodeNetworkConfigList{}
+	if err := reader.List(ctx, nodeNetworkConfigList); err != nil {
+		// crd of NodeNetworkConfig might not be installed yet
+		if meta.IsNoMatchError(err) {
+			return nil, nil, "", nil
+		}
+		return nil, nil, "", fmt.Errorf("failed to list node network configs: %v", err)
+	}
+
+	if len(nodeNetworkConfigList.Items) == 0 {
+		return nil, nil, "", nil
+	}
+
+	node := &corev1.Node{}
+	if err := reader.Get(ctx, types.NamespacedName{Name: nodeName}, node); err != nil {
+		return nil, nil, "", fmt.Errorf("failed to get node %v: %v", nodeName, err)
+	}
+
+	nodeNetworkConfig, err := networkingv1.SelectNodeNetworkConfig(nodeNetworkConfigList.Items, node.Name, node.Labels)
+	if err != nil || nodeNetworkConfig == nil {
+		return nil, nil, "", err
+	}
+
+	spec, revision, err := networkingv1.GetEffectiveNodeNetworkConfigSpec(nodeNetworkConfig, node.Labels, node.Annotations)
+	if err != nil {
+		return nodeNetworkConfig, nil, "", err
+	}
+	return nodeNetworkConfig, spec, revision, nil
+}
+
+func (config *Configuration) applyNodeNetworkConfig(spec *networkingv1.NodeNetworkConfigSpec) error {
+	if err := networkingv1.ValidateNodeNetworkConfigSpec(spec); err != nil {
+		return err
+	}
+
+	if spec.VlanInterfaces != "" {
+		config.NodeVlanIfName = spec.VlanInterfaces
+	}
+	if spec.VxlanInterfaces != "" {
+		config.NodeVxlanIfName = spec.VxlanInterfaces
+	}
+	if spec.BGPInterfaces != "" {
+		config.NodeBGPIfName = spec.BGPInterfaces
+	}
+
+	if len(spec.VtepAddressCIDRs) > 0 {
+		config.VtepAddressCIDRs = nil
+		for _, cidrString := range spec.VtepAddressCIDRs {
+			cidrs, err := parseCidrString(cidrString)
+			if err != nil {
+				return err
+			}
+			config.VtepAddressCIDRs = append(config.VtepAddressCIDRs, cidrs...)
+		}
+	}
+
+	if spec.MTU != nil {
+		if spec.MTU.Vlan != nil {
+			config.VlanMTU = int(*spec.MTU.Vlan)
+		}
+		if spec.MTU.Vxlan != nil {
+			config.VxlanMTU = int(*spec.MTU.Vxlan)
+		}
+		if spec.MTU.BGP != nil {
+			config.BGPMTU = int(*spec.MTU.BGP)
+		}
+	}
+	return nil
+}
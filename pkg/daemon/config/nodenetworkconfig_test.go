@@ -0,0 +1,108 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package config
+
+import (
+	"net"
+	"testing"
+
+	"github.com/vishvananda/netlink"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	daemonutils "github.com/alibaba/hybridnet/pkg/daemon/utils"
+)
+
+func TestApplyNodeNetworkConfig(t *testing.T) {
+	vxlanMTU := int32(1400)
+	config := &Configuration{
+		NodeVlanIfName:  "eth0",
+		NodeVxlanIfName: "eth0",
+		NodeBGPIfName:   "eth0",
+	}
+	if err := config.applyNodeNetworkConfig(&networkingv1.NodeNetworkConfigSpec{
+		VlanInterfaces:   "bond0.100,eth1",
+		VtepAddressCIDRs: []string{"192.168.10.0/24", "fd00::/64"},
+		MTU:              &networkingv1.NodeMTUConfig{Vxlan: &vxlanMTU},
+	}); err != nil {
+		t.Fatalf("unable to apply node network config: %v", err)
+	}
+
+	if config.NodeVlanIfName != "bond0.100,eth1" || config.NodeVxlanIfName != "eth0" || config.NodeBGPIfName != "eth0" {
+		t.Errorf("unexpected interfaces %v, %v, %v", config.NodeVlanIfName, config.NodeVxlanIfName, config.NodeBGPIfName)
+	}
+	if len(config.VtepAddressCIDRs) != 2 || config.VtepAddressCIDRs[1].String() != "fd00::/64" {
+		t.Errorf("unexpected vtep address cidrs %v", config.VtepAddressCIDRs)
+	}
+	if config.VxlanMTU != 1400 || config.VlanMTU != 0 {
+		t.Errorf("unexpected mtu, vlan %v, vxlan %v", config.VlanMTU, config.VxlanMTU)
+	}
+
+	invalidMTU := int32(100)
+	if err := config.applyNodeNetworkConfig(&networkingv1.NodeNetworkConfigSpec{
+		MTU: &networkingv1.NodeMTUConfig{Vlan: &invalidMTU},
+	}); err == nil {
+		t.Errorf("expect error of invalid mtu")
+	}
+}
+
+func TestInitDryNicConfig(t *testing.T) {
+	config := &Configuration{
+		NodeVxlanIfName: "bond0",
+		VxlanMTU:        1400,
+		DryDataplane:    true,
+	}
+	if err := config.initNicConfig(); err != nil {
+		t.Fatalf("unable to init nic config of dry dataplane: %v", err)
+	}
+
+	if config.NodeVlanIfName != DefaultDryDataplaneIfName || config.NodeVxlanIfName != "bond0" ||
+		config.NodeBGPIfName != DefaultDryDataplaneIfName {
+		t.Errorf("unexpected interfaces %v, %v, %v", config.NodeVlanIfName, config.NodeVxlanIfName, config.NodeBGPIfName)
+	}
+	if config.VlanMTU != DefaultDryDataplaneMTU || config.VxlanMTU != 1400 || config.BGPMTU != DefaultDryDataplaneMTU {
+		t.Errorf("unexpected mtu, vlan %v, vxlan %v, bgp %v", config.VlanMTU, config.VxlanMTU, config.BGPMTU)
+	}
+}
+
+func TestVxlanOverheadOfVtep(t *testing.T) {
+	_, v4CIDR, _ := net.ParseCIDR("0.0.0.0/0")
+	_, v6CIDR, _ := net.ParseCIDR("::/0")
+	addrList := []netlink.Addr{
+		{IPNet: &net.IPNet{IP: net.ParseIP("192.168.10.2"), Mask: net.CIDRMask(24, 32)}},
+		{IPNet: &net.IPNet{IP: net.ParseIP("fd00::2"), Mask: net.CIDRMask(64, 128)}},
+	}
+
+	tests := []struct {
+		name     string
+		addrList []netlink.Addr
+		cidrs    []*net.IPNet
+		overhead int
+	}{
+		{"dual stack node", addrList, []*net.IPNet{v4CIDR, v6CIDR}, vxlanOverheadIPv4},
+		{"ipv6 vtep selected", addrList, []*net.IPNet{v6CIDR}, vxlanOverheadIPv6},
+		{"ipv6-only node", addrList[1:], []*net.IPNet{v4CIDR, v6CIDR}, vxlanOverheadIPv6},
+		{"no vtep address", nil, []*net.IPNet{v4CIDR, v6CIDR}, vxlanOverheadIPv4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if overhead := vxlanOverheadOfVtep(daemonutils.SelectVtepAddress(test.addrList, test.cidrs)); overhead != test.overhead {
+				t.Errorf("unexpected vxlan overhead %v, expect %v", overhead, test.overhead)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/alibaba/hybridnet/pkg/constants"
+)
+
+// DefaultNodeNetworkConfigCheckInterval is the interval to check whether another revision of
+// NodeNetworkConfig is expected to apply on node, which reads from the cache of daemon
+const DefaultNodeNetworkConfigCheckInterval = 5 * time.Second
+
+// NodeNetworkConfigWatcher reports the revision of NodeNetworkConfig applied on start to node after
+// daemon passes the self-check, which promotes a rollout if node is a canary one. Then it polls the
+// NodeNetworkConfig applying to node, OnRolloutChange is called if another revision of a config with
+// rollout is expected to apply, on canary nodes at once (including rolling back a failed revision) and
+// on the other nodes after the revision is released to node by manager. Changes of other configs are
+// only logged because they require restart.
+type NodeNetworkConfigWatcher struct {
+	Config   *Configuration
+	Client   client.Client
+	Reader   client.Reader
+	Interval time.Duration
+	Logger   logr.Logger
+
+	// SelfCheck checks if daemon works with the applied config, e.g., caches are synced and
+	// interfaces are usable
+	SelfCheck       func(ctx context.Context) error
+	OnRolloutChange func(revision string)
+
+	// reportPending is true if daemon passes the self-check but the revision is not reported yet
+	reportPending bool
+	// lastRevision is the last revision expected to apply on node
+	lastRevision string
+}
+
+// Start implements manager.Runnable
+func (w *NodeNetworkConfigWatcher) Start(ctx context.Context) error {
+	w.lastRevision = w.Config.NodeNetworkConfigRevision
+
+	if err := w.SelfCheck(ctx); err != nil {
+		// revision is never reported, so that changes stop at canary nodes
+		w.Logger.Error(err, "daemon fails the self-check with node network config",
+			"revision", w.Config.NodeNetworkConfigRevision)
+	} else {
+		w.Logger.Info("daemon passes the self-check with node network config",
+			"revision", w.Config.NodeNetworkConfigRevision)
+		w.reportPending = true
+	}
+
+	w.Logger.Info("node network config watcher started", "interval", w.Interval)
+	wait.UntilWithContext(ctx, w.check, w.Interval)
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (w *NodeNetworkConfigWatcher) NeedLeaderElection() bool {
+	return false
+}
+
+func (w *NodeNetworkConfigWatcher) report(ctx context.Context) {
+	var patchBody string
+	if len(w.Config.NodeNetworkConfigRevision) == 0 || w.Config.NodeNetworkConfigSpec == nil {
+		patchBody = fmt.Sprintf(`{"metadata":{"annotations":{%q:null,%q:null}}}`, constants.AnnotationNodeNetworkConfigRevision,
+			constants.AnnotationNodeNetworkConfigAppliedSpec)
+	} else {
+		appliedSpec, err := json.Marshal(w.Config.NodeNetworkConfigSpec)
+		if err != nil {
+			w.Logger.Error(err, "failed to marshal applied node network config spec")
+			return
+		}
+		patchBody = fmt.Sprintf(`{"metadata":{"annotations":{%q:%q,%q:%q}}}`, constants.AnnotationNodeNetworkConfigRevision,
+			w.Config.NodeNetworkConfigRevision, constants.AnnotationNodeNetworkConfigAppliedSpec, appliedSpec)
+	}
+
+	if err := w.Client.Patch(ctx, &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: w.Config.NodeName,
+		},
+	}, client.RawPatch(types.MergePatchType, []byte(patchBody))); err != nil {
+		w.Logger.Error(err, "failed to report node network config revision", "node", w.Config.NodeName)
+		return
+	}
+	w.reportPending = false
+}
+
+func (w *NodeNetworkConfigWatcher) check(ctx context.Context) {
+	if w.reportPending {
+		w.report(ctx)
+	}
+
+	nodeNetworkConfig, _, revision, err := selectEffectiveNodeNetworkConfig(ctx, w.Reader, w.Config.NodeName)
+	if err != nil {
+		w.Logger.Error(err, "failed to select node network config")
+		return
+	}
+
+	if revision == w.lastRevision {
+		return
+	}
+
+	// the effective revision of a config with rollout only changes on other nodes after it's released
+	if nodeNetworkConfig != nil && nodeNetworkConfig.Spec.Rollout != nil {
+		w.lastRevision = revision
+		w.Logger.Info("node network config is rolled out to node", "applied-revision",
+			w.Config.NodeNetworkConfigRevision, "revision", revision)
+		w.OnRolloutChange(revision)
+		return
+	}
+
+	w.lastRevision = revision
+	w.Logger.Info("node network config changes, restart daemon to apply it", "applied-revision",
+		w.Config.NodeNetworkConfigRevision, "revision", revision)
+}
+
+// SelfCheck checks if the resolved node interfaces are up and mtu of pods fits them, which are the
+// settings of NodeNetworkConfig, interfaces are never checked in a dry dataplane
+func (config *Configuration) SelfCheck() error {
+	if config.DryDataplane {
+		return nil
+	}
+
+	if _, err := checkNodeInterface(config.NodeVlanIfName, config.VlanMTU); err != nil {
+		return fmt.Errorf("vlan node interface fails self-check: %v", err)
+	}
+
+	if _, err := checkNodeInterface(config.NodeBGPIfName, config.BGPMTU); err != nil {
+		return fmt.Errorf("bgp node interface fails self-check: %v", err)
+	}
+
+	vxlanNodeInterface, err := checkNodeInterface(config.NodeVxlanIfName, 0)
+	if err != nil {
+		return fmt.Errorf("vxlan node interface fails self-check: %v", err)
+	}
+
+	vxlanOverhead, err := config.vxlanOverheadOf(vxlanNodeInterface)
+	if err != nil {
+		return err
+	}
+	if config.VxlanMTU > vxlanNodeInterface.MTU-vxlanOverhead {
+		return fmt.Errorf("vxlan mtu %v exceeds mtu %v of vxlan node interface %v with overhead %v",
+			config.VxlanMTU, vxlanNodeInterface.MTU, vxlanNodeInterface.Name, vxlanOverhead)
+	}
+	return nil
+}
+
+func checkNodeInterface(name string, mtu int) (*net.Interface, error) {
+	nodeInterface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get interface %v: %v", name, err)
+	}
+	if nodeInterface.Flags&net.FlagUp == 0 {
+		return nil, fmt.Errorf("interface %v is down", name)
+	}
+	if mtu > nodeInterface.MTU {
+		return nil, fmt.Errorf("mtu %v exceeds mtu %v of interface %v", mtu, nodeInterface.MTU, name)
+	}
+	return nodeInterface, nil
+}
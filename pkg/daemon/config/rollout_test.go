@@ -0,0 +1,136 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+)
+
+func TestNodeNetworkConfigWatcherCheck(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+
+	tests := []struct {
+		name             string
+		canary           bool
+		failed           bool
+		released         string
+		expectedRevision string
+	}{
+		{
+			name: "other node waits for release",
+		},
+		{
+			name:     "other node waits for release of the promoted revision",
+			released: "pool/1",
+		},
+		{
+			name:             "other node is released",
+			released:         "pool/2",
+			expectedRevision: "pool/2",
+		},
+		{
+			name:             "canary node applies the latest revision at once",
+			canary:           true,
+			expectedRevision: "pool/3",
+		},
+		{
+			name:             "canary node rolls back to the promoted revision if rollout fails",
+			canary:           true,
+			failed:           true,
+			expectedRevision: "pool/2",
+		},
+	}
+
+	for _, test := range tests {
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "node1",
+				Labels: map[string]string{"pool": "a"},
+				Annotations: map[string]string{
+					constants.AnnotationNodeNetworkConfigRevision:    "pool/1",
+					constants.AnnotationNodeNetworkConfigAppliedSpec: "{}",
+				},
+			},
+		}
+		if test.canary {
+			node.Labels["canary"] = "true"
+		}
+		if len(test.released) > 0 {
+			node.Annotations[constants.AnnotationNodeNetworkConfigReleasedRevision] = test.released
+		}
+
+		nodeNetworkConfig := &networkingv1.NodeNetworkConfig{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "pool",
+				Generation: 3,
+			},
+			Spec: networkingv1.NodeNetworkConfigSpec{
+				NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "a"}},
+				Rollout: &networkingv1.NodeNetworkConfigRollout{
+					CanaryNodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"canary": "true"}},
+				},
+			},
+			Status: networkingv1.NodeNetworkConfigStatus{
+				Phase:              networkingv1.NodeNetworkConfigRolloutCanary,
+				ObservedGeneration: 3,
+				PromotedGeneration: 2,
+				PromotedSpec:       &networkingv1.NodeNetworkConfigSpec{},
+			},
+		}
+		if test.failed {
+			nodeNetworkConfig.Status.Phase = networkingv1.NodeNetworkConfigRolloutFailed
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(node, nodeNetworkConfig).Build()
+
+		var rolledOut string
+		w := &NodeNetworkConfigWatcher{
+			Config: &Configuration{
+				NodeName:                  node.Name,
+				NodeNetworkConfigRevision: "pool/1",
+			},
+			Client:          c,
+			Reader:          c,
+			Logger:          logr.Discard(),
+			OnRolloutChange: func(revision string) { rolledOut = revision },
+			lastRevision:    "pool/1",
+		}
+
+		w.check(context.Background())
+		if rolledOut != test.expectedRevision {
+			t.Errorf("test %s fails, expected rolled out revision %q but got %q", test.name, test.expectedRevision, rolledOut)
+		}
+
+		// daemon keeps waiting if it's not released
+		if len(test.expectedRevision) == 0 && w.lastRevision != "pool/1" {
+			t.Errorf("test %s fails, revision should not be changed before release, got %s", test.name, w.lastRevision)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// DefaultConfigFileCheckInterval is the interval to check whether config file changes, config
+// file is usually mounted from a ConfigMap whose updates are propagated with a delay anyway
+const DefaultConfigFileCheckInterval = 10 * time.Second
+
+// FileWatcher polls config file of daemon and calls OnReload with the reloadable configuration
+// after every change, changes of other fields are only logged because they require restart
+type FileWatcher struct {
+	Config   *Configuration
+	Interval time.Duration
+	Logger   logr.Logger
+	OnReload func(*ReloadableConfiguration)
+
+	lastData []byte
+}
+
+// Start implements manager.Runnable
+func (w *FileWatcher) Start(ctx context.Context) error {
+	// config file has been applied on start
+	w.lastData, _ = os.ReadFile(w.Config.ConfigFile)
+
+	w.Logger.Info("config file watcher started", "path", w.Config.ConfigFile, "interval", w.Interval)
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		w.check()
+	}, w.Interval)
+	return nil
+}
+
+func (w *FileWatcher) check() {
+	data, err := os.ReadFile(w.Config.ConfigFile)
+	if err != nil {
+		w.Logger.Error(err, "failed to read config file", "path", w.Config.ConfigFile)
+		return
+	}
+	if bytes.Equal(data, w.lastData) {
+		return
+	}
+	w.lastData = data
+
+	fileConfig, err := parseFileConfiguration(data)
+	if err != nil {
+		w.Logger.Error(err, "ignore invalid config file", "path", w.Config.ConfigFile)
+		return
+	}
+
+	reloadable, err := w.Config.reloadableFrom(fileConfig)
+	if err != nil {
+		w.Logger.Error(err, "ignore invalid config file", "path", w.Config.ConfigFile)
+		return
+	}
+
+	if w.Config.fileConfig == nil || !reflect.DeepEqual(w.Config.fileConfig.withoutReloadable(), fileConfig.withoutReloadable()) {
+		w.Logger.Info("config file changes fields which are not reloadable, restart daemon to apply them",
+			"path", w.Config.ConfigFile)
+	}
+
+	w.Logger.Info("reload config file", "path", w.Config.ConfigFile, "config", *reloadable)
+	w.OnReload(reloadable)
+}
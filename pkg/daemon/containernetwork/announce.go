@@ -0,0 +1,82 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package containernetwork
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/daemon/arp"
+	"github.com/alibaba/hybridnet/pkg/daemon/ndp"
+	daemonutils "github.com/alibaba/hybridnet/pkg/daemon/utils"
+)
+
+// Announce sends gratuitous arp (IPv4) or unsolicited na (IPv6) of ip over interface.
+func Announce(ifi *net.Interface, ip net.IP) error {
+	if ip.To4() != nil {
+		return arp.Gratuitous(ifi, ip)
+	}
+	return ndp.Gratuitous(ifi, ip)
+}
+
+// AnnounceWithBackoff resends announcements of ip over interface for retries times, the first
+// retry is sent after interval, which is doubled for every following retry. A failed retry
+// doesn't stop the following ones, and the last error is returned.
+func AnnounceWithBackoff(ifi *net.Interface, ip net.IP, retries int, interval time.Duration) error {
+	var lastErr error
+	for i := 0; i < retries; i++ {
+		time.Sleep(interval)
+		interval *= 2
+
+		if err := Announce(ifi, ip); err != nil {
+			lastErr = fmt.Errorf("failed to announce ip %v over interface %v: %v", ip.String(), ifi.Name, err)
+		}
+	}
+	return lastErr
+}
+
+// AnnounceAllocatedIPs resends announcements of the allocated ips of a new vlan pod in background,
+// the first announcement has been sent while checking vlan environment.
+func AnnounceAllocatedIPs(nodeIfName string, allocatedIPs map[networkingv1.IPVersion]*daemonutils.IPInfo,
+	networkMode networkingv1.NetworkMode, logger logr.Logger) {
+	if networkMode != networkingv1.NetworkModeVlan {
+		return
+	}
+
+	for _, ipInfo := range allocatedIPs {
+		if ipInfo == nil || ipInfo.AnnounceRetries <= 0 {
+			continue
+		}
+
+		forwardNodeIf, err := ensureForwardNodeIf(networkMode, nodeIfName, ipInfo)
+		if err != nil {
+			logger.Error(err, "failed to get forward interface for announcing", "ip", ipInfo.Addr.String())
+			continue
+		}
+
+		go func(ipInfo *daemonutils.IPInfo) {
+			if err := AnnounceWithBackoff(forwardNodeIf, ipInfo.Addr, ipInfo.AnnounceRetries,
+				ipInfo.AnnounceInterval); err != nil {
+				logger.Error(err, "failed to resend announcements", "ip", ipInfo.Addr.String())
+			}
+		}(ipInfo)
+	}
+}
@@ -36,13 +36,14 @@ import (
 	daemonutils "github.com/alibaba/hybridnet/pkg/daemon/utils"
 )
 
-func ConfigureHostNic(nicName string, allocatedIPs map[networkingv1.IPVersion]*daemonutils.IPInfo, localDirectTableNum int) error {
-	hostLink, err := netlink.LinkByName(nicName)
+func ConfigureHostNic(hostNetwork HostNetwork, nicName string, allocatedIPs map[networkingv1.IPVersion]*daemonutils.IPInfo,
+	localDirectTableNum int) error {
+	hostLink, err := hostNetwork.LinkByName(nicName)
 	if err != nil {
 		return fmt.Errorf("can not find host nic %s %v", nicName, err)
 	}
 
-	if err = netlink.LinkSetUp(hostLink); err != nil {
+	if err = hostNetwork.LinkSetUp(hostLink); err != nil {
 		return fmt.Errorf("can not set host nic %s up %v", nicName, err)
 	}
 
@@ -51,10 +52,24 @@ func ConfigureHostNic(nicName string, allocatedIPs map[networkingv1.IPVersion]*d
 		return fmt.Errorf("failed to parse mac %v: %v", constants.ContainerHostLinkMac, err)
 	}
 
-	if err = netlink.LinkSetHardwareAddr(hostLink, macAddress); err != nil {
+	if err = hostNetwork.LinkSetHardwareAddr(hostLink, macAddress); err != nil {
 		return fmt.Errorf("failed to set mac address to nic %s %v", hostLink, err)
 	}
 
+	// pods of tenant networks are isolated in the vrf of their tenants, so are the routes to them
+	routeTable := localDirectTableNum
+	if vrfName, vrfTable := tenantVRFOf(allocatedIPs); len(vrfName) > 0 {
+		vrfLink, err := hostNetwork.LinkByName(vrfName)
+		if err != nil {
+			return fmt.Errorf("can not find vrf device %s %v", vrfName, err)
+		}
+
+		if err = hostNetwork.LinkSetMaster(hostLink, vrfLink); err != nil {
+			return fmt.Errorf("can not set master of host nic %s to vrf device %s %v", nicName, vrfName, err)
+		}
+		routeTable = vrfTable
+	}
+
 	if allocatedIPs[networkingv1.IPv4] != nil {
 		// Enable proxy ARP, this makes the host respond to all ARP requests with its own
 		// MAC.  This has a couple of advantages:
@@ -65,21 +80,21 @@ func ConfigureHostNic(nicName string, allocatedIPs map[networkingv1.IPVersion]*d
 		//   host side of the veth, which is one fewer thing to maintain and one fewer
 		//   thing we may clash over.
 		sysctlPath := fmt.Sprintf(constants.ProxyArpSysctl, nicName)
-		if err := daemonutils.SetSysctl(sysctlPath, 1); err != nil {
+		if err := hostNetwork.SetSysctl(sysctlPath, 1); err != nil {
 			return fmt.Errorf("failed to set sysctl parameter %v: %v", sysctlPath, err)
 		}
 
 		// Enable routing to localhost.  This is required to allow for NAT to the local
 		// host.
 		sysctlPath = fmt.Sprintf(constants.RouteLocalNetSysctl, nicName)
-		if err := daemonutils.SetSysctl(sysctlPath, 1); err != nil {
+		if err := hostNetwork.SetSysctl(sysctlPath, 1); err != nil {
 			return fmt.Errorf("failed to set sysctl parameter %v: %v", sysctlPath, err)
 		}
 
 		// Normally, the kernel has a delay before responding to proxy ARP but we know
 		// that's not needed in a Hybridnet network so we disable it.
 		sysctlPath = fmt.Sprintf(constants.ProxyDelaySysctl, nicName)
-		if err := daemonutils.SetSysctl(sysctlPath, 0); err != nil {
+		if err := hostNetwork.SetSysctl(sysctlPath, 0); err != nil {
 			return fmt.Errorf("failed to set sysctl parameter %v: %v", sysctlPath, err)
 		}
 
@@ -87,7 +102,7 @@ func ConfigureHostNic(nicName string, allocatedIPs map[networkingv1.IPVersion]*d
 		// be forwarded in both directions we need this flag to be set on the fabric-facing
 		// interface too (or for the global default to be set).
 		sysctlPath = fmt.Sprintf(constants.IPv4ForwardingSysctl, nicName)
-		if err := daemonutils.SetSysctl(sysctlPath, 1); err != nil {
+		if err := hostNetwork.SetSysctl(sysctlPath, 1); err != nil {
 			return fmt.Errorf("failed to set sysctl parameter %v: %v", sysctlPath, err)
 		}
 
@@ -98,10 +113,10 @@ func ConfigureHostNic(nicName string, allocatedIPs map[networkingv1.IPVersion]*d
 				IP:   allocatedIPs[networkingv1.IPv4].Addr,
 				Mask: mask,
 			},
-			Table: localDirectTableNum,
+			Table: routeTable,
 		}
 
-		if err := netlink.RouteReplace(localPodRoute); err != nil {
+		if err := hostNetwork.RouteReplace(localPodRoute); err != nil {
 			return fmt.Errorf("failed to add route %v: %v", localPodRoute.String(), err)
 		}
 	}
@@ -113,7 +128,7 @@ func ConfigureHostNic(nicName string, allocatedIPs map[networkingv1.IPVersion]*d
 		// But only proxy_ndp be set cannot work, proxy neigh entries should also be added
 		// for each ip to proxy.
 		sysctlPath := fmt.Sprintf(constants.ProxyNdpSysctl, nicName)
-		if err := daemonutils.SetSysctl(sysctlPath, 1); err != nil {
+		if err := hostNetwork.SetSysctl(sysctlPath, 1); err != nil {
 			return fmt.Errorf("failed to set sysctl parameter %v: %v", sysctlPath, err)
 		}
 
@@ -121,7 +136,7 @@ func ConfigureHostNic(nicName string, allocatedIPs map[networkingv1.IPVersion]*d
 		// be forwarded in both directions we need this flag to be set on the fabric-facing
 		// interface too (or for the global default to be set).
 		sysctlPath = fmt.Sprintf(constants.IPv6ForwardingSysctl, nicName)
-		if err := daemonutils.SetSysctl(sysctlPath, 1); err != nil {
+		if err := hostNetwork.SetSysctl(sysctlPath, 1); err != nil {
 			return fmt.Errorf("failed to set sysctl parameter %v: %v", sysctlPath, err)
 		}
 
@@ -132,20 +147,20 @@ func ConfigureHostNic(nicName string, allocatedIPs map[networkingv1.IPVersion]*d
 				IP:   allocatedIPs[networkingv1.IPv6].Addr,
 				Mask: mask,
 			},
-			Table: localDirectTableNum,
+			Table: routeTable,
 		}
 
-		if err := netlink.RouteReplace(localPodRoute); err != nil {
+		if err := hostNetwork.RouteReplace(localPodRoute); err != nil {
 			return fmt.Errorf("failed to add route %v: %v", localPodRoute.String(), err)
 		}
 
-		if err := netlink.NeighAdd(&netlink.Neigh{
+		if err := hostNetwork.NeighAdd(&netlink.Neigh{
 			LinkIndex: hostLink.Attrs().Index,
 			Family:    netlink.FAMILY_V6,
 			Flags:     netlink.NTF_PROXY,
 			IP:        net.ParseIP(constants.PodVirtualV6DefaultGateway),
 		}); err != nil {
-			return fmt.Errorf("failed to add neigh for ip %v/%v: %v", constants.PodVirtualV4DefaultGateway,
+			return fmt.Errorf("failed to add neigh for ip %v/%v: %v", constants.PodVirtualV6DefaultGateway,
 				hostLink.Attrs().Name, err)
 		}
 	}
@@ -153,9 +168,28 @@ func ConfigureHostNic(nicName string, allocatedIPs map[networkingv1.IPVersion]*d
 	return nil
 }
 
+// tenantVRFOf returns the vrf device and its route table of allocated ips, empty if they belong to no tenant
+func tenantVRFOf(allocatedIPs map[networkingv1.IPVersion]*daemonutils.IPInfo) (string, int) {
+	for _, ipInfo := range allocatedIPs {
+		if ipInfo != nil && len(ipInfo.VRFName) > 0 {
+			return ipInfo.VRFName, ipInfo.VRFTable
+		}
+	}
+	return "", 0
+}
+
+// adoptedHardwareAddr returns the MAC of pod if ip is adopted, whose existing owner with the same MAC
+// is taken over by pod, otherwise nil
+func adoptedHardwareAddr(ipInfo *daemonutils.IPInfo, macAddr net.HardwareAddr) net.HardwareAddr {
+	if ipInfo.Adopted {
+		return macAddr
+	}
+	return nil
+}
+
 func ConfigureContainerNic(containerNicName, hostNicName, nodeIfName string, allocatedIPs map[networkingv1.IPVersion]*daemonutils.IPInfo,
 	macAddr net.HardwareAddr, netns ns.NetNS, mtu int, vlanCheckTimeout time.Duration, networkMode networkingv1.NetworkMode,
-	neighGCThresh1, neighGCThresh2, neighGCThresh3, ipv6RouteCacheMaxSize, ipv6RouteCacheGCThresh int,
+	staticRoutes []networkingv1.StaticRoute, neighGCThresh1, neighGCThresh2, neighGCThresh3, ipv6RouteCacheMaxSize, ipv6RouteCacheGCThresh int,
 	bgpManager *bgp.Manager) error {
 
 	var defaultRouteNets []*types.Route
@@ -163,7 +197,7 @@ func ConfigureContainerNic(containerNicName, hostNicName, nodeIfName string, all
 
 	ipv6AddressAllocated := false
 	if allocatedIPs[networkingv1.IPv4] != nil {
-		forwardNodeIf, err := ensureForwardNodeIf(networkMode, nodeIfName, allocatedIPs[networkingv1.IPv4].NetID)
+		forwardNodeIf, err := ensureForwardNodeIf(networkMode, nodeIfName, allocatedIPs[networkingv1.IPv4])
 		if err != nil {
 			return fmt.Errorf("failed to ensure v4 forward interface: %v", err)
 		}
@@ -212,7 +246,8 @@ func ConfigureContainerNic(containerNicName, hostNicName, nodeIfName string, all
 			}
 
 			if err := arp.CheckWithTimeout(forwardNodeIf, podIP,
-				allocatedIPs[networkingv1.IPv4].Gw, vlanCheckTimeout); err != nil {
+				allocatedIPs[networkingv1.IPv4].Gw, adoptedHardwareAddr(allocatedIPs[networkingv1.IPv4], macAddr),
+				vlanCheckTimeout); err != nil {
 				return fmt.Errorf("failed to check ipv4 vlan environment: %v", err)
 			}
 		}
@@ -224,7 +259,7 @@ func ConfigureContainerNic(containerNicName, hostNicName, nodeIfName string, all
 	}
 
 	if allocatedIPs[networkingv1.IPv6] != nil {
-		forwardNodeIf, err := ensureForwardNodeIf(networkMode, nodeIfName, allocatedIPs[networkingv1.IPv6].NetID)
+		forwardNodeIf, err := ensureForwardNodeIf(networkMode, nodeIfName, allocatedIPs[networkingv1.IPv6])
 		if err != nil {
 			return fmt.Errorf("failed to ensure v6 forward interface: %v", err)
 		}
@@ -266,7 +301,8 @@ func ConfigureContainerNic(containerNicName, hostNicName, nodeIfName string, all
 			}
 
 			if err := ndp.CheckWithTimeout(forwardNodeIf, podIP,
-				allocatedIPs[networkingv1.IPv6].Gw, vlanCheckTimeout); err != nil {
+				allocatedIPs[networkingv1.IPv6].Gw, adoptedHardwareAddr(allocatedIPs[networkingv1.IPv6], macAddr),
+				vlanCheckTimeout); err != nil {
 				return fmt.Errorf("failed to check ipv6 vlan environment: %v", err)
 			}
 		}
@@ -313,6 +349,15 @@ func ConfigureContainerNic(containerNicName, hostNicName, nodeIfName string, all
 			}
 		}
 
+		// Pods of underlay networks share the link with routers, which may advertise prefixes of subnets.
+		// Addresses generated by SLAAC are unknown to IPAM, so temporary addresses are disabled, and SLAAC
+		// is pinned to the allocated address by token or disabled if impossible.
+		if ipv6AddressAllocated && (networkMode == networkingv1.NetworkModeVlan || networkMode == networkingv1.NetworkModeBGP) {
+			if err := ensureStableIPv6Address(link, allocatedIPs[networkingv1.IPv6]); err != nil {
+				return fmt.Errorf("failed to ensure stable ipv6 address: %v", err)
+			}
+		}
+
 		if err := daemonutils.ConfigureIface(constants.ContainerNicName, result); err != nil {
 			return fmt.Errorf("failed to config container nic: %v", err)
 		}
@@ -324,6 +369,10 @@ func ConfigureContainerNic(containerNicName, hostNicName, nodeIfName string, all
 		if err = netlink.LinkSetMTU(link, mtu); err != nil {
 			return fmt.Errorf("can not set nic %s mtu %v", link, err)
 		}
+
+		if err = configureStaticRoutes(staticRoutes, allocatedIPs); err != nil {
+			return fmt.Errorf("failed to configure static routes: %v", err)
+		}
 		return nil
 	}); err != nil {
 		return err
@@ -332,12 +381,108 @@ func ConfigureContainerNic(containerNicName, hostNicName, nodeIfName string, all
 	return nil
 }
 
-func ensureForwardNodeIf(networkMode networkingv1.NetworkMode, nodeIfName string, netID *int32) (
+// configureStaticRoutes adds the static routes declared by pod in current netns, routes of the family
+// which pod has no address of are skipped
+func configureStaticRoutes(staticRoutes []networkingv1.StaticRoute, allocatedIPs map[networkingv1.IPVersion]*daemonutils.IPInfo) error {
+	for _, staticRoute := range staticRoutes {
+		_, dst, err := net.ParseCIDR(staticRoute.Destination)
+		if err != nil {
+			return fmt.Errorf("failed to parse destination of static route %v: %v", staticRoute.Destination, err)
+		}
+
+		ipVersion := networkingv1.IPv4
+		if dst.IP.To4() == nil {
+			ipVersion = networkingv1.IPv6
+		}
+		if allocatedIPs[ipVersion] == nil {
+			continue
+		}
+
+		deviceName := staticRoute.Device
+		if len(deviceName) == 0 {
+			deviceName = constants.ContainerNicName
+		}
+		device, err := netlink.LinkByName(deviceName)
+		if err != nil {
+			return fmt.Errorf("failed to get device %v of static route to %v: %v", deviceName, staticRoute.Destination, err)
+		}
+
+		route := &netlink.Route{
+			LinkIndex: device.Attrs().Index,
+			Dst:       dst,
+			Scope:     netlink.SCOPE_LINK,
+		}
+
+		if len(staticRoute.NextHop) > 0 {
+			route.Gw = net.ParseIP(staticRoute.NextHop)
+			if route.Gw == nil {
+				return fmt.Errorf("invalid next hop %v of static route to %v", staticRoute.NextHop, staticRoute.Destination)
+			}
+			// next hop is not always inside the subnet of pod address
+			route.Flags = int(netlink.FLAG_ONLINK)
+			route.Scope = netlink.SCOPE_UNIVERSE
+		}
+
+		if err := netlink.RouteReplace(route); err != nil {
+			return fmt.Errorf("failed to add static route %v: %v", route.String(), err)
+		}
+	}
+	return nil
+}
+
+// ensureStableIPv6Address keeps container nic from using ipv6 addresses which are not allocated by IPAM
+func ensureStableIPv6Address(link netlink.Link, ipInfo *daemonutils.IPInfo) error {
+	sysctlPath := fmt.Sprintf(constants.UseTempAddrSysctl, link.Attrs().Name)
+	if err := daemonutils.SetSysctl(sysctlPath, 0); err != nil {
+		return fmt.Errorf("failed to set sysctl parameter %s to %v: %v", sysctlPath, 0, err)
+	}
+
+	// token is refused if router advertisements are not accepted, then SLAAC is disabled instead
+	if token := stableIPv6Token(ipInfo); token != nil {
+		if err := daemonutils.SetIPv6Token(link, token); err == nil {
+			return nil
+		}
+	}
+
+	sysctlPath = fmt.Sprintf(constants.AutoconfSysctl, link.Attrs().Name)
+	if err := daemonutils.SetSysctl(sysctlPath, 0); err != nil {
+		return fmt.Errorf("failed to set sysctl parameter %s to %v: %v", sysctlPath, 0, err)
+	}
+	return nil
+}
+
+// stableIPv6Token returns the interface identifier of allocated ipv6 address as the token of SLAAC,
+// so that SLAAC generates the same address for the prefix of subnet. It's nil if subnet is not a /64,
+// which is the only prefix length SLAAC works with.
+func stableIPv6Token(ipInfo *daemonutils.IPInfo) net.IP {
+	if ipInfo == nil || ipInfo.Cidr == nil || ipInfo.Addr.To16() == nil {
+		return nil
+	}
+
+	if ones, bits := ipInfo.Cidr.Mask.Size(); ones != 64 || bits != 128 {
+		return nil
+	}
+
+	token := make(net.IP, net.IPv6len)
+	copy(token[8:], ipInfo.Addr.To16()[8:])
+	return token
+}
+
+// ensureForwardNodeIf returns the forward interface of ip on node, node interface specified by the subnet
+// of ip takes precedence over nodeIfName for vlan mode
+func ensureForwardNodeIf(networkMode networkingv1.NetworkMode, nodeIfName string, ipInfo *daemonutils.IPInfo) (
 	forwardNodeIf *net.Interface, err error) {
-	var forwardNodeIfName string
+	var (
+		forwardNodeIfName string
+		netID             = ipInfo.NetID
+	)
 
 	switch networkMode {
 	case networkingv1.NetworkModeVlan:
+		if len(ipInfo.NodeIfName) > 0 {
+			nodeIfName = ipInfo.NodeIfName
+		}
+
 		forwardNodeIfName, err = daemonutils.GenerateVlanNetIfName(nodeIfName, netID)
 		if err != nil {
 			err = fmt.Errorf("failed to generate vlan forward node interface name: %v", err)
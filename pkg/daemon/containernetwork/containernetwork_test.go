@@ -0,0 +1,151 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package containernetwork
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	daemonutils "github.com/alibaba/hybridnet/pkg/daemon/utils"
+)
+
+func TestConfigureHostNic(t *testing.T) {
+	const hostNicName = "h_test"
+	const tableNum = 39999
+
+	hostNetwork := NewFakeHostNetwork()
+	if err := ConfigureHostNic(hostNetwork, hostNicName, nil, tableNum); err == nil {
+		t.Fatalf("expect error for a non-existent host nic")
+	}
+
+	hostNetwork.AddLink(hostNicName)
+	allocatedIPs := map[networkingv1.IPVersion]*daemonutils.IPInfo{
+		networkingv1.IPv4: {Addr: net.ParseIP("10.0.0.10")},
+		networkingv1.IPv6: {Addr: net.ParseIP("fd00::10")},
+	}
+	if err := ConfigureHostNic(hostNetwork, hostNicName, allocatedIPs, tableNum); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	link, _ := hostNetwork.LinkByName(hostNicName)
+	if link.Attrs().Flags&net.FlagUp == 0 {
+		t.Errorf("expect host nic to be up")
+	}
+	if link.Attrs().HardwareAddr.String() != constants.ContainerHostLinkMac {
+		t.Errorf("expect mac %v, got %v", constants.ContainerHostLinkMac, link.Attrs().HardwareAddr)
+	}
+
+	for _, sysctlPath := range []string{constants.ProxyArpSysctl, constants.ProxyNdpSysctl,
+		constants.IPv4ForwardingSysctl, constants.IPv6ForwardingSysctl} {
+		if value, _ := hostNetwork.Sysctl(fmt.Sprintf(sysctlPath, hostNicName)); value != 1 {
+			t.Errorf("expect sysctl %v to be 1, got %v", sysctlPath, value)
+		}
+	}
+
+	routes := hostNetwork.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("expect 2 routes, got %v", routes)
+	}
+	for _, route := range routes {
+		if route.Table != tableNum || route.LinkIndex != link.Attrs().Index {
+			t.Errorf("unexpected route %v", route)
+		}
+	}
+
+	neighs := hostNetwork.Neighs()
+	if len(neighs) != 1 || !neighs[0].IP.Equal(net.ParseIP(constants.PodVirtualV6DefaultGateway)) {
+		t.Errorf("expect a proxy neigh of v6 virtual gateway, got %v", neighs)
+	}
+
+	// routes are replaced but the proxy neigh can not be added twice
+	if err := ConfigureHostNic(hostNetwork, hostNicName, allocatedIPs, tableNum); err == nil {
+		t.Errorf("expect error for an existent proxy neigh")
+	}
+	if routes = hostNetwork.Routes(); len(routes) != 2 {
+		t.Errorf("expect routes to be replaced, got %v", routes)
+	}
+}
+
+func TestConfigureHostNicInVRF(t *testing.T) {
+	const hostNicName = "h_test"
+	const vrfName = "hvrf4"
+	const vrfTable = 1<<28 + 4
+
+	hostNetwork := NewFakeHostNetwork()
+	hostNetwork.AddLink(hostNicName)
+	allocatedIPs := map[networkingv1.IPVersion]*daemonutils.IPInfo{
+		networkingv1.IPv4: {Addr: net.ParseIP("10.0.0.10"), VRFName: vrfName, VRFTable: vrfTable},
+	}
+	if err := ConfigureHostNic(hostNetwork, hostNicName, allocatedIPs, 39999); err == nil {
+		t.Fatalf("expect error for a non-existent vrf device")
+	}
+
+	vrfLink := hostNetwork.AddLink(vrfName)
+	if err := ConfigureHostNic(hostNetwork, hostNicName, allocatedIPs, 39999); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	link, _ := hostNetwork.LinkByName(hostNicName)
+	if link.Attrs().MasterIndex != vrfLink.Attrs().Index {
+		t.Errorf("expect host nic to be enslaved to vrf device")
+	}
+
+	routes := hostNetwork.Routes()
+	if len(routes) != 1 || routes[0].Table != vrfTable {
+		t.Errorf("expect a local pod route in vrf table, got %v", routes)
+	}
+}
+
+func TestStableIPv6Token(t *testing.T) {
+	tests := []struct {
+		name   string
+		ipInfo *daemonutils.IPInfo
+		expect net.IP
+	}{
+		{
+			name:   "nil",
+			ipInfo: nil,
+			expect: nil,
+		},
+		{
+			name: "prefix of 64",
+			ipInfo: &daemonutils.IPInfo{
+				Addr: net.ParseIP("fd00:10::1234:5678"),
+				Cidr: &net.IPNet{IP: net.ParseIP("fd00:10::"), Mask: net.CIDRMask(64, 128)},
+			},
+			expect: net.ParseIP("::1234:5678"),
+		},
+		{
+			name: "prefix longer than 64",
+			ipInfo: &daemonutils.IPInfo{
+				Addr: net.ParseIP("fd00:10::1234:5678"),
+				Cidr: &net.IPNet{IP: net.ParseIP("fd00:10::"), Mask: net.CIDRMask(112, 128)},
+			},
+			expect: nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if token := stableIPv6Token(test.ipInfo); !token.Equal(test.expect) {
+				t.Errorf("test %s fail, expect %v but got %v", test.name, test.expect, token)
+			}
+		})
+	}
+}
@@ -0,0 +1,327 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package containernetwork
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/vishvananda/netlink"
+)
+
+// FakeHostNetwork is an in-memory HostNetwork which records links, addresses, routes, neighs and
+// sysctls instead of configuring them on host, for testing without privileges.
+type FakeHostNetwork struct {
+	mu sync.Mutex
+
+	links     map[string]*netlink.Dummy
+	lastIndex int
+	addrs     map[int][]netlink.Addr
+	routes    []netlink.Route
+	neighs    []netlink.Neigh
+	sysctls   map[string]int
+}
+
+var _ HostNetwork = &FakeHostNetwork{}
+
+func NewFakeHostNetwork() *FakeHostNetwork {
+	return &FakeHostNetwork{
+		links:   map[string]*netlink.Dummy{},
+		addrs:   map[int][]netlink.Addr{},
+		sysctls: map[string]int{},
+	}
+}
+
+// AddLink adds a link which is down by default, like a newly created veth.
+func (f *FakeHostNetwork) AddLink(name string) netlink.Link {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.lastIndex++
+	link := &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{
+		Name:  name,
+		Index: f.lastIndex,
+	}}
+	f.links[name] = link
+	return link
+}
+
+// DeleteLink deletes a link with its routes and neighs, like the kernel does.
+func (f *FakeHostNetwork) DeleteLink(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	link, exist := f.links[name]
+	if !exist {
+		return
+	}
+	delete(f.links, name)
+	delete(f.addrs, link.Index)
+
+	var routes []netlink.Route
+	for _, route := range f.routes {
+		if route.LinkIndex != link.Index {
+			routes = append(routes, route)
+		}
+	}
+	f.routes = routes
+
+	var neighs []netlink.Neigh
+	for _, neigh := range f.neighs {
+		if neigh.LinkIndex != link.Index {
+			neighs = append(neighs, neigh)
+		}
+	}
+	f.neighs = neighs
+}
+
+func (f *FakeHostNetwork) LinkByName(name string) (netlink.Link, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	link, exist := f.links[name]
+	if !exist {
+		return nil, netlink.LinkNotFoundError{}
+	}
+	return link, nil
+}
+
+func (f *FakeHostNetwork) LinkByIndex(index int) (netlink.Link, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, link := range f.links {
+		if link.Index == index {
+			return link, nil
+		}
+	}
+	return nil, netlink.LinkNotFoundError{}
+}
+
+func (f *FakeHostNetwork) LinkList() ([]netlink.Link, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var links []netlink.Link
+	for _, link := range f.links {
+		links = append(links, link)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].Attrs().Index < links[j].Attrs().Index
+	})
+	return links, nil
+}
+
+func (f *FakeHostNetwork) LinkSetUp(link netlink.Link) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fakeLink, err := f.getLink(link)
+	if err != nil {
+		return err
+	}
+	fakeLink.Flags |= net.FlagUp
+	return nil
+}
+
+func (f *FakeHostNetwork) LinkSetHardwareAddr(link netlink.Link, hwaddr net.HardwareAddr) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fakeLink, err := f.getLink(link)
+	if err != nil {
+		return err
+	}
+	fakeLink.HardwareAddr = hwaddr
+	return nil
+}
+
+func (f *FakeHostNetwork) LinkSetMaster(link, master netlink.Link) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fakeLink, err := f.getLink(link)
+	if err != nil {
+		return err
+	}
+
+	fakeMaster, err := f.getLink(master)
+	if err != nil {
+		return err
+	}
+	fakeLink.MasterIndex = fakeMaster.Index
+	return nil
+}
+
+func (f *FakeHostNetwork) AddrList(link netlink.Link, family int) ([]netlink.Addr, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fakeLink, err := f.getLink(link)
+	if err != nil {
+		return nil, err
+	}
+
+	var addrs []netlink.Addr
+	for _, addr := range f.addrs[fakeLink.Index] {
+		if family == netlink.FAMILY_ALL || family == familyOfIP(addr.IP) {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs, nil
+}
+
+func (f *FakeHostNetwork) AddrAdd(link netlink.Link, addr *netlink.Addr) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fakeLink, err := f.getLink(link)
+	if err != nil {
+		return err
+	}
+
+	for _, existAddr := range f.addrs[fakeLink.Index] {
+		if existAddr.IP.Equal(addr.IP) {
+			return fmt.Errorf("addr %v on link %v already exists", addr.IP, fakeLink.Name)
+		}
+	}
+	f.addrs[fakeLink.Index] = append(f.addrs[fakeLink.Index], *addr)
+	return nil
+}
+
+func (f *FakeHostNetwork) AddrDel(link netlink.Link, addr *netlink.Addr) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fakeLink, err := f.getLink(link)
+	if err != nil {
+		return err
+	}
+
+	addrs := f.addrs[fakeLink.Index]
+	for i := range addrs {
+		if addrs[i].IP.Equal(addr.IP) {
+			f.addrs[fakeLink.Index] = append(addrs[:i:i], addrs[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("addr %v on link %v not found", addr.IP, fakeLink.Name)
+}
+
+func (f *FakeHostNetwork) RouteReplace(route *netlink.Route) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := range f.routes {
+		if f.routes[i].Table == route.Table && f.routes[i].Dst.String() == route.Dst.String() {
+			f.routes[i] = *route
+			return nil
+		}
+	}
+	f.routes = append(f.routes, *route)
+	return nil
+}
+
+func (f *FakeHostNetwork) NeighAdd(neigh *netlink.Neigh) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, existNeigh := range f.neighs {
+		if existNeigh.LinkIndex == neigh.LinkIndex && existNeigh.IP.Equal(neigh.IP) {
+			return fmt.Errorf("neigh %v on link %v already exists", neigh.IP, neigh.LinkIndex)
+		}
+	}
+	f.neighs = append(f.neighs, *neigh)
+	return nil
+}
+
+func (f *FakeHostNetwork) NeighSet(neigh *netlink.Neigh) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := range f.neighs {
+		if f.neighs[i].LinkIndex == neigh.LinkIndex && f.neighs[i].IP.Equal(neigh.IP) {
+			f.neighs[i] = *neigh
+			return nil
+		}
+	}
+	f.neighs = append(f.neighs, *neigh)
+	return nil
+}
+
+func (f *FakeHostNetwork) NeighList(linkIndex, family int) ([]netlink.Neigh, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var neighs []netlink.Neigh
+	for _, neigh := range f.neighs {
+		if (linkIndex == 0 || neigh.LinkIndex == linkIndex) && (family == netlink.FAMILY_ALL || neigh.Family == family) {
+			neighs = append(neighs, neigh)
+		}
+	}
+	return neighs, nil
+}
+
+func (f *FakeHostNetwork) SetSysctl(sysctlPath string, newVal int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sysctls[sysctlPath] = newVal
+	return nil
+}
+
+// Routes returns all the routes recorded.
+func (f *FakeHostNetwork) Routes() []netlink.Route {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]netlink.Route(nil), f.routes...)
+}
+
+// Neighs returns all the neighs recorded.
+func (f *FakeHostNetwork) Neighs() []netlink.Neigh {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]netlink.Neigh(nil), f.neighs...)
+}
+
+// Sysctl returns the recorded value of a sysctl path.
+func (f *FakeHostNetwork) Sysctl(sysctlPath string) (int, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	value, exist := f.sysctls[sysctlPath]
+	return value, exist
+}
+
+func (f *FakeHostNetwork) getLink(link netlink.Link) (*netlink.Dummy, error) {
+	fakeLink, exist := f.links[link.Attrs().Name]
+	if !exist {
+		return nil, netlink.LinkNotFoundError{}
+	}
+	return fakeLink, nil
+}
+
+func familyOfIP(ip net.IP) int {
+	if ip.To4() != nil {
+		return netlink.FAMILY_V4
+	}
+	return netlink.FAMILY_V6
+}
@@ -0,0 +1,110 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package containernetwork
+
+import (
+	"net"
+
+	"github.com/vishvananda/netlink"
+
+	daemonutils "github.com/alibaba/hybridnet/pkg/daemon/utils"
+)
+
+// HostNetwork is the netlink and sysctl layer used to configure host side nics of containers and
+// by daemon controllers, it's implemented by a netlink based one and by FakeHostNetwork which keeps
+// everything in memory.
+type HostNetwork interface {
+	LinkByName(name string) (netlink.Link, error)
+	LinkByIndex(index int) (netlink.Link, error)
+	LinkList() ([]netlink.Link, error)
+	LinkSetUp(link netlink.Link) error
+	LinkSetHardwareAddr(link netlink.Link, hwaddr net.HardwareAddr) error
+	LinkSetMaster(link, master netlink.Link) error
+	AddrList(link netlink.Link, family int) ([]netlink.Addr, error)
+	AddrAdd(link netlink.Link, addr *netlink.Addr) error
+	AddrDel(link netlink.Link, addr *netlink.Addr) error
+	RouteReplace(route *netlink.Route) error
+	NeighAdd(neigh *netlink.Neigh) error
+	NeighSet(neigh *netlink.Neigh) error
+	NeighList(linkIndex, family int) ([]netlink.Neigh, error)
+	SetSysctl(sysctlPath string, newVal int) error
+}
+
+type netlinkHostNetwork struct{}
+
+var _ HostNetwork = netlinkHostNetwork{}
+
+// NewHostNetwork returns a HostNetwork which configures the host by netlink and sysctl.
+func NewHostNetwork() HostNetwork {
+	return netlinkHostNetwork{}
+}
+
+func (netlinkHostNetwork) LinkByName(name string) (netlink.Link, error) {
+	return netlink.LinkByName(name)
+}
+
+func (netlinkHostNetwork) LinkByIndex(index int) (netlink.Link, error) {
+	return netlink.LinkByIndex(index)
+}
+
+func (netlinkHostNetwork) LinkList() ([]netlink.Link, error) {
+	return netlink.LinkList()
+}
+
+func (netlinkHostNetwork) LinkSetUp(link netlink.Link) error {
+	return netlink.LinkSetUp(link)
+}
+
+func (netlinkHostNetwork) LinkSetHardwareAddr(link netlink.Link, hwaddr net.HardwareAddr) error {
+	return netlink.LinkSetHardwareAddr(link, hwaddr)
+}
+
+func (netlinkHostNetwork) LinkSetMaster(link, master netlink.Link) error {
+	return netlink.LinkSetMaster(link, master)
+}
+
+func (netlinkHostNetwork) AddrList(link netlink.Link, family int) ([]netlink.Addr, error) {
+	return netlink.AddrList(link, family)
+}
+
+func (netlinkHostNetwork) AddrAdd(link netlink.Link, addr *netlink.Addr) error {
+	return netlink.AddrAdd(link, addr)
+}
+
+func (netlinkHostNetwork) AddrDel(link netlink.Link, addr *netlink.Addr) error {
+	return netlink.AddrDel(link, addr)
+}
+
+func (netlinkHostNetwork) RouteReplace(route *netlink.Route) error {
+	return netlink.RouteReplace(route)
+}
+
+func (netlinkHostNetwork) NeighAdd(neigh *netlink.Neigh) error {
+	return netlink.NeighAdd(neigh)
+}
+
+func (netlinkHostNetwork) NeighSet(neigh *netlink.Neigh) error {
+	return netlink.NeighSet(neigh)
+}
+
+func (netlinkHostNetwork) NeighList(linkIndex, family int) ([]netlink.Neigh, error) {
+	return netlink.NeighList(linkIndex, family)
+}
+
+func (netlinkHostNetwork) SetSysctl(sysctlPath string, newVal int) error {
+	return daemonutils.SetSysctl(sysctlPath, newVal)
+}
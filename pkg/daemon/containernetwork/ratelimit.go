@@ -0,0 +1,153 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package containernetwork
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// Every ARP/ND packet is accounted as a cell of fixed size by police action, so that
+	// byte rate of police can be used as packet rate. Cell size must be larger than the
+	// largest ARP/ND packet.
+	neighborRateLimitCellSize = 128
+
+	arpRateLimitFilterPriority = 1
+	ndRateLimitFilterPriority  = 2
+
+	arpRateLimitFilterHandle = 1
+	// 800::800, a node of default hash table of u32 filter
+	ndRateLimitFilterHandle = 0x80000800
+
+	icmpv6TypeNeighborSolicitation = 135
+)
+
+// EnsureNeighborRateLimit polices ARP packets and IPv6 neighbor solicitations which are sent
+// by pod through host veth, packets over limit will be dropped
+func EnsureNeighborRateLimit(hostIfName string, packetsPerSecond, burst uint32) error {
+	link, err := netlink.LinkByName(hostIfName)
+	if err != nil {
+		return fmt.Errorf("failed to get host veth %v: %v", hostIfName, err)
+	}
+
+	if err = ensureIngressQdisc(link); err != nil {
+		return fmt.Errorf("failed to ensure ingress qdisc of host veth %v: %v", hostIfName, err)
+	}
+
+	arpFilter := &netlink.MatchAll{
+		FilterAttrs: neighborRateLimitFilterAttrs(link, arpRateLimitFilterPriority, unix.ETH_P_ARP, arpRateLimitFilterHandle),
+		Actions:     []netlink.Action{newNeighborPoliceAction(packetsPerSecond, burst)},
+	}
+	if err = netlink.FilterReplace(arpFilter); err != nil {
+		return fmt.Errorf("failed to replace arp rate limit filter of host veth %v: %v", hostIfName, err)
+	}
+
+	// Extension headers of IPv6 are not considered, neighbor solicitations never have them.
+	ndFilter := &netlink.U32{
+		FilterAttrs: neighborRateLimitFilterAttrs(link, ndRateLimitFilterPriority, unix.ETH_P_IPV6, ndRateLimitFilterHandle),
+		Sel: &netlink.TcU32Sel{
+			Flags: netlink.TC_U32_TERMINAL,
+			Keys: []netlink.TcU32Key{
+				// next header is ICMPv6
+				{Mask: 0x0000ff00, Val: unix.IPPROTO_ICMPV6 << 8, Off: 4},
+				// ICMPv6 type is neighbor solicitation
+				{Mask: 0xff000000, Val: icmpv6TypeNeighborSolicitation << 24, Off: 40},
+			},
+		},
+		Actions: []netlink.Action{newNeighborPoliceAction(packetsPerSecond, burst)},
+	}
+	if err = netlink.FilterReplace(ndFilter); err != nil {
+		return fmt.Errorf("failed to replace nd rate limit filter of host veth %v: %v", hostIfName, err)
+	}
+
+	return nil
+}
+
+// CleanNeighborRateLimit removes the ARP/ND rate limit filters of host veth if they exist
+func CleanNeighborRateLimit(hostIfName string) error {
+	link, err := netlink.LinkByName(hostIfName)
+	if err != nil {
+		return fmt.Errorf("failed to get host veth %v: %v", hostIfName, err)
+	}
+
+	filters, err := netlink.FilterList(link, netlink.HANDLE_INGRESS)
+	if err != nil {
+		// no ingress qdisc, nothing to clean
+		if errors.Is(err, unix.EINVAL) || errors.Is(err, unix.ENOENT) {
+			return nil
+		}
+		return fmt.Errorf("failed to list ingress filters of host veth %v: %v", hostIfName, err)
+	}
+
+	for _, filter := range filters {
+		attrs := filter.Attrs()
+		if !(attrs.Priority == arpRateLimitFilterPriority && attrs.Protocol == unix.ETH_P_ARP) &&
+			!(attrs.Priority == ndRateLimitFilterPriority && attrs.Protocol == unix.ETH_P_IPV6) {
+			continue
+		}
+
+		if err = netlink.FilterDel(filter); err != nil && !errors.Is(err, unix.ENOENT) {
+			return fmt.Errorf("failed to delete rate limit filter %v of host veth %v: %v", attrs, hostIfName, err)
+		}
+	}
+
+	return nil
+}
+
+func ensureIngressQdisc(link netlink.Link) error {
+	qdiscs, err := netlink.QdiscList(link)
+	if err != nil {
+		return fmt.Errorf("failed to list qdisc: %v", err)
+	}
+
+	for _, qdisc := range qdiscs {
+		if qdisc.Attrs().Parent == netlink.HANDLE_INGRESS {
+			return nil
+		}
+	}
+
+	return netlink.QdiscAdd(&netlink.Ingress{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: link.Attrs().Index,
+			Handle:    netlink.MakeHandle(0xffff, 0),
+			Parent:    netlink.HANDLE_INGRESS,
+		},
+	})
+}
+
+func neighborRateLimitFilterAttrs(link netlink.Link, priority, protocol uint16, handle uint32) netlink.FilterAttrs {
+	return netlink.FilterAttrs{
+		LinkIndex: link.Attrs().Index,
+		Parent:    netlink.HANDLE_INGRESS,
+		Priority:  priority,
+		Protocol:  protocol,
+		Handle:    handle,
+	}
+}
+
+func newNeighborPoliceAction(packetsPerSecond, burst uint32) *netlink.PoliceAction {
+	police := netlink.NewPoliceAction()
+	police.Rate = packetsPerSecond * neighborRateLimitCellSize
+	police.Burst = burst * neighborRateLimitCellSize
+	police.Mpu = neighborRateLimitCellSize
+	police.ExceedAction = netlink.TC_POLICE_SHOT
+	return police
+}
@@ -0,0 +1,192 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/daemon/chaos"
+)
+
+// chaosRuleReconciler injects the faults of ChaosRules selecting this node, it only runs
+// with DataplaneChaos feature gate enabled.
+type chaosRuleReconciler struct {
+	client.Client
+	ctrlHubRef *CtrlHub
+}
+
+func (r *chaosRuleReconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	logger := log.FromContext(ctx)
+
+	chaosRuleList := &networkingv1.ChaosRuleList{}
+	if err := r.List(ctx, chaosRuleList); err != nil {
+		return reconcile.Result{Requeue: true}, fmt.Errorf("failed to list chaos rule: %v", err)
+	}
+
+	// Node objects are not supposed to be in list/watch cache.
+	thisNode := &corev1.Node{}
+	if err := r.ctrlHubRef.mgr.GetAPIReader().Get(ctx, types.NamespacedName{
+		Name: r.ctrlHubRef.config.NodeName,
+	}, thisNode); err != nil {
+		return reconcile.Result{Requeue: true}, fmt.Errorf("failed to get node object %v: %v",
+			r.ctrlHubRef.config.NodeName, err)
+	}
+
+	// the first delay by name applies if multiple rules delay tunnel traffic
+	sort.Slice(chaosRuleList.Items, func(i, j int) bool {
+		return chaosRuleList.Items[i].Name < chaosRuleList.Items[j].Name
+	})
+
+	now := time.Now()
+	faults := &chaos.Faults{}
+	var nextExpiration time.Duration
+
+	for i := range chaosRuleList.Items {
+		rule := &chaosRuleList.Items[i]
+		if networkingv1.IsChaosRuleExpired(rule, now) {
+			continue
+		}
+
+		if rule.Spec.NodeSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(rule.Spec.NodeSelector)
+			if err != nil {
+				logger.Error(err, "ignore chaos rule with invalid node selector", "chaos-rule", rule.Name)
+				continue
+			}
+			if !selector.Matches(labels.Set(thisNode.Labels)) {
+				continue
+			}
+		}
+
+		if err := networkingv1.ValidateChaosRuleSpec(&rule.Spec); err != nil {
+			logger.Error(err, "ignore invalid chaos rule", "chaos-rule", rule.Name)
+			continue
+		}
+
+		if rule.Spec.ExpireTime != nil {
+			if untilExpired := rule.Spec.ExpireTime.Sub(now); nextExpiration == 0 || untilExpired < nextExpiration {
+				nextExpiration = untilExpired
+			}
+		}
+
+		switch rule.Spec.Action {
+		case networkingv1.ChaosActionDrop:
+			_, destination, _ := net.ParseCIDR(rule.Spec.Drop.Destination)
+			faults.Drops = append(faults.Drops, chaos.Drop{
+				Destination: destination,
+				Percent:     rule.Spec.Drop.Percent,
+			})
+		case networkingv1.ChaosActionDelay:
+			if faults.Delay != nil {
+				logger.Info("ignore delay of chaos rule, tunnel traffic has been delayed by another one",
+					"chaos-rule", rule.Name)
+				continue
+			}
+			faults.Delay = &chaos.Delay{
+				Latency: time.Duration(rule.Spec.Delay.LatencyMilliseconds) * time.Millisecond,
+				Jitter:  time.Duration(rule.Spec.Delay.JitterMilliseconds) * time.Millisecond,
+			}
+		case networkingv1.ChaosActionBlackholeVTEP:
+			vtepIP, err := r.resolveVtepIP(ctx, rule.Spec.BlackholeVTEP)
+			if err != nil {
+				return reconcile.Result{Requeue: true}, fmt.Errorf("failed to resolve vtep ip of chaos rule %v: %v",
+					rule.Name, err)
+			}
+			if vtepIP == nil {
+				logger.Info("ignore chaos rule of node without vtep", "chaos-rule", rule.Name,
+					"node", rule.Spec.BlackholeVTEP.NodeName)
+				continue
+			}
+			faults.BlackholeVTEPs = append(faults.BlackholeVTEPs, vtepIP)
+		}
+	}
+
+	if err := r.ctrlHubRef.chaosManager.Sync(faults); err != nil {
+		return reconcile.Result{Requeue: true}, fmt.Errorf("failed to sync chaos faults: %v", err)
+	}
+
+	// faults are removed when the earliest rule expires
+	return reconcile.Result{RequeueAfter: nextExpiration}, nil
+}
+
+// resolveVtepIP returns nil if the node does not exist or is not a vtep
+func (r *chaosRuleReconciler) resolveVtepIP(ctx context.Context, blackhole *networkingv1.ChaosBlackholeVTEP) (net.IP, error) {
+	if len(blackhole.VTEPIP) != 0 {
+		return net.ParseIP(blackhole.VTEPIP), nil
+	}
+
+	nodeInfo := &networkingv1.NodeInfo{}
+	if err := r.Get(ctx, types.NamespacedName{Name: blackhole.NodeName}, nodeInfo); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get node info %v: %v", blackhole.NodeName, err)
+	}
+
+	if nodeInfo.Spec.VTEPInfo == nil {
+		return nil, nil
+	}
+	return net.ParseIP(nodeInfo.Spec.VTEPInfo.IP), nil
+}
+
+func (r *chaosRuleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	chaosRuleController, err := controller.New("chaos-rule", mgr, controller.Options{
+		Reconciler:   r,
+		RecoverPanic: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create chaos rule controller: %v", err)
+	}
+
+	if err := chaosRuleController.Watch(&source.Kind{Type: &networkingv1.ChaosRule{}},
+		&fixedKeyHandler{key: "ForChaosRuleChange"},
+		predicate.GenerationChangedPredicate{}); err != nil {
+		return fmt.Errorf("failed to watch networkingv1.ChaosRule for chaos rule controller: %v", err)
+	}
+
+	if err := chaosRuleController.Watch(&source.Kind{Type: &networkingv1.NodeInfo{}},
+		&fixedKeyHandler{key: "ForNodeInfoChange"},
+		predicate.GenerationChangedPredicate{}); err != nil {
+		return fmt.Errorf("failed to watch networkingv1.NodeInfo for chaos rule controller: %v", err)
+	}
+
+	// netem qdiscs are lost if vxlan devices are recreated
+	if err := chaosRuleController.Watch(r.ctrlHubRef.chaosRuleTriggerSourceForHostLink, &handler.Funcs{}); err != nil {
+		return fmt.Errorf("failed to watch chaosRuleTriggerSourceForHostLink for chaos rule controller: %v", err)
+	}
+
+	return nil
+}
@@ -44,22 +44,30 @@ import (
 	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
 	"github.com/alibaba/hybridnet/pkg/constants"
 	"github.com/alibaba/hybridnet/pkg/daemon/addr"
+	"github.com/alibaba/hybridnet/pkg/daemon/chaos"
 	daemonconfig "github.com/alibaba/hybridnet/pkg/daemon/config"
+	"github.com/alibaba/hybridnet/pkg/daemon/containernetwork"
 	"github.com/alibaba/hybridnet/pkg/daemon/iptables"
 	"github.com/alibaba/hybridnet/pkg/daemon/neigh"
 	"github.com/alibaba/hybridnet/pkg/daemon/route"
+	"github.com/alibaba/hybridnet/pkg/daemon/startup"
 	"github.com/alibaba/hybridnet/pkg/feature"
+	"github.com/alibaba/hybridnet/pkg/metrics"
 )
 
 const (
-	InstanceIPIndex = "instanceIP"
-	EndpointIPIndex = "endpointIP"
+	InstanceIPIndex        = "instanceIP"
+	InstanceNetworkIPIndex = "instanceNetworkIP"
+	EndpointIPIndex        = "endpointIP"
 
 	NeighUpdateChanSize = 2000
 	LinkUpdateChainSize = 200
 	AddrUpdateChainSize = 200
 
 	NetlinkSubscribeRetryInterval = 10 * time.Second
+
+	// StartupReportPath is the path on healthy server which reports the progress of startup stages
+	StartupReportPath = "/startup"
 )
 
 type CtrlHub struct {
@@ -70,66 +78,39 @@ type CtrlHub struct {
 	subnetTriggerSourceForNodeInfoChange *simpleTriggerSource
 	ipInstanceTriggerSourceForHostLink   *simpleTriggerSource
 	nodeInfoTriggerSourceForHostAddr     *simpleTriggerSource
+	chaosRuleTriggerSourceForHostLink    *simpleTriggerSource
 
-	routeV4Manager *route.Manager
-	routeV6Manager *route.Manager
+	routeV4Manager route.Interface
+	routeV6Manager route.Interface
 
-	neighV4Manager *neigh.Manager
-	neighV6Manager *neigh.Manager
+	neighV4Manager neigh.Interface
+	neighV6Manager neigh.Interface
 
-	addrV4Manager *addr.Manager
+	addrV4Manager addr.Interface
 
 	bgpManager *bgp.Manager
 
-	iptablesV4Manager  *iptables.Manager
-	iptablesV6Manager  *iptables.Manager
+	// hostNetwork is the netlink layer which links, addresses and neighs of host are read and changed by
+	hostNetwork containernetwork.HostNetwork
+
+	chaosManager *chaos.Manager
+
+	iptablesV4Manager  iptables.Interface
+	iptablesV6Manager  iptables.Interface
 	iptablesSyncCh     chan struct{}
 	iptablesSyncTicker *time.Ticker
 
+	vxlanNeighClearTicker *time.Ticker
+
 	nodeIPCache *NodeIPCache
 
+	// startup runs the steps of initialization concurrently and reports their progress
+	startup *startup.Runner
+
 	logger logr.Logger
 }
 
 func NewCtrlHub(config *daemonconfig.Configuration, mgr ctrl.Manager, logger logr.Logger) (*CtrlHub, error) {
-	routeV4Manager, err := route.CreateRouteManager(config.LocalDirectTableNum,
-		config.ToOverlaySubnetTableNum,
-		config.OverlayMarkTableNum,
-		netlink.FAMILY_V4,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create ipv4 route manager: %v", err)
-	}
-
-	routeV6Manager, err := route.CreateRouteManager(config.LocalDirectTableNum,
-		config.ToOverlaySubnetTableNum,
-		config.OverlayMarkTableNum,
-		netlink.FAMILY_V6,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create ipv6 route manager: %v", err)
-	}
-
-	neighV4Manager := neigh.CreateNeighManager(netlink.FAMILY_V4)
-	neighV6Manager := neigh.CreateNeighManager(netlink.FAMILY_V6)
-
-	iptablesV4Manager, err := iptables.CreateIPtablesManager(iptables.ProtocolIpv4)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create ipv4 iptables manager: %v", err)
-	}
-
-	iptablesV6Manager, err := iptables.CreateIPtablesManager(iptables.ProtocolIpv6)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create ipv6 iptables manager: %v", err)
-	}
-
-	addrV4Manager := addr.CreateAddrManager(netlink.FAMILY_V4, config.NodeName)
-
-	bgpManager, err := bgp.NewManager(config.NodeBGPIfName, config.BGPgRPCServerAddress, logger.WithName("bgp-server"))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create bgp manager: %v", err)
-	}
-
 	ctrlHub := &CtrlHub{
 		config: config,
 		mgr:    mgr,
@@ -138,77 +119,139 @@ func NewCtrlHub(config *daemonconfig.Configuration, mgr ctrl.Manager, logger log
 		subnetTriggerSourceForNodeInfoChange: &simpleTriggerSource{key: "ForNodeInfo"},
 		ipInstanceTriggerSourceForHostLink:   &simpleTriggerSource{key: "ForHostLinkEvent"},
 		nodeInfoTriggerSourceForHostAddr:     &simpleTriggerSource{key: "ForHostAddr"},
+		chaosRuleTriggerSourceForHostLink:    &simpleTriggerSource{key: "ForHostLinkEvent"},
 
-		routeV4Manager: routeV4Manager,
-		routeV6Manager: routeV6Manager,
-
-		neighV4Manager: neighV4Manager,
-		neighV6Manager: neighV6Manager,
-
-		addrV4Manager: addrV4Manager,
-
-		bgpManager: bgpManager,
-
-		iptablesV4Manager:  iptablesV4Manager,
-		iptablesV6Manager:  iptablesV6Manager,
 		iptablesSyncCh:     make(chan struct{}, 1),
 		iptablesSyncTicker: time.NewTicker(config.IptablesCheckDuration),
 
-		nodeIPCache: NewNodeIPCache(),
+		vxlanNeighClearTicker: time.NewTicker(config.VxlanExpiredNeighCachesClearInterval),
 
-		logger: logger,
-	}
-
-	thisNode := &corev1.Node{}
-	if err = mgr.GetAPIReader().Get(context.TODO(), types.NamespacedName{Name: config.NodeName}, thisNode); err != nil {
-		return nil, fmt.Errorf("failed to get node %s info %v", config.NodeName, err)
-	}
+		nodeIPCache: NewNodeIPCache(),
 
-	return ctrlHub, nil
-}
+		hostNetwork: containernetwork.NewHostNetwork(),
 
-func (c *CtrlHub) Run(ctx context.Context) error {
-	c.runHealthyServer()
+		startup: startup.NewRunner(logger.WithName("startup")),
 
-	if err := c.mgr.GetFieldIndexer().IndexField(context.TODO(), &networkingv1.IPInstance{},
-		InstanceIPIndex, instanceIPIndexer); err != nil {
-		return fmt.Errorf("failed to add instance ip indexer to manager: %v", err)
+		logger: logger,
 	}
 
-	if feature.MultiClusterEnabled() {
-		if err := c.mgr.GetFieldIndexer().IndexField(context.TODO(), &multiclusterv1.RemoteVtep{},
-			EndpointIPIndex, endpointIPIndexer); err != nil {
-			return fmt.Errorf("failed to add endpoint ip indexer to manager: %v", err)
-		}
-	}
+	// progress of startup is reported by the healthy server
+	ctrlHub.runHealthyServer()
 
-	if err := (&subnetReconciler{
-		Client:     c.mgr.GetClient(),
-		ctrlHubRef: c,
-	}).SetupWithManager(c.mgr); err != nil {
-		return fmt.Errorf("failed to setup subnet controller: %v", err)
+	stages := []startup.Stage{
+		{
+			Name: "node",
+			Run: func(ctx context.Context) error {
+				thisNode := &corev1.Node{}
+				if err := mgr.GetAPIReader().Get(ctx, types.NamespacedName{Name: config.NodeName}, thisNode); err != nil {
+					return fmt.Errorf("failed to get node %s info %v", config.NodeName, err)
+				}
+				return nil
+			},
+		},
 	}
 
-	if err := (&ipInstanceReconciler{
-		Client:     c.mgr.GetClient(),
-		ctrlHubRef: c,
-	}).SetupWithManager(c.mgr); err != nil {
-		return fmt.Errorf("failed to setup ip instance controller: %v", err)
+	if config.DryDataplane {
+		ctrlHub.initDryDataplaneManagers()
+	} else {
+		stages = append(stages, ctrlHub.dataplaneManagerStages()...)
 	}
 
-	if err := (&nodeInfoReconciler{
-		Client:     c.mgr.GetClient(),
-		ctrlHubRef: c,
-	}).SetupWithManager(c.mgr); err != nil {
-		return fmt.Errorf("failed to setup node controller: %v", err)
+	if err := ctrlHub.startup.Run(context.TODO(), stages...); err != nil {
+		return nil, err
 	}
 
-	if err := c.handleLocalNetworkDeviceEvent(); err != nil {
-		return fmt.Errorf("failed to handle local network device event: %v", err)
+	return ctrlHub, nil
+}
+
+// dataplaneManagerStages create the managers of host dataplane, which are independent of each
+// other and created concurrently, so that daemon gets ready soon on nodes with many interfaces
+func (c *CtrlHub) dataplaneManagerStages() []startup.Stage {
+	return []startup.Stage{
+		{
+			Name: "route-v4-manager",
+			Run: func(ctx context.Context) (err error) {
+				if c.routeV4Manager, err = route.CreateRouteManager(c.config.LocalDirectTableNum,
+					c.config.ToOverlaySubnetTableNum,
+					c.config.OverlayMarkTableNum,
+					netlink.FAMILY_V4,
+				); err != nil {
+					return fmt.Errorf("failed to create ipv4 route manager: %v", err)
+				}
+				return nil
+			},
+		},
+		{
+			Name: "route-v6-manager",
+			Run: func(ctx context.Context) (err error) {
+				if c.routeV6Manager, err = route.CreateRouteManager(c.config.LocalDirectTableNum,
+					c.config.ToOverlaySubnetTableNum,
+					c.config.OverlayMarkTableNum,
+					netlink.FAMILY_V6,
+				); err != nil {
+					return fmt.Errorf("failed to create ipv6 route manager: %v", err)
+				}
+				return nil
+			},
+		},
+		{
+			Name: "neigh-managers",
+			Run: func(ctx context.Context) error {
+				c.neighV4Manager = neigh.CreateNeighManager(netlink.FAMILY_V4)
+				c.neighV6Manager = neigh.CreateNeighManager(netlink.FAMILY_V6)
+				return nil
+			},
+		},
+		{
+			Name: "iptables-v4-manager",
+			Run: func(ctx context.Context) (err error) {
+				if c.iptablesV4Manager, err = iptables.CreateIPtablesManager(iptables.ProtocolIpv4); err != nil {
+					return fmt.Errorf("failed to create ipv4 iptables manager: %v", err)
+				}
+				return nil
+			},
+		},
+		{
+			Name: "iptables-v6-manager",
+			Run: func(ctx context.Context) (err error) {
+				if c.iptablesV6Manager, err = iptables.CreateIPtablesManager(iptables.ProtocolIpv6); err != nil {
+					return fmt.Errorf("failed to create ipv6 iptables manager: %v", err)
+				}
+				return nil
+			},
+		},
+		{
+			Name: "addr-manager",
+			Run: func(ctx context.Context) error {
+				c.addrV4Manager = addr.CreateAddrManager(netlink.FAMILY_V4, c.config.NodeName)
+				return nil
+			},
+		},
+		{
+			Name: "bgp-manager",
+			Run: func(ctx context.Context) (err error) {
+				if c.bgpManager, err = bgp.NewManager(c.config.NodeBGPIfName, c.config.BGPgRPCServerAddress,
+					c.logger.WithName("bgp-server")); err != nil {
+					return fmt.Errorf("failed to create bgp manager: %v", err)
+				}
+				return nil
+			},
+		},
+		{
+			Name: "chaos-manager",
+			Run: func(ctx context.Context) (err error) {
+				if c.chaosManager, err = chaos.NewManager(c.config.VxlanUDPPort); err != nil {
+					return fmt.Errorf("failed to create chaos manager: %v", err)
+				}
+				return nil
+			},
+		},
 	}
+}
 
-	if err := c.handleVxlanInterfaceNeighEvent(); err != nil {
-		return fmt.Errorf("failed to handle vxlan interface neigh event: %v", err)
+func (c *CtrlHub) Run(ctx context.Context) error {
+	if err := c.startup.Run(ctx, c.controllerStages()...); err != nil {
+		return err
 	}
 
 	c.iptablesSyncLoop()
@@ -219,6 +262,144 @@ func (c *CtrlHub) Run(ctx context.Context) error {
 	return nil
 }
 
+// controllerStages set up controllers and event handlers, a stage depends on another if it reads
+// the indexes or triggers the controllers set up by that one
+func (c *CtrlHub) controllerStages() []startup.Stage {
+	stages := []startup.Stage{
+		{
+			Name: "indexers",
+			Run: func(ctx context.Context) error {
+				if err := c.mgr.GetFieldIndexer().IndexField(ctx, &networkingv1.IPInstance{},
+					InstanceIPIndex, instanceIPIndexer); err != nil {
+					return fmt.Errorf("failed to add instance ip indexer to manager: %v", err)
+				}
+
+				if err := c.mgr.GetFieldIndexer().IndexField(ctx, &networkingv1.IPInstance{},
+					InstanceNetworkIPIndex, instanceNetworkIPIndexer); err != nil {
+					return fmt.Errorf("failed to add instance network ip indexer to manager: %v", err)
+				}
+
+				if feature.MultiClusterEnabled() {
+					if err := c.mgr.GetFieldIndexer().IndexField(ctx, &multiclusterv1.RemoteVtep{},
+						EndpointIPIndex, endpointIPIndexer); err != nil {
+						return fmt.Errorf("failed to add endpoint ip indexer to manager: %v", err)
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Name: "subnet-controller",
+			Run: func(ctx context.Context) error {
+				if err := (&subnetReconciler{
+					Client:     c.mgr.GetClient(),
+					ctrlHubRef: c,
+				}).SetupWithManager(c.mgr); err != nil {
+					return fmt.Errorf("failed to setup subnet controller: %v", err)
+				}
+				return nil
+			},
+		},
+		{
+			Name: "ip-instance-controller",
+			Run: func(ctx context.Context) error {
+				if err := (&ipInstanceReconciler{
+					Client:     c.mgr.GetClient(),
+					ctrlHubRef: c,
+				}).SetupWithManager(c.mgr); err != nil {
+					return fmt.Errorf("failed to setup ip instance controller: %v", err)
+				}
+				return nil
+			},
+		},
+		{
+			Name: "pod-annotation-controller",
+			Run: func(ctx context.Context) error {
+				if err := (&podAnnotationReconciler{
+					Client:     c.mgr.GetClient(),
+					ctrlHubRef: c,
+				}).SetupWithManager(c.mgr); err != nil {
+					return fmt.Errorf("failed to setup pod annotation controller: %v", err)
+				}
+				return nil
+			},
+		},
+	}
+
+	// vxlan device, host links and neighs are never watched or changed in a dry dataplane
+	if c.config.DryDataplane {
+		return stages
+	}
+
+	return append(stages,
+		startup.Stage{
+			Name: "node-info-controller",
+			Run: func(ctx context.Context) error {
+				if err := (&nodeInfoReconciler{
+					Client:     c.mgr.GetClient(),
+					ctrlHubRef: c,
+				}).SetupWithManager(c.mgr); err != nil {
+					return fmt.Errorf("failed to setup node controller: %v", err)
+				}
+				return nil
+			},
+		},
+		startup.Stage{
+			Name: "chaos-rule-controller",
+			Run: func(ctx context.Context) error {
+				if !feature.DataplaneChaosEnabled() {
+					// faults injected before the feature gate is disabled should never be left
+					if err := c.chaosManager.Sync(&chaos.Faults{}); err != nil {
+						return fmt.Errorf("failed to clean chaos faults: %v", err)
+					}
+					return nil
+				}
+
+				if err := (&chaosRuleReconciler{
+					Client:     c.mgr.GetClient(),
+					ctrlHubRef: c,
+				}).SetupWithManager(c.mgr); err != nil {
+					return fmt.Errorf("failed to setup chaos rule controller: %v", err)
+				}
+				return nil
+			},
+		},
+		startup.Stage{
+			Name:      "host-device-events",
+			DependsOn: []string{"subnet-controller", "ip-instance-controller", "node-info-controller", "chaos-rule-controller"},
+			Run: func(ctx context.Context) error {
+				if err := c.handleLocalNetworkDeviceEvent(); err != nil {
+					return fmt.Errorf("failed to handle local network device event: %v", err)
+				}
+				return nil
+			},
+		},
+		startup.Stage{
+			Name:      "vxlan-neigh-controller",
+			DependsOn: []string{"indexers"},
+			Run: func(ctx context.Context) error {
+				if err := (&vxlanNeighReconciler{
+					Client:     c.mgr.GetClient(),
+					ctrlHubRef: c,
+				}).SetupWithManager(c.mgr); err != nil {
+					return fmt.Errorf("failed to setup vxlan neigh controller: %v", err)
+				}
+				return nil
+			},
+		},
+		startup.Stage{
+			Name:      "vxlan-neigh-events",
+			DependsOn: []string{"indexers"},
+			Run: func(ctx context.Context) error {
+				if err := c.handleVxlanInterfaceNeighEvent(); err != nil {
+					return fmt.Errorf("failed to handle vxlan interface neigh event: %v", err)
+				}
+				return nil
+			},
+		},
+	)
+}
+
 func (c *CtrlHub) CacheSynced(ctx context.Context) bool {
 	return c.mgr.GetCache().WaitForCacheSync(ctx)
 }
@@ -235,6 +416,12 @@ func (c *CtrlHub) GetBGPManager() *bgp.Manager {
 	return c.bgpManager
 }
 
+// ApplyReloadableConfiguration changes intervals of periodic syncs at runtime
+func (c *CtrlHub) ApplyReloadableConfiguration(reloadable *daemonconfig.ReloadableConfiguration) {
+	c.iptablesSyncTicker.Reset(reloadable.IptablesCheckDuration)
+	c.vxlanNeighClearTicker.Reset(reloadable.VxlanExpiredNeighCachesClearInterval)
+}
+
 // Once node network interface is set from down to up for some reasons, the routes and neigh caches for this interface
 // will be cleaned, which should cause unrecoverable problems. Listening "UP" netlink events for interfaces and
 // triggering subnet and ip instance reconcile loop will be the best way to recover routes and neigh caches.
@@ -275,6 +462,7 @@ func (c *CtrlHub) handleLocalNetworkDeviceEvent() error {
 						// Create event to flush routes and neigh caches.
 						c.subnetTriggerSourceForHostLink.Trigger()
 						c.ipInstanceTriggerSourceForHostLink.Trigger()
+						c.chaosRuleTriggerSourceForHostLink.Trigger()
 					}
 				case <-exitCh:
 					break linkLoop
@@ -306,7 +494,7 @@ func (c *CtrlHub) handleLocalNetworkDeviceEvent() error {
 			for {
 				select {
 				case update := <-addrCh:
-					link, err := netlink.LinkByIndex(update.LinkIndex)
+					link, err := c.hostNetwork.LinkByIndex(update.LinkIndex)
 					if err != nil {
 						c.logger.Error(err, "failed to get link by addr update event link index", "addr",
 							update.LinkAddress, "link index", update.LinkIndex)
@@ -331,48 +519,14 @@ func (c *CtrlHub) handleLocalNetworkDeviceEvent() error {
 func (c *CtrlHub) handleVxlanInterfaceNeighEvent() error {
 
 	ipSearch := func(ip net.IP, link netlink.Link) error {
-		var vtepMac net.HardwareAddr
-
-		if mac, exist := c.nodeIPCache.SearchIP(ip); exist {
-			// find node ip.
-			vtepMac = mac
-		} else {
-			ipInstance, err := c.getIPInstanceByAddress(ip)
-			if err != nil {
-				return fmt.Errorf("failed to get ip instance by address %v: %v", ip.String(), err)
-			}
-
-			if ipInstance != nil {
-				nodeInfo := &networkingv1.NodeInfo{}
-				nodeName := ipInstance.Labels[constants.LabelNode]
-
-				if err := c.mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: nodeName}, nodeInfo); err != nil {
-					return fmt.Errorf("failed to get node %v: %v", nodeName, err)
-				}
-
-				if nodeInfo.Spec.VTEPInfo == nil {
-					return fmt.Errorf("node info of %v is nil", nodeName)
-				}
-
-				vtepMac, err = net.ParseMAC(nodeInfo.Spec.VTEPInfo.MAC)
-				if err != nil {
-					return fmt.Errorf("failed to parse vtep mac %v: %v",
-						nodeInfo.Spec.VTEPInfo.MAC, err)
-				}
-			} else if feature.MultiClusterEnabled() {
-				// try to find remote vtep according to pod ip
-				vtep, err := c.getRemoteVtepByEndpointAddress(ip)
-				if err != nil {
-					return fmt.Errorf("failed to get remote vtep by address %s: %v", ip.String(), err)
-				}
+		tenantNetwork, err := getTenantNetworkOfVxlanLink(context.TODO(), c.mgr.GetClient(), link)
+		if err != nil {
+			return fmt.Errorf("failed to get tenant network of link %v: %v", link.Attrs().Name, err)
+		}
 
-				if vtep != nil {
-					vtepMac, err = net.ParseMAC(vtep.Spec.VTEPInfo.MAC)
-					if err != nil {
-						return fmt.Errorf("failed to parse vtep mac %v: %v", vtep.Spec.VTEPInfo.MAC, err)
-					}
-				}
-			}
+		vtepMac, err := c.resolveVtepMac(ip, tenantNetwork)
+		if err != nil {
+			return err
 		}
 
 		if len(vtepMac) == 0 {
@@ -392,7 +546,7 @@ func (c *CtrlHub) handleVxlanInterfaceNeighEvent() error {
 			IP:           ip,
 			HardwareAddr: vtepMac,
 		}
-		if err := netlink.NeighSet(&neighEntry); err != nil {
+		if err := c.hostNetwork.NeighSet(&neighEntry); err != nil {
 			return fmt.Errorf("failed to set neigh %v: %v", neighEntry.String(), err)
 		}
 
@@ -413,7 +567,6 @@ func (c *CtrlHub) handleVxlanInterfaceNeighEvent() error {
 		return fmt.Errorf("failed to get root netns: %v", err)
 	}
 
-	timer := time.NewTicker(c.config.VxlanExpiredNeighCachesClearInterval)
 	errorMessageWrapper := initErrorMessageWrapper("failed to handle vxlan interface neigh event: ")
 
 	go func() {
@@ -421,7 +574,7 @@ func (c *CtrlHub) handleVxlanInterfaceNeighEvent() error {
 			// Clear stale and failed neigh entries for vxlan interface at the first time.
 			// Once neigh subscribe failed (include the goroutine exit), it's most probably because of
 			// "No buffer space available" problem, we need to clean expired neigh caches.
-			if err := clearVxlanExpiredNeighCaches(); err != nil {
+			if err := c.clearVxlanExpiredNeighCaches(); err != nil {
 				c.logger.Error(err, "failed to clear vxlan expired neigh caches")
 			}
 
@@ -451,7 +604,7 @@ func (c *CtrlHub) handleVxlanInterfaceNeighEvent() error {
 							continue
 						}
 
-						link, err := netlink.LinkByIndex(update.LinkIndex)
+						link, err := c.hostNetwork.LinkByIndex(update.LinkIndex)
 						if err != nil {
 							c.logger.Error(err, errorMessageWrapper("failed to get link by index %v", update.LinkIndex))
 							continue
@@ -461,9 +614,9 @@ func (c *CtrlHub) handleVxlanInterfaceNeighEvent() error {
 							go ipSearchExecWrapper(update.IP, link)
 						}
 					}
-				case <-timer.C:
-					if err := clearVxlanExpiredNeighCaches(); err != nil {
-						c.logger.Error(err, "failed to clear vxlan expired neigh caches")
+				case <-c.vxlanNeighClearTicker.C:
+					if err := c.gcVxlanNeighCaches(); err != nil {
+						c.logger.Error(err, "failed to gc vxlan neigh caches")
 					}
 				case <-exitCh:
 					break neighLoop
@@ -553,6 +706,12 @@ func (c *CtrlHub) iptablesSyncLoop() {
 			return fmt.Errorf("failed to list pod ip instances of node %v: %v", c.config.NodeName, err)
 		}
 
+		// dual stack pods have two ip instances but only one host veth
+		neighborRateLimitSynced := map[string]bool{}
+
+		// rp_filter values of host veths and vlan forward interfaces specified by networks
+		rpFilterValues := map[string]int{}
+
 		for _, ipInstance := range ipInstanceList.Items {
 			// skip reserved ip instance
 			if networkingv1.IsReserved(&ipInstance) {
@@ -574,6 +733,50 @@ func (c *CtrlHub) iptablesSyncLoop() {
 			} else {
 				c.iptablesV4Manager.RecordLocalPodIP(podIP)
 			}
+
+			// failure of one pod should not block iptables rules of other pods
+			egressRules, limited, err := c.getEgressRulesOfIPInstance(context.TODO(), &ipInstance)
+			if err != nil {
+				c.logger.Error(err, "failed to get egress rules, skip egress allowlist of pod", "ipInstance", ipInstance.Name)
+			} else if limited {
+				c.getIPtablesManager(ipInstance.Spec.Address.Version).RecordLocalPodEgressRules(podIP, egressRules)
+			}
+
+			network := &networkingv1.Network{}
+			if err := c.mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: ipInstance.Spec.Network}, network); err != nil {
+				return fmt.Errorf("failed to get network for ip instance %v: %v", ipInstance.Name, err)
+			}
+
+			hostIfName, _ := containernetwork.GenerateContainerVethPair(ipInstance.Namespace, ipInstance.Spec.Binding.PodName)
+
+			if networkingv1.IsMACSpoofProtectedNetwork(network) {
+				podMAC, err := net.ParseMAC(ipInstance.Spec.Address.MAC)
+				if err != nil {
+					return fmt.Errorf("parse mac %v of ip instance %v error: %v", ipInstance.Spec.Address.MAC, ipInstance.Name, err)
+				}
+
+				c.getIPtablesManager(ipInstance.Spec.Address.Version).RecordLocalPodMAC(hostIfName, podMAC)
+			}
+
+			guarded, err := c.ipSourceGuardEnabled(context.TODO(), &ipInstance, network)
+			if err != nil {
+				return fmt.Errorf("failed to check ip source guard of ip instance %v: %v", ipInstance.Name, err)
+			}
+
+			if guarded {
+				c.getIPtablesManager(ipInstance.Spec.Address.Version).RecordLocalPodSourceGuard(hostIfName, podIP)
+			}
+
+			rpFilterValues[hostIfName] = daemonutils.RpFilterValueOf(networkingv1.GetNetworkRPFilter(network))
+
+			if !neighborRateLimitSynced[hostIfName] && !c.config.DryDataplane {
+				neighborRateLimitSynced[hostIfName] = true
+				// failure of tc should not block iptables rules of other pods, and tc is
+				// never configured in a dry dataplane
+				if err := c.syncNeighborRateLimit(hostIfName, network); err != nil {
+					c.logger.Error(err, "failed to sync neighbor rate limit", "ipInstance", ipInstance.Name)
+				}
+			}
 		}
 
 		// Record local subnet cidr.
@@ -593,6 +796,11 @@ func (c *CtrlHub) iptablesSyncLoop() {
 				return fmt.Errorf("failed to get network for subnet %v", subnet.Name)
 			}
 
+			// subnets of tenant networks are isolated in vrf, and never nat outgoing
+			if networkingv1.IsTenantNetwork(network) {
+				continue
+			}
+
 			iptablesManager := c.getIPtablesManager(subnet.Spec.Range.Version)
 
 			// isLocal means whether this node belongs to this network
@@ -605,18 +813,57 @@ func (c *CtrlHub) iptablesSyncLoop() {
 					netID = network.Spec.NetID
 				}
 
-				vlanForwardIfName, err := daemonutils.GenerateVlanNetIfName(c.config.NodeVlanIfName, netID)
+				vlanNodeIfName := c.getVlanNodeIfName(&subnet.Spec)
+				vlanForwardIfName, err := daemonutils.GenerateVlanNetIfName(vlanNodeIfName, netID)
 				if err != nil {
-					c.logger.Error(err, "failed to generate vlan network interface name", "vlanMasterInterface", c.config.NodeVlanIfName, "netID", netID)
+					c.logger.Error(err, "failed to generate vlan network interface name", "vlanMasterInterface", vlanNodeIfName, "netID", netID)
 					continue
 				}
 
 				iptablesManager.RecordVlanForwardIfName(vlanForwardIfName)
+
+				// a forward interface shared by networks takes the max value, in the same way
+				// as kernel combines the values of "all" and interface
+				value := daemonutils.RpFilterValueOf(networkingv1.GetNetworkRPFilter(network))
+				if value > rpFilterValues[vlanForwardIfName] {
+					rpFilterValues[vlanForwardIfName] = value
+				}
 			}
 
 			iptablesManager.RecordSubnet(cidr,
 				networkingv1.GetNetworkType(network) == networkingv1.NetworkTypeOverlay,
 				isLocal)
+
+			// pods of subnet never run on this node if network is not local
+			if isLocal {
+				for _, flow := range networkingv1.GetSubnetNoTrackFlows(&subnet.Spec, network) {
+					_, dst, err := net.ParseCIDR(flow.Destination)
+					if err != nil {
+						c.logger.Error(err, "failed to parse notrack flow destination", "subnet", subnet.Name)
+						continue
+					}
+
+					var port int
+					if flow.Port != nil {
+						port = int(*flow.Port)
+					}
+
+					iptablesManager.RecordNoTrackFlow(iptables.NoTrackFlow{
+						Source:      cidr,
+						Destination: dst,
+						Protocol:    flow.Protocol,
+						Port:        port,
+					})
+				}
+			}
+		}
+
+		if !c.config.DryDataplane {
+			// failure of rp_filter should not block iptables rules, as the same as tc
+			daemonutils.ResetRpFilterOverrides(rpFilterValues)
+			if err := daemonutils.EnsureRpFilter(); err != nil {
+				c.logger.Error(err, "failed to ensure rp_filter of interfaces")
+			}
 		}
 
 		if feature.MultiClusterEnabled() {
@@ -676,7 +923,7 @@ func (c *CtrlHub) iptablesSyncLoop() {
 			return fmt.Errorf("failed to sync v4 iptables rule: %v", err)
 		}
 
-		globalDisabled, err := daemonutils.CheckIPv6GlobalDisabled()
+		globalDisabled, err := c.ipv6GlobalDisabled()
 		if err != nil {
 			return fmt.Errorf("failed to check ipv6 global disabled: %v", err)
 		}
@@ -713,9 +960,15 @@ func (c *CtrlHub) iptablesSyncTrigger() {
 
 func (c *CtrlHub) runHealthyServer() {
 	health := healthcheck.NewHandler()
+	// daemon is not ready until all the stages of startup succeed
+	health.AddReadinessCheck("startup", c.startup.Check)
+
+	mux := http.NewServeMux()
+	mux.Handle(StartupReportPath, c.startup.Handler())
+	mux.Handle("/", health)
 
 	go func() {
-		_ = http.ListenAndServe(c.config.HealthyServerAddress, health)
+		_ = http.ListenAndServe(c.config.HealthyServerAddress, mux)
 	}()
 
 	c.logger.Info("start healthy server", "bind-address", c.config.HealthyServerAddress)
@@ -739,6 +992,82 @@ func instanceIPIndexer(obj client.Object) []string {
 	return []string{}
 }
 
+// instanceNetworkIPIndexer indexes ip instances by network and address, because addresses of
+// tenant networks are only unique in their own networks
+func instanceNetworkIPIndexer(obj client.Object) []string {
+	instance, ok := obj.(*networkingv1.IPInstance)
+	if ok {
+		podIP, _, err := net.ParseCIDR(instance.Spec.Address.IP)
+		if err != nil {
+			return []string{}
+		}
+
+		return []string{instanceNetworkIPIndexValue(instance.Spec.Network, podIP.String())}
+	}
+	return []string{}
+}
+
+func instanceNetworkIPIndexValue(network, ip string) string {
+	return network + "/" + ip
+}
+
+// resolveVtepMac returns the mac address of the vtep which an overlay address lives behind,
+// the address may be a node ip, a pod ip, or an endpoint ip of remote clusters. Empty mac
+// address is returned if the address is unknown. If tenantNetwork is not empty, the address
+// is only looked up in pods of the tenant network, which are isolated from nodes and remote
+// clusters.
+func (c *CtrlHub) resolveVtepMac(ip net.IP, tenantNetwork string) (net.HardwareAddr, error) {
+	if len(tenantNetwork) == 0 {
+		if mac, exist := c.nodeIPCache.SearchIP(ip); exist {
+			// find node ip.
+			return mac, nil
+		}
+	}
+
+	ipInstance, err := getIPInstanceInAddressSpace(context.TODO(), c.mgr.GetClient(), ip, tenantNetwork)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ip instance by address %v: %v", ip.String(), err)
+	}
+
+	if ipInstance != nil {
+		nodeInfo := &networkingv1.NodeInfo{}
+		nodeName := ipInstance.Labels[constants.LabelNode]
+
+		if err := c.mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: nodeName}, nodeInfo); err != nil {
+			return nil, fmt.Errorf("failed to get node %v: %v", nodeName, err)
+		}
+
+		if nodeInfo.Spec.VTEPInfo == nil {
+			return nil, fmt.Errorf("node info of %v is nil", nodeName)
+		}
+
+		vtepMac, err := net.ParseMAC(nodeInfo.Spec.VTEPInfo.MAC)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse vtep mac %v: %v",
+				nodeInfo.Spec.VTEPInfo.MAC, err)
+		}
+		return vtepMac, nil
+	}
+
+	if feature.MultiClusterEnabled() && len(tenantNetwork) == 0 {
+		// try to find remote vtep according to pod ip
+		vtep, err := c.getRemoteVtepByEndpointAddress(ip)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get remote vtep by address %s: %v", ip.String(), err)
+		}
+
+		if vtep != nil {
+			vtepMac, err := net.ParseMAC(vtep.Spec.VTEPInfo.MAC)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse vtep mac %v: %v", vtep.Spec.VTEPInfo.MAC, err)
+			}
+			return vtepMac, nil
+		}
+	}
+
+	return nil, nil
+}
+
 func endpointIPIndexer(obj client.Object) []string {
 	vtep, ok := obj.(*multiclusterv1.RemoteVtep)
 	if ok {
@@ -750,8 +1079,8 @@ func endpointIPIndexer(obj client.Object) []string {
 	return []string{}
 }
 
-func clearVxlanExpiredNeighCaches() error {
-	linkList, err := netlink.LinkList()
+func (c *CtrlHub) clearVxlanExpiredNeighCaches() error {
+	linkList, err := c.hostNetwork.LinkList()
 	if err != nil {
 		return fmt.Errorf("failed to list link: %v", err)
 	}
@@ -779,3 +1108,69 @@ func clearVxlanExpiredNeighCaches() error {
 
 	return nil
 }
+
+// gcVxlanNeighCaches clears expired neigh caches on vxlan interfaces, then deletes neigh entries of
+// deleted pods and corrects entries of moved pods which are missed by vxlan neigh controller, e.g.,
+// pods deleted while daemon is down. Sizes of neigh and fdb tables are exported as metrics.
+func (c *CtrlHub) gcVxlanNeighCaches() error {
+	if err := c.clearVxlanExpiredNeighCaches(); err != nil {
+		return err
+	}
+
+	// every entry looks unknown before caches are synced
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if !c.CacheSynced(ctx) {
+		return nil
+	}
+
+	linkList, err := c.hostNetwork.LinkList()
+	if err != nil {
+		return fmt.Errorf("failed to list link: %v", err)
+	}
+
+	metrics.VxlanNeighEntryGauge.Reset()
+	metrics.VxlanFdbEntryGauge.Reset()
+
+	for _, link := range linkList {
+		if !strings.Contains(link.Attrs().Name, constants.VxlanLinkInfix) {
+			continue
+		}
+
+		families := map[int]string{netlink.FAMILY_V4: metrics.IPv4}
+
+		ipv6Disabled, err := daemonutils.CheckIPv6Disabled(link.Attrs().Name)
+		if err != nil {
+			return fmt.Errorf("failed to check ipv6 disables for link %v: %v", link.Attrs().Name, err)
+		}
+		if !ipv6Disabled {
+			families[netlink.FAMILY_V6] = metrics.IPv6
+		}
+
+		// addresses of tenant networks are only resolved in the address spaces of their own
+		tenantNetwork, err := getTenantNetworkOfVxlanLink(context.TODO(), c.mgr.GetClient(), link)
+		if err != nil {
+			return fmt.Errorf("failed to get tenant network of link %v: %v", link.Attrs().Name, err)
+		}
+		resolve := func(ip net.IP) (net.HardwareAddr, error) {
+			return c.resolveVtepMac(ip, tenantNetwork)
+		}
+
+		for family, ipVersion := range families {
+			size, err := neigh.GCVxlanNeighEntries(link.Attrs().Index, family, resolve)
+			if err != nil {
+				// entries failed to be resolved are left to the next round
+				c.logger.Error(err, "failed to gc neigh entries", "link", link.Attrs().Name, "ipVersion", ipVersion)
+			}
+			metrics.VxlanNeighEntryGauge.WithLabelValues(link.Attrs().Name, ipVersion).Set(float64(size))
+		}
+
+		fdbEntryList, err := c.hostNetwork.NeighList(link.Attrs().Index, syscall.AF_BRIDGE)
+		if err != nil {
+			return fmt.Errorf("failed to list fdb entries for link %v: %v", link.Attrs().Name, err)
+		}
+		metrics.VxlanFdbEntryGauge.WithLabelValues(link.Attrs().Name).Set(float64(len(fdbEntryList)))
+	}
+
+	return nil
+}
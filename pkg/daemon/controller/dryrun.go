@@ -0,0 +1,116 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package controller
+
+import (
+	"net"
+
+	"github.com/alibaba/hybridnet/pkg/daemon/addr"
+	"github.com/alibaba/hybridnet/pkg/daemon/bgp"
+	"github.com/alibaba/hybridnet/pkg/daemon/containernetwork"
+	"github.com/alibaba/hybridnet/pkg/daemon/iptables"
+	"github.com/alibaba/hybridnet/pkg/daemon/neigh"
+	"github.com/alibaba/hybridnet/pkg/daemon/route"
+	daemonutils "github.com/alibaba/hybridnet/pkg/daemon/utils"
+
+	"github.com/vishvananda/netlink"
+)
+
+// initDryDataplaneManagers uses in-memory managers which log what would be synced to host,
+// so that controllers can run without privileges or even on a non-linux os
+func (c *CtrlHub) initDryDataplaneManagers() {
+	logger := c.logger.WithName("dry-dataplane")
+	logger.Info("running against a dry dataplane, host network will never be changed")
+
+	onRouteSync := func(family int, subnets []route.FakeSubnetInfo) {
+		for _, subnet := range subnets {
+			logger.Info("sync subnet routes", "family", familyString(family), "cidr", subnet.Cidr.String(),
+				"gateway", subnet.Gateway, "forwardNodeIfName", subnet.ForwardNodeIfName, "mode", subnet.Mode,
+				"isOverlay", subnet.IsOverlay, "isUnderlayOnHost", subnet.IsUnderlayOnHost, "isRemote", subnet.IsRemote,
+				"autoNatOutgoing", subnet.AutoNatOutgoing, "routeMetric", subnet.RouteOptions.Priority,
+				"routeRealm", subnet.RouteOptions.Realm)
+		}
+	}
+	routeV4Manager, routeV6Manager := route.NewFakeManager(netlink.FAMILY_V4), route.NewFakeManager(netlink.FAMILY_V6)
+	routeV4Manager.OnSync, routeV6Manager.OnSync = onRouteSync, onRouteSync
+	c.routeV4Manager, c.routeV6Manager = routeV4Manager, routeV6Manager
+
+	onNeighSync := func(family int, interfaceToIPs map[string][]net.IP) {
+		for forwardNodeIfName, ips := range interfaceToIPs {
+			logger.Info("sync proxy neighs", "family", familyString(family),
+				"forwardNodeIfName", forwardNodeIfName, "ips", ips)
+		}
+	}
+	neighV4Manager, neighV6Manager := neigh.NewFakeManager(netlink.FAMILY_V4), neigh.NewFakeManager(netlink.FAMILY_V6)
+	neighV4Manager.OnSync, neighV6Manager.OnSync = onNeighSync, onNeighSync
+	c.neighV4Manager, c.neighV6Manager = neighV4Manager, neighV6Manager
+
+	addrV4Manager := addr.NewFakeManager(netlink.FAMILY_V4)
+	addrV4Manager.OnSync = func(family int, interfaceToSubnetAddrs map[string]map[string]net.IP) {
+		for forwardNodeIfName, subnetAddrs := range interfaceToSubnetAddrs {
+			logger.Info("sync enhanced addresses", "family", familyString(family),
+				"forwardNodeIfName", forwardNodeIfName, "subnetAddresses", subnetAddrs)
+		}
+	}
+	c.addrV4Manager = addrV4Manager
+
+	// iptables rules are synced periodically, so they are only logged with a higher verbosity
+	onIPtablesSync := func(protocol iptables.Protocol, records iptables.FakeRecords) {
+		family := netlink.FAMILY_V4
+		if protocol == iptables.ProtocolIpv6 {
+			family = netlink.FAMILY_V6
+		}
+		logger.V(1).Info("sync iptables rules", "family", familyString(family),
+			"subnets", len(records.Subnets), "localPodIPs", len(records.LocalPodIPs),
+			"nodeIPs", len(records.NodeIPs), "remoteNodeIPs", len(records.RemoteNodeIPs),
+			"egressRules", len(records.EgressRules), "macSpoofProtectedPods", len(records.PodMACs),
+			"sourceGuardedPods", len(records.PodSourceGuards), "noTrackFlows", len(records.NoTrackFlows),
+			"overlayIfName", records.OverlayIfName,
+			"bgpIfName", records.BgpIfName, "vlanForwardIfNames", records.VlanForwardIfNames)
+	}
+	iptablesV4Manager, iptablesV6Manager := iptables.NewFakeManager(iptables.ProtocolIpv4), iptables.NewFakeManager(iptables.ProtocolIpv6)
+	iptablesV4Manager.OnSync, iptablesV6Manager.OnSync = onIPtablesSync, onIPtablesSync
+	c.iptablesV4Manager, c.iptablesV6Manager = iptablesV4Manager, iptablesV6Manager
+
+	c.bgpManager = bgp.NewDryRunManager(logger.WithName("bgp-server"))
+
+	// links of host are never found in a dry dataplane, so that host network is never changed
+	c.hostNetwork = containernetwork.NewFakeHostNetwork()
+}
+
+func familyString(family int) string {
+	if family == netlink.FAMILY_V6 {
+		return "ipv6"
+	}
+	return "ipv4"
+}
+
+// ensureVlanIf only generates the name of vlan forward interface in a dry dataplane
+func (c *CtrlHub) ensureVlanIf(nodeIfName string, vlanID *int32) (string, error) {
+	if c.config.DryDataplane {
+		return daemonutils.GenerateVlanNetIfName(nodeIfName, vlanID)
+	}
+	return daemonutils.EnsureVlanIf(nodeIfName, vlanID)
+}
+
+// ipv6GlobalDisabled always reports ipv6 enabled in a dry dataplane, whose host might not have procfs
+func (c *CtrlHub) ipv6GlobalDisabled() (bool, error) {
+	if c.config.DryDataplane {
+		return false, nil
+	}
+	return daemonutils.CheckIPv6GlobalDisabled()
+}
@@ -22,8 +22,10 @@ import (
 	"net"
 	"time"
 
+	"github.com/go-logr/logr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
@@ -37,7 +39,9 @@ import (
 
 	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
 	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/daemon/containernetwork"
 	daemonutils "github.com/alibaba/hybridnet/pkg/daemon/utils"
+	"github.com/alibaba/hybridnet/pkg/feature"
 )
 
 type ipInstanceReconciler struct {
@@ -61,6 +65,15 @@ func (r *ipInstanceReconciler) Reconcile(ctx context.Context, request reconcile.
 			r.ctrlHubRef.config.NodeName, err)
 	}
 
+	// ip instances rebound away from this node keep being forwarded here until
+	// the data plane of new node is ready
+	rebindSourceIPInstanceList := &networkingv1.IPInstanceList{}
+	if err := r.List(ctx, rebindSourceIPInstanceList,
+		client.MatchingLabels{constants.LabelRebindSourceNode: r.ctrlHubRef.config.NodeName}); err != nil {
+		return reconcile.Result{Requeue: true}, fmt.Errorf("list rebind source ip instances for node %v error: %v",
+			r.ctrlHubRef.config.NodeName, err)
+	}
+
 	r.ctrlHubRef.neighV4Manager.ResetInfos()
 	r.ctrlHubRef.neighV6Manager.ResetInfos()
 
@@ -73,12 +86,19 @@ func (r *ipInstanceReconciler) Reconcile(ctx context.Context, request reconcile.
 		return reconcile.Result{Requeue: true}, fmt.Errorf("failed to collect global network info and init: %v", err)
 	}
 
-	for _, ipInstance := range ipInstanceList.Items {
+	var reboundAnnouncements []addressAnnouncement
+
+	for _, ipInstance := range append(ipInstanceList.Items, rebindSourceIPInstanceList.Items...) {
 		// skip reserved ip instance
 		if networkingv1.IsReserved(&ipInstance) {
 			continue
 		}
 
+		// skip terminating ip instance, its dataplane will be cleaned by this sync
+		if ipInstance.DeletionTimestamp != nil {
+			continue
+		}
+
 		netID := ipInstance.Spec.Address.NetID
 		podIP, subnetCidr, err := net.ParseCIDR(ipInstance.Spec.Address.IP)
 		if err != nil {
@@ -94,7 +114,13 @@ func (r *ipInstanceReconciler) Reconcile(ctx context.Context, request reconcile.
 		var forwardNodeIfName string
 		switch networkingv1.GetNetworkMode(network) {
 		case networkingv1.NetworkModeVlan:
-			forwardNodeIfName, err = daemonutils.GenerateVlanNetIfName(r.ctrlHubRef.config.NodeVlanIfName, netID)
+			vlanNodeIfName, err := r.ctrlHubRef.getVlanNodeIfNameOfIPInstance(ctx, &ipInstance)
+			if err != nil {
+				return reconcile.Result{Requeue: true}, fmt.Errorf("failed to get vlan node interface of ip instance %v: %v",
+					ipInstance.Name, err)
+			}
+
+			forwardNodeIfName, err = daemonutils.GenerateVlanNetIfName(vlanNodeIfName, netID)
 			if err != nil {
 				return reconcile.Result{Requeue: true}, fmt.Errorf("failed to generate vlan forward node interface name: %v", err)
 			}
@@ -105,7 +131,24 @@ func (r *ipInstanceReconciler) Reconcile(ctx context.Context, request reconcile.
 					r.ctrlHubRef.addrV4Manager.TryAddPodInfo(forwardNodeIfName, subnetCidr, podIP)
 				}
 			}
+
+			if r.reboundToNode(&ipInstance) {
+				announcement, announced, err := r.getAddressAnnouncement(ctx, &ipInstance, forwardNodeIfName, podIP)
+				if err != nil {
+					return reconcile.Result{Requeue: true}, fmt.Errorf("failed to get address announcement of ip instance %v: %v",
+						ipInstance.Name, err)
+				}
+
+				if announced {
+					reboundAnnouncements = append(reboundAnnouncements, announcement)
+				}
+			}
 		case networkingv1.NetworkModeVxlan:
+			// vxlan devices of tenant networks are not created if tenant network is disabled
+			if networkingv1.IsTenantNetwork(network) && !feature.TenantNetworkEnabled() {
+				break
+			}
+
 			forwardNodeIfName, err = daemonutils.GenerateVxlanNetIfName(r.ctrlHubRef.config.NodeVxlanIfName, netID)
 			if err != nil {
 				return reconcile.Result{Requeue: true}, fmt.Errorf("failed to generate vxlan forward node interface name: %v", err)
@@ -119,7 +162,8 @@ func (r *ipInstanceReconciler) Reconcile(ctx context.Context, request reconcile.
 		// create proxy neigh
 		neighManager := r.ctrlHubRef.getNeighManager(ipInstance.Spec.Address.Version)
 
-		if len(overlayForwardNodeIfName) != 0 {
+		// pods of tenant networks are only reachable through the vxlan device of their tenants
+		if len(overlayForwardNodeIfName) != 0 && !networkingv1.IsTenantNetwork(network) {
 			// Every underlay pod should also add a proxy neigh on overlay forward interface.
 			// neighManager.AddPodInfo is idempotent
 			neighManager.AddPodInfo(podIP, overlayForwardNodeIfName)
@@ -135,7 +179,7 @@ func (r *ipInstanceReconciler) Reconcile(ctx context.Context, request reconcile.
 		return reconcile.Result{Requeue: true}, fmt.Errorf("failed to sync ipv4 neighs: %v", err)
 	}
 
-	globalDisabled, err := daemonutils.CheckIPv6GlobalDisabled()
+	globalDisabled, err := r.ctrlHubRef.ipv6GlobalDisabled()
 	if err != nil {
 		return reconcile.Result{Requeue: true}, fmt.Errorf("failed to check ipv6 global disabled: %v", err)
 	}
@@ -156,9 +200,124 @@ func (r *ipInstanceReconciler) Reconcile(ctx context.Context, request reconcile.
 
 	r.ctrlHubRef.iptablesSyncTrigger()
 
+	if err := r.finishRebind(ctx, ipInstanceList.Items); err != nil {
+		return reconcile.Result{Requeue: true}, fmt.Errorf("failed to finish rebind of ip instances: %v", err)
+	}
+
+	if err := r.finishCleanup(ctx, append(ipInstanceList.Items, rebindSourceIPInstanceList.Items...)); err != nil {
+		return reconcile.Result{Requeue: true}, fmt.Errorf("failed to finish dataplane cleanup of ip instances: %v", err)
+	}
+
+	if !r.ctrlHubRef.config.DryDataplane {
+		announceReboundAddresses(reboundAnnouncements, logger)
+	}
+
 	return reconcile.Result{}, nil
 }
 
+// finishRebind removes the rebind source node of ip instances after they are synced on this
+// node, then the source node will stop forwarding them
+func (r *ipInstanceReconciler) finishRebind(ctx context.Context, ipInstances []networkingv1.IPInstance) error {
+	for i := range ipInstances {
+		ipInstance := &ipInstances[i]
+		if _, exist := ipInstance.Labels[constants.LabelRebindSourceNode]; !exist {
+			continue
+		}
+
+		patch := client.MergeFrom(ipInstance.DeepCopy())
+		delete(ipInstance.Labels, constants.LabelRebindSourceNode)
+		if err := client.IgnoreNotFound(r.Patch(ctx, ipInstance, patch)); err != nil {
+			return fmt.Errorf("failed to remove rebind source node of ip instance %v: %v", ipInstance.Name, err)
+		}
+	}
+	return nil
+}
+
+// finishCleanup removes the dataplane cleanup finalizer of terminating ip instances after they are
+// synced on this node, then their addresses can be released and reallocated by manager
+func (r *ipInstanceReconciler) finishCleanup(ctx context.Context, ipInstances []networkingv1.IPInstance) error {
+	for i := range ipInstances {
+		ipInstance := &ipInstances[i]
+		if ipInstance.DeletionTimestamp == nil ||
+			!controllerutil.ContainsFinalizer(ipInstance, constants.FinalizerDataplaneCleanup) {
+			continue
+		}
+
+		// rebind source node also forwards the address, the cleanup is finished by the bound node
+		if ipInstance.GetLabels()[constants.LabelNode] != r.ctrlHubRef.config.NodeName {
+			continue
+		}
+
+		patch := client.MergeFromWithOptions(ipInstance.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		controllerutil.RemoveFinalizer(ipInstance, constants.FinalizerDataplaneCleanup)
+		if err := client.IgnoreNotFound(r.Patch(ctx, ipInstance, patch)); err != nil {
+			return fmt.Errorf("failed to remove dataplane cleanup finalizer of ip instance %v: %v", ipInstance.Name, err)
+		}
+	}
+	return nil
+}
+
+// addressAnnouncement is the announcement of an address rebound to this node
+type addressAnnouncement struct {
+	forwardNodeIfName string
+	ip                net.IP
+	retries           int
+	interval          time.Duration
+}
+
+// reboundToNode means an ip instance is rebound to this node and the rebind is not finished
+func (r *ipInstanceReconciler) reboundToNode(ipInstance *networkingv1.IPInstance) bool {
+	_, rebound := ipInstance.GetLabels()[constants.LabelRebindSourceNode]
+	return rebound && ipInstance.GetLabels()[constants.LabelNode] == r.ctrlHubRef.config.NodeName
+}
+
+// getAddressAnnouncement returns the announcement of an ip instance in vlan network, announced is false
+// if address announcement is not specified by subnet of ip instance
+func (r *ipInstanceReconciler) getAddressAnnouncement(ctx context.Context, ipInstance *networkingv1.IPInstance,
+	forwardNodeIfName string, podIP net.IP) (announcement addressAnnouncement, announced bool, err error) {
+	subnet := &networkingv1.Subnet{}
+	if err = r.Get(ctx, types.NamespacedName{Name: ipInstance.Spec.Subnet}, subnet); err != nil {
+		return announcement, false, client.IgnoreNotFound(err)
+	}
+
+	retries, interval, announced := networkingv1.GetSubnetAddressAnnouncement(&subnet.Spec)
+	return addressAnnouncement{
+		forwardNodeIfName: forwardNodeIfName,
+		ip:                podIP,
+		retries:           retries,
+		interval:          interval,
+	}, announced, nil
+}
+
+// announceReboundAddresses sends gratuitous arp or unsolicited na of rebound addresses in background,
+// then upstream switches and routers forward traffic of addresses to this node immediately
+func announceReboundAddresses(announcements []addressAnnouncement, logger logr.Logger) {
+	for _, announcement := range announcements {
+		go func(announcement addressAnnouncement) {
+			forwardNodeIf, err := net.InterfaceByName(announcement.forwardNodeIfName)
+			if err != nil {
+				logger.Error(err, "failed to get forward interface for announcing", "ip", announcement.ip.String())
+				return
+			}
+
+			if err := containernetwork.Announce(forwardNodeIf, announcement.ip); err != nil {
+				logger.Error(err, "failed to announce rebound address", "ip", announcement.ip.String())
+			}
+
+			if err := containernetwork.AnnounceWithBackoff(forwardNodeIf, announcement.ip,
+				announcement.retries, announcement.interval); err != nil {
+				logger.Error(err, "failed to resend announcements of rebound address", "ip", announcement.ip.String())
+			}
+		}(announcement)
+	}
+}
+
+// relatedToNode means an ip instance is bound to this node, or rebound away from this node
+func (r *ipInstanceReconciler) relatedToNode(ipInstance *networkingv1.IPInstance) bool {
+	return ipInstance.GetLabels()[constants.LabelNode] == r.ctrlHubRef.config.NodeName ||
+		ipInstance.GetLabels()[constants.LabelRebindSourceNode] == r.ctrlHubRef.config.NodeName
+}
+
 func (r *ipInstanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	ipInstanceController, err := controller.New("ip-instance", mgr, controller.Options{
 		Reconciler:   r,
@@ -174,26 +333,21 @@ func (r *ipInstanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		&predicate.Funcs{
 			CreateFunc: func(createEvent event.CreateEvent) bool {
 				ipInstance := createEvent.Object.(*networkingv1.IPInstance)
-				return ipInstance.GetLabels()[constants.LabelNode] == r.ctrlHubRef.config.NodeName
+				return r.relatedToNode(ipInstance)
 			},
 			DeleteFunc: func(deleteEvent event.DeleteEvent) bool {
 				ipInstance := deleteEvent.Object.(*networkingv1.IPInstance)
-				return ipInstance.GetLabels()[constants.LabelNode] == r.ctrlHubRef.config.NodeName
+				return r.relatedToNode(ipInstance)
 			},
 			UpdateFunc: func(updateEvent event.UpdateEvent) bool {
 				oldIPInstance := updateEvent.ObjectOld.(*networkingv1.IPInstance)
 				newIPInstance := updateEvent.ObjectNew.(*networkingv1.IPInstance)
 
-				if newIPInstance.GetLabels()[constants.LabelNode] == r.ctrlHubRef.config.NodeName ||
-					oldIPInstance.GetLabels()[constants.LabelNode] == r.ctrlHubRef.config.NodeName {
-					return true
-				}
-
-				return false
+				return r.relatedToNode(newIPInstance) || r.relatedToNode(oldIPInstance)
 			},
 			GenericFunc: func(genericEvent event.GenericEvent) bool {
 				ipInstance := genericEvent.Object.(*networkingv1.IPInstance)
-				return ipInstance.GetLabels()[constants.LabelNode] == r.ctrlHubRef.config.NodeName
+				return r.relatedToNode(ipInstance)
 			},
 		}); err != nil {
 		return fmt.Errorf("failed to watch networkingv1.IPInstance for ip instance controller: %v", err)
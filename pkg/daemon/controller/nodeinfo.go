@@ -35,6 +35,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	"github.com/alibaba/hybridnet/pkg/daemon/containernetwork"
 	"github.com/alibaba/hybridnet/pkg/daemon/utils"
 
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -45,6 +46,7 @@ import (
 
 	multiclusterv1 "github.com/alibaba/hybridnet/pkg/apis/multicluster/v1"
 	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/daemon/vrf"
 	"github.com/alibaba/hybridnet/pkg/daemon/vxlan"
 	"github.com/alibaba/hybridnet/pkg/feature"
 	ipamutils "github.com/alibaba/hybridnet/pkg/ipam/utils"
@@ -68,6 +70,7 @@ func (r *nodeInfoReconciler) Reconcile(ctx context.Context, request reconcile.Re
 
 	var overlayNetID *int32
 	var overlayNodeNum int
+	var tenantNetIDs []int32
 
 	networkList := &networkingv1.NetworkList{}
 	if err := r.List(ctx, networkList); err != nil {
@@ -75,15 +78,25 @@ func (r *nodeInfoReconciler) Reconcile(ctx context.Context, request reconcile.Re
 	}
 
 	for _, network := range networkList.Items {
-		if networkingv1.GetNetworkType(&network) == networkingv1.NetworkTypeOverlay {
-			overlayNetID = network.Spec.NetID
-			overlayNodeNum = len(network.Status.NodeList)
-			break
+		if networkingv1.GetNetworkType(&network) != networkingv1.NetworkTypeOverlay {
+			continue
+		}
+		overlayNodeNum = len(network.Status.NodeList)
+
+		if networkingv1.IsTenantNetwork(&network) {
+			if feature.TenantNetworkEnabled() && network.Spec.NetID != nil {
+				tenantNetIDs = append(tenantNetIDs, *network.Spec.NetID)
+			}
+			continue
 		}
+		overlayNetID = network.Spec.NetID
 	}
 
-	// overlay network not exist, do nothing
-	if overlayNetID == nil {
+	// overlay network not exist, only clean the vrf devices of tenant networks
+	if overlayNetID == nil && len(tenantNetIDs) == 0 {
+		if err := vrf.CleanVRFs(nil); err != nil {
+			return reconcile.Result{Requeue: true}, fmt.Errorf("failed to clean vrf devices: %v", err)
+		}
 		return reconcile.Result{}, nil
 	}
 
@@ -92,9 +105,12 @@ func (r *nodeInfoReconciler) Reconcile(ctx context.Context, request reconcile.Re
 		return reconcile.Result{Requeue: true}, fmt.Errorf("failed to select vtep address: %v", err)
 	}
 
-	vxlanLinkName, err := utils.GenerateVxlanNetIfName(r.ctrlHubRef.config.NodeVxlanIfName, overlayNetID)
-	if err != nil {
-		return reconcile.Result{Requeue: true}, fmt.Errorf("failed to generate vxlan interface name: %v", err)
+	var vxlanLinkName string
+	if overlayNetID != nil {
+		vxlanLinkName, err = utils.GenerateVxlanNetIfName(r.ctrlHubRef.config.NodeVxlanIfName, overlayNetID)
+		if err != nil {
+			return reconcile.Result{Requeue: true}, fmt.Errorf("failed to generate vxlan interface name: %v", err)
+		}
 	}
 
 	// Node objects are not supposed to be in list/watch cache.
@@ -119,17 +135,31 @@ func (r *nodeInfoReconciler) Reconcile(ctx context.Context, request reconcile.Re
 		return reconcile.Result{Requeue: true}, fmt.Errorf("failed list node: %v", err)
 	}
 
-	// if the vtep ip change, vxlan interface will be rebuilt
-	vxlanDev, err := vxlan.NewVxlanDevice(vxlanLinkName, int(*overlayNetID),
-		r.ctrlHubRef.config.NodeVxlanIfName, vtepIP, r.ctrlHubRef.config.VxlanUDPPort,
-		r.ctrlHubRef.config.VxlanBaseReachableTime, true)
+	var vxlanDev *vxlan.Device
+	if overlayNetID != nil {
+		// if the vtep ip change, vxlan interface will be rebuilt
+		vxlanDev, err = vxlan.NewVxlanDevice(vxlanLinkName, int(*overlayNetID),
+			r.ctrlHubRef.config.NodeVxlanIfName, vtepIP, r.ctrlHubRef.config.VxlanUDPPort,
+			r.ctrlHubRef.config.VxlanBaseReachableTime, true)
+		if err != nil {
+			return reconcile.Result{Requeue: true}, fmt.Errorf("failed to create vxlan device %v: %v", vxlanLinkName, err)
+		}
+
+		if err := ensureInterfaceAddresses(r.ctrlHubRef.hostNetwork, vxlanDev.Link(), nodeLocalVxlanAddrs); err != nil {
+			return reconcile.Result{Requeue: true}, fmt.Errorf("failed to ensure addresses for vxlan device %v: %v",
+				vxlanLinkName, err)
+		}
+	}
+
+	tenantVxlanDevs, err := r.ensureTenantVxlanDevices(tenantNetIDs, vtepIP, nodeLocalVxlanAddrs)
 	if err != nil {
-		return reconcile.Result{Requeue: true}, fmt.Errorf("failed to create vxlan device %v: %v", vxlanLinkName, err)
+		return reconcile.Result{Requeue: true}, fmt.Errorf("failed to ensure vxlan devices of tenant networks: %v", err)
 	}
 
-	if err := ensureVxlanInterfaceAddresses(vxlanDev, nodeLocalVxlanAddrs); err != nil {
-		return reconcile.Result{Requeue: true}, fmt.Errorf("failed to ensure addresses for vxlan device %v: %v",
-			vxlanLinkName, err)
+	// every node is a vtep of the overlay networks of all tenants
+	vxlanDevs := tenantVxlanDevs
+	if vxlanDev != nil {
+		vxlanDevs = append(vxlanDevs, vxlanDev)
 	}
 
 	for _, nodeInfo := range nodeInfoList.Items {
@@ -152,12 +182,15 @@ func (r *nodeInfoReconciler) Reconcile(ctx context.Context, request reconcile.Re
 				nodeInfo.Spec.VTEPInfo.IP)
 		}
 
-		vxlanDev.RecordVtepInfo(vtepMac, vtepIP)
+		for _, dev := range vxlanDevs {
+			dev.RecordVtepInfo(vtepMac, vtepIP)
+		}
 	}
 
 	var remoteVtepList []*multiclusterv1.RemoteVtep
 
-	if feature.MultiClusterEnabled() {
+	// remote clusters are only connected to the overlay network without tenant
+	if feature.MultiClusterEnabled() && vxlanDev != nil {
 		remoteVtepList := &multiclusterv1.RemoteVtepList{}
 		if err = r.List(ctx, remoteVtepList); err != nil {
 			return reconcile.Result{Requeue: true}, fmt.Errorf("failed to list remote vtep: %v", err)
@@ -186,9 +219,11 @@ func (r *nodeInfoReconciler) Reconcile(ctx context.Context, request reconcile.Re
 	}
 
 	// Only delete fdb when the number of NodeInfo objects equals the number of overlay Nodes, to avoid network flapping.
-	if err := vxlanDev.SyncVtepInfo(len(nodeInfoList.Items) == overlayNodeNum); err != nil {
-		return reconcile.Result{Requeue: true}, fmt.Errorf("failed to sync vtep info for vxlan device %v: %v",
-			vxlanDev.Link().Name, err)
+	for _, dev := range vxlanDevs {
+		if err := dev.SyncVtepInfo(len(nodeInfoList.Items) == overlayNodeNum); err != nil {
+			return reconcile.Result{Requeue: true}, fmt.Errorf("failed to sync vtep info for vxlan device %v: %v",
+				dev.Link().Name, err)
+		}
 	}
 
 	if len(nodeInfoList.Items) != overlayNodeNum {
@@ -208,14 +243,14 @@ func (r *nodeInfoReconciler) Reconcile(ctx context.Context, request reconcile.Re
 }
 
 func (r *nodeInfoReconciler) selectVtepAddressFromLink() (net.IP, net.HardwareAddr, error) {
-	link, err := netlink.LinkByName(r.ctrlHubRef.config.NodeVxlanIfName)
+	link, err := r.ctrlHubRef.hostNetwork.LinkByName(r.ctrlHubRef.config.NodeVxlanIfName)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get node vxlan interface %v: %v",
 			r.ctrlHubRef.config.NodeVxlanIfName, err)
 	}
 
 	// Use parent's valid ipv4 address first, try ipv6 address if no valid ipv4 address exist.
-	existParentAddrList, err := utils.ListAllGlobalUnicastAddress(link)
+	existParentAddrList, err := listGlobalUnicastAddresses(r.ctrlHubRef.hostNetwork, link)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to list address for vxlan parent link %v: %v",
 			link.Attrs().Name, err)
@@ -226,17 +261,7 @@ func (r *nodeInfoReconciler) selectVtepAddressFromLink() (net.IP, net.HardwareAd
 			link.Attrs().Name)
 	}
 
-	var vtepIP net.IP
-existParentAddrLoop:
-	for _, addr := range existParentAddrList {
-		for _, cidr := range r.ctrlHubRef.config.VtepAddressCIDRs {
-			if cidr.Contains(addr.IP) {
-				vtepIP = addr.IP
-				break existParentAddrLoop
-			}
-		}
-	}
-
+	vtepIP := utils.SelectVtepAddress(existParentAddrList, r.ctrlHubRef.config.VtepAddressCIDRs)
 	if vtepIP == nil {
 		return nil, nil, fmt.Errorf("no availuable vtep ip can be used for link %v",
 			link.Attrs().Name)
@@ -279,16 +304,69 @@ func (r *nodeInfoReconciler) selectNodeLocalVxlanAddrs(thisNode *corev1.Node, vt
 	return nodeLocalVxlanAddr, nil
 }
 
-func ensureVxlanInterfaceAddresses(vxlanDev *vxlan.Device, addresses []netlink.Addr) error {
+// ensureTenantVxlanDevices ensures a vrf device and a vxlan device enslaved to it for every tenant network,
+// the vni of vxlan device is the net id of tenant network
+func (r *nodeInfoReconciler) ensureTenantVxlanDevices(tenantNetIDs []int32, vtepIP net.IP,
+	nodeLocalVxlanAddrs []netlink.Addr) ([]*vxlan.Device, error) {
+	// vtep address on vrf device is used as the source address of neigh requests in vrf
+	var vtepAddrs []netlink.Addr
+	for _, addr := range nodeLocalVxlanAddrs {
+		if addr.IP.Equal(vtepIP) {
+			vtepAddrs = append(vtepAddrs, addr)
+		}
+	}
+
+	var devices []*vxlan.Device
+	expectedVRFNames := map[string]bool{}
+	for i := range tenantNetIDs {
+		netID := tenantNetIDs[i]
+
+		vrfLink, err := vrf.EnsureVRF(netID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to ensure vrf device for net id %v: %v", netID, err)
+		}
+		expectedVRFNames[vrfLink.Name] = true
+
+		if err := ensureInterfaceAddresses(r.ctrlHubRef.hostNetwork, vrfLink, vtepAddrs); err != nil {
+			return nil, fmt.Errorf("failed to ensure addresses for vrf device %v: %v", vrfLink.Name, err)
+		}
+
+		vxlanLinkName, err := utils.GenerateVxlanNetIfName(r.ctrlHubRef.config.NodeVxlanIfName, &netID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate vxlan interface name: %v", err)
+		}
+
+		vxlanDev, err := vxlan.NewVxlanDevice(vxlanLinkName, int(netID),
+			r.ctrlHubRef.config.NodeVxlanIfName, vtepIP, r.ctrlHubRef.config.VxlanUDPPort,
+			r.ctrlHubRef.config.VxlanBaseReachableTime, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create vxlan device %v: %v", vxlanLinkName, err)
+		}
+
+		if err := vrf.EnslaveLink(vxlanDev.Link(), vrfLink); err != nil {
+			return nil, err
+		}
+
+		devices = append(devices, vxlanDev)
+	}
+
+	if err := vrf.CleanVRFs(expectedVRFNames); err != nil {
+		return nil, fmt.Errorf("failed to clean vrf devices: %v", err)
+	}
+
+	return devices, nil
+}
+
+func ensureInterfaceAddresses(hostNetwork containernetwork.HostNetwork, link netlink.Link, addresses []netlink.Addr) error {
 	nodeLocalVxlanAddrMap := map[string]bool{}
 	for _, addr := range addresses {
 		nodeLocalVxlanAddrMap[addr.IP.String()] = true
 	}
 
-	vxlanDevAddrList, err := utils.ListAllGlobalUnicastAddress(vxlanDev.Link())
+	vxlanDevAddrList, err := listGlobalUnicastAddresses(hostNetwork, link)
 	if err != nil {
-		return fmt.Errorf("failed to list address for vxlan interface %v: %v",
-			vxlanDev.Link().Name, err)
+		return fmt.Errorf("failed to list address for interface %v: %v",
+			link.Attrs().Name, err)
 	}
 
 	existVxlanDevAddrMap := map[string]bool{}
@@ -299,13 +377,13 @@ func ensureVxlanInterfaceAddresses(vxlanDev *vxlan.Device, addresses []netlink.A
 	// Add all node local vxlan ip address to vxlan interface.
 	for _, addr := range addresses {
 		if _, exist := existVxlanDevAddrMap[addr.IP.String()]; !exist {
-			if err := netlink.AddrAdd(vxlanDev.Link(), &netlink.Addr{
+			if err := hostNetwork.AddrAdd(link, &netlink.Addr{
 				IPNet: addr.IPNet,
 				Label: "",
 				Flags: unix.IFA_F_NOPREFIXROUTE,
 			}); err != nil {
 				return fmt.Errorf("failed to set addr %v to link %v: %v",
-					addr.IP.String(), vxlanDev.Link().Name, err)
+					addr.IP.String(), link.Attrs().Name, err)
 			}
 		}
 	}
@@ -313,9 +391,9 @@ func ensureVxlanInterfaceAddresses(vxlanDev *vxlan.Device, addresses []netlink.A
 	// Delete invalid address.
 	for _, addr := range vxlanDevAddrList {
 		if _, exist := nodeLocalVxlanAddrMap[addr.IP.String()]; !exist {
-			if err := netlink.AddrDel(vxlanDev.Link(), &addr); err != nil {
+			if err := hostNetwork.AddrDel(link, &addr); err != nil {
 				return fmt.Errorf("failed to del addr %v for link %v: %v",
-					addr.IP.String(), vxlanDev.Link().Name, err)
+					addr.IP.String(), link.Attrs().Name, err)
 			}
 		}
 	}
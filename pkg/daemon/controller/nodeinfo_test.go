@@ -0,0 +1,204 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package controller
+
+import (
+	"net"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/vishvananda/netlink"
+
+	daemonconfig "github.com/alibaba/hybridnet/pkg/daemon/config"
+	"github.com/alibaba/hybridnet/pkg/daemon/containernetwork"
+)
+
+func mustParseAddr(t *testing.T, cidr string) netlink.Addr {
+	ip, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		t.Fatalf("failed to parse cidr %v: %v", cidr, err)
+	}
+	ipNet.IP = ip
+	return netlink.Addr{IPNet: ipNet}
+}
+
+func addressesOfLink(t *testing.T, hostNetwork containernetwork.HostNetwork, link netlink.Link) []string {
+	addrList, err := hostNetwork.AddrList(link, netlink.FAMILY_ALL)
+	if err != nil {
+		t.Fatalf("failed to list addresses of link %v: %v", link.Attrs().Name, err)
+	}
+
+	var result []string
+	for _, addr := range addrList {
+		result = append(result, addr.IPNet.String())
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestEnsureInterfaceAddresses(t *testing.T) {
+	tests := []struct {
+		name      string
+		existing  []string
+		addresses []string
+		expect    []string
+	}{
+		{
+			name:      "add addresses",
+			addresses: []string{"10.0.0.1/24", "fd00::1/64"},
+			expect:    []string{"10.0.0.1/24", "fd00::1/64"},
+		},
+		{
+			name:      "delete stale addresses",
+			existing:  []string{"10.0.0.1/24", "10.0.0.2/24"},
+			addresses: []string{"10.0.0.1/24"},
+			expect:    []string{"10.0.0.1/24"},
+		},
+		{
+			name:      "keep addresses which are not global unicast",
+			existing:  []string{"fe80::1/64", "10.0.0.2/24"},
+			addresses: []string{"10.0.0.3/24"},
+			expect:    []string{"10.0.0.3/24", "fe80::1/64"},
+		},
+		{
+			name:     "delete all addresses",
+			existing: []string{"10.0.0.1/24", "fd00::1/64"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hostNetwork := containernetwork.NewFakeHostNetwork()
+			link := hostNetwork.AddLink("eth0.vxlan4")
+			for _, cidr := range test.existing {
+				addr := mustParseAddr(t, cidr)
+				if err := hostNetwork.AddrAdd(link, &addr); err != nil {
+					t.Fatalf("test %s fails, failed to add address %v: %v", test.name, cidr, err)
+				}
+			}
+
+			var addresses []netlink.Addr
+			for _, cidr := range test.addresses {
+				addresses = append(addresses, mustParseAddr(t, cidr))
+			}
+
+			if err := ensureInterfaceAddresses(hostNetwork, link, addresses); err != nil {
+				t.Fatalf("test %s fails, unexpected error: %v", test.name, err)
+			}
+
+			if result := addressesOfLink(t, hostNetwork, link); !reflect.DeepEqual(result, test.expect) {
+				t.Errorf("test %s fails, expected addresses %v but got %v", test.name, test.expect, result)
+			}
+		})
+	}
+}
+
+func TestSelectVtepAddressFromLink(t *testing.T) {
+	hostNetwork := containernetwork.NewFakeHostNetwork()
+	link := hostNetwork.AddLink("eth0")
+	hwaddr, _ := net.ParseMAC("02:00:00:00:00:01")
+	if err := hostNetwork.LinkSetHardwareAddr(link, hwaddr); err != nil {
+		t.Fatalf("failed to set hardware address: %v", err)
+	}
+	for _, cidr := range []string{"fe80::1/64", "192.168.0.10/24", "fd00::10/64"} {
+		addr := mustParseAddr(t, cidr)
+		if err := hostNetwork.AddrAdd(link, &addr); err != nil {
+			t.Fatalf("failed to add address %v: %v", cidr, err)
+		}
+	}
+	hostNetwork.AddLink("eth1")
+
+	parseCIDRs := func(cidrs ...string) []*net.IPNet {
+		var result []*net.IPNet
+		for _, cidr := range cidrs {
+			_, ipNet, _ := net.ParseCIDR(cidr)
+			result = append(result, ipNet)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name      string
+		linkName  string
+		cidrs     []*net.IPNet
+		expectIP  string
+		expectErr bool
+	}{
+		{
+			name:     "ipv4 address first",
+			linkName: "eth0",
+			cidrs:    parseCIDRs("0.0.0.0/0", "::/0"),
+			expectIP: "192.168.0.10",
+		},
+		{
+			name:     "address in vtep address cidrs",
+			linkName: "eth0",
+			cidrs:    parseCIDRs("fd00::/64"),
+			expectIP: "fd00::10",
+		},
+		{
+			name:      "no address in vtep address cidrs",
+			linkName:  "eth0",
+			cidrs:     parseCIDRs("10.0.0.0/8"),
+			expectErr: true,
+		},
+		{
+			name:      "link without address",
+			linkName:  "eth1",
+			cidrs:     parseCIDRs("0.0.0.0/0", "::/0"),
+			expectErr: true,
+		},
+		{
+			name:      "link not found",
+			linkName:  "eth2",
+			cidrs:     parseCIDRs("0.0.0.0/0", "::/0"),
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := &nodeInfoReconciler{
+				ctrlHubRef: &CtrlHub{
+					config: &daemonconfig.Configuration{
+						NodeVxlanIfName:  test.linkName,
+						VtepAddressCIDRs: test.cidrs,
+					},
+					hostNetwork: hostNetwork,
+				},
+			}
+
+			ip, mac, err := r.selectVtepAddressFromLink()
+			if test.expectErr {
+				if err == nil {
+					t.Errorf("test %s fails, expected error but got nil", test.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("test %s fails, unexpected error: %v", test.name, err)
+			}
+			if ip.String() != test.expectIP {
+				t.Errorf("test %s fails, expected vtep address %v but got %v", test.name, test.expectIP, ip)
+			}
+			if mac.String() != hwaddr.String() {
+				t.Errorf("test %s fails, expected vtep mac %v but got %v", test.name, hwaddr, mac)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/alibaba/hybridnet/pkg/constants"
+)
+
+// iptablesAnnotationKeys are the annotations of pods and namespaces which iptables rules of pods depend on
+var iptablesAnnotationKeys = []string{
+	constants.AnnotationEgressAllowlist,
+	constants.AnnotationIPSourceGuard,
+}
+
+// podAnnotationReconciler triggers iptables sync when the annotations of local pods or namespaces,
+// which iptables rules of pods depend on, are changed
+type podAnnotationReconciler struct {
+	client.Client
+	ctrlHubRef *CtrlHub
+}
+
+func (r *podAnnotationReconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	r.ctrlHubRef.iptablesSyncTrigger()
+	return reconcile.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *podAnnotationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	podAnnotationController, err := controller.New("pod-annotation", mgr, controller.Options{
+		Reconciler:   r,
+		RecoverPanic: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create pod annotation controller: %v", err)
+	}
+
+	// only pods of this node are cached, and creation or deletion of pods is handled by
+	// ip instance controller
+	if err := podAnnotationController.Watch(&source.Kind{Type: &corev1.Pod{}},
+		&fixedKeyHandler{key: "ForPodAnnotationChange"},
+		iptablesAnnotationChangedPredicate()); err != nil {
+		return fmt.Errorf("failed to watch corev1.Pod for pod annotation controller: %v", err)
+	}
+
+	if err := podAnnotationController.Watch(&source.Kind{Type: &corev1.Namespace{}},
+		&fixedKeyHandler{key: "ForNamespaceAnnotationChange"},
+		iptablesAnnotationChangedPredicate()); err != nil {
+		return fmt.Errorf("failed to watch corev1.Namespace for pod annotation controller: %v", err)
+	}
+
+	return nil
+}
+
+func iptablesAnnotationChangedPredicate() predicate.Predicate {
+	return &predicate.Funcs{
+		CreateFunc: func(createEvent event.CreateEvent) bool {
+			return false
+		},
+		DeleteFunc: func(deleteEvent event.DeleteEvent) bool {
+			return false
+		},
+		UpdateFunc: func(updateEvent event.UpdateEvent) bool {
+			for _, key := range iptablesAnnotationKeys {
+				if updateEvent.ObjectOld.GetAnnotations()[key] != updateEvent.ObjectNew.GetAnnotations()[key] {
+					return true
+				}
+			}
+			return false
+		},
+		GenericFunc: func(genericEvent event.GenericEvent) bool {
+			return false
+		},
+	}
+}
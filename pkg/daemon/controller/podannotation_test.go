@@ -0,0 +1,80 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/alibaba/hybridnet/pkg/constants"
+)
+
+func TestIPtablesAnnotationChangedPredicate(t *testing.T) {
+	newPod := func(annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "pod",
+				Namespace:   "default",
+				Annotations: annotations,
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		old      map[string]string
+		new      map[string]string
+		expected bool
+	}{
+		{
+			name:     "egress allowlist added",
+			new:      map[string]string{constants.AnnotationEgressAllowlist: "10.0.0.0/8"},
+			expected: true,
+		},
+		{
+			name:     "egress allowlist changed",
+			old:      map[string]string{constants.AnnotationEgressAllowlist: "10.0.0.0/8"},
+			new:      map[string]string{constants.AnnotationEgressAllowlist: "10.0.0.0/8,192.168.0.0/16"},
+			expected: true,
+		},
+		{
+			name:     "ip source guard removed",
+			old:      map[string]string{constants.AnnotationIPSourceGuard: "false"},
+			expected: true,
+		},
+		{
+			name:     "unrelated annotation changed",
+			old:      map[string]string{constants.AnnotationEgressAllowlist: "10.0.0.0/8", "foo": "bar"},
+			new:      map[string]string{constants.AnnotationEgressAllowlist: "10.0.0.0/8"},
+			expected: false,
+		},
+	}
+
+	p := iptablesAnnotationChangedPredicate()
+	for _, test := range tests {
+		if result := p.Update(event.UpdateEvent{ObjectOld: newPod(test.old), ObjectNew: newPod(test.new)}); result != test.expected {
+			t.Errorf("test %s fails, expected %v but got %v", test.name, test.expected, result)
+		}
+	}
+
+	if p.Create(event.CreateEvent{Object: newPod(map[string]string{constants.AnnotationEgressAllowlist: "10.0.0.0/8"})}) {
+		t.Errorf("creation of pod should be handled by ip instance controller")
+	}
+}
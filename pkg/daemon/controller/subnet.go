@@ -19,9 +19,20 @@ package controller
 import (
 	"context"
 	"fmt"
+	"net"
 	"reflect"
+	"sort"
+	"strings"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/daemon/route"
 	daemonutils "github.com/alibaba/hybridnet/pkg/daemon/utils"
+	"github.com/alibaba/hybridnet/pkg/daemon/vrf"
+	"github.com/vishvananda/netlink"
 
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -48,6 +59,9 @@ import (
 type subnetReconciler struct {
 	client.Client
 	ctrlHubRef *CtrlHub
+
+	// acknowledgedCleanedSubnets is the last acknowledged value of cleaned subnets on node
+	acknowledgedCleanedSubnets string
 }
 
 func (r *subnetReconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
@@ -71,9 +85,25 @@ func (r *subnetReconciler) Reconcile(ctx context.Context, request reconcile.Requ
 		return reconcile.Result{Requeue: true}, fmt.Errorf("failed to collect global network info and init: %v", err)
 	}
 
+	// subnets which are terminating or have no parent network will be left out of route
+	// infos, so that their routes will be cleaned in this round of sync
+	var cleanedSubnets []string
+
+	// subnets of tenant networks are routed in the vrf of their tenants, keyed by net id
+	tenantSubnetCidrs := map[int32][]*net.IPNet{}
 	for _, subnet := range subnetList.Items {
+		if subnet.DeletionTimestamp != nil {
+			cleanedSubnets = append(cleanedSubnets, subnet.Name)
+			continue
+		}
+
 		network := &networkingv1.Network{}
 		if err := r.Get(ctx, types.NamespacedName{Name: subnet.Spec.Network}, network); err != nil {
+			if errors.IsNotFound(err) {
+				logger.Info("network of subnet is not found, skip it", "subnet", subnet.Name, "network", subnet.Spec.Network)
+				cleanedSubnets = append(cleanedSubnets, subnet.Name)
+				continue
+			}
 			return reconcile.Result{Requeue: true}, fmt.Errorf("failed to get network for subnet %v", subnet.Name)
 		}
 
@@ -98,12 +128,19 @@ func (r *subnetReconciler) Reconcile(ctx context.Context, request reconcile.Requ
 		switch networkMode {
 		case networkingv1.NetworkModeVlan:
 			if isUnderlayOnHost {
-				forwardNodeIfName, err = daemonutils.EnsureVlanIf(r.ctrlHubRef.config.NodeVlanIfName, netID)
+				forwardNodeIfName, err = r.ctrlHubRef.ensureVlanIf(r.ctrlHubRef.getVlanNodeIfName(&subnet.Spec), netID)
 				if err != nil {
 					return reconcile.Result{Requeue: true}, fmt.Errorf("failed to ensure vlan forward node interface: %v", err)
 				}
 			}
 		case networkingv1.NetworkModeVxlan:
+			if networkingv1.IsTenantNetwork(network) {
+				if network.Spec.NetID != nil {
+					tenantSubnetCidrs[*network.Spec.NetID] = append(tenantSubnetCidrs[*network.Spec.NetID], subnetCidr)
+				}
+				continue
+			}
+
 			forwardNodeIfName = overlayForwardNodeIfName
 			isOverlay = true
 			autoNatOutgoing = networkingv1.IsSubnetAutoNatOutgoing(&subnet.Spec)
@@ -129,10 +166,18 @@ func (r *subnetReconciler) Reconcile(ctx context.Context, request reconcile.Requ
 			return reconcile.Result{Requeue: true}, fmt.Errorf("invalic network mode %v for %v", networkMode, network.Name)
 		}
 
+		egressRoutes, err := parseSubnetEgressRoutes(&subnet.Spec)
+		if err != nil {
+			return reconcile.Result{Requeue: true}, fmt.Errorf("failed to parse subnet %v egress routes: %v", subnet.Name, err)
+		}
+
+		routeMetric, routeRealm := networkingv1.GetNetworkRouteOptions(network)
+
 		// create policy route
 		routeManager := r.ctrlHubRef.getRouterManager(subnet.Spec.Range.Version)
 		routeManager.AddSubnetInfo(subnetCidr, gatewayIP, startIP, endIP, excludeIPs,
-			forwardNodeIfName, autoNatOutgoing, isOverlay, isUnderlayOnHost, networkMode)
+			forwardNodeIfName, autoNatOutgoing, isOverlay, isUnderlayOnHost, networkMode, egressRoutes,
+			route.Options{Priority: routeMetric, Realm: routeRealm})
 	}
 
 	if feature.MultiClusterEnabled() {
@@ -166,7 +211,7 @@ func (r *subnetReconciler) Reconcile(ctx context.Context, request reconcile.Requ
 		return reconcile.Result{Requeue: true}, fmt.Errorf("failed to sync ipv4 routes: %v", err)
 	}
 
-	globalDisabled, err := daemonutils.CheckIPv6GlobalDisabled()
+	globalDisabled, err := r.ctrlHubRef.ipv6GlobalDisabled()
 	if err != nil {
 		return reconcile.Result{Requeue: true}, fmt.Errorf("failed to check ipv6 global disabled: %v", err)
 	}
@@ -181,11 +226,99 @@ func (r *subnetReconciler) Reconcile(ctx context.Context, request reconcile.Requ
 		return reconcile.Result{Requeue: true}, fmt.Errorf("failed to sync bgp peers and subnet paths: %v", err)
 	}
 
+	if feature.TenantNetworkEnabled() {
+		if err := r.syncTenantSubnetRoutes(ctx, tenantSubnetCidrs); err != nil {
+			return reconcile.Result{Requeue: true}, fmt.Errorf("failed to sync tenant subnet routes: %v", err)
+		}
+	}
+
 	r.ctrlHubRef.iptablesSyncTrigger()
 
+	if err := r.acknowledgeCleanedSubnets(ctx, cleanedSubnets); err != nil {
+		return reconcile.Result{Requeue: true}, fmt.Errorf("failed to acknowledge cleaned subnets: %v", err)
+	}
+
 	return reconcile.Result{}, nil
 }
 
+// syncTenantSubnetRoutes routes the subnets of every tenant network through its vxlan device in the vrf
+// of tenant, vrf and vxlan devices not created by node info controller yet are skipped
+func (r *subnetReconciler) syncTenantSubnetRoutes(ctx context.Context, tenantSubnetCidrs map[int32][]*net.IPNet) error {
+	networkList := &networkingv1.NetworkList{}
+	if err := r.List(ctx, networkList); err != nil {
+		return fmt.Errorf("failed to list network: %v", err)
+	}
+
+	for i := range networkList.Items {
+		network := &networkList.Items[i]
+		if !networkingv1.IsTenantNetwork(network) || network.Spec.NetID == nil {
+			continue
+		}
+		netID := *network.Spec.NetID
+
+		vrfName := vrf.GenerateVRFName(netID)
+		vrfLink, err := r.ctrlHubRef.hostNetwork.LinkByName(vrfName)
+		if err != nil {
+			if _, ok := err.(netlink.LinkNotFoundError); ok {
+				continue
+			}
+			return fmt.Errorf("failed to get vrf device %v: %v", vrfName, err)
+		}
+
+		vrfDev, ok := vrfLink.(*netlink.Vrf)
+		if !ok {
+			return fmt.Errorf("link %v is not a vrf device", vrfName)
+		}
+
+		vxlanLinkName, err := daemonutils.GenerateVxlanNetIfName(r.ctrlHubRef.config.NodeVxlanIfName, &netID)
+		if err != nil {
+			return fmt.Errorf("failed to generate vxlan interface name: %v", err)
+		}
+
+		vxlanLink, err := r.ctrlHubRef.hostNetwork.LinkByName(vxlanLinkName)
+		if err != nil {
+			if _, ok := err.(netlink.LinkNotFoundError); ok {
+				continue
+			}
+			return fmt.Errorf("failed to get vxlan device %v: %v", vxlanLinkName, err)
+		}
+
+		if err := vrf.SyncSubnetRoutes(vrfDev, vxlanLink, tenantSubnetCidrs[netID]); err != nil {
+			return fmt.Errorf("failed to sync subnet routes for vrf device %v: %v", vrfName, err)
+		}
+	}
+
+	return nil
+}
+
+// acknowledgeCleanedSubnets records the subnets whose dataplane has been cleaned on node
+// annotation, which is required by manager before removing terminating subnets
+func (r *subnetReconciler) acknowledgeCleanedSubnets(ctx context.Context, cleanedSubnets []string) error {
+	sort.Strings(cleanedSubnets)
+	value := strings.Join(cleanedSubnets, ",")
+	if value == r.acknowledgedCleanedSubnets {
+		return nil
+	}
+
+	var patchBody string
+	if len(value) == 0 {
+		patchBody = fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, constants.AnnotationDataplaneCleanedSubnets)
+	} else {
+		patchBody = fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, constants.AnnotationDataplaneCleanedSubnets, value)
+	}
+
+	if err := r.Patch(ctx, &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: r.ctrlHubRef.config.NodeName,
+		},
+	}, client.RawPatch(types.MergePatchType, []byte(patchBody))); err != nil {
+		return fmt.Errorf("failed to patch node %v: %v", r.ctrlHubRef.config.NodeName, err)
+	}
+
+	r.acknowledgedCleanedSubnets = value
+	return nil
+}
+
 func (r *subnetReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	subnetController, err := controller.New("subnet", mgr, controller.Options{
 		Reconciler:   r,
@@ -210,8 +343,12 @@ func (r *subnetReconciler) SetupWithManager(mgr ctrl.Manager) error {
 					(oldSubnetNetID != nil && newSubnetNetID == nil) ||
 					(oldSubnetNetID != nil && newSubnetNetID != nil && *oldSubnetNetID != *newSubnetNetID) ||
 					oldSubnet.Spec.Network != newSubnet.Spec.Network ||
+					oldSubnet.DeletionTimestamp.IsZero() != newSubnet.DeletionTimestamp.IsZero() ||
 					!reflect.DeepEqual(oldSubnet.Spec.Range, newSubnet.Spec.Range) ||
-					networkingv1.IsSubnetAutoNatOutgoing(&oldSubnet.Spec) != networkingv1.IsSubnetAutoNatOutgoing(&newSubnet.Spec) {
+					networkingv1.IsSubnetAutoNatOutgoing(&oldSubnet.Spec) != networkingv1.IsSubnetAutoNatOutgoing(&newSubnet.Spec) ||
+					// flows of network are left out without network
+					!reflect.DeepEqual(networkingv1.GetSubnetNoTrackFlows(&oldSubnet.Spec, nil),
+						networkingv1.GetSubnetNoTrackFlows(&newSubnet.Spec, nil)) {
 					return true
 				}
 				return false
@@ -0,0 +1,100 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	daemonconfig "github.com/alibaba/hybridnet/pkg/daemon/config"
+	"github.com/alibaba/hybridnet/pkg/daemon/containernetwork"
+	"github.com/alibaba/hybridnet/pkg/daemon/vrf"
+)
+
+func TestSyncTenantSubnetRoutes(t *testing.T) {
+	netID, tenantNetID := int32(4), int32(1001)
+	newNetwork := func(name, tenant string, netID *int32) client.Object {
+		return &networkingv1.Network{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: networkingv1.NetworkSpec{
+				NetID:  netID,
+				Type:   networkingv1.NetworkTypeOverlay,
+				Tenant: tenant,
+			},
+		}
+	}
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+
+	_, cidr, _ := net.ParseCIDR("172.16.0.0/24")
+	tenantSubnetCidrs := map[int32][]*net.IPNet{tenantNetID: {cidr}}
+
+	tests := []struct {
+		name      string
+		links     []string
+		expectErr bool
+	}{
+		{
+			name: "vrf device not found",
+		},
+		{
+			name:  "vxlan device without vrf device",
+			links: []string{"eth0.vxlan1001"},
+		},
+		{
+			name:      "link of vrf name is not a vrf device",
+			links:     []string{vrf.GenerateVRFName(tenantNetID), "eth0.vxlan1001"},
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hostNetwork := containernetwork.NewFakeHostNetwork()
+			for _, name := range test.links {
+				hostNetwork.AddLink(name)
+			}
+
+			r := &subnetReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+					newNetwork("overlay", "", &netID),
+					newNetwork("tenant-a", "a", &tenantNetID),
+					newNetwork("tenant-b", "b", nil),
+				).Build(),
+				ctrlHubRef: &CtrlHub{
+					config:      &daemonconfig.Configuration{NodeVxlanIfName: "eth0"},
+					hostNetwork: hostNetwork,
+				},
+			}
+
+			err := r.syncTenantSubnetRoutes(context.Background(), tenantSubnetCidrs)
+			if test.expectErr != (err != nil) {
+				t.Errorf("test %s fails, expected error %v but got %v", test.name, test.expectErr, err)
+			}
+		})
+	}
+}
@@ -24,6 +24,8 @@ import (
 	"github.com/alibaba/hybridnet/pkg/daemon/bgp"
 	daemonutils "github.com/alibaba/hybridnet/pkg/daemon/utils"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
@@ -41,9 +43,11 @@ import (
 	multiclusterv1 "github.com/alibaba/hybridnet/pkg/apis/multicluster/v1"
 	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
 	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/daemon/containernetwork"
 	"github.com/alibaba/hybridnet/pkg/daemon/iptables"
 	"github.com/alibaba/hybridnet/pkg/daemon/neigh"
 	"github.com/alibaba/hybridnet/pkg/daemon/route"
+	globalutils "github.com/alibaba/hybridnet/pkg/utils"
 )
 
 // simpleTriggerSource is a trigger to add a simple event to queue of controller
@@ -95,32 +99,83 @@ func (h *fixedKeyHandler) Update(e event.UpdateEvent, q workqueue.RateLimitingIn
 	}})
 }
 
-func (c *CtrlHub) getRouterManager(ipVersion networkingv1.IPVersion) *route.Manager {
+func (c *CtrlHub) getRouterManager(ipVersion networkingv1.IPVersion) route.Interface {
 	if ipVersion == networkingv1.IPv6 {
 		return c.routeV6Manager
 	}
 	return c.routeV4Manager
 }
 
-func (c *CtrlHub) getNeighManager(ipVersion networkingv1.IPVersion) *neigh.Manager {
+func (c *CtrlHub) getNeighManager(ipVersion networkingv1.IPVersion) neigh.Interface {
 	if ipVersion == networkingv1.IPv6 {
 		return c.neighV6Manager
 	}
 	return c.neighV4Manager
 }
 
-func (c *CtrlHub) getIPtablesManager(ipVersion networkingv1.IPVersion) *iptables.Manager {
+func (c *CtrlHub) getIPtablesManager(ipVersion networkingv1.IPVersion) iptables.Interface {
 	if ipVersion == networkingv1.IPv6 {
 		return c.iptablesV6Manager
 	}
 	return c.iptablesV4Manager
 }
 
+// getVlanNodeIfName returns the node interface of a vlan subnet, which is the parent of its vlan
+// sub-interface, the global vlan interface is used if subnet does not specify one
+func (c *CtrlHub) getVlanNodeIfName(subnetSpec *networkingv1.SubnetSpec) string {
+	return globalutils.PickFirstNonEmptyString(networkingv1.GetSubnetNodeInterface(subnetSpec), c.config.NodeVlanIfName)
+}
+
+// getVlanNodeIfNameOfIPInstance returns the node interface of the subnet of ip instance, the global
+// vlan interface is used if subnet is not found
+func (c *CtrlHub) getVlanNodeIfNameOfIPInstance(ctx context.Context, ipInstance *networkingv1.IPInstance) (string, error) {
+	subnet := &networkingv1.Subnet{}
+	if err := c.mgr.GetClient().Get(ctx, types.NamespacedName{Name: ipInstance.Spec.Subnet}, subnet); err != nil {
+		if errors.IsNotFound(err) {
+			return c.config.NodeVlanIfName, nil
+		}
+		return "", fmt.Errorf("failed to get subnet %v: %v", ipInstance.Spec.Subnet, err)
+	}
+	return c.getVlanNodeIfName(&subnet.Spec), nil
+}
+
+// getIPInstanceByAddress returns the ip instance of address in the address space shared by networks
+// without tenant, nil is returned if not found
 func (c *CtrlHub) getIPInstanceByAddress(address net.IP) (*networkingv1.IPInstance, error) {
-	ctx := context.Background()
+	return getIPInstanceInAddressSpace(context.Background(), c.mgr.GetClient(), address, "")
+}
+
+// getIPInstanceInAddressSpace returns the ip instance of address in the address space of a tenant
+// network, or in the address space shared by networks without tenant if tenantNetwork is empty, nil
+// is returned if not found
+func getIPInstanceInAddressSpace(ctx context.Context, c client.Reader, address net.IP, tenantNetwork string) (
+	*networkingv1.IPInstance, error) {
 	ipInstanceList := &networkingv1.IPInstanceList{}
-	if err := c.mgr.GetClient().List(ctx, ipInstanceList, client.MatchingFields{InstanceIPIndex: address.String()}); err != nil {
-		return nil, fmt.Errorf("get ip instance by ip %v indexer failed: %v", address.String(), err)
+	if len(tenantNetwork) > 0 {
+		if err := c.List(ctx, ipInstanceList, client.MatchingFields{
+			InstanceNetworkIPIndex: instanceNetworkIPIndexValue(tenantNetwork, address.String())}); err != nil {
+			return nil, fmt.Errorf("get ip instance by ip %v of network %v indexer failed: %v", address.String(),
+				tenantNetwork, err)
+		}
+	} else {
+		if err := c.List(ctx, ipInstanceList, client.MatchingFields{InstanceIPIndex: address.String()}); err != nil {
+			return nil, fmt.Errorf("get ip instance by ip %v indexer failed: %v", address.String(), err)
+		}
+
+		// the same address may be used by tenant networks, which are out of the shared address space
+		var items []networkingv1.IPInstance
+		for i := range ipInstanceList.Items {
+			network := &networkingv1.Network{}
+			if err := c.Get(ctx, types.NamespacedName{Name: ipInstanceList.Items[i].Spec.Network}, network); err != nil {
+				if !errors.IsNotFound(err) {
+					return nil, fmt.Errorf("failed to get network %v: %v", ipInstanceList.Items[i].Spec.Network, err)
+				}
+			} else if networkingv1.IsTenantNetwork(network) {
+				continue
+			}
+			items = append(items, ipInstanceList.Items[i])
+		}
+		ipInstanceList.Items = items
 	}
 
 	if len(ipInstanceList.Items) > 1 {
@@ -131,12 +186,114 @@ func (c *CtrlHub) getIPInstanceByAddress(address net.IP) (*networkingv1.IPInstan
 		return &ipInstanceList.Items[0], nil
 	}
 
-	if len(ipInstanceList.Items) == 0 {
-		// not found
-		return nil, nil
+	// not found
+	return nil, nil
+}
+
+// getTenantNetworkOfVxlanLink returns the tenant network whose net id is the vni of vxlan link, empty if
+// the vxlan link belongs to the overlay network without tenant
+func getTenantNetworkOfVxlanLink(ctx context.Context, c client.Reader, link netlink.Link) (string, error) {
+	vxlanLink, ok := link.(*netlink.Vxlan)
+	if !ok {
+		return "", nil
+	}
+
+	networkList := &networkingv1.NetworkList{}
+	if err := c.List(ctx, networkList); err != nil {
+		return "", fmt.Errorf("failed to list network: %v", err)
 	}
 
-	return nil, fmt.Errorf("ip instance for address %v not found", address.String())
+	for i := range networkList.Items {
+		network := &networkList.Items[i]
+		if networkingv1.IsTenantNetwork(network) && network.Spec.NetID != nil &&
+			int(*network.Spec.NetID) == vxlanLink.VxlanId {
+			return network.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// getEgressRulesOfIPInstance returns egress allowlist of the pod which ip instance is bound to,
+// limited is false if neither the pod nor its namespace has an allowlist
+func (c *CtrlHub) getEgressRulesOfIPInstance(ctx context.Context, ipInstance *networkingv1.IPInstance) (
+	rules []globalutils.EgressRule, limited bool, err error) {
+	pod := &corev1.Pod{}
+	if err = c.mgr.GetClient().Get(ctx, types.NamespacedName{Namespace: ipInstance.Namespace,
+		Name: ipInstance.Spec.Binding.PodName}, pod); err != nil {
+		return nil, false, client.IgnoreNotFound(err)
+	}
+
+	allowlist, exist := pod.Annotations[constants.AnnotationEgressAllowlist]
+	if !exist {
+		namespace := &corev1.Namespace{}
+		if err = c.mgr.GetClient().Get(ctx, types.NamespacedName{Name: pod.Namespace}, namespace); err != nil {
+			return nil, false, client.IgnoreNotFound(err)
+		}
+		if allowlist, exist = namespace.Annotations[constants.AnnotationEgressAllowlist]; !exist {
+			return nil, false, nil
+		}
+	}
+
+	if rules, err = globalutils.ParseEgressAllowlist(allowlist); err != nil {
+		// an invalid allowlist allows nothing
+		c.logger.Error(err, "failed to parse egress allowlist, all egress traffic will be rejected",
+			"pod", pod.Name, "namespace", pod.Namespace)
+		return nil, true, nil
+	}
+
+	return rules, true, nil
+}
+
+// ipSourceGuardEnabled returns true for pods of underlay network unless pod opts out by annotation
+func (c *CtrlHub) ipSourceGuardEnabled(ctx context.Context, ipInstance *networkingv1.IPInstance,
+	network *networkingv1.Network) (bool, error) {
+	if networkingv1.GetNetworkType(network) != networkingv1.NetworkTypeUnderlay {
+		return false, nil
+	}
+
+	pod := &corev1.Pod{}
+	if err := c.mgr.GetClient().Get(ctx, types.NamespacedName{Namespace: ipInstance.Namespace,
+		Name: ipInstance.Spec.Binding.PodName}, pod); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+
+	return globalutils.ParseBoolOrDefault(pod.Annotations[constants.AnnotationIPSourceGuard], true), nil
+}
+
+// listGlobalUnicastAddresses lists ipv4 and ipv6 global unicast addresses of link, ipv4 ones come first
+func listGlobalUnicastAddresses(hostNetwork containernetwork.HostNetwork, link netlink.Link) ([]netlink.Addr, error) {
+	var result []netlink.Addr
+	for _, family := range []int{netlink.FAMILY_V4, netlink.FAMILY_V6} {
+		addrList, err := hostNetwork.AddrList(link, family)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list addresses of family %v for link %v: %v",
+				family, link.Attrs().Name, err)
+		}
+
+		for _, addr := range addrList {
+			if daemonutils.CheckIPIsGlobalUnicast(addr.IP) {
+				result = append(result, addr)
+			}
+		}
+	}
+	return result, nil
+}
+
+// syncNeighborRateLimit applies the ARP/ND rate limit of network on host veth of pod, or cleans it
+// if network has no rate limit
+func (c *CtrlHub) syncNeighborRateLimit(hostIfName string, network *networkingv1.Network) error {
+	if _, err := c.hostNetwork.LinkByName(hostIfName); err != nil {
+		if _, ok := err.(netlink.LinkNotFoundError); ok {
+			// pod sandbox has not been created yet or has been removed
+			return nil
+		}
+		return fmt.Errorf("failed to get host veth %v: %v", hostIfName, err)
+	}
+
+	if packetsPerSecond, burst, limited := networkingv1.GetNeighborRateLimit(network); limited {
+		return containernetwork.EnsureNeighborRateLimit(hostIfName, packetsPerSecond, burst)
+	}
+	return containernetwork.CleanNeighborRateLimit(hostIfName)
 }
 
 func (c *CtrlHub) getRemoteVtepByEndpointAddress(address net.IP) (*multiclusterv1.RemoteVtep, error) {
@@ -248,6 +405,29 @@ func parseSubnetSpecRangeMeta(addressRange *networkingv1.AddressRange) (cidr *ne
 	return
 }
 
+// parseSubnetEgressRoutes parses the egress routes of subnet for route manager
+func parseSubnetEgressRoutes(subnetSpec *networkingv1.SubnetSpec) ([]route.EgressRoute, error) {
+	var egressRoutes []route.EgressRoute
+	for _, egressRoute := range networkingv1.GetSubnetEgressRoutes(subnetSpec) {
+		_, dst, err := net.ParseCIDR(egressRoute.Destination)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse egress route destination %v: %v", egressRoute.Destination, err)
+		}
+
+		gateway := net.ParseIP(egressRoute.Gateway)
+		if gateway == nil {
+			return nil, fmt.Errorf("invalid egress route gateway %v", egressRoute.Gateway)
+		}
+
+		egressRoutes = append(egressRoutes, route.EgressRoute{
+			Dst:     dst,
+			Gateway: gateway,
+		})
+	}
+
+	return egressRoutes, nil
+}
+
 func isIPListEqual(a, b []string) bool {
 	if len(a) == 0 && len(b) == 0 {
 		return true
@@ -287,6 +467,11 @@ func collectGlobalNetworkInfoAndInit(ctx context.Context, client client.Reader,
 	for _, network := range networkList.Items {
 		switch networkingv1.GetNetworkMode(&network) {
 		case networkingv1.NetworkModeVxlan:
+			// tenant networks are forwarded by their own vxlan devices in vrf
+			if networkingv1.IsTenantNetwork(&network) {
+				continue
+			}
+
 			netID := network.Spec.NetID
 			vxlanForwardNodeIfName, err = daemonutils.GenerateVxlanNetIfName(nodeVxlanIfName, netID)
 			if err != nil {
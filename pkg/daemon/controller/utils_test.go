@@ -0,0 +1,184 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/vishvananda/netlink"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+)
+
+// indexedReader filters the results of fake client by field indexers, which are not supported by fake client
+type indexedReader struct {
+	client.Reader
+	indexers map[string]client.IndexerFunc
+}
+
+func (r *indexedReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if err := r.Reader.List(ctx, list, opts...); err != nil {
+		return err
+	}
+
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	if listOpts.FieldSelector == nil || listOpts.FieldSelector.Empty() {
+		return nil
+	}
+
+	ipInstanceList, ok := list.(*networkingv1.IPInstanceList)
+	if !ok {
+		return nil
+	}
+
+	var items []networkingv1.IPInstance
+	for i := range ipInstanceList.Items {
+		for field, indexer := range r.indexers {
+			value, required := listOpts.FieldSelector.RequiresExactMatch(field)
+			if !required {
+				continue
+			}
+			for _, indexed := range indexer(&ipInstanceList.Items[i]) {
+				if indexed == value {
+					items = append(items, ipInstanceList.Items[i])
+				}
+			}
+		}
+	}
+	ipInstanceList.Items = items
+	return nil
+}
+
+func newIndexedReader(objects ...client.Object) client.Reader {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+
+	return &indexedReader{
+		Reader: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+		indexers: map[string]client.IndexerFunc{
+			InstanceIPIndex:        instanceIPIndexer,
+			InstanceNetworkIPIndex: instanceNetworkIPIndexer,
+		},
+	}
+}
+
+func TestGetIPInstanceInOverlappedAddressSpaces(t *testing.T) {
+	netID, tenantANetID, tenantBNetID := int32(4), int32(1001), int32(1002)
+	newNetwork := func(name, tenant string, netID *int32) *networkingv1.Network {
+		return &networkingv1.Network{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: networkingv1.NetworkSpec{
+				NetID:  netID,
+				Type:   networkingv1.NetworkTypeOverlay,
+				Tenant: tenant,
+			},
+		}
+	}
+	newIPInstance := func(namespace, name, network, ip string) *networkingv1.IPInstance {
+		return &networkingv1.IPInstance{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec: networkingv1.IPInstanceSpec{
+				Network: network,
+				Address: networkingv1.Address{IP: ip, Version: networkingv1.IPv4},
+			},
+		}
+	}
+
+	reader := newIndexedReader(
+		newNetwork("overlay", "", &netID),
+		newNetwork("tenant-a", "a", &tenantANetID),
+		newNetwork("tenant-b", "b", &tenantBNetID),
+		newIPInstance("default", "10-0-0-1", "overlay", "10.0.0.1/24"),
+		newIPInstance("ns-a", "172-16-0-1", "tenant-a", "172.16.0.1/24"),
+		newIPInstance("ns-b", "172-16-0-1", "tenant-b", "172.16.0.1/24"),
+		newIPInstance("ns-a", "172-16-0-2", "tenant-a", "172.16.0.2/24"),
+	)
+
+	tests := []struct {
+		name           string
+		link           netlink.Link
+		ip             string
+		expectNetwork  string
+		expectInstance string
+	}{
+		{
+			name:           "overlapped address of tenant a",
+			link:           &netlink.Vxlan{VxlanId: int(tenantANetID)},
+			ip:             "172.16.0.1",
+			expectNetwork:  "tenant-a",
+			expectInstance: "ns-a/172-16-0-1",
+		},
+		{
+			name:           "overlapped address of tenant b",
+			link:           &netlink.Vxlan{VxlanId: int(tenantBNetID)},
+			ip:             "172.16.0.1",
+			expectNetwork:  "tenant-b",
+			expectInstance: "ns-b/172-16-0-1",
+		},
+		{
+			name:          "address of other tenant",
+			link:          &netlink.Vxlan{VxlanId: int(tenantBNetID)},
+			ip:            "172.16.0.2",
+			expectNetwork: "tenant-b",
+		},
+		{
+			name:           "address of overlay network without tenant",
+			link:           &netlink.Vxlan{VxlanId: int(netID)},
+			ip:             "10.0.0.1",
+			expectInstance: "default/10-0-0-1",
+		},
+		{
+			name: "address of tenants out of shared address space",
+			link: &netlink.Vxlan{VxlanId: int(netID)},
+			ip:   "172.16.0.1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tenantNetwork, err := getTenantNetworkOfVxlanLink(context.Background(), reader, test.link)
+			if err != nil {
+				t.Fatalf("test %s fails, unexpected error: %v", test.name, err)
+			}
+			if tenantNetwork != test.expectNetwork {
+				t.Fatalf("test %s fails, expect tenant network %q but got %q", test.name, test.expectNetwork, tenantNetwork)
+			}
+
+			ipInstance, err := getIPInstanceInAddressSpace(context.Background(), reader, net.ParseIP(test.ip), tenantNetwork)
+			if err != nil {
+				t.Fatalf("test %s fails, unexpected error: %v", test.name, err)
+			}
+
+			var instance string
+			if ipInstance != nil {
+				instance = ipInstance.Namespace + "/" + ipInstance.Name
+			}
+			if instance != test.expectInstance {
+				t.Errorf("test %s fails, expect ip instance %q but got %q", test.name, test.expectInstance, instance)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/daemon/neigh"
+)
+
+// vxlanNeighReconciler keeps neigh entries of an overlay address on vxlan interfaces consistent
+// with ip instances, the entries are deleted once the pod is deleted and pointed to the vtep of
+// new node once the pod is moved. Entries are still added by proxy resolving on demand.
+type vxlanNeighReconciler struct {
+	client.Client
+	ctrlHubRef *CtrlHub
+}
+
+func (r *vxlanNeighReconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	ip := net.ParseIP(request.Name)
+	if ip == nil {
+		return reconcile.Result{}, nil
+	}
+
+	// addresses of a tenant network are only resolved on the vxlan interface of tenant network,
+	// because the same addresses may be used by other tenants
+	var tenantNetwork string
+	network := &networkingv1.Network{}
+	if err := r.Get(ctx, types.NamespacedName{Name: request.Namespace}, network); err != nil {
+		if !errors.IsNotFound(err) {
+			return reconcile.Result{Requeue: true}, fmt.Errorf("failed to get network %v: %v", request.Namespace, err)
+		}
+	} else if networkingv1.IsTenantNetwork(network) {
+		tenantNetwork = network.Name
+	}
+
+	vtepMac, err := r.ctrlHubRef.resolveVtepMac(ip, tenantNetwork)
+	if err != nil {
+		return reconcile.Result{Requeue: true}, fmt.Errorf("failed to resolve vtep of %v: %v", ip.String(), err)
+	}
+
+	linkList, err := r.ctrlHubRef.hostNetwork.LinkList()
+	if err != nil {
+		return reconcile.Result{Requeue: true}, fmt.Errorf("failed to list link: %v", err)
+	}
+
+	for _, link := range linkList {
+		if !strings.Contains(link.Attrs().Name, constants.VxlanLinkInfix) {
+			continue
+		}
+
+		linkTenantNetwork, err := getTenantNetworkOfVxlanLink(ctx, r, link)
+		if err != nil {
+			return reconcile.Result{Requeue: true}, fmt.Errorf("failed to get tenant network of link %v: %v",
+				link.Attrs().Name, err)
+		}
+		if linkTenantNetwork != tenantNetwork {
+			continue
+		}
+
+		if err := neigh.SyncVxlanNeighEntriesByIP(link.Attrs().Index, ip, vtepMac); err != nil {
+			return reconcile.Result{Requeue: true}, fmt.Errorf("failed to sync neigh entries of %v for link %v: %v",
+				ip.String(), link.Attrs().Name, err)
+		}
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// ipInstanceToAddress enqueues the address of ip instance as request name, and the network of ip
+// instance as request namespace
+func ipInstanceToAddress(obj client.Object) []reconcile.Request {
+	ipInstance, ok := obj.(*networkingv1.IPInstance)
+	if !ok {
+		return nil
+	}
+
+	ip, _, err := net.ParseCIDR(ipInstance.Spec.Address.IP)
+	if err != nil {
+		return nil
+	}
+
+	return []reconcile.Request{
+		{
+			NamespacedName: types.NamespacedName{Namespace: ipInstance.Spec.Network, Name: ip.String()},
+		},
+	}
+}
+
+func (r *vxlanNeighReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	vxlanNeighController, err := controller.New("vxlan-neigh", mgr, controller.Options{
+		Reconciler:   r,
+		RecoverPanic: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create vxlan neigh controller: %v", err)
+	}
+
+	// neigh entries of existing addresses are never changed until pods are deleted or moved, so
+	// the creation of ip instances is ignored
+	if err := vxlanNeighController.Watch(&source.Kind{Type: &networkingv1.IPInstance{}},
+		handler.EnqueueRequestsFromMapFunc(ipInstanceToAddress),
+		&predicate.Funcs{
+			CreateFunc: func(createEvent event.CreateEvent) bool {
+				return false
+			},
+			DeleteFunc: func(deleteEvent event.DeleteEvent) bool {
+				return true
+			},
+			UpdateFunc: func(updateEvent event.UpdateEvent) bool {
+				return updateEvent.ObjectOld.GetLabels()[constants.LabelNode] !=
+					updateEvent.ObjectNew.GetLabels()[constants.LabelNode]
+			},
+			GenericFunc: func(genericEvent event.GenericEvent) bool {
+				return false
+			},
+		}); err != nil {
+		return fmt.Errorf("failed to watch networkingv1.IPInstance for vxlan neigh controller: %v", err)
+	}
+
+	return nil
+}
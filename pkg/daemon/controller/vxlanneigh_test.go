@@ -0,0 +1,72 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+)
+
+func TestIPInstanceToAddress(t *testing.T) {
+	tests := []struct {
+		name   string
+		obj    client.Object
+		expect []reconcile.Request
+	}{
+		{
+			name: "ip instance",
+			obj: &networkingv1.IPInstance{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns-a", Name: "fd00--1"},
+				Spec: networkingv1.IPInstanceSpec{
+					Network: "tenant-a",
+					Address: networkingv1.Address{IP: "fd00::1/64", Version: networkingv1.IPv6},
+				},
+			},
+			expect: []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Namespace: "tenant-a", Name: "fd00::1"}},
+			},
+		},
+		{
+			name: "invalid address",
+			obj: &networkingv1.IPInstance{
+				Spec: networkingv1.IPInstanceSpec{
+					Network: "tenant-a",
+					Address: networkingv1.Address{IP: "fd00::1"},
+				},
+			},
+		},
+		{
+			name: "not ip instance",
+			obj:  &networkingv1.Network{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if requests := ipInstanceToAddress(test.obj); !reflect.DeepEqual(requests, test.expect) {
+				t.Errorf("test %s fails, expect %v but got %v", test.name, test.expect, requests)
+			}
+		})
+	}
+}
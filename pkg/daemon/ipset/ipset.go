@@ -23,6 +23,7 @@ import (
 	"encoding/base32"
 	"errors"
 	"fmt"
+	"net"
 	"os/exec"
 	"sort"
 	"strings"
@@ -672,3 +673,108 @@ func (set *Set) RefreshWithBuiltinOptions(entries [][]string) error {
 
 	return nil
 }
+
+// Sync makes a Set contain exactly the entries, unlike Refresh which rebuilds the whole set, only the
+// stale entries are deleted and the missing ones are added, so the cost of a sync is in proportion to
+// the changes. An entry may end with the "nomatch" option, and extraOptions are appended to every
+// added entry.
+func (set *Set) Sync(entries []string, extraOptions ...string) error {
+	stdout, err := set.Parent.run("save", set.name())
+	if err != nil {
+		return err
+	}
+
+	restoreContents := buildIPSetSync(set.name(), parseSetMembers(stdout), entries, extraOptions)
+	if len(restoreContents) == 0 {
+		return nil
+	}
+
+	return set.Parent.runWithStdin(bytes.NewBufferString(restoreContents), "restore", "-exist")
+}
+
+// parseSetMembers parses the output of "ipset save <set>" into members keyed by their normalized forms.
+// ex:
+// create HYBR-LOCAL-POD-IP hash:ip family inet hashsize 1024 maxelem 65536 timeout 0
+// add HYBR-LOCAL-POD-IP 100.96.1.6 timeout 0
+func parseSetMembers(result string) map[string]string {
+	members := map[string]string{}
+	for _, line := range strings.Split(result, "\n") {
+		content := strings.Fields(line)
+		if len(content) < 3 || content[0] != "add" {
+			continue
+		}
+		members[entryKey(content[2:])] = content[2]
+	}
+	return members
+}
+
+// buildIPSetSync builds the restore input to turn members into entries, stale members are deleted before
+// adding, so that an entry is still added if its saved form is different
+// ex:
+// del HYBR-LOCAL-POD-IP 100.96.1.6
+// add HYBR-LOCAL-POD-IP 100.96.1.7 timeout 0
+func buildIPSetSync(setName string, members map[string]string, entries []string, extraOptions []string) string {
+	expected := map[string]string{}
+	for _, entry := range entries {
+		expected[entryKey(strings.Fields(entry))] = entry
+	}
+
+	var staleKeys, missingKeys []string
+	for key := range members {
+		if _, exist := expected[key]; !exist {
+			staleKeys = append(staleKeys, key)
+		}
+	}
+	for key := range expected {
+		if _, exist := members[key]; !exist {
+			missingKeys = append(missingKeys, key)
+		}
+	}
+
+	// keep output in order for unit test
+	sort.Strings(staleKeys)
+	sort.Strings(missingKeys)
+
+	ipSetSync := &strings.Builder{}
+	for _, key := range staleKeys {
+		ipSetSync.WriteString(fmt.Sprintf("del %s %s\n", setName, members[key]))
+	}
+	for _, key := range missingKeys {
+		ipSetSync.WriteString(strings.Join(append([]string{"add", setName, expected[key]}, extraOptions...), " ") + "\n")
+	}
+
+	return ipSetSync.String()
+}
+
+// entryKey identifies an entry by its normalized member and whether it is marked as nomatch
+func entryKey(fields []string) string {
+	if len(fields) == 0 {
+		return ""
+	}
+
+	key := normalizeMember(fields[0])
+	for _, option := range fields[1:] {
+		if option == OptionNoMatch {
+			return key + " " + OptionNoMatch
+		}
+	}
+	return key
+}
+
+// normalizeMember formats addresses and cidrs in a member the same as ipset, e.g., a cidr of
+// a single address is saved without prefix length
+func normalizeMember(member string) string {
+	parts := strings.Split(member, ",")
+	for i, part := range parts {
+		if ip, cidr, err := net.ParseCIDR(part); err == nil {
+			if ones, bits := cidr.Mask.Size(); ones == bits {
+				parts[i] = ip.String()
+			} else {
+				parts[i] = cidr.String()
+			}
+		} else if ip := net.ParseIP(part); ip != nil {
+			parts[i] = ip.String()
+		}
+	}
+	return strings.Join(parts, ",")
+}
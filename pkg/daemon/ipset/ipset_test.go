@@ -0,0 +1,66 @@
+/*
+  Copyright 2004 The Kube-router Authors.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package ipset
+
+import "testing"
+
+func TestBuildIPSetSync(t *testing.T) {
+	saved := `create HYBR-SOURCE-GUARD hash:net,iface family inet hashsize 1024 maxelem 65536 timeout 0
+add HYBR-SOURCE-GUARD 0.0.0.0/1,h_a timeout 0
+add HYBR-SOURCE-GUARD 10.0.0.1,h_a timeout 0 nomatch
+add HYBR-SOURCE-GUARD 10.0.0.2,h_b timeout 0 nomatch
+`
+
+	tests := []struct {
+		name    string
+		entries []string
+		expect  string
+	}{
+		{
+			name:    "unchanged",
+			entries: []string{"0.0.0.0/1,h_a", "10.0.0.1/32,h_a nomatch", "10.0.0.2,h_b nomatch"},
+			expect:  "",
+		},
+		{
+			name:    "stale and missing",
+			entries: []string{"0.0.0.0/1,h_a", "128.0.0.0/1,h_a", "10.0.0.1,h_a nomatch"},
+			expect: "del HYBR-SOURCE-GUARD 10.0.0.2,h_b\n" +
+				"add HYBR-SOURCE-GUARD 128.0.0.0/1,h_a timeout 0\n",
+		},
+		{
+			name:    "nomatch changed",
+			entries: []string{"0.0.0.0/1,h_a", "10.0.0.1,h_a", "10.0.0.2,h_b nomatch"},
+			expect: "del HYBR-SOURCE-GUARD 10.0.0.1,h_a\n" +
+				"add HYBR-SOURCE-GUARD 10.0.0.1,h_a timeout 0\n",
+		},
+		{
+			name:    "empty",
+			entries: nil,
+			expect: "del HYBR-SOURCE-GUARD 0.0.0.0/1,h_a\n" +
+				"del HYBR-SOURCE-GUARD 10.0.0.1,h_a\n" +
+				"del HYBR-SOURCE-GUARD 10.0.0.2,h_b\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := buildIPSetSync("HYBR-SOURCE-GUARD", parseSetMembers(saved), test.entries, []string{OptionTimeout, "0"})
+			if result != test.expect {
+				t.Errorf("test %s fail, expect:\n%s\nbut got:\n%s", test.name, test.expect, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package iptables
+
+import (
+	"net"
+	"sync"
+
+	globalutils "github.com/alibaba/hybridnet/pkg/utils"
+)
+
+// FakeSubnet is a subnet recorded by FakeManager.
+type FakeSubnet struct {
+	Cidr      *net.IPNet
+	IsOverlay bool
+	IsLocal   bool
+	IsRemote  bool
+}
+
+// FakeEgressRules are egress rules of a local pod recorded by FakeManager.
+type FakeEgressRules struct {
+	PodIP net.IP
+	Rules []globalutils.EgressRule
+}
+
+// FakeRecords are everything recorded by FakeManager since the last Reset.
+type FakeRecords struct {
+	NodeIPs            []net.IP
+	LocalNodeIPs       []net.IP
+	LocalPodIPs        []net.IP
+	RemoteNodeIPs      []net.IP
+	Subnets            []FakeSubnet
+	EgressRules        []FakeEgressRules
+	PodMACs            map[string]net.HardwareAddr
+	PodSourceGuards    map[string][]net.IP
+	OverlayIfName      string
+	BgpIfName          string
+	VlanForwardIfNames []string
+	NoTrackFlows       []NoTrackFlow
+}
+
+// FakeManager is an in-memory Interface which records expected rules instead of
+// configuring iptables and ipset on host, for testing without privileges.
+type FakeManager struct {
+	mu sync.Mutex
+
+	protocol Protocol
+
+	records   FakeRecords
+	synced    FakeRecords
+	syncCount int
+
+	// SyncErr will be returned by SyncRules if not nil.
+	SyncErr error
+
+	// OnSync will be called with the synced records every time SyncRules succeeds.
+	OnSync func(protocol Protocol, records FakeRecords)
+}
+
+var _ Interface = &FakeManager{}
+
+func NewFakeManager(protocol Protocol) *FakeManager {
+	f := &FakeManager{
+		protocol: protocol,
+	}
+	f.Reset()
+	return f
+}
+
+func (f *FakeManager) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.records = FakeRecords{
+		PodMACs:         map[string]net.HardwareAddr{},
+		PodSourceGuards: map[string][]net.IP{},
+	}
+}
+
+func (f *FakeManager) RecordNodeIP(nodeIP net.IP) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.records.NodeIPs = append(f.records.NodeIPs, nodeIP)
+}
+
+func (f *FakeManager) RecordLocalNodeIP(nodeIP net.IP) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.records.LocalNodeIPs = append(f.records.LocalNodeIPs, nodeIP)
+}
+
+func (f *FakeManager) RecordLocalPodIP(podIP net.IP) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.records.LocalPodIPs = append(f.records.LocalPodIPs, podIP)
+}
+
+func (f *FakeManager) RecordLocalPodEgressRules(podIP net.IP, rules []globalutils.EgressRule) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var familyRules []globalutils.EgressRule
+	for _, rule := range rules {
+		if (rule.CIDR.IP.To4() != nil) == (f.protocol == ProtocolIpv4) {
+			familyRules = append(familyRules, rule)
+		}
+	}
+	f.records.EgressRules = append(f.records.EgressRules, FakeEgressRules{PodIP: podIP, Rules: familyRules})
+}
+
+func (f *FakeManager) RecordLocalPodMAC(hostIfName string, mac net.HardwareAddr) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.records.PodMACs[hostIfName] = mac
+}
+
+func (f *FakeManager) RecordLocalPodSourceGuard(hostIfName string, podIP net.IP) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.records.PodSourceGuards[hostIfName] = append(f.records.PodSourceGuards[hostIfName], podIP)
+}
+
+func (f *FakeManager) RecordSubnet(subnetCidr *net.IPNet, isOverlay, isLocal bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.records.Subnets = append(f.records.Subnets, FakeSubnet{Cidr: subnetCidr, IsOverlay: isOverlay, IsLocal: isLocal})
+}
+
+func (f *FakeManager) RecordRemoteNodeIP(nodeIP net.IP) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.records.RemoteNodeIPs = append(f.records.RemoteNodeIPs, nodeIP)
+}
+
+func (f *FakeManager) RecordRemoteSubnet(subnetCidr *net.IPNet, isOverlay bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.records.Subnets = append(f.records.Subnets, FakeSubnet{Cidr: subnetCidr, IsOverlay: isOverlay, IsRemote: true})
+}
+
+func (f *FakeManager) SetOverlayIfName(overlayIfName string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.records.OverlayIfName = overlayIfName
+}
+
+func (f *FakeManager) SetBgpIfName(bgpIfName string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.records.BgpIfName = bgpIfName
+}
+
+func (f *FakeManager) RecordVlanForwardIfName(vlanForwardIfName string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, name := range f.records.VlanForwardIfNames {
+		if name == vlanForwardIfName {
+			return
+		}
+	}
+	f.records.VlanForwardIfNames = append(f.records.VlanForwardIfNames, vlanForwardIfName)
+}
+
+func (f *FakeManager) RecordNoTrackFlow(flow NoTrackFlow) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if (flow.Source.IP.To4() != nil) != (f.protocol == ProtocolIpv4) {
+		return
+	}
+	f.records.NoTrackFlows = append(f.records.NoTrackFlows, flow)
+}
+
+func (f *FakeManager) SyncRules() error {
+	f.mu.Lock()
+	if f.SyncErr != nil {
+		f.mu.Unlock()
+		return f.SyncErr
+	}
+
+	f.synced = f.records.deepCopy()
+	f.syncCount++
+	synced, onSync := f.synced.deepCopy(), f.OnSync
+	f.mu.Unlock()
+
+	if onSync != nil {
+		onSync(f.protocol, synced)
+	}
+	return nil
+}
+
+// Synced returns the records of the last successful SyncRules.
+func (f *FakeManager) Synced() FakeRecords {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.synced.deepCopy()
+}
+
+// SyncCount returns how many times SyncRules succeeds.
+func (f *FakeManager) SyncCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.syncCount
+}
+
+func (r FakeRecords) deepCopy() FakeRecords {
+	out := r
+	out.NodeIPs = append([]net.IP(nil), r.NodeIPs...)
+	out.LocalNodeIPs = append([]net.IP(nil), r.LocalNodeIPs...)
+	out.LocalPodIPs = append([]net.IP(nil), r.LocalPodIPs...)
+	out.RemoteNodeIPs = append([]net.IP(nil), r.RemoteNodeIPs...)
+	out.Subnets = append([]FakeSubnet(nil), r.Subnets...)
+	out.EgressRules = append([]FakeEgressRules(nil), r.EgressRules...)
+	out.VlanForwardIfNames = append([]string(nil), r.VlanForwardIfNames...)
+	out.NoTrackFlows = append([]NoTrackFlow(nil), r.NoTrackFlows...)
+
+	out.PodMACs = make(map[string]net.HardwareAddr, len(r.PodMACs))
+	for k, v := range r.PodMACs {
+		out.PodMACs[k] = v
+	}
+	out.PodSourceGuards = make(map[string][]net.IP, len(r.PodSourceGuards))
+	for k, v := range r.PodSourceGuards {
+		out.PodSourceGuards[k] = append([]net.IP(nil), v...)
+	}
+	return out
+}
@@ -20,12 +20,15 @@ import (
 	"bytes"
 	"fmt"
 	"net"
+	"strconv"
+	"strings"
 
 	"github.com/alibaba/hybridnet/pkg/constants"
 
 	extraliptables "github.com/coreos/go-iptables/iptables"
 
 	"github.com/alibaba/hybridnet/pkg/daemon/ipset"
+	globalutils "github.com/alibaba/hybridnet/pkg/utils"
 
 	utiliptables "k8s.io/kubernetes/pkg/util/iptables"
 	"k8s.io/utils/exec"
@@ -35,6 +38,7 @@ const (
 	TableNAT    = "nat"
 	TableFilter = "filter"
 	TableMangle = "mangle"
+	TableRaw    = "raw"
 
 	ChainPostRouting = "POSTROUTING"
 	ChainPreRouting  = "PREROUTING"
@@ -45,9 +49,12 @@ const (
 	ChainHybridnetPostRouting = CustomChainPrefix + "POSTROUTING"
 	ChainHybridnetForward     = CustomChainPrefix + "FORWARD"
 	ChainHybridnetPreRouting  = CustomChainPrefix + "PREROUTING"
+	ChainHybridnetNoTrack     = CustomChainPrefix + "NOTRACK"
 
 	ChainHybridnetFromRuleSkip         = CustomChainPrefix + "FROM-RULE-SKIP"
 	ChainHybridnetPodToNodeTrafficMark = CustomChainPrefix + "POD-TO-NODE-MARK"
+	ChainHybridnetEgress               = CustomChainPrefix + "EGRESS"
+	ChainHybridnetSourceGuard          = CustomChainPrefix + "SOURCE-GUARD"
 
 	// The origin ip set name below should not be longer than 25 characters, because v6 ip set name will get an "inet6:" prefix,
 	// and the actual length of ip set name should not be longer than 31 characters.
@@ -57,6 +64,11 @@ const (
 	HybridnetNodeIPSetName           = "HYBR-NODE-IP"
 	HybridnetLocalPodIPSetName       = "HYBR-LOCAL-POD-IP"
 	HybridnetLocalUnderlayNetSetName = "HYBR-LOCAL-UNDERLAY-NET"
+	HybridnetLocalClusterNetSetName  = "HYBR-LOCAL-CLUSTER-NET"
+	HybridnetSourceGuardSetName      = "HYBR-SOURCE-GUARD"
+	HybridnetEgressPodSetName        = "HYBR-EGRESS-POD"
+	HybridnetEgressNetSetName        = "HYBR-EGRESS-NET"
+	HybridnetEgressPortSetName       = "HYBR-EGRESS-PORT"
 
 	PodToNodeBackTrafficMarkString = "0x20"
 	FullNATedPodTrafficMarkString  = "0x40"
@@ -77,6 +89,52 @@ const (
 	ProtocolIpv6
 )
 
+type podEgressRules struct {
+	podIP net.IP
+	rules []globalutils.EgressRule
+}
+
+type podMAC struct {
+	hostIfName string
+	mac        net.HardwareAddr
+}
+
+type podSourceGuard struct {
+	hostIfName string
+	podIPs     []net.IP
+}
+
+// NoTrackFlow is a flow from pods of a subnet to destination whose packets in both directions
+// are not tracked by conntrack, a zero port means all the ports of destination
+type NoTrackFlow struct {
+	Source      *net.IPNet
+	Destination *net.IPNet
+	Protocol    string
+	Port        int
+}
+
+// Interface records expected iptables rules and ip sets and syncs them, it's implemented
+// by Manager which configures the host and by FakeManager which keeps everything in memory.
+type Interface interface {
+	Reset()
+	RecordNodeIP(nodeIP net.IP)
+	RecordLocalNodeIP(nodeIP net.IP)
+	RecordLocalPodIP(podIP net.IP)
+	RecordLocalPodEgressRules(podIP net.IP, rules []globalutils.EgressRule)
+	RecordLocalPodMAC(hostIfName string, mac net.HardwareAddr)
+	RecordLocalPodSourceGuard(hostIfName string, podIP net.IP)
+	RecordSubnet(subnetCidr *net.IPNet, isOverlay, isLocal bool)
+	RecordRemoteNodeIP(nodeIP net.IP)
+	RecordRemoteSubnet(subnetCidr *net.IPNet, isOverlay bool)
+	SetOverlayIfName(overlayIfName string)
+	SetBgpIfName(bgpIfName string)
+	RecordVlanForwardIfName(vlanForwardIfName string)
+	RecordNoTrackFlow(flow NoTrackFlow)
+	SyncRules() error
+}
+
+var _ Interface = &Manager{}
+
 type Manager struct {
 	executor utiliptables.Interface
 	helper   *extraliptables.IPTables
@@ -90,6 +148,18 @@ type Manager struct {
 	localNodeIPList []net.IP
 	localPodIPList  []net.IP
 
+	// egress allowlists of local pods, traffic of other pods is not limited
+	localPodEgressRules []podEgressRules
+
+	// local pods which can only send frames with MAC of their ip instances
+	localPodMACs []podMAC
+
+	// local pods which can only send packets with their own addresses as source
+	localPodSourceGuards []podSourceGuard
+
+	// flows of pods exempted from connection tracking
+	noTrackFlows []NoTrackFlow
+
 	overlayIfName      string
 	bgpIfName          string
 	vlanForwardIfNames []string
@@ -169,6 +239,10 @@ func (mgr *Manager) Reset() {
 	mgr.nodeIPList = []net.IP{}
 	mgr.localNodeIPList = []net.IP{}
 	mgr.localPodIPList = []net.IP{}
+	mgr.localPodEgressRules = []podEgressRules{}
+	mgr.localPodMACs = []podMAC{}
+	mgr.localPodSourceGuards = []podSourceGuard{}
+	mgr.noTrackFlows = []NoTrackFlow{}
 	mgr.vlanForwardIfNames = []string{}
 	mgr.overlayIfName = ""
 
@@ -189,6 +263,40 @@ func (mgr *Manager) RecordLocalPodIP(podIP net.IP) {
 	mgr.localPodIPList = append(mgr.localPodIPList, podIP)
 }
 
+// RecordLocalPodEgressRules limits egress traffic of local pod to the rules, rules of
+// another ip family are ignored
+func (mgr *Manager) RecordLocalPodEgressRules(podIP net.IP, rules []globalutils.EgressRule) {
+	var familyRules []globalutils.EgressRule
+	for _, rule := range rules {
+		if (rule.CIDR.IP.To4() != nil) == (mgr.protocol == ProtocolIpv4) {
+			familyRules = append(familyRules, rule)
+		}
+	}
+	mgr.localPodEgressRules = append(mgr.localPodEgressRules, podEgressRules{podIP: podIP, rules: familyRules})
+}
+
+// RecordLocalPodMAC drops frames from host interface of local pod whose source MAC is not mac
+func (mgr *Manager) RecordLocalPodMAC(hostIfName string, mac net.HardwareAddr) {
+	for _, recorded := range mgr.localPodMACs {
+		if recorded.hostIfName == hostIfName && bytes.Equal(recorded.mac, mac) {
+			return
+		}
+	}
+	mgr.localPodMACs = append(mgr.localPodMACs, podMAC{hostIfName: hostIfName, mac: mac})
+}
+
+// RecordLocalPodSourceGuard drops packets from host interface of local pod whose source is not
+// one of the recorded pod ips
+func (mgr *Manager) RecordLocalPodSourceGuard(hostIfName string, podIP net.IP) {
+	for i := range mgr.localPodSourceGuards {
+		if mgr.localPodSourceGuards[i].hostIfName == hostIfName {
+			mgr.localPodSourceGuards[i].podIPs = append(mgr.localPodSourceGuards[i].podIPs, podIP)
+			return
+		}
+	}
+	mgr.localPodSourceGuards = append(mgr.localPodSourceGuards, podSourceGuard{hostIfName: hostIfName, podIPs: []net.IP{podIP}})
+}
+
 func (mgr *Manager) RecordSubnet(subnetCidr *net.IPNet, isOverlay, isLocal bool) {
 	if isOverlay {
 		mgr.localClusterOverlaySubnets = append(mgr.localClusterOverlaySubnets, subnetCidr)
@@ -230,6 +338,15 @@ func (mgr *Manager) RecordVlanForwardIfName(vlanForwardIfName string) {
 	mgr.vlanForwardIfNames = append(mgr.vlanForwardIfNames, vlanForwardIfName)
 }
 
+// RecordNoTrackFlow exempts packets of flow from connection tracking, flows of another ip family
+// are ignored
+func (mgr *Manager) RecordNoTrackFlow(flow NoTrackFlow) {
+	if (flow.Source.IP.To4() != nil) != (mgr.protocol == ProtocolIpv4) {
+		return
+	}
+	mgr.noTrackFlows = append(mgr.noTrackFlows, flow)
+}
+
 func (mgr *Manager) SyncRules() error {
 	mgr.lock()
 	defer mgr.unlock()
@@ -254,7 +371,12 @@ func (mgr *Manager) SyncRules() error {
 		return fmt.Errorf("failed to create ipset instance: %v", err)
 	}
 
-	var overlayNetSet, allIPSet, nodeIPSet, localUnderlayNetSet, localPodIPSet *ipset.Set
+	localClusterIPNets := generateStringsFromIPNets(append(mgr.localClusterUnderlaySubnets, mgr.localClusterOverlaySubnets...))
+	sourceGuardEntries := generateSourceGuardEntries(mgr.localPodSourceGuards, mgr.protocol)
+	egressPodIPs, egressNetEntries, egressPortEntries := generateEgressEntries(mgr.localPodEgressRules)
+
+	var overlayNetSet, allIPSet, nodeIPSet, localUnderlayNetSet, localPodIPSet, localClusterNetSet,
+		sourceGuardSet, egressPodSet, egressNetSet, egressPortSet *ipset.Set
 
 	if overlayNetSet, err = createAndRefreshIPSet(ipsetInterface, HybridnetOverlayNetSetName, overlayIPNets,
 		ipset.TypeHashNet, ipset.OptionTimeout, "0"); err != nil {
@@ -281,6 +403,31 @@ func (mgr *Manager) SyncRules() error {
 		return fmt.Errorf("failed to create and refresh ip set %v: %v", HybridnetLocalPodIPSetName, err)
 	}
 
+	if localClusterNetSet, err = createAndRefreshIPSet(ipsetInterface, HybridnetLocalClusterNetSetName, localClusterIPNets,
+		ipset.TypeHashNet, ipset.OptionTimeout, "0"); err != nil {
+		return fmt.Errorf("failed to create and refresh ip set %v: %v", HybridnetLocalClusterNetSetName, err)
+	}
+
+	if sourceGuardSet, err = createAndRefreshIPSet(ipsetInterface, HybridnetSourceGuardSetName, sourceGuardEntries,
+		ipset.TypeHashNetIface, ipset.OptionTimeout, "0"); err != nil {
+		return fmt.Errorf("failed to create and refresh ip set %v: %v", HybridnetSourceGuardSetName, err)
+	}
+
+	if egressPodSet, err = createAndRefreshIPSet(ipsetInterface, HybridnetEgressPodSetName, egressPodIPs,
+		ipset.TypeHashIP, ipset.OptionTimeout, "0"); err != nil {
+		return fmt.Errorf("failed to create and refresh ip set %v: %v", HybridnetEgressPodSetName, err)
+	}
+
+	if egressNetSet, err = createAndRefreshIPSet(ipsetInterface, HybridnetEgressNetSetName, egressNetEntries,
+		ipset.TypeHashNetNet, ipset.OptionTimeout, "0"); err != nil {
+		return fmt.Errorf("failed to create and refresh ip set %v: %v", HybridnetEgressNetSetName, err)
+	}
+
+	if egressPortSet, err = createAndRefreshIPSet(ipsetInterface, HybridnetEgressPortSetName, egressPortEntries,
+		ipset.TypeHashIPPortNet, ipset.OptionTimeout, "0"); err != nil {
+		return fmt.Errorf("failed to create and refresh ip set %v: %v", HybridnetEgressPortSetName, err)
+	}
+
 	if err := mgr.ensureBasicRuleAndChains(); err != nil {
 		return fmt.Errorf("failed to ensure basic rules and chains: %v", err)
 	}
@@ -292,11 +439,14 @@ func (mgr *Manager) SyncRules() error {
 	natRules := bytes.NewBuffer(nil)
 	mangleChains := bytes.NewBuffer(nil)
 	mangleRules := bytes.NewBuffer(nil)
+	rawChains := bytes.NewBuffer(nil)
+	rawRules := bytes.NewBuffer(nil)
 
 	// Write table headers.
 	writeLine(natChains, "*nat")
 	writeLine(filterChains, "*filter")
 	writeLine(mangleChains, "*mangle")
+	writeLine(rawChains, "*raw")
 
 	writeLine(natChains, utiliptables.MakeChainLine(ChainHybridnetPostRouting))
 	writeLine(filterChains, utiliptables.MakeChainLine(ChainHybridnetForward))
@@ -304,6 +454,34 @@ func (mgr *Manager) SyncRules() error {
 	writeLine(mangleChains, utiliptables.MakeChainLine(ChainHybridnetPostRouting))
 	writeLine(mangleChains, utiliptables.MakeChainLine(ChainHybridnetFromRuleSkip))
 	writeLine(mangleChains, utiliptables.MakeChainLine(ChainHybridnetPodToNodeTrafficMark))
+	writeLine(filterChains, utiliptables.MakeChainLine(ChainHybridnetEgress))
+	writeLine(mangleChains, utiliptables.MakeChainLine(ChainHybridnetSourceGuard))
+	writeLine(rawChains, utiliptables.MakeChainLine(ChainHybridnetNoTrack))
+
+	// egress rules must be checked before any other forward rules, allowlists of all the pods
+	// are matched by ip sets, so the count of rules is constant
+	if len(mgr.localPodEgressRules) != 0 {
+		writeLine(filterRules, generateEgressJumpRuleSpec()...)
+		writeLine(filterRules, generateEgressEstablishedRuleSpec()...)
+		writeLine(filterRules, generateEgressAllowNetRuleSpec(egressNetSet.GetNameWithProtocol())...)
+		writeLine(filterRules, generateEgressAllowPortRuleSpec(egressPortSet.GetNameWithProtocol())...)
+		writeLine(filterRules, generateEgressRejectRuleSpec(egressPodSet.GetNameWithProtocol(), mgr.protocol)...)
+	}
+
+	// spoofed frames must be dropped before any other prerouting rules
+	for _, podMAC := range mgr.localPodMACs {
+		writeLine(mangleRules, generateMACSpoofDropRuleSpec(podMAC.hostIfName, podMAC.mac)...)
+	}
+
+	if len(mgr.localPodSourceGuards) != 0 {
+		writeLine(mangleRules, generateSourceGuardJumpRuleSpec()...)
+		if mgr.protocol == ProtocolIpv6 {
+			// neighbor discovery and duplicate address detection use link-local and unspecified source
+			writeLine(mangleRules, generateSourceGuardAllowRuleSpec("", "fe80::/10")...)
+			writeLine(mangleRules, generateSourceGuardAllowRuleSpec("", "::/128")...)
+		}
+		writeLine(mangleRules, generateSourceGuardDropRuleSpec(sourceGuardSet.GetNameWithProtocol())...)
+	}
 
 	if len(mgr.overlayIfName) != 0 {
 		// There might be two scenarios where overlayIfName is nil
@@ -338,15 +516,21 @@ func (mgr *Manager) SyncRules() error {
 	}
 
 	writeLine(mangleRules, generateFullNATMarkSNATRuleSpec()...)
+	writeLine(mangleRules, generateFullNATMarkDNATRuleSpec()...)
 	// no need for remote subnets, because there are no "from" rules for them
-	for _, subnet := range append(mgr.localClusterUnderlaySubnets, mgr.localClusterOverlaySubnets...) {
-		writeLine(mangleRules, generateFullNATMarkDNATRuleSpec(subnet)...)
+	writeLine(mangleRules, generateFullNATConnMarkRuleSpec(localClusterNetSet.GetNameWithProtocol())...)
+
+	for _, flow := range mgr.noTrackFlows {
+		for _, ruleSpec := range generateNoTrackRuleSpecs(flow) {
+			writeLine(rawRules, ruleSpec...)
+		}
 	}
 
 	// Write the end-of-table markers
 	writeLine(natRules, "COMMIT")
 	writeLine(filterRules, "COMMIT")
 	writeLine(mangleRules, "COMMIT")
+	writeLine(rawRules, "COMMIT")
 
 	// Sync rules
 	iptablesData.Write(natChains.Bytes())
@@ -355,6 +539,8 @@ func (mgr *Manager) SyncRules() error {
 	iptablesData.Write(filterRules.Bytes())
 	iptablesData.Write(mangleChains.Bytes())
 	iptablesData.Write(mangleRules.Bytes())
+	iptablesData.Write(rawChains.Bytes())
+	iptablesData.Write(rawRules.Bytes())
 
 	if err := mgr.executor.RestoreAll(iptablesData.Bytes(), utiliptables.NoFlushTables,
 		utiliptables.RestoreCounters); err != nil {
@@ -414,6 +600,16 @@ func (mgr *Manager) ensureBasicRuleAndChains() error {
 		return fmt.Errorf("failed to ensure %v rule in %v table: %v", ChainHybridnetPostRouting, TableMangle, err)
 	}
 
+	// ensure base chain and rule for HYBRIDNET-NOTRACK in raw table
+	if _, err := mgr.executor.EnsureChain(TableRaw, ChainHybridnetNoTrack); err != nil {
+		return fmt.Errorf("failed to ensure %v chain in %v table: %v", ChainHybridnetNoTrack, TableRaw, err)
+	}
+
+	if _, err := mgr.executor.EnsureRule(utiliptables.Append, TableRaw, ChainPreRouting,
+		generateHybridnetNoTrackBaseRuleSpec()...); err != nil {
+		return fmt.Errorf("failed to ensure %v rule in %v table: %v", ChainHybridnetNoTrack, TableRaw, err)
+	}
+
 	return nil
 }
 
@@ -423,7 +619,7 @@ func createAndRefreshIPSet(ipsetInterface *ipset.IPSet, setName string, members
 		return nil, fmt.Errorf("failed to create ip set: %v", err)
 	}
 
-	if err = set.Refresh(members); err != nil {
+	if err = set.Sync(members); err != nil {
 		return nil, fmt.Errorf("failed to refresh ip set: %v", err)
 	}
 
@@ -442,6 +638,10 @@ func generateHybridnetPreRoutingBaseRuleSpec() []string {
 	return []string{"-m", "comment", "--comment", "hybridnet prerouting rules", "-j", ChainHybridnetPreRouting}
 }
 
+func generateHybridnetNoTrackBaseRuleSpec() []string {
+	return []string{"-m", "comment", "--comment", "hybridnet notrack rules", "-j", ChainHybridnetNoTrack}
+}
+
 func generateMasqueradeRuleSpec(vxlanIf, overlayNetSet string) []string {
 	return []string{"-A", ChainHybridnetPostRouting, "-m", "comment", "--comment", `"hybridnet overlay nat-outgoing masquerade rule"`,
 		"!", "-o", vxlanIf, "-m", "set", "--match-set", overlayNetSet, "src", "-j", "MASQUERADE"}
@@ -513,6 +713,56 @@ func generateUnderlayEndLoopRuleSpec(underlayIf, localPodIPSet, localUnderlayNet
 	}
 }
 
+func generateEgressJumpRuleSpec() []string {
+	return []string{"-A", ChainHybridnetForward, "-m", "comment", "--comment", `"hybridnet pod egress rules"`,
+		"-i", constants.ContainerHostLinkPrefix + "+", "-j", ChainHybridnetEgress}
+}
+
+func generateEgressEstablishedRuleSpec() []string {
+	return []string{"-A", ChainHybridnetEgress, "-m", "comment", "--comment", `"allow established pod egress traffic"`,
+		"-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "RETURN"}
+}
+
+func generateEgressAllowNetRuleSpec(egressNetSet string) []string {
+	return []string{"-A", ChainHybridnetEgress, "-m", "comment", "--comment", `"allowed pod egress traffic"`,
+		"-m", "set", "--match-set", egressNetSet, "src,dst", "-j", "RETURN"}
+}
+
+func generateEgressAllowPortRuleSpec(egressPortSet string) []string {
+	return []string{"-A", ChainHybridnetEgress, "-m", "comment", "--comment", `"allowed pod egress traffic with port"`,
+		"-m", "set", "--match-set", egressPortSet, "src,dst,dst", "-j", "RETURN"}
+}
+
+func generateEgressRejectRuleSpec(egressPodSet string, protocol Protocol) []string {
+	return []string{"-A", ChainHybridnetEgress, "-m", "comment", "--comment", `"reject pod egress traffic not allowed"`,
+		"-m", "set", "--match-set", egressPodSet, "src", "-j", "REJECT", "--reject-with", rejectWithOption(protocol)}
+}
+
+func generateMACSpoofDropRuleSpec(hostIfName string, mac net.HardwareAddr) []string {
+	return []string{"-A", ChainHybridnetPreRouting, "-m", "comment", "--comment", `"drop pod traffic with spoofed mac"`,
+		"-i", hostIfName, "-m", "mac", "!", "--mac-source", mac.String(), "-j", "DROP"}
+}
+
+func generateSourceGuardJumpRuleSpec() []string {
+	return []string{"-A", ChainHybridnetPreRouting, "-m", "comment", "--comment", `"hybridnet pod source guard rules"`,
+		"-i", constants.ContainerHostLinkPrefix + "+", "-j", ChainHybridnetSourceGuard}
+}
+
+// generateSourceGuardAllowRuleSpec matches all interfaces if hostIfName is empty
+func generateSourceGuardAllowRuleSpec(hostIfName, source string) []string {
+	spec := []string{"-A", ChainHybridnetSourceGuard, "-m", "comment", "--comment", `"allowed pod source"`}
+	if len(hostIfName) != 0 {
+		spec = append(spec, "-i", hostIfName)
+	}
+	return append(spec, "-s", source, "-j", "RETURN")
+}
+
+// the source guard set matches any source from guarded interfaces except the pod ips marked as nomatch
+func generateSourceGuardDropRuleSpec(sourceGuardSet string) []string {
+	return []string{"-A", ChainHybridnetSourceGuard, "-m", "comment", "--comment", `"drop pod traffic with spoofed source"`,
+		"-m", "set", "--match-set", sourceGuardSet, "src,src", "-j", "DROP"}
+}
+
 func generateFullNATMarkSNATRuleSpec() []string {
 	return []string{"-A", ChainHybridnetPreRouting, "-m", "comment", "--comment", `"match full NATed pod traffic"`,
 		"-m", "conntrack", "--ctstate", "SNAT",
@@ -520,13 +770,47 @@ func generateFullNATMarkSNATRuleSpec() []string {
 	}
 }
 
-func generateFullNATMarkDNATRuleSpec(cidr *net.IPNet) []string {
+// generateFullNATMarkDNATRuleSpec marks packets of connections DNATed to local cluster pods, which are
+// recorded in connmark by generateFullNATConnMarkRuleSpec, because conntrack match is unable to match
+// the reply source with an ip set
+func generateFullNATMarkDNATRuleSpec() []string {
 	return []string{"-A", ChainHybridnetFromRuleSkip, "-m", "conntrack", "--ctstate", "DNAT",
-		"--ctreplsrc", cidr.String(), "-j", "MARK", "--set-xmark", fmt.Sprintf("%s/%s",
+		"-m", "connmark", "--mark", fmt.Sprintf("%s/%s", FullNATedPodTrafficMarkString, FullNATedPodTrafficMarkString),
+		"-j", "MARK", "--set-xmark", fmt.Sprintf("%s/%s",
 			FullNATedPodTrafficMarkString, FullNATedPodTrafficMarkString),
 	}
 }
 
+// destination of DNATed original traffic in postrouting is the reply source of connection
+func generateFullNATConnMarkRuleSpec(localClusterNetSet string) []string {
+	return []string{"-A", ChainHybridnetPostRouting, "-m", "comment", "--comment", `"mark connection DNATed to pod"`,
+		"-m", "conntrack", "--ctstate", "DNAT",
+		"-m", "set", "--match-set", localClusterNetSet, "dst",
+		"-j", "CONNMARK", "--set-xmark", fmt.Sprintf("%s/%s",
+			FullNATedPodTrafficMarkString, FullNATedPodTrafficMarkString),
+	}
+}
+
+// generateNoTrackRuleSpecs exempts both the packets from pods to destination and the replies
+// from connection tracking
+func generateNoTrackRuleSpecs(flow NoTrackFlow) [][]string {
+	protocol := strings.ToLower(flow.Protocol)
+	original := []string{"-A", ChainHybridnetNoTrack, "-m", "comment", "--comment", `"notrack pod flow"`,
+		"-s", flow.Source.String(), "-d", flow.Destination.String(), "-p", protocol}
+	reply := []string{"-A", ChainHybridnetNoTrack, "-m", "comment", "--comment", `"notrack pod flow reply"`,
+		"-s", flow.Destination.String(), "-d", flow.Source.String(), "-p", protocol}
+
+	if flow.Port != 0 {
+		original = append(original, "--dport", strconv.Itoa(flow.Port))
+		reply = append(reply, "--sport", strconv.Itoa(flow.Port))
+	}
+
+	return [][]string{
+		append(original, "-j", "CT", "--notrack"),
+		append(reply, "-j", "CT", "--notrack"),
+	}
+}
+
 func rejectWithOption(protocol Protocol) string {
 	if protocol == ProtocolIpv4 {
 		return "icmp-host-unreachable"
@@ -0,0 +1,88 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package iptables
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestGenerateMACSpoofDropRuleSpec(t *testing.T) {
+	mac, _ := net.ParseMAC("0a:1b:2c:3d:4e:5f")
+
+	expected := []string{"-A", ChainHybridnetPreRouting, "-m", "comment", "--comment", `"drop pod traffic with spoofed mac"`,
+		"-i", "h_1234", "-m", "mac", "!", "--mac-source", "0a:1b:2c:3d:4e:5f", "-j", "DROP"}
+	if spec := generateMACSpoofDropRuleSpec("h_1234", mac); !reflect.DeepEqual(spec, expected) {
+		t.Errorf("expected rule spec %v but got %v", expected, spec)
+	}
+}
+
+func TestRecordLocalPodMAC(t *testing.T) {
+	mac1, _ := net.ParseMAC("0a:1b:2c:3d:4e:5f")
+	mac2, _ := net.ParseMAC("0a:1b:2c:3d:4e:60")
+
+	tests := []struct {
+		name    string
+		records []podMAC
+		expect  []podMAC
+	}{
+		{
+			name:    "single pod",
+			records: []podMAC{{hostIfName: "h_1", mac: mac1}},
+			expect:  []podMAC{{hostIfName: "h_1", mac: mac1}},
+		},
+		{
+			name: "duplicated records of dual-stack pod",
+			records: []podMAC{
+				{hostIfName: "h_1", mac: mac1},
+				{hostIfName: "h_1", mac: mac1},
+			},
+			expect: []podMAC{{hostIfName: "h_1", mac: mac1}},
+		},
+		{
+			name: "different pods",
+			records: []podMAC{
+				{hostIfName: "h_1", mac: mac1},
+				{hostIfName: "h_2", mac: mac2},
+			},
+			expect: []podMAC{
+				{hostIfName: "h_1", mac: mac1},
+				{hostIfName: "h_2", mac: mac2},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mgr := &Manager{}
+			mgr.Reset()
+
+			for _, record := range test.records {
+				mgr.RecordLocalPodMAC(record.hostIfName, record.mac)
+			}
+			if !reflect.DeepEqual(mgr.localPodMACs, test.expect) {
+				t.Errorf("expected recorded macs %v but got %v", test.expect, mgr.localPodMACs)
+			}
+
+			mgr.Reset()
+			if len(mgr.localPodMACs) != 0 {
+				t.Errorf("expected recorded macs to be reset but got %v", mgr.localPodMACs)
+			}
+		})
+	}
+}
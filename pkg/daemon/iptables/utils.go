@@ -18,7 +18,11 @@ package iptables
 
 import (
 	"bytes"
+	"fmt"
 	"net"
+	"strconv"
+
+	"github.com/alibaba/hybridnet/pkg/daemon/ipset"
 )
 
 // Join all words with spaces, terminate with newline and write to buf.
@@ -49,3 +53,58 @@ func generateStringsFromIPs(ips []net.IP) []string {
 	}
 	return ipStrings
 }
+
+// generateSourceGuardEntries generates members of a hash:net,iface set which match any source from the
+// interfaces of guarded pods, except the pod ips which are marked as nomatch
+func generateSourceGuardEntries(guards []podSourceGuard, protocol Protocol) []string {
+	anySource := &net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)}
+	if protocol == ProtocolIpv6 {
+		anySource = &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)}
+	}
+
+	var entries []string
+	for _, guard := range guards {
+		for _, half := range splitZeroPrefix(anySource) {
+			entries = append(entries, half+","+guard.hostIfName)
+		}
+		for _, podIP := range guard.podIPs {
+			entries = append(entries, podIP.String()+","+guard.hostIfName+" "+ipset.OptionNoMatch)
+		}
+	}
+	return entries
+}
+
+// generateEgressEntries generates members of the sets of pods with egress limits, allowed destinations
+// without port (hash:net,net) and allowed destinations with port (hash:ip,port,net)
+func generateEgressEntries(podRules []podEgressRules) (podIPs, netEntries, portEntries []string) {
+	for _, podRule := range podRules {
+		podIP := podRule.podIP.String()
+		podIPs = append(podIPs, podIP)
+
+		for _, rule := range podRule.rules {
+			for _, cidr := range splitZeroPrefix(rule.CIDR) {
+				if rule.Port == 0 {
+					netEntries = append(netEntries, fmt.Sprintf("%s,%s", podIP, cidr))
+				} else {
+					portEntries = append(portEntries, fmt.Sprintf("%s,%s:%s,%s", podIP, rule.Protocol,
+						strconv.Itoa(rule.Port), cidr))
+				}
+			}
+		}
+	}
+	return
+}
+
+// splitZeroPrefix splits a cidr with zero prefix into two halves, because net sets are unable to
+// store zero prefix
+func splitZeroPrefix(cidr *net.IPNet) []string {
+	ones, bits := cidr.Mask.Size()
+	if ones != 0 {
+		return []string{cidr.String()}
+	}
+
+	lower := &net.IPNet{IP: make(net.IP, bits/8), Mask: net.CIDRMask(1, bits)}
+	upper := &net.IPNet{IP: make(net.IP, bits/8), Mask: net.CIDRMask(1, bits)}
+	upper.IP[0] = 0x80
+	return []string{lower.String(), upper.String()}
+}
@@ -0,0 +1,86 @@
+/*
+  Copyright 2004 The Kube-router Authors.
+
+  Licensed under the Apache License, Version 2.0 (the "License");
+  you may not use this file except in compliance with the License.
+  You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+  Unless required by applicable law or agreed to in writing, software
+  distributed under the License is distributed on an "AS IS" BASIS,
+  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+  See the License for the specific language governing permissions and
+  limitations under the License.
+*/
+
+package iptables
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	globalutils "github.com/alibaba/hybridnet/pkg/utils"
+)
+
+func TestGenerateEgressEntries(t *testing.T) {
+	_, anyV4, _ := net.ParseCIDR("0.0.0.0/0")
+	_, privateV4, _ := net.ParseCIDR("10.0.0.0/8")
+
+	podIPs, netEntries, portEntries := generateEgressEntries([]podEgressRules{
+		{
+			podIP: net.ParseIP("192.168.0.2"),
+			rules: []globalutils.EgressRule{
+				{CIDR: privateV4},
+				{CIDR: anyV4, Protocol: globalutils.EgressProtocolUDP, Port: 53},
+			},
+		},
+		{
+			podIP: net.ParseIP("192.168.0.3"),
+		},
+	})
+
+	if expect := []string{"192.168.0.2", "192.168.0.3"}; !reflect.DeepEqual(podIPs, expect) {
+		t.Errorf("expect pod ips %v but got %v", expect, podIPs)
+	}
+	if expect := []string{"192.168.0.2,10.0.0.0/8"}; !reflect.DeepEqual(netEntries, expect) {
+		t.Errorf("expect net entries %v but got %v", expect, netEntries)
+	}
+	if expect := []string{"192.168.0.2,udp:53,0.0.0.0/1", "192.168.0.2,udp:53,128.0.0.0/1"}; !reflect.DeepEqual(portEntries, expect) {
+		t.Errorf("expect port entries %v but got %v", expect, portEntries)
+	}
+}
+
+func TestGenerateSourceGuardEntries(t *testing.T) {
+	tests := []struct {
+		name     string
+		guards   []podSourceGuard
+		protocol Protocol
+		expect   []string
+	}{
+		{
+			name: "ipv4",
+			guards: []podSourceGuard{
+				{hostIfName: "h_a", podIPs: []net.IP{net.ParseIP("192.168.0.2")}},
+			},
+			protocol: ProtocolIpv4,
+			expect:   []string{"0.0.0.0/1,h_a", "128.0.0.0/1,h_a", "192.168.0.2,h_a nomatch"},
+		},
+		{
+			name: "ipv6",
+			guards: []podSourceGuard{
+				{hostIfName: "h_a", podIPs: []net.IP{net.ParseIP("fd00::2")}},
+			},
+			protocol: ProtocolIpv6,
+			expect:   []string{"::/1,h_a", "8000::/1,h_a", "fd00::2,h_a nomatch"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if entries := generateSourceGuardEntries(test.guards, test.protocol); !reflect.DeepEqual(entries, test.expect) {
+				t.Errorf("test %s fail, expect %v but got %v", test.name, test.expect, entries)
+			}
+		})
+	}
+}
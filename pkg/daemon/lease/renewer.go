@@ -0,0 +1,136 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package lease
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+)
+
+// leases are checked every twelfth of duration and only renewed in their last quarter, which leaves
+// three chances to renew before expiring
+const (
+	checkDivisor = 12
+	renewDivisor = 4
+)
+
+// Renewer periodically renews the leases of ip instances bound to node, a lease is only renewed
+// while the pod it is bound to exists, so that ip instances of gone pods expire and are reclaimed
+// by manager even if the deletion events of pods are lost. Leases are renewed only when they are
+// about to expire, with jittered expire times to spread the patches of ip instances over checks.
+type Renewer struct {
+	Client   client.Client
+	NodeName string
+	Duration time.Duration
+	Logger   logr.Logger
+}
+
+// Start implements manager.Runnable
+func (r *Renewer) Start(ctx context.Context) error {
+	interval := r.Duration / checkDivisor
+	r.Logger.Info("ip instance lease renewer started", "duration", r.Duration, "interval", interval)
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := r.renew(ctx); err != nil {
+			r.Logger.Error(err, "failed to renew leases of ip instances")
+		}
+	}, interval)
+	return nil
+}
+
+func (r *Renewer) renew(ctx context.Context) error {
+	ipInstanceList := &networkingv1.IPInstanceList{}
+	if err := r.Client.List(ctx, ipInstanceList, client.MatchingLabels{constants.LabelNode: r.NodeName}); err != nil {
+		return fmt.Errorf("failed to list ip instances of node %v: %v", r.NodeName, err)
+	}
+
+	now := time.Now()
+	for i := range ipInstanceList.Items {
+		ipInstance := &ipInstanceList.Items[i]
+		if !ipInstance.DeletionTimestamp.IsZero() || networkingv1.IsReserved(ipInstance) {
+			continue
+		}
+
+		if !r.aboutToExpire(ipInstance, now) {
+			continue
+		}
+
+		bound, err := r.boundToExistingPod(ctx, ipInstance)
+		if err != nil {
+			return err
+		}
+		if !bound {
+			r.Logger.V(1).Info("skip renewing lease of ip instance without pod", "ipInstance",
+				ipInstance.Namespace+"/"+ipInstance.Name)
+			continue
+		}
+
+		patch := client.MergeFrom(ipInstance.DeepCopy())
+		if ipInstance.Annotations == nil {
+			ipInstance.Annotations = map[string]string{}
+		}
+		ipInstance.Annotations[constants.AnnotationLeaseExpireTime] = r.nextExpireTime(now).Format(time.RFC3339)
+		if err = client.IgnoreNotFound(r.Client.Patch(ctx, ipInstance, patch)); err != nil {
+			return fmt.Errorf("failed to renew lease of ip instance %v/%v: %v", ipInstance.Namespace, ipInstance.Name, err)
+		}
+	}
+	return nil
+}
+
+// aboutToExpire checks if the lease of ip instance is missing, invalid or in its last quarter
+func (r *Renewer) aboutToExpire(ipInstance *networkingv1.IPInstance, now time.Time) bool {
+	expireTime, err := time.Parse(time.RFC3339, ipInstance.Annotations[constants.AnnotationLeaseExpireTime])
+	if err != nil {
+		return true
+	}
+	return expireTime.Sub(now) < r.Duration/renewDivisor
+}
+
+// nextExpireTime returns an expire time within the last quarter of duration from now, leases
+// renewed in the same check will expire at different times
+func (r *Renewer) nextExpireTime(now time.Time) time.Time {
+	base := r.Duration - r.Duration/renewDivisor
+	return now.Add(wait.Jitter(base, float64(r.Duration/renewDivisor)/float64(base)))
+}
+
+// boundToExistingPod checks if the pod which ip instance is bound to still exists on this node
+func (r *Renewer) boundToExistingPod(ctx context.Context, ipInstance *networkingv1.IPInstance) (bool, error) {
+	podName := networkingv1.FetchBindingPodName(ipInstance)
+	if len(podName) == 0 {
+		return false, nil
+	}
+
+	pod := &corev1.Pod{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: ipInstance.Namespace, Name: podName}, pod); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get pod %v/%v: %v", ipInstance.Namespace, podName, err)
+	}
+
+	return pod.UID == ipInstance.Spec.Binding.PodUID, nil
+}
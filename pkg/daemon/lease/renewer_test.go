@@ -0,0 +1,129 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package lease
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+)
+
+func ipInstanceOf(name, nodeName, podName string, podUID types.UID) *networkingv1.IPInstance {
+	return &networkingv1.IPInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    map[string]string{constants.LabelNode: "node1"},
+		},
+		Spec: networkingv1.IPInstanceSpec{
+			Binding: networkingv1.Binding{
+				NodeName: nodeName,
+				PodName:  podName,
+				PodUID:   podUID,
+			},
+		},
+	}
+}
+
+func withLease(ipInstance *networkingv1.IPInstance, expireTime time.Time) *networkingv1.IPInstance {
+	ipInstance.Annotations = map[string]string{constants.AnnotationLeaseExpireTime: expireTime.Format(time.RFC3339)}
+	return ipInstance
+}
+
+func TestRenew(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod1",
+			Namespace: "default",
+			UID:       "pod1-uid",
+		},
+	}
+
+	now := time.Now()
+	freshExpireTime := now.Add(50 * time.Second)
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		pod,
+		ipInstanceOf("bound", "node1", "pod1", "pod1-uid"),
+		withLease(ipInstanceOf("fresh", "node1", "pod1", "pod1-uid"), freshExpireTime),
+		withLease(ipInstanceOf("about-to-expire", "node1", "pod1", "pod1-uid"), now.Add(10*time.Second)),
+		withLease(ipInstanceOf("expired", "node1", "pod1", "pod1-uid"), now.Add(-time.Second)),
+		ipInstanceOf("stale-uid", "node1", "pod1", "old-uid"),
+		ipInstanceOf("pod-gone", "node1", "pod2", "pod2-uid"),
+		ipInstanceOf("reserved", "", "pod1", ""),
+	).Build()
+
+	r := &Renewer{
+		Client:   c,
+		NodeName: "node1",
+		Duration: time.Minute,
+		Logger:   logr.Discard(),
+	}
+	if err := r.renew(context.Background()); err != nil {
+		t.Fatalf("unable to renew: %v", err)
+	}
+
+	expected := map[string]bool{
+		"bound":           true,
+		"fresh":           false,
+		"about-to-expire": true,
+		"expired":         true,
+		"stale-uid":       false,
+		"pod-gone":        false,
+		"reserved":        false,
+	}
+	for name, renewed := range expected {
+		ipInstance := &networkingv1.IPInstance{}
+		if err := c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, ipInstance); err != nil {
+			t.Fatalf("unable to get ip instance %s: %v", name, err)
+		}
+
+		expireTimeStr, exist := ipInstance.Annotations[constants.AnnotationLeaseExpireTime]
+		if !renewed {
+			if name == "fresh" && expireTimeStr != freshExpireTime.Format(time.RFC3339) {
+				t.Errorf("ip instance %s expects lease untouched but got expire time %q", name, expireTimeStr)
+			} else if name != "fresh" && exist {
+				t.Errorf("ip instance %s expects no lease but got expire time %q", name, expireTimeStr)
+			}
+			continue
+		}
+
+		expireTime, err := time.Parse(time.RFC3339, expireTimeStr)
+		if err != nil {
+			t.Fatalf("ip instance %s expects renewed but got expire time %q", name, expireTimeStr)
+		}
+		// renewed leases expire in the last quarter of duration, RFC3339 drops the fraction of seconds
+		if expireTime.Before(now.Add(44*time.Second)) || expireTime.After(now.Add(61*time.Second)) {
+			t.Errorf("ip instance %s expects renewed within the last quarter of duration but got expire time %v", name, expireTime)
+		}
+	}
+}
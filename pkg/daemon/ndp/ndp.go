@@ -26,7 +26,8 @@ import (
 
 // CheckWithTimeout checks vlan network environment and duplicate ip problems,
 // timeout parameter determines how long this function will exactly last.
-func CheckWithTimeout(ifi *net.Interface, srcPod, gateway net.IP, timeout time.Duration) error {
+// A duplicate ip owned by adoptedHw is adopted instead of being a conflict, if adoptedHw is not nil.
+func CheckWithTimeout(ifi *net.Interface, srcPod, gateway net.IP, adoptedHw net.HardwareAddr, timeout time.Duration) error {
 	// Use link-local address as the source IPv6 address for NDP communications.
 	ndpConn, srcIP, err := ndp.Dial(ifi, ndp.LinkLocal)
 	if err != nil {
@@ -43,7 +44,8 @@ func CheckWithTimeout(ifi *net.Interface, srcPod, gateway net.IP, timeout time.D
 			srcIP.String(), gateway.String(), err, ifi.Name)
 	}
 
-	if duplicatedHw, err := doNS(ndpConn, srcPod, ifi.HardwareAddr, timeout); err == nil {
+	if duplicatedHw, err := doNS(ndpConn, srcPod, ifi.HardwareAddr, timeout); err == nil &&
+		(adoptedHw == nil || duplicatedHw.String() != adoptedHw.String()) {
 		return fmt.Errorf("pod ip %v duplicated"+
 			", please check if ip %v is occupied by other machines or containers, another hw addr is %v",
 			srcPod.String(), srcPod.String(), duplicatedHw.String())
@@ -56,6 +58,25 @@ func CheckWithTimeout(ifi *net.Interface, srcPod, gateway net.IP, timeout time.D
 	return nil
 }
 
+// Gratuitous sends unsolicited neighbor advertisement of ip over interface to all nodes,
+// remote neigh caches of ip are overridden to the hw addr of interface.
+func Gratuitous(ifi *net.Interface, ip net.IP) error {
+	ndpConn, _, err := ndp.Dial(ifi, ndp.LinkLocal)
+	if err != nil {
+		return fmt.Errorf("failed to ndp dial interface %v: %v", ifi.Name, err)
+	}
+
+	defer func() {
+		_ = ndpConn.Close()
+	}()
+
+	if err := doGratuitous(ndpConn, ip, ifi.HardwareAddr); err != nil {
+		return fmt.Errorf("failed to send gratuitous ndp for ip %v: %v", ip.String(), err)
+	}
+
+	return nil
+}
+
 func doNS(c *ndp.Conn, target net.IP, hwaddr net.HardwareAddr, timeout time.Duration) (net.HardwareAddr, error) {
 
 	// Always multicast the message to the target's solicited-node multicast
@@ -0,0 +1,111 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package neigh
+
+import (
+	"net"
+	"sort"
+	"sync"
+)
+
+// FakeManager is an in-memory Interface which records proxy neighs instead of
+// configuring them on host, for testing without privileges.
+type FakeManager struct {
+	mu sync.Mutex
+
+	family int
+
+	interfaceToIPMap       map[string]IPMap
+	syncedInterfaceToIPMap map[string][]net.IP
+	syncCount              int
+
+	// SyncErr will be returned by SyncNeighs if not nil.
+	SyncErr error
+
+	// OnSync will be called with the synced proxy neighs every time SyncNeighs succeeds.
+	OnSync func(family int, interfaceToIPs map[string][]net.IP)
+}
+
+var _ Interface = &FakeManager{}
+
+func NewFakeManager(family int) *FakeManager {
+	return &FakeManager{
+		family:           family,
+		interfaceToIPMap: map[string]IPMap{},
+	}
+}
+
+func (f *FakeManager) ResetInfos() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.interfaceToIPMap = map[string]IPMap{}
+}
+
+func (f *FakeManager) AddPodInfo(podIP net.IP, forwardNodeIfName string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.interfaceToIPMap[forwardNodeIfName] == nil {
+		f.interfaceToIPMap[forwardNodeIfName] = IPMap{}
+	}
+	f.interfaceToIPMap[forwardNodeIfName][podIP.String()] = podIP
+}
+
+func (f *FakeManager) SyncNeighs() error {
+	f.mu.Lock()
+	if f.SyncErr != nil {
+		f.mu.Unlock()
+		return f.SyncErr
+	}
+
+	synced := make(map[string][]net.IP, len(f.interfaceToIPMap))
+	for forwardNodeIfName, ipMap := range f.interfaceToIPMap {
+		for _, ip := range ipMap {
+			synced[forwardNodeIfName] = append(synced[forwardNodeIfName], ip)
+		}
+		sort.Slice(synced[forwardNodeIfName], func(i, j int) bool {
+			return synced[forwardNodeIfName][i].String() < synced[forwardNodeIfName][j].String()
+		})
+	}
+
+	f.syncedInterfaceToIPMap = synced
+	f.syncCount++
+	onSync := f.OnSync
+	f.mu.Unlock()
+
+	if onSync != nil {
+		onSync(f.family, synced)
+	}
+	return nil
+}
+
+// Synced returns proxy neighs of the last successful SyncNeighs, ips of each interface are sorted.
+func (f *FakeManager) Synced() map[string][]net.IP {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.syncedInterfaceToIPMap
+}
+
+// SyncCount returns how many times SyncNeighs succeeds.
+func (f *FakeManager) SyncCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.syncCount
+}
@@ -0,0 +1,135 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package neigh
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+)
+
+// VtepResolver returns the mac address of the vtep which an overlay address lives behind,
+// an empty mac address means the address is unknown
+type VtepResolver func(ip net.IP) (net.HardwareAddr, error)
+
+type gcAction int
+
+const (
+	gcKeep gcAction = iota
+	gcDelete
+	gcUpdate
+)
+
+// decideVxlanNeighEntry decides what to do with a neigh entry on vxlan link according to
+// the vtep mac address which it should point to
+func decideVxlanNeighEntry(entry *netlink.Neigh, vtepMac net.HardwareAddr) gcAction {
+	// entries being resolved are left to the proxy resolving of daemon
+	if entry.State&netlink.NUD_INCOMPLETE != 0 {
+		return gcKeep
+	}
+
+	if len(vtepMac) == 0 {
+		return gcDelete
+	}
+
+	if entry.HardwareAddr.String() != vtepMac.String() {
+		return gcUpdate
+	}
+
+	return gcKeep
+}
+
+// GCVxlanNeighEntries deletes neigh entries of unknown addresses on a vxlan link, and corrects
+// entries still pointing to former vteps of addresses, the size of neigh table left is returned.
+// Entries failed to be resolved are kept.
+func GCVxlanNeighEntries(linkIndex int, family int, resolve VtepResolver) (int, error) {
+	neighList, err := netlink.NeighList(linkIndex, family)
+	if err != nil {
+		return 0, fmt.Errorf("list neigh for link index %v error: %v", linkIndex, err)
+	}
+
+	var resolveErr error
+	size := len(neighList)
+	for i := range neighList {
+		vtepMac, err := resolve(neighList[i].IP)
+		if err != nil {
+			if resolveErr == nil {
+				resolveErr = fmt.Errorf("failed to resolve vtep of %v: %v", neighList[i].IP.String(), err)
+			}
+			continue
+		}
+
+		deleted, err := syncVxlanNeighEntry(&neighList[i], vtepMac)
+		if err != nil {
+			return size, err
+		}
+		if deleted {
+			size--
+		}
+	}
+
+	return size, resolveErr
+}
+
+// SyncVxlanNeighEntriesByIP makes neigh entries of an ip on vxlan link point to the vtep mac,
+// entries are deleted if vtep mac is empty. Nothing will be added if neigh entry not exist,
+// which is left to the proxy resolving of daemon.
+func SyncVxlanNeighEntriesByIP(linkIndex int, ip net.IP, vtepMac net.HardwareAddr) error {
+	family := netlink.FAMILY_V4
+	if ip.To4() == nil {
+		family = netlink.FAMILY_V6
+	}
+
+	neighList, err := netlink.NeighList(linkIndex, family)
+	if err != nil {
+		return fmt.Errorf("list neigh for link index %v error: %v", linkIndex, err)
+	}
+
+	for i := range neighList {
+		if neighList[i].IP.Equal(ip) {
+			if _, err := syncVxlanNeighEntry(&neighList[i], vtepMac); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func syncVxlanNeighEntry(entry *netlink.Neigh, vtepMac net.HardwareAddr) (deleted bool, err error) {
+	switch decideVxlanNeighEntry(entry, vtepMac) {
+	case gcDelete:
+		if err := netlink.NeighDel(entry); err != nil {
+			return false, fmt.Errorf("del neigh cache %v error: %v", entry.String(), err)
+		}
+		return true, nil
+	case gcUpdate:
+		updatedEntry := netlink.Neigh{
+			LinkIndex:    entry.LinkIndex,
+			State:        netlink.NUD_REACHABLE,
+			Type:         syscall.RTN_UNICAST,
+			IP:           entry.IP,
+			HardwareAddr: vtepMac,
+		}
+		if err := netlink.NeighSet(&updatedEntry); err != nil {
+			return false, fmt.Errorf("set neigh cache %v error: %v", updatedEntry.String(), err)
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,84 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package neigh
+
+import (
+	"net"
+	"testing"
+
+	"github.com/vishvananda/netlink"
+)
+
+func TestDecideVxlanNeighEntry(t *testing.T) {
+	vtepMac, _ := net.ParseMAC("ee:ee:ee:ee:ee:01")
+	formerVtepMac, _ := net.ParseMAC("ee:ee:ee:ee:ee:02")
+
+	tests := []struct {
+		name    string
+		entry   netlink.Neigh
+		vtepMac net.HardwareAddr
+		expect  gcAction
+	}{
+		{
+			name: "entry pointing to vtep",
+			entry: netlink.Neigh{
+				IP:           net.ParseIP("10.0.0.1"),
+				State:        netlink.NUD_REACHABLE,
+				HardwareAddr: vtepMac,
+			},
+			vtepMac: vtepMac,
+			expect:  gcKeep,
+		},
+		{
+			name: "entry of unknown address",
+			entry: netlink.Neigh{
+				IP:           net.ParseIP("10.0.0.1"),
+				State:        netlink.NUD_REACHABLE,
+				HardwareAddr: vtepMac,
+			},
+			vtepMac: nil,
+			expect:  gcDelete,
+		},
+		{
+			name: "entry pointing to former vtep",
+			entry: netlink.Neigh{
+				IP:           net.ParseIP("10.0.0.1"),
+				State:        netlink.NUD_STALE,
+				HardwareAddr: formerVtepMac,
+			},
+			vtepMac: vtepMac,
+			expect:  gcUpdate,
+		},
+		{
+			name: "entry being resolved",
+			entry: netlink.Neigh{
+				IP:    net.ParseIP("10.0.0.1"),
+				State: netlink.NUD_INCOMPLETE,
+			},
+			vtepMac: nil,
+			expect:  gcKeep,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if action := decideVxlanNeighEntry(&test.entry, test.vtepMac); action != test.expect {
+				t.Errorf("expect action %v, but got %v", test.expect, action)
+			}
+		})
+	}
+}
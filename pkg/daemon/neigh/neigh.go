@@ -27,6 +27,16 @@ import (
 
 type IPMap map[string]net.IP
 
+// Interface maintains proxy neighs of pods on forward interfaces, it's implemented by Manager
+// which configures the host and by FakeManager which keeps everything in memory.
+type Interface interface {
+	ResetInfos()
+	AddPodInfo(podIP net.IP, forwardNodeIfName string)
+	SyncNeighs() error
+}
+
+var _ Interface = &Manager{}
+
 type Manager struct {
 	family int
 
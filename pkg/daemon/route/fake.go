@@ -0,0 +1,153 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package route
+
+import (
+	"net"
+	"sort"
+	"sync"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+)
+
+// FakeSubnetInfo is a subnet info recorded by FakeManager.
+type FakeSubnetInfo struct {
+	Cidr              *net.IPNet
+	Gateway           net.IP
+	Start             net.IP
+	End               net.IP
+	ExcludeIPs        []net.IP
+	ForwardNodeIfName string
+	AutoNatOutgoing   bool
+	IsOverlay         bool
+	IsUnderlayOnHost  bool
+	IsRemote          bool
+	Mode              networkingv1.NetworkMode
+	EgressRoutes      []EgressRoute
+	RouteOptions      Options
+}
+
+// FakeManager is an in-memory Interface which records subnet infos instead of
+// configuring policy rules and routes on host, for testing without privileges.
+type FakeManager struct {
+	mu sync.Mutex
+
+	family int
+
+	subnets       []FakeSubnetInfo
+	syncedSubnets []FakeSubnetInfo
+	syncCount     int
+
+	// SyncErr will be returned by SyncRoutes if not nil.
+	SyncErr error
+
+	// OnSync will be called with the synced subnet infos every time SyncRoutes succeeds.
+	OnSync func(family int, subnets []FakeSubnetInfo)
+}
+
+var _ Interface = &FakeManager{}
+
+func NewFakeManager(family int) *FakeManager {
+	return &FakeManager{
+		family: family,
+	}
+}
+
+func (f *FakeManager) ResetInfos() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.subnets = nil
+}
+
+func (f *FakeManager) AddSubnetInfo(cidr *net.IPNet, gateway, start, end net.IP, excludeIPs []net.IP, forwardNodeIfName string,
+	autoNatOutgoing, isOverlay, isUnderlayOnHost bool, mode networkingv1.NetworkMode, egressRoutes []EgressRoute,
+	routeOptions Options) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.subnets = append(f.subnets, FakeSubnetInfo{
+		Cidr:              cidr,
+		Gateway:           gateway,
+		Start:             start,
+		End:               end,
+		ExcludeIPs:        excludeIPs,
+		ForwardNodeIfName: forwardNodeIfName,
+		AutoNatOutgoing:   autoNatOutgoing,
+		IsOverlay:         isOverlay,
+		IsUnderlayOnHost:  isUnderlayOnHost,
+		Mode:              mode,
+		EgressRoutes:      egressRoutes,
+		RouteOptions:      routeOptions,
+	})
+}
+
+func (f *FakeManager) AddRemoteSubnetInfo(cidr *net.IPNet, gateway, start, end net.IP, excludeIPs []net.IP, isOverlay bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.subnets = append(f.subnets, FakeSubnetInfo{
+		Cidr:       cidr,
+		Gateway:    gateway,
+		Start:      start,
+		End:        end,
+		ExcludeIPs: excludeIPs,
+		IsOverlay:  isOverlay,
+		IsRemote:   true,
+	})
+	return nil
+}
+
+func (f *FakeManager) SyncRoutes() error {
+	f.mu.Lock()
+	if f.SyncErr != nil {
+		f.mu.Unlock()
+		return f.SyncErr
+	}
+
+	synced := make([]FakeSubnetInfo, len(f.subnets))
+	copy(synced, f.subnets)
+	sort.SliceStable(synced, func(i, j int) bool {
+		return synced[i].Cidr.String() < synced[j].Cidr.String()
+	})
+
+	f.syncedSubnets = synced
+	f.syncCount++
+	onSync := f.OnSync
+	f.mu.Unlock()
+
+	if onSync != nil {
+		onSync(f.family, synced)
+	}
+	return nil
+}
+
+// SyncedSubnets returns subnet infos of the last successful SyncRoutes, sorted by cidr.
+func (f *FakeManager) SyncedSubnets() []FakeSubnetInfo {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.syncedSubnets
+}
+
+// SyncCount returns how many times SyncRoutes succeeds.
+func (f *FakeManager) SyncCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.syncCount
+}
@@ -51,6 +51,19 @@ import (
 // Local-pod-direct table doesn't need to be maintained manually,
 // because route will be deleted by kernel while specific device is not exist.
 
+// Interface maintains policy rules and routes of subnets, it's implemented by Manager which
+// configures the host and by FakeManager which keeps everything in memory.
+type Interface interface {
+	ResetInfos()
+	AddSubnetInfo(cidr *net.IPNet, gateway, start, end net.IP, excludeIPs []net.IP, forwardNodeIfName string,
+		autoNatOutgoing, isOverlay, isUnderlayOnHost bool, mode networkingv1.NetworkMode, egressRoutes []EgressRoute,
+		routeOptions Options)
+	AddRemoteSubnetInfo(cidr *net.IPNet, gateway, start, end net.IP, excludeIPs []net.IP, isOverlay bool) error
+	SyncRoutes() error
+}
+
+var _ Interface = &Manager{}
+
 type Manager struct {
 	// Use fixed table num to mark "local-pod-direct rule"
 	localDirectTableNum int
@@ -186,8 +199,9 @@ func (m *Manager) ResetInfos() {
 	m.remoteUnderlaySubnetInfoMap = SubnetInfoMap{}
 }
 
-func (m *Manager) AddSubnetInfo(cidr *net.IPNet, gateway, start, end net.IP, excludeIPs []net.IP,
-	forwardNodeIfName string, autoNatOutgoing, isOverlay, isUnderlayOnHost bool, mode networkingv1.NetworkMode) {
+func (m *Manager) AddSubnetInfo(cidr *net.IPNet, gateway, start, end net.IP, excludeIPs []net.IP, forwardNodeIfName string,
+	autoNatOutgoing, isOverlay, isUnderlayOnHost bool, mode networkingv1.NetworkMode, egressRoutes []EgressRoute,
+	routeOptions Options) {
 
 	cidrString := cidr.String()
 
@@ -201,6 +215,8 @@ func (m *Manager) AddSubnetInfo(cidr *net.IPNet, gateway, start, end net.IP, exc
 			excludeIPs:        []net.IP{},
 			isUnderlayOnHost:  isUnderlayOnHost,
 			mode:              mode,
+			egressRoutes:      egressRoutes,
+			routeOptions:      routeOptions,
 		}
 	}
 
@@ -371,7 +387,7 @@ func (m *Manager) SyncRoutes() error {
 		if err := ensureFromPodSubnetRuleAndRoutes(info.forwardNodeIfName, info.cidr, info.gateway, info.autoNatOutgoing, m.family,
 			combineSubnetInfoMap(m.localClusterUnderlaySubnetInfoMap, m.remoteUnderlaySubnetInfoMap),
 			combineNetMap(localUnderlayExcludeIPBlockMap, remoteUnderlayExcludeIPBlockMap),
-			info.mode,
+			info.mode, nil, info.routeOptions,
 		); err != nil {
 			return fmt.Errorf("failed to add overlay subnet %v rule and routes: %v", info.cidr, err)
 		}
@@ -385,7 +401,7 @@ func (m *Manager) SyncRoutes() error {
 
 		// Append underlay from-pod-subnet rules which don't exist and adapt to subnet configuration
 		if err := ensureFromPodSubnetRuleAndRoutes(info.forwardNodeIfName, info.cidr,
-			info.gateway, info.autoNatOutgoing, m.family, nil, nil, info.mode,
+			info.gateway, info.autoNatOutgoing, m.family, nil, nil, info.mode, info.egressRoutes, info.routeOptions,
 		); err != nil {
 			return fmt.Errorf("failed to add underlay subnet %v rule and routes: %v", info.cidr, err)
 		}
@@ -410,8 +426,14 @@ func (m *Manager) ensureToOverlaySubnetRoutes(excludeIPBlockMap map[string]*net.
 			continue
 		}
 
-		if _, exist := m.localClusterOverlaySubnetInfoMap[route.Dst.String()]; exist {
+		// routes of local overlay subnets are recreated if route options change
+		if info, exist := m.localClusterOverlaySubnetInfoMap[route.Dst.String()]; exist &&
+			info.routeOptions.matches(&route, m.family) {
 			existOverlaySubnetRouteMap[route.Dst.String()] = true
+		} else if exist {
+			if err := netlink.RouteDel(&route); err != nil {
+				return fmt.Errorf("failed to delete route %v: %v", route.String(), err)
+			}
 		} else if _, exist := m.remoteOverlaySubnetInfoMap[route.Dst.String()]; exist {
 			existRemoteOverlaySubnetRouteMap[route.Dst.String()] = true
 		} else if err := netlink.RouteDel(&route); err != nil {
@@ -426,12 +448,12 @@ func (m *Manager) ensureToOverlaySubnetRoutes(excludeIPBlockMap map[string]*net.
 				return fmt.Errorf("failed to get overlay link %v: %v", info.forwardNodeIfName, err)
 			}
 
-			if err := netlink.RouteReplace(&netlink.Route{
+			if err := netlink.RouteReplace(info.routeOptions.apply(&netlink.Route{
 				Dst:       info.cidr,
 				LinkIndex: overlayLink.Attrs().Index,
 				Table:     m.toOverlaySubnetTableNum,
 				Scope:     netlink.SCOPE_UNIVERSE,
-			}); err != nil {
+			}, m.family)); err != nil {
 				return fmt.Errorf("failed to add to-overlay-pod-subnet route for %v: %v", info.cidr.String(), err)
 			}
 		}
@@ -40,6 +40,9 @@ const (
 
 	fromRuleMask = iptables.KubeProxyMasqueradeMark + iptables.FullNATedPodTrafficMark
 	fromRuleMark = 0x0
+
+	// kernel sets the metric of IPv6 routes to 1024 if not specified
+	defaultIPv6RoutePriority = 1024
 )
 
 type SubnetInfo struct {
@@ -58,6 +61,43 @@ type SubnetInfo struct {
 	isUnderlayOnHost bool
 
 	mode networkingv1.NetworkMode
+
+	// next hop gateways selected by destination for traffic from underlay pods
+	egressRoutes []EgressRoute
+
+	routeOptions Options
+}
+
+// EgressRoute is a next hop gateway for traffic from pods of a subnet to a destination.
+type EgressRoute struct {
+	Dst     *net.IPNet
+	Gateway net.IP
+}
+
+// Options are the metric and realm of routes for a subnet, zero means not specified. Realms only
+// apply to IPv4 routes.
+type Options struct {
+	Priority int
+	Realm    int
+}
+
+// apply sets options to route, the metric of route is kept if not specified
+func (o Options) apply(route *netlink.Route, family int) *netlink.Route {
+	if o.Priority != 0 {
+		route.Priority = o.Priority
+	}
+	if family == netlink.FAMILY_V4 {
+		route.Realm = o.Realm
+	}
+	return route
+}
+
+// matches checks if an existing route is installed with options
+func (o Options) matches(route *netlink.Route, family int) bool {
+	if realRoutePriority(o.Priority, family) != realRoutePriority(route.Priority, family) {
+		return false
+	}
+	return family != netlink.FAMILY_V4 || route.Realm == o.Realm
 }
 
 type SubnetInfoMap map[string]*SubnetInfo
@@ -194,7 +234,8 @@ func clearRouteTable(table int, family int) error {
 
 func ensureFromPodSubnetRuleAndRoutes(forwardNodeIfName string, cidr *net.IPNet,
 	gateway net.IP, autoNatOutgoing bool, family int, underlaySubnetInfoMap SubnetInfoMap,
-	underlayExcludeIPBlockMap map[string]*net.IPNet, mode networkingv1.NetworkMode) error {
+	underlayExcludeIPBlockMap map[string]*net.IPNet, mode networkingv1.NetworkMode, egressRoutes []EgressRoute,
+	options Options) error {
 
 	var table int
 	var err error
@@ -222,21 +263,32 @@ func ensureFromPodSubnetRuleAndRoutes(forwardNodeIfName string, cidr *net.IPNet,
 	switch mode {
 	case networkingv1.NetworkModeVxlan:
 		if err := ensureRoutesForVxlanSubnet(forwardLink, cidr, table, autoNatOutgoing, family,
-			underlaySubnetInfoMap, underlayExcludeIPBlockMap); err != nil {
+			underlaySubnetInfoMap, underlayExcludeIPBlockMap, options); err != nil {
 			return fmt.Errorf("failed to ensure routes for vxlan subnet %v: %v", cidr.String(), err)
 		}
 	case networkingv1.NetworkModeVlan:
-		if err := ensureRoutesForVlanSubnet(forwardLink, cidr, gateway, table, family); err != nil {
+		if err := ensureRoutesForVlanSubnet(forwardLink, cidr, gateway, egressDefaultGateway(egressRoutes, family),
+			table, family, options); err != nil {
 			return fmt.Errorf("failed to ensure routes for vlan subnet %v: %v", cidr.String(), err)
 		}
 	case networkingv1.NetworkModeBGP, networkingv1.NetworkModeGlobalBGP:
-		if err := ensureRoutesForBGPSubnet(forwardLink, cidr, gateway, table, family); err != nil {
+		if defaultGateway := egressDefaultGateway(egressRoutes, family); defaultGateway != nil {
+			gateway = defaultGateway
+		}
+
+		if err := ensureRoutesForBGPSubnet(forwardLink, cidr, gateway, table, family, options); err != nil {
 			return fmt.Errorf("failed to ensure routes for bgp subnet %v: %v", cidr.String(), err)
 		}
 	default:
 		return fmt.Errorf("unsupported network mode %v", mode)
 	}
 
+	if mode != networkingv1.NetworkModeVxlan {
+		if err := ensureEgressRoutes(forwardLink, egressRoutes, table, family, options); err != nil {
+			return fmt.Errorf("failed to ensure egress routes for subnet %v: %v", cidr.String(), err)
+		}
+	}
+
 	// Add rule at the last in case error happens while failed to add any routes to table.
 	if !ruleExist {
 		if err := appendHighestUnusedPriorityRuleIfNotExist(cidr, table, family, fromRuleMark, fromRuleMask); err != nil {
@@ -248,7 +300,7 @@ func ensureFromPodSubnetRuleAndRoutes(forwardNodeIfName string, cidr *net.IPNet,
 }
 
 func ensureRoutesForVxlanSubnet(forwardLink netlink.Link, cidr *net.IPNet, table int, autoNatOutgoing bool,
-	family int, underlaySubnetInfoMap SubnetInfoMap, underlayExcludeIPBlockMap map[string]*net.IPNet) error {
+	family int, underlaySubnetInfoMap SubnetInfoMap, underlayExcludeIPBlockMap map[string]*net.IPNet, options Options) error {
 
 	routeList, err := netlink.RouteListFiltered(family, &netlink.Route{
 		Table: table,
@@ -265,7 +317,7 @@ func ensureRoutesForVxlanSubnet(forwardLink netlink.Link, cidr *net.IPNet, table
 			Scope:     netlink.SCOPE_UNIVERSE,
 		}
 
-		if err := netlink.RouteReplace(defaultRoute); err != nil {
+		if err := replaceRouteWithOptions(defaultRoute, options, family); err != nil {
 			return fmt.Errorf("failed to add overlay subnet %v default route %v: %v", cidr.String(), defaultRoute.String(), err)
 		}
 
@@ -307,7 +359,7 @@ func ensureRoutesForVxlanSubnet(forwardLink netlink.Link, cidr *net.IPNet, table
 				Scope:     netlink.SCOPE_UNIVERSE,
 			}
 
-			if err := netlink.RouteReplace(subnetRoute); err != nil {
+			if err := replaceRouteWithOptions(subnetRoute, options, family); err != nil {
 				return fmt.Errorf("failed to set overlay route %v for table %v: %v", subnetRoute.String(), table, err)
 			}
 		}
@@ -320,7 +372,10 @@ func ensureRoutesForVxlanSubnet(forwardLink netlink.Link, cidr *net.IPNet, table
 	return nil
 }
 
-func ensureRoutesForVlanSubnet(forwardLink netlink.Link, cidr *net.IPNet, gateway net.IP, table, family int) error {
+// ensureRoutesForVlanSubnet ensures the direct route and default route of a vlan subnet, the default route goes
+// through defaultGateway if it is not nil, otherwise through the gateway of subnet
+func ensureRoutesForVlanSubnet(forwardLink netlink.Link, cidr *net.IPNet, gateway, defaultGateway net.IP, table, family int,
+	options Options) error {
 	localAddrList, err := netlink.AddrList(nil, family)
 	if err != nil {
 		return fmt.Errorf("failed to list local addresses: %v", err)
@@ -383,26 +438,46 @@ func ensureRoutesForVlanSubnet(forwardLink netlink.Link, cidr *net.IPNet, gatewa
 		subnetDirectRoute.Src = directRouteList[0].Src
 	}
 
+	if defaultGateway == nil {
+		defaultGateway = gateway
+	}
+
 	// avoid to use onlink flag because it doesn't work for ipv6 routes until linux 4.16
 	defaultRoute := &netlink.Route{
 		LinkIndex: forwardLink.Attrs().Index,
 		Table:     table,
 		Scope:     netlink.SCOPE_UNIVERSE,
-		Gw:        gateway,
+		Gw:        defaultGateway,
 	}
 
-	if err := netlink.RouteReplace(subnetDirectRoute); err != nil {
+	if err := replaceRouteWithOptions(subnetDirectRoute, options, family); err != nil {
 		return fmt.Errorf("failed to add vlan subent %v direct route %v: %v", cidr.String(), subnetDirectRoute.String(), err)
 	}
 
-	if err := netlink.RouteReplace(defaultRoute); err != nil {
+	if err := replaceRouteWithOptions(defaultRoute, options, family); err != nil {
 		return fmt.Errorf("failed to add vlan subnet %v default route %v: %v", cidr.String(), defaultRoute.String(), err)
 	}
 
+	// default gateway changes with egress routes, the stale default route needs to be deleted additionally
+	routeList, err := netlink.RouteListFiltered(family, &netlink.Route{
+		Table: table,
+	}, netlink.RT_FILTER_TABLE)
+	if err != nil {
+		return fmt.Errorf("failed to list route for table %v: %v", table, err)
+	}
+
+	for _, route := range routeList {
+		if daemonutils.IsDefaultRoute(&route, family) && !route.Gw.Equal(defaultGateway) {
+			if err := netlink.RouteDel(&route); err != nil {
+				return fmt.Errorf("failed to delete vlan route %v for table %v: %v", route.String(), table, err)
+			}
+		}
+	}
+
 	return nil
 }
 
-func ensureRoutesForBGPSubnet(forwardLink netlink.Link, cidr *net.IPNet, gateway net.IP, table, family int) error {
+func ensureRoutesForBGPSubnet(forwardLink netlink.Link, cidr *net.IPNet, gateway net.IP, table, family int, options Options) error {
 	// default route is always needed
 	var defaultRoute *netlink.Route
 	var err error
@@ -425,7 +500,7 @@ func ensureRoutesForBGPSubnet(forwardLink netlink.Link, cidr *net.IPNet, gateway
 		}
 	}
 
-	if err := netlink.RouteReplace(defaultRoute); err != nil {
+	if err := replaceRouteWithOptions(defaultRoute, options, family); err != nil {
 		return fmt.Errorf("failed to add bgp subnet %v default route %v: %v", cidr.String(), defaultRoute.String(), err)
 	}
 
@@ -451,6 +526,111 @@ func ensureRoutesForBGPSubnet(forwardLink netlink.Link, cidr *net.IPNet, gateway
 	return nil
 }
 
+// ensureEgressRoutes ensures the non-default egress routes of a subnet in its table, and removes the stale ones,
+// which are all the routes with both destination and gateway because other routes of underlay subnets have none
+func ensureEgressRoutes(forwardLink netlink.Link, egressRoutes []EgressRoute, table, family int, options Options) error {
+	expectedRoutes := map[string]*netlink.Route{}
+	for _, egressRoute := range egressRoutes {
+		if daemonutils.IsDefaultRoute(&netlink.Route{Dst: egressRoute.Dst}, family) {
+			continue
+		}
+
+		expectedRoutes[egressRoute.Dst.String()] = &netlink.Route{
+			LinkIndex: forwardLink.Attrs().Index,
+			Dst:       egressRoute.Dst,
+			Table:     table,
+			Scope:     netlink.SCOPE_UNIVERSE,
+			Gw:        egressRoute.Gateway,
+		}
+	}
+
+	routeList, err := netlink.RouteListFiltered(family, &netlink.Route{
+		Table: table,
+	}, netlink.RT_FILTER_TABLE)
+	if err != nil {
+		return fmt.Errorf("failed to list route for table %v: %v", table, err)
+	}
+
+	for _, route := range routeList {
+		if route.Dst == nil || route.Gw == nil || daemonutils.IsDefaultRoute(&route, family) {
+			continue
+		}
+
+		if expectedRoute, exist := expectedRoutes[route.Dst.String()]; exist &&
+			expectedRoute.Gw.Equal(route.Gw) && expectedRoute.LinkIndex == route.LinkIndex {
+			continue
+		}
+
+		if err := netlink.RouteDel(&route); err != nil {
+			return fmt.Errorf("failed to delete egress route %v for table %v: %v", route.String(), table, err)
+		}
+	}
+
+	for _, route := range expectedRoutes {
+		if err := replaceRouteWithOptions(route, options, family); err != nil {
+			return fmt.Errorf("failed to add egress route %v for table %v: %v", route.String(), table, err)
+		}
+	}
+
+	return nil
+}
+
+// egressDefaultGateway returns the gateway of default egress route, nil if not specified
+func egressDefaultGateway(egressRoutes []EgressRoute, family int) net.IP {
+	for _, egressRoute := range egressRoutes {
+		if daemonutils.IsDefaultRoute(&netlink.Route{Dst: egressRoute.Dst}, family) {
+			return egressRoute.Gateway
+		}
+	}
+	return nil
+}
+
+// replaceRouteWithOptions replaces route with options in its table, and deletes the routes to the same
+// destination left with former metrics, because routes of different metrics are never replaced by each other
+func replaceRouteWithOptions(route *netlink.Route, options Options, family int) error {
+	if err := netlink.RouteReplace(options.apply(route, family)); err != nil {
+		return err
+	}
+
+	routeList, err := netlink.RouteListFiltered(family, &netlink.Route{
+		Table: route.Table,
+	}, netlink.RT_FILTER_TABLE)
+	if err != nil {
+		return fmt.Errorf("failed to list route for table %v: %v", route.Table, err)
+	}
+
+	for _, existRoute := range routeList {
+		if isExcludeRoute(&existRoute) || !isSameRouteDst(existRoute.Dst, route.Dst, family) ||
+			realRoutePriority(existRoute.Priority, family) == realRoutePriority(route.Priority, family) {
+			continue
+		}
+
+		if err := netlink.RouteDel(&existRoute); err != nil {
+			return fmt.Errorf("failed to delete route %v with stale metric for table %v: %v", existRoute.String(), route.Table, err)
+		}
+	}
+
+	return nil
+}
+
+// isSameRouteDst checks if two routes have the same destination, a nil destination is the default one
+func isSameRouteDst(a, b *net.IPNet, family int) bool {
+	if a == nil {
+		a = defaultRouteDstByFamily(family)
+	}
+	if b == nil {
+		b = defaultRouteDstByFamily(family)
+	}
+	return a.String() == b.String()
+}
+
+func realRoutePriority(priority, family int) int {
+	if priority == 0 && family == netlink.FAMILY_V6 {
+		return defaultIPv6RoutePriority
+	}
+	return priority
+}
+
 func realRulePriority(priority int) int {
 	if priority == -1 {
 		return 0
@@ -0,0 +1,100 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package route
+
+import (
+	"net"
+	"testing"
+
+	"github.com/vishvananda/netlink"
+)
+
+func TestEgressDefaultGateway(t *testing.T) {
+	_, v4Default, _ := net.ParseCIDR("0.0.0.0/0")
+	_, v6Default, _ := net.ParseCIDR("::/0")
+	_, v6Dst, _ := net.ParseCIDR("fd00:100::/64")
+	egressRoutes := []EgressRoute{
+		{Dst: v6Dst, Gateway: net.ParseIP("fd00::fe")},
+		{Dst: v6Default, Gateway: net.ParseIP("fd00::1")},
+	}
+
+	tests := []struct {
+		name         string
+		egressRoutes []EgressRoute
+		family       int
+		gateway      net.IP
+	}{
+		{"ipv6 default route", egressRoutes, netlink.FAMILY_V6, net.ParseIP("fd00::1")},
+		{"no ipv4 default route", egressRoutes, netlink.FAMILY_V4, nil},
+		{"ipv4 default route", []EgressRoute{{Dst: v4Default, Gateway: net.ParseIP("10.0.0.1")}}, netlink.FAMILY_V4,
+			net.ParseIP("10.0.0.1")},
+	}
+
+	for _, test := range tests {
+		if gateway := egressDefaultGateway(test.egressRoutes, test.family); !gateway.Equal(test.gateway) {
+			t.Errorf("test %s fails, expected gateway %v but got %v", test.name, test.gateway, gateway)
+		}
+	}
+}
+
+func TestIsSameRouteDst(t *testing.T) {
+	_, v6Default, _ := net.ParseCIDR("::/0")
+	_, v6Dst, _ := net.ParseCIDR("fd00:100::/64")
+
+	tests := []struct {
+		name   string
+		a, b   *net.IPNet
+		family int
+		same   bool
+	}{
+		{"nil is ipv6 default", nil, v6Default, netlink.FAMILY_V6, true},
+		{"nil is not ipv4 default of ipv6 family", nil, defaultRouteDstByFamily(netlink.FAMILY_V4), netlink.FAMILY_V6, false},
+		{"different ipv6 destinations", v6Dst, v6Default, netlink.FAMILY_V6, false},
+		{"same ipv6 destinations", v6Dst, v6Dst, netlink.FAMILY_V6, true},
+	}
+
+	for _, test := range tests {
+		if same := isSameRouteDst(test.a, test.b, test.family); same != test.same {
+			t.Errorf("test %s fails, expected %v but got %v", test.name, test.same, same)
+		}
+	}
+}
+
+func TestOptionsMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		route   *netlink.Route
+		family  int
+		matches bool
+	}{
+		{"default ipv6 metric", Options{}, &netlink.Route{Priority: defaultIPv6RoutePriority}, netlink.FAMILY_V6, true},
+		{"realm ignored for ipv6", Options{Realm: 10}, &netlink.Route{Priority: defaultIPv6RoutePriority},
+			netlink.FAMILY_V6, true},
+		{"ipv6 metric changed", Options{Priority: 100}, &netlink.Route{Priority: defaultIPv6RoutePriority},
+			netlink.FAMILY_V6, false},
+		{"ipv4 realm changed", Options{Realm: 10}, &netlink.Route{}, netlink.FAMILY_V4, false},
+		{"ipv4 options applied", Options{Priority: 100, Realm: 10},
+			Options{Priority: 100, Realm: 10}.apply(&netlink.Route{}, netlink.FAMILY_V4), netlink.FAMILY_V4, true},
+	}
+
+	for _, test := range tests {
+		if matches := test.options.matches(test.route, test.family); matches != test.matches {
+			t.Errorf("test %s fails, expected %v but got %v", test.name, test.matches, matches)
+		}
+	}
+}
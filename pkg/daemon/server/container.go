@@ -35,7 +35,8 @@ import (
 
 // ipAddr is a CIDR notation IP address and prefix length
 func (cdh *cniDaemonHandler) configureNic(podName, podNamespace, netns, mac string,
-	allocatedIPs map[networkingv1.IPVersion]*utils.IPInfo, networkMode networkingv1.NetworkMode) (string, error) {
+	allocatedIPs map[networkingv1.IPVersion]*utils.IPInfo, networkMode networkingv1.NetworkMode,
+	staticRoutes []networkingv1.StaticRoute) (string, error) {
 
 	var err error
 	var nodeIfName string
@@ -58,6 +59,10 @@ func (cdh *cniDaemonHandler) configureNic(podName, podNamespace, netns, mac stri
 		return "", fmt.Errorf("failed to parse mac %s %v", macAddr, err)
 	}
 
+	if cdh.config.DryDataplane {
+		return cdh.configureDryNic(podName, podNamespace, allocatedIPs, macAddr, mtu, nodeIfName, networkMode)
+	}
+
 	containerNicName, hostNicName, podNS, err := initContainerNic(podName, podNamespace, netns, mtu)
 	if err != nil {
 		return "", fmt.Errorf("failed to init container nic for pod %v: %v", podName, err)
@@ -70,12 +75,12 @@ func (cdh *cniDaemonHandler) configureNic(podName, podNamespace, netns, mac stri
 		}
 	}()
 
-	if err = containernetwork.ConfigureHostNic(hostNicName, allocatedIPs, cdh.config.LocalDirectTableNum); err != nil {
+	if err = containernetwork.ConfigureHostNic(cdh.hostNetwork, hostNicName, allocatedIPs, cdh.config.LocalDirectTableNum); err != nil {
 		return "", fmt.Errorf("failed to configure host nic for %v.%v: %v", podName, podNamespace, err)
 	}
 
 	if err = containernetwork.ConfigureContainerNic(containerNicName, hostNicName, nodeIfName,
-		allocatedIPs, macAddr, podNS, mtu, cdh.config.VlanCheckTimeout, networkMode,
+		allocatedIPs, macAddr, podNS, mtu, cdh.config.VlanCheckTimeout, networkMode, staticRoutes,
 		cdh.config.NeighGCThresh1, cdh.config.NeighGCThresh2, cdh.config.NeighGCThresh3, cdh.config.IPv6RouteCacheMaxSize,
 		cdh.config.IPv6RouteCacheGCThresh, cdh.bgpManager); err != nil {
 		return "", fmt.Errorf("failed to configure container nic for %v.%v: %v", podName, podNamespace, err)
@@ -84,7 +89,8 @@ func (cdh *cniDaemonHandler) configureNic(podName, podNamespace, netns, mac stri
 	if allocatedIPs[networkingv1.IPv4] != nil {
 		podIP := allocatedIPs[networkingv1.IPv4].Addr
 
-		if cdh.config.CheckPodConnectivityFromHost {
+		// host is not able to reach pods in the vrf of tenants
+		if cdh.config.CheckPodConnectivityFromHost && len(allocatedIPs[networkingv1.IPv4].VRFName) == 0 {
 			// ICMP traffic from pod's node to pod is always assumed allowed.
 			// If the node has an usable ip, check the local pod's connectivity from node.
 			if err := containernetwork.CheckReachabilityFromHost(podIP, netlink.FAMILY_V4); err != nil {
@@ -96,7 +102,8 @@ func (cdh *cniDaemonHandler) configureNic(podName, podNamespace, netns, mac stri
 	if allocatedIPs[networkingv1.IPv6] != nil {
 		podIP := allocatedIPs[networkingv1.IPv6].Addr
 
-		if cdh.config.CheckPodConnectivityFromHost {
+		// host is not able to reach pods in the vrf of tenants
+		if cdh.config.CheckPodConnectivityFromHost && len(allocatedIPs[networkingv1.IPv6].VRFName) == 0 {
 			// ICMP traffic from pod's node to pod is always assumed allowed.
 			// If the node has an usable ip, check the local pod's connectivity from node.
 			if err := containernetwork.CheckReachabilityFromHost(podIP, netlink.FAMILY_V6); err != nil {
@@ -105,13 +112,53 @@ func (cdh *cniDaemonHandler) configureNic(podName, podNamespace, netns, mac stri
 		}
 	}
 
+	// resend announcements in background, so that upstream switches and routers converge even if the
+	// first one sent while checking vlan environment is lost
+	containernetwork.AnnounceAllocatedIPs(nodeIfName, allocatedIPs, networkMode,
+		cdh.logger.WithValues("podName", podName, "podNamespace", podNamespace))
+
 	return hostNicName, nil
 }
 
 func (cdh *cniDaemonHandler) deleteNic(netns string) error {
+	if cdh.config.DryDataplane {
+		cdh.logger.Info("dry dataplane, skip deleting container nic", "netns", netns)
+		return nil
+	}
 	return deleteContainerNic(netns)
 }
 
+// configureDryNic configures host nic on a fake host network and logs the result, container
+// nic is never created because there is no real netns in a dry dataplane
+func (cdh *cniDaemonHandler) configureDryNic(podName, podNamespace string, allocatedIPs map[networkingv1.IPVersion]*utils.IPInfo,
+	macAddr net.HardwareAddr, mtu int, nodeIfName string, networkMode networkingv1.NetworkMode) (string, error) {
+	hostNicName, containerNicName := containernetwork.GenerateContainerVethPair(podNamespace, podName)
+
+	hostNetwork, ok := cdh.hostNetwork.(*containernetwork.FakeHostNetwork)
+	if !ok {
+		return "", fmt.Errorf("host network of a dry dataplane must be fake")
+	}
+
+	// host nic is recreated for every request, as if the veth pair was deleted by a failed request
+	hostNetwork.DeleteLink(hostNicName)
+	hostNetwork.AddLink(hostNicName)
+
+	if err := containernetwork.ConfigureHostNic(hostNetwork, hostNicName, allocatedIPs, cdh.config.LocalDirectTableNum); err != nil {
+		return "", fmt.Errorf("failed to configure host nic for %v.%v: %v", podName, podNamespace, err)
+	}
+
+	cdh.logger.Info("dry dataplane, container nic configured",
+		"podName", podName,
+		"podNamespace", podNamespace,
+		"hostNicName", hostNicName,
+		"containerNicName", containerNicName,
+		"nodeIfName", nodeIfName,
+		"networkMode", networkMode,
+		"macAddr", macAddr.String(),
+		"mtu", mtu)
+	return hostNicName, nil
+}
+
 func deleteContainerNic(netns string) error {
 	nsHandler, err := ns.GetNS(netns)
 	if err != nil {
@@ -37,8 +37,10 @@ import (
 	"github.com/alibaba/hybridnet/pkg/constants"
 	"github.com/alibaba/hybridnet/pkg/daemon/bgp"
 	daemonconfig "github.com/alibaba/hybridnet/pkg/daemon/config"
+	"github.com/alibaba/hybridnet/pkg/daemon/containernetwork"
 	"github.com/alibaba/hybridnet/pkg/daemon/controller"
 	"github.com/alibaba/hybridnet/pkg/daemon/utils"
+	"github.com/alibaba/hybridnet/pkg/daemon/vrf"
 	ipamtypes "github.com/alibaba/hybridnet/pkg/ipam/types"
 	"github.com/alibaba/hybridnet/pkg/request"
 	globalutils "github.com/alibaba/hybridnet/pkg/utils"
@@ -50,6 +52,8 @@ type cniDaemonHandler struct {
 	mgrClient    client.Client
 	mgrAPIReader client.Reader
 	bgpManager   *bgp.Manager
+	limiter      *requestLimiter
+	hostNetwork  containernetwork.HostNetwork
 
 	logger logr.Logger
 }
@@ -61,9 +65,15 @@ func createCniDaemonHandler(ctx context.Context, config *daemonconfig.Configurat
 		mgrClient:    ctrlRef.GetMgrClient(),
 		mgrAPIReader: ctrlRef.GetMgrAPIReader(),
 		bgpManager:   ctrlRef.GetBGPManager(),
+		limiter:      newRequestLimiter(config),
+		hostNetwork:  containernetwork.NewHostNetwork(),
 		logger:       logger,
 	}
 
+	if config.DryDataplane {
+		cdh.hostNetwork = containernetwork.NewFakeHostNetwork()
+	}
+
 	if ok := ctrlRef.CacheSynced(ctx); !ok {
 		return nil, fmt.Errorf("failed to wait for ip instance & pod caches to sync")
 	}
@@ -191,6 +201,16 @@ func (cdh *cniDaemonHandler) handleAdd(req *restful.Request, resp *restful.Respo
 
 		gatewayIP := net.ParseIP(ipInstance.Spec.Address.Gateway)
 
+		subnet := &networkingv1.Subnet{}
+		if err := cdh.mgrClient.Get(context.TODO(), types.NamespacedName{Name: ipInstance.Spec.Subnet}, subnet); err != nil {
+			errMsg := fmt.Errorf("failed to get subnet %v: %v", ipInstance.Spec.Subnet, err)
+			cdh.errorWrapper(errMsg, http.StatusInternalServerError, resp)
+			return
+		}
+
+		nodeIfName := networkingv1.GetSubnetNodeInterface(&subnet.Spec)
+		announceRetries, announceInterval, _ := networkingv1.GetSubnetAddressAnnouncement(&subnet.Spec)
+
 		ipVersion := networkingv1.IPv4
 		switch ipInstance.Spec.Address.Version {
 		case networkingv1.IPv4:
@@ -201,10 +221,14 @@ func (cdh *cniDaemonHandler) handleAdd(req *restful.Request, resp *restful.Respo
 			}
 
 			allocatedIPs[networkingv1.IPv4] = &utils.IPInfo{
-				Addr:  containerIP,
-				Gw:    gatewayIP,
-				Cidr:  cidrNet,
-				NetID: ipInstance.Spec.Address.NetID,
+				Addr:             containerIP,
+				Gw:               gatewayIP,
+				Cidr:             cidrNet,
+				NetID:            ipInstance.Spec.Address.NetID,
+				NodeIfName:       nodeIfName,
+				AnnounceRetries:  announceRetries,
+				AnnounceInterval: announceInterval,
+				Adopted:          networkingv1.IsAdoptedIPInstance(ipInstance),
 			}
 		case networkingv1.IPv6:
 			if allocatedIPs[networkingv1.IPv6] != nil {
@@ -214,10 +238,14 @@ func (cdh *cniDaemonHandler) handleAdd(req *restful.Request, resp *restful.Respo
 			}
 
 			allocatedIPs[networkingv1.IPv6] = &utils.IPInfo{
-				Addr:  containerIP,
-				Gw:    gatewayIP,
-				Cidr:  cidrNet,
-				NetID: ipInstance.Spec.Address.NetID,
+				Addr:             containerIP,
+				Gw:               gatewayIP,
+				Cidr:             cidrNet,
+				NetID:            ipInstance.Spec.Address.NetID,
+				NodeIfName:       nodeIfName,
+				AnnounceRetries:  announceRetries,
+				AnnounceInterval: announceInterval,
+				Adopted:          networkingv1.IsAdoptedIPInstance(ipInstance),
 			}
 
 			ipVersion = networkingv1.IPv6
@@ -262,13 +290,34 @@ func (cdh *cniDaemonHandler) handleAdd(req *restful.Request, resp *restful.Respo
 		return
 	}
 
+	// pods of tenant networks are put into the vrf of their tenants
+	if networkingv1.IsTenantNetwork(network) && network.Spec.NetID != nil {
+		for _, ipInfo := range allocatedIPs {
+			if ipInfo != nil {
+				ipInfo.VRFName = vrf.GenerateVRFName(*network.Spec.NetID)
+				ipInfo.VRFTable = vrf.TableOf(*network.Spec.NetID)
+			}
+		}
+	}
+
+	staticRoutes, err := networkingv1.ParseStaticRoutes(pod.Annotations[constants.AnnotationStaticRoutes])
+	if err != nil {
+		errMsg := fmt.Errorf("failed to parse static routes of pod %v: %v", pod.Name, err)
+		cdh.errorWrapper(errMsg, http.StatusBadRequest, resp)
+		return
+	}
+
+	// rp_filter of host veth is applied while configuring container nic, and is kept by later syncs
+	hostNicName, _ := containernetwork.GenerateContainerVethPair(podRequest.PodNamespace, podRequest.PodName)
+	utils.SetRpFilterOverride(hostNicName, utils.RpFilterValueOf(networkingv1.GetNetworkRPFilter(network)))
+
 	cdh.logger.Info("Create container",
 		"podName", podRequest.PodName,
 		"podNamespace", podRequest.PodNamespace,
 		"ipAddr", printAllocatedIPs(allocatedIPs),
 		"macAddr", macAddr)
 	hostInterface, err := cdh.configureNic(podRequest.PodName, podRequest.PodNamespace, podRequest.NetNs, macAddr,
-		allocatedIPs, networkingv1.GetNetworkMode(network))
+		allocatedIPs, networkingv1.GetNetworkMode(network), staticRoutes)
 	if err != nil {
 		errMsg := fmt.Errorf("failed to configure nic: %v", err)
 		cdh.errorWrapper(errMsg, http.StatusInternalServerError, resp)
@@ -302,6 +351,14 @@ func (cdh *cniDaemonHandler) handleAdd(req *restful.Request, resp *restful.Respo
 		}
 	}
 
+	// network status is only for tooling, pod can still start without it
+	if cdh.config.PatchNetworkStatusAnnotation {
+		if err := cdh.patchNetworkStatus(context.TODO(), pod, network, affectedIPInstances); err != nil {
+			cdh.logger.Error(err, "failed to patch network status of pod",
+				"podName", podRequest.PodName, "podNamespace", podRequest.PodNamespace)
+		}
+	}
+
 	_ = resp.WriteHeaderAndEntity(http.StatusOK, request.PodResponse{
 		IPAddress:     returnIPAddress,
 		HostInterface: hostInterface,
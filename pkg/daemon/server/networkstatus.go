@@ -0,0 +1,125 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+)
+
+// networkStatus is an element of the network status annotation defined by Kubernetes Network Plumbing
+// Working Group, details only known by hybridnet are kept in a separate field which other tools ignore.
+type networkStatus struct {
+	Name      string                  `json:"name"`
+	Interface string                  `json:"interface,omitempty"`
+	IPs       []string                `json:"ips,omitempty"`
+	Mac       string                  `json:"mac,omitempty"`
+	Default   bool                    `json:"default"`
+	Gateway   []string                `json:"gateway,omitempty"`
+	Hybridnet *hybridnetNetworkStatus `json:"hybridnet,omitempty"`
+}
+
+type hybridnetNetworkStatus struct {
+	NetworkType networkingv1.NetworkType `json:"networkType"`
+	Subnets     []string                 `json:"subnets"`
+	NodeName    string                   `json:"nodeName"`
+	// VTEP of node is only for pods of overlay network
+	VTEP *networkingv1.VTEPInfo `json:"vtep,omitempty"`
+}
+
+// networkStatusOf summarizes the addresses of pod on its only interface
+func networkStatusOf(network *networkingv1.Network, ipInstances []*networkingv1.IPInstance, nodeName string,
+	vtepInfo *networkingv1.VTEPInfo) []networkStatus {
+	status := networkStatus{
+		Name:      network.Name,
+		Interface: constants.ContainerNicName,
+		Default:   true,
+		Hybridnet: &hybridnetNetworkStatus{
+			NetworkType: networkingv1.GetNetworkType(network),
+			NodeName:    nodeName,
+		},
+	}
+
+	subnets := map[string]bool{}
+	for _, ipInstance := range ipInstances {
+		if ip, _, err := net.ParseCIDR(ipInstance.Spec.Address.IP); err == nil {
+			status.IPs = append(status.IPs, ip.String())
+		}
+		if len(ipInstance.Spec.Address.Gateway) > 0 {
+			status.Gateway = append(status.Gateway, ipInstance.Spec.Address.Gateway)
+		}
+		status.Mac = ipInstance.Spec.Address.MAC
+
+		if !subnets[ipInstance.Spec.Subnet] {
+			subnets[ipInstance.Spec.Subnet] = true
+			status.Hybridnet.Subnets = append(status.Hybridnet.Subnets, ipInstance.Spec.Subnet)
+		}
+	}
+
+	if status.Hybridnet.NetworkType == networkingv1.NetworkTypeOverlay {
+		status.Hybridnet.VTEP = vtepInfo
+	}
+
+	return []networkStatus{status}
+}
+
+// patchNetworkStatus writes the network status annotation of pod
+func (cdh *cniDaemonHandler) patchNetworkStatus(ctx context.Context, pod *corev1.Pod, network *networkingv1.Network,
+	ipInstances []*networkingv1.IPInstance) error {
+	var vtepInfo *networkingv1.VTEPInfo
+	if networkingv1.GetNetworkType(network) == networkingv1.NetworkTypeOverlay {
+		nodeInfo := &networkingv1.NodeInfo{}
+		if err := cdh.mgrClient.Get(ctx, types.NamespacedName{Name: cdh.config.NodeName}, nodeInfo); err != nil {
+			if !errors.IsNotFound(err) {
+				return fmt.Errorf("failed to get node info %v: %v", cdh.config.NodeName, err)
+			}
+		} else {
+			vtepInfo = nodeInfo.Spec.VTEPInfo
+		}
+	}
+
+	statusBytes, err := json.Marshal(networkStatusOf(network, ipInstances, cdh.config.NodeName, vtepInfo))
+	if err != nil {
+		return fmt.Errorf("failed to marshal network status: %v", err)
+	}
+
+	patchBytes, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				constants.AnnotationNetworkStatus: string(statusBytes),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal patch of network status: %v", err)
+	}
+
+	if err = cdh.mgrClient.Patch(ctx, pod, client.RawPatch(types.MergePatchType, patchBytes)); err != nil {
+		return fmt.Errorf("failed to patch network status annotation: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+)
+
+func TestNetworkStatusOf(t *testing.T) {
+	ipInstances := []*networkingv1.IPInstance{
+		{Spec: networkingv1.IPInstanceSpec{
+			Network: "overlay",
+			Subnet:  "subnet-v4",
+			Address: networkingv1.Address{IP: "10.0.0.2/24", Gateway: "10.0.0.1", MAC: "02:00:00:00:00:01"},
+		}},
+		{Spec: networkingv1.IPInstanceSpec{
+			Network: "overlay",
+			Subnet:  "subnet-v6",
+			Address: networkingv1.Address{IP: "fd00::2/64", MAC: "02:00:00:00:00:01"},
+		}},
+	}
+	vtepInfo := &networkingv1.VTEPInfo{IP: "192.168.0.10", MAC: "02:00:00:00:00:ff"}
+
+	tests := []struct {
+		name     string
+		network  *networkingv1.Network
+		expected string
+	}{
+		{
+			"overlay network",
+			&networkingv1.Network{
+				ObjectMeta: metav1.ObjectMeta{Name: "overlay"},
+				Spec:       networkingv1.NetworkSpec{Type: networkingv1.NetworkTypeOverlay},
+			},
+			`[{"name":"overlay","interface":"eth0","ips":["10.0.0.2","fd00::2"],"mac":"02:00:00:00:00:01",` +
+				`"default":true,"gateway":["10.0.0.1"],"hybridnet":{"networkType":"Overlay",` +
+				`"subnets":["subnet-v4","subnet-v6"],"nodeName":"node1",` +
+				`"vtep":{"ip":"192.168.0.10","mac":"02:00:00:00:00:ff"}}}]`,
+		},
+		{
+			"underlay network without vtep",
+			&networkingv1.Network{
+				ObjectMeta: metav1.ObjectMeta{Name: "underlay"},
+				Spec:       networkingv1.NetworkSpec{Type: networkingv1.NetworkTypeUnderlay},
+			},
+			`[{"name":"underlay","interface":"eth0","ips":["10.0.0.2","fd00::2"],"mac":"02:00:00:00:00:01",` +
+				`"default":true,"gateway":["10.0.0.1"],"hybridnet":{"networkType":"Underlay",` +
+				`"subnets":["subnet-v4","subnet-v6"],"nodeName":"node1"}}]`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statusBytes, err := json.Marshal(networkStatusOf(test.network, ipInstances, "node1", vtepInfo))
+			if err != nil {
+				t.Fatalf("failed to marshal network status: %v", err)
+			}
+			if string(statusBytes) != test.expected {
+				t.Errorf("expected %s but got %s", test.expected, statusBytes)
+			}
+		})
+	}
+}
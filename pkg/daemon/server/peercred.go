@@ -0,0 +1,169 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/emicklei/go-restful"
+	"golang.org/x/sys/unix"
+
+	daemonconfig "github.com/alibaba/hybridnet/pkg/daemon/config"
+)
+
+type peerCredentialKey struct{}
+
+// peerCredential is the credential of local process on the other side of unix socket,
+// which is provided by kernel and can not be forged by the caller
+type peerCredential struct {
+	PID int32
+	UID uint32
+	// Binary is empty if executable of peer is unknown
+	Binary string
+	// BinaryID is nil if executable of peer is unknown
+	BinaryID *fileID
+
+	// Err is the reason why credential can not be fetched from socket
+	Err error
+}
+
+func (p *peerCredential) String() string {
+	return fmt.Sprintf("pid=%d uid=%d binary=%s", p.PID, p.UID, p.Binary)
+}
+
+// withPeerCredential is used as ConnContext of http server, credential is fetched once
+// on connection accepted and shared by all requests of the connection
+func withPeerCredential(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, peerCredentialKey{}, peerCredentialOf(conn))
+}
+
+func peerCredentialOf(conn net.Conn) *peerCredential {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return &peerCredential{Err: fmt.Errorf("connection is not from unix socket")}
+	}
+
+	rawConn, err := unixConn.SyscallConn()
+	if err != nil {
+		return &peerCredential{Err: fmt.Errorf("failed to get raw connection: %v", err)}
+	}
+
+	var ucred *unix.Ucred
+	var sockErr error
+	if err = rawConn.Control(func(fd uintptr) {
+		ucred, sockErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	}); err != nil {
+		return &peerCredential{Err: fmt.Errorf("failed to control raw connection: %v", err)}
+	}
+	if sockErr != nil {
+		return &peerCredential{Err: fmt.Errorf("failed to get peer credential: %v", sockErr)}
+	}
+
+	credential := &peerCredential{
+		PID: ucred.Pid,
+		UID: ucred.Uid,
+	}
+
+	// daemon runs in host pid namespace, so /proc of peer is visible
+	exe := "/proc/" + strconv.Itoa(int(ucred.Pid)) + "/exe"
+	credential.Binary, _ = os.Readlink(exe)
+	credential.BinaryID, _ = fileIDOf(exe)
+
+	return credential
+}
+
+// fileID identifies a file by device and inode, the path of executable of peer is resolved in
+// its own mount namespace, so a file of the same path in a container must not be trusted
+type fileID struct {
+	Dev uint64
+	Ino uint64
+}
+
+// fileIDOf follows symbolic links, including the exe link of /proc which points to the
+// executable even if it is out of the mount namespace of daemon
+func fileIDOf(path string) (*fileID, error) {
+	var stat unix.Stat_t
+	if err := unix.Stat(path, &stat); err != nil {
+		return nil, err
+	}
+	return &fileID{Dev: uint64(stat.Dev), Ino: stat.Ino}, nil
+}
+
+// authorizePeer checks peer credential against rule of verb before request is handled
+func (cdh *cniDaemonHandler) authorizePeer(verb string) restful.FilterFunction {
+	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		credential, _ := req.Request.Context().Value(peerCredentialKey{}).(*peerCredential)
+		if err := authorize(cdh.config.CNIServerPeerRules, verb, credential); err != nil {
+			cdh.errorWrapper(fmt.Errorf("unauthorized %s request: %v", verb, err), http.StatusForbidden, resp)
+			return
+		}
+		chain.ProcessFilter(req, resp)
+	}
+}
+
+func authorize(rules map[string]daemonconfig.CNIServerPeerRule, verb string, credential *peerCredential) error {
+	rule, exist := rules[verb]
+	if !exist {
+		return fmt.Errorf("no rule for verb %s", verb)
+	}
+
+	if credential == nil {
+		return fmt.Errorf("no peer credential")
+	}
+	if credential.Err != nil {
+		return credential.Err
+	}
+
+	if len(rule.UIDs) > 0 && !containsUID(rule.UIDs, credential.UID) {
+		return fmt.Errorf("peer %v is not of allowed uids %v", credential, rule.UIDs)
+	}
+
+	if len(rule.Binaries) > 0 && !binaryAllowed(rule.Binaries, credential.BinaryID) {
+		return fmt.Errorf("peer %v is not of allowed binaries %v", credential, rule.Binaries)
+	}
+
+	return nil
+}
+
+// binaryAllowed compares executable of peer with allowed binaries on host, which are checked on
+// every request because binaries may be re-installed, an unknown executable never matches
+func binaryAllowed(binaries []string, binaryID *fileID) bool {
+	if binaryID == nil {
+		return false
+	}
+
+	for _, binary := range binaries {
+		if allowedID, err := fileIDOf(binary); err == nil && *allowedID == *binaryID {
+			return true
+		}
+	}
+	return false
+}
+
+func containsUID(uids []uint32, uid uint32) bool {
+	for _, allowed := range uids {
+		if allowed == uid {
+			return true
+		}
+	}
+	return false
+}
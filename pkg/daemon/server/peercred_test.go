@@ -0,0 +1,180 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	daemonconfig "github.com/alibaba/hybridnet/pkg/daemon/config"
+)
+
+func TestPeerCredentialOf(t *testing.T) {
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "test.sock"))
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	defer listener.Close()
+
+	client, err := net.Dial("unix", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("unable to dial: %v", err)
+	}
+	defer client.Close()
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("unable to accept: %v", err)
+	}
+	defer conn.Close()
+
+	credential := peerCredentialOf(conn)
+	if credential.Err != nil {
+		t.Fatalf("unexpected error: %v", credential.Err)
+	}
+	if credential.PID != int32(os.Getpid()) || credential.UID != uint32(os.Getuid()) {
+		t.Errorf("unexpected credential %v", credential)
+	}
+
+	executable, _ := os.Executable()
+	if credential.Binary != executable {
+		t.Errorf("expect binary %s but got %s", executable, credential.Binary)
+	}
+
+	executableID, err := fileIDOf(executable)
+	if err != nil {
+		t.Fatalf("unable to stat executable: %v", err)
+	}
+	if credential.BinaryID == nil || *credential.BinaryID != *executableID {
+		t.Errorf("expect binary id %v but got %v", executableID, credential.BinaryID)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	// binaries are matched by device and inode, the same path in another mount namespace
+	// is a different file
+	binDir := t.TempDir()
+	newBinary := func(name string) *fileID {
+		path := filepath.Join(binDir, name)
+		if err := os.WriteFile(path, []byte(name), 0755); err != nil {
+			t.Fatalf("unable to write binary %s: %v", name, err)
+		}
+		id, err := fileIDOf(path)
+		if err != nil {
+			t.Fatalf("unable to stat binary %s: %v", name, err)
+		}
+		return id
+	}
+	hybridnet := newBinary("hybridnet")
+	curl := newBinary("curl")
+	if err := os.Symlink(filepath.Join(binDir, "hybridnet"), filepath.Join(binDir, "link")); err != nil {
+		t.Fatalf("unable to link binary: %v", err)
+	}
+
+	rules := map[string]daemonconfig.CNIServerPeerRule{
+		daemonconfig.CNIServerVerbAdd: {
+			UIDs:     []uint32{0},
+			Binaries: []string{filepath.Join(binDir, "link"), filepath.Join(binDir, "missing")},
+		},
+		daemonconfig.CNIServerVerbDel: {
+			UIDs: []uint32{0, 1000},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		verb       string
+		credential *peerCredential
+		allowed    bool
+	}{
+		{
+			"allowed uid and binary",
+			daemonconfig.CNIServerVerbAdd,
+			&peerCredential{PID: 1, UID: 0, BinaryID: hybridnet},
+			true,
+		},
+		{
+			"forbidden binary",
+			daemonconfig.CNIServerVerbAdd,
+			&peerCredential{PID: 1, UID: 0, BinaryID: curl},
+			false,
+		},
+		{
+			"binary of same path in another mount namespace",
+			daemonconfig.CNIServerVerbAdd,
+			&peerCredential{PID: 1, UID: 0, Binary: filepath.Join(binDir, "hybridnet"), BinaryID: curl},
+			false,
+		},
+		{
+			"unknown binary",
+			daemonconfig.CNIServerVerbAdd,
+			&peerCredential{PID: 1, UID: 0},
+			false,
+		},
+		{
+			"uid allowed only for del",
+			daemonconfig.CNIServerVerbAdd,
+			&peerCredential{PID: 1, UID: 1000, BinaryID: hybridnet},
+			false,
+		},
+		{
+			"del with any binary",
+			daemonconfig.CNIServerVerbDel,
+			&peerCredential{PID: 1, UID: 1000, BinaryID: curl},
+			true,
+		},
+		{
+			"forbidden uid",
+			daemonconfig.CNIServerVerbDel,
+			&peerCredential{PID: 1, UID: 1001},
+			false,
+		},
+		{
+			"credential error",
+			daemonconfig.CNIServerVerbDel,
+			&peerCredential{Err: fmt.Errorf("no credential")},
+			false,
+		},
+		{
+			"no credential",
+			daemonconfig.CNIServerVerbDel,
+			nil,
+			false,
+		},
+		{
+			"unknown verb",
+			"get",
+			&peerCredential{PID: 1, UID: 0},
+			false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := authorize(rules, test.verb, test.credential)
+			if test.allowed && err != nil {
+				t.Errorf("expect allowed but got %v", err)
+			}
+			if !test.allowed && err == nil {
+				t.Errorf("expect forbidden")
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/emicklei/go-restful"
+	"golang.org/x/time/rate"
+
+	daemonconfig "github.com/alibaba/hybridnet/pkg/daemon/config"
+)
+
+// requestLimiter limits the rate of add requests and the number of requests handled concurrently,
+// requests beyond the limits wait in a bounded queue or are rejected with a retryable status
+type requestLimiter struct {
+	// addLimiter is nil if rate of add requests is not limited
+	addLimiter *rate.Limiter
+	// inflight is nil if concurrency is not limited
+	inflight     chan struct{}
+	queued       int32
+	maxQueued    int32
+	queueTimeout time.Duration
+}
+
+func newRequestLimiter(config *daemonconfig.Configuration) *requestLimiter {
+	l := &requestLimiter{
+		maxQueued:    int32(config.CNIServerMaxQueuedRequests),
+		queueTimeout: config.CNIServerQueueTimeout,
+	}
+	if config.CNIServerAddQPS > 0 {
+		l.addLimiter = rate.NewLimiter(rate.Limit(config.CNIServerAddQPS), config.CNIServerAddBurst)
+	}
+	if config.CNIServerMaxInflightRequests > 0 {
+		l.inflight = make(chan struct{}, config.CNIServerMaxInflightRequests)
+	}
+	return l
+}
+
+// acquire returns a release function if request is admitted, or the duration after which
+// caller should retry
+func (l *requestLimiter) acquire(ctx context.Context, verb string) (func(), time.Duration, error) {
+	if verb == daemonconfig.CNIServerVerbAdd && l.addLimiter != nil && !l.addLimiter.Allow() {
+		return nil, l.retryAfter(), fmt.Errorf("rate of add requests exceeds %v per second", l.addLimiter.Limit())
+	}
+
+	if l.inflight == nil {
+		return func() {}, 0, nil
+	}
+
+	release := func() { <-l.inflight }
+	select {
+	case l.inflight <- struct{}{}:
+		return release, 0, nil
+	default:
+	}
+
+	if atomic.AddInt32(&l.queued, 1) > l.maxQueued {
+		atomic.AddInt32(&l.queued, -1)
+		return nil, l.retryAfter(), fmt.Errorf("%d requests are being handled and %d requests are queued",
+			cap(l.inflight), l.maxQueued)
+	}
+	defer atomic.AddInt32(&l.queued, -1)
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case l.inflight <- struct{}{}:
+		return release, 0, nil
+	case <-timer.C:
+		return nil, l.retryAfter(), fmt.Errorf("request is queued for more than %v", l.queueTimeout)
+	case <-ctx.Done():
+		return nil, 0, ctx.Err()
+	}
+}
+
+func (l *requestLimiter) retryAfter() time.Duration {
+	if l.addLimiter != nil && l.addLimiter.Limit() > 0 {
+		return time.Duration(float64(l.addLimiter.Burst()) / float64(l.addLimiter.Limit()) * float64(time.Second))
+	}
+	return time.Second
+}
+
+// limitRequest rejects requests beyond limits with 429, which is treated as try-again-later by cni plugin
+func (cdh *cniDaemonHandler) limitRequest(verb string) restful.FilterFunction {
+	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		release, retryAfter, err := cdh.limiter.acquire(req.Request.Context(), verb)
+		if err != nil {
+			resp.AddHeader("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			cdh.errorWrapper(fmt.Errorf("cni server is busy, %s request should be retried later: %v", verb, err),
+				http.StatusTooManyRequests, resp)
+			return
+		}
+		defer release()
+
+		chain.ProcessFilter(req, resp)
+	}
+}
@@ -0,0 +1,90 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	daemonconfig "github.com/alibaba/hybridnet/pkg/daemon/config"
+)
+
+func TestRequestLimiter(t *testing.T) {
+	l := newRequestLimiter(&daemonconfig.Configuration{
+		CNIServerAddQPS:              1,
+		CNIServerAddBurst:            2,
+		CNIServerMaxInflightRequests: 1,
+		CNIServerMaxQueuedRequests:   1,
+		CNIServerQueueTimeout:        100 * time.Millisecond,
+	})
+	ctx := context.Background()
+
+	release, _, err := l.acquire(ctx, daemonconfig.CNIServerVerbAdd)
+	if err != nil {
+		t.Fatalf("expect first request admitted but got %v", err)
+	}
+
+	// the only inflight slot is taken, queued request times out
+	if _, retryAfter, err := l.acquire(ctx, daemonconfig.CNIServerVerbDel); err == nil || retryAfter <= 0 {
+		t.Errorf("expect queued request timed out with retry after, but got %v, %v", retryAfter, err)
+	}
+
+	// queued request is admitted once slot is released
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		release()
+	}()
+	release, _, err = l.acquire(ctx, daemonconfig.CNIServerVerbAdd)
+	if err != nil {
+		t.Fatalf("expect queued request admitted but got %v", err)
+	}
+	release()
+
+	// burst is used up by add requests, but del requests are not rate limited
+	if _, _, err = l.acquire(ctx, daemonconfig.CNIServerVerbAdd); err == nil {
+		t.Errorf("expect add request rejected by rate limit")
+	}
+	if release, _, err = l.acquire(ctx, daemonconfig.CNIServerVerbDel); err != nil {
+		t.Errorf("expect del request admitted but got %v", err)
+	} else {
+		release()
+	}
+}
+
+func TestRequestLimiterQueueFull(t *testing.T) {
+	l := newRequestLimiter(&daemonconfig.Configuration{
+		CNIServerMaxInflightRequests: 1,
+		CNIServerMaxQueuedRequests:   0,
+		CNIServerQueueTimeout:        time.Second,
+	})
+	ctx := context.Background()
+
+	release, _, err := l.acquire(ctx, daemonconfig.CNIServerVerbAdd)
+	if err != nil {
+		t.Fatalf("expect first request admitted but got %v", err)
+	}
+	defer release()
+
+	start := time.Now()
+	if _, _, err = l.acquire(ctx, daemonconfig.CNIServerVerbAdd); err == nil {
+		t.Errorf("expect request rejected while queue is full")
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("request is expected to be rejected immediately while queue is full")
+	}
+}
@@ -39,7 +39,8 @@ func RunServer(ctx context.Context, config *config.Configuration, ctrlRef *contr
 		return
 	}
 	server := http.Server{
-		Handler: createHandler(cdh),
+		Handler:     createHandler(cdh),
+		ConnContext: withPeerCredential,
 	}
 	unixListener, err := net.Listen("unix", config.BindSocket)
 	if err != nil {
@@ -65,10 +66,14 @@ func createHandler(cdh *cniDaemonHandler) http.Handler {
 
 	ws.Route(
 		ws.POST("/add").
+			Filter(cdh.authorizePeer(config.CNIServerVerbAdd)).
+			Filter(cdh.limitRequest(config.CNIServerVerbAdd)).
 			To(cdh.handleAdd).
 			Reads(request.PodRequest{}))
 	ws.Route(
 		ws.POST("/del").
+			Filter(cdh.authorizePeer(config.CNIServerVerbDel)).
+			Filter(cdh.limitRequest(config.CNIServerVerbDel)).
 			To(cdh.handleDel).
 			Reads(request.PodRequest{}))
 
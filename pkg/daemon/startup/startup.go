@@ -0,0 +1,250 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package startup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+type Phase string
+
+const (
+	PhasePending   = Phase("Pending")
+	PhaseRunning   = Phase("Running")
+	PhaseSucceeded = Phase("Succeeded")
+	PhaseFailed    = Phase("Failed")
+	// PhaseSkipped means the stage never runs because a stage it depends on failed
+	PhaseSkipped = Phase("Skipped")
+)
+
+// Stage is a step of startup, stages without dependencies between them run concurrently
+type Stage struct {
+	Name string
+	// DependsOn are the names of stages which must succeed before this one runs
+	DependsOn []string
+	Run       func(ctx context.Context) error
+}
+
+// Report is the progress of a stage
+type Report struct {
+	Name      string        `json:"name"`
+	Phase     Phase         `json:"phase"`
+	StartTime *time.Time    `json:"startTime,omitempty"`
+	Duration  time.Duration `json:"duration,omitempty"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// Runner runs stages in the order of dependencies and reports their progress, it can run
+// multiple groups of stages one after another and reports all of them.
+type Runner struct {
+	logger logr.Logger
+
+	mutex   sync.RWMutex
+	reports []*Report
+}
+
+func NewRunner(logger logr.Logger) *Runner {
+	return &Runner{logger: logger}
+}
+
+// Run runs the stages and waits for all of them to finish. Every stage starts as soon as the
+// stages it depends on succeed, which must be among the stages of the same run, and it is
+// skipped if any of them fails. The errors of failed stages are returned together.
+func (r *Runner) Run(ctx context.Context, stages ...Stage) error {
+	if err := validateStages(stages); err != nil {
+		return err
+	}
+
+	reports := make(map[string]*Report, len(stages))
+	done := make(map[string]chan struct{}, len(stages))
+
+	r.mutex.Lock()
+	for _, stage := range stages {
+		report := &Report{Name: stage.Name, Phase: PhasePending}
+		reports[stage.Name] = report
+		done[stage.Name] = make(chan struct{})
+		r.reports = append(r.reports, report)
+	}
+	r.mutex.Unlock()
+
+	begin := time.Now()
+	wg := sync.WaitGroup{}
+	for i := range stages {
+		stage := stages[i]
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer close(done[stage.Name])
+
+			for _, dependency := range stage.DependsOn {
+				<-done[dependency]
+				if r.phaseOf(reports[dependency]) != PhaseSucceeded {
+					r.finish(reports[stage.Name], PhaseSkipped, 0,
+						fmt.Errorf("stage %v it depends on does not succeed", dependency))
+					r.logger.Info("skip startup stage", "stage", stage.Name, "dependency", dependency)
+					return
+				}
+			}
+
+			startTime := time.Now()
+			r.mutex.Lock()
+			reports[stage.Name].Phase = PhaseRunning
+			reports[stage.Name].StartTime = &startTime
+			r.mutex.Unlock()
+
+			if err := stage.Run(ctx); err != nil {
+				r.finish(reports[stage.Name], PhaseFailed, time.Since(startTime), err)
+				r.logger.Error(err, "startup stage failed", "stage", stage.Name, "duration", time.Since(startTime))
+				return
+			}
+
+			r.finish(reports[stage.Name], PhaseSucceeded, time.Since(startTime), nil)
+			r.logger.Info("startup stage succeeded", "stage", stage.Name, "duration", time.Since(startTime))
+		}()
+	}
+	wg.Wait()
+
+	var errs []string
+	for _, stage := range stages {
+		if report := reports[stage.Name]; report.Phase == PhaseFailed {
+			errs = append(errs, fmt.Sprintf("stage %v: %v", report.Name, report.Error))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to run startup stages: %v", strings.Join(errs, "; "))
+	}
+
+	r.logger.Info("startup stages finished", "stages", len(stages), "duration", time.Since(begin))
+	return nil
+}
+
+func (r *Runner) phaseOf(report *Report) Phase {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return report.Phase
+}
+
+func (r *Runner) finish(report *Report, phase Phase, duration time.Duration, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	report.Phase = phase
+	report.Duration = duration
+	if err != nil {
+		report.Error = err.Error()
+	}
+}
+
+// Reports returns the progress of all stages in the order they are run
+func (r *Runner) Reports() []Report {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	reports := make([]Report, 0, len(r.reports))
+	for _, report := range r.reports {
+		reports = append(reports, *report)
+	}
+	return reports
+}
+
+// Check returns an error unless all stages have succeeded, which is used as a readiness check
+func (r *Runner) Check() error {
+	var unfinished []string
+	for _, report := range r.Reports() {
+		if report.Phase != PhaseSucceeded {
+			unfinished = append(unfinished, fmt.Sprintf("%v(%v)", report.Name, report.Phase))
+		}
+	}
+
+	if len(unfinished) > 0 {
+		return fmt.Errorf("startup stages not succeeded: %v", strings.Join(unfinished, ", "))
+	}
+	return nil
+}
+
+// Handler serves the progress of all stages as json
+func (r *Runner) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(r.Reports()); err != nil {
+			http.Error(w, fmt.Sprintf("failed to encode startup reports: %v", err), http.StatusInternalServerError)
+		}
+	})
+}
+
+// validateStages makes sure stage names are unique and dependencies are known and acyclic
+func validateStages(stages []Stage) error {
+	dependencies := make(map[string][]string, len(stages))
+	for _, stage := range stages {
+		if _, exist := dependencies[stage.Name]; exist {
+			return fmt.Errorf("duplicated startup stage %v", stage.Name)
+		}
+		dependencies[stage.Name] = stage.DependsOn
+	}
+
+	for _, stage := range stages {
+		for _, dependency := range stage.DependsOn {
+			if _, exist := dependencies[dependency]; !exist {
+				return fmt.Errorf("unknown stage %v which stage %v depends on", dependency, stage.Name)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make(map[string]int, len(stages))
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch states[name] {
+		case visiting:
+			return fmt.Errorf("circular dependency of startup stage %v", name)
+		case visited:
+			return nil
+		}
+
+		states[name] = visiting
+		for _, dependency := range dependencies[name] {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		states[name] = visited
+		return nil
+	}
+
+	for _, stage := range stages {
+		if err := visit(stage.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
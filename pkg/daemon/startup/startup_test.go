@@ -0,0 +1,141 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package startup
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+func TestRunOrder(t *testing.T) {
+	var mutex sync.Mutex
+	var order []string
+	record := func(name string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			order = append(order, name)
+			return nil
+		}
+	}
+
+	// independent stages wait for each other, which never returns if they run one by one
+	bothStarted := sync.WaitGroup{}
+	bothStarted.Add(2)
+	concurrent := func(name string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			bothStarted.Done()
+			bothStarted.Wait()
+			return record(name)(ctx)
+		}
+	}
+
+	runner := NewRunner(logr.Discard())
+	if err := runner.Run(context.Background(),
+		Stage{Name: "controllers", DependsOn: []string{"route", "iptables"}, Run: record("controllers")},
+		Stage{Name: "route", Run: concurrent("route")},
+		Stage{Name: "iptables", Run: concurrent("iptables")},
+	); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(order) != 3 || order[2] != "controllers" {
+		t.Errorf("expect controllers to run after its dependencies but got order %v", order)
+	}
+	if err := runner.Check(); err != nil {
+		t.Errorf("expect all stages succeeded but got %v", err)
+	}
+}
+
+func TestRunFailure(t *testing.T) {
+	runner := NewRunner(logr.Discard())
+	err := runner.Run(context.Background(),
+		Stage{Name: "route", Run: func(ctx context.Context) error { return fmt.Errorf("table is used by others") }},
+		Stage{Name: "neigh", Run: func(ctx context.Context) error { return nil }},
+		Stage{Name: "controllers", DependsOn: []string{"route", "neigh"}, Run: func(ctx context.Context) error {
+			t.Errorf("stage controllers should never run")
+			return nil
+		}},
+	)
+	if err == nil {
+		t.Fatalf("expect error of failed stage")
+	}
+
+	expect := map[string]Phase{"route": PhaseFailed, "neigh": PhaseSucceeded, "controllers": PhaseSkipped}
+	for _, report := range runner.Reports() {
+		if report.Phase != expect[report.Name] {
+			t.Errorf("expect stage %v to be %v but got %v", report.Name, expect[report.Name], report.Phase)
+		}
+	}
+	if runner.Check() == nil {
+		t.Errorf("expect check error with failed stages")
+	}
+}
+
+func TestValidateStages(t *testing.T) {
+	noop := func(ctx context.Context) error { return nil }
+
+	tests := []struct {
+		name      string
+		stages    []Stage
+		expectErr bool
+	}{
+		{
+			name: "valid",
+			stages: []Stage{
+				{Name: "a", Run: noop},
+				{Name: "b", DependsOn: []string{"a"}, Run: noop},
+			},
+		},
+		{
+			name: "duplicated",
+			stages: []Stage{
+				{Name: "a", Run: noop},
+				{Name: "a", Run: noop},
+			},
+			expectErr: true,
+		},
+		{
+			name: "unknown dependency",
+			stages: []Stage{
+				{Name: "a", DependsOn: []string{"b"}, Run: noop},
+			},
+			expectErr: true,
+		},
+		{
+			name: "circular dependency",
+			stages: []Stage{
+				{Name: "a", DependsOn: []string{"c"}, Run: noop},
+				{Name: "b", DependsOn: []string{"a"}, Run: noop},
+				{Name: "c", DependsOn: []string{"b"}, Run: noop},
+			},
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateStages(test.stages)
+			if test.expectErr != (err != nil) {
+				t.Errorf("test %s fail, expect error %v but got %v", test.name, test.expectErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package statedump
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/vishvananda/netlink"
+
+	"github.com/alibaba/hybridnet/pkg/daemon/config"
+)
+
+// Path is the path of the state dump endpoint on metrics server of daemon
+const Path = "/debug/state"
+
+// State is the snapshot of daemon configuration and host dataplane of a node, for support bundles
+type State struct {
+	NodeName    string                `json:"nodeName"`
+	CollectedAt time.Time             `json:"collectedAt"`
+	Config      *config.Configuration `json:"config"`
+	// Dataplane is empty if daemon runs with dry dataplane
+	Dataplane *Dataplane `json:"dataplane,omitempty"`
+	// Errors of collecting dataplane, the state is partial if any
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Dataplane is the host network state which hybridnet-daemon manages, every entry is in its
+// "ip" command like string format
+type Dataplane struct {
+	Links     []string `json:"links"`
+	Addresses []string `json:"addresses"`
+	Routes    []string `json:"routes"`
+	Rules     []string `json:"rules"`
+	Neighs    []string `json:"neighs"`
+	FDB       []string `json:"fdb"`
+}
+
+// Handler serves the state of daemon as json
+func Handler(config *config.Configuration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		state := Collect(config)
+
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(state); err != nil {
+			http.Error(w, fmt.Sprintf("failed to encode daemon state: %v", err), http.StatusInternalServerError)
+		}
+	})
+}
+
+// Collect collects the state of daemon, failures of collecting dataplane are recorded in the state
+func Collect(config *config.Configuration) *State {
+	state := &State{
+		NodeName:    config.NodeName,
+		CollectedAt: time.Now(),
+		Config:      config,
+	}
+
+	if config.DryDataplane {
+		return state
+	}
+
+	dataplane, errs := collectDataplane()
+	state.Dataplane = dataplane
+	for _, err := range errs {
+		state.Errors = append(state.Errors, err.Error())
+	}
+	return state
+}
+
+func collectDataplane() (*Dataplane, []error) {
+	var (
+		dataplane = &Dataplane{}
+		errs      []error
+	)
+
+	linkList, err := netlink.LinkList()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to list links: %v", err))
+	}
+	linkNames := map[int]string{}
+	for _, link := range linkList {
+		linkNames[link.Attrs().Index] = link.Attrs().Name
+	}
+	for _, link := range linkList {
+		dataplane.Links = append(dataplane.Links, formatLink(link, linkNames))
+	}
+
+	for _, family := range []int{netlink.FAMILY_V4, netlink.FAMILY_V6} {
+		addrList, err := netlink.AddrList(nil, family)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list addresses of family %v: %v", family, err))
+		}
+		for _, addr := range addrList {
+			dataplane.Addresses = append(dataplane.Addresses,
+				fmt.Sprintf("%v dev %v", addr.String(), linkNames[addr.LinkIndex]))
+		}
+
+		// routes of all tables, including the ones of vrf devices
+		routeList, err := netlink.RouteListFiltered(family, &netlink.Route{Table: syscall.RT_TABLE_UNSPEC},
+			netlink.RT_FILTER_TABLE)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list routes of family %v: %v", family, err))
+		}
+		for _, route := range routeList {
+			dataplane.Routes = append(dataplane.Routes,
+				fmt.Sprintf("%v dev %v table %v", route.String(), linkNames[route.LinkIndex], route.Table))
+		}
+
+		ruleList, err := netlink.RuleList(family)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list rules of family %v: %v", family, err))
+		}
+		for _, rule := range ruleList {
+			dataplane.Rules = append(dataplane.Rules, rule.String())
+		}
+
+		neighList, err := netlink.NeighList(0, family)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list neighs of family %v: %v", family, err))
+		}
+		for _, neigh := range neighList {
+			dataplane.Neighs = append(dataplane.Neighs, formatNeigh(neigh, linkNames))
+		}
+	}
+
+	fdbList, err := netlink.NeighList(0, syscall.AF_BRIDGE)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to list fdb entries: %v", err))
+	}
+	for _, fdb := range fdbList {
+		dataplane.FDB = append(dataplane.FDB, formatNeigh(fdb, linkNames))
+	}
+
+	return dataplane, errs
+}
+
+func formatLink(link netlink.Link, linkNames map[int]string) string {
+	attrs := link.Attrs()
+	formatted := fmt.Sprintf("%d: %v type %v mtu %d state %v flags %v", attrs.Index, attrs.Name, link.Type(),
+		attrs.MTU, attrs.OperState, attrs.Flags)
+	if attrs.MasterIndex != 0 {
+		formatted += fmt.Sprintf(" master %v", linkNames[attrs.MasterIndex])
+	}
+	if attrs.HardwareAddr != nil {
+		formatted += fmt.Sprintf(" lladdr %v", attrs.HardwareAddr)
+	}
+	return formatted
+}
+
+func formatNeigh(neigh netlink.Neigh, linkNames map[int]string) string {
+	return fmt.Sprintf("%v dev %v lladdr %v state %#x flags %#x", neigh.IP, linkNames[neigh.LinkIndex],
+		neigh.HardwareAddr, neigh.State, neigh.Flags)
+}
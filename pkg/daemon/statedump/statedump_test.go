@@ -0,0 +1,55 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package statedump
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alibaba/hybridnet/pkg/daemon/config"
+)
+
+func TestHandler(t *testing.T) {
+	handler := Handler(&config.Configuration{
+		NodeName:     "node1",
+		DryDataplane: true,
+		VxlanUDPPort: 8472,
+	})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, Path, nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("unexpected status code %v", recorder.Code)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("unexpected content type %v", contentType)
+	}
+
+	state := &State{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), state); err != nil {
+		t.Fatalf("failed to decode state: %v", err)
+	}
+	if state.NodeName != "node1" || state.Config == nil || state.Config.VxlanUDPPort != 8472 {
+		t.Errorf("unexpected state %+v", state)
+	}
+	if state.Dataplane != nil || len(state.Errors) != 0 {
+		t.Errorf("dataplane of dry dataplane daemon should not be collected, got %+v", state)
+	}
+}
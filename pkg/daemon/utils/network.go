@@ -22,7 +22,10 @@ import (
 	"net"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
 	"github.com/alibaba/hybridnet/pkg/constants"
 
 	"github.com/containernetworking/cni/pkg/types/current"
@@ -31,6 +34,7 @@ import (
 	"golang.org/x/sys/unix"
 
 	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
 )
 
 type IPInfo struct {
@@ -38,6 +42,23 @@ type IPInfo struct {
 	Gw    net.IP
 	Cidr  *net.IPNet
 	NetID *int32
+
+	// NodeIfName is the node interface specified by subnet of ip, empty if not specified
+	NodeIfName string
+
+	// VRFName and VRFTable are the vrf device and its route table of the tenant network of ip,
+	// empty if ip belongs to no tenant
+	VRFName  string
+	VRFTable int
+
+	// AnnounceRetries and AnnounceInterval are how many times the announcement of ip is resent and
+	// the wait before the first retry, zero if address announcement is not specified by subnet of ip
+	AnnounceRetries  int
+	AnnounceInterval time.Duration
+
+	// Adopted means ip was configured out-of-band before its ip instance was created, the existing
+	// owner of ip with the same MAC is taken over instead of being a conflict
+	Adopted bool
 }
 
 func GenerateVlanNetIfName(parentName string, vlanID *int32) (string, error) {
@@ -121,7 +142,7 @@ func GetDefaultInterface(family int) (*net.Interface, error) {
 	}
 
 	if defaultRoute.LinkIndex <= 0 {
-		return nil, errors.New("found ipv4 default route but could not determine interface")
+		return nil, errors.New("found default route but could not determine interface")
 	}
 
 	iface, err := net.InterfaceByIndex(defaultRoute.LinkIndex)
@@ -222,6 +243,19 @@ func ListGlobalUnicastAddress(link netlink.Link, family int) ([]netlink.Addr, er
 	return result, nil
 }
 
+// SelectVtepAddress picks the first address matching vtep address cidrs, ipv4 addresses are
+// expected to be in front of ipv6 ones, so that ipv6 vtep is only used on ipv6-only nodes
+func SelectVtepAddress(addrList []netlink.Addr, vtepAddressCIDRs []*net.IPNet) net.IP {
+	for _, addr := range addrList {
+		for _, cidr := range vtepAddressCIDRs {
+			if cidr.Contains(addr.IP) {
+				return addr.IP
+			}
+		}
+	}
+	return nil
+}
+
 func CheckIPIsGlobalUnicast(ip net.IP) bool {
 	return !ip.IsInterfaceLocalMulticast() && ip.IsGlobalUnicast()
 }
@@ -606,7 +640,9 @@ func ListLocalAddressExceptLink(exceptLinkName string) ([]netlink.Addr, error) {
 
 	for _, link := range linkList {
 		linkName := link.Attrs().Name
-		if linkName != exceptLinkName && !CheckIfContainerNetworkLink(linkName) {
+		// addresses of tenant vrf devices are copied from host links
+		if linkName != exceptLinkName && !CheckIfContainerNetworkLink(linkName) &&
+			!strings.HasPrefix(linkName, constants.TenantVRFLinkPrefix) {
 
 			linkAddrList, err := ListAllGlobalUnicastAddress(link)
 			if err != nil {
@@ -620,6 +656,61 @@ func ListLocalAddressExceptLink(exceptLinkName string) ([]netlink.Addr, error) {
 	return addrList, nil
 }
 
+// rpFilterOverrides are the rp_filter values of interfaces specified by networks, which are kept by
+// EnsureRpFilter rather than being disabled
+var rpFilterOverrides = struct {
+	sync.RWMutex
+	values map[string]int
+}{values: map[string]int{}}
+
+// RpFilterValueOf returns the rp_filter sysctl value of a reverse path filtering mode
+func RpFilterValueOf(mode networkingv1.RPFilterMode) int {
+	switch mode {
+	case networkingv1.RPFilterStrict:
+		return 1
+	case networkingv1.RPFilterLoose:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// SetRpFilterOverride sets the rp_filter value of an interface which will be kept by EnsureRpFilter
+func SetRpFilterOverride(ifName string, value int) {
+	rpFilterOverrides.Lock()
+	defer rpFilterOverrides.Unlock()
+
+	if value == 0 {
+		delete(rpFilterOverrides.values, ifName)
+		return
+	}
+	rpFilterOverrides.values[ifName] = value
+}
+
+// ResetRpFilterOverrides replaces all the rp_filter values of interfaces kept by EnsureRpFilter,
+// interfaces not in values will be disabled by the next EnsureRpFilter
+func ResetRpFilterOverrides(values map[string]int) {
+	rpFilterOverrides.Lock()
+	defer rpFilterOverrides.Unlock()
+
+	rpFilterOverrides.values = map[string]int{}
+	for ifName, value := range values {
+		if value != 0 {
+			rpFilterOverrides.values[ifName] = value
+		}
+	}
+}
+
+func getRpFilterOverride(ifName string) (int, bool) {
+	rpFilterOverrides.RLock()
+	defer rpFilterOverrides.RUnlock()
+
+	value, exist := rpFilterOverrides.values[ifName]
+	return value, exist
+}
+
+// EnsureRpFilter disables rp_filter of "default", "all", containerIfs and all the existing non-container
+// interfaces, except the ones with values specified by networks, which are set to the specified values
 func EnsureRpFilter(containerIfs ...string) error {
 	ifArray := append([]string{"default", "all"}, containerIfs...)
 
@@ -629,24 +720,46 @@ func EnsureRpFilter(containerIfs ...string) error {
 	}
 
 	for _, existIf := range existInterfaces {
-		if CheckIfContainerNetworkLink(existIf.Name) {
+		// container interfaces are only ensured if specified or overridden
+		if _, overridden := getRpFilterOverride(existIf.Name); CheckIfContainerNetworkLink(existIf.Name) && !overridden {
 			continue
 		}
 		ifArray = append(ifArray, existIf.Name)
 	}
 
 	for _, key := range ifArray {
+		expectValue, _ := getRpFilterOverride(key)
+
 		sysctlPath := fmt.Sprintf(constants.RpFilterSysctl, key)
 		sysctlValue, err := GetSysctl(sysctlPath)
 		if err != nil {
 			return fmt.Errorf("error get: %s sysctl path: %v", sysctlPath, err)
 		}
-		if sysctlValue != 0 {
-			if err = SetSysctl(sysctlPath, 0); err != nil {
-				return fmt.Errorf("failed to set %s sysctl path to 0, error: %v", sysctlPath, err)
+		if sysctlValue != expectValue {
+			if err = SetSysctl(sysctlPath, expectValue); err != nil {
+				return fmt.Errorf("failed to set %s sysctl path to %d, error: %v", sysctlPath, expectValue, err)
 			}
 		}
 	}
 
 	return nil
 }
+
+// SetIPv6Token sets the interface identifier used by SLAAC of link, which works the same as "ip token set",
+// and requires accept_ra of link to be enabled
+func SetIPv6Token(link netlink.Link, token net.IP) error {
+	req := nl.NewNetlinkRequest(unix.RTM_SETLINK, unix.NLM_F_ACK)
+
+	msg := nl.NewIfInfomsg(unix.AF_INET6)
+	msg.Index = int32(link.Attrs().Index)
+	req.AddData(msg)
+
+	afSpec := nl.NewRtAttr(unix.IFLA_AF_SPEC, nil)
+	afSpec.AddRtAttr(unix.AF_INET6, nil).AddRtAttr(unix.IFLA_INET6_TOKEN, token.To16())
+	req.AddData(afSpec)
+
+	if _, err := req.Execute(unix.NETLINK_ROUTE, 0); err != nil {
+		return fmt.Errorf("failed to set ipv6 token %v for link %v: %v", token, link.Attrs().Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vrf
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/alibaba/hybridnet/pkg/constants"
+	daemonutils "github.com/alibaba/hybridnet/pkg/daemon/utils"
+)
+
+const (
+	// tableBase keeps vrf tables away from the ones used by route managers, the table of
+	// a tenant vrf is tableBase plus the net id (vni) of tenant network.
+	tableBase = 1 << 28
+
+	// unreachableRouteMetric is the highest metric of routes, the unreachable default route
+	// of a vrf table keeps lookups of tenant traffic from falling through to other tables.
+	unreachableRouteMetric = 4278198272
+)
+
+// GenerateVRFName returns the name of the vrf device for a tenant network.
+func GenerateVRFName(netID int32) string {
+	return fmt.Sprintf("%s%d", constants.TenantVRFLinkPrefix, netID)
+}
+
+// TableOf returns the route table of the vrf device for a tenant network.
+func TableOf(netID int32) int {
+	return tableBase + int(netID)
+}
+
+// EnsureVRF ensures the vrf device of a tenant network exists and is up.
+func EnsureVRF(netID int32) (*netlink.Vrf, error) {
+	name := GenerateVRFName(netID)
+	table := uint32(TableOf(netID))
+
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		if _, ok := err.(netlink.LinkNotFoundError); !ok {
+			return nil, fmt.Errorf("failed to get vrf device %v: %v", name, err)
+		}
+	} else if existVRF, ok := link.(*netlink.Vrf); !ok || existVRF.Table != table {
+		if err := netlink.LinkDel(link); err != nil {
+			return nil, fmt.Errorf("failed to delete incompatible vrf device %v: %v", name, err)
+		}
+		link = nil
+	}
+
+	if link == nil {
+		if err := netlink.LinkAdd(&netlink.Vrf{
+			LinkAttrs: netlink.LinkAttrs{Name: name},
+			Table:     table,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to add vrf device %v: %v", name, err)
+		}
+
+		if link, err = netlink.LinkByName(name); err != nil {
+			return nil, fmt.Errorf("failed to get vrf device %v: %v", name, err)
+		}
+	}
+
+	if err := netlink.LinkSetUp(link); err != nil {
+		return nil, fmt.Errorf("failed to set vrf device %v up: %v", name, err)
+	}
+
+	if err := ensureUnreachableDefaultRoute(int(table), netlink.FAMILY_V4); err != nil {
+		return nil, err
+	}
+
+	ipv6Disabled, err := daemonutils.CheckIPv6Disabled(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check ipv6 disables for link %v: %v", name, err)
+	}
+
+	if !ipv6Disabled {
+		if err := ensureUnreachableDefaultRoute(int(table), netlink.FAMILY_V6); err != nil {
+			return nil, err
+		}
+	}
+
+	return link.(*netlink.Vrf), nil
+}
+
+// EnslaveLink puts a link into the vrf device.
+func EnslaveLink(link netlink.Link, vrf *netlink.Vrf) error {
+	if link.Attrs().MasterIndex == vrf.Index {
+		return nil
+	}
+
+	if err := netlink.LinkSetMasterByIndex(link, vrf.Index); err != nil {
+		return fmt.Errorf("failed to set master of link %v to vrf device %v: %v", link.Attrs().Name, vrf.Name, err)
+	}
+	return nil
+}
+
+// SyncSubnetRoutes ensures the routes of subnet cidrs through the vxlan device in the vrf table,
+// and removes the stale ones. Local pod routes created by cni are left untouched.
+func SyncSubnetRoutes(vrf *netlink.Vrf, vxlanLink netlink.Link, cidrs []*net.IPNet) error {
+	cidrMap := map[string]*net.IPNet{}
+	for _, cidr := range cidrs {
+		cidrMap[cidr.String()] = cidr
+	}
+
+	for _, family := range []int{netlink.FAMILY_V4, netlink.FAMILY_V6} {
+		routeList, err := netlink.RouteListFiltered(family, &netlink.Route{
+			Table:     int(vrf.Table),
+			LinkIndex: vxlanLink.Attrs().Index,
+		}, netlink.RT_FILTER_TABLE|netlink.RT_FILTER_OIF)
+		if err != nil {
+			return fmt.Errorf("failed to list route for table %v: %v", vrf.Table, err)
+		}
+
+		for _, route := range routeList {
+			if route.Type != unix.RTN_UNICAST || route.Dst == nil {
+				continue
+			}
+
+			if _, exist := cidrMap[route.Dst.String()]; !exist {
+				if err := netlink.RouteDel(&route); err != nil {
+					return fmt.Errorf("failed to delete route %v for table %v: %v", route.String(), vrf.Table, err)
+				}
+			}
+		}
+	}
+
+	for _, cidr := range cidrs {
+		route := &netlink.Route{
+			LinkIndex: vxlanLink.Attrs().Index,
+			Dst:       cidr,
+			Table:     int(vrf.Table),
+			Scope:     netlink.SCOPE_UNIVERSE,
+		}
+
+		if err := netlink.RouteReplace(route); err != nil {
+			return fmt.Errorf("failed to add route %v for table %v: %v", route.String(), vrf.Table, err)
+		}
+	}
+
+	return nil
+}
+
+// CleanVRFs deletes the tenant vrf devices which are not expected, with their vxlan slaves.
+func CleanVRFs(expectedVRFNames map[string]bool) error {
+	linkList, err := netlink.LinkList()
+	if err != nil {
+		return fmt.Errorf("failed to list links: %v", err)
+	}
+
+	for _, link := range linkList {
+		vrf, ok := link.(*netlink.Vrf)
+		if !ok || !strings.HasPrefix(vrf.Name, constants.TenantVRFLinkPrefix) || expectedVRFNames[vrf.Name] {
+			continue
+		}
+
+		for _, slave := range linkList {
+			if _, isVxlan := slave.(*netlink.Vxlan); isVxlan && slave.Attrs().MasterIndex == vrf.Index {
+				if err := netlink.LinkDel(slave); err != nil {
+					return fmt.Errorf("failed to delete vxlan device %v of vrf device %v: %v",
+						slave.Attrs().Name, vrf.Name, err)
+				}
+			}
+		}
+
+		if err := netlink.LinkDel(vrf); err != nil {
+			return fmt.Errorf("failed to delete vrf device %v: %v", vrf.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func ensureUnreachableDefaultRoute(table, family int) error {
+	defaultDst := &net.IPNet{IP: net.IPv4zero, Mask: net.CIDRMask(0, 32)}
+	if family == netlink.FAMILY_V6 {
+		defaultDst = &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)}
+	}
+
+	route := &netlink.Route{
+		Dst:      defaultDst,
+		Table:    table,
+		Type:     unix.RTN_UNREACHABLE,
+		Priority: unreachableRouteMetric,
+	}
+
+	if err := netlink.RouteReplace(route); err != nil {
+		return fmt.Errorf("failed to add unreachable default route for table %v: %v", table, err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vrf
+
+import (
+	"testing"
+)
+
+func TestGenerateVRFNameAndTable(t *testing.T) {
+	tests := []struct {
+		name        string
+		netID       int32
+		expectName  string
+		expectTable int
+	}{
+		{
+			name:        "small net id",
+			netID:       4,
+			expectName:  "hvrf4",
+			expectTable: tableBase + 4,
+		},
+		{
+			name:        "max vni",
+			netID:       1<<24 - 1,
+			expectName:  "hvrf16777215",
+			expectTable: tableBase + 1<<24 - 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			name := GenerateVRFName(test.netID)
+			if name != test.expectName {
+				t.Errorf("test %s fail, expect name %s but got %s", test.name, test.expectName, name)
+			}
+			// interface names are limited to 15 characters
+			if len(name) > 15 {
+				t.Errorf("test %s fail, name %s is too long", test.name, name)
+			}
+			if table := TableOf(test.netID); table != test.expectTable {
+				t.Errorf("test %s fail, expect table %d but got %d", test.name, test.expectTable, table)
+			}
+		})
+	}
+}
@@ -45,6 +45,35 @@ const (
 	MultiCluster featuregate.Feature = "MultiCluster"
 
 	VMIPRetain featuregate.Feature = "VMIPRetain"
+
+	// Publish available underlay addresses of each node as extended resources, and
+	// make underlay pods request them, so that pods will not be scheduled to nodes
+	// whose underlay network is exhausted.
+	AddressExtendedResource featuregate.Feature = "AddressExtendedResource"
+
+	// Allocate and reserve addresses for the pods of scaled-up StatefulSets before the
+	// pods are created, so that pod creation only picks up the reserved addresses.
+	StatefulSetIPPreAllocation featuregate.Feature = "StatefulSetIPPreAllocation"
+
+	// Release ip instances of stopped pods on cordoned nodes without waiting for the pods
+	// to be removed, so that draining a node frees its addresses as soon as possible.
+	NodeDrainIPRelease featuregate.Feature = "NodeDrainIPRelease"
+
+	// Restrict TLS and other cryptography to FIPS-approved algorithms, which is always
+	// enabled for binaries built with boringcrypto.
+	FIPSMode featuregate.Feature = "FIPSMode"
+
+	// Bound IPInstances carry a lease expire time which is renewed by the daemon of node while
+	// their pods exist, IPInstances of unrenewed leases whose pods are gone are reclaimed.
+	IPInstanceLease featuregate.Feature = "IPInstanceLease"
+
+	// Allow overlay networks to belong to tenants, whose subnets may overlap with the ones
+	// of other tenants and are isolated by per-tenant VRFs and VNIs on nodes.
+	TenantNetwork featuregate.Feature = "TenantNetwork"
+
+	// Inject faults described by ChaosRules into the dataplane of nodes for resilience testing,
+	// it should never be enabled in production clusters.
+	DataplaneChaos featuregate.Feature = "DataplaneChaos"
 )
 
 var DefaultHybridnetFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
@@ -56,14 +85,70 @@ var DefaultHybridnetFeatureGates = map[featuregate.Feature]featuregate.FeatureSp
 		Default:    false,
 		PreRelease: featuregate.Alpha,
 	},
+	AddressExtendedResource: {
+		Default:    false,
+		PreRelease: featuregate.Alpha,
+	},
+	StatefulSetIPPreAllocation: {
+		Default:    false,
+		PreRelease: featuregate.Alpha,
+	},
+	NodeDrainIPRelease: {
+		Default:    false,
+		PreRelease: featuregate.Alpha,
+	},
+	FIPSMode: {
+		Default:    false,
+		PreRelease: featuregate.Alpha,
+	},
+	IPInstanceLease: {
+		Default:    false,
+		PreRelease: featuregate.Alpha,
+	},
+	TenantNetwork: {
+		Default:    false,
+		PreRelease: featuregate.Alpha,
+	},
+	DataplaneChaos: {
+		Default:    false,
+		PreRelease: featuregate.Alpha,
+	},
 }
 
 func MultiClusterEnabled() bool {
-	return feature.DefaultMutableFeatureGate.Enabled(MultiCluster)
+	return enabled(MultiCluster)
 }
 
 func VMIPRetainEnabled() bool {
-	return feature.DefaultMutableFeatureGate.Enabled(VMIPRetain)
+	return enabled(VMIPRetain)
+}
+
+func AddressExtendedResourceEnabled() bool {
+	return enabled(AddressExtendedResource)
+}
+
+func StatefulSetIPPreAllocationEnabled() bool {
+	return enabled(StatefulSetIPPreAllocation)
+}
+
+func NodeDrainIPReleaseEnabled() bool {
+	return enabled(NodeDrainIPRelease)
+}
+
+func FIPSModeEnabled() bool {
+	return enabled(FIPSMode)
+}
+
+func IPInstanceLeaseEnabled() bool {
+	return enabled(IPInstanceLease)
+}
+
+func TenantNetworkEnabled() bool {
+	return enabled(TenantNetwork)
+}
+
+func DataplaneChaosEnabled() bool {
+	return enabled(DataplaneChaos)
 }
 
 func KnownFeatures() []string {
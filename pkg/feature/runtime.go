@@ -0,0 +1,102 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package feature
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/component-base/featuregate"
+)
+
+// RuntimeToggleableFeatures are the features which can be flipped at runtime by the
+// ConfigMap of manager without restarting components. The ConfigMap is only watched by
+// manager and webhook, daemons read feature gates from flags or config file on startup,
+// so MultiCluster flipped at runtime does not change daemons until they restart.
+//
+// Other features need restarts of the components using them after flags are changed:
+//   - StatefulSetIPPreAllocation: manager, whose controller is registered on startup
+//   - FIPSMode: all components, whose cryptography is set up on startup
+//   - IPInstanceLease: manager and daemon
+//   - TenantNetwork, DataplaneChaos: daemon and webhook, daemon builds its dataplane on startup
+var RuntimeToggleableFeatures = map[featuregate.Feature]bool{
+	MultiCluster:            true,
+	VMIPRetain:              true,
+	AddressExtendedResource: true,
+	NodeDrainIPRelease:      true,
+}
+
+// runtimeOverrides stores a map[featuregate.Feature]bool which takes precedence
+// over the values of flags
+var runtimeOverrides atomic.Value
+
+func enabled(f featuregate.Feature) bool {
+	if overrides, ok := runtimeOverrides.Load().(map[featuregate.Feature]bool); ok {
+		if value, exist := overrides[f]; exist {
+			return value
+		}
+	}
+	return feature.DefaultMutableFeatureGate.Enabled(f)
+}
+
+// ParseRuntimeFeatureGates parses a string in the same format of --feature-gates
+// flag, e.g. "MultiCluster=true,VMIPRetain=false", and only accepts features
+// which are runtime toggleable
+func ParseRuntimeFeatureGates(value string) (map[featuregate.Feature]bool, error) {
+	var gates = map[featuregate.Feature]bool{}
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+
+		pair := strings.SplitN(item, "=", 2)
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("missing bool value for %s", item)
+		}
+
+		name := featuregate.Feature(strings.TrimSpace(pair[0]))
+		if !RuntimeToggleableFeatures[name] {
+			return nil, fmt.Errorf("feature %s is unknown or can not be toggled at runtime, "+
+				"which must be set by --feature-gates and takes effect after restart", name)
+		}
+
+		enable, err := strconv.ParseBool(strings.TrimSpace(pair[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of %s=%s: %v", name, pair[1], err)
+		}
+		gates[name] = enable
+	}
+
+	return gates, nil
+}
+
+// SetRuntimeFeatureGates replaces all the runtime overrides, features absent
+// from value fall back to the values of flags
+func SetRuntimeFeatureGates(value string) error {
+	gates, err := ParseRuntimeFeatureGates(value)
+	if err != nil {
+		return err
+	}
+
+	runtimeOverrides.Store(gates)
+	return nil
+}
@@ -0,0 +1,56 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package feature
+
+import "testing"
+
+func TestSetRuntimeFeatureGates(t *testing.T) {
+	defer func() {
+		_ = SetRuntimeFeatureGates("")
+	}()
+
+	if VMIPRetainEnabled() {
+		t.Fatalf("VMIPRetain is expected to be disabled by default")
+	}
+
+	if err := SetRuntimeFeatureGates("VMIPRetain=true, AddressExtendedResource=false"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !VMIPRetainEnabled() {
+		t.Errorf("VMIPRetain is expected to be enabled at runtime")
+	}
+
+	if err := SetRuntimeFeatureGates("VMIPRetain"); err == nil {
+		t.Errorf("expect error for missing value")
+	}
+	if err := SetRuntimeFeatureGates("Unknown=true"); err == nil {
+		t.Errorf("expect error for unknown feature")
+	}
+	if err := SetRuntimeFeatureGates("TenantNetwork=true"); err == nil {
+		t.Errorf("expect error for feature requiring restart")
+	}
+	if !VMIPRetainEnabled() {
+		t.Errorf("invalid feature gates should not change current overrides")
+	}
+
+	if err := SetRuntimeFeatureGates(""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if VMIPRetainEnabled() {
+		t.Errorf("VMIPRetain is expected to fall back to flag value")
+	}
+}
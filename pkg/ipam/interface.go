@@ -30,10 +30,13 @@ type Manager interface {
 	GetNetworkUsage(networkName string) (*types.NetworkUsage, error)
 	GetSubnetUsage(networkName, subnetName string) (*types.Usage, error)
 
+	ElectDefaultNetwork(networkType types.NetworkType, podInfo types.PodInfo, eligible func(networkName string) bool) string
+
 	Allocate(networkName string, podInfo types.PodInfo, options ...types.AllocateOption) (allocatedIPs []*types.IP, err error)
 	Assign(networkName string, podInfo types.PodInfo, assignedSuites []types.SubnetIPSuite, options ...types.AssignOption) (assignedIPs []*types.IP, err error)
 	Release(networkName string, releaseSuites []types.SubnetIPSuite) (err error)
 	Reserve(networkName string, reserveSuites []types.SubnetIPSuite) (err error)
+	Track(networkName string, ip *types.IP) (err error)
 }
 
 type Store interface {
@@ -73,6 +73,13 @@ func (m *Manager) Refresh(opts ...types.RefreshOption) error {
 	}
 
 	for _, networkName := range toRefreshNetworkNames {
+		if !options.ForceAll && len(options.Subnets) > 0 {
+			if err := m.refreshSubnets(networkName, options.Subnets); err != nil {
+				return err
+			}
+			continue
+		}
+
 		if err := m.refreshNetwork(networkName); err != nil {
 			return err
 		}
@@ -92,13 +99,13 @@ func (m *Manager) GetNetworkUsage(networkName string) (*types.NetworkUsage, erro
 	}
 
 	if err := errors.AggregateGoroutines(validateFunctions...); err != nil {
-		return nil, fmt.Errorf("validation fail: %v", err)
+		return nil, types.NewAllocationError(types.FailureInvalidRequest, "validation fail: %v", err)
 	}
 
 	var network *types.Network
 	var err error
 	if network, err = m.NetworkSet.GetNetworkByName(networkName); err != nil {
-		return nil, fmt.Errorf("fail to get network %s: %v", networkName, err)
+		return nil, fmt.Errorf("fail to get network %s: %w", networkName, err)
 	}
 	return network.Usage(), nil
 }
@@ -114,23 +121,32 @@ func (m *Manager) GetSubnetUsage(networkName, subnetName string) (*types.Usage,
 	}
 
 	if err := errors.AggregateGoroutines(validateFunctions...); err != nil {
-		return nil, fmt.Errorf("validation fail: %v", err)
+		return nil, types.NewAllocationError(types.FailureInvalidRequest, "validation fail: %v", err)
 	}
 
 	var network *types.Network
 	var err error
 	if network, err = m.NetworkSet.GetNetworkByName(networkName); err != nil {
-		return nil, fmt.Errorf("fail to get network %s: %v", networkName, err)
+		return nil, fmt.Errorf("fail to get network %s: %w", networkName, err)
 	}
 
 	var subnet *types.Subnet
 	if subnet, err = network.GetSubnetByName(subnetName); err != nil {
-		return nil, fmt.Errorf("fail to get subnet %s: %v", subnetName, err)
+		return nil, fmt.Errorf("fail to get subnet %s: %w", subnetName, err)
 	}
 
 	return subnet.Usage(), nil
 }
 
+// ElectDefaultNetwork will return the network elected by default policies for a pod which specifies no network,
+// empty means no eligible network of the type has a default policy
+func (m *Manager) ElectDefaultNetwork(networkType types.NetworkType, podInfo types.PodInfo, eligible func(networkName string) bool) string {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.NetworkSet.ElectDefaultNetwork(networkType, podInfo.String(), eligible)
+}
+
 // Allocate will allocate some new IP for a specified pod
 func (m *Manager) Allocate(networkName string, podInfo types.PodInfo, opts ...types.AllocateOption) (allocatedIPs []*types.IP, err error) {
 	m.Lock()
@@ -147,7 +163,13 @@ func (m *Manager) Allocate(networkName string, podInfo types.PodInfo, opts ...ty
 	}
 
 	if err = errors.AggregateGoroutines(validateFunctions...); err != nil {
-		return nil, fmt.Errorf("validation fail: %v", err)
+		return nil, types.NewAllocationError(types.FailureInvalidRequest, "validation fail: %v", err)
+	}
+
+	// assignment of retained or specified addresses is still permitted on cordoned networks,
+	// only new allocations are blocked
+	if network, err := m.NetworkSet.GetNetworkByName(networkName); err == nil && network.Cordoned {
+		return nil, fmt.Errorf("fail to allocate from network %s: %w", networkName, types.ErrCordonedNetwork)
 	}
 
 	switch podInfo.IPFamily {
@@ -158,14 +180,14 @@ func (m *Manager) Allocate(networkName string, podInfo types.PodInfo, opts ...ty
 	case types.DualStack:
 		return m.allocateDualStack(networkName, podInfo, *options)
 	default:
-		return nil, fmt.Errorf("unsupported ip family %s", podInfo.IPFamily)
+		return nil, types.NewAllocationError(types.FailureInvalidRequest, "unsupported ip family %s", podInfo.IPFamily)
 	}
 }
 
 func (m *Manager) allocateIPv4(networkName string, podInfo types.PodInfo, options types.AllocateOptions) (IPs []*types.IP, err error) {
 	var network *types.Network
 	if network, err = m.NetworkSet.GetNetworkByName(networkName); err != nil {
-		return nil, fmt.Errorf("fail to get network %s: %v", networkName, err)
+		return nil, fmt.Errorf("fail to get network %s: %w", networkName, err)
 	}
 
 	var specifiedSubnetName string
@@ -176,18 +198,19 @@ func (m *Manager) allocateIPv4(networkName string, podInfo types.PodInfo, option
 	case 1:
 		specifiedSubnetName = options.Subnets[0]
 	default:
-		return nil, fmt.Errorf("only support one specified subnet when IPv4 family, but %v", options.Subnets)
+		return nil, types.NewAllocationError(types.FailureInvalidRequest, "only support one specified subnet when IPv4 family, but %v", options.Subnets)
 	}
 
 	var subnet *types.Subnet
-	if subnet, err = network.GetIPv4SubnetByNameOrAvailable(specifiedSubnetName); err != nil {
-		return nil, fmt.Errorf("fail to get ipv4 subnet: %v", err)
+	if subnet, err = network.GetIPv4SubnetByNameOrTopology(specifiedSubnetName, options.NodeLabels); err != nil {
+		return nil, fmt.Errorf("fail to get ipv4 subnet: %w", err)
 	}
 
 	var ip *types.IP
 	if ip = subnet.AllocateNext(podInfo.Name, podInfo.Namespace); ip == nil {
-		return nil, fmt.Errorf("fail to get one available ipv4 address from subnet %s", subnet.Name)
+		return nil, fmt.Errorf("fail to get one available ipv4 address from subnet %s: %w", subnet.Name, types.ErrSubnetExhausted)
 	}
+	recordTopology(ip, subnet, specifiedSubnetName, options.NodeLabels)
 
 	IPs = append(IPs, ip)
 	return
@@ -196,7 +219,7 @@ func (m *Manager) allocateIPv4(networkName string, podInfo types.PodInfo, option
 func (m *Manager) allocateIPv6(networkName string, podInfo types.PodInfo, options types.AllocateOptions) (IPs []*types.IP, err error) {
 	var network *types.Network
 	if network, err = m.NetworkSet.GetNetworkByName(networkName); err != nil {
-		return nil, fmt.Errorf("fail to get network %s: %v", networkName, err)
+		return nil, fmt.Errorf("fail to get network %s: %w", networkName, err)
 	}
 
 	var specifiedSubnetName string
@@ -207,18 +230,19 @@ func (m *Manager) allocateIPv6(networkName string, podInfo types.PodInfo, option
 	case 1:
 		specifiedSubnetName = options.Subnets[0]
 	default:
-		return nil, fmt.Errorf("only support one specified subnet when IPv6 family, but %v", options.Subnets)
+		return nil, types.NewAllocationError(types.FailureInvalidRequest, "only support one specified subnet when IPv6 family, but %v", options.Subnets)
 	}
 
 	var subnet *types.Subnet
-	if subnet, err = network.GetIPv6SubnetByNameOrAvailable(specifiedSubnetName); err != nil {
-		return nil, fmt.Errorf("fail to get ipv6 subnet: %v", err)
+	if subnet, err = network.GetIPv6SubnetByNameOrTopology(specifiedSubnetName, options.NodeLabels); err != nil {
+		return nil, fmt.Errorf("fail to get ipv6 subnet: %w", err)
 	}
 
 	var ip *types.IP
 	if ip = subnet.AllocateNext(podInfo.Name, podInfo.Namespace); ip == nil {
-		return nil, fmt.Errorf("fail to get one available ipv6 address from subnet %s", subnet.Name)
+		return nil, fmt.Errorf("fail to get one available ipv6 address from subnet %s: %w", subnet.Name, types.ErrSubnetExhausted)
 	}
+	recordTopology(ip, subnet, specifiedSubnetName, options.NodeLabels)
 
 	IPs = append(IPs, ip)
 	return
@@ -227,7 +251,7 @@ func (m *Manager) allocateIPv6(networkName string, podInfo types.PodInfo, option
 func (m *Manager) allocateDualStack(networkName string, podInfo types.PodInfo, options types.AllocateOptions) (IPs []*types.IP, err error) {
 	var network *types.Network
 	if network, err = m.NetworkSet.GetNetworkByName(networkName); err != nil {
-		return nil, fmt.Errorf("fail to get network %s: %v", networkName, err)
+		return nil, fmt.Errorf("fail to get network %s: %w", networkName, err)
 	}
 
 	var specifiedIPv4SubnetName, specifiedIPv6SubnetName string
@@ -238,28 +262,40 @@ func (m *Manager) allocateDualStack(networkName string, podInfo types.PodInfo, o
 	case 2:
 		specifiedIPv4SubnetName, specifiedIPv6SubnetName = options.Subnets[0], options.Subnets[1]
 	default:
-		return nil, fmt.Errorf("only support two assigned subnets when DualStack family, but %v", options.Subnets)
+		return nil, types.NewAllocationError(types.FailureInvalidRequest, "only support two assigned subnets when DualStack family, but %v", options.Subnets)
 	}
 
 	var ipv4Subnet, ipv6Subnet *types.Subnet
-	if ipv4Subnet, ipv6Subnet, err = network.GetDualStackSubnetsByNameOrAvailable(specifiedIPv4SubnetName, specifiedIPv6SubnetName); err != nil {
-		return nil, fmt.Errorf("fail to get paired subnets: %v", err)
+	if ipv4Subnet, err = network.GetIPv4SubnetByNameOrTopology(specifiedIPv4SubnetName, options.NodeLabels); err != nil {
+		return nil, fmt.Errorf("fail to get paired subnets: %w", err)
+	}
+	if ipv6Subnet, err = network.GetIPv6SubnetByNameOrTopology(specifiedIPv6SubnetName, options.NodeLabels); err != nil {
+		return nil, fmt.Errorf("fail to get paired subnets: %w", err)
 	}
 
 	var ipv4IP, ipv6IP *types.IP
 	if ipv4IP = ipv4Subnet.AllocateNext(podInfo.Name, podInfo.Namespace); ipv4IP == nil {
-		return nil, fmt.Errorf("fail to get ipv4 address from subnet %s", ipv4Subnet.Name)
+		return nil, fmt.Errorf("fail to get ipv4 address from subnet %s: %w", ipv4Subnet.Name, types.ErrSubnetExhausted)
 	}
 	if ipv6IP = ipv6Subnet.AllocateNext(podInfo.Name, podInfo.Namespace); ipv6IP == nil {
 		// recycle IPv4 address if IPv6 allocation fails
 		ipv4Subnet.Release(ipv4IP.Address.IP.String())
-		return nil, fmt.Errorf("fail to get ipv6 address zfrom subnet %s", ipv6Subnet.Name)
+		return nil, fmt.Errorf("fail to get ipv6 address from subnet %s: %w", ipv6Subnet.Name, types.ErrSubnetExhausted)
 	}
+	recordTopology(ipv4IP, ipv4Subnet, specifiedIPv4SubnetName, options.NodeLabels)
+	recordTopology(ipv6IP, ipv6Subnet, specifiedIPv6SubnetName, options.NodeLabels)
 
 	IPs = append(IPs, ipv4IP, ipv6IP)
 	return
 }
 
+// recordTopology records the topology on IP if its subnet was selected by node labels
+func recordTopology(ip *types.IP, subnet *types.Subnet, specifiedSubnetName string, nodeLabels map[string]string) {
+	if len(specifiedSubnetName) == 0 && subnet.MatchTopology(nodeLabels) {
+		ip.Topology = subnet.TopologyString()
+	}
+}
+
 // Assign will recouple a specified pod with some allocated IPs
 func (m *Manager) Assign(networkName string, podInfo types.PodInfo, assignedSuites []types.SubnetIPSuite, opts ...types.AssignOption) (assignedIPs []*types.IP, err error) {
 	m.Lock()
@@ -276,7 +312,7 @@ func (m *Manager) Assign(networkName string, podInfo types.PodInfo, assignedSuit
 	}
 
 	if err = errors.AggregateGoroutines(validateFunctions...); err != nil {
-		return nil, fmt.Errorf("validation fail: %v", err)
+		return nil, types.NewAllocationError(types.FailureInvalidRequest, "validation fail: %v", err)
 	}
 
 	switch podInfo.IPFamily {
@@ -287,18 +323,18 @@ func (m *Manager) Assign(networkName string, podInfo types.PodInfo, assignedSuit
 	case types.DualStack:
 		return m.assignDualStack(networkName, podInfo, assignedSuites, *options)
 	default:
-		return nil, fmt.Errorf("unsupported ip family %s", podInfo.IPFamily)
+		return nil, types.NewAllocationError(types.FailureInvalidRequest, "unsupported ip family %s", podInfo.IPFamily)
 	}
 }
 
 func (m *Manager) assignIPv4(networkName string, podInfo types.PodInfo, assignedSuites []types.SubnetIPSuite, options types.AssignOptions) (assignedIPs []*types.IP, err error) {
 	var network *types.Network
 	if network, err = m.NetworkSet.GetNetworkByName(networkName); err != nil {
-		return nil, fmt.Errorf("fail to get network %s: %v", networkName, err)
+		return nil, fmt.Errorf("fail to get network %s: %w", networkName, err)
 	}
 
 	if len(assignedSuites) != 1 {
-		return nil, fmt.Errorf("must assign only one IP when IPv4 family, but %v", assignedSuites)
+		return nil, types.NewAllocationError(types.FailureInvalidRequest, "must assign only one IP when IPv4 family, but %v", assignedSuites)
 	}
 
 	var subnetName, ip = assignedSuites[0].Subnet, assignedSuites[0].IP
@@ -308,12 +344,12 @@ func (m *Manager) assignIPv4(networkName string, podInfo types.PodInfo, assigned
 
 	var subnet *types.Subnet
 	if subnet, err = network.GetSubnetByNameOrIP(subnetName, ip); err != nil {
-		return nil, fmt.Errorf("fail to get subnet by %v: %v", assignedSuites[0], err)
+		return nil, fmt.Errorf("fail to get subnet by %v: %w", assignedSuites[0], err)
 	}
 
 	var assignedIP *types.IP
 	if assignedIP, err = subnet.Assign(podInfo.Name, podInfo.Namespace, ip, options.Force); err != nil {
-		return nil, fmt.Errorf("fail to assign ip %v to pod %s: %v", assignedSuites[0], podInfo, err)
+		return nil, fmt.Errorf("fail to assign ip %v to pod %s: %w", assignedSuites[0], podInfo, err)
 	}
 
 	assignedIPs = append(assignedIPs, assignedIP)
@@ -324,11 +360,11 @@ func (m *Manager) assignIPv4(networkName string, podInfo types.PodInfo, assigned
 func (m *Manager) assignIPv6(networkName string, podInfo types.PodInfo, assignedSuites []types.SubnetIPSuite, options types.AssignOptions) (assignedIPs []*types.IP, err error) {
 	var network *types.Network
 	if network, err = m.NetworkSet.GetNetworkByName(networkName); err != nil {
-		return nil, fmt.Errorf("fail to get network %s: %v", networkName, err)
+		return nil, fmt.Errorf("fail to get network %s: %w", networkName, err)
 	}
 
 	if len(assignedSuites) != 1 {
-		return nil, fmt.Errorf("must assign only one IP when IPv6 family, but %v", assignedSuites)
+		return nil, types.NewAllocationError(types.FailureInvalidRequest, "must assign only one IP when IPv6 family, but %v", assignedSuites)
 	}
 
 	var subnetName, ip = assignedSuites[0].Subnet, assignedSuites[0].IP
@@ -338,12 +374,12 @@ func (m *Manager) assignIPv6(networkName string, podInfo types.PodInfo, assigned
 
 	var subnet *types.Subnet
 	if subnet, err = network.GetSubnetByNameOrIP(subnetName, ip); err != nil {
-		return nil, fmt.Errorf("fail to get subnet by %v: %v", assignedSuites[0], err)
+		return nil, fmt.Errorf("fail to get subnet by %v: %w", assignedSuites[0], err)
 	}
 
 	var assignedIP *types.IP
 	if assignedIP, err = subnet.Assign(podInfo.Name, podInfo.Namespace, ip, options.Force); err != nil {
-		return nil, fmt.Errorf("fail to assign ip %v to pod %s: %v", assignedSuites[0], podInfo, err)
+		return nil, fmt.Errorf("fail to assign ip %v to pod %s: %w", assignedSuites[0], podInfo, err)
 	}
 
 	assignedIPs = append(assignedIPs, assignedIP)
@@ -354,11 +390,11 @@ func (m *Manager) assignIPv6(networkName string, podInfo types.PodInfo, assigned
 func (m *Manager) assignDualStack(networkName string, podInfo types.PodInfo, assignedSuites []types.SubnetIPSuite, options types.AssignOptions) (assignedIPs []*types.IP, err error) {
 	var network *types.Network
 	if network, err = m.NetworkSet.GetNetworkByName(networkName); err != nil {
-		return nil, fmt.Errorf("fail to get network %s: %v", networkName, err)
+		return nil, fmt.Errorf("fail to get network %s: %w", networkName, err)
 	}
 
 	if len(assignedSuites) != 2 {
-		return nil, fmt.Errorf("must assign two IPs when DualStack family, but %v", assignedSuites)
+		return nil, types.NewAllocationError(types.FailureInvalidRequest, "must assign two IPs when DualStack family, but %v", assignedSuites)
 	}
 
 	if err = utils.ValidateIPv4(assignedSuites[0].IP); err != nil {
@@ -370,18 +406,18 @@ func (m *Manager) assignDualStack(networkName string, podInfo types.PodInfo, ass
 
 	var v4Subnet, v6Subnet *types.Subnet
 	if v4Subnet, err = network.GetSubnetByNameOrIP(assignedSuites[0].Subnet, assignedSuites[0].IP); err != nil {
-		return nil, fmt.Errorf("fail to get subnet by %v: %v", assignedSuites[0], err)
+		return nil, fmt.Errorf("fail to get subnet by %v: %w", assignedSuites[0], err)
 	}
 	if v6Subnet, err = network.GetSubnetByNameOrIP(assignedSuites[1].Subnet, assignedSuites[1].IP); err != nil {
-		return nil, fmt.Errorf("fail to get subnet by %v: %v", assignedSuites[1], err)
+		return nil, fmt.Errorf("fail to get subnet by %v: %w", assignedSuites[1], err)
 	}
 
 	var assignedIPv4, assignedIPv6 *types.IP
 	if assignedIPv4, err = v4Subnet.Assign(podInfo.Name, podInfo.Namespace, assignedSuites[0].IP, options.Force); err != nil {
-		return nil, fmt.Errorf("fail to assign ip %v to pod %s: %v", assignedSuites[0], podInfo, err)
+		return nil, fmt.Errorf("fail to assign ip %v to pod %s: %w", assignedSuites[0], podInfo, err)
 	}
 	if assignedIPv6, err = v6Subnet.Assign(podInfo.Name, podInfo.Namespace, assignedSuites[1].IP, options.Force); err != nil {
-		return nil, fmt.Errorf("fail to assign ip %v to pod %s: %v", assignedSuites[1], podInfo, err)
+		return nil, fmt.Errorf("fail to assign ip %v to pod %s: %w", assignedSuites[1], podInfo, err)
 	}
 
 	assignedIPs = append(assignedIPs, assignedIPv4, assignedIPv6)
@@ -397,22 +433,22 @@ func (m *Manager) Release(networkName string, releaseSuites []types.SubnetIPSuit
 		func() error { return utils.CheckNotEmpty("network name", networkName) },
 	}
 	if err = errors.AggregateGoroutines(validateFunctions...); err != nil {
-		return fmt.Errorf("validation fail: %v", err)
+		return types.NewAllocationError(types.FailureInvalidRequest, "validation fail: %v", err)
 	}
 
 	var network *types.Network
 	if network, err = m.NetworkSet.GetNetworkByName(networkName); err != nil {
-		return fmt.Errorf("fail to get network %s: %v", networkName, err)
+		return fmt.Errorf("fail to get network %s: %w", networkName, err)
 	}
 
 	for _, releaseSuite := range releaseSuites {
 		if len(releaseSuite.Subnet) == 0 {
-			return fmt.Errorf("must assign subnet when releasing IP, but %v", releaseSuite)
+			return types.NewAllocationError(types.FailureInvalidRequest, "must assign subnet when releasing IP, but %v", releaseSuite)
 		}
 
 		var subnet *types.Subnet
 		if subnet, err = network.GetSubnetByName(releaseSuite.Subnet); err != nil {
-			return fmt.Errorf("fail to get subnet %s: %v", releaseSuite.Subnet, err)
+			return fmt.Errorf("fail to get subnet %s: %w", releaseSuite.Subnet, err)
 		}
 
 		subnet.Release(releaseSuite.IP)
@@ -430,22 +466,22 @@ func (m *Manager) Reserve(networkName string, reserveSuites []types.SubnetIPSuit
 		func() error { return utils.CheckNotEmpty("network name", networkName) },
 	}
 	if err = errors.AggregateGoroutines(validateFunctions...); err != nil {
-		return fmt.Errorf("validation fail: %v", err)
+		return types.NewAllocationError(types.FailureInvalidRequest, "validation fail: %v", err)
 	}
 
 	var network *types.Network
 	if network, err = m.NetworkSet.GetNetworkByName(networkName); err != nil {
-		return fmt.Errorf("fail to get network %s: %v", networkName, err)
+		return fmt.Errorf("fail to get network %s: %w", networkName, err)
 	}
 
 	for _, reserveSuite := range reserveSuites {
 		if len(reserveSuite.Subnet) == 0 {
-			return fmt.Errorf("must assign subnet when reserving IP, but %v", reserveSuite)
+			return types.NewAllocationError(types.FailureInvalidRequest, "must assign subnet when reserving IP, but %v", reserveSuite)
 		}
 
 		var subnet *types.Subnet
 		if subnet, err = network.GetSubnetByName(reserveSuite.Subnet); err != nil {
-			return fmt.Errorf("fail to get subnet %s: %v", reserveSuite.Subnet, err)
+			return fmt.Errorf("fail to get subnet %s: %w", reserveSuite.Subnet, err)
 		}
 
 		subnet.Reserve(reserveSuite.IP)
@@ -454,6 +490,47 @@ func (m *Manager) Reserve(networkName string, reserveSuites []types.SubnetIPSuit
 	return
 }
 
+// Track records an address in use by store which is unknown to cache, e.g., the one of an ip instance
+// created by users, so that cache is updated by ip instance events incrementally. Address already in
+// cache is kept unchanged, and it fails if the address is in use by another pod.
+func (m *Manager) Track(networkName string, ip *types.IP) (err error) {
+	m.Lock()
+	defer m.Unlock()
+
+	validateFunctions := []func() error{
+		func() error { return utils.CheckNotEmpty("network name", networkName) },
+		func() error { return utils.CheckNotEmpty("subnet name", ip.Subnet) },
+		func() error { return utils.CheckNotEmpty("pod name", ip.PodName) },
+		func() error { return utils.CheckNotEmpty("pod namespace", ip.PodNamespace) },
+	}
+	if err = errors.AggregateGoroutines(validateFunctions...); err != nil {
+		return types.NewAllocationError(types.FailureInvalidRequest, "validation fail: %v", err)
+	}
+
+	var network *types.Network
+	if network, err = m.NetworkSet.GetNetworkByName(networkName); err != nil {
+		return fmt.Errorf("fail to get network %s: %w", networkName, err)
+	}
+
+	var subnet *types.Subnet
+	if subnet, err = network.GetSubnetByName(ip.Subnet); err != nil {
+		return fmt.Errorf("fail to get subnet %s: %w", ip.Subnet, err)
+	}
+
+	return subnet.Track(ip)
+}
+
+// usingIPsOf returns the addresses in use of a subnet, which are taken from cache if the subnet is
+// cached, because allocations in flight have no ip instances in store yet
+func (m *Manager) usingIPsOf(cachedNetwork *types.Network, subnetName string) (types.IPSet, error) {
+	if cachedNetwork != nil {
+		if subnet, err := cachedNetwork.GetSubnetByName(subnetName); err == nil {
+			return subnet.AssignedIPs(), nil
+		}
+	}
+	return m.IPSetGetter(subnetName)
+}
+
 func (m *Manager) refreshNetwork(name string) error {
 	// get network spec
 	network, err := m.NetworkGetter(name)
@@ -472,10 +549,13 @@ func (m *Manager) refreshNetwork(name string) error {
 		return err
 	}
 
+	// cached network is nil if it does not exist
+	cachedNetwork, _ := m.NetworkSet.GetNetworkByName(name)
+
 	var ips types.IPSet
 	for _, subnet := range subnets {
 		// get using ips which belongs to this subnet
-		ips, err = m.IPSetGetter(subnet.Name)
+		ips, err = m.usingIPsOf(cachedNetwork, subnet.Name)
 		if err != nil {
 			return err
 		}
@@ -488,3 +568,41 @@ func (m *Manager) refreshNetwork(name string) error {
 
 	return nil
 }
+
+// refreshSubnets only rebuilds the specified subnets of a cached network with their cached usage,
+// which avoids fetching ip sets of all the subnets on every subnet change
+func (m *Manager) refreshSubnets(networkName string, subnetNames []string) error {
+	network, err := m.NetworkSet.GetNetworkByName(networkName)
+	if err != nil {
+		// network is not cached yet
+		return m.refreshNetwork(networkName)
+	}
+
+	subnets, err := m.SubnetGetter(networkName)
+	if err != nil {
+		return err
+	}
+
+	var subnetMap = make(map[string]*types.Subnet, len(subnets))
+	for _, subnet := range subnets {
+		subnetMap[subnet.Name] = subnet
+	}
+
+	for _, subnetName := range subnetNames {
+		subnet, exist := subnetMap[subnetName]
+		if !exist {
+			network.RemoveSubnet(subnetName)
+			continue
+		}
+
+		var ips types.IPSet
+		if ips, err = m.usingIPsOf(network, subnetName); err != nil {
+			return err
+		}
+		if err = network.ReplaceSubnet(subnet, ips); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -17,6 +17,7 @@
 package manager_test
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"testing"
@@ -112,6 +113,222 @@ func TestManager(t *testing.T) {
 	}
 }
 
+func TestManagerRefreshSubnets(t *testing.T) {
+	var networkGetter = func(network string) (*types.Network, error) {
+		return types.NewNetwork(network, nil, "", "", types.Underlay), nil
+	}
+
+	var subnetNames = []string{"subnet1", "subnet2"}
+	var subnetGetter = func(networkName string) ([]*types.Subnet, error) {
+		var subnets []*types.Subnet
+		for i, subnetName := range subnetNames {
+			_, cidrNet, _ := net.ParseCIDR(fmt.Sprintf("192.168.%d.0/24", i))
+			subnets = append(subnets, types.NewSubnet(subnetName, networkName, generatePointerInt(100),
+				nil, nil, nil, cidrNet, nil, nil, nil, false, false))
+		}
+		return subnets, nil
+	}
+
+	var ipSetGetter = func(subnet string) (types.IPSet, error) {
+		return types.NewIPSet(), nil
+	}
+
+	networkTest := "network-test-2"
+	manager, err := manager.NewManager([]string{networkTest}, networkGetter, subnetGetter, ipSetGetter)
+	if err != nil {
+		t.Fatalf("fail to new manager: %v", err)
+	}
+
+	if _, err = manager.Allocate(networkTest, types.PodInfo{
+		NamespacedName: apitypes.NamespacedName{
+			Namespace: "testns",
+			Name:      "testname",
+		},
+		IPFamily: types.IPv4,
+	}, types.AllocateSubnets{"subnet1"}); err != nil {
+		t.Fatalf("fail to allocate ip: %v", err)
+	}
+
+	// refreshing other subnets keeps in-memory usage of subnet1
+	if err = manager.Refresh(types.RefreshNetworks{networkTest}, types.RefreshSubnets{"subnet2"}); err != nil {
+		t.Fatalf("fail to refresh subnets: %v", err)
+	}
+	usage, err := manager.GetSubnetUsage(networkTest, "subnet1")
+	if err != nil {
+		t.Fatalf("fail to get subnet usage: %v", err)
+	}
+	if usage.Used != 1 {
+		t.Errorf("expect 1 used ip in subnet1, but got %d", usage.Used)
+	}
+
+	// refreshing subnet1 itself keeps the allocation whose ip instance is not in store yet
+	if err = manager.Refresh(types.RefreshNetworks{networkTest}, types.RefreshSubnets{"subnet1"}); err != nil {
+		t.Fatalf("fail to refresh subnets: %v", err)
+	}
+	if usage, err = manager.GetSubnetUsage(networkTest, "subnet1"); err != nil || usage.Used != 1 {
+		t.Errorf("expect subnet1 to be refreshed with 1 used ip, but got %+v, %v", usage, err)
+	}
+
+	// subnets which no longer exist are removed
+	subnetNames = []string{"subnet1"}
+	if err = manager.Refresh(types.RefreshNetworks{networkTest}, types.RefreshSubnets{"subnet2"}); err != nil {
+		t.Fatalf("fail to refresh subnets: %v", err)
+	}
+	if _, err = manager.GetSubnetUsage(networkTest, "subnet2"); err == nil {
+		t.Errorf("expect subnet2 to be removed")
+	}
+	if usage, err = manager.GetSubnetUsage(networkTest, "subnet1"); err != nil || usage.Used != 1 {
+		t.Errorf("expect subnet1 to be kept with 1 used ip, but got %+v, %v", usage, err)
+	}
+}
+
+func TestManagerCordonedNetwork(t *testing.T) {
+	var cordoned = true
+	var networkGetter = func(network string) (*types.Network, error) {
+		n := types.NewNetwork(network, nil, "", "", types.Underlay)
+		n.Cordoned = cordoned
+		return n, nil
+	}
+
+	var subnetGetter = func(networkName string) ([]*types.Subnet, error) {
+		_, cidrNet, _ := net.ParseCIDR("192.168.0.0/24")
+		return []*types.Subnet{
+			types.NewSubnet("subnet1", networkName, generatePointerInt(100),
+				nil, nil, nil, cidrNet, nil, nil, nil, false, false),
+		}, nil
+	}
+
+	var ipSetGetter = func(subnet string) (types.IPSet, error) {
+		return types.NewIPSet(), nil
+	}
+
+	networkTest := "network-test-cordon"
+	manager, err := manager.NewManager([]string{networkTest}, networkGetter, subnetGetter, ipSetGetter)
+	if err != nil {
+		t.Fatalf("fail to new manager: %v", err)
+	}
+
+	podInfo := types.PodInfo{
+		NamespacedName: apitypes.NamespacedName{
+			Namespace: "testns",
+			Name:      "testname",
+		},
+		IPFamily: types.IPv4,
+	}
+
+	if _, err = manager.Allocate(networkTest, podInfo); err == nil {
+		t.Errorf("expect allocation on cordoned network to fail")
+	}
+
+	// assignment is still permitted for retained addresses
+	if _, err = manager.Assign(networkTest, podInfo, []types.SubnetIPSuite{
+		types.AssignIPOfSubnet("subnet1", "192.168.0.10"),
+	}); err != nil {
+		t.Errorf("fail to assign ip on cordoned network: %v", err)
+	}
+
+	cordoned = false
+	if err = manager.Refresh(types.RefreshNetworks{networkTest}); err != nil {
+		t.Fatalf("fail to refresh network: %v", err)
+	}
+	if _, err = manager.Allocate(networkTest, podInfo); err != nil {
+		t.Errorf("fail to allocate ip after uncordon: %v", err)
+	}
+}
+
+func TestManagerTrack(t *testing.T) {
+	var networkGetter = func(network string) (*types.Network, error) {
+		return types.NewNetwork(network, nil, "", "", types.Underlay), nil
+	}
+
+	var subnetGetter = func(networkName string) ([]*types.Subnet, error) {
+		_, cidrNet, _ := net.ParseCIDR("192.168.0.0/24")
+		return []*types.Subnet{
+			types.NewSubnet("subnet1", networkName, generatePointerInt(100),
+				nil, nil, nil, cidrNet, map[string]struct{}{"192.168.0.20": {}}, nil, nil, false, false),
+		}, nil
+	}
+
+	var ipSetGetter = func(subnet string) (types.IPSet, error) {
+		return types.NewIPSet(), nil
+	}
+
+	networkTest := "network-test-track"
+	manager, err := manager.NewManager([]string{networkTest}, networkGetter, subnetGetter, ipSetGetter)
+	if err != nil {
+		t.Fatalf("fail to new manager: %v", err)
+	}
+
+	newIP := func(address, podName string) *types.IP {
+		return &types.IP{
+			Address:      &net.IPNet{IP: net.ParseIP(address), Mask: net.CIDRMask(24, 32)},
+			Subnet:       "subnet1",
+			Network:      networkTest,
+			PodName:      podName,
+			PodNamespace: "testns",
+			Status:       types.IPStatusReserved,
+		}
+	}
+
+	tests := []struct {
+		name      string
+		ip        *types.IP
+		expectErr error
+	}{
+		{
+			name: "unknown address",
+			ip:   newIP("192.168.0.10", "pod1"),
+		},
+		{
+			name: "tracked address of the same pod",
+			ip:   newIP("192.168.0.10", "pod1"),
+		},
+		{
+			name:      "tracked address of another pod",
+			ip:        newIP("192.168.0.10", "pod2"),
+			expectErr: types.ErrNotAvailableAssignedIP,
+		},
+		{
+			name:      "reserved address",
+			ip:        newIP("192.168.0.20", "pod2"),
+			expectErr: types.ErrNotAvailableAssignedIP,
+		},
+		{
+			name:      "address out of subnet",
+			ip:        newIP("192.168.1.10", "pod2"),
+			expectErr: types.ErrNotFoundAssignedIP,
+		},
+	}
+
+	for _, test := range tests {
+		if err = manager.Track(networkTest, test.ip); !errors.Is(err, test.expectErr) {
+			t.Errorf("test %s fails, expected error %v but got %v", test.name, test.expectErr, err)
+		}
+	}
+
+	usage, err := manager.GetSubnetUsage(networkTest, "subnet1")
+	if err != nil {
+		t.Fatalf("fail to get subnet usage: %v", err)
+	}
+	if usage.Used != 1 {
+		t.Errorf("expect 1 used ip in subnet1, but got %d", usage.Used)
+	}
+
+	// tracked addresses are not allocated again
+	for i := 0; i < 250; i++ {
+		ips, err := manager.Allocate(networkTest, types.PodInfo{
+			NamespacedName: apitypes.NamespacedName{Namespace: "testns", Name: fmt.Sprintf("pod%d", i+3)},
+			IPFamily:       types.IPv4,
+		})
+		if err != nil {
+			break
+		}
+		if address := ips[0].Address.IP.String(); address == "192.168.0.10" {
+			t.Fatalf("expect tracked address not to be allocated")
+		}
+	}
+}
+
 func generatePointerInt(a uint32) *uint32 {
 	return &a
 }
@@ -0,0 +1,261 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+
+	"github.com/alibaba/hybridnet/pkg/metrics"
+)
+
+// EventType is the type of address allocation events
+type EventType string
+
+const (
+	// EventAllocate is sent after addresses are allocated to a pod, or retained ones are coupled
+	// with a new pod
+	EventAllocate EventType = "Allocate"
+	// EventRelease is sent after an address is released to its subnet
+	EventRelease EventType = "Release"
+)
+
+const (
+	// maxBatchSize is the max count of events sent in one request
+	maxBatchSize = 100
+
+	// retryInitialInterval is the interval before the first retry of a failed request, which is
+	// doubled for every retry and capped by retryMaxInterval
+	retryInitialInterval = time.Second
+	retryMaxInterval     = time.Minute
+)
+
+// Event is an address allocation event sent to webhook
+type Event struct {
+	Type      EventType `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	// IP is in the format of CIDR, e.g., 192.168.0.10/24
+	IP           string `json:"ip"`
+	Gateway      string `json:"gateway,omitempty"`
+	Subnet       string `json:"subnet"`
+	Network      string `json:"network"`
+	PodNamespace string `json:"podNamespace,omitempty"`
+	PodName      string `json:"podName,omitempty"`
+	NodeName     string `json:"nodeName,omitempty"`
+}
+
+// Payload is the body of webhook requests
+type Payload struct {
+	Events []Event `json:"events"`
+}
+
+type Options struct {
+	// URL is where events are posted to
+	URL string
+	// BearerTokenFile is read on every request, so that token can be rotated, empty means no token
+	BearerTokenFile string
+	// CAFile is the CA bundle to verify the certificate of webhook, system roots are used if empty
+	CAFile string
+	// CertFile and KeyFile are the client certificate presented to webhook, which are reloaded
+	// on changes, empty means no client certificate
+	CertFile string
+	KeyFile  string
+
+	// Timeout is the timeout of every request
+	Timeout time.Duration
+	// QueueSize is the max count of events waiting to be sent, events beyond are dropped
+	QueueSize int
+	// MaxRetries is the max count of retries of a failed request, before its events are dropped
+	MaxRetries int
+}
+
+// Notifier sends address allocation events to an outbound webhook, so that third-party systems,
+// e.g., CMDBs and firewall automation, can track addresses of pods in near real time without
+// watching CRDs. Events are queued without blocking IPAM and sent in batches in order.
+type Notifier struct {
+	options Options
+	logger  logr.Logger
+	queue   chan Event
+}
+
+func New(options Options, logger logr.Logger) *Notifier {
+	return &Notifier{
+		options: options,
+		logger:  logger,
+		queue:   make(chan Event, options.QueueSize),
+	}
+}
+
+// Notify queues events to be sent, events are dropped if queue is full
+func (n *Notifier) Notify(events ...Event) {
+	for _, event := range events {
+		select {
+		case n.queue <- event:
+		default:
+			metrics.IPAMNotificationCounter.WithLabelValues(metrics.IPAMNotificationDropped).Inc()
+			n.logger.Info("queue is full, drop event", "type", event.Type, "ip", event.IP)
+		}
+	}
+}
+
+// Start sends queued events until ctx is done, it implements manager.Runnable
+func (n *Notifier) Start(ctx context.Context) error {
+	if u, err := url.Parse(n.options.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("invalid url %q of ipam notifier, an http or https url is expected", n.options.URL)
+	}
+
+	httpClient, err := n.newHTTPClient(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to create http client of ipam notifier: %v", err)
+	}
+
+	n.logger.Info("ipam notifier is sending events", "url", n.options.URL)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-n.queue:
+			n.sendWithRetry(ctx, httpClient, n.batch(event))
+		}
+	}
+}
+
+// NeedLeaderElection makes notifier only run on leader, where addresses are allocated and released
+func (n *Notifier) NeedLeaderElection() bool {
+	return true
+}
+
+// batch drains the queued events following the first one, up to maxBatchSize
+func (n *Notifier) batch(first Event) []Event {
+	events := []Event{first}
+	for len(events) < maxBatchSize {
+		select {
+		case event := <-n.queue:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+	return events
+}
+
+func (n *Notifier) sendWithRetry(ctx context.Context, httpClient *http.Client, events []Event) {
+	backoff := wait.Backoff{
+		Duration: retryInitialInterval,
+		Factor:   2,
+		Jitter:   0.1,
+		Steps:    n.options.MaxRetries + 1,
+		Cap:      retryMaxInterval,
+	}
+
+	var lastErr error
+	if err := wait.ExponentialBackoffWithContext(ctx, backoff, func() (bool, error) {
+		if lastErr = n.send(ctx, httpClient, events); lastErr != nil {
+			n.logger.Error(lastErr, "failed to send events, will retry", "count", len(events))
+			return false, nil
+		}
+		return true, nil
+	}); err != nil {
+		metrics.IPAMNotificationCounter.WithLabelValues(metrics.IPAMNotificationFailed).Add(float64(len(events)))
+		n.logger.Error(lastErr, "failed to send events, drop them", "count", len(events))
+		return
+	}
+	metrics.IPAMNotificationCounter.WithLabelValues(metrics.IPAMNotificationSent).Add(float64(len(events)))
+}
+
+func (n *Notifier) send(ctx context.Context, httpClient *http.Client, events []Event) error {
+	body, err := json.Marshal(&Payload{Events: events})
+	if err != nil {
+		return fmt.Errorf("failed to marshal events: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, n.options.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.options.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if n.options.BearerTokenFile != "" {
+		token, err := os.ReadFile(n.options.BearerTokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read bearer token file %v: %v", n.options.BearerTokenFile, err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post events: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %v of webhook", resp.StatusCode)
+	}
+	return nil
+}
+
+func (n *Notifier) newHTTPClient(ctx context.Context) (*http.Client, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if n.options.CAFile != "" {
+		caData, err := os.ReadFile(n.options.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca file %v: %v", n.options.CAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("no certificate found in ca file %v", n.options.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if n.options.CertFile != "" || n.options.KeyFile != "" {
+		watcher, err := certwatcher.New(n.options.CertFile, n.options.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		go func() {
+			if err := watcher.Start(ctx); err != nil {
+				n.logger.Error(err, "client certificate watcher exits")
+			}
+		}()
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return watcher.GetCertificate(nil)
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}, nil
+}
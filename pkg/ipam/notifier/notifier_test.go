@@ -0,0 +1,209 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/alibaba/hybridnet/pkg/ipam"
+	ipamtypes "github.com/alibaba/hybridnet/pkg/ipam/types"
+)
+
+type webhook struct {
+	lock     sync.Mutex
+	failures int
+	tokens   []string
+	payloads []Payload
+}
+
+func (w *webhook) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.tokens = append(w.tokens, req.Header.Get("Authorization"))
+	if w.failures > 0 {
+		w.failures--
+		rw.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	payload := Payload{}
+	if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.payloads = append(w.payloads, payload)
+}
+
+func (w *webhook) events() []Event {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	var events []Event
+	for _, payload := range w.payloads {
+		events = append(events, payload.Events...)
+	}
+	return events
+}
+
+func TestNotifier(t *testing.T) {
+	hook := &webhook{failures: 1}
+	server := httptest.NewServer(hook)
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("secret\n"), 0600); err != nil {
+		t.Fatalf("failed to write token file: %v", err)
+	}
+
+	n := New(Options{
+		URL:             server.URL,
+		BearerTokenFile: tokenFile,
+		Timeout:         time.Second,
+		QueueSize:       10,
+		MaxRetries:      3,
+	}, ctrl.Log.WithName("test"))
+
+	n.Notify(
+		Event{Type: EventAllocate, IP: "192.168.0.10/24", Subnet: "subnet1", Network: "network1", PodName: "pod1"},
+		Event{Type: EventRelease, IP: "192.168.0.11/24", Subnet: "subnet1", Network: "network1", PodName: "pod2"},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		if err := n.Start(ctx); err != nil {
+			t.Errorf("failed to start notifier: %v", err)
+		}
+	}()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for len(hook.events()) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("events are not sent in time")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	events := hook.events()
+	if events[0].Type != EventAllocate || events[0].PodName != "pod1" ||
+		events[1].Type != EventRelease || events[1].PodName != "pod2" {
+		t.Errorf("unexpected events %+v", events)
+	}
+
+	hook.lock.Lock()
+	defer hook.lock.Unlock()
+	// the first request fails and is retried
+	if len(hook.tokens) != 2 {
+		t.Errorf("expected 2 requests, got %v", len(hook.tokens))
+	}
+	for _, token := range hook.tokens {
+		if token != "Bearer secret" {
+			t.Errorf("unexpected authorization header %q", token)
+		}
+	}
+}
+
+func TestNotifyDropsEventsBeyondQueueSize(t *testing.T) {
+	n := New(Options{QueueSize: 1}, ctrl.Log.WithName("test"))
+	n.Notify(Event{IP: "192.168.0.10/24"}, Event{IP: "192.168.0.11/24"})
+
+	if len(n.queue) != 1 {
+		t.Fatalf("expected 1 queued event, got %v", len(n.queue))
+	}
+	if event := <-n.queue; event.IP != "192.168.0.10/24" {
+		t.Errorf("unexpected queued event %+v", event)
+	}
+}
+
+func TestStartWithInvalidURL(t *testing.T) {
+	n := New(Options{URL: "ftp://example.com", QueueSize: 1}, ctrl.Log.WithName("test"))
+	if err := n.Start(context.Background()); err == nil {
+		t.Errorf("expected error of invalid url")
+	}
+}
+
+type fakeStore struct {
+	ipam.Store
+	err error
+}
+
+func (f *fakeStore) Couple(ctx context.Context, pod *corev1.Pod, IPs []*ipamtypes.IP, opts ...ipamtypes.CoupleOption) error {
+	return f.err
+}
+
+func TestStoreCouple(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "node1"},
+	}
+	IPs := []*ipamtypes.IP{
+		{
+			Address: &net.IPNet{IP: net.ParseIP("192.168.0.10").To4(), Mask: net.CIDRMask(24, 32)},
+			Gateway: net.ParseIP("192.168.0.1"),
+			Subnet:  "subnet1",
+			Network: "network1",
+		},
+		{
+			Address: &net.IPNet{IP: net.ParseIP("fe80::10"), Mask: net.CIDRMask(64, 128)},
+			Subnet:  "subnet2",
+			Network: "network1",
+		},
+	}
+
+	n := New(Options{QueueSize: 10}, ctrl.Log.WithName("test"))
+	if err := NewStore(&fakeStore{err: context.Canceled}, n).Couple(context.Background(), pod, IPs); err == nil {
+		t.Fatalf("expected error of store")
+	}
+	if len(n.queue) != 0 {
+		t.Fatalf("events should not be sent if coupling fails")
+	}
+
+	if err := NewStore(&fakeStore{}, n).Couple(context.Background(), pod, IPs); err != nil {
+		t.Fatalf("failed to couple: %v", err)
+	}
+	if len(n.queue) != 2 {
+		t.Fatalf("expected 2 queued events, got %v", len(n.queue))
+	}
+
+	expected := []Event{
+		{Type: EventAllocate, IP: "192.168.0.10/24", Gateway: "192.168.0.1", Subnet: "subnet1", Network: "network1",
+			PodNamespace: "default", PodName: "pod1", NodeName: "node1"},
+		{Type: EventAllocate, IP: "fe80::10/64", Subnet: "subnet2", Network: "network1",
+			PodNamespace: "default", PodName: "pod1", NodeName: "node1"},
+	}
+	for _, expectedEvent := range expected {
+		event := <-n.queue
+		event.Timestamp = time.Time{}
+		if event != expectedEvent {
+			t.Errorf("expected event %+v, got %+v", expectedEvent, event)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package notifier
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/ipam"
+	ipamtypes "github.com/alibaba/hybridnet/pkg/ipam/types"
+)
+
+// notifyingStore sends allocate events after IPs are coupled with pods
+type notifyingStore struct {
+	ipam.Store
+	notifier *Notifier
+}
+
+// NewStore wraps an IPAM store to send allocate events of IPs which are coupled with pods
+// successfully. Release events are sent by ReleaseEvent when IPs are released to IPAM manager.
+func NewStore(store ipam.Store, notifier *Notifier) ipam.Store {
+	return &notifyingStore{
+		Store:    store,
+		notifier: notifier,
+	}
+}
+
+func (s *notifyingStore) Couple(ctx context.Context, pod *corev1.Pod, IPs []*ipamtypes.IP, opts ...ipamtypes.CoupleOption) (err error) {
+	if err = s.Store.Couple(ctx, pod, IPs, opts...); err != nil {
+		return
+	}
+	s.notifier.Notify(allocateEvents(pod, IPs)...)
+	return
+}
+
+func (s *notifyingStore) ReCouple(ctx context.Context, pod *corev1.Pod, IPs []*ipamtypes.IP, opts ...ipamtypes.ReCoupleOption) (err error) {
+	if err = s.Store.ReCouple(ctx, pod, IPs, opts...); err != nil {
+		return
+	}
+	s.notifier.Notify(allocateEvents(pod, IPs)...)
+	return
+}
+
+func allocateEvents(pod *corev1.Pod, IPs []*ipamtypes.IP) []Event {
+	now := time.Now()
+	events := make([]Event, 0, len(IPs))
+	for _, ip := range IPs {
+		event := Event{
+			Type:         EventAllocate,
+			Timestamp:    now,
+			IP:           ip.Address.String(),
+			Subnet:       ip.Subnet,
+			Network:      ip.Network,
+			PodNamespace: pod.Namespace,
+			PodName:      pod.Name,
+			NodeName:     pod.Spec.NodeName,
+		}
+		if ip.Gateway != nil {
+			event.Gateway = ip.Gateway.String()
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// ReleaseEvent returns the release event of an IPInstance
+func ReleaseEvent(ipInstance *networkingv1.IPInstance) Event {
+	return Event{
+		Type:         EventRelease,
+		Timestamp:    time.Now(),
+		IP:           ipInstance.Spec.Address.IP,
+		Gateway:      ipInstance.Spec.Address.Gateway,
+		Subnet:       ipInstance.Spec.Subnet,
+		Network:      ipInstance.Spec.Network,
+		PodNamespace: ipInstance.Namespace,
+		PodName:      ipInstance.Spec.Binding.PodName,
+		NodeName:     ipInstance.Spec.Binding.NodeName,
+	}
+}
@@ -0,0 +1,205 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package service exposes IPAM manager through an authenticated gRPC service, so that workloads
+// out of kubernetes, e.g., VMs and bare metal provisioning, can draw addresses from the same
+// subnets of cluster. Addresses are held by leases, which must be renewed before expiration.
+//
+// Messages are encoded as JSON with the content subtype "json" instead of protobuf, which
+// saves generated code and is easy for clients of other languages.
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding"
+)
+
+const (
+	ServiceName = "hybridnet.ipam.v1.IPAM"
+
+	codecName = "json"
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return codecName
+}
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// IPAMServer is the server API of IPAM service
+type IPAMServer interface {
+	Allocate(context.Context, *AllocateRequest) (*Lease, error)
+	Renew(context.Context, *LeaseRequest) (*Lease, error)
+	Release(context.Context, *LeaseRequest) (*Empty, error)
+	Get(context.Context, *LeaseRequest) (*Lease, error)
+	List(context.Context, *ListRequest) (*LeaseList, error)
+}
+
+// RegisterIPAMServer registers an implementation of IPAM service to gRPC server
+func RegisterIPAMServer(s grpc.ServiceRegistrar, srv IPAMServer) {
+	s.RegisterService(&serviceDesc, srv)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*IPAMServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Allocate",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := &AllocateRequest{}
+				return handle(srv, ctx, dec, interceptor, in, "Allocate", func(ctx context.Context) (interface{}, error) {
+					return srv.(IPAMServer).Allocate(ctx, in)
+				})
+			},
+		},
+		{
+			MethodName: "Renew",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := &LeaseRequest{}
+				return handle(srv, ctx, dec, interceptor, in, "Renew", func(ctx context.Context) (interface{}, error) {
+					return srv.(IPAMServer).Renew(ctx, in)
+				})
+			},
+		},
+		{
+			MethodName: "Release",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := &LeaseRequest{}
+				return handle(srv, ctx, dec, interceptor, in, "Release", func(ctx context.Context) (interface{}, error) {
+					return srv.(IPAMServer).Release(ctx, in)
+				})
+			},
+		},
+		{
+			MethodName: "Get",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := &LeaseRequest{}
+				return handle(srv, ctx, dec, interceptor, in, "Get", func(ctx context.Context) (interface{}, error) {
+					return srv.(IPAMServer).Get(ctx, in)
+				})
+			},
+		},
+		{
+			MethodName: "List",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := &ListRequest{}
+				return handle(srv, ctx, dec, interceptor, in, "List", func(ctx context.Context) (interface{}, error) {
+					return srv.(IPAMServer).List(ctx, in)
+				})
+			},
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+// handle decodes request and calls the method through interceptor if there is
+func handle(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor,
+	in interface{}, method string, call func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return call(ctx)
+	}
+
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: fullMethod(method),
+	}
+	return interceptor(ctx, in, info, func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return call(ctx)
+	})
+}
+
+func fullMethod(method string) string {
+	return "/" + ServiceName + "/" + method
+}
+
+// Client is the client of IPAM service
+type Client struct {
+	conn grpc.ClientConnInterface
+}
+
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{conn: conn}
+}
+
+// Dial connects to IPAM service, tlsConfig is supposed to be created by mtls.NewClientTLSConfig
+// with the certificate of role ipam-client and only trust manager.
+func Dial(address string, tlsConfig *tls.Config, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	return grpc.Dial(address, append([]grpc.DialOption{
+		grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)),
+		grpc.WithDefaultCallOptions(grpc.CallContentSubtype(codecName)),
+	}, opts...)...)
+}
+
+func (c *Client) Allocate(ctx context.Context, in *AllocateRequest) (*Lease, error) {
+	out := &Lease{}
+	if err := c.invoke(ctx, "Allocate", in, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *Client) Renew(ctx context.Context, in *LeaseRequest) (*Lease, error) {
+	out := &Lease{}
+	if err := c.invoke(ctx, "Renew", in, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *Client) Release(ctx context.Context, in *LeaseRequest) error {
+	return c.invoke(ctx, "Release", in, &Empty{})
+}
+
+func (c *Client) Get(ctx context.Context, in *LeaseRequest) (*Lease, error) {
+	out := &Lease{}
+	if err := c.invoke(ctx, "Get", in, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *Client) List(ctx context.Context) (*LeaseList, error) {
+	out := &LeaseList{}
+	if err := c.invoke(ctx, "List", &ListRequest{}, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *Client) invoke(ctx context.Context, method string, in, out interface{}) error {
+	return c.conn.Invoke(ctx, fullMethod(method), in, out, grpc.CallContentSubtype(codecName))
+}
@@ -0,0 +1,430 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/ipam"
+	ipamtypes "github.com/alibaba/hybridnet/pkg/ipam/types"
+	ipamutils "github.com/alibaba/hybridnet/pkg/ipam/utils"
+	"github.com/alibaba/hybridnet/pkg/utils/mtls"
+)
+
+const leaseNamePrefix = "ipam-lease-"
+
+var leaseGVK = coordinationv1.SchemeGroupVersion.WithKind("Lease")
+
+type Options struct {
+	// Address is the tcp address to listen on, e.g., :9900
+	Address string
+	// TLS is the mutual TLS config of server, only clients of role ipam-client are accepted
+	TLS mtls.Config
+	// Namespace is where leases and their IPInstances are created
+	Namespace string
+
+	DefaultLeaseDuration time.Duration
+	MaxLeaseDuration     time.Duration
+	// ExpirationCheckInterval is the interval to release expired leases
+	ExpirationCheckInterval time.Duration
+}
+
+// Server implements IPAM service. Every lease is a coordination.k8s.io Lease object, and
+// IPInstances of a lease are owned by it, which are coupled as if they belong to a pod with
+// the name and uid of lease, so that addresses are recovered and released in the same way
+// of pods'.
+type Server struct {
+	// Client is used to write objects, and APIReader is used to read leases and IPInstances
+	// which are just written
+	Client      client.Client
+	APIReader   client.Reader
+	IPAMManager ipam.Manager
+	IPAMStore   ipam.Store
+	Options     Options
+	Logger      logr.Logger
+}
+
+// Start serves until ctx is done, it implements manager.Runnable
+func (s *Server) Start(ctx context.Context) error {
+	tlsConfig, err := mtls.NewServerTLSConfig(ctx, s.Options.TLS, mtls.RoleIPAMClient)
+	if err != nil {
+		return fmt.Errorf("unable to create tls config of ipam service: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", s.Options.Address)
+	if err != nil {
+		return fmt.Errorf("unable to listen on %s: %v", s.Options.Address, err)
+	}
+
+	server := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(tlsConfig)),
+		grpc.UnaryInterceptor(authenticate),
+	)
+	RegisterIPAMServer(server, s)
+
+	go wait.UntilWithContext(ctx, s.releaseExpiredLeases, s.Options.ExpirationCheckInterval)
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+
+	s.Logger.Info("ipam service is serving", "address", s.Options.Address)
+	return server.Serve(listener)
+}
+
+// NeedLeaderElection makes service only run on leader, where IPAM manager works
+func (s *Server) NeedLeaderElection() bool {
+	return true
+}
+
+func (s *Server) Allocate(ctx context.Context, req *AllocateRequest) (*Lease, error) {
+	if len(req.Network) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "network must be specified")
+	}
+	if len(req.Subnets) > 2 {
+		return nil, status.Error(codes.InvalidArgument, "cannot have more than two specified subnets")
+	}
+
+	ipFamily := ipamtypes.ParseIPFamilyFromString(req.IPFamily)
+	if !ipamtypes.IsValidFamilyMode(ipFamily) {
+		return nil, status.Errorf(codes.InvalidArgument, "unrecognized ip family %s", req.IPFamily)
+	}
+
+	durationSeconds, err := s.leaseDurationSeconds(req.DurationSeconds)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		holder = identityFrom(ctx).String()
+		now    = metav1.NewMicroTime(time.Now())
+		lease  = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      leaseNamePrefix + utilrand.String(8),
+				Namespace: s.Options.Namespace,
+				Labels: map[string]string{
+					constants.LabelNetwork: req.Network,
+				},
+				Annotations: map[string]string{
+					constants.AnnotationIPLeaseWorkload: req.Workload,
+				},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &holder,
+				LeaseDurationSeconds: &durationSeconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+	)
+	lease.Labels[constants.LabelIPLease] = lease.Name
+
+	if err = s.Client.Create(ctx, lease); err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to create lease: %v", err)
+	}
+
+	if err = s.allocate(ctx, lease, req, ipFamily); err != nil {
+		_ = s.Client.Delete(ctx, lease)
+		return nil, status.Errorf(codes.FailedPrecondition, "unable to allocate addresses: %s", ipamtypes.DescribeAllocationFailure(err))
+	}
+
+	s.Logger.Info("lease is allocated", "lease", lease.Name, "holder", holder,
+		"network", req.Network, "workload", req.Workload)
+	return s.leaseOf(ctx, lease)
+}
+
+func (s *Server) allocate(ctx context.Context, lease *coordinationv1.Lease, req *AllocateRequest,
+	ipFamily ipamtypes.IPFamilyMode) (err error) {
+	var allocatedIPs []*ipamtypes.IP
+	if allocatedIPs, err = s.IPAMManager.Allocate(req.Network, ipamtypes.PodInfo{
+		NamespacedName: apitypes.NamespacedName{Namespace: lease.Namespace, Name: lease.Name},
+		IPFamily:       ipFamily,
+	}, ipamtypes.AllocateSubnets(req.Subnets)); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			var releaseSuites []ipamtypes.SubnetIPSuite
+			for _, ip := range allocatedIPs {
+				releaseSuites = append(releaseSuites, ipamtypes.ReleaseIPOfSubnet(ip.Subnet, ip.Address.IP.String()))
+			}
+			_ = s.IPAMManager.Release(req.Network, releaseSuites)
+		}
+	}()
+
+	return s.IPAMStore.Couple(ctx, podOfLease(lease), allocatedIPs,
+		ipamtypes.OwnerReference(*ipamutils.NewControllerRef(lease, leaseGVK, true, false)),
+		ipamtypes.AdditionalLabels{constants.LabelIPLease: lease.Name})
+}
+
+func (s *Server) Renew(ctx context.Context, req *LeaseRequest) (*Lease, error) {
+	lease, err := s.getLease(ctx, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if expired(lease, time.Now()) {
+		return nil, status.Errorf(codes.NotFound, "lease %s has expired", req.Name)
+	}
+
+	if req.DurationSeconds != 0 {
+		durationSeconds, err := s.leaseDurationSeconds(req.DurationSeconds)
+		if err != nil {
+			return nil, err
+		}
+		lease.Spec.LeaseDurationSeconds = &durationSeconds
+	}
+
+	now := metav1.NewMicroTime(time.Now())
+	lease.Spec.RenewTime = &now
+	if err = s.Client.Update(ctx, lease); err != nil {
+		if apierrors.IsConflict(err) {
+			return nil, status.Errorf(codes.Aborted, "lease %s is changed concurrently", req.Name)
+		}
+		return nil, status.Errorf(codes.Internal, "unable to update lease %s: %v", req.Name, err)
+	}
+	return s.leaseOf(ctx, lease)
+}
+
+func (s *Server) Release(ctx context.Context, req *LeaseRequest) (*Empty, error) {
+	lease, err := s.getLease(ctx, req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.releaseLease(ctx, lease); err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to release lease %s: %v", req.Name, err)
+	}
+
+	s.Logger.Info("lease is released", "lease", lease.Name, "holder", identityFrom(ctx).String())
+	return &Empty{}, nil
+}
+
+func (s *Server) Get(ctx context.Context, req *LeaseRequest) (*Lease, error) {
+	lease, err := s.getLease(ctx, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	return s.leaseOf(ctx, lease)
+}
+
+func (s *Server) List(ctx context.Context, _ *ListRequest) (*LeaseList, error) {
+	leases, err := s.listLeases(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to list leases: %v", err)
+	}
+
+	var holder = identityFrom(ctx).String()
+	var leaseList = &LeaseList{Leases: []Lease{}}
+	for i := range leases {
+		if holderOf(&leases[i]) != holder {
+			continue
+		}
+
+		lease, err := s.leaseOf(ctx, &leases[i])
+		if err != nil {
+			return nil, err
+		}
+		leaseList.Leases = append(leaseList.Leases, *lease)
+	}
+	return leaseList, nil
+}
+
+// getLease returns the lease only if it is held by the client
+func (s *Server) getLease(ctx context.Context, name string) (*coordinationv1.Lease, error) {
+	lease := &coordinationv1.Lease{}
+	if err := s.APIReader.Get(ctx, apitypes.NamespacedName{Namespace: s.Options.Namespace, Name: name}, lease); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, status.Errorf(codes.NotFound, "lease %s is not found", name)
+		}
+		return nil, status.Errorf(codes.Internal, "unable to get lease %s: %v", name, err)
+	}
+
+	// leases of others are invisible
+	if _, ok := lease.Labels[constants.LabelIPLease]; !ok || holderOf(lease) != identityFrom(ctx).String() {
+		return nil, status.Errorf(codes.NotFound, "lease %s is not found", name)
+	}
+	return lease, nil
+}
+
+func (s *Server) listLeases(ctx context.Context) ([]coordinationv1.Lease, error) {
+	leaseList := &coordinationv1.LeaseList{}
+	if err := s.APIReader.List(ctx, leaseList, client.InNamespace(s.Options.Namespace),
+		client.HasLabels{constants.LabelIPLease}); err != nil {
+		return nil, err
+	}
+	return leaseList.Items, nil
+}
+
+func (s *Server) leaseOf(ctx context.Context, lease *coordinationv1.Lease) (*Lease, error) {
+	ipInstanceList := &networkingv1.IPInstanceList{}
+	if err := s.APIReader.List(ctx, ipInstanceList, client.InNamespace(lease.Namespace),
+		client.MatchingLabels{constants.LabelIPLease: lease.Name}); err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to list ip instances of lease %s: %v", lease.Name, err)
+	}
+
+	var ret = &Lease{
+		Name:       lease.Name,
+		Workload:   lease.Annotations[constants.AnnotationIPLeaseWorkload],
+		Network:    lease.Labels[constants.LabelNetwork],
+		Addresses:  []Address{},
+		ExpireTime: expireTime(lease),
+	}
+	for i := range ipInstanceList.Items {
+		ipInstance := &ipInstanceList.Items[i]
+		ret.Addresses = append(ret.Addresses, Address{
+			Subnet:  ipInstance.Spec.Subnet,
+			Version: string(ipInstance.Spec.Address.Version),
+			IP:      ipInstance.Spec.Address.IP,
+			Gateway: ipInstance.Spec.Address.Gateway,
+			MAC:     ipInstance.Spec.Address.MAC,
+		})
+	}
+	sort.SliceStable(ret.Addresses, func(i, j int) bool {
+		return ret.Addresses[i].Version < ret.Addresses[j].Version
+	})
+	return ret, nil
+}
+
+// releaseLease deletes IPInstances whose addresses are released by IPInstance controller
+// through finalizer, then the lease
+func (s *Server) releaseLease(ctx context.Context, lease *coordinationv1.Lease) error {
+	if err := s.IPAMStore.DeCouple(ctx, podOfLease(lease)); err != nil {
+		return fmt.Errorf("unable to delete ip instances: %v", err)
+	}
+	return client.IgnoreNotFound(s.Client.Delete(ctx, lease))
+}
+
+func (s *Server) releaseExpiredLeases(ctx context.Context) {
+	leases, err := s.listLeases(ctx)
+	if err != nil {
+		s.Logger.Error(err, "unable to list leases")
+		return
+	}
+
+	now := time.Now()
+	for i := range leases {
+		lease := &leases[i]
+		if !expired(lease, now) {
+			continue
+		}
+
+		if err = s.releaseLease(ctx, lease); err != nil {
+			s.Logger.Error(err, "unable to release expired lease", "lease", lease.Name)
+			continue
+		}
+		s.Logger.Info("expired lease is released", "lease", lease.Name, "holder", holderOf(lease))
+	}
+}
+
+func (s *Server) leaseDurationSeconds(durationSeconds int32) (int32, error) {
+	if durationSeconds < 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid lease duration %d", durationSeconds)
+	}
+
+	duration := time.Duration(durationSeconds) * time.Second
+	if duration == 0 {
+		duration = s.Options.DefaultLeaseDuration
+	}
+	if s.Options.MaxLeaseDuration > 0 && duration > s.Options.MaxLeaseDuration {
+		return 0, status.Errorf(codes.InvalidArgument, "lease duration %v exceeds the max %v",
+			duration, s.Options.MaxLeaseDuration)
+	}
+	return int32(duration / time.Second), nil
+}
+
+// podOfLease is the pod skeleton which IPInstances of lease are coupled with
+func podOfLease(lease *coordinationv1.Lease) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      lease.Name,
+			Namespace: lease.Namespace,
+			UID:       lease.UID,
+		},
+	}
+}
+
+func holderOf(lease *coordinationv1.Lease) string {
+	if lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+	return *lease.Spec.HolderIdentity
+}
+
+func expireTime(lease *coordinationv1.Lease) time.Time {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return time.Time{}
+	}
+	return lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+}
+
+func expired(lease *coordinationv1.Lease, now time.Time) bool {
+	return expireTime(lease).Before(now)
+}
+
+type identityKey struct{}
+
+// authenticate puts the identity of verified client certificate into context
+func authenticate(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "no peer")
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "no tls connection")
+	}
+
+	identity, err := mtls.PeerIdentity(tlsInfo.State)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "unable to identify peer: %v", err)
+	}
+	return handler(withIdentity(ctx, identity), req)
+}
+
+func withIdentity(ctx context.Context, identity *mtls.Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// identityFrom never returns nil, because all methods are called after authentication
+func identityFrom(ctx context.Context) *mtls.Identity {
+	if identity, ok := ctx.Value(identityKey{}).(*mtls.Identity); ok {
+		return identity
+	}
+	return &mtls.Identity{}
+}
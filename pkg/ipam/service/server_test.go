@@ -0,0 +1,187 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/ipam"
+	"github.com/alibaba/hybridnet/pkg/ipam/store"
+	ipamtypes "github.com/alibaba/hybridnet/pkg/ipam/types"
+	"github.com/alibaba/hybridnet/pkg/utils/mtls"
+)
+
+// fakeIPAMManager allocates 192.168.0.x/24 in order
+type fakeIPAMManager struct {
+	ipam.Manager
+
+	next     int
+	released []string
+}
+
+func (f *fakeIPAMManager) Allocate(networkName string, _ ipamtypes.PodInfo, _ ...ipamtypes.AllocateOption) ([]*ipamtypes.IP, error) {
+	f.next++
+	return []*ipamtypes.IP{{
+		Address: &net.IPNet{IP: net.IPv4(192, 168, 0, byte(f.next)), Mask: net.CIDRMask(24, 32)},
+		Gateway: net.IPv4(192, 168, 0, 254),
+		Subnet:  "subnet1",
+		Network: networkName,
+	}}, nil
+}
+
+func (f *fakeIPAMManager) Release(_ string, releaseSuites []ipamtypes.SubnetIPSuite) error {
+	for _, suite := range releaseSuites {
+		f.released = append(f.released, suite.IP)
+	}
+	return nil
+}
+
+func newTestServer() *Server {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	return &Server{
+		Client:      c,
+		APIReader:   c,
+		IPAMManager: &fakeIPAMManager{},
+		IPAMStore:   store.NewCRDStore(c),
+		Options: Options{
+			Namespace:            "kube-system",
+			DefaultLeaseDuration: time.Hour,
+			MaxLeaseDuration:     24 * time.Hour,
+		},
+		Logger: logr.Discard(),
+	}
+}
+
+func clientContext(name string) context.Context {
+	return withIdentity(context.Background(), &mtls.Identity{
+		TrustDomain: "cluster.local",
+		Role:        mtls.RoleIPAMClient,
+		Name:        name,
+	})
+}
+
+func TestLeaseLifecycle(t *testing.T) {
+	s := newTestServer()
+	ctx := clientContext("vm-provisioner")
+
+	lease, err := s.Allocate(ctx, &AllocateRequest{Network: "network1", Workload: "vm1"})
+	if err != nil {
+		t.Fatalf("unable to allocate: %v", err)
+	}
+	if lease.Network != "network1" || lease.Workload != "vm1" || len(lease.Addresses) != 1 {
+		t.Fatalf("unexpected lease %+v", *lease)
+	}
+	if address := lease.Addresses[0]; address.IP != "192.168.0.1/24" || address.Gateway != "192.168.0.254" ||
+		address.Version != "4" || len(address.MAC) == 0 {
+		t.Errorf("unexpected address %+v", address)
+	}
+	if expire := time.Until(lease.ExpireTime); expire <= 59*time.Minute || expire > time.Hour {
+		t.Errorf("expect lease to expire in an hour, but got %v", lease.ExpireTime)
+	}
+
+	if _, err = s.Allocate(ctx, &AllocateRequest{Network: "network1", DurationSeconds: 48 * 3600}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expect duration exceeding max to be invalid, but got %v", err)
+	}
+
+	// leases are isolated between clients
+	if _, err = s.Get(clientContext("other"), &LeaseRequest{Name: lease.Name}); status.Code(err) != codes.NotFound {
+		t.Errorf("expect lease of others not to be found, but got %v", err)
+	}
+	if leaseList, err := s.List(clientContext("other"), &ListRequest{}); err != nil || len(leaseList.Leases) != 0 {
+		t.Errorf("expect no lease of others, but got %v, %v", leaseList, err)
+	}
+
+	renewed, err := s.Renew(ctx, &LeaseRequest{Name: lease.Name, DurationSeconds: 7200})
+	if err != nil {
+		t.Fatalf("unable to renew: %v", err)
+	}
+	if !renewed.ExpireTime.After(lease.ExpireTime.Add(59 * time.Minute)) {
+		t.Errorf("expect lease to be extended, but got %v", renewed.ExpireTime)
+	}
+
+	if _, err = s.Release(ctx, &LeaseRequest{Name: lease.Name}); err != nil {
+		t.Fatalf("unable to release: %v", err)
+	}
+	if _, err = s.Get(ctx, &LeaseRequest{Name: lease.Name}); status.Code(err) != codes.NotFound {
+		t.Errorf("expect released lease not to be found, but got %v", err)
+	}
+	assertIPInstancesReleased(t, s, lease.Name)
+}
+
+func TestReleaseExpiredLeases(t *testing.T) {
+	s := newTestServer()
+	ctx := clientContext("vm-provisioner")
+
+	lease, err := s.Allocate(ctx, &AllocateRequest{Network: "network1", DurationSeconds: 60})
+	if err != nil {
+		t.Fatalf("unable to allocate: %v", err)
+	}
+
+	// renewed two minutes ago
+	expiredLease := &coordinationv1.Lease{}
+	if err = s.Client.Get(ctx, client.ObjectKey{Namespace: "kube-system", Name: lease.Name}, expiredLease); err != nil {
+		t.Fatalf("unable to get lease: %v", err)
+	}
+	renewTime := metav1.NewMicroTime(time.Now().Add(-2 * time.Minute))
+	expiredLease.Spec.RenewTime = &renewTime
+	if err = s.Client.Update(ctx, expiredLease); err != nil {
+		t.Fatalf("unable to update lease: %v", err)
+	}
+
+	if _, err = s.Renew(ctx, &LeaseRequest{Name: lease.Name}); status.Code(err) != codes.NotFound {
+		t.Errorf("expect expired lease not to be renewed, but got %v", err)
+	}
+
+	s.releaseExpiredLeases(ctx)
+	if _, err = s.Get(ctx, &LeaseRequest{Name: lease.Name}); status.Code(err) != codes.NotFound {
+		t.Errorf("expect expired lease to be released, but got %v", err)
+	}
+	assertIPInstancesReleased(t, s, lease.Name)
+}
+
+func assertIPInstancesReleased(t *testing.T, s *Server, leaseName string) {
+	ipInstanceList := &networkingv1.IPInstanceList{}
+	if err := s.Client.List(context.Background(), ipInstanceList,
+		client.MatchingLabels{constants.LabelIPLease: leaseName}); err != nil {
+		t.Fatalf("unable to list ip instances: %v", err)
+	}
+	// ip instances are kept by finalizer until addresses are released by IPInstance controller
+	for _, ipInstance := range ipInstanceList.Items {
+		if ipInstance.DeletionTimestamp == nil {
+			t.Errorf("expect ip instance %s to be deleted", ipInstance.Name)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package service
+
+import "time"
+
+type AllocateRequest struct {
+	// Network is the name of network to allocate addresses from
+	Network string `json:"network"`
+	// Subnets are names of specified subnets, the ipv4 one goes first if both are specified
+	Subnets []string `json:"subnets,omitempty"`
+	// IPFamily is one of IPv4Only, IPv6Only and DualStack, default ip family of cluster is used if empty
+	IPFamily string `json:"ipFamily,omitempty"`
+	// Workload describes the workload using addresses, e.g., name of VM, only for tracing
+	Workload string `json:"workload,omitempty"`
+	// DurationSeconds is the duration of lease, default duration of server is used if zero
+	DurationSeconds int32 `json:"durationSeconds,omitempty"`
+}
+
+type LeaseRequest struct {
+	// Name is the name of lease
+	Name string `json:"name"`
+	// DurationSeconds is the new duration of lease on renewal, the current one is kept if zero
+	DurationSeconds int32 `json:"durationSeconds,omitempty"`
+}
+
+type ListRequest struct{}
+
+type Empty struct{}
+
+// Lease holds addresses until it expires or is released
+type Lease struct {
+	Name       string    `json:"name"`
+	Workload   string    `json:"workload,omitempty"`
+	Network    string    `json:"network"`
+	Addresses  []Address `json:"addresses"`
+	ExpireTime time.Time `json:"expireTime"`
+}
+
+type LeaseList struct {
+	Leases []Lease `json:"leases"`
+}
+
+type Address struct {
+	Subnet string `json:"subnet"`
+	// Version is "4" or "6"
+	Version string `json:"version"`
+	// IP is in the format of CIDR, e.g., 192.168.0.10/24
+	IP      string `json:"ip"`
+	Gateway string `json:"gateway,omitempty"`
+	MAC     string `json:"mac"`
+}
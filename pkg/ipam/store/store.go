@@ -23,6 +23,7 @@ import (
 	"github.com/alibaba/hybridnet/pkg/utils/transform"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/errors"
@@ -99,6 +100,21 @@ func (s *crdStore) ReCouple(ctx context.Context, pod *corev1.Pod, IPs []*ipamtyp
 	// parse options
 	options.ApplyOptions(opts)
 
+	// Existing ip instances, usually retained ones of a recreated stateful pod, are fetched
+	// once and will be rebound in place.
+	var existingIPInstances = make(map[string]*networkingv1.IPInstance, len(IPs))
+	for _, ip := range IPs {
+		var ipInstance *networkingv1.IPInstance
+		if ipInstance, err = s.getIPInstance(ctx, pod.Namespace, ip); err != nil {
+			// ignore the not-found error
+			if err = client.IgnoreNotFound(err); err == nil {
+				continue
+			}
+			return
+		}
+		existingIPInstances[ipInstance.Name] = ipInstance
+	}
+
 	// If MAC address is specified in options, use it as unified MAC address.
 	if !options.SpecifiedMACAddress.IsEmpty() {
 		unifiedMACAddr = string(options.SpecifiedMACAddress)
@@ -108,18 +124,11 @@ func (s *crdStore) ReCouple(ctx context.Context, pod *corev1.Pod, IPs []*ipamtyp
 	// multi IPs, a unified MAC address should be reused.
 	if options.SpecifiedMACAddress.IsEmpty() && len(IPs) > 1 {
 		for _, ip := range IPs {
-			var ipInstance *networkingv1.IPInstance
-			if ipInstance, err = s.getIPInstance(ctx, pod.Namespace, ip); err != nil {
-				// ignore the not-found error
-				if err = client.IgnoreNotFound(err); err == nil {
-					continue
-				}
-				return
-			}
-
 			// fetch valid MAC address from created ip instances and try to reuse it
-			unifiedMACAddr = ipInstance.Spec.Address.MAC
-			break
+			if ipInstance, exist := existingIPInstances[utils.ToDNSLabelFormatName(ip)]; exist {
+				unifiedMACAddr = ipInstance.Spec.Address.MAC
+				break
+			}
 		}
 	}
 
@@ -129,6 +138,16 @@ func (s *crdStore) ReCouple(ctx context.Context, pod *corev1.Pod, IPs []*ipamtyp
 	}
 
 	for _, ip := range IPs {
+		if ipInstance, exist := existingIPInstances[utils.ToDNSLabelFormatName(ip)]; exist {
+			if err = s.rebindIPInstance(ctx, ipInstance, pod, ip, unifiedMACAddr, options.OwnerReference, options.AdditionalLabels); err == nil {
+				continue
+			}
+			// cached ip instance is stale, fall back to the slow path
+			if !apierrors.IsConflict(err) && !apierrors.IsNotFound(err) {
+				return
+			}
+		}
+
 		if _, err = s.createOrUpdateIPInstance(ctx, pod, ip, unifiedMACAddr, options.OwnerReference, options.AdditionalLabels); err != nil {
 			return
 		}
@@ -217,7 +236,19 @@ func (s *crdStore) createIPInstance(ctx context.Context, pod *corev1.Pod, ip *ip
 
 	assembleIPInstance(ipInstance, ip, pod, macAddr, ownerReference, additionalLabels)
 
-	return ipInstance, s.Create(ctx, ipInstance)
+	if err = s.Create(ctx, ipInstance); err != nil {
+		return ipInstance, err
+	}
+
+	// status is a subresource, the topology decision has to be recorded after creation
+	if len(ip.Topology) > 0 {
+		patchBody := fmt.Sprintf(`{"status":{"topology":%q}}`, ip.Topology)
+		if err = s.Status().Patch(ctx, ipInstance, client.RawPatch(types.MergePatchType, []byte(patchBody))); err != nil {
+			return ipInstance, fmt.Errorf("failed to record topology on ip instance %s/%s: %v", ipInstance.Namespace, ipInstance.Name, err)
+		}
+	}
+
+	return ipInstance, nil
 }
 
 // createOrUpdateIPInstance will create or update an IPInstance by pod info, ip info and mac address
@@ -242,6 +273,18 @@ func (s *crdStore) createOrUpdateIPInstance(ctx context.Context, pod *corev1.Pod
 	return ipInstance, err
 }
 
+// rebindIPInstance redirects an existing IPInstance to pod with a single patch, which is
+// guarded by resource version, so that a stale IPInstance will never be overwritten
+func (s *crdStore) rebindIPInstance(ctx context.Context, ipInstance *networkingv1.IPInstance, pod *corev1.Pod, ip *ipamtypes.IP, macAddr string, ownerReference *metav1.OwnerReference, additionalLabels map[string]string) error {
+	if !ipInstance.DeletionTimestamp.IsZero() {
+		return fmt.Errorf("ip instance %s/%s is deleting, can not be updated", ipInstance.Namespace, ipInstance.Name)
+	}
+
+	var rebound = ipInstance.DeepCopy()
+	assembleIPInstance(rebound, ip, pod, macAddr, ownerReference, additionalLabels)
+	return s.Patch(ctx, rebound, client.MergeFromWithOptions(ipInstance, client.MergeFromWithOptimisticLock{}))
+}
+
 // deleteIPInstance will remove an IPInstance by namespace and name
 func (s *crdStore) deleteIPInstance(ctx context.Context, namespace, name string) error {
 	return s.Delete(ctx, &networkingv1.IPInstance{
@@ -277,6 +320,10 @@ func (s *crdStore) reserveIPInstance(ctx context.Context, ipInstance *networking
 			ipInstance.Spec.Binding.PodUID = ""
 			delete(ipInstance.Labels, constants.LabelNode)
 			delete(ipInstance.Labels, constants.LabelPodUID)
+			if ipInstance.Labels == nil {
+				ipInstance.Labels = map[string]string{}
+			}
+			ipInstance.Labels[constants.LabelPhase] = networkingv1.IPInstancePhaseReserved
 
 			// clean pod name if set
 			if dropPodName {
@@ -318,8 +365,8 @@ func (s *crdStore) removeFinalizerOfIPInstance(ctx context.Context, namespace, n
 // assembleIPInstance will assemble the spec of IPInstance with provided inputs,
 // including pod, ip info and mac address
 func assembleIPInstance(ipIns *networkingv1.IPInstance, ip *ipamtypes.IP, pod *corev1.Pod, macAddr string, ownerReference *metav1.OwnerReference, additionalLabels map[string]string) {
-	// finalizer will block deletion for garbage collection
-	ipIns.Finalizers = []string{constants.FinalizerIPAllocated}
+	// finalizers will block deletion for garbage collection and dataplane cleanup of daemon
+	ipIns.Finalizers = []string{constants.FinalizerIPAllocated, constants.FinalizerDataplaneCleanup}
 
 	// labels will help quick search by label-selecting
 	if len(ipIns.Labels) == 0 {
@@ -331,6 +378,7 @@ func assembleIPInstance(ipIns *networkingv1.IPInstance, ip *ipamtypes.IP, pod *c
 	ipIns.Labels[constants.LabelNode] = pod.Spec.NodeName
 	ipIns.Labels[constants.LabelPod] = transform.TransferPodNameForLabelValue(pod.Name)
 	ipIns.Labels[constants.LabelPodUID] = string(pod.UID)
+	ipIns.Labels[constants.LabelPhase] = networkingv1.IPInstancePhaseAllocated
 
 	// additional labels will be patched
 	// NOTICE: additional labels will take higher priority than built-in lables
@@ -392,10 +440,11 @@ func assembleIPInstance(ipIns *networkingv1.IPInstance, ip *ipamtypes.IP, pod *c
 		PodName:  pod.Name,
 	}
 
-	// index is the serial number of a stateful workload
-	if strategy.OwnByStatefulWorkload(pod) {
-		ipIns.Spec.Binding.Stateful = &networkingv1.StatefulInfo{
-			Index: utils.IntToInt32P(utils.GetIndexFromName(pod.Name)),
+	// index is the serial number of a stateful workload, which only makes sense for pods named by ordinals
+	if strategy.IsStatefulWorkloadReference(owner) {
+		ipIns.Spec.Binding.Stateful = &networkingv1.StatefulInfo{}
+		if strategy.IsOrdinalWorkloadReference(owner) {
+			ipIns.Spec.Binding.Stateful.Index = utils.IntToInt32P(utils.GetIndexFromName(pod.Name))
 		}
 	}
 }
@@ -0,0 +1,219 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package store
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/ipam/strategy"
+	ipamtypes "github.com/alibaba/hybridnet/pkg/ipam/types"
+	"github.com/alibaba/hybridnet/pkg/ipam/utils"
+)
+
+func init() {
+	strategy.StatefulWorkloadKinds = []string{"StatefulSet", "Rollout.argoproj.io"}
+}
+
+func TestReCoupleRebindInPlace(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = networkingv1.AddToScheme(scheme)
+
+	isController := true
+	owner := metav1.OwnerReference{
+		APIVersion: "apps/v1",
+		Kind:       "StatefulSet",
+		Name:       "sts",
+		UID:        "sts-uid",
+		Controller: &isController,
+	}
+
+	ip := &ipamtypes.IP{
+		Address: &net.IPNet{
+			IP:   net.ParseIP("192.168.0.10"),
+			Mask: net.CIDRMask(24, 32),
+		},
+		Subnet:  "subnet",
+		Network: "network",
+	}
+
+	// retained ip instance of the deleted pod
+	retained := &networkingv1.IPInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            utils.ToDNSLabelFormatName(ip),
+			Namespace:       "default",
+			OwnerReferences: []metav1.OwnerReference{owner},
+			Labels: map[string]string{
+				constants.LabelPod: "sts-0",
+			},
+		},
+		Spec: networkingv1.IPInstanceSpec{
+			Network: "network",
+			Subnet:  "subnet",
+			Address: networkingv1.Address{
+				Version: networkingv1.IPv4,
+				IP:      "192.168.0.10/24",
+				MAC:     "00:00:00:00:00:01",
+			},
+			Binding: networkingv1.Binding{
+				PodName: "sts-0",
+			},
+		},
+	}
+
+	ctx := context.Background()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(retained).Build()
+	s := NewCRDStore(c)
+
+	before := &networkingv1.IPInstance{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(retained), before); err != nil {
+		t.Fatalf("unable to get ip instance: %v", err)
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "sts-0",
+			Namespace:       "default",
+			UID:             "new-pod-uid",
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+		Spec: corev1.PodSpec{
+			NodeName: "node1",
+		},
+	}
+
+	if err := s.ReCouple(ctx, pod, []*ipamtypes.IP{ip}); err != nil {
+		t.Fatalf("unable to recouple: %v", err)
+	}
+
+	after := &networkingv1.IPInstance{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(retained), after); err != nil {
+		t.Fatalf("unable to get ip instance: %v", err)
+	}
+
+	if after.Spec.Binding.PodUID != pod.UID || after.Spec.Binding.NodeName != "node1" {
+		t.Errorf("ip instance is not rebound to new pod: %+v", after.Spec.Binding)
+	}
+	if after.Labels[constants.LabelNode] != "node1" || after.Labels[constants.LabelPodUID] != string(pod.UID) {
+		t.Errorf("labels are not rebound to new pod: %v", after.Labels)
+	}
+	if after.Spec.Address.MAC != retained.Spec.Address.MAC {
+		t.Errorf("expect MAC %s to be kept, but got %s", retained.Spec.Address.MAC, after.Spec.Address.MAC)
+	}
+	if after.ResourceVersion == before.ResourceVersion {
+		t.Errorf("ip instance is not updated")
+	}
+}
+
+func TestCoupleStatefulInfo(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = networkingv1.AddToScheme(scheme)
+
+	isController := true
+	newOwner := func(apiVersion, kind, name string) *metav1.OwnerReference {
+		return &metav1.OwnerReference{
+			APIVersion: apiVersion,
+			Kind:       kind,
+			Name:       name,
+			UID:        types.UID(name + "-uid"),
+			Controller: &isController,
+		}
+	}
+
+	tests := []struct {
+		name           string
+		podName        string
+		owner          *metav1.OwnerReference
+		expectStateful bool
+		expectIndex    *int32
+	}{
+		{
+			name:           "pod of statefulset",
+			podName:        "sts-2",
+			owner:          newOwner("apps/v1", "StatefulSet", "sts"),
+			expectStateful: true,
+			expectIndex:    utils.IntToInt32P(2),
+		},
+		{
+			name:           "pod of stateful workload without ordinal names",
+			podName:        "rollout-7d9f8c-24567",
+			owner:          newOwner("argoproj.io/v1alpha1", "Rollout", "rollout"),
+			expectStateful: true,
+		},
+		{
+			name:    "pod of deployment",
+			podName: "deploy-7d9f8c-24567",
+		},
+	}
+
+	for _, test := range tests {
+		ip := &ipamtypes.IP{
+			Address: &net.IPNet{
+				IP:   net.ParseIP("192.168.0.10"),
+				Mask: net.CIDRMask(24, 32),
+			},
+			Subnet:  "subnet",
+			Network: "network",
+		}
+
+		ctx := context.Background()
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		s := NewCRDStore(c)
+
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            test.podName,
+				Namespace:       "default",
+				UID:             "pod-uid",
+				OwnerReferences: []metav1.OwnerReference{*newOwner("apps/v1", "ReplicaSet", "rs")},
+			},
+		}
+
+		var opts []ipamtypes.CoupleOption
+		if test.owner != nil {
+			opts = append(opts, ipamtypes.OwnerReference(*test.owner))
+		}
+		if err := s.Couple(ctx, pod, []*ipamtypes.IP{ip}, opts...); err != nil {
+			t.Fatalf("test %s fails, unable to couple: %v", test.name, err)
+		}
+
+		ipInstance := &networkingv1.IPInstance{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: utils.ToDNSLabelFormatName(ip)}, ipInstance); err != nil {
+			t.Fatalf("test %s fails, unable to get ip instance: %v", test.name, err)
+		}
+
+		stateful := ipInstance.Spec.Binding.Stateful
+		if (stateful != nil) != test.expectStateful {
+			t.Errorf("test %s fails, expected stateful %v but got %+v", test.name, test.expectStateful, stateful)
+			continue
+		}
+		if stateful != nil && !reflect.DeepEqual(stateful.Index, test.expectIndex) {
+			t.Errorf("test %s fails, expected index %v but got %v", test.name, test.expectIndex, stateful.Index)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/alibaba/hybridnet/pkg/constants"
+)
+
+const (
+	jobKind     = "Job"
+	cronJobKind = "CronJob"
+)
+
+// OwnByIPRetainedJob returns whether pod is controlled by a Job and explicitly asks for ip retention,
+// Job pods never retain their IPs by default.
+func OwnByIPRetainedJob(pod *v1.Pod) bool {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil || ref.Kind != jobKind || ref.APIVersion != batchv1.SchemeGroupVersion.String() {
+		return false
+	}
+
+	retain, err := strconv.ParseBool(pod.Annotations[constants.AnnotationIPRetain])
+	return err == nil && retain
+}
+
+// ResolveJobIPRetainOwner returns the owner which retained IPs of a Job pod belong to, the CronJob
+// of Job if exists, or the Job itself. Nil will be returned if pod does not ask for ip retention.
+func ResolveJobIPRetainOwner(ctx context.Context, pod *v1.Pod, c client.Reader) (*metav1.OwnerReference, error) {
+	if !OwnByIPRetainedJob(pod) {
+		return nil, nil
+	}
+
+	jobRef := metav1.GetControllerOf(pod)
+	retainOwner := jobRef.DeepCopy()
+
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{
+		Name:      jobRef.Name,
+		Namespace: pod.Namespace,
+	}, job); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get job %v/%v: %v", pod.Namespace, jobRef.Name, err)
+		}
+	} else if job.UID == jobRef.UID {
+		if cronJobRef := metav1.GetControllerOf(job); cronJobRef != nil && cronJobRef.Kind == cronJobKind {
+			retainOwner = cronJobRef.DeepCopy()
+		}
+	}
+
+	// retained IPs should never block deletion of Jobs and CronJobs
+	ifBlockOwnerDeletion := false
+	retainOwner.BlockOwnerDeletion = &ifBlockOwnerDeletion
+	return retainOwner, nil
+}
+
+// JobIPRetainTTLOfPod returns how long IPs of a failed Job pod will be reserved, which is specified
+// by pod annotation or flag, and bounded by JobIPRetainMaxTTL.
+func JobIPRetainTTLOfPod(pod *v1.Pod) time.Duration {
+	ttl := JobIPRetainTTL
+	if ttlStr := pod.Annotations[constants.AnnotationIPRetainTTL]; len(ttlStr) > 0 {
+		if parsed, err := time.ParseDuration(ttlStr); err == nil && parsed > 0 {
+			ttl = parsed
+		}
+	}
+
+	if JobIPRetainMaxTTL > 0 && ttl > JobIPRetainMaxTTL {
+		return JobIPRetainMaxTTL
+	}
+	return ttl
+}
@@ -19,13 +19,16 @@ package strategy
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
-
-	"k8s.io/apimachinery/pkg/types"
+	"time"
 
 	"github.com/spf13/pflag"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -34,8 +37,12 @@ import (
 )
 
 var (
-	StatefulWorkloadKinds []string
-	DefaultIPRetain       bool
+	StatefulWorkloadKinds  []string
+	DefaultIPRetain        bool
+	OwnerReferenceMaxDepth int
+
+	JobIPRetainTTL    time.Duration
+	JobIPRetainMaxTTL time.Duration
 )
 
 var (
@@ -46,11 +53,22 @@ var (
 func init() {
 	pflag.BoolVar(&DefaultIPRetain, "default-ip-retain", true, "Whether pod IP of stateful workloads will be retained by default.")
 	pflag.StringSliceVar(&StatefulWorkloadKinds, "stateful-workload-kinds", []string{"StatefulSet"}, `stateful workload kinds to use strategic IP allocation,`+
-		`eg: "StatefulSet,AdvancedStatefulSet", default: "StatefulSet"`)
+		`eg: "StatefulSet,AdvancedStatefulSet,Rollout.argoproj.io", a kind can be qualified by its group, default: "StatefulSet"`)
+	pflag.IntVar(&OwnerReferenceMaxDepth, "owner-reference-max-depth", 1, "The max depth of controller owner reference chain "+
+		"to resolve stateful workloads, 1 means only the direct controller of pod will be checked.")
+	pflag.DurationVar(&JobIPRetainTTL, "job-ip-retain-ttl", 10*time.Minute, "The default time that IPs of failed "+
+		"ip-retained Job pods are reserved for the next pods of the same Job or CronJob.")
+	pflag.DurationVar(&JobIPRetainMaxTTL, "job-ip-retain-max-ttl", 24*time.Hour, "The max time that IPs of failed "+
+		"ip-retained Job pods can be reserved, which bounds the TTL specified by pods.")
 }
 
 func OwnByStatefulWorkload(obj client.Object) bool {
-	ref := metav1.GetControllerOf(obj)
+	return IsStatefulWorkloadReference(metav1.GetControllerOf(obj))
+}
+
+// IsStatefulWorkloadReference returns whether the owner reference points to a known stateful workload,
+// the kind can be matched by either "Kind" or "Kind.group".
+func IsStatefulWorkloadReference(ref *metav1.OwnerReference) bool {
 	if ref == nil {
 		return false
 	}
@@ -61,7 +79,76 @@ func OwnByStatefulWorkload(obj client.Object) bool {
 		logger.Info("Adding known stateful workloads", "Kinds", StatefulWorkloadKinds)
 	})
 
-	return statefulWorkloadKindSet.Has(ref.Kind)
+	if statefulWorkloadKindSet.Has(ref.Kind) {
+		return true
+	}
+
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil || len(gv.Group) == 0 {
+		return false
+	}
+	return statefulWorkloadKindSet.Has(strings.Join([]string{ref.Kind, gv.Group}, "."))
+}
+
+// ordinalWorkloadKinds are the stateful workloads whose pods are named by ordinals
+var ordinalWorkloadKinds = sets.NewString("StatefulSet", "AdvancedStatefulSet")
+
+// IsOrdinalWorkloadReference returns whether the owner reference points to a stateful workload whose pods
+// are named by ordinals, e.g., StatefulSet. Retained IPs of these workloads are bound to pod names, while
+// the ones of other stateful workloads (e.g., Argo Rollouts) are retained in a pool of the workload.
+func IsOrdinalWorkloadReference(ref *metav1.OwnerReference) bool {
+	return IsStatefulWorkloadReference(ref) && ordinalWorkloadKinds.Has(ref.Kind)
+}
+
+// ResolveStatefulWorkloadOwner walks up the controller owner reference chain of obj, at most
+// OwnerReferenceMaxDepth levels, and returns the topmost controller which is a known stateful
+// workload. Nil will be returned if no stateful workload found in chain.
+func ResolveStatefulWorkloadOwner(ctx context.Context, obj client.Object, c client.Reader) (*metav1.OwnerReference, error) {
+	var (
+		statefulOwner *metav1.OwnerReference
+		current       = obj
+	)
+
+	for depth := 0; depth < OwnerReferenceMaxDepth; depth++ {
+		ref := metav1.GetControllerOf(current)
+		if ref == nil {
+			break
+		}
+
+		if IsStatefulWorkloadReference(ref) {
+			statefulOwner = ref.DeepCopy()
+			if depth > 0 {
+				// indirect owner should not block its deletion
+				ifBlockOwnerDeletion := false
+				statefulOwner.BlockOwnerDeletion = &ifBlockOwnerDeletion
+			}
+		}
+
+		if depth+1 >= OwnerReferenceMaxDepth {
+			break
+		}
+
+		owner := &metav1.PartialObjectMetadata{}
+		owner.SetGroupVersionKind(schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind))
+		if err := c.Get(ctx, types.NamespacedName{
+			Name:      ref.Name,
+			Namespace: obj.GetNamespace(),
+		}, owner); err != nil {
+			if apierrors.IsNotFound(err) {
+				// owner has gone, the chain ends here
+				break
+			}
+			return nil, fmt.Errorf("failed to get owner %v %v/%v: %v", ref.Kind, obj.GetNamespace(), ref.Name, err)
+		}
+
+		// owner was recreated with the same name, not the one we are looking for
+		if owner.GetUID() != ref.UID {
+			break
+		}
+		current = owner
+	}
+
+	return statefulOwner, nil
 }
 
 func OwnByVirtualMachineInstance(obj client.Object) (bool, string) {
@@ -0,0 +1,322 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package strategy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/alibaba/hybridnet/pkg/constants"
+)
+
+func init() {
+	StatefulWorkloadKinds = []string{"StatefulSet", "Rollout.argoproj.io"}
+}
+
+func controllerRef(apiVersion, kind, name string, uid types.UID) metav1.OwnerReference {
+	isController := true
+	return metav1.OwnerReference{
+		APIVersion: apiVersion,
+		Kind:       kind,
+		Name:       name,
+		UID:        uid,
+		Controller: &isController,
+	}
+}
+
+func TestIsStatefulWorkloadReference(t *testing.T) {
+	tests := []struct {
+		name     string
+		ref      *metav1.OwnerReference
+		expected bool
+	}{
+		{
+			"nil reference",
+			nil,
+			false,
+		},
+		{
+			"kind only",
+			&metav1.OwnerReference{APIVersion: "apps/v1", Kind: "StatefulSet"},
+			true,
+		},
+		{
+			"kind qualified by group",
+			&metav1.OwnerReference{APIVersion: "argoproj.io/v1alpha1", Kind: "Rollout"},
+			true,
+		},
+		{
+			"kind in another group",
+			&metav1.OwnerReference{APIVersion: "foo.io/v1", Kind: "Rollout"},
+			false,
+		},
+		{
+			"unknown kind",
+			&metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet"},
+			false,
+		},
+	}
+
+	for _, test := range tests {
+		if result := IsStatefulWorkloadReference(test.ref); result != test.expected {
+			t.Errorf("test %s fails, expected %v but got %v", test.name, test.expected, result)
+		}
+	}
+}
+
+func TestIsOrdinalWorkloadReference(t *testing.T) {
+	tests := []struct {
+		name     string
+		ref      *metav1.OwnerReference
+		expected bool
+	}{
+		{
+			"nil reference",
+			nil,
+			false,
+		},
+		{
+			"statefulset",
+			&metav1.OwnerReference{APIVersion: "apps/v1", Kind: "StatefulSet"},
+			true,
+		},
+		{
+			"argo rollout",
+			&metav1.OwnerReference{APIVersion: "argoproj.io/v1alpha1", Kind: "Rollout"},
+			false,
+		},
+	}
+
+	for _, test := range tests {
+		if result := IsOrdinalWorkloadReference(test.ref); result != test.expected {
+			t.Errorf("test %s fails, expected %v but got %v", test.name, test.expected, result)
+		}
+	}
+}
+
+func TestResolveStatefulWorkloadOwner(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	replicaSet := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "rs",
+			Namespace:       "default",
+			UID:             "rs-uid",
+			OwnerReferences: []metav1.OwnerReference{controllerRef("argoproj.io/v1alpha1", "Rollout", "rollout", "rollout-uid")},
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "pod",
+			Namespace:       "default",
+			OwnerReferences: []metav1.OwnerReference{controllerRef("apps/v1", "ReplicaSet", "rs", "rs-uid")},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(replicaSet).Build()
+
+	tests := []struct {
+		name     string
+		maxDepth int
+		expected string
+	}{
+		{
+			"direct controller only",
+			1,
+			"",
+		},
+		{
+			"resolve through replica set",
+			2,
+			"rollout",
+		},
+		{
+			"deeper than chain",
+			5,
+			"rollout",
+		},
+	}
+
+	for _, test := range tests {
+		OwnerReferenceMaxDepth = test.maxDepth
+		owner, err := ResolveStatefulWorkloadOwner(context.Background(), pod, c)
+		if err != nil {
+			t.Errorf("test %s fails: %v", test.name, err)
+			continue
+		}
+
+		var ownerName string
+		if owner != nil {
+			ownerName = owner.Name
+			if owner.BlockOwnerDeletion == nil || *owner.BlockOwnerDeletion {
+				t.Errorf("test %s fails, indirect owner should not block owner deletion", test.name)
+			}
+		}
+		if ownerName != test.expected {
+			t.Errorf("test %s fails, expected owner %q but got %q", test.name, test.expected, ownerName)
+		}
+	}
+}
+
+func TestResolveJobIPRetainOwner(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	cronJobOwnedJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "cron-job",
+			Namespace:       "default",
+			UID:             "cron-job-uid",
+			OwnerReferences: []metav1.OwnerReference{controllerRef("batch/v1", "CronJob", "cron", "cron-uid")},
+		},
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "job",
+			Namespace: "default",
+			UID:       "job-uid",
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cronJobOwnedJob, job).Build()
+
+	tests := []struct {
+		name     string
+		owner    metav1.OwnerReference
+		retain   string
+		expected string
+	}{
+		{
+			"job pod without retain",
+			controllerRef("batch/v1", "Job", "job", "job-uid"),
+			"",
+			"",
+		},
+		{
+			"job pod not retained",
+			controllerRef("batch/v1", "Job", "job", "job-uid"),
+			"false",
+			"",
+		},
+		{
+			"retained job pod",
+			controllerRef("batch/v1", "Job", "job", "job-uid"),
+			"true",
+			"job",
+		},
+		{
+			"retained cron job pod",
+			controllerRef("batch/v1", "Job", "cron-job", "cron-job-uid"),
+			"true",
+			"cron",
+		},
+		{
+			"retained pod of deleted job",
+			controllerRef("batch/v1", "Job", "gone", "gone-uid"),
+			"true",
+			"gone",
+		},
+		{
+			"retained pod of replica set",
+			controllerRef("apps/v1", "ReplicaSet", "rs", "rs-uid"),
+			"true",
+			"",
+		},
+	}
+
+	for _, test := range tests {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "pod",
+				Namespace:       "default",
+				OwnerReferences: []metav1.OwnerReference{test.owner},
+				Annotations:     map[string]string{constants.AnnotationIPRetain: test.retain},
+			},
+		}
+
+		owner, err := ResolveJobIPRetainOwner(context.Background(), pod, c)
+		if err != nil {
+			t.Errorf("test %s fails: %v", test.name, err)
+			continue
+		}
+
+		var ownerName string
+		if owner != nil {
+			ownerName = owner.Name
+			if owner.BlockOwnerDeletion == nil || *owner.BlockOwnerDeletion {
+				t.Errorf("test %s fails, job owner should not block owner deletion", test.name)
+			}
+		}
+		if ownerName != test.expected {
+			t.Errorf("test %s fails, expected owner %q but got %q", test.name, test.expected, ownerName)
+		}
+	}
+}
+
+func TestJobIPRetainTTLOfPod(t *testing.T) {
+	JobIPRetainTTL = 10 * time.Minute
+	JobIPRetainMaxTTL = time.Hour
+
+	tests := []struct {
+		name     string
+		ttl      string
+		expected time.Duration
+	}{
+		{
+			"default",
+			"",
+			10 * time.Minute,
+		},
+		{
+			"specified",
+			"30m",
+			30 * time.Minute,
+		},
+		{
+			"bounded",
+			"48h",
+			time.Hour,
+		},
+		{
+			"invalid",
+			"forever",
+			10 * time.Minute,
+		},
+	}
+
+	for _, test := range tests {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{constants.AnnotationIPRetainTTL: test.ttl},
+			},
+		}
+		if ttl := JobIPRetainTTLOfPod(pod); ttl != test.expected {
+			t.Errorf("test %s fails, expected ttl %v but got %v", test.name, test.expected, ttl)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+// AllocationFailureReason is the category of an allocation failure, it is the same in events,
+// webhook denials and metrics, so that failures can be told apart without matching messages
+type AllocationFailureReason string
+
+const (
+	FailureSubnetExhausted        AllocationFailureReason = "SubnetExhausted"
+	FailureSubnetFrozen           AllocationFailureReason = "SubnetFrozen"
+	FailureSubnetCordoned         AllocationFailureReason = "SubnetCordoned"
+	FailureIPConflict             AllocationFailureReason = "IPConflict"
+	FailureIPNotInSubnet          AllocationFailureReason = "IPNotInSubnet"
+	FailureNetworkNotCoveringNode AllocationFailureReason = "NetworkNotCoveringNode"
+	FailureNetworkNotVisible      AllocationFailureReason = "NetworkNotVisible"
+	FailureNetworkCordoned        AllocationFailureReason = "NetworkCordoned"
+	FailureNetworkNotFound        AllocationFailureReason = "NetworkNotFound"
+	FailureSubnetNotFound         AllocationFailureReason = "SubnetNotFound"
+	FailureInvalidRequest         AllocationFailureReason = "InvalidRequest"
+	FailureUnknown                AllocationFailureReason = "Unknown"
+)
+
+// AllocationError is a typed allocation failure, errors of the same reason match each other
+// by errors.Is, so a detailed one can still be checked against the sentinel of its reason
+type AllocationError struct {
+	Reason  AllocationFailureReason
+	Message string
+}
+
+func (e *AllocationError) Error() string {
+	return e.Message
+}
+
+func (e *AllocationError) Is(target error) bool {
+	t, ok := target.(*AllocationError)
+	return ok && t.Reason == e.Reason
+}
+
+// NewAllocationError creates an allocation error of reason with a formatted message
+func NewAllocationError(reason AllocationFailureReason, format string, args ...interface{}) error {
+	return &AllocationError{
+		Reason:  reason,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+var (
+	ErrSubnetExhausted        = NewAllocationError(FailureSubnetExhausted, "no available ip in subnet")
+	ErrIPConflict             = NewAllocationError(FailureIPConflict, "ip is already in use")
+	ErrNetworkNotCoveringNode = NewAllocationError(FailureNetworkNotCoveringNode, "network does not cover node")
+	ErrNetworkNotVisible      = NewAllocationError(FailureNetworkNotVisible, "network is not visible to namespace")
+)
+
+// FailureReasonOf returns the reason of the first allocation error wrapped in err, Unknown
+// if err is not caused by any allocation error
+func FailureReasonOf(err error) AllocationFailureReason {
+	var allocationError *AllocationError
+	if errors.As(err, &allocationError) {
+		return allocationError.Reason
+	}
+	return FailureUnknown
+}
+
+// DescribeAllocationFailure prefixes the message of err with its failure reason
+func DescribeAllocationFailure(err error) string {
+	return fmt.Sprintf("%s: %v", FailureReasonOf(err), err)
+}
@@ -0,0 +1,61 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestFailureReasonOf(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedReason AllocationFailureReason
+		expectedIs     error
+	}{
+		{
+			"wrapped sentinel",
+			fmt.Errorf("fail to allocate: %w", ErrNoAvailableSubnet),
+			FailureSubnetExhausted,
+			ErrSubnetExhausted,
+		},
+		{
+			"detailed error",
+			NewAllocationError(FailureNetworkNotCoveringNode, "unable to find underlay network for node %s", "node1"),
+			FailureNetworkNotCoveringNode,
+			ErrNetworkNotCoveringNode,
+		},
+		{
+			"untyped error",
+			fmt.Errorf("fail to allocate: %v", ErrIPConflict),
+			FailureUnknown,
+			nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if reason := FailureReasonOf(test.err); reason != test.expectedReason {
+				t.Errorf("test %s fails: expected reason %s but got %s", test.name, test.expectedReason, reason)
+			}
+			if test.expectedIs != nil && !errors.Is(test.err, test.expectedIs) {
+				t.Errorf("test %s fails: expected %v to be %v", test.name, test.err, test.expectedIs)
+			}
+		})
+	}
+}
@@ -17,16 +17,18 @@
 package types
 
 import (
-	"errors"
-	"fmt"
+	"hash/fnv"
 	"net"
+	"sort"
+	"time"
 
 	"github.com/alibaba/hybridnet/pkg/utils"
 )
 
 var (
-	ErrNotFoundNetwork = errors.New("network not found")
-	ErrEmptySubnetName = errors.New("subnet name must be specified")
+	ErrNotFoundNetwork = NewAllocationError(FailureNetworkNotFound, "network not found")
+	ErrEmptySubnetName = NewAllocationError(FailureInvalidRequest, "subnet name must be specified")
+	ErrCordonedNetwork = NewAllocationError(FailureNetworkCordoned, "network is cordoned, no new addresses will be allocated")
 )
 
 func NewNetworkSet() NetworkSet {
@@ -58,6 +60,55 @@ func (n NetworkSet) CheckNetworkByType(networkName string, networkType NetworkTy
 	return false
 }
 
+// ElectDefaultNetwork elects the default network of a type among the eligible networks with default policies,
+// only the candidates of the highest priority are elected and one of them is picked in proportion to weights
+// by the hash of key, so the same key always gets the same network unless candidates change. Cordoned networks
+// are never elected, and empty will be returned if no candidate is found.
+func (n NetworkSet) ElectDefaultNetwork(networkType NetworkType, key string, eligible func(networkName string) bool) string {
+	var candidates []*Network
+	for name, network := range n {
+		if network.Type != networkType || network.Default == nil || network.Cordoned || network.Default.Weight <= 0 {
+			continue
+		}
+		if eligible != nil && !eligible(name) {
+			continue
+		}
+
+		switch {
+		case len(candidates) == 0 || network.Default.Priority == candidates[0].Default.Priority:
+			candidates = append(candidates, network)
+		case network.Default.Priority > candidates[0].Default.Priority:
+			candidates = []*Network{network}
+		}
+	}
+
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	// map iteration is random, sort candidates to keep election stable
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Name < candidates[j].Name
+	})
+
+	var totalWeight uint32
+	for _, candidate := range candidates {
+		totalWeight += uint32(candidate.Default.Weight)
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(key))
+	point := hash.Sum32() % totalWeight
+
+	for _, candidate := range candidates {
+		if point < uint32(candidate.Default.Weight) {
+			return candidate.Name
+		}
+		point -= uint32(candidate.Default.Weight)
+	}
+	return candidates[len(candidates)-1].Name
+}
+
 func (n NetworkSet) ListNetworkToNames() []string {
 	var names []string
 	for name := range n {
@@ -83,6 +134,21 @@ func (n *Network) AddSubnet(subnet *Subnet, ips IPSet) error {
 	return n.IPv4Subnets.AddSubnet(subnet, n.NetID, ips)
 }
 
+// ReplaceSubnet refreshes a single subnet of network without touching others
+func (n *Network) ReplaceSubnet(subnet *Subnet, ips IPSet) error {
+	if subnet.IsIPv6() {
+		n.IPv4Subnets.RemoveSubnet(subnet.Name)
+		return n.IPv6Subnets.ReplaceSubnet(subnet, n.NetID, ips)
+	}
+	n.IPv6Subnets.RemoveSubnet(subnet.Name)
+	return n.IPv4Subnets.ReplaceSubnet(subnet, n.NetID, ips)
+}
+
+func (n *Network) RemoveSubnet(subnetName string) {
+	n.IPv4Subnets.RemoveSubnet(subnetName)
+	n.IPv6Subnets.RemoveSubnet(subnetName)
+}
+
 func (n *Network) GetSubnetByName(subnetName string) (*Subnet, error) {
 	if len(subnetName) == 0 {
 		return nil, ErrEmptySubnetName
@@ -103,7 +169,7 @@ func (n *Network) GetSubnetByNameOrIP(subnetName, ip string) (*Subnet, error) {
 	// validate IP
 	parsedIP := net.ParseIP(ip)
 	if parsedIP == nil {
-		return nil, fmt.Errorf("%s is not a valid IP", ip)
+		return nil, NewAllocationError(FailureInvalidRequest, "%s is not a valid IP", ip)
 	}
 
 	if parsedIP.To4() == nil {
@@ -118,7 +184,13 @@ func (n *Network) GetIPv4SubnetByNameOrAvailable(subnetName string) (sn *Subnet,
 			return nil, err
 		}
 		if sn.IsIPv6() {
-			return nil, fmt.Errorf("assigned subnet %s is not IPv4 family", subnetName)
+			return nil, NewAllocationError(FailureInvalidRequest, "assigned subnet %s is not IPv4 family", subnetName)
+		}
+		if err = sn.CordonedError(); err != nil {
+			return nil, err
+		}
+		if err = sn.FrozenAt(time.Now()); err != nil {
+			return nil, err
 		}
 		return
 	}
@@ -132,7 +204,13 @@ func (n *Network) GetIPv6SubnetByNameOrAvailable(subnetName string) (sn *Subnet,
 			return nil, err
 		}
 		if !sn.IsIPv6() {
-			return nil, fmt.Errorf("assigned subnet %s is not IPv6 family", subnetName)
+			return nil, NewAllocationError(FailureInvalidRequest, "assigned subnet %s is not IPv6 family", subnetName)
+		}
+		if err = sn.CordonedError(); err != nil {
+			return nil, err
+		}
+		if err = sn.FrozenAt(time.Now()); err != nil {
+			return nil, err
 		}
 		return
 	}
@@ -140,6 +218,26 @@ func (n *Network) GetIPv6SubnetByNameOrAvailable(subnetName string) (sn *Subnet,
 	return n.IPv6Subnets.GetAvailableSubnet()
 }
 
+// GetIPv4SubnetByNameOrTopology gets the specified IPv4 subnet, or an available one which
+// prefers to match node labels by topology
+func (n *Network) GetIPv4SubnetByNameOrTopology(subnetName string, nodeLabels map[string]string) (sn *Subnet, err error) {
+	if len(subnetName) > 0 {
+		return n.GetIPv4SubnetByNameOrAvailable(subnetName)
+	}
+
+	return n.IPv4Subnets.GetAvailableSubnetByTopology(nodeLabels)
+}
+
+// GetIPv6SubnetByNameOrTopology gets the specified IPv6 subnet, or an available one which
+// prefers to match node labels by topology
+func (n *Network) GetIPv6SubnetByNameOrTopology(subnetName string, nodeLabels map[string]string) (sn *Subnet, err error) {
+	if len(subnetName) > 0 {
+		return n.GetIPv6SubnetByNameOrAvailable(subnetName)
+	}
+
+	return n.IPv6Subnets.GetAvailableSubnetByTopology(nodeLabels)
+}
+
 func (n *Network) GetDualStackSubnetsByNameOrAvailable(v4SubnetName, v6SubnetName string) (v4Subnet *Subnet, v6Subnet *Subnet, err error) {
 	if v4Subnet, err = n.GetIPv4SubnetByNameOrAvailable(v4SubnetName); err != nil {
 		return
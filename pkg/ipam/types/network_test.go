@@ -0,0 +1,121 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package types
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNetworkSet_ElectDefaultNetwork(t *testing.T) {
+	newDefaultNetwork := func(name string, networkType NetworkType, priority, weight int32) *Network {
+		network := NewNetwork(name, nil, "", "", networkType)
+		network.Default = &DefaultPolicy{
+			Priority: priority,
+			Weight:   weight,
+		}
+		return network
+	}
+
+	cordonedNetwork := newDefaultNetwork("cordoned", Overlay, 10, 1)
+	cordonedNetwork.Cordoned = true
+
+	networkSet := NewNetworkSet()
+	for _, network := range []*Network{
+		NewNetwork("implicit", nil, "", "", Overlay),
+		newDefaultNetwork("low", Overlay, 0, 1),
+		newDefaultNetwork("high-a", Overlay, 1, 1),
+		newDefaultNetwork("high-b", Overlay, 1, 3),
+		newDefaultNetwork("underlay", Underlay, 5, 1),
+		cordonedNetwork,
+	} {
+		networkSet.RefreshNetwork(network.Name, network)
+	}
+
+	tests := []struct {
+		name        string
+		networkType NetworkType
+		eligible    func(string) bool
+		expected    []string
+	}{
+		{
+			"highest priority",
+			Overlay,
+			nil,
+			[]string{"high-a", "high-b"},
+		},
+		{
+			"highest priority of eligible networks",
+			Overlay,
+			func(networkName string) bool {
+				return networkName == "low" || networkName == "implicit"
+			},
+			[]string{"low"},
+		},
+		{
+			"filter by type",
+			Underlay,
+			nil,
+			[]string{"underlay"},
+		},
+		{
+			"no candidate",
+			GlobalBGP,
+			nil,
+			[]string{""},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var elected = map[string]int{}
+			for i := 0; i < 1000; i++ {
+				elected[networkSet.ElectDefaultNetwork(test.networkType, fmt.Sprintf("default/pod-%d", i), test.eligible)]++
+			}
+
+			if len(elected) != len(test.expected) {
+				t.Fatalf("test %s fails: expected %v but got %v", test.name, test.expected, elected)
+			}
+			for _, networkName := range test.expected {
+				if elected[networkName] == 0 {
+					t.Fatalf("test %s fails: expected %v but got %v", test.name, test.expected, elected)
+				}
+			}
+		})
+	}
+
+	t.Run("stable election", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprintf("default/pod-%d", i)
+			if first, second := networkSet.ElectDefaultNetwork(Overlay, key, nil), networkSet.ElectDefaultNetwork(Overlay, key, nil); first != second {
+				t.Fatalf("election of %s is not stable: %s and %s", key, first, second)
+			}
+		}
+	})
+
+	t.Run("weighted spread", func(t *testing.T) {
+		var elected = map[string]int{}
+		for i := 0; i < 10000; i++ {
+			elected[networkSet.ElectDefaultNetwork(Overlay, fmt.Sprintf("default/pod-%d", i), nil)]++
+		}
+
+		// weights of high-a and high-b are 1:3
+		if ratio := float64(elected["high-b"]) / float64(elected["high-a"]); ratio < 2.5 || ratio > 3.5 {
+			t.Fatalf("expected weighted spread of 1:3 but got %v", elected)
+		}
+	})
+}
@@ -31,6 +31,11 @@ type RefreshOptions struct {
 
 	// Networks is the specified network list to be refreshed
 	Networks []string
+
+	// Subnets limits the refreshing of specified networks to these subnets, other
+	// subnets keep their in-memory usage, networks which are not cached yet will
+	// still be refreshed entirely
+	Subnets []string
 }
 
 func (r *RefreshOptions) ApplyOptions(opts []RefreshOption) {
@@ -45,6 +50,12 @@ func (r RefreshNetworks) ApplyToRefresh(options *RefreshOptions) {
 	options.Networks = r
 }
 
+type RefreshSubnets []string
+
+func (r RefreshSubnets) ApplyToRefresh(options *RefreshOptions) {
+	options.Subnets = r
+}
+
 type RefreshForceAll bool
 
 func (r RefreshForceAll) ApplyToRefresh(options *RefreshOptions) {
@@ -59,6 +70,10 @@ type AllocateOption interface {
 type AllocateOptions struct {
 	// Subnets is the specified subnet list where IP should be allocated from
 	Subnets []string
+
+	// NodeLabels is the labels of node where pod is scheduled, subnets matching
+	// these labels by topology will be preferred if no subnet is specified
+	NodeLabels map[string]string
 }
 
 func (a *AllocateOptions) ApplyOptions(opts []AllocateOption) {
@@ -73,6 +88,12 @@ func (a AllocateSubnets) ApplyToAllocate(options *AllocateOptions) {
 	options.Subnets = a
 }
 
+type AllocateNodeLabels map[string]string
+
+func (a AllocateNodeLabels) ApplyToAllocate(options *AllocateOptions) {
+	options.NodeLabels = a
+}
+
 type AssignOption interface {
 	ApplyToAssign(options *AssignOptions)
 }
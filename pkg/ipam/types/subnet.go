@@ -17,18 +17,20 @@
 package types
 
 import (
-	"errors"
 	"fmt"
 	"net"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/alibaba/hybridnet/pkg/utils"
 )
 
 var (
-	ErrNoAvailableSubnet      = errors.New("no available subnet")
-	ErrNotFoundSubnet         = errors.New("subnet not found")
-	ErrNotFoundAssignedIP     = errors.New("assigned ip not found")
-	ErrNotAvailableAssignedIP = errors.New("assigned ip is not available")
+	ErrNoAvailableSubnet      = NewAllocationError(FailureSubnetExhausted, "no available subnet")
+	ErrNotFoundSubnet         = NewAllocationError(FailureSubnetNotFound, "subnet not found")
+	ErrNotFoundAssignedIP     = NewAllocationError(FailureIPNotInSubnet, "assigned ip not found")
+	ErrNotAvailableAssignedIP = NewAllocationError(FailureIPConflict, "assigned ip is not available")
 )
 
 func NewSubnetSlice(lastAllocatedSubnet string) *SubnetSlice {
@@ -58,6 +60,52 @@ func (s *SubnetSlice) AddSubnet(subnet *Subnet, parentNetID *uint32, ips IPSet)
 	return nil
 }
 
+// ReplaceSubnet replaces the existing subnet with the same name and keeps its position,
+// or appends subnet if it does not exist
+func (s *SubnetSlice) ReplaceSubnet(subnet *Subnet, parentNetID *uint32, ips IPSet) error {
+	index, exist := s.SubnetIndexMap[subnet.Name]
+	if !exist {
+		return s.AddSubnet(subnet, parentNetID, ips)
+	}
+
+	if err := subnet.Canonicalize(); err != nil {
+		return err
+	}
+
+	if err := subnet.Sync(parentNetID, ips); err != nil {
+		return err
+	}
+
+	s.Subnets[index] = subnet
+	return nil
+}
+
+// RemoveSubnet removes subnet by name and keeps the order of others
+func (s *SubnetSlice) RemoveSubnet(name string) {
+	index, exist := s.SubnetIndexMap[name]
+	if !exist {
+		return
+	}
+
+	currentSubnetName := s.CurrentSubnetName()
+
+	s.Subnets = append(s.Subnets[:index], s.Subnets[index+1:]...)
+	s.SubnetCount = len(s.Subnets)
+	s.SubnetIndexMap = make(map[string]int, s.SubnetCount)
+	for i, subnet := range s.Subnets {
+		s.SubnetIndexMap[subnet.Name] = i
+	}
+
+	// keep the current subnet unchanged if it still exists
+	if currentIndex, ok := s.SubnetIndexMap[currentSubnetName]; ok {
+		s.SubnetIndex = currentIndex
+	} else if s.SubnetCount > 0 {
+		s.SubnetIndex = index % s.SubnetCount
+	} else {
+		s.SubnetIndex = 0
+	}
+}
+
 func (s *SubnetSlice) GetSubnet(name string) (*Subnet, error) {
 	if subnetIndex, exist := s.SubnetIndexMap[name]; exist {
 		return s.Subnets[subnetIndex], nil
@@ -71,19 +119,53 @@ func (s *SubnetSlice) GetAvailableSubnet() (*Subnet, error) {
 		return nil, ErrNoAvailableSubnet
 	}
 
-	lastIndex := s.SubnetIndex
+	var (
+		now       = time.Now()
+		frozenErr error
+		lastIndex = s.SubnetIndex
+	)
 	for {
-		if s.Subnets[s.SubnetIndex].IsAvailable() {
-			return s.Subnets[s.SubnetIndex], nil
+		if subnet := s.Subnets[s.SubnetIndex]; subnet.IsAvailable() {
+			err := subnet.FrozenAt(now)
+			if err == nil {
+				return subnet, nil
+			}
+			if frozenErr == nil {
+				frozenErr = err
+			}
 		}
 
 		s.SubnetIndex = (s.SubnetIndex + 1) % s.SubnetCount
 		if s.SubnetIndex == lastIndex {
+			// frozen subnets are more helpful to be told than exhausted ones
+			if frozenErr != nil {
+				return nil, frozenErr
+			}
 			return nil, ErrNoAvailableSubnet
 		}
 	}
 }
 
+// GetAvailableSubnetByTopology picks the available subnet matching node labels by topology
+// in a round-robin way, and falls back to any available subnet if none matches
+func (s *SubnetSlice) GetAvailableSubnetByTopology(nodeLabels map[string]string) (*Subnet, error) {
+	if len(nodeLabels) == 0 {
+		return s.GetAvailableSubnet()
+	}
+
+	now := time.Now()
+	for i := 0; i < s.SubnetCount; i++ {
+		index := (s.SubnetIndex + i) % s.SubnetCount
+		if s.Subnets[index].MatchTopology(nodeLabels) && s.Subnets[index].IsAvailable() &&
+			s.Subnets[index].FrozenAt(now) == nil {
+			s.SubnetIndex = index
+			return s.Subnets[index], nil
+		}
+	}
+
+	return s.GetAvailableSubnet()
+}
+
 func (s *SubnetSlice) GetSubnetByIP(ip string) (*Subnet, error) {
 	for _, subnet := range s.Subnets {
 		if subnet.Contains(net.ParseIP(ip)) {
@@ -312,7 +394,34 @@ func (s *Subnet) Overlap(s1 *Subnet) bool {
 }
 
 func (s *Subnet) IsAvailable() bool {
-	return s.AvailableIPs.Count() > s.UsingIPCount() && !s.Private
+	return s.AvailableIPs.Count() > s.UsingIPCount() && !s.Private && !s.Cordoned
+}
+
+// CordonedError returns an error of FailureSubnetCordoned if subnet is cordoned
+func (s *Subnet) CordonedError() error {
+	if !s.Cordoned {
+		return nil
+	}
+	return NewAllocationError(FailureSubnetCordoned, "subnet %s is cordoned, no new addresses will be allocated", s.Name)
+}
+
+// FrozenAt returns an error of FailureSubnetFrozen if t is in any freeze window of subnet,
+// new ips should not be allocated from a frozen subnet
+func (s *Subnet) FrozenAt(t time.Time) error {
+	for _, window := range s.FreezeWindows {
+		start, ok := window.Schedule.ActiveWindow(t.In(window.Location), window.Duration)
+		if !ok {
+			continue
+		}
+
+		message := fmt.Sprintf("subnet %s is frozen for allocation until %s", s.Name,
+			start.Add(window.Duration).Format(time.RFC3339))
+		if len(window.Reason) > 0 {
+			message = fmt.Sprintf("%s: %s", message, window.Reason)
+		}
+		return NewAllocationError(FailureSubnetFrozen, "%s", message)
+	}
+	return nil
 }
 
 // UsingIPCount will count the IP which are being used, but
@@ -326,6 +435,7 @@ func (s *Subnet) Usage() *Usage {
 		Total:          uint32(s.AvailableIPs.Count()),
 		Used:           uint32(s.UsingIPCount()),
 		Available:      uint32(s.AvailableIPs.Count() - s.UsingIPCount()),
+		Reserved:       uint32(s.ReservedIPCount),
 		LastAllocation: s.AvailableIPs.Current(),
 	}
 }
@@ -376,6 +486,10 @@ func (s *Subnet) Assign(podName, podNamespace, ip string, forced bool) (*IP, err
 		return nil, ErrNotFoundAssignedIP
 	}
 
+	if s.IsInReservedRanges(ip) {
+		return nil, ErrNotAvailableAssignedIP
+	}
+
 	switch {
 	case !s.UsingIPs.Has(ip):
 		s.UsingIPs.Add(ip, &IP{
@@ -402,16 +516,84 @@ func (s *Subnet) Assign(podName, podNamespace, ip string, forced bool) (*IP, err
 	return s.UsingIPs.Get(ip), nil
 }
 
+// Track adds the address in use if it is unknown, it fails if the address is in use by another pod
+// or is a reserved one
+func (s *Subnet) Track(ip *IP) error {
+	address := ip.Address.IP.String()
+	if !s.Contains(ip.Address.IP) {
+		return ErrNotFoundAssignedIP
+	}
+
+	if existing := s.UsingIPs.Get(address); existing != nil {
+		if existing.PodNamespace != ip.PodNamespace || existing.PodName != ip.PodName {
+			return ErrNotAvailableAssignedIP
+		}
+		return nil
+	}
+
+	s.UsingIPs.Add(address, ip)
+	return nil
+}
+
+// AssignedIPs returns the addresses in use except the unassigned reserved ones, which will be
+// generated again by Sync
+func (s *Subnet) AssignedIPs() IPSet {
+	ips := NewIPSet()
+	for address, ip := range s.UsingIPs {
+		if s.IsReservedIP(address) && len(ip.PodName) == 0 {
+			continue
+		}
+		ips.Add(address, ip)
+	}
+	return ips
+}
+
 func (s *Subnet) IsReservedIP(ip string) bool {
 	_, found := s.ReservedList[ip]
 	return found
 }
 
+// IsInReservedRanges checks if ip is in any reserved range of subnet
+func (s *Subnet) IsInReservedRanges(ip string) bool {
+	addr := net.ParseIP(ip)
+	for _, r := range s.ReservedRanges {
+		if utils.Cmp(addr, r.Start) >= 0 && utils.Cmp(addr, r.End) <= 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *Subnet) IsBlackIP(ip string) bool {
 	_, found := s.BlackList[ip]
 	return found
 }
 
+// MatchTopology checks whether all the topology labels of subnet are matched by node labels,
+// subnet without topology never matches
+func (s *Subnet) MatchTopology(nodeLabels map[string]string) bool {
+	if len(s.Topology) == 0 {
+		return false
+	}
+
+	for key, value := range s.Topology {
+		if nodeValue, exist := nodeLabels[key]; !exist || nodeValue != value {
+			return false
+		}
+	}
+	return true
+}
+
+// TopologyString returns the topology labels of subnet as sorted "key=value" pairs
+func (s *Subnet) TopologyString() string {
+	var pairs []string
+	for key, value := range s.Topology {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
 func (s *Subnet) IsIPv6() bool {
 	return s.IPv6
 }
@@ -17,8 +17,12 @@
 package types
 
 import (
+	"errors"
 	"net"
 	"testing"
+	"time"
+
+	"github.com/alibaba/hybridnet/pkg/utils/cron"
 )
 
 func TestSubnetSlice_CurrentSubnetName(t *testing.T) {
@@ -84,6 +88,54 @@ func TestSubnet_AllocateNext(t *testing.T) {
 	}
 }
 
+func TestSubnet_AssignInReservedRanges(t *testing.T) {
+	_, cidr, _ := net.ParseCIDR("192.168.0.0/24")
+	subnet := NewSubnet("test", "fake", nil, nil, nil, nil, cidr,
+		map[string]struct{}{"192.168.0.10": {}, "192.168.0.20": {}}, nil, nil, false, false)
+	subnet.ReservedRanges = []*IPRange{{Start: net.ParseIP("192.168.0.20"), End: net.ParseIP("192.168.0.29")}}
+	if err := subnet.Canonicalize(); err != nil {
+		t.Fatalf("fail to canonicalize: %v", err)
+	}
+	if err := subnet.Sync(nil, NewIPSet()); err != nil {
+		t.Fatalf("fail to sync: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		ip        string
+		forced    bool
+		expectErr error
+	}{
+		{
+			name: "address out of reserved list",
+			ip:   "192.168.0.30",
+		},
+		{
+			name:   "reserved address by force",
+			ip:     "192.168.0.10",
+			forced: true,
+		},
+		{
+			name:      "address of reserved range by force",
+			ip:        "192.168.0.20",
+			forced:    true,
+			expectErr: ErrNotAvailableAssignedIP,
+		},
+		{
+			name:      "address of reserved range out of reserved list",
+			ip:        "192.168.0.25",
+			expectErr: ErrNotAvailableAssignedIP,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := subnet.Assign("pod", "ns", test.ip, test.forced); !errors.Is(err, test.expectErr) {
+				t.Errorf("test %s fails, expected error %v but got %v", test.name, test.expectErr, err)
+			}
+		})
+	}
+}
+
 func TestSubnet_SyncSubnetStartsWithZeroByteIP(t *testing.T) {
 	var err error
 	var cidr *net.IPNet
@@ -98,3 +150,152 @@ func TestSubnet_SyncSubnetStartsWithZeroByteIP(t *testing.T) {
 		t.Fatalf("fail to sync: %v", err)
 	}
 }
+
+func TestSubnetSlice_GetAvailableSubnetByTopology(t *testing.T) {
+	newSubnet := func(name, cidrStr string, topology map[string]string) *Subnet {
+		ip, cidr, _ := net.ParseCIDR(cidrStr)
+		subnet := NewSubnet(name, "fake", nil, nil, nil, ip, cidr, nil, nil, nil, false, false)
+		subnet.Topology = topology
+		return subnet
+	}
+
+	ss := NewSubnetSlice("")
+	for _, subnet := range []*Subnet{
+		newSubnet("no-topology", "192.168.0.1/24", nil),
+		newSubnet("zone-a", "192.168.1.1/24", map[string]string{"topology.kubernetes.io/zone": "a"}),
+		newSubnet("zone-b-rack-1", "192.168.2.1/24", map[string]string{
+			"topology.kubernetes.io/zone": "b",
+			"rack":                        "1",
+		}),
+	} {
+		if err := ss.AddSubnet(subnet, nil, NewIPSet()); err != nil {
+			t.Fatalf("fail to add subnet %s: %v", subnet.Name, err)
+		}
+	}
+
+	tests := []struct {
+		name           string
+		nodeLabels     map[string]string
+		expectedSubnet string
+		expectedMatch  bool
+	}{
+		{
+			"no node labels",
+			nil,
+			"no-topology",
+			false,
+		},
+		{
+			"matched zone",
+			map[string]string{"topology.kubernetes.io/zone": "a", "rack": "1"},
+			"zone-a",
+			true,
+		},
+		{
+			"matched zone and rack",
+			map[string]string{"topology.kubernetes.io/zone": "b", "rack": "1"},
+			"zone-b-rack-1",
+			true,
+		},
+		{
+			"partially matched topology",
+			map[string]string{"topology.kubernetes.io/zone": "b", "rack": "2"},
+			"no-topology",
+			false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ss.SubnetIndex = 0
+			subnet, err := ss.GetAvailableSubnetByTopology(test.nodeLabels)
+			if err != nil {
+				t.Fatalf("test %s fails: %v", test.name, err)
+			}
+			if subnet.Name != test.expectedSubnet {
+				t.Errorf("test %s fails: expected %s but got %s", test.name, test.expectedSubnet, subnet.Name)
+			}
+			if subnet.MatchTopology(test.nodeLabels) != test.expectedMatch {
+				t.Errorf("test %s fails: expected match %v", test.name, test.expectedMatch)
+			}
+		})
+	}
+
+	if topology := ss.Subnets[2].TopologyString(); topology != "rack=1,topology.kubernetes.io/zone=b" {
+		t.Errorf("unexpected topology string %s", topology)
+	}
+}
+
+func TestSubnetSlice_GetAvailableSubnetWithFreezeWindows(t *testing.T) {
+	newFreezeWindow := func(spec string) *FreezeWindow {
+		schedule, err := cron.Parse(spec)
+		if err != nil {
+			t.Fatalf("fail to parse schedule %s: %v", spec, err)
+		}
+		return &FreezeWindow{Schedule: schedule, Duration: time.Minute, Location: time.UTC, Reason: "maintenance"}
+	}
+	newSubnet := func(name, cidrStr string, windows ...*FreezeWindow) *Subnet {
+		ip, cidr, _ := net.ParseCIDR(cidrStr)
+		subnet := NewSubnet(name, "fake", nil, nil, nil, ip, cidr, nil, nil, nil, false, false)
+		subnet.FreezeWindows = windows
+		return subnet
+	}
+
+	// "* * * * *" lasting for one minute is always active, and "0 0 30 2 *" never happens
+	frozen := newSubnet("frozen", "192.168.0.1/24", newFreezeWindow("* * * * *"))
+	if err := frozen.FrozenAt(time.Now()); !errors.Is(err, NewAllocationError(FailureSubnetFrozen, "")) {
+		t.Fatalf("expected subnet to be frozen but got %v", err)
+	}
+
+	ss := NewSubnetSlice("")
+	for _, subnet := range []*Subnet{
+		frozen,
+		newSubnet("never-frozen", "192.168.1.1/24", newFreezeWindow("0 0 30 2 *")),
+	} {
+		if err := ss.AddSubnet(subnet, nil, NewIPSet()); err != nil {
+			t.Fatalf("fail to add subnet %s: %v", subnet.Name, err)
+		}
+	}
+
+	ss.SubnetIndex = 0
+	subnet, err := ss.GetAvailableSubnet()
+	if err != nil {
+		t.Fatalf("fail to get available subnet: %v", err)
+	}
+	if subnet.Name != "never-frozen" {
+		t.Errorf("expected subnet never-frozen but got %s", subnet.Name)
+	}
+
+	ss.RemoveSubnet("never-frozen")
+	if _, err = ss.GetAvailableSubnet(); FailureReasonOf(err) != FailureSubnetFrozen {
+		t.Errorf("expected reason %s but got %v", FailureSubnetFrozen, err)
+	}
+}
+
+func TestNetwork_GetCordonedSubnet(t *testing.T) {
+	network := NewNetwork("fake", nil, "", "", Underlay)
+	for name, cidrStr := range map[string]string{
+		"cordoned":  "192.168.0.1/24",
+		"available": "192.168.1.1/24",
+	} {
+		ip, cidr, _ := net.ParseCIDR(cidrStr)
+		subnet := NewSubnet(name, "fake", nil, nil, nil, ip, cidr, nil, nil, nil, false, false)
+		subnet.Cordoned = name == "cordoned"
+		if err := network.AddSubnet(subnet, NewIPSet()); err != nil {
+			t.Fatalf("fail to add subnet %s: %v", name, err)
+		}
+	}
+
+	if _, err := network.GetIPv4SubnetByNameOrAvailable("cordoned"); FailureReasonOf(err) != FailureSubnetCordoned {
+		t.Errorf("expected reason %s for specified cordoned subnet but got %v", FailureSubnetCordoned, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		subnet, err := network.GetIPv4SubnetByNameOrAvailable("")
+		if err != nil {
+			t.Fatalf("fail to get available subnet: %v", err)
+		}
+		if subnet.Name != "available" {
+			t.Errorf("expected subnet available but got %s", subnet.Name)
+		}
+	}
+}
@@ -16,7 +16,12 @@
 
 package types
 
-import "net"
+import (
+	"net"
+	"time"
+
+	"github.com/alibaba/hybridnet/pkg/utils/cron"
+)
 
 const (
 	IPStatusAllocated = "Allocated"
@@ -28,8 +33,19 @@ type Network struct {
 	Name        string
 	NetID       *uint32
 	Type        NetworkType
+	Cordoned    bool
 	IPv4Subnets *SubnetSlice
 	IPv6Subnets *SubnetSlice
+	// Default is the policy of electing this network as the default one of its type,
+	// nil means this network is never elected
+	Default *DefaultPolicy
+}
+
+// DefaultPolicy orders the candidates of default network by Priority, and spreads pods
+// across the elected candidates by Weight
+type DefaultPolicy struct {
+	Priority int32
+	Weight   int32
 }
 
 type NetworkSet map[string]*Network
@@ -49,6 +65,15 @@ type Subnet struct {
 	LastAllocatedIP net.IP
 	Private         bool
 	IPv6            bool
+	// Cordoned subnet is never chosen automatically, and can not be specified for new allocations either
+	Cordoned bool
+	// Topology is the node labels which this subnet prefers to serve
+	Topology map[string]string
+	// FreezeWindows are the recurring windows during which no new ip is allocated from this subnet
+	FreezeWindows []*FreezeWindow
+	// ReservedRanges are taken by consumers other than pods, whose addresses are also in reserved list,
+	// but never assigned to pods even by force
+	ReservedRanges []*IPRange
 
 	// Status fields
 	// `Sync` method will initialize these
@@ -57,6 +82,20 @@ type Subnet struct {
 	ReservedIPCount int
 }
 
+// FreezeWindow is a recurring window starting at every time matched by Schedule in Location
+type FreezeWindow struct {
+	Schedule *cron.Schedule
+	Duration time.Duration
+	Location *time.Location
+	Reason   string
+}
+
+// IPRange is an inclusive range of addresses
+type IPRange struct {
+	Start net.IP
+	End   net.IP
+}
+
 type SubnetSlice struct {
 	Subnets             []*Subnet
 	SubnetIndexMap      map[string]int
@@ -74,6 +113,9 @@ type IP struct {
 	PodName      string
 	PodNamespace string
 	Status       string
+	// Topology records the node topology by which the subnet of IP was selected,
+	// empty if the subnet was not selected by topology
+	Topology string
 }
 
 type IPSet map[string]*IP
@@ -20,6 +20,7 @@ type Usage struct {
 	Total          uint32
 	Used           uint32
 	Available      uint32
+	Reserved       uint32
 	LastAllocation string
 }
 
@@ -31,6 +32,7 @@ func (u *Usage) Add(in *Usage) {
 	u.Total += in.Total
 	u.Used += in.Used
 	u.Available += in.Available
+	u.Reserved += in.Reserved
 	if len(u.LastAllocation) == 0 {
 		u.LastAllocation = in.LastAllocation
 	}
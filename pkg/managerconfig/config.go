@@ -0,0 +1,213 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package managerconfig
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibaba/hybridnet/pkg/feature"
+)
+
+// keys of configuration in ConfigMap, which are the same as the names of flags
+const (
+	KeyKubeClientQPS              = "kube-client-qps"
+	KeyKubeClientBurst            = "kube-client-burst"
+	KeySubnetDrainBatchSize       = "subnet-drain-batch-size"
+	KeySubnetDrainInterval        = "subnet-drain-interval"
+	KeyDualStackRetrofitBatchSize = "dualstack-retrofit-batch-size"
+	KeyDualStackRetrofitInterval  = "dualstack-retrofit-interval"
+	KeyDataplaneCleanupTimeout    = "dataplane-cleanup-timeout"
+	KeyGarbageCollectionInterval  = "garbage-collection-interval"
+	KeyFeatureGates               = "feature-gates"
+	KeyPodStartupTimeout          = "pod-startup-timeout"
+)
+
+// RestartRequiredKeys are the flags of manager which can not be changed at runtime, e.g., the
+// workers of controllers are started once with their concurrency, they are refused in ConfigMap
+// with a hint of restart
+var RestartRequiredKeys = map[string]bool{
+	"controller-concurrency": true,
+}
+
+// Configuration is the set of manager configs which can be changed at runtime
+// without restarting manager
+type Configuration struct {
+	KubeClientQPS   float32
+	KubeClientBurst int
+
+	SubnetDrainBatchSize int
+	SubnetDrainInterval  time.Duration
+
+	DualStackRetrofitBatchSize int
+	DualStackRetrofitInterval  time.Duration
+
+	DataplaneCleanupTimeout time.Duration
+
+	GarbageCollectionInterval time.Duration
+
+	// PodStartupTimeout is the max duration for scheduled pods to get ip instances
+	// before being flagged as stuck, zero disables the watchdog
+	PodStartupTimeout time.Duration
+
+	// FeatureGates overrides the runtime toggleable features, in the same
+	// format of --feature-gates flag
+	FeatureGates string
+}
+
+// Store holds the current configuration, which is the defaults from flags
+// overridden by the data of ConfigMap
+type Store struct {
+	sync.RWMutex
+
+	defaults Configuration
+	current  Configuration
+	handlers []func(*Configuration)
+}
+
+func NewStore(defaults Configuration) *Store {
+	return &Store{
+		defaults: defaults,
+		current:  defaults,
+	}
+}
+
+// Get returns a copy of current configuration, nil store returns nil
+func (s *Store) Get() *Configuration {
+	if s == nil {
+		return nil
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	config := s.current
+	return &config
+}
+
+// OnChange registers a handler which will be called with new configuration after every change
+func (s *Store) OnChange(handler func(*Configuration)) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.handlers = append(s.handlers, handler)
+}
+
+// Apply overrides defaults with data and makes it the current configuration, unknown keys
+// will be returned as an error after all the known ones are applied, empty data means
+// reverting to defaults
+func (s *Store) Apply(data map[string]string) error {
+	config, err := parse(s.defaults, data)
+	if config == nil {
+		return err
+	}
+
+	s.Lock()
+	changed := s.current != *config
+	s.current = *config
+	handlers := s.handlers
+	s.Unlock()
+
+	if changed {
+		for _, handler := range handlers {
+			handler(config)
+		}
+	}
+	return err
+}
+
+func parse(defaults Configuration, data map[string]string) (*Configuration, error) {
+	var (
+		config          = defaults
+		unknowns        []string
+		restartRequired []string
+		err             error
+	)
+
+	for key, value := range data {
+		switch key {
+		case KeyKubeClientQPS:
+			var qps float64
+			if qps, err = strconv.ParseFloat(value, 32); err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %v", key, value, err)
+			}
+			config.KubeClientQPS = float32(qps)
+		case KeyKubeClientBurst:
+			if config.KubeClientBurst, err = strconv.Atoi(value); err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %v", key, value, err)
+			}
+		case KeySubnetDrainBatchSize:
+			if config.SubnetDrainBatchSize, err = strconv.Atoi(value); err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %v", key, value, err)
+			}
+		case KeySubnetDrainInterval:
+			if config.SubnetDrainInterval, err = time.ParseDuration(value); err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %v", key, value, err)
+			}
+		case KeyDualStackRetrofitBatchSize:
+			if config.DualStackRetrofitBatchSize, err = strconv.Atoi(value); err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %v", key, value, err)
+			}
+		case KeyDualStackRetrofitInterval:
+			if config.DualStackRetrofitInterval, err = time.ParseDuration(value); err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %v", key, value, err)
+			}
+		case KeyDataplaneCleanupTimeout:
+			if config.DataplaneCleanupTimeout, err = time.ParseDuration(value); err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %v", key, value, err)
+			}
+		case KeyGarbageCollectionInterval:
+			if config.GarbageCollectionInterval, err = time.ParseDuration(value); err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %v", key, value, err)
+			}
+		case KeyPodStartupTimeout:
+			if config.PodStartupTimeout, err = time.ParseDuration(value); err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %v", key, value, err)
+			}
+		case KeyFeatureGates:
+			if _, err = feature.ParseRuntimeFeatureGates(value); err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %v", key, value, err)
+			}
+			config.FeatureGates = value
+		default:
+			if RestartRequiredKeys[key] {
+				restartRequired = append(restartRequired, key)
+				continue
+			}
+			unknowns = append(unknowns, key)
+		}
+	}
+
+	var messages []string
+	if len(restartRequired) > 0 {
+		sort.Strings(restartRequired)
+		messages = append(messages, fmt.Sprintf("keys %v are ignored, which must be set by flags and take effect "+
+			"after manager restarts", restartRequired))
+	}
+	if len(unknowns) > 0 {
+		sort.Strings(unknowns)
+		messages = append(messages, fmt.Sprintf("unknown or non-reloadable keys %v are ignored", unknowns))
+	}
+	if len(messages) > 0 {
+		return &config, fmt.Errorf("%s", strings.Join(messages, ", "))
+	}
+	return &config, nil
+}
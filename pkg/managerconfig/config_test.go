@@ -0,0 +1,179 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package managerconfig
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+)
+
+var defaults = Configuration{
+	KubeClientQPS:             300,
+	KubeClientBurst:           600,
+	SubnetDrainBatchSize:      1,
+	SubnetDrainInterval:       30 * time.Second,
+	DataplaneCleanupTimeout:   5 * time.Minute,
+	GarbageCollectionInterval: time.Minute,
+}
+
+func TestStoreApply(t *testing.T) {
+	store := NewStore(defaults)
+
+	var notified int
+	store.OnChange(func(config *Configuration) {
+		notified++
+	})
+
+	if err := store.Apply(map[string]string{
+		KeyKubeClientQPS:              "100",
+		KeySubnetDrainBatchSize:       "5",
+		KeySubnetDrainInterval:        "10s",
+		KeyDualStackRetrofitBatchSize: "2",
+		KeyFeatureGates:               "VMIPRetain=true",
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	config := store.Get()
+	if config.KubeClientQPS != 100 || config.SubnetDrainBatchSize != 5 || config.SubnetDrainInterval != 10*time.Second {
+		t.Errorf("configuration is not applied: %+v", config)
+	}
+	if config.DualStackRetrofitBatchSize != 2 {
+		t.Errorf("dual-stack retrofit batch size is not applied: %+v", config)
+	}
+	if config.FeatureGates != "VMIPRetain=true" {
+		t.Errorf("feature gates are not applied: %+v", config)
+	}
+	if config.KubeClientBurst != defaults.KubeClientBurst {
+		t.Errorf("expect default burst %d, but got %d", defaults.KubeClientBurst, config.KubeClientBurst)
+	}
+	if notified != 1 {
+		t.Errorf("expect 1 notification, but got %d", notified)
+	}
+
+	// applying the same data does not notify handlers
+	if err := store.Apply(map[string]string{
+		KeyKubeClientQPS:              "100",
+		KeySubnetDrainBatchSize:       "5",
+		KeySubnetDrainInterval:        "10s",
+		KeyDualStackRetrofitBatchSize: "2",
+		KeyFeatureGates:               "VMIPRetain=true",
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if notified != 1 {
+		t.Errorf("expect 1 notification, but got %d", notified)
+	}
+
+	// empty data reverts to defaults
+	if err := store.Apply(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *store.Get() != defaults {
+		t.Errorf("expect defaults %+v, but got %+v", defaults, store.Get())
+	}
+	if notified != 2 {
+		t.Errorf("expect 2 notifications, but got %d", notified)
+	}
+}
+
+func TestStoreApplyInvalid(t *testing.T) {
+	store := NewStore(defaults)
+
+	if err := store.Apply(map[string]string{
+		KeySubnetDrainInterval: "ten seconds",
+	}); err == nil {
+		t.Errorf("expect error for invalid duration")
+	}
+	if *store.Get() != defaults {
+		t.Errorf("invalid data should not be applied, got %+v", store.Get())
+	}
+
+	if err := store.Apply(map[string]string{
+		KeyFeatureGates: "VMIPRetain=true,UnknownFeature=true",
+	}); err == nil {
+		t.Errorf("expect error for unknown feature")
+	}
+	if len(store.Get().FeatureGates) != 0 {
+		t.Errorf("invalid feature gates should not be applied, got %+v", store.Get())
+	}
+
+	if err := store.Apply(map[string]string{
+		KeyKubeClientBurst: "1000",
+		"metrics-port":     "8080",
+	}); err == nil {
+		t.Errorf("expect error for unknown keys")
+	}
+	if store.Get().KubeClientBurst != 1000 {
+		t.Errorf("known keys should be applied, got %+v", store.Get())
+	}
+
+	err := store.Apply(map[string]string{
+		"controller-concurrency": "Pod=10",
+	})
+	if err == nil || !strings.Contains(err.Error(), "restarts") {
+		t.Errorf("expect error with a hint of restart, but got %v", err)
+	}
+}
+
+func TestNilStore(t *testing.T) {
+	var store *Store
+	if store.Get() != nil {
+		t.Errorf("expect nil configuration of nil store")
+	}
+}
+
+func TestStoreApplyAndLog(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   map[string]string
+		expect string
+	}{
+		{
+			name:   "valid data",
+			data:   map[string]string{KeySubnetDrainBatchSize: "5"},
+			expect: "manager configuration applied",
+		},
+		{
+			name:   "invalid value",
+			data:   map[string]string{KeySubnetDrainBatchSize: "five"},
+			expect: "unable to apply manager configuration",
+		},
+		{
+			name:   "unknown key",
+			data:   map[string]string{"unknown": "1"},
+			expect: "unable to apply manager configuration",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var messages []string
+			logger := funcr.New(func(prefix, args string) {
+				messages = append(messages, args)
+			}, funcr.Options{})
+
+			NewStore(defaults).applyAndLog(test.data, logger)
+			if len(messages) != 1 || !strings.Contains(messages[0], test.expect) {
+				t.Errorf("test %s fails, expected one log of %q but got %v", test.name, test.expect, messages)
+			}
+		})
+	}
+}
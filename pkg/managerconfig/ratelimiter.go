@@ -0,0 +1,62 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package managerconfig
+
+import (
+	"context"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+var _ flowcontrol.RateLimiter = &AdjustableRateLimiter{}
+
+// AdjustableRateLimiter is a token bucket rate limiter for apiserver clients,
+// whose QPS and burst can be adjusted without rebuilding clients
+type AdjustableRateLimiter struct {
+	limiter *rate.Limiter
+}
+
+func NewAdjustableRateLimiter(qps float32, burst int) *AdjustableRateLimiter {
+	return &AdjustableRateLimiter{
+		limiter: rate.NewLimiter(rate.Limit(qps), burst),
+	}
+}
+
+// SetRate changes QPS and burst, it takes effect for the following requests
+func (r *AdjustableRateLimiter) SetRate(qps float32, burst int) {
+	r.limiter.SetLimit(rate.Limit(qps))
+	r.limiter.SetBurst(burst)
+}
+
+func (r *AdjustableRateLimiter) TryAccept() bool {
+	return r.limiter.Allow()
+}
+
+func (r *AdjustableRateLimiter) Accept() {
+	_ = r.limiter.Wait(context.Background())
+}
+
+func (r *AdjustableRateLimiter) Wait(ctx context.Context) error {
+	return r.limiter.Wait(ctx)
+}
+
+func (r *AdjustableRateLimiter) Stop() {}
+
+func (r *AdjustableRateLimiter) QPS() float32 {
+	return float32(r.limiter.Limit())
+}
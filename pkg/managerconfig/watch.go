@@ -0,0 +1,72 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package managerconfig
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Watch watches the specified ConfigMap and applies its data to store until context
+// is done, deletion of ConfigMap means reverting to defaults
+func (s *Store) Watch(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string, logger logr.Logger) {
+	WatchConfigMap(ctx, kubeClient, namespace, name, func(data map[string]string) {
+		s.applyAndLog(data, logger.WithValues("namespace", namespace, "name", name))
+	})
+}
+
+// applyAndLog applies data to store, the configuration is only logged as applied if nothing fails
+func (s *Store) applyAndLog(data map[string]string, logger logr.Logger) {
+	if err := s.Apply(data); err != nil {
+		logger.Error(err, "unable to apply manager configuration")
+		return
+	}
+	logger.Info("manager configuration applied", "configuration", s.Get())
+}
+
+// WatchConfigMap calls apply with the data of specified ConfigMap on every change
+// until context is done, data will be nil if ConfigMap is deleted
+func WatchConfigMap(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string, apply func(data map[string]string)) {
+	listWatch := cache.NewListWatchFromClient(kubeClient.CoreV1().RESTClient(), "configmaps", namespace,
+		fields.OneTermEqualSelector("metadata.name", name))
+
+	applyObject := func(obj interface{}) {
+		var data map[string]string
+		if configMap, ok := obj.(*corev1.ConfigMap); ok {
+			data = configMap.Data
+		}
+		apply(data)
+	}
+
+	_, controller := cache.NewInformer(listWatch, &corev1.ConfigMap{}, 10*time.Minute, cache.ResourceEventHandlerFuncs{
+		AddFunc: applyObject,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			applyObject(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			applyObject(nil)
+		},
+	})
+
+	controller.Run(ctx.Done())
+}
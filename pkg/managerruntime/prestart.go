@@ -0,0 +1,150 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package managerruntime
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// PreStartHook is a named step which must succeed after leader election and before
+// controllers are registered, e.g. a data migration
+type PreStartHook struct {
+	Name string
+
+	// DependsOn is the names of hooks which must succeed before this one
+	DependsOn []string
+
+	// Retries is the max count of retries after the first failure
+	Retries int
+
+	// Backoff is the initial waiting duration before retry, which doubles on every retry
+	Backoff time.Duration
+
+	// Timeout limits the duration of every attempt, zero means no limit
+	Timeout time.Duration
+
+	Run func(ctx context.Context) error
+}
+
+// RunPreStartHooks runs hooks one by one in an order that respects dependencies, and
+// keeps the given order among independent hooks. It stops at the first hook which
+// fails after all retries.
+func RunPreStartHooks(ctx context.Context, logger logr.Logger, hooks []PreStartHook) error {
+	ordered, err := sortPreStartHooks(hooks)
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range ordered {
+		hookLog := logger.WithValues("hook", hook.Name)
+		start := time.Now()
+
+		hookLog.Info("pre-start hook is running")
+		if err = runPreStartHook(ctx, hookLog, hook); err != nil {
+			hookLog.Error(err, "pre-start hook fails", "duration", time.Since(start).String())
+			return fmt.Errorf("pre-start hook %s fails: %v", hook.Name, err)
+		}
+		hookLog.Info("pre-start hook succeeds", "duration", time.Since(start).String())
+	}
+	return nil
+}
+
+func runPreStartHook(ctx context.Context, logger logr.Logger, hook PreStartHook) (err error) {
+	backoff := hook.Backoff
+	for attempt := 0; ; attempt++ {
+		if err = runPreStartHookOnce(ctx, hook); err == nil || attempt >= hook.Retries {
+			return err
+		}
+
+		logger.Info("pre-start hook will retry", "attempt", attempt+1, "backoff", backoff.String(), "error", err.Error())
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func runPreStartHookOnce(ctx context.Context, hook PreStartHook) error {
+	if hook.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, hook.Timeout)
+		defer cancel()
+	}
+	return hook.Run(ctx)
+}
+
+// sortPreStartHooks sorts hooks topologically by dependencies
+func sortPreStartHooks(hooks []PreStartHook) ([]PreStartHook, error) {
+	var indexes = map[string]int{}
+	for i, hook := range hooks {
+		if hook.Run == nil {
+			return nil, fmt.Errorf("pre-start hook %s has no run function", hook.Name)
+		}
+		if _, exist := indexes[hook.Name]; exist {
+			return nil, fmt.Errorf("pre-start hook %s is duplicated", hook.Name)
+		}
+		indexes[hook.Name] = i
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	var (
+		states  = make([]int, len(hooks))
+		ordered = make([]PreStartHook, 0, len(hooks))
+		visit   func(i int) error
+	)
+
+	visit = func(i int) error {
+		switch states[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("pre-start hook %s has circular dependencies", hooks[i].Name)
+		}
+
+		states[i] = visiting
+		for _, dependency := range hooks[i].DependsOn {
+			j, exist := indexes[dependency]
+			if !exist {
+				return fmt.Errorf("pre-start hook %s depends on unknown hook %s", hooks[i].Name, dependency)
+			}
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+		states[i] = visited
+		ordered = append(ordered, hooks[i])
+		return nil
+	}
+
+	for i := range hooks {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
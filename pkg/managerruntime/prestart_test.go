@@ -0,0 +1,138 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package managerruntime
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestRunPreStartHooks(t *testing.T) {
+	var executed []string
+	record := func(name string) func(context.Context) error {
+		return func(ctx context.Context) error {
+			executed = append(executed, name)
+			return nil
+		}
+	}
+
+	if err := RunPreStartHooks(context.Background(), logr.Discard(), []PreStartHook{
+		{Name: "c", DependsOn: []string{"b"}, Run: record("c")},
+		{Name: "a", Run: record("a")},
+		{Name: "b", DependsOn: []string{"a"}, Run: record("b")},
+		{Name: "d", Run: record("d")},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := []string{"a", "b", "c", "d"}; !reflect.DeepEqual(executed, expected) {
+		t.Errorf("expect order %v, but got %v", expected, executed)
+	}
+}
+
+func TestRunPreStartHooksRetry(t *testing.T) {
+	var attempts int
+	flaky := PreStartHook{
+		Name:    "flaky",
+		Retries: 2,
+		Backoff: time.Millisecond,
+		Run: func(ctx context.Context) error {
+			attempts++
+			if attempts < 3 {
+				return fmt.Errorf("attempt %d fails", attempts)
+			}
+			return nil
+		},
+	}
+
+	if err := RunPreStartHooks(context.Background(), logr.Discard(), []PreStartHook{flaky}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expect 3 attempts, but got %d", attempts)
+	}
+
+	attempts = -10
+	if err := RunPreStartHooks(context.Background(), logr.Discard(), []PreStartHook{flaky}); err == nil {
+		t.Errorf("expect error after all retries fail")
+	}
+}
+
+func TestRunPreStartHooksTimeout(t *testing.T) {
+	err := RunPreStartHooks(context.Background(), logr.Discard(), []PreStartHook{
+		{
+			Name:    "slow",
+			Timeout: 10 * time.Millisecond,
+			Run: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		},
+	})
+	if err == nil {
+		t.Errorf("expect error for timeout")
+	}
+}
+
+func TestSortPreStartHooksInvalid(t *testing.T) {
+	noop := func(ctx context.Context) error { return nil }
+
+	tests := []struct {
+		name  string
+		hooks []PreStartHook
+	}{
+		{
+			"circular dependencies",
+			[]PreStartHook{
+				{Name: "a", DependsOn: []string{"b"}, Run: noop},
+				{Name: "b", DependsOn: []string{"a"}, Run: noop},
+			},
+		},
+		{
+			"unknown dependency",
+			[]PreStartHook{
+				{Name: "a", DependsOn: []string{"b"}, Run: noop},
+			},
+		},
+		{
+			"duplicated name",
+			[]PreStartHook{
+				{Name: "a", Run: noop},
+				{Name: "a", Run: noop},
+			},
+		},
+		{
+			"missing run function",
+			[]PreStartHook{
+				{Name: "a"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := sortPreStartHooks(test.hooks); err == nil {
+				t.Errorf("expect error")
+			}
+		})
+	}
+}
@@ -25,8 +25,15 @@ func init() {
 	metrics.Registry.MustRegister(
 		IPUsageGauge,
 		SubnetIPUsageGauge,
+		SubnetIPExhaustionGauge,
 		IPAllocationPeriodSummary,
+		IPAllocationFailureCounter,
 		RemoteClusterStatusCheckDuration,
+		StuckPodGauge,
+		IPAMNotificationCounter,
+		DaemonPoolReadyGauge,
+		VxlanNeighEntryGauge,
+		VxlanFdbEntryGauge,
 	)
 }
 
@@ -34,6 +41,7 @@ const (
 	IPTotalUsageType     = "total"
 	IPUsedUsageType      = "used"
 	IPAvailableUsageType = "available"
+	IPReservedUsageType  = "reserved"
 )
 
 const (
@@ -65,6 +73,16 @@ var SubnetIPUsageGauge = prometheus.NewGaugeVec(
 		"usageType",
 	})
 
+var SubnetIPExhaustionGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "subnet_ip_exhaustion_seconds",
+		Help: "the estimated seconds until IPs of subnets are exhausted at the fastest allocation rate of forecast windows",
+	},
+	[]string{
+		"subnetName",
+		"networkName",
+	})
+
 const (
 	IPStatefulAllocateType = "stateful"
 	IPNormalAllocateType   = "normal"
@@ -82,6 +100,17 @@ var IPAllocationPeriodSummary = prometheus.NewSummaryVec(
 	},
 )
 
+var IPAllocationFailureCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "ip_allocation_failure_count",
+		Help: "the count of failed ip allocations for pods by failure reason",
+	},
+	[]string{
+		"networkName",
+		"reason",
+	},
+)
+
 var RemoteClusterStatusCheckDuration = prometheus.NewHistogramVec(
 	prometheus.HistogramOpts{
 		Name:    "remote_cluster_status_check_duration",
@@ -92,3 +121,54 @@ var RemoteClusterStatusCheckDuration = prometheus.NewHistogramVec(
 		"clusterName",
 	},
 )
+
+var StuckPodGauge = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "stuck_pod_count",
+		Help: "the count of scheduled pods still without ip instances after startup timeout",
+	},
+)
+
+var DaemonPoolReadyGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "daemon_pool_ready",
+		Help: "whether daemons of node pools pass self-checks for rollout, 1 for ready and 0 for not ready",
+	},
+	[]string{
+		"nodePool",
+	})
+
+var VxlanNeighEntryGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "vxlan_neigh_entry_count",
+		Help: "the count of neigh entries of overlay addresses on vxlan interfaces after garbage collection",
+	},
+	[]string{
+		"interface",
+		"ipVersion",
+	})
+
+var VxlanFdbEntryGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "vxlan_fdb_entry_count",
+		Help: "the count of fdb entries of remote vteps on vxlan interfaces",
+	},
+	[]string{
+		"interface",
+	})
+
+const (
+	IPAMNotificationSent    = "sent"
+	IPAMNotificationFailed  = "failed"
+	IPAMNotificationDropped = "dropped"
+)
+
+var IPAMNotificationCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "ipam_notification_count",
+		Help: "the count of address allocation events sent to webhook by result",
+	},
+	[]string{
+		"result",
+	},
+)
@@ -56,6 +56,15 @@ type PodResponse struct {
 	Err           string      `json:"error"`
 }
 
+// TryAgainLaterError is returned if cni daemon is too busy to handle the request
+type TryAgainLaterError struct {
+	Message string
+}
+
+func (e *TryAgainLaterError) Error() string {
+	return e.Message
+}
+
 // NewCniDaemonClient return a new cnidaemonclient
 func NewCniDaemonClient(socketAddress string) CniDaemonClient {
 	request := gorequest.New()
@@ -72,6 +81,9 @@ func (cdc CniDaemonClient) Add(podRequest PodRequest) (*PodResponse, error) {
 	if len(errors) != 0 {
 		return nil, errors[0]
 	}
+	if res.StatusCode == http.StatusTooManyRequests {
+		return nil, &TryAgainLaterError{Message: resp.Err}
+	}
 	if res.StatusCode != 200 {
 		return nil, fmt.Errorf("request ip return %d %s", res.StatusCode, resp.Err)
 	}
@@ -84,6 +96,9 @@ func (cdc CniDaemonClient) Del(podRequest PodRequest) error {
 	if len(errors) != 0 {
 		return errors[0]
 	}
+	if res.StatusCode == http.StatusTooManyRequests {
+		return &TryAgainLaterError{Message: body}
+	}
 	if res.StatusCode != 204 {
 		return fmt.Errorf("delete ip return %d %s", res.StatusCode, body)
 	}
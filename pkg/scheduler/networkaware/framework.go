@@ -0,0 +1,151 @@
+//go:build scheduler
+
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networkaware
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+)
+
+const stateKey framework.StateKey = Name
+
+var (
+	_ framework.PreFilterPlugin = &FrameworkPlugin{}
+	_ framework.FilterPlugin    = &FrameworkPlugin{}
+	_ framework.ScorePlugin     = &FrameworkPlugin{}
+	_ framework.ScoreExtensions = &FrameworkPlugin{}
+)
+
+// FrameworkPlugin adapts NetworkAware to scheduler framework.
+type FrameworkPlugin struct {
+	networkAware *NetworkAware
+	handle       framework.Handle
+}
+
+// NewFrameworkPlugin is the plugin factory to be registered into scheduler, e.g.,
+// app.NewSchedulerCommand(app.WithPlugin(networkaware.Name, networkaware.NewFrameworkPlugin))
+func NewFrameworkPlugin(_ runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+	// scheduler runs in cluster, and hybridnet objects are not in the clientset of handle
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get in-cluster config: %v", err)
+	}
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create client: %v", err)
+	}
+
+	return &FrameworkPlugin{
+		networkAware: New(c),
+		handle:       handle,
+	}, nil
+}
+
+func (f *FrameworkPlugin) Name() string {
+	return f.networkAware.Name()
+}
+
+// podStateData stores PodState in cycle state, it is never modified after PreFilter
+// so that cloning is unnecessary.
+type podStateData struct {
+	*PodState
+}
+
+func (p *podStateData) Clone() framework.StateData {
+	return p
+}
+
+func (f *FrameworkPlugin) PreFilter(ctx context.Context, state *framework.CycleState, pod *corev1.Pod) *framework.Status {
+	podState, err := f.networkAware.PreFilter(ctx, pod)
+	if err != nil {
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, err.Error())
+	}
+
+	state.Write(stateKey, &podStateData{PodState: podState})
+	return nil
+}
+
+func (f *FrameworkPlugin) PreFilterExtensions() framework.PreFilterExtensions {
+	return nil
+}
+
+func (f *FrameworkPlugin) Filter(_ context.Context, state *framework.CycleState, _ *corev1.Pod,
+	nodeInfo *framework.NodeInfo) *framework.Status {
+	data, err := state.Read(stateKey)
+	if err != nil {
+		return framework.AsStatus(fmt.Errorf("unable to read state of %s: %v", Name, err))
+	}
+
+	podState, ok := data.(*podStateData)
+	if !ok {
+		return framework.AsStatus(fmt.Errorf("unexpected state type %T of %s", data, Name))
+	}
+
+	if err = f.networkAware.Filter(podState.PodState, nodeInfo.Node()); err != nil {
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, err.Error())
+	}
+	return nil
+}
+
+func (f *FrameworkPlugin) Score(ctx context.Context, _ *framework.CycleState, pod *corev1.Pod,
+	nodeName string) (int64, *framework.Status) {
+	nodeInfo, err := f.handle.SnapshotSharedLister().NodeInfos().Get(nodeName)
+	if err != nil {
+		return MinNodeScore, framework.AsStatus(fmt.Errorf("unable to get node %s from snapshot: %v", nodeName, err))
+	}
+
+	score, err := f.networkAware.Score(ctx, pod, nodeInfo.Node())
+	if err != nil {
+		return MinNodeScore, framework.AsStatus(err)
+	}
+	return score, nil
+}
+
+func (f *FrameworkPlugin) ScoreExtensions() framework.ScoreExtensions {
+	return f
+}
+
+func (f *FrameworkPlugin) NormalizeScore(_ context.Context, _ *framework.CycleState, _ *corev1.Pod,
+	scores framework.NodeScoreList) *framework.Status {
+	var scoreMap = make(map[string]int64, len(scores))
+	for _, nodeScore := range scores {
+		scoreMap[nodeScore.Name] = nodeScore.Score
+	}
+
+	f.networkAware.NormalizeScore(scoreMap)
+
+	for i := range scores {
+		scores[i].Score = scoreMap[scores[i].Name]
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networkaware
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/controllers/utils"
+	"github.com/alibaba/hybridnet/pkg/ipam/types"
+	globalutils "github.com/alibaba/hybridnet/pkg/utils"
+)
+
+const (
+	// Name is the name of plugin used in scheduler profiles
+	Name = "HybridnetNetworkAware"
+
+	// MinNodeScore and MaxNodeScore are the same as the ones of scheduler framework
+	MinNodeScore int64 = 0
+	MaxNodeScore int64 = 100
+)
+
+// NetworkAware makes scheduling decisions by hybridnet networking objects. It only
+// depends on hybridnet objects and core api, so that it can be registered into a
+// scheduler framework of any version by a thin adapter which converts results to
+// framework status.
+//
+// PreFilter rejects pods whose specified subnets have no available addresses, and
+// Filter rejects nodes which are not covered by the network of specified subnets.
+// Score prefers nodes whose underlay network has more free addresses, to balance
+// allocations across subnets. The adapter of scheduler framework is built with tag
+// "scheduler", see framework.go.
+type NetworkAware struct {
+	client client.Reader
+}
+
+func New(c client.Reader) *NetworkAware {
+	return &NetworkAware{client: c}
+}
+
+func (n *NetworkAware) Name() string {
+	return Name
+}
+
+// PodState is computed once in every scheduling cycle of a pod by PreFilter, so that
+// Filter does not need to query API server for every node.
+type PodState struct {
+	constraints []networkConstraint
+}
+
+// networkConstraint requires node to be covered by network, which is specified directly
+// or by the subnet of subnetName.
+type networkConstraint struct {
+	network    *networkingv1.Network
+	subnetName string
+}
+
+// PreFilter resolves the networks which pod has to be placed in, and returns a non-nil
+// error as the reason if pod can not be placed on any node.
+func (n *NetworkAware) PreFilter(ctx context.Context, pod *corev1.Pod) (*PodState, error) {
+	var state = &PodState{}
+	if pod.Spec.HostNetwork || !isUnderlayPod(pod) {
+		return state, nil
+	}
+
+	subnetNames := specifiedSubnetNames(pod)
+	if len(subnetNames) == 0 {
+		networkName := specifiedNetworkName(pod)
+		if len(networkName) == 0 {
+			// node is selected by quota labels and attachment labels of webhook
+			return state, nil
+		}
+
+		network, err := utils.GetNetwork(ctx, n.client, networkName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get specified network %s: %v", networkName, err)
+		}
+		state.constraints = append(state.constraints, networkConstraint{network: network})
+		return state, nil
+	}
+
+	retained, err := n.hasRetainedIPInstances(ctx, pod)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, subnetName := range subnetNames {
+		subnet, err := utils.GetSubnet(ctx, n.client, subnetName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get specified subnet %s: %v", subnetName, err)
+		}
+
+		// retained addresses do not consume the capacity of subnet
+		if !retained && subnet.Status.Available <= 0 {
+			return nil, fmt.Errorf("specified subnet %s has no available addresses", subnetName)
+		}
+
+		network, err := utils.GetNetwork(ctx, n.client, subnet.Spec.Network)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get network %s of subnet %s: %v", subnet.Spec.Network, subnetName, err)
+		}
+		state.constraints = append(state.constraints, networkConstraint{network: network, subnetName: subnetName})
+	}
+
+	return state, nil
+}
+
+// Filter returns a non-nil error as the reason if pod can not be placed on node.
+func (n *NetworkAware) Filter(state *PodState, node *corev1.Node) error {
+	for _, constraint := range state.constraints {
+		if nodeCoveredByNetwork(node, constraint.network) {
+			continue
+		}
+		if len(constraint.subnetName) == 0 {
+			return fmt.Errorf("node %s is not in specified network %s", node.Name, constraint.network.Name)
+		}
+		return fmt.Errorf("node %s is not in network %s of specified subnet %s", node.Name, constraint.network.Name,
+			constraint.subnetName)
+	}
+
+	return nil
+}
+
+// Score returns a score in [MinNodeScore, MaxNodeScore] which is proportional to the
+// ratio of free addresses in the underlay network of node.
+func (n *NetworkAware) Score(ctx context.Context, pod *corev1.Pod, node *corev1.Node) (int64, error) {
+	if pod.Spec.HostNetwork || !isUnderlayPod(pod) {
+		return MinNodeScore, nil
+	}
+
+	networkName, err := utils.FindUnderlayNetworkForNode(ctx, n.client, node.GetLabels())
+	if err != nil {
+		return MinNodeScore, fmt.Errorf("unable to find underlay network for node %s: %v", node.Name, err)
+	}
+	if len(networkName) == 0 {
+		return MinNodeScore, nil
+	}
+
+	var network *networkingv1.Network
+	if network, err = utils.GetNetwork(ctx, n.client, networkName); err != nil {
+		return MinNodeScore, fmt.Errorf("unable to get network %s: %v", networkName, err)
+	}
+
+	var statistics *networkingv1.Count
+	switch types.ParseIPFamilyFromString(pod.Annotations[constants.AnnotationIPFamily]) {
+	case types.IPv6:
+		statistics = network.Status.IPv6Statistics
+	case types.DualStack:
+		statistics = network.Status.DualStackStatistics
+	default:
+		statistics = network.Status.Statistics
+	}
+
+	return scoreOfCount(statistics), nil
+}
+
+// NormalizeScore scales scores of nodes to make the highest one be MaxNodeScore.
+func (n *NetworkAware) NormalizeScore(scores map[string]int64) {
+	var highest int64
+	for _, score := range scores {
+		if score > highest {
+			highest = score
+		}
+	}
+
+	if highest == 0 {
+		return
+	}
+
+	for nodeName, score := range scores {
+		scores[nodeName] = score * MaxNodeScore / highest
+	}
+}
+
+func (n *NetworkAware) hasRetainedIPInstances(ctx context.Context, pod *corev1.Pod) (bool, error) {
+	ipInstances, err := utils.ListAllocatedIPInstancesOfPod(ctx, n.client, pod)
+	if err != nil {
+		return false, fmt.Errorf("unable to list allocated ip instances of pod %s/%s: %v", pod.Namespace, pod.Name, err)
+	}
+	return len(ipInstances) > 0, nil
+}
+
+func isUnderlayPod(pod *corev1.Pod) bool {
+	return types.ParseNetworkTypeFromString(pod.Annotations[constants.AnnotationNetworkType]) == types.Underlay
+}
+
+func specifiedNetworkName(pod *corev1.Pod) string {
+	return globalutils.PickFirstNonEmptyString(pod.Annotations[constants.AnnotationSpecifiedNetwork],
+		pod.Labels[constants.LabelSpecifiedNetwork])
+}
+
+func specifiedSubnetNames(pod *corev1.Pod) []string {
+	subnetNameStr := globalutils.PickFirstNonEmptyString(pod.Annotations[constants.AnnotationSpecifiedSubnet],
+		pod.Labels[constants.LabelSpecifiedSubnet])
+	if len(subnetNameStr) == 0 {
+		return nil
+	}
+	// ipv4 and ipv6 subnets are separated by "/" in dual stack
+	return strings.Split(subnetNameStr, "/")
+}
+
+func nodeCoveredByNetwork(node *corev1.Node, network *networkingv1.Network) bool {
+	// network without node selector, e.g. overlay network, covers all nodes
+	if len(network.Spec.NodeSelector) == 0 {
+		return true
+	}
+	return labels.SelectorFromSet(network.Spec.NodeSelector).Matches(labels.Set(node.GetLabels()))
+}
+
+func scoreOfCount(count *networkingv1.Count) int64 {
+	if count == nil || count.Total <= 0 || count.Available <= 0 {
+		return MinNodeScore
+	}
+	return int64(count.Available) * MaxNodeScore / int64(count.Total)
+}
@@ -0,0 +1,253 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package networkaware
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+)
+
+func TestNodeCoveredByNetwork(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "node1",
+			Labels: map[string]string{"network": "underlay1"},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		nodeSelector map[string]string
+		expected     bool
+	}{
+		{
+			"network without node selector",
+			nil,
+			true,
+		},
+		{
+			"matched node selector",
+			map[string]string{"network": "underlay1"},
+			true,
+		},
+		{
+			"unmatched node selector",
+			map[string]string{"network": "underlay2"},
+			false,
+		},
+	}
+
+	for _, test := range tests {
+		network := &networkingv1.Network{
+			Spec: networkingv1.NetworkSpec{
+				NodeSelector: test.nodeSelector,
+			},
+		}
+		if result := nodeCoveredByNetwork(node, network); result != test.expected {
+			t.Errorf("test %s fails, expected %v but got %v", test.name, test.expected, result)
+		}
+	}
+}
+
+func TestScoreOfCount(t *testing.T) {
+	tests := []struct {
+		name     string
+		count    *networkingv1.Count
+		expected int64
+	}{
+		{
+			"nil count",
+			nil,
+			MinNodeScore,
+		},
+		{
+			"empty network",
+			&networkingv1.Count{},
+			MinNodeScore,
+		},
+		{
+			"exhausted network",
+			&networkingv1.Count{Total: 10, Used: 10, Available: 0},
+			MinNodeScore,
+		},
+		{
+			"half used network",
+			&networkingv1.Count{Total: 10, Used: 5, Available: 5},
+			50,
+		},
+		{
+			"unused network",
+			&networkingv1.Count{Total: 10, Used: 0, Available: 10},
+			MaxNodeScore,
+		},
+	}
+
+	for _, test := range tests {
+		if result := scoreOfCount(test.count); result != test.expected {
+			t.Errorf("test %s fails, expected %v but got %v", test.name, test.expected, result)
+		}
+	}
+}
+
+func TestNormalizeScore(t *testing.T) {
+	scores := map[string]int64{
+		"node1": 10,
+		"node2": 20,
+		"node3": 0,
+	}
+
+	New(nil).NormalizeScore(scores)
+
+	expected := map[string]int64{
+		"node1": 50,
+		"node2": MaxNodeScore,
+		"node3": MinNodeScore,
+	}
+	for nodeName, score := range expected {
+		if scores[nodeName] != score {
+			t.Errorf("node %s expected score %v but got %v", nodeName, score, scores[nodeName])
+		}
+	}
+}
+
+func TestPreFilterAndFilter(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = networkingv1.AddToScheme(scheme)
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&networkingv1.Network{
+			ObjectMeta: metav1.ObjectMeta{Name: "underlay1"},
+			Spec:       networkingv1.NetworkSpec{NodeSelector: map[string]string{"network": "underlay1"}},
+		},
+		&networkingv1.Subnet{
+			ObjectMeta: metav1.ObjectMeta{Name: "subnet1"},
+			Spec:       networkingv1.SubnetSpec{Network: "underlay1"},
+			Status:     networkingv1.SubnetStatus{Count: networkingv1.Count{Available: 10}},
+		},
+		&networkingv1.Subnet{
+			ObjectMeta: metav1.ObjectMeta{Name: "exhausted"},
+			Spec:       networkingv1.SubnetSpec{Network: "underlay1"},
+		},
+		&networkingv1.IPInstance{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "10-0-0-1",
+				Namespace: "default",
+				Labels:    map[string]string{constants.LabelPod: "retained"},
+			},
+		},
+	).Build()
+
+	inNetwork := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{"network": "underlay1"}}}
+	outOfNetwork := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2"}}
+
+	tests := []struct {
+		name                 string
+		podName              string
+		annotations          map[string]string
+		expectPreFilterErr   bool
+		expectOutOfNetworkOK bool
+	}{
+		{
+			name:                 "overlay pod",
+			podName:              "overlay",
+			annotations:          map[string]string{constants.AnnotationNetworkType: "Overlay"},
+			expectOutOfNetworkOK: true,
+		},
+		{
+			name:                 "nothing specified",
+			podName:              "unspecified",
+			annotations:          map[string]string{constants.AnnotationNetworkType: "Underlay"},
+			expectOutOfNetworkOK: true,
+		},
+		{
+			name:    "network specified",
+			podName: "network",
+			annotations: map[string]string{
+				constants.AnnotationNetworkType:      "Underlay",
+				constants.AnnotationSpecifiedNetwork: "underlay1",
+			},
+		},
+		{
+			name:    "subnet specified",
+			podName: "subnet",
+			annotations: map[string]string{
+				constants.AnnotationNetworkType:     "Underlay",
+				constants.AnnotationSpecifiedSubnet: "subnet1",
+			},
+		},
+		{
+			name:    "exhausted subnet specified",
+			podName: "exhausted",
+			annotations: map[string]string{
+				constants.AnnotationNetworkType:     "Underlay",
+				constants.AnnotationSpecifiedSubnet: "exhausted",
+			},
+			expectPreFilterErr: true,
+		},
+		{
+			name:    "exhausted subnet specified with retained addresses",
+			podName: "retained",
+			annotations: map[string]string{
+				constants.AnnotationNetworkType:     "Underlay",
+				constants.AnnotationSpecifiedSubnet: "exhausted",
+			},
+		},
+		{
+			name:    "missing network specified",
+			podName: "missing",
+			annotations: map[string]string{
+				constants.AnnotationNetworkType:      "Underlay",
+				constants.AnnotationSpecifiedNetwork: "missing",
+			},
+			expectPreFilterErr: true,
+		},
+	}
+
+	plugin := New(c)
+	for _, test := range tests {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: test.podName, Namespace: "default", Annotations: test.annotations},
+		}
+
+		state, err := plugin.PreFilter(context.Background(), pod)
+		if (err != nil) != test.expectPreFilterErr {
+			t.Errorf("test %s fails, expected pre-filter error %v but got %v", test.name, test.expectPreFilterErr, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		if err = plugin.Filter(state, inNetwork); err != nil {
+			t.Errorf("test %s fails, expected node in network to pass but got %v", test.name, err)
+		}
+		if err = plugin.Filter(state, outOfNetwork); (err == nil) != test.expectOutOfNetworkOK {
+			t.Errorf("test %s fails, expected node out of network to pass %v but got %v", test.name,
+				test.expectOutOfNetworkOK, err)
+		}
+	}
+}
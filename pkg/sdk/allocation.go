@@ -0,0 +1,167 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	ipamtypes "github.com/alibaba/hybridnet/pkg/ipam/types"
+	"github.com/alibaba/hybridnet/pkg/utils"
+	macutils "github.com/alibaba/hybridnet/pkg/utils/mac"
+)
+
+// AllocationRequest is the network intent of a pod, empty fields are left to the defaults of
+// namespace and cluster.
+type AllocationRequest struct {
+	// Network is the name of specified network
+	Network string
+	// Subnets are names of specified subnets, the ipv4 one goes first if both ipv4 and ipv6 subnets are specified
+	Subnets []string
+	// NetworkType is one of Underlay, Overlay and GlobalBGP
+	NetworkType networkingv1.NetworkType
+	// IPFamily is one of IPv4Only, IPv6Only and DualStack
+	IPFamily ipamtypes.IPFamilyMode
+	// IPPool are addresses to assign, one for each replica of a stateful workload, and an address
+	// of dual stack is in the format of "<ipv4>/<ipv6>"
+	IPPool []string
+	// MACPool are mac addresses to assign, one for each replica of a stateful workload
+	MACPool []string
+	// Retain is whether addresses are kept for the next pod of a stateful workload
+	Retain *bool
+}
+
+// Validate checks the request in the same way of hybridnet webhook, except the existence of
+// network and subnets.
+func (r *AllocationRequest) Validate() error {
+	if len(r.Subnets) > 2 {
+		return fmt.Errorf("cannot have more than two specified subnets")
+	}
+	for _, subnet := range r.Subnets {
+		if len(subnet) == 0 || strings.Contains(subnet, "/") {
+			return fmt.Errorf("invalid subnet name %q", subnet)
+		}
+	}
+
+	switch r.NetworkType {
+	case "", networkingv1.NetworkTypeUnderlay, networkingv1.NetworkTypeOverlay, networkingv1.NetworkTypeGlobalBGP:
+	default:
+		return fmt.Errorf("unrecognized network type %v", r.NetworkType)
+	}
+
+	if len(r.IPFamily) > 0 && !ipamtypes.IsValidFamilyMode(r.IPFamily) {
+		return fmt.Errorf("unrecognized ip family %v", r.IPFamily)
+	}
+
+	if len(r.IPPool) > 0 && len(r.Network) == 0 && len(r.Subnets) == 0 {
+		return fmt.Errorf("ip pool and network(subnet) must be specified at the same time")
+	}
+	for idx, ips := range r.IPPool {
+		for _, ip := range strings.Split(ips, "/") {
+			if len(ip) == 0 || utils.NormalizedIP(ip) != ip {
+				return fmt.Errorf("the %d ip %q in ip pool is not valid", idx, ips)
+			}
+		}
+	}
+
+	for idx, mac := range r.MACPool {
+		if len(macutils.NormalizeMAC(mac)) == 0 {
+			return fmt.Errorf("the %d mac address %q in mac pool is not valid", idx, mac)
+		}
+	}
+	return nil
+}
+
+// SetAllocationRequest writes the request into annotations of a pod, or the template of a
+// workload, which is the way for admission controllers to request addresses.
+func SetAllocationRequest(obj metav1.Object, req *AllocationRequest) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	setIfNotEmpty := func(key, value string) {
+		if len(value) > 0 {
+			annotations[key] = value
+		}
+	}
+	setIfNotEmpty(constants.AnnotationSpecifiedNetwork, req.Network)
+	setIfNotEmpty(constants.AnnotationSpecifiedSubnet, strings.Join(req.Subnets, "/"))
+	setIfNotEmpty(constants.AnnotationNetworkType, string(req.NetworkType))
+	setIfNotEmpty(constants.AnnotationIPFamily, string(req.IPFamily))
+	setIfNotEmpty(constants.AnnotationIPPool, strings.Join(req.IPPool, ","))
+	setIfNotEmpty(constants.AnnotationMACPool, strings.Join(req.MACPool, ","))
+	if req.Retain != nil {
+		annotations[constants.AnnotationIPRetain] = strconv.FormatBool(*req.Retain)
+	}
+
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// AllocateIP creates a pod with the allocation request. Addresses are allocated by hybridnet
+// manager asynchronously, use WaitForPodNetworkInfo to get them.
+func (c *Client) AllocateIP(ctx context.Context, pod *corev1.Pod, req *AllocationRequest) (*corev1.Pod, error) {
+	pod = pod.DeepCopy()
+	if err := SetAllocationRequest(pod, req); err != nil {
+		return nil, fmt.Errorf("invalid allocation request: %v", err)
+	}
+
+	return c.kubeClient.CoreV1().Pods(pod.Namespace).Create(ctx, pod, metav1.CreateOptions{})
+}
+
+// ReleaseIP releases an address which is not used by any running pod, e.g., an address retained
+// for a stateful workload which has been scaled in.
+func (c *Client) ReleaseIP(ctx context.Context, namespace, ip string) error {
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return fmt.Errorf("invalid ip %v", ip)
+	}
+
+	ipInstances := c.hybridnetClient.NetworkingV1().IPInstances(namespace)
+	ipInstance, err := ipInstances.Get(ctx, utils.ToDNSFormat(parsedIP), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	if podName := ipInstance.Spec.Binding.PodName; len(podName) > 0 {
+		pod, err := c.kubeClient.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err):
+		case err != nil:
+			return fmt.Errorf("failed to get pod %v/%v: %v", namespace, podName, err)
+		case pod.UID == ipInstance.Spec.Binding.PodUID && pod.DeletionTimestamp == nil:
+			return fmt.Errorf("ip %v is still used by pod %v/%v", ip, namespace, podName)
+		}
+	}
+
+	// addresses are released by hybridnet manager before ip instance is removed
+	return ipInstances.Delete(ctx, ipInstance.Name, metav1.DeleteOptions{})
+}
@@ -0,0 +1,65 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package sdk helps in-house operators and admission controllers to request and query addresses
+// of hybridnet, without reimplementing the conventions of annotations, labels and IPInstance names.
+package sdk
+
+import (
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/alibaba/hybridnet/pkg/client/clientset/versioned"
+)
+
+// Client wraps clientsets of kubernetes and hybridnet.
+type Client struct {
+	kubeClient      kubernetes.Interface
+	hybridnetClient versioned.Interface
+}
+
+// NewForConfig creates a Client for the given rest config.
+func NewForConfig(config *rest.Config) (*Client, error) {
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	hybridnetClient, err := versioned.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewForClientsets(kubeClient, hybridnetClient), nil
+}
+
+// NewForClientsets creates a Client with existing clientsets, e.g., fake ones for testing.
+func NewForClientsets(kubeClient kubernetes.Interface, hybridnetClient versioned.Interface) *Client {
+	return &Client{
+		kubeClient:      kubeClient,
+		hybridnetClient: hybridnetClient,
+	}
+}
+
+// Kubernetes returns the wrapped clientset of kubernetes.
+func (c *Client) Kubernetes() kubernetes.Interface {
+	return c.kubeClient
+}
+
+// Hybridnet returns the wrapped clientset of hybridnet.
+func (c *Client) Hybridnet() versioned.Interface {
+	return c.hybridnetClient
+}
@@ -0,0 +1,121 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/utils/transform"
+)
+
+// ErrPodNetworkNotAllocated means no address has been allocated for the pod yet.
+var ErrPodNetworkNotAllocated = errors.New("pod network is not allocated")
+
+// PodNetworkInfo is the allocated network of a pod.
+type PodNetworkInfo struct {
+	Network  string
+	NodeName string
+	// Addresses are sorted by ip version, the ipv4 one goes first
+	Addresses []PodAddress
+}
+
+// PodAddress is an allocated address of a pod.
+type PodAddress struct {
+	IPInstance string
+	Subnet     string
+	Version    networkingv1.IPVersion
+	IP         net.IP
+	CIDR       *net.IPNet
+	Gateway    net.IP
+	MAC        string
+}
+
+// GetPodNetworkInfo returns the allocated network of a pod, ErrPodNetworkNotAllocated will be
+// returned if there is no address.
+func (c *Client) GetPodNetworkInfo(ctx context.Context, namespace, podName string) (*PodNetworkInfo, error) {
+	ipInstanceList, err := c.hybridnetClient.NetworkingV1().IPInstances(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{
+			constants.LabelPod: transform.TransferPodNameForLabelValue(podName),
+		}).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ip instances of pod %v/%v: %v", namespace, podName, err)
+	}
+
+	info := &PodNetworkInfo{}
+	for i := range ipInstanceList.Items {
+		ipInstance := &ipInstanceList.Items[i]
+		// label value of long pod names might conflict
+		if ipInstance.Spec.Binding.PodName != podName || ipInstance.DeletionTimestamp != nil {
+			continue
+		}
+
+		ip, cidr, err := net.ParseCIDR(ipInstance.Spec.Address.IP)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ip %v of ip instance %v: %v", ipInstance.Spec.Address.IP, ipInstance.Name, err)
+		}
+
+		info.Network = ipInstance.Spec.Network
+		info.NodeName = ipInstance.Spec.Binding.NodeName
+		info.Addresses = append(info.Addresses, PodAddress{
+			IPInstance: ipInstance.Name,
+			Subnet:     ipInstance.Spec.Subnet,
+			Version:    ipInstance.Spec.Address.Version,
+			IP:         ip,
+			CIDR:       cidr,
+			Gateway:    net.ParseIP(ipInstance.Spec.Address.Gateway),
+			MAC:        ipInstance.Spec.Address.MAC,
+		})
+	}
+
+	if len(info.Addresses) == 0 {
+		return nil, ErrPodNetworkNotAllocated
+	}
+
+	sort.SliceStable(info.Addresses, func(i, j int) bool {
+		return info.Addresses[i].Version < info.Addresses[j].Version
+	})
+	return info, nil
+}
+
+// WaitForPodNetworkInfo polls the allocated network of a pod until there is any address or
+// context is done.
+func (c *Client) WaitForPodNetworkInfo(ctx context.Context, namespace, podName string, interval time.Duration) (*PodNetworkInfo, error) {
+	var info *PodNetworkInfo
+	err := wait.PollImmediateUntilWithContext(ctx, interval, func(ctx context.Context) (bool, error) {
+		var err error
+		if info, err = c.GetPodNetworkInfo(ctx, namespace, podName); err != nil {
+			if errors.Is(err, ErrPodNetworkNotAllocated) {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	})
+	return info, err
+}
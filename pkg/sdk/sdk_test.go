@@ -0,0 +1,173 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgotesting "k8s.io/client-go/testing"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/client/clientset/versioned/fake"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/utils/transform"
+)
+
+// newFakeClientset returns a fake clientset of hybridnet, types are registered with the group of
+// generated fake clients, which is different from the group of CRDs
+func newFakeClientset(objects ...runtime.Object) *fake.Clientset {
+	scheme := runtime.NewScheme()
+	groupVersion := schema.GroupVersion{Group: "networking", Version: "v1"}
+	scheme.AddKnownTypes(groupVersion, &networkingv1.IPInstance{}, &networkingv1.IPInstanceList{})
+	metav1.AddToGroupVersion(scheme, groupVersion)
+
+	tracker := clientgotesting.NewObjectTracker(scheme, serializer.NewCodecFactory(scheme).UniversalDecoder())
+	for _, obj := range objects {
+		if err := tracker.Add(obj); err != nil {
+			panic(err)
+		}
+	}
+
+	cs := &fake.Clientset{}
+	cs.AddReactor("*", "*", clientgotesting.ObjectReaction(tracker))
+	return cs
+}
+
+func newIPInstance(name, podName string, podUID types.UID, version networkingv1.IPVersion, ip string) *networkingv1.IPInstance {
+	return &networkingv1.IPInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels: map[string]string{
+				constants.LabelPod: transform.TransferPodNameForLabelValue(podName),
+			},
+		},
+		Spec: networkingv1.IPInstanceSpec{
+			Network: "network1",
+			Subnet:  "subnet-" + string(version),
+			Address: networkingv1.Address{
+				IP:      ip,
+				Version: version,
+				MAC:     "00:00:00:00:00:01",
+			},
+			Binding: networkingv1.Binding{
+				NodeName: "node1",
+				PodName:  podName,
+				PodUID:   podUID,
+			},
+		},
+	}
+}
+
+func TestSetAllocationRequest(t *testing.T) {
+	retain := true
+	pod := &corev1.Pod{}
+	if err := SetAllocationRequest(pod, &AllocationRequest{
+		Subnets:  []string{"subnet1", "subnet2"},
+		IPFamily: "DualStack",
+		IPPool:   []string{"10.0.0.1/fe80::1", "10.0.0.2/fe80::2"},
+		Retain:   &retain,
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		constants.AnnotationSpecifiedSubnet: "subnet1/subnet2",
+		constants.AnnotationIPFamily:        "DualStack",
+		constants.AnnotationIPPool:          "10.0.0.1/fe80::1,10.0.0.2/fe80::2",
+		constants.AnnotationIPRetain:        "true",
+	}
+	if len(pod.Annotations) != len(expected) {
+		t.Fatalf("expect annotations %v but got %v", expected, pod.Annotations)
+	}
+	for key, value := range expected {
+		if pod.Annotations[key] != value {
+			t.Errorf("expect annotation %v to be %q but got %q", key, value, pod.Annotations[key])
+		}
+	}
+
+	for _, req := range []*AllocationRequest{
+		{Subnets: []string{"subnet1", "subnet2", "subnet3"}},
+		{NetworkType: "Unknown"},
+		{IPFamily: "Unknown"},
+		{IPPool: []string{"10.0.0.1"}},
+		{Network: "network1", IPPool: []string{"10.0.0.256"}},
+		{MACPool: []string{"invalid"}},
+	} {
+		if err := SetAllocationRequest(&corev1.Pod{}, req); err == nil {
+			t.Errorf("expect request %+v to be invalid", *req)
+		}
+	}
+}
+
+func TestGetPodNetworkInfo(t *testing.T) {
+	client := NewForClientsets(kubefake.NewSimpleClientset(), newFakeClientset(
+		newIPInstance("fe80--1", "pod1", "uid1", networkingv1.IPv6, "fe80::1/64"),
+		newIPInstance("10-0-0-1", "pod1", "uid1", networkingv1.IPv4, "10.0.0.1/24"),
+		newIPInstance("10-0-0-2", "pod2", "uid2", networkingv1.IPv4, "10.0.0.2/24"),
+	))
+
+	info, err := client.GetPodNetworkInfo(context.Background(), "default", "pod1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Network != "network1" || info.NodeName != "node1" || len(info.Addresses) != 2 {
+		t.Fatalf("unexpected pod network info %+v", *info)
+	}
+	if info.Addresses[0].IP.String() != "10.0.0.1" || info.Addresses[0].CIDR.String() != "10.0.0.0/24" {
+		t.Errorf("expect ipv4 address goes first but got %+v", info.Addresses[0])
+	}
+	if info.Addresses[1].IPInstance != "fe80--1" || info.Addresses[1].Subnet != "subnet-6" {
+		t.Errorf("unexpected ipv6 address %+v", info.Addresses[1])
+	}
+
+	if _, err = client.GetPodNetworkInfo(context.Background(), "default", "pod3"); !errors.Is(err, ErrPodNetworkNotAllocated) {
+		t.Errorf("expect not allocated error but got %v", err)
+	}
+}
+
+func TestReleaseIP(t *testing.T) {
+	client := NewForClientsets(
+		kubefake.NewSimpleClientset(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default", UID: "uid1"},
+		}),
+		newFakeClientset(
+			newIPInstance("10-0-0-1", "pod1", "uid1", networkingv1.IPv4, "10.0.0.1/24"),
+			newIPInstance("10-0-0-2", "pod2", "uid2", networkingv1.IPv4, "10.0.0.2/24"),
+		),
+	)
+
+	if err := client.ReleaseIP(context.Background(), "default", "10.0.0.1"); err == nil {
+		t.Errorf("expect ip used by running pod not to be released")
+	}
+	if err := client.ReleaseIP(context.Background(), "default", "10.0.0.2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := client.Hybridnet().NetworkingV1().IPInstances("default").Get(context.Background(),
+		"10-0-0-2", metav1.GetOptions{}); err == nil {
+		t.Errorf("expect ip instance 10-0-0-2 to be deleted")
+	}
+}
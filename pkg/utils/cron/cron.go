@@ -0,0 +1,246 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression in the standard five fields, i.e., minute, hour, day of month,
+// month and day of week. Every field is a set of values in bits.
+type Schedule struct {
+	minute, hour, dayOfMonth, month, dayOfWeek uint64
+
+	// the day matches if either day of month or day of week matches when both of them are restricted,
+	// i.e., not starting with "*", as what cron does
+	dayOfMonthRestricted, dayOfWeekRestricted bool
+}
+
+type bounds struct {
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	minuteBounds     = bounds{min: 0, max: 59}
+	hourBounds       = bounds{min: 0, max: 23}
+	dayOfMonthBounds = bounds{min: 1, max: 31}
+	monthBounds      = bounds{min: 1, max: 12, names: map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is also sunday as what cron does
+	dayOfWeekBounds = bounds{min: 0, max: 7, names: map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// searchYears limits the search of next time for schedules which never match, e.g., "0 0 30 2 *"
+const searchYears = 5
+
+// Parse parses a cron expression in the standard five fields, every field accepts "*", values,
+// ranges ("1-5"), steps ("*/15", "0-30/10") and lists of them ("1,3,5"), names are accepted for
+// months ("jan") and days of week ("mon"). Macros like "@daily" and "@weekly" are also accepted.
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if expanded, ok := macros[strings.ToLower(spec)]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields in cron expression %q, got %d", spec, len(fields))
+	}
+
+	schedule := &Schedule{
+		dayOfMonthRestricted: !strings.HasPrefix(fields[2], "*"),
+		dayOfWeekRestricted:  !strings.HasPrefix(fields[4], "*"),
+	}
+
+	var err error
+	for _, field := range []struct {
+		name   string
+		value  string
+		bounds bounds
+		bits   *uint64
+	}{
+		{name: "minute", value: fields[0], bounds: minuteBounds, bits: &schedule.minute},
+		{name: "hour", value: fields[1], bounds: hourBounds, bits: &schedule.hour},
+		{name: "day of month", value: fields[2], bounds: dayOfMonthBounds, bits: &schedule.dayOfMonth},
+		{name: "month", value: fields[3], bounds: monthBounds, bits: &schedule.month},
+		{name: "day of week", value: fields[4], bounds: dayOfWeekBounds, bits: &schedule.dayOfWeek},
+	} {
+		if *field.bits, err = parseField(field.value, field.bounds); err != nil {
+			return nil, fmt.Errorf("invalid %s field %q: %v", field.name, field.value, err)
+		}
+	}
+
+	// 7 and 0 are both sunday
+	if schedule.dayOfWeek&(1<<7) != 0 {
+		schedule.dayOfWeek |= 1
+	}
+
+	return schedule, nil
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangeAndStep := strings.Split(part, "/")
+		if len(rangeAndStep) > 2 {
+			return 0, fmt.Errorf("too many slashes in %q", part)
+		}
+
+		var start, end uint
+		switch {
+		case rangeAndStep[0] == "*":
+			start, end = b.min, b.max
+		case strings.Contains(rangeAndStep[0], "-"):
+			startAndEnd := strings.SplitN(rangeAndStep[0], "-", 2)
+			var err error
+			if start, err = parseValue(startAndEnd[0], b); err != nil {
+				return 0, err
+			}
+			if end, err = parseValue(startAndEnd[1], b); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("range %q starts after its end", rangeAndStep[0])
+			}
+		default:
+			value, err := parseValue(rangeAndStep[0], b)
+			if err != nil {
+				return 0, err
+			}
+			start, end = value, value
+			// a single value with step means the range from value to max, e.g., "5/15"
+			if len(rangeAndStep) == 2 {
+				end = b.max
+			}
+		}
+
+		step := uint(1)
+		if len(rangeAndStep) == 2 {
+			parsed, err := strconv.ParseUint(rangeAndStep[1], 10, 8)
+			if err != nil || parsed == 0 {
+				return 0, fmt.Errorf("invalid step %q", rangeAndStep[1])
+			}
+			step = uint(parsed)
+		}
+
+		for value := start; value <= end; value += step {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(value string, b bounds) (uint, error) {
+	if named, ok := b.names[strings.ToLower(value)]; ok {
+		return named, nil
+	}
+
+	parsed, err := strconv.ParseUint(value, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	if uint(parsed) < b.min || uint(parsed) > b.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", parsed, b.min, b.max)
+	}
+	return uint(parsed), nil
+}
+
+// Next returns the first time matching schedule after t, in the location of t. Zero time is
+// returned if schedule never matches in the following years.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	yearLimit := t.Year() + searchYears
+
+wrap:
+	for t.Year() <= yearLimit {
+		for s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			if t.Month() == time.January {
+				continue wrap
+			}
+		}
+
+		for !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			if t.Day() == 1 {
+				continue wrap
+			}
+		}
+
+		for s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			if t.Hour() == 0 {
+				continue wrap
+			}
+		}
+
+		for s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			if t.Minute() == 0 {
+				continue wrap
+			}
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dayOfMonthMatches := s.dayOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeekMatches := s.dayOfWeek&(1<<uint(t.Weekday())) != 0
+	if s.dayOfMonthRestricted && s.dayOfWeekRestricted {
+		return dayOfMonthMatches || dayOfWeekMatches
+	}
+	return dayOfMonthMatches && dayOfWeekMatches
+}
+
+// ActiveWindow returns the start of the window containing t, for windows which start at the times
+// matching schedule and last for duration, ok is false if t is in none of them.
+func (s *Schedule) ActiveWindow(t time.Time, duration time.Duration) (start time.Time, ok bool) {
+	if duration <= 0 {
+		return time.Time{}, false
+	}
+
+	// the first window starting after t-duration is the only candidate, because windows
+	// starting before it end before t and the ones starting after t do not contain t
+	start = s.Next(t.Add(-duration))
+	if start.IsZero() || start.After(t) {
+		return time.Time{}, false
+	}
+	return start, true
+}
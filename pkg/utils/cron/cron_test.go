@@ -0,0 +1,145 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr bool
+	}{
+		{name: "every minute", spec: "* * * * *"},
+		{name: "lists ranges and steps", spec: "0,30 1-5 */2 1-12/3 mon-fri"},
+		{name: "single value with step", spec: "5/15 * * * *"},
+		{name: "names", spec: "0 2 * JAN,jul SUN"},
+		{name: "sunday as 7", spec: "0 2 * * 7"},
+		{name: "macro", spec: "@daily"},
+		{name: "too few fields", spec: "0 2 * *", wantErr: true},
+		{name: "too many fields", spec: "0 0 2 * * *", wantErr: true},
+		{name: "minute out of range", spec: "60 * * * *", wantErr: true},
+		{name: "day of month out of range", spec: "0 0 0 * *", wantErr: true},
+		{name: "reversed range", spec: "0 5-1 * * *", wantErr: true},
+		{name: "zero step", spec: "*/0 * * * *", wantErr: true},
+		{name: "unknown name", spec: "0 0 * * funday", wantErr: true},
+		{name: "unknown macro", spec: "@sometimes", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Parse(test.spec); (err != nil) != test.wantErr {
+				t.Errorf("expected error %v, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		from     string
+		expected string
+	}{
+		{name: "next minute", spec: "* * * * *", from: "2022-01-01T10:00:30Z", expected: "2022-01-01T10:01:00Z"},
+		{name: "strictly after", spec: "0 2 * * *", from: "2022-01-01T02:00:00Z", expected: "2022-01-02T02:00:00Z"},
+		{name: "same day", spec: "30 2 * * *", from: "2022-01-01T01:00:00Z", expected: "2022-01-01T02:30:00Z"},
+		{name: "step", spec: "*/15 * * * *", from: "2022-01-01T10:16:00Z", expected: "2022-01-01T10:30:00Z"},
+		// 2022-01-01 is saturday
+		{name: "day of week", spec: "0 0 * * mon", from: "2022-01-01T00:00:00Z", expected: "2022-01-03T00:00:00Z"},
+		{name: "sunday as 7", spec: "0 0 * * 7", from: "2022-01-01T00:00:00Z", expected: "2022-01-02T00:00:00Z"},
+		{name: "day of month or day of week", spec: "0 0 15 * mon", from: "2022-01-11T00:00:00Z", expected: "2022-01-15T00:00:00Z"},
+		{name: "month rollover", spec: "0 0 1 * *", from: "2022-01-15T00:00:00Z", expected: "2022-02-01T00:00:00Z"},
+		{name: "year rollover", spec: "0 0 1 jan *", from: "2022-06-01T00:00:00Z", expected: "2023-01-01T00:00:00Z"},
+		{name: "leap day", spec: "0 0 29 2 *", from: "2022-03-01T00:00:00Z", expected: "2024-02-29T00:00:00Z"},
+		{name: "never", spec: "0 0 30 2 *", from: "2022-01-01T00:00:00Z", expected: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, err := Parse(test.spec)
+			if err != nil {
+				t.Fatalf("failed to parse %q: %v", test.spec, err)
+			}
+			from, _ := time.Parse(time.RFC3339, test.from)
+
+			next := schedule.Next(from)
+			if test.expected == "" {
+				if !next.IsZero() {
+					t.Errorf("expected no next time, got %v", next)
+				}
+				return
+			}
+			if next.Format(time.RFC3339) != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, next.Format(time.RFC3339))
+			}
+		})
+	}
+}
+
+func TestNextInLocation(t *testing.T) {
+	schedule, err := Parse("0 2 * * *")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	loc := time.FixedZone("UTC+8", 8*60*60)
+	from := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC).In(loc)
+	next := schedule.Next(from)
+	if !next.Equal(time.Date(2022, 1, 1, 18, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected next time %v", next)
+	}
+}
+
+func TestActiveWindow(t *testing.T) {
+	// windows of two hours from 02:00 every day
+	schedule, err := Parse("0 2 * * *")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		at            string
+		duration      time.Duration
+		expectedStart string
+	}{
+		{name: "before window", at: "2022-01-01T01:59:59Z", duration: 2 * time.Hour},
+		{name: "window starts", at: "2022-01-01T02:00:00Z", duration: 2 * time.Hour, expectedStart: "2022-01-01T02:00:00Z"},
+		{name: "in window", at: "2022-01-01T03:30:20Z", duration: 2 * time.Hour, expectedStart: "2022-01-01T02:00:00Z"},
+		{name: "window ends", at: "2022-01-01T04:00:00Z", duration: 2 * time.Hour},
+		{name: "window across days", at: "2022-01-02T01:00:00Z", duration: 24 * time.Hour, expectedStart: "2022-01-01T02:00:00Z"},
+		{name: "zero duration", at: "2022-01-01T02:00:00Z"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			at, _ := time.Parse(time.RFC3339, test.at)
+			start, ok := schedule.ActiveWindow(at, test.duration)
+			if ok != (test.expectedStart != "") {
+				t.Fatalf("expected active %v, got %v", test.expectedStart != "", ok)
+			}
+			if ok && start.Format(time.RFC3339) != test.expectedStart {
+				t.Errorf("expected start %v, got %v", test.expectedStart, start.Format(time.RFC3339))
+			}
+		})
+	}
+}
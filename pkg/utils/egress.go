@@ -0,0 +1,76 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	EgressProtocolTCP = "tcp"
+	EgressProtocolUDP = "udp"
+)
+
+// EgressRule allows traffic to destination CIDR, on a port of protocol if Port is not zero
+type EgressRule struct {
+	CIDR     *net.IPNet
+	Protocol string
+	Port     int
+}
+
+// ParseEgressAllowlist parses rules in format of "<cidr>[@<port>[/<protocol>]]" separated by comma,
+// e.g., "10.0.0.0/8,0.0.0.0/0@53/udp,fd00::/64@443". Protocol is tcp by default.
+func ParseEgressAllowlist(allowlist string) ([]EgressRule, error) {
+	var rules []EgressRule
+	if len(strings.TrimSpace(allowlist)) == 0 {
+		return rules, nil
+	}
+
+	for _, ruleString := range strings.Split(allowlist, ",") {
+		ruleString = strings.TrimSpace(ruleString)
+		cidrString, portString, hasPort := strings.Cut(ruleString, "@")
+
+		_, cidr, err := net.ParseCIDR(cidrString)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr of egress rule %q: %v", ruleString, err)
+		}
+
+		rule := EgressRule{CIDR: cidr}
+		if hasPort {
+			portString, protocol, hasProtocol := strings.Cut(portString, "/")
+			if rule.Port, err = strconv.Atoi(portString); err != nil || rule.Port < 1 || rule.Port > 65535 {
+				return nil, fmt.Errorf("invalid port of egress rule %q", ruleString)
+			}
+
+			rule.Protocol = EgressProtocolTCP
+			if hasProtocol {
+				switch rule.Protocol = strings.ToLower(protocol); rule.Protocol {
+				case EgressProtocolTCP, EgressProtocolUDP:
+				default:
+					return nil, fmt.Errorf("unsupported protocol of egress rule %q", ruleString)
+				}
+			}
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,95 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package utils
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParseEgressAllowlist(t *testing.T) {
+	mustParseCIDR := func(s string) *net.IPNet {
+		_, cidr, _ := net.ParseCIDR(s)
+		return cidr
+	}
+
+	tests := []struct {
+		name      string
+		allowlist string
+		expected  []EgressRule
+		expectErr bool
+	}{
+		{
+			"empty",
+			"",
+			nil,
+			false,
+		},
+		{
+			"cidr only",
+			"10.0.0.0/8",
+			[]EgressRule{{CIDR: mustParseCIDR("10.0.0.0/8")}},
+			false,
+		},
+		{
+			"port with default protocol",
+			"fd00::/64@443",
+			[]EgressRule{{CIDR: mustParseCIDR("fd00::/64"), Protocol: EgressProtocolTCP, Port: 443}},
+			false,
+		},
+		{
+			"multiple rules",
+			"10.0.0.1/32, 0.0.0.0/0@53/UDP",
+			[]EgressRule{
+				{CIDR: mustParseCIDR("10.0.0.1/32")},
+				{CIDR: mustParseCIDR("0.0.0.0/0"), Protocol: EgressProtocolUDP, Port: 53},
+			},
+			false,
+		},
+		{
+			"invalid cidr",
+			"10.0.0.1",
+			nil,
+			true,
+		},
+		{
+			"invalid port",
+			"10.0.0.0/8@65536",
+			nil,
+			true,
+		},
+		{
+			"unsupported protocol",
+			"10.0.0.0/8@80/sctp",
+			nil,
+			true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rules, err := ParseEgressAllowlist(test.allowlist)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("test %s fail, expect error %v but got %v", test.name, test.expectErr, err)
+			}
+			if !reflect.DeepEqual(rules, test.expected) {
+				t.Errorf("test %s fail, expected %v but got %v", test.name, test.expected, rules)
+			}
+		})
+	}
+}
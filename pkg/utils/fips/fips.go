@@ -0,0 +1,108 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package fips restricts the cryptography of hybridnet components to FIPS-approved algorithms.
+//
+// FIPS mode is turned on by the FIPSMode feature gate, or always on for binaries built with
+// GOEXPERIMENT=boringcrypto, which use the FIPS 140-2 validated BoringCrypto module. Without
+// BoringCrypto only the algorithms are restricted, the crypto module of Go itself is not validated.
+package fips
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/alibaba/hybridnet/pkg/feature"
+)
+
+// minRSAKeySize is the minimum size of RSA keys approved by FIPS 186-4
+const minRSAKeySize = 2048
+
+// CipherSuites are the FIPS-approved cipher suites of TLS 1.2, cipher suites of TLS 1.3 are
+// not configurable in crypto/tls, and are limited to the approved ones with BoringCrypto
+var CipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+// CurvePreferences are the FIPS-approved curves for key exchange
+var CurvePreferences = []tls.CurveID{
+	tls.CurveP256,
+	tls.CurveP384,
+}
+
+// Enabled reports whether FIPS mode is on
+func Enabled() bool {
+	return boringCrypto || feature.FIPSModeEnabled()
+}
+
+// ValidatedModule reports whether the FIPS validated BoringCrypto module is in use
+func ValidatedModule() bool {
+	return boringCryptoEnabled()
+}
+
+// Validate checks on startup that FIPS mode can be honored
+func Validate() error {
+	if boringCrypto && !boringCryptoEnabled() {
+		return fmt.Errorf("binary is built with boringcrypto but BoringCrypto module is not in use")
+	}
+	return nil
+}
+
+// RestrictTLSConfig limits cfg to FIPS-approved protocol versions, cipher suites and curves
+// if FIPS mode is on, it must be called after other fields of cfg are set. TLS 1.3 is kept,
+// so configs requiring TLS 1.3 still work in FIPS mode.
+func RestrictTLSConfig(cfg *tls.Config) {
+	if !Enabled() {
+		return
+	}
+
+	if cfg.MinVersion < tls.VersionTLS12 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+	cfg.CipherSuites = CipherSuites
+	cfg.CurvePreferences = CurvePreferences
+}
+
+// ValidateCertificate checks the public key of cert is of FIPS-approved algorithm and size
+// if FIPS mode is on
+func ValidateCertificate(cert *x509.Certificate) error {
+	if !Enabled() {
+		return nil
+	}
+
+	switch publicKey := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if publicKey.N.BitLen() < minRSAKeySize {
+			return fmt.Errorf("rsa key size %d of certificate %s is less than %d", publicKey.N.BitLen(),
+				cert.Subject, minRSAKeySize)
+		}
+	case *ecdsa.PublicKey:
+		if publicKey.Curve != elliptic.P256() && publicKey.Curve != elliptic.P384() &&
+			publicKey.Curve != elliptic.P521() {
+			return fmt.Errorf("curve %s of certificate %s is not approved", publicKey.Curve.Params().Name, cert.Subject)
+		}
+	default:
+		return fmt.Errorf("public key algorithm %s of certificate %s is not approved", cert.PublicKeyAlgorithm, cert.Subject)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package fips
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+
+	"github.com/alibaba/hybridnet/pkg/feature"
+)
+
+func TestRestrictTLSConfig(t *testing.T) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS13}
+	if !boringCrypto {
+		RestrictTLSConfig(cfg)
+		if cfg.MinVersion != tls.VersionTLS13 || cfg.CipherSuites != nil {
+			t.Errorf("tls config is not expected to be changed while fips mode is off")
+		}
+	}
+
+	enableFIPSMode(t)
+
+	RestrictTLSConfig(cfg)
+	if cfg.MinVersion != tls.VersionTLS13 || cfg.MaxVersion != 0 {
+		t.Errorf("expect tls 1.3 kept but got min %x and max %x", cfg.MinVersion, cfg.MaxVersion)
+	}
+
+	legacyCfg := &tls.Config{MinVersion: tls.VersionTLS10}
+	RestrictTLSConfig(legacyCfg)
+	if legacyCfg.MinVersion != tls.VersionTLS12 || legacyCfg.MaxVersion != 0 {
+		t.Errorf("expect tls 1.2 and above allowed but got min %x and max %x", legacyCfg.MinVersion, legacyCfg.MaxVersion)
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		for _, id := range cfg.CipherSuites {
+			if suite.ID == id {
+				t.Errorf("insecure cipher suite %s is allowed", suite.Name)
+			}
+		}
+	}
+}
+
+func TestValidateCertificate(t *testing.T) {
+	enableFIPSMode(t)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("unable to generate rsa key: %v", err)
+	}
+	ecdsaP224Key, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate ecdsa key: %v", err)
+	}
+	ecdsaP256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate ecdsa key: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		cert      *x509.Certificate
+		expectErr bool
+	}{
+		{
+			"short rsa key",
+			&x509.Certificate{PublicKey: &rsaKey.PublicKey, PublicKeyAlgorithm: x509.RSA},
+			true,
+		},
+		{
+			"unapproved curve",
+			&x509.Certificate{PublicKey: &ecdsaP224Key.PublicKey, PublicKeyAlgorithm: x509.ECDSA},
+			true,
+		},
+		{
+			"p256",
+			&x509.Certificate{PublicKey: &ecdsaP256Key.PublicKey, PublicKeyAlgorithm: x509.ECDSA},
+			false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := ValidateCertificate(test.cert); (err != nil) != test.expectErr {
+				t.Errorf("test %s fail, expect error %v but got %v", test.name, test.expectErr, err)
+			}
+		})
+	}
+}
+
+func enableFIPSMode(t *testing.T) {
+	if err := utilfeature.DefaultMutableFeatureGate.Set(string(feature.FIPSMode) + "=true"); err != nil {
+		t.Fatalf("unable to enable fips mode: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = utilfeature.DefaultMutableFeatureGate.Set(string(feature.FIPSMode) + "=false")
+	})
+}
@@ -0,0 +1,281 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package mtls builds mutual TLS configurations for the channels between manager and daemons.
+// Every component is identified by a SPIFFE-style URI SAN in its certificate, which is
+// spiffe://<trust domain>/hybridnet/<role>[/<name>], and only the expected roles are accepted
+// as peers. Certificates and CA bundle are reloaded from files, so they can be rotated
+// without restart.
+package mtls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+
+	"github.com/alibaba/hybridnet/pkg/utils/fips"
+)
+
+type Role string
+
+const (
+	RoleManager Role = "manager"
+	RoleDaemon  Role = "daemon"
+	// RoleIPAMClient is of the external consumers of IPAM service, e.g., VM and bare metal provisioners
+	RoleIPAMClient Role = "ipam-client"
+)
+
+const (
+	spiffeScheme = "spiffe"
+	pathPrefix   = "hybridnet"
+)
+
+// Identity is the parsed SPIFFE ID of a component
+type Identity struct {
+	TrustDomain string
+	Role        Role
+	// Name is optional, e.g. node name of daemon
+	Name string
+}
+
+func (i Identity) String() string {
+	return i.URL().String()
+}
+
+func (i Identity) URL() *url.URL {
+	path := "/" + pathPrefix + "/" + string(i.Role)
+	if len(i.Name) > 0 {
+		path += "/" + i.Name
+	}
+	return &url.URL{Scheme: spiffeScheme, Host: i.TrustDomain, Path: path}
+}
+
+// ParseIdentity parses a SPIFFE ID of hybridnet components
+func ParseIdentity(u *url.URL) (*Identity, error) {
+	if u == nil || u.Scheme != spiffeScheme || len(u.Host) == 0 {
+		return nil, fmt.Errorf("%v is not a spiffe id", u)
+	}
+
+	segments := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+	if len(segments) < 2 || len(segments) > 3 || segments[0] != pathPrefix {
+		return nil, fmt.Errorf("spiffe id %v is not of hybridnet components", u)
+	}
+
+	identity := &Identity{
+		TrustDomain: u.Host,
+		Role:        Role(segments[1]),
+	}
+	if len(segments) == 3 {
+		identity.Name = segments[2]
+	}
+	return identity, nil
+}
+
+type Config struct {
+	CertFile string
+	KeyFile  string
+	// CAFile is the bundle of CAs which sign certificates of all components
+	CAFile string
+
+	TrustDomain string
+}
+
+// NewServerTLSConfig returns a TLS config which requires client certificates of the allowed roles.
+// Certificate watching stops when ctx is done.
+func NewServerTLSConfig(ctx context.Context, config Config, allowedRoles ...Role) (*tls.Config, error) {
+	watcher, caPool, err := newWatchers(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS13,
+		GetCertificate: watcher.GetCertificate,
+		// peer is verified against the reloadable CA bundle in VerifyPeerCertificate
+		ClientAuth: tls.RequireAnyClientCert,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			_, err := verifyPeer(rawCerts, caPool, x509.ExtKeyUsageClientAuth, config.TrustDomain, allowedRoles)
+			return err
+		},
+	}
+	fips.RestrictTLSConfig(tlsConfig)
+	return tlsConfig, nil
+}
+
+// NewClientTLSConfig returns a TLS config which presents client certificate and only trusts
+// servers of the allowed roles. Certificate watching stops when ctx is done.
+func NewClientTLSConfig(ctx context.Context, config Config, allowedRoles ...Role) (*tls.Config, error) {
+	watcher, caPool, err := newWatchers(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS13,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return watcher.GetCertificate(nil)
+		},
+		// servers are identified by SPIFFE ID instead of host name, verification of chain and
+		// identity is done in VerifyPeerCertificate
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			_, err := verifyPeer(rawCerts, caPool, x509.ExtKeyUsageServerAuth, config.TrustDomain, allowedRoles)
+			return err
+		},
+	}
+	fips.RestrictTLSConfig(tlsConfig)
+	return tlsConfig, nil
+}
+
+// PeerIdentity returns the identity of verified peer of connection
+func PeerIdentity(state tls.ConnectionState) (*Identity, error) {
+	if len(state.PeerCertificates) == 0 {
+		return nil, fmt.Errorf("no peer certificate")
+	}
+	return identityOfCertificate(state.PeerCertificates[0])
+}
+
+func newWatchers(ctx context.Context, config Config) (*certwatcher.CertWatcher, *reloadablePool, error) {
+	if len(config.TrustDomain) == 0 {
+		return nil, nil, fmt.Errorf("trust domain must be specified")
+	}
+
+	watcher, err := certwatcher.New(config.CertFile, config.KeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to load certificate: %v", err)
+	}
+
+	caPool := &reloadablePool{file: config.CAFile}
+	if _, err = caPool.get(); err != nil {
+		return nil, nil, fmt.Errorf("unable to load ca bundle: %v", err)
+	}
+
+	go func() {
+		_ = watcher.Start(ctx)
+	}()
+	return watcher, caPool, nil
+}
+
+func verifyPeer(rawCerts [][]byte, caPool *reloadablePool, usage x509.ExtKeyUsage, trustDomain string,
+	allowedRoles []Role) (*Identity, error) {
+	if len(rawCerts) == 0 {
+		return nil, fmt.Errorf("no peer certificate")
+	}
+
+	var certs = make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse peer certificate: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	roots, err := caPool.get()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load ca bundle: %v", err)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err = certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	}); err != nil {
+		return nil, fmt.Errorf("unable to verify peer certificate: %v", err)
+	}
+	if err = fips.ValidateCertificate(certs[0]); err != nil {
+		return nil, fmt.Errorf("peer certificate is not allowed in fips mode: %v", err)
+	}
+
+	identity, err := identityOfCertificate(certs[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if identity.TrustDomain != trustDomain {
+		return nil, fmt.Errorf("peer %v is not in trust domain %s", identity, trustDomain)
+	}
+	for _, role := range allowedRoles {
+		if identity.Role == role {
+			return identity, nil
+		}
+	}
+	return nil, fmt.Errorf("peer %v is not one of roles %v", identity, allowedRoles)
+}
+
+func identityOfCertificate(cert *x509.Certificate) (*Identity, error) {
+	// a SPIFFE-style certificate has exactly one URI SAN
+	if len(cert.URIs) != 1 {
+		return nil, fmt.Errorf("peer certificate must have exactly one uri san, but got %d", len(cert.URIs))
+	}
+	return ParseIdentity(cert.URIs[0])
+}
+
+// reloadablePool reloads the CA bundle when file is modified, so that CA rotation
+// takes effect on next handshake
+type reloadablePool struct {
+	sync.Mutex
+
+	file    string
+	modTime time.Time
+	pool    *x509.CertPool
+}
+
+func (r *reloadablePool) get() (*x509.CertPool, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	info, err := os.Stat(r.file)
+	if err != nil {
+		if r.pool != nil {
+			// keep the last valid bundle while file is being replaced
+			return r.pool, nil
+		}
+		return nil, err
+	}
+
+	if r.pool != nil && info.ModTime().Equal(r.modTime) {
+		return r.pool, nil
+	}
+
+	data, err := os.ReadFile(r.file)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		if r.pool != nil {
+			return r.pool, nil
+		}
+		return nil, fmt.Errorf("no valid certificate in %s", r.file)
+	}
+
+	r.pool, r.modTime = pool, info.ModTime()
+	return r.pool, nil
+}
@@ -0,0 +1,261 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package mtls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+
+	"github.com/alibaba/hybridnet/pkg/feature"
+)
+
+func TestParseIdentity(t *testing.T) {
+	tests := []struct {
+		name   string
+		uri    string
+		expect *Identity
+	}{
+		{
+			"manager",
+			"spiffe://cluster.local/hybridnet/manager",
+			&Identity{TrustDomain: "cluster.local", Role: RoleManager},
+		},
+		{
+			"daemon with node name",
+			"spiffe://cluster.local/hybridnet/daemon/node1",
+			&Identity{TrustDomain: "cluster.local", Role: RoleDaemon, Name: "node1"},
+		},
+		{
+			"not spiffe",
+			"https://cluster.local/hybridnet/manager",
+			nil,
+		},
+		{
+			"not hybridnet",
+			"spiffe://cluster.local/ns/default/sa/default",
+			nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			u, _ := url.Parse(test.uri)
+			identity, err := ParseIdentity(u)
+			if test.expect == nil {
+				if err == nil {
+					t.Errorf("expect error but got %v", identity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *identity != *test.expect {
+				t.Errorf("expect %v but got %v", test.expect, identity)
+			}
+			if identity.String() != test.uri {
+				t.Errorf("expect %s but got %s", test.uri, identity.String())
+			}
+		})
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := newCA(t, dir)
+
+	manager := writeLeaf(t, dir, "manager", ca, caKey, Identity{TrustDomain: "cluster.local", Role: RoleManager})
+	daemon := writeLeaf(t, dir, "daemon", ca, caKey, Identity{TrustDomain: "cluster.local", Role: RoleDaemon, Name: "node1"})
+	foreign := writeLeaf(t, dir, "foreign", ca, caKey, Identity{TrustDomain: "other.local", Role: RoleManager})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tests := []struct {
+		name   string
+		server Config
+		client Config
+		// roles allowed by server and client
+		serverAllowed Role
+		clientAllowed Role
+		fipsMode      bool
+		expectSuccess bool
+	}{
+		{"daemon to manager", manager, daemon, RoleDaemon, RoleManager, false, true},
+		{"daemon to manager in fips mode", manager, daemon, RoleDaemon, RoleManager, true, true},
+		{"daemon impersonates manager", daemon, daemon, RoleDaemon, RoleManager, false, false},
+		{"manager impersonates daemon", manager, manager, RoleDaemon, RoleManager, false, false},
+		{"foreign trust domain", manager, foreign, RoleManager, RoleManager, false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.fipsMode {
+				if err := utilfeature.DefaultMutableFeatureGate.Set(string(feature.FIPSMode) + "=true"); err != nil {
+					t.Fatalf("unable to enable fips mode: %v", err)
+				}
+				t.Cleanup(func() {
+					_ = utilfeature.DefaultMutableFeatureGate.Set(string(feature.FIPSMode) + "=false")
+				})
+			}
+
+			serverConfig, err := NewServerTLSConfig(ctx, test.server, test.serverAllowed)
+			if err != nil {
+				t.Fatalf("unable to build server config: %v", err)
+			}
+			clientConfig, err := NewClientTLSConfig(ctx, test.client, test.clientAllowed)
+			if err != nil {
+				t.Fatalf("unable to build client config: %v", err)
+			}
+
+			listener, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+			if err != nil {
+				t.Fatalf("unable to listen: %v", err)
+			}
+			defer listener.Close()
+
+			type result struct {
+				conn *tls.Conn
+				err  error
+			}
+			serverResult := make(chan result, 1)
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					serverResult <- result{err: err}
+					return
+				}
+				tlsConn := conn.(*tls.Conn)
+				_ = tlsConn.SetDeadline(time.Now().Add(10 * time.Second))
+				serverResult <- result{conn: tlsConn, err: tlsConn.Handshake()}
+			}()
+
+			// client certificate is verified by server after client handshake in TLS 1.3,
+			// so the result of server side is checked first
+			client, clientErr := tls.DialWithDialer(&net.Dialer{Timeout: 10 * time.Second}, "tcp",
+				listener.Addr().String(), clientConfig)
+			server := <-serverResult
+			if client != nil {
+				defer client.Close()
+			}
+			if server.conn != nil {
+				defer server.conn.Close()
+			}
+
+			if err = server.err; err == nil {
+				err = clientErr
+			}
+
+			if test.expectSuccess && err != nil {
+				t.Errorf("expect handshake to succeed, but got %v", err)
+			}
+			if !test.expectSuccess && err == nil {
+				t.Errorf("expect handshake to fail")
+			}
+
+			if test.expectSuccess {
+				identity, err := PeerIdentity(server.conn.ConnectionState())
+				if err != nil || identity.Role != RoleDaemon || identity.Name != "node1" {
+					t.Errorf("unexpected peer identity %v, %v", identity, err)
+				}
+				if version := server.conn.ConnectionState().Version; version != tls.VersionTLS13 {
+					t.Errorf("expect tls 1.3 negotiated but got %x", version)
+				}
+			}
+		})
+	}
+}
+
+func newCA(t *testing.T, dir string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "hybridnet-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unable to create ca: %v", err)
+	}
+	writePEM(t, filepath.Join(dir, "ca.crt"), "CERTIFICATE", raw)
+
+	ca, _ := x509.ParseCertificate(raw)
+	return ca, key
+}
+
+func writeLeaf(t *testing.T, dir, name string, ca *x509.Certificate, caKey *ecdsa.PrivateKey, identity Identity) Config {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		URIs:         []*url.URL{identity.URL()},
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("unable to create certificate: %v", err)
+	}
+	keyRaw, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("unable to marshal key: %v", err)
+	}
+
+	config := Config{
+		CertFile:    filepath.Join(dir, name+".crt"),
+		KeyFile:     filepath.Join(dir, name+".key"),
+		CAFile:      filepath.Join(dir, "ca.crt"),
+		TrustDomain: "cluster.local",
+	}
+	writePEM(t, config.CertFile, "CERTIFICATE", raw)
+	writePEM(t, config.KeyFile, "EC PRIVATE KEY", keyRaw)
+	return config
+}
+
+func writePEM(t *testing.T, file, blockType string, raw []byte) {
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: raw}), 0600); err != nil {
+		t.Fatalf("unable to write %s: %v", file, err)
+	}
+}
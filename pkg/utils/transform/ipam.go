@@ -29,28 +29,68 @@ import (
 func TransferSubnetForIPAM(in *v1.Subnet) *ipamtypes.Subnet {
 	_, cidr, _ := net.ParseCIDR(in.Spec.Range.CIDR)
 
-	return ipamtypes.NewSubnet(in.Name,
+	subnet := ipamtypes.NewSubnet(in.Name,
 		in.Spec.Network,
 		int32pToUint32p(in.Spec.NetID),
 		net.ParseIP(in.Spec.Range.Start),
 		net.ParseIP(in.Spec.Range.End),
 		net.ParseIP(in.Spec.Range.Gateway),
 		cidr,
-		utils.StringSliceToMap(in.Spec.Range.ReservedIPs),
+		utils.StringSliceToMap(v1.GetReservedIPsOfRange(&in.Spec.Range)),
 		utils.StringSliceToMap(in.Spec.Range.ExcludeIPs),
 		net.ParseIP(in.Status.LastAllocatedIP),
-		v1.IsPrivateSubnet(in),
+		// namespace-restricted subnet is private for IPAM, which will never be chosen automatically
+		v1.IsPrivateSubnet(in) || v1.IsNamespaceRestrictedSubnet(in),
 		v1.IsIPv6Subnet(in),
 	)
+	subnet.Cordoned = v1.IsCordonedSubnet(in)
+	subnet.Topology = in.Spec.Topology
+	subnet.FreezeWindows = transferFreezeWindows(&in.Spec)
+	for _, rr := range in.Spec.Range.ReservedRanges {
+		subnet.ReservedRanges = append(subnet.ReservedRanges, &ipamtypes.IPRange{
+			Start: net.ParseIP(rr.Start),
+			End:   net.ParseIP(rr.End),
+		})
+	}
+	return subnet
+}
+
+// transferFreezeWindows skips invalid windows, which should have been denied by webhook
+func transferFreezeWindows(in *v1.SubnetSpec) []*ipamtypes.FreezeWindow {
+	var windows []*ipamtypes.FreezeWindow
+	for _, window := range v1.GetSubnetAllocationFreezeWindows(in) {
+		schedule, duration, location, err := v1.ParseAllocationFreezeWindow(&window)
+		if err != nil {
+			continue
+		}
+		windows = append(windows, &ipamtypes.FreezeWindow{
+			Schedule: schedule,
+			Duration: duration,
+			Location: location,
+			Reason:   window.Reason,
+		})
+	}
+	return windows
 }
 
 func TransferNetworkForIPAM(in *v1.Network) *ipamtypes.Network {
-	return ipamtypes.NewNetwork(in.Name,
+	network := ipamtypes.NewNetwork(in.Name,
 		int32pToUint32p(in.Spec.NetID),
 		in.Status.LastAllocatedSubnet,
 		in.Status.LastAllocatedIPv6Subnet,
 		ipamtypes.ParseNetworkTypeFromString(string(v1.GetNetworkType(in))),
 	)
+	network.Cordoned = v1.IsCordonedNetwork(in)
+	if in.Spec.Default != nil {
+		network.Default = &ipamtypes.DefaultPolicy{
+			Priority: in.Spec.Default.Priority,
+			Weight:   1,
+		}
+		if in.Spec.Default.Weight != nil {
+			network.Default.Weight = *in.Spec.Default.Weight
+		}
+	}
+	return network
 }
 
 func TransferIPInstanceForIPAM(in *v1.IPInstance) *ipamtypes.IP {
@@ -21,13 +21,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
 	"github.com/alibaba/hybridnet/pkg/constants"
+	"github.com/alibaba/hybridnet/pkg/feature"
 	ipamtypes "github.com/alibaba/hybridnet/pkg/ipam/types"
 	webhookutils "github.com/alibaba/hybridnet/pkg/webhook/utils"
 )
@@ -63,6 +71,15 @@ func PodCreateMutation(ctx context.Context, req *admission.Request, handler *Han
 			}), logger)
 	}
 
+	// translate the referred network claim into network annotations before parsing
+	var denyReason string
+	if denyReason, err = applyNetworkClaimToPod(ctx, handler.Cache, pod); err != nil {
+		return webhookutils.AdmissionErroredWithLog(http.StatusInternalServerError, err, logger)
+	}
+	if len(denyReason) > 0 {
+		return webhookutils.AdmissionDeniedWithLog(denyReason, logger)
+	}
+
 	// select 4 networking configs in order as below
 	var (
 		networkName     string
@@ -80,6 +97,43 @@ func PodCreateMutation(ctx context.Context, req *admission.Request, handler *Han
 		return webhookutils.AdmissionErroredWithLog(http.StatusInternalServerError, fmt.Errorf("unable to parse network config for pod: %v", err), logger)
 	}
 
+	// pods with retained ip addresses can still be created on cordoned networks
+	if len(networkName) > 0 && !retainedIPExist {
+		var network = &networkingv1.Network{}
+		if err = handler.Cache.Get(ctx, types.NamespacedName{Name: networkName}, network); err != nil {
+			return webhookutils.AdmissionErroredWithLog(http.StatusInternalServerError, fmt.Errorf("unable to get network %s: %v", networkName, err), logger)
+		}
+		if networkingv1.IsCordonedNetwork(network) {
+			return webhookutils.AdmissionDeniedWithLog(webhookutils.AllocationFailureDenial(ipamtypes.FailureNetworkCordoned,
+				"network %s is cordoned, no new addresses can be allocated from it", networkName), logger)
+		}
+	}
+
+	// pods with retained ip addresses can still be created on frozen or cordoned subnets
+	if len(subnetNameStr) > 0 && !retainedIPExist {
+		for _, subnetName := range strings.Split(subnetNameStr, "/") {
+			var subnet = &networkingv1.Subnet{}
+			if err = handler.Cache.Get(ctx, types.NamespacedName{Name: subnetName}, subnet); err != nil {
+				if errors.IsNotFound(err) {
+					continue
+				}
+				return webhookutils.AdmissionErroredWithLog(http.StatusInternalServerError, fmt.Errorf("unable to get subnet %s: %v", subnetName, err), logger)
+			}
+			if networkingv1.IsCordonedSubnet(subnet) {
+				return webhookutils.AdmissionDeniedWithLog(webhookutils.AllocationFailureDenial(ipamtypes.FailureSubnetCordoned,
+					"subnet %s is cordoned, no new addresses can be allocated from it", subnetName), logger)
+			}
+			if window, end, frozen := networkingv1.ActiveAllocationFreezeWindow(&subnet.Spec, time.Now()); frozen {
+				message := fmt.Sprintf("subnet %s is frozen for allocation until %s", subnetName, end.Format(time.RFC3339))
+				if len(window.Reason) > 0 {
+					message = fmt.Sprintf("%s: %s", message, window.Reason)
+				}
+				return webhookutils.AdmissionDeniedWithLog(webhookutils.AllocationFailureDenial(ipamtypes.FailureSubnetFrozen,
+					"%s", message), logger)
+			}
+		}
+	}
+
 	// persistent specified network and subnet in pod annotations
 	patchAnnotationToPod(pod, constants.AnnotationSpecifiedNetwork, networkName)
 	patchAnnotationToPod(pod, constants.AnnotationSpecifiedSubnet, subnetNameStr)
@@ -123,6 +177,19 @@ func PodCreateMutation(ctx context.Context, req *admission.Request, handler *Han
 				constants.LabelDualStackAddressQuota: constants.QuotaNonEmpty,
 			})
 		}
+
+		// address requests to make sure scheduler will account addresses
+		// of pods which have not been allocated yet
+		if feature.AddressExtendedResourceEnabled() {
+			switch ipFamily {
+			case ipamtypes.IPv4:
+				patchResourceRequestToPod(pod, constants.ResourceIPv4Address)
+			case ipamtypes.IPv6:
+				patchResourceRequestToPod(pod, constants.ResourceIPv6Address)
+			case ipamtypes.DualStack:
+				patchResourceRequestToPod(pod, constants.ResourceIPv4Address, constants.ResourceIPv6Address)
+			}
+		}
 	case ipamtypes.Overlay:
 		logger.Info("patch pod with overlay attachment selector",
 			"namespace", req.Namespace, "name", req.Name)
@@ -142,6 +209,32 @@ func PodCreateMutation(ctx context.Context, req *admission.Request, handler *Han
 	return generatePatchResponseFromPod(req.Object.Raw, pod, logger)
 }
 
+// applyNetworkClaimToPod writes the spec of PodNetworkClaim referred by pod into its network annotations,
+// which are still where hybridnet components read network intent of pods from. A non-empty reason is
+// returned if the claim does not exist or conflicts with annotations set on pod explicitly.
+func applyNetworkClaimToPod(ctx context.Context, c client.Reader, pod *corev1.Pod) (string, error) {
+	claimName := pod.Annotations[constants.AnnotationNetworkClaim]
+	if len(claimName) == 0 {
+		return "", nil
+	}
+
+	claim := &networkingv1.PodNetworkClaim{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: claimName}, claim); err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Sprintf("network claim %s not found", claimName), nil
+		}
+		return "", fmt.Errorf("unable to get network claim %s: %v", claimName, err)
+	}
+
+	for key, value := range networkingv1.PodNetworkClaimSpecToAnnotations(&claim.Spec) {
+		if existing := pod.Annotations[key]; len(existing) > 0 && !strings.EqualFold(existing, value) {
+			return fmt.Sprintf("annotation %s=%s conflicts with network claim %s", key, existing, claimName), nil
+		}
+		patchAnnotationToPod(pod, key, value)
+	}
+	return "", nil
+}
+
 func generatePatchResponseFromPod(original []byte, pod *corev1.Pod, logger logr.Logger) admission.Response {
 	marshaled, err := json.Marshal(pod)
 	if err != nil {
@@ -162,6 +255,27 @@ func patchSelectorToPod(pod *corev1.Pod, selector map[string]string) {
 	}
 }
 
+// patchResourceRequestToPod makes the first container of pod request one of each specified resource,
+// extended resources can not be overcommitted so the limits must be equal to the requests
+func patchResourceRequestToPod(pod *corev1.Pod, resourceNames ...corev1.ResourceName) {
+	if len(pod.Spec.Containers) == 0 {
+		return
+	}
+
+	container := &pod.Spec.Containers[0]
+	if container.Resources.Requests == nil {
+		container.Resources.Requests = corev1.ResourceList{}
+	}
+	if container.Resources.Limits == nil {
+		container.Resources.Limits = corev1.ResourceList{}
+	}
+
+	for _, resourceName := range resourceNames {
+		container.Resources.Requests[resourceName] = resource.MustParse("1")
+		container.Resources.Limits[resourceName] = resource.MustParse("1")
+	}
+}
+
 func patchAnnotationToPod(pod *corev1.Pod, key, value string) {
 	if len(value) == 0 {
 		return
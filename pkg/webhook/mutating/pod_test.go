@@ -0,0 +1,114 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package mutating
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/alibaba/hybridnet/pkg/constants"
+)
+
+func TestPatchResourceRequestToPod(t *testing.T) {
+	one := resource.MustParse("1")
+	cpu := resource.MustParse("100m")
+
+	tests := []struct {
+		name           string
+		containers     []corev1.Container
+		resourceNames  []corev1.ResourceName
+		expectRequests corev1.ResourceList
+		expectLimits   corev1.ResourceList
+	}{
+		{
+			name:          "no container",
+			resourceNames: []corev1.ResourceName{constants.ResourceIPv4Address},
+		},
+		{
+			name:           "container without resources",
+			containers:     []corev1.Container{{Name: "main"}},
+			resourceNames:  []corev1.ResourceName{constants.ResourceIPv4Address},
+			expectRequests: corev1.ResourceList{constants.ResourceIPv4Address: one},
+			expectLimits:   corev1.ResourceList{constants.ResourceIPv4Address: one},
+		},
+		{
+			name: "existing resources are kept",
+			containers: []corev1.Container{{
+				Name: "main",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: cpu},
+				},
+			}},
+			resourceNames:  []corev1.ResourceName{constants.ResourceIPv4Address},
+			expectRequests: corev1.ResourceList{corev1.ResourceCPU: cpu, constants.ResourceIPv4Address: one},
+			expectLimits:   corev1.ResourceList{constants.ResourceIPv4Address: one},
+		},
+		{
+			name:          "dual stack",
+			containers:    []corev1.Container{{Name: "main"}, {Name: "sidecar"}},
+			resourceNames: []corev1.ResourceName{constants.ResourceIPv4Address, constants.ResourceIPv6Address},
+			expectRequests: corev1.ResourceList{
+				constants.ResourceIPv4Address: one,
+				constants.ResourceIPv6Address: one,
+			},
+			expectLimits: corev1.ResourceList{
+				constants.ResourceIPv4Address: one,
+				constants.ResourceIPv6Address: one,
+			},
+		},
+	}
+
+	equal := func(a, b corev1.ResourceList) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for name, quantity := range a {
+			if expect, exist := b[name]; !exist || quantity.Cmp(expect) != 0 {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: test.containers}}
+			patchResourceRequestToPod(pod, test.resourceNames...)
+
+			if len(pod.Spec.Containers) == 0 {
+				return
+			}
+
+			resources := pod.Spec.Containers[0].Resources
+			if !equal(resources.Requests, test.expectRequests) {
+				t.Errorf("expected requests %v but got %v", test.expectRequests, resources.Requests)
+			}
+			if !equal(resources.Limits, test.expectLimits) {
+				t.Errorf("expected limits %v but got %v", test.expectLimits, resources.Limits)
+			}
+
+			// only the first container requests addresses
+			for _, container := range pod.Spec.Containers[1:] {
+				if len(container.Resources.Requests) != 0 || len(container.Resources.Limits) != 0 {
+					t.Errorf("expected no resource of container %s but got %+v", container.Name, container.Resources)
+				}
+			}
+		})
+	}
+}
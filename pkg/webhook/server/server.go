@@ -0,0 +1,95 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto/tls"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	"github.com/alibaba/hybridnet/pkg/utils/fips"
+	"github.com/alibaba/hybridnet/pkg/webhook/mutating"
+	"github.com/alibaba/hybridnet/pkg/webhook/validating"
+)
+
+// Register registers validating and mutating handlers of hybridnet on webhook server, the handlers
+// look up objects through the client and cache of the manager which the server belongs to, so that
+// they share informers with controllers if the server is in hybridnet manager
+func Register(server *webhook.Server) {
+	server.Register("/validate", &webhook.Admission{
+		Handler: validating.NewHandler(),
+	})
+	server.Register("/mutate", &webhook.Admission{
+		Handler: mutating.NewHandler(),
+	})
+}
+
+// TLSOpts returns the tls options of webhook server, which disables insecure cipher suites
+// and keeps algorithms approved in fips mode
+func TLSOpts() []func(*tls.Config) {
+	return []func(*tls.Config){
+		func(cfg *tls.Config) {
+			cfg.CipherSuites = cipherOrder()
+			cfg.MinVersion = tls.VersionTLS12
+			fips.RestrictTLSConfig(cfg)
+		},
+	}
+}
+
+// Disable insecure cipher suites for CVE-2016-2183
+// cipherOrder returns an ordered list of Ciphers that are considered secure
+// Deprecated ciphers are not returned.
+func cipherOrder() []uint16 {
+	var first []uint16
+	var second []uint16
+
+	allowable := func(c *tls.CipherSuite) bool {
+		// Disallow block ciphers using straight SHA1
+		// See: https://tools.ietf.org/html/rfc7540#appendix-A
+		if strings.HasSuffix(c.Name, "CBC_SHA") {
+			return false
+		}
+		// 3DES is considered insecure
+		if strings.Contains(c.Name, "3DES") {
+			return false
+		}
+		return true
+	}
+
+	for _, c := range tls.CipherSuites() {
+		for _, v := range c.SupportedVersions {
+			if v == tls.VersionTLS13 {
+				first = append(first, c.ID)
+			}
+			if v == tls.VersionTLS12 && allowable(c) {
+				inFirst := false
+				for _, id := range first {
+					if c.ID == id {
+						inFirst = true
+						break
+					}
+				}
+				if !inFirst {
+					second = append(second, c.ID)
+				}
+			}
+		}
+	}
+
+	return append(first, second...)
+}
@@ -0,0 +1,66 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+)
+
+// VerbRebind is the verb on ipinstances which authorizes users to move addresses between pods,
+// updating ip instances is not enough as a rebind takes the address away from its pod
+const VerbRebind = "rebind"
+
+// AuthorizeIPInstanceRebind checks if the user of an admission request is allowed to rebind ip
+// instances in namespace through a SubjectAccessReview
+func AuthorizeIPInstanceRebind(ctx context.Context, c client.Client, userInfo authenticationv1.UserInfo,
+	namespace string) (allowed bool, reason string, err error) {
+	var extra = make(map[string]authorizationv1.ExtraValue, len(userInfo.Extra))
+	for key, value := range userInfo.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      VerbRebind,
+				Group:     networkingv1.GroupVersion.Group,
+				Resource:  "ipinstances",
+			},
+			User:   userInfo.Username,
+			Groups: userInfo.Groups,
+			Extra:  extra,
+			UID:    userInfo.UID,
+		},
+	}
+	if err = c.Create(ctx, review); err != nil {
+		return false, "", fmt.Errorf("unable to review access of user %s: %v", userInfo.Username, err)
+	}
+
+	if !review.Status.Allowed {
+		return false, fmt.Sprintf("user %s is not allowed to %s ipinstances in namespace %s: %s",
+			userInfo.Username, VerbRebind, namespace, review.Status.Reason), nil
+	}
+	return true, "", nil
+}
@@ -0,0 +1,90 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// reviewingClient answers access reviews by the allowed users
+type reviewingClient struct {
+	client.Client
+	allowedUsers map[string]bool
+	reviewed     *authorizationv1.SubjectAccessReview
+}
+
+func (c *reviewingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	review := obj.(*authorizationv1.SubjectAccessReview)
+	review.Status.Allowed = c.allowedUsers[review.Spec.User]
+	c.reviewed = review
+	return nil
+}
+
+func TestAuthorizeIPInstanceRebind(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("unable to build scheme: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		user          string
+		expectAllowed bool
+	}{
+		{
+			name:          "allowed user",
+			user:          "migrator",
+			expectAllowed: true,
+		},
+		{
+			name: "user without rebind verb",
+			user: "editor",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &reviewingClient{
+				Client:       fake.NewClientBuilder().WithScheme(scheme).Build(),
+				allowedUsers: map[string]bool{"migrator": true},
+			}
+
+			allowed, reason, err := AuthorizeIPInstanceRebind(context.Background(), c,
+				authenticationv1.UserInfo{Username: test.user, Groups: []string{"group"}}, "default")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if allowed != test.expectAllowed {
+				t.Errorf("expected allowed %v but got %v, reason: %s", test.expectAllowed, allowed, reason)
+			}
+
+			attributes := c.reviewed.Spec.ResourceAttributes
+			if attributes.Verb != VerbRebind || attributes.Resource != "ipinstances" || attributes.Namespace != "default" ||
+				c.reviewed.Spec.Groups[0] != "group" {
+				t.Errorf("unexpected access review %+v", c.reviewed.Spec)
+			}
+		})
+	}
+}
@@ -10,10 +10,14 @@ import (
 	ipamtypes "github.com/alibaba/hybridnet/pkg/ipam/types"
 
 	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -108,6 +112,12 @@ func AdmissionDeniedWithLog(reason string, logger logr.Logger) admission.Respons
 	return admission.Denied(reason)
 }
 
+// AllocationFailureDenial formats a deny reason prefixed with the reason of allocation failure,
+// which is the same as in events of pods failing in allocation
+func AllocationFailureDenial(reason ipamtypes.AllocationFailureReason, format string, args ...interface{}) string {
+	return ipamtypes.DescribeAllocationFailure(ipamtypes.NewAllocationError(reason, format, args...))
+}
+
 // ParseNetworkConfigOfPodByPriority will try to parse network-related configs for pod by priority as below,
 // 1. if pod was stateful allocated and no need to be reallocated, reusing the existing network
 // 2. if pod have labels or annotations which contain network config, use it all
@@ -138,7 +148,13 @@ func ParseNetworkConfigOfPodByPriority(ctx context.Context, c client.Reader, pod
 	)
 
 	// priority 1
-	if strategy.OwnByStatefulWorkload(pod) {
+	var statefulOwner *metav1.OwnerReference
+	if statefulOwner, err = strategy.ResolveStatefulWorkloadOwner(ctx, pod, c); err != nil {
+		err = fmt.Errorf("unable to resolve stateful owner of pod %v/%v: %v", pod.Namespace, pod.Name, err)
+		return
+	}
+
+	if statefulOwner != nil {
 		var shouldReuse = utils.ParseBoolOrDefault(pod.Annotations[constants.AnnotationIPRetain], strategy.DefaultIPRetain)
 		if shouldReuse {
 			// if networkName is not empty, elected will be true
@@ -198,9 +214,25 @@ func ParseNetworkConfigOfPodByPriority(ctx context.Context, c client.Reader, pod
 		}
 	}
 
+	// ip family preference of workload only fills the ip family not specified by pod or namespace
+	if len(ipFamily) == 0 && len(ipFamilyStr) == 0 {
+		if ipFamilyStr, err = ResolveIPFamilyOfWorkload(ctx, c, pod); err != nil {
+			err = fmt.Errorf("unable to resolve ip family of workload of pod %v/%v: %v", pod.Namespace, pod.Name, err)
+			return
+		}
+	}
+
 	networkType = ipamtypes.ParseNetworkTypeFromString(networkTypeStr)
 	if len(ipFamily) == 0 {
+		// the default ip family could be missing on the target subnets, e.g., IPv4 on ipv6-only clusters
+		var ipFamilyDefaulted = len(ipFamilyStr) == 0
 		ipFamily = ipamtypes.ParseIPFamilyFromString(ipFamilyStr)
+		if ipFamilyDefaulted {
+			if ipFamily, err = fallbackDefaultIPFamily(ctx, c, ipFamily, networkName, subnetNameStr); err != nil {
+				err = fmt.Errorf("unable to fall back default ip family of pod %v/%v: %v", pod.Namespace, pod.Name, err)
+				return
+			}
+		}
 	}
 
 	if len(networkName) > 0 {
@@ -237,6 +269,57 @@ func ParseNetworkConfigOfPodByPriority(ctx context.Context, c client.Reader, pod
 	return
 }
 
+// fallbackDefaultIPFamily returns the other single-stack family if the default one is not served by any
+// candidate subnet but the other one is, candidate subnets are the specified subnets if any, otherwise the
+// subnets of specified network, otherwise all subnets
+func fallbackDefaultIPFamily(ctx context.Context, c client.Reader, defaultIPFamily ipamtypes.IPFamilyMode,
+	networkName, subnetNameStr string) (ipamtypes.IPFamilyMode, error) {
+	var fallbackIPFamily ipamtypes.IPFamilyMode
+	switch defaultIPFamily {
+	case ipamtypes.IPv4:
+		fallbackIPFamily = ipamtypes.IPv6
+	case ipamtypes.IPv6:
+		fallbackIPFamily = ipamtypes.IPv4
+	default:
+		return defaultIPFamily, nil
+	}
+
+	var subnets []networkingv1.Subnet
+	if subnetNames := specifiedSubnetStrToSubnetNames(subnetNameStr); len(subnetNames) > 0 {
+		for _, subnetName := range subnetNames {
+			subnet := &networkingv1.Subnet{}
+			if err := c.Get(ctx, types.NamespacedName{Name: subnetName}, subnet); err != nil {
+				return "", fmt.Errorf("failed to get subnet %v: %v", subnetName, err)
+			}
+			subnets = append(subnets, *subnet)
+		}
+	} else {
+		subnetList := &networkingv1.SubnetList{}
+		if err := c.List(ctx, subnetList); err != nil {
+			return "", fmt.Errorf("failed to list subnets: %v", err)
+		}
+		for i := range subnetList.Items {
+			if len(networkName) == 0 || subnetList.Items[i].Spec.Network == networkName {
+				subnets = append(subnets, subnetList.Items[i])
+			}
+		}
+	}
+
+	var served = map[ipamtypes.IPFamilyMode]bool{}
+	for i := range subnets {
+		if subnets[i].Spec.Range.Version == networkingv1.IPv6 {
+			served[ipamtypes.IPv6] = true
+		} else {
+			served[ipamtypes.IPv4] = true
+		}
+	}
+
+	if !served[defaultIPFamily] && served[fallbackIPFamily] {
+		return fallbackIPFamily, nil
+	}
+	return defaultIPFamily, nil
+}
+
 func parseNetworkConfigByExistIPInstances(ctx context.Context, c client.Reader, opts ...client.ListOption) (networkName string,
 	ipFamily ipamtypes.IPFamilyMode, err error) {
 	ipList := &networkingv1.IPInstanceList{}
@@ -286,3 +369,52 @@ func parseNetworkConfigByExistIPInstances(ctx context.Context, c client.Reader,
 
 	return
 }
+
+// ResolveIPFamilyOfWorkload returns the ip family label of the Deployment or StatefulSet which pod
+// belongs to, an empty string is returned if pod is not created by them or the label is not set.
+func ResolveIPFamilyOfWorkload(ctx context.Context, c client.Reader, pod *corev1.Pod) (string, error) {
+	var current client.Object = pod
+	// pod is controlled by StatefulSet directly, or by Deployment through ReplicaSet
+	for depth := 0; depth < 2; depth++ {
+		ref := metav1.GetControllerOf(current)
+		if ref == nil {
+			return "", nil
+		}
+
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil || gv.Group != appsv1.GroupName {
+			return "", nil
+		}
+		switch ref.Kind {
+		case "ReplicaSet", "Deployment", "StatefulSet":
+		default:
+			return "", nil
+		}
+
+		owner := &metav1.PartialObjectMetadata{}
+		owner.SetGroupVersionKind(gv.WithKind(ref.Kind))
+		if err = c.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: ref.Name}, owner); err != nil {
+			if apierrors.IsNotFound(err) {
+				return "", nil
+			}
+			return "", fmt.Errorf("failed to get %v %v/%v: %v", ref.Kind, pod.Namespace, ref.Name, err)
+		}
+
+		// owner was recreated with the same name
+		if owner.GetUID() != ref.UID {
+			return "", nil
+		}
+
+		if ref.Kind == "ReplicaSet" {
+			current = owner
+			continue
+		}
+
+		ipFamily := owner.GetLabels()[constants.LabelIPFamily]
+		if len(ipFamily) > 0 && !ipamtypes.IsValidFamilyMode(ipamtypes.ParseIPFamilyFromString(ipFamily)) {
+			return "", fmt.Errorf("invalid ip family label %q of %v %v/%v", ipFamily, ref.Kind, pod.Namespace, ref.Name)
+		}
+		return ipFamily, nil
+	}
+	return "", nil
+}
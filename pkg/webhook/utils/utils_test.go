@@ -0,0 +1,210 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/constants"
+	ipamtypes "github.com/alibaba/hybridnet/pkg/ipam/types"
+)
+
+func TestResolveIPFamilyOfWorkload(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("unable to build scheme: %v", err)
+	}
+
+	isController := true
+	controllerRef := func(kind, name string, uid types.UID) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{
+			APIVersion: "apps/v1",
+			Kind:       kind,
+			Name:       name,
+			UID:        uid,
+			Controller: &isController,
+		}}
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default", Name: "web", UID: "deploy-uid",
+			Labels: map[string]string{constants.LabelIPFamily: "IPv6Only"},
+		}},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default", Name: "web-1", UID: "rs-uid",
+			OwnerReferences: controllerRef("Deployment", "web", "deploy-uid"),
+		}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default", Name: "db", UID: "sts-uid",
+			Labels: map[string]string{constants.LabelIPFamily: "DualStack"},
+		}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default", Name: "invalid", UID: "invalid-uid",
+			Labels: map[string]string{constants.LabelIPFamily: "IPv5"},
+		}},
+	).Build()
+
+	tests := []struct {
+		name      string
+		owners    []metav1.OwnerReference
+		expect    string
+		expectErr bool
+	}{
+		{
+			name:   "deployment",
+			owners: controllerRef("ReplicaSet", "web-1", "rs-uid"),
+			expect: "IPv6Only",
+		},
+		{
+			name:   "statefulset",
+			owners: controllerRef("StatefulSet", "db", "sts-uid"),
+			expect: "DualStack",
+		},
+		{
+			name:   "recreated statefulset",
+			owners: controllerRef("StatefulSet", "db", "old-uid"),
+		},
+		{
+			name:   "statefulset not found",
+			owners: controllerRef("StatefulSet", "cache", "cache-uid"),
+		},
+		{
+			name:      "invalid label",
+			owners:    controllerRef("StatefulSet", "invalid", "invalid-uid"),
+			expectErr: true,
+		},
+		{
+			name: "no controller",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default", Name: "pod", OwnerReferences: test.owners,
+			}}
+
+			ipFamily, err := ResolveIPFamilyOfWorkload(context.Background(), c, pod)
+			if test.expectErr != (err != nil) {
+				t.Fatalf("test %s fail, expect error %v but got %v", test.name, test.expectErr, err)
+			}
+			if ipFamily != test.expect {
+				t.Errorf("test %s fail, expect %q but got %q", test.name, test.expect, ipFamily)
+			}
+		})
+	}
+}
+
+func TestParseIPFamilyOfPodByPriority(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("unable to build scheme: %v", err)
+	}
+	if err := networkingv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unable to build scheme: %v", err)
+	}
+
+	network := func(name string) *networkingv1.Network {
+		return &networkingv1.Network{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       networkingv1.NetworkSpec{Type: networkingv1.NetworkTypeUnderlay},
+		}
+	}
+	subnet := func(name, networkName string, version networkingv1.IPVersion) *networkingv1.Subnet {
+		return &networkingv1.Subnet{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: networkingv1.SubnetSpec{
+				Network: networkName,
+				Range:   networkingv1.AddressRange{Version: version},
+			},
+		}
+	}
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+
+	tests := []struct {
+		name        string
+		objects     []client.Object
+		annotations map[string]string
+		expect      ipamtypes.IPFamilyMode
+	}{
+		{
+			name:    "no subnets",
+			objects: []client.Object{namespace},
+			expect:  ipamtypes.IPv4,
+		},
+		{
+			name: "ipv6-only cluster",
+			objects: []client.Object{namespace, network("net1"), network("net2"),
+				subnet("subnet1", "net1", networkingv1.IPv6), subnet("subnet2", "net2", networkingv1.IPv6)},
+			expect: ipamtypes.IPv6,
+		},
+		{
+			name: "dual stack cluster",
+			objects: []client.Object{namespace, network("net1"),
+				subnet("subnet1", "net1", networkingv1.IPv4), subnet("subnet2", "net1", networkingv1.IPv6)},
+			expect: ipamtypes.IPv4,
+		},
+		{
+			name: "ipv6-only network specified",
+			objects: []client.Object{namespace, network("net1"), network("net2"),
+				subnet("subnet1", "net1", networkingv1.IPv4), subnet("subnet2", "net2", networkingv1.IPv6)},
+			annotations: map[string]string{constants.AnnotationSpecifiedNetwork: "net2"},
+			expect:      ipamtypes.IPv6,
+		},
+		{
+			name: "ipv6 subnet specified",
+			objects: []client.Object{namespace, network("net1"),
+				subnet("subnet1", "net1", networkingv1.IPv4), subnet("subnet2", "net1", networkingv1.IPv6)},
+			annotations: map[string]string{constants.AnnotationSpecifiedSubnet: "subnet2"},
+			expect:      ipamtypes.IPv6,
+		},
+		{
+			name: "ip family specified",
+			objects: []client.Object{namespace, network("net1"),
+				subnet("subnet1", "net1", networkingv1.IPv6)},
+			annotations: map[string]string{constants.AnnotationIPFamily: "IPv4"},
+			expect:      ipamtypes.IPv4,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(test.objects...).Build()
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default", Name: "pod", Annotations: test.annotations,
+			}}
+
+			_, _, _, ipFamily, _, _, err := ParseNetworkConfigOfPodByPriority(context.Background(), c, pod)
+			if err != nil {
+				t.Fatalf("test %s fail, unexpected error %v", test.name, err)
+			}
+			if ipFamily != test.expect {
+				t.Errorf("test %s fail, expect %q but got %q", test.name, test.expect, ipFamily)
+			}
+		})
+	}
+}
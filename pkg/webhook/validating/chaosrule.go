@@ -0,0 +1,73 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package validating
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-logr/logr"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	"github.com/alibaba/hybridnet/pkg/feature"
+	webhookutils "github.com/alibaba/hybridnet/pkg/webhook/utils"
+)
+
+var chaosRuleGVK = gvkConverter(networkingv1.GroupVersion.WithKind("ChaosRule"))
+
+func init() {
+	createHandlers[chaosRuleGVK] = ChaosRuleCreateValidation
+	updateHandlers[chaosRuleGVK] = ChaosRuleUpdateValidation
+}
+
+func ChaosRuleCreateValidation(ctx context.Context, req *admission.Request, handler *Handler) admission.Response {
+	logger := log.FromContext(ctx)
+
+	rule := &networkingv1.ChaosRule{}
+	if err := handler.Decoder.Decode(*req, rule); err != nil {
+		return webhookutils.AdmissionErroredWithLog(http.StatusBadRequest, err, logger)
+	}
+
+	return validateChaosRule(rule, logger)
+}
+
+func ChaosRuleUpdateValidation(ctx context.Context, req *admission.Request, handler *Handler) admission.Response {
+	logger := log.FromContext(ctx)
+
+	rule := &networkingv1.ChaosRule{}
+	if err := handler.Decoder.DecodeRaw(req.Object, rule); err != nil {
+		return webhookutils.AdmissionErroredWithLog(http.StatusBadRequest, err, logger)
+	}
+
+	return validateChaosRule(rule, logger)
+}
+
+// validateChaosRule allows no ChaosRule to be created or updated unless the feature gate is enabled,
+// while deletion is always allowed
+func validateChaosRule(rule *networkingv1.ChaosRule, logger logr.Logger) admission.Response {
+	if !feature.DataplaneChaosEnabled() {
+		return webhookutils.AdmissionDeniedWithLog("chaos rule requires DataplaneChaos feature gate enabled", logger)
+	}
+
+	if err := networkingv1.ValidateChaosRuleSpec(&rule.Spec); err != nil {
+		return webhookutils.AdmissionDeniedWithLog(err.Error(), logger)
+	}
+	return admission.Allowed("validation pass")
+}
@@ -18,10 +18,17 @@ package validating
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"reflect"
 
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	webhookutils "github.com/alibaba/hybridnet/pkg/webhook/utils"
 )
 
 var ipInstanceGVK = gvkConverter(networkingv1.GroupVersion.WithKind("IPInstance"))
@@ -32,12 +39,89 @@ func init() {
 	deleteHandlers[ipInstanceGVK] = IPInstanceDeleteValidation
 }
 
+// IPInstanceCreateValidation only validates ip instances created by users for adoption, others are
+// created by manager after allocation.
 func IPInstanceCreateValidation(ctx context.Context, req *admission.Request, handler *Handler) admission.Response {
-	return admission.Allowed("no validation")
+	logger := log.FromContext(ctx)
+
+	ipInstance := &networkingv1.IPInstance{}
+	if err := handler.Decoder.Decode(*req, ipInstance); err != nil {
+		return webhookutils.AdmissionErroredWithLog(http.StatusBadRequest, err, logger)
+	}
+
+	if !networkingv1.IsAdoptedIPInstance(ipInstance) {
+		return admission.Allowed("not adopted")
+	}
+
+	subnet := &networkingv1.Subnet{}
+	if err := handler.Client.Get(ctx, types.NamespacedName{Name: ipInstance.Spec.Subnet}, subnet); err != nil {
+		if errors.IsNotFound(err) {
+			return webhookutils.AdmissionDeniedWithLog(fmt.Sprintf("subnet %s does not exist", ipInstance.Spec.Subnet), logger)
+		}
+		return webhookutils.AdmissionErroredWithLog(http.StatusInternalServerError, err, logger)
+	}
+
+	network := &networkingv1.Network{}
+	if err := handler.Client.Get(ctx, types.NamespacedName{Name: ipInstance.Spec.Network}, network); err != nil {
+		if errors.IsNotFound(err) {
+			return webhookutils.AdmissionDeniedWithLog(fmt.Sprintf("network %s does not exist", ipInstance.Spec.Network), logger)
+		}
+		return webhookutils.AdmissionErroredWithLog(http.StatusInternalServerError, err, logger)
+	}
+
+	if err := networkingv1.ValidateAdoptedIPInstance(ipInstance, subnet, network); err != nil {
+		return webhookutils.AdmissionDeniedWithLog(err.Error(), logger)
+	}
+
+	// ip instances are named by address, so the same name in any namespace means a conflict, this is only
+	// a fast check of cached ones, addresses allocated in flight are refused when manager adopts it in IPAM
+	ipList := &networkingv1.IPInstanceList{}
+	if err := handler.Client.List(ctx, ipList); err != nil {
+		return webhookutils.AdmissionErroredWithLog(http.StatusInternalServerError, err, logger)
+	}
+	for i := range ipList.Items {
+		if ipList.Items[i].Name == ipInstance.Name {
+			return webhookutils.AdmissionDeniedWithLog(fmt.Sprintf("address %s is already allocated in namespace %s",
+				ipInstance.Spec.Address.IP, ipList.Items[i].Namespace), logger)
+		}
+	}
+
+	return admission.Allowed("validation pass")
 }
 
+// IPInstanceUpdateValidation only validates rebind requests, which take addresses away from their pods, so
+// users must be authorized to rebind ip instances besides updating them.
 func IPInstanceUpdateValidation(ctx context.Context, req *admission.Request, handler *Handler) admission.Response {
-	return admission.Allowed("no validation")
+	logger := log.FromContext(ctx)
+
+	oldIPInstance := &networkingv1.IPInstance{}
+	if err := handler.Decoder.DecodeRaw(req.OldObject, oldIPInstance); err != nil {
+		return webhookutils.AdmissionErroredWithLog(http.StatusBadRequest, err, logger)
+	}
+
+	newIPInstance := &networkingv1.IPInstance{}
+	if err := handler.Decoder.DecodeRaw(req.Object, newIPInstance); err != nil {
+		return webhookutils.AdmissionErroredWithLog(http.StatusBadRequest, err, logger)
+	}
+
+	// clearing a rebind request is always allowed, which is how manager finishes it
+	if newIPInstance.Spec.Rebind == nil || reflect.DeepEqual(oldIPInstance.Spec.Rebind, newIPInstance.Spec.Rebind) {
+		return admission.Allowed("no rebind request")
+	}
+
+	if err := networkingv1.ValidateRebindTarget(oldIPInstance, newIPInstance.Spec.Rebind); err != nil {
+		return webhookutils.AdmissionDeniedWithLog(err.Error(), logger)
+	}
+
+	allowed, reason, err := webhookutils.AuthorizeIPInstanceRebind(ctx, handler.Client, req.UserInfo, req.Namespace)
+	if err != nil {
+		return webhookutils.AdmissionErroredWithLog(http.StatusInternalServerError, err, logger)
+	}
+	if !allowed {
+		return webhookutils.AdmissionDeniedWithLog(reason, logger)
+	}
+
+	return admission.Allowed("validation pass")
 }
 
 func IPInstanceDeleteValidation(ctx context.Context, req *admission.Request, handler *Handler) admission.Response {
@@ -60,6 +60,27 @@ func NetworkCreateValidation(ctx context.Context, req *admission.Request, handle
 
 	networkType := networkingv1.GetNetworkType(network)
 
+	if networkingv1.IsTenantNetwork(network) {
+		if !feature.TenantNetworkEnabled() {
+			return webhookutils.AdmissionDeniedWithLog("tenant network requires TenantNetwork feature gate enabled", logger)
+		}
+
+		if networkType != networkingv1.NetworkTypeOverlay {
+			return webhookutils.AdmissionDeniedWithLog("only overlay network can belong to a tenant", logger)
+		}
+
+		// namespaces of different tenants must be separated, as ip instances are named by addresses
+		if network.Spec.NamespaceSelector == nil {
+			return webhookutils.AdmissionDeniedWithLog("must have namespace selector for tenant network", logger)
+		}
+
+		if reason, err := checkTenantNamespaceSelectorOverlapped(ctx, handler.Client, network); err != nil {
+			return webhookutils.AdmissionErroredWithLog(http.StatusInternalServerError, err, logger)
+		} else if len(reason) > 0 {
+			return webhookutils.AdmissionDeniedWithLog(reason, logger)
+		}
+	}
+
 	switch networkType {
 	case networkingv1.NetworkTypeUnderlay:
 		if network.Spec.NodeSelector == nil || len(network.Spec.NodeSelector) == 0 {
@@ -75,10 +96,10 @@ func NetworkCreateValidation(ctx context.Context, req *admission.Request, handle
 
 	case networkingv1.NetworkTypeOverlay:
 		// check uniqueness
-		if exist, _, err := checkNetworkTypeExist(ctx, handler.Client, networkType); err != nil {
+		if reason, err := checkOverlayNetworkConflicted(ctx, handler.Client, network); err != nil {
 			return webhookutils.AdmissionErroredWithLog(http.StatusInternalServerError, err, logger)
-		} else if exist {
-			return webhookutils.AdmissionDeniedWithLog("must have one overlay network at most", logger)
+		} else if len(reason) > 0 {
+			return webhookutils.AdmissionDeniedWithLog(reason, logger)
 		}
 
 		// check node selector
@@ -151,6 +172,16 @@ func NetworkCreateValidation(ctx context.Context, req *admission.Request, handle
 		return admission.Denied(fmt.Sprintf("unknown network mode %s", networkingv1.GetNetworkMode(network)))
 	}
 
+	if _, err = networkingv1.IsNetworkVisibleToNamespace(network, nil); err != nil {
+		return webhookutils.AdmissionDeniedWithLog(err.Error(), logger)
+	}
+
+	if network.Spec.Config != nil {
+		if err = networkingv1.ValidateNoTrackFlows(network.Spec.Config.NoTrackFlows); err != nil {
+			return webhookutils.AdmissionDeniedWithLog(err.Error(), logger)
+		}
+	}
+
 	return admission.Allowed("validation pass")
 }
 
@@ -191,6 +222,34 @@ func NetworkUpdateValidation(ctx context.Context, req *admission.Request, handle
 		return admission.Denied("network mode must not be changed")
 	}
 
+	if networkingv1.GetNetworkTenant(oldN) != networkingv1.GetNetworkTenant(newN) {
+		return webhookutils.AdmissionDeniedWithLog("network tenant must not be changed", logger)
+	}
+
+	if networkingv1.IsTenantNetwork(newN) {
+		if newN.Spec.NamespaceSelector == nil {
+			return webhookutils.AdmissionDeniedWithLog("must have namespace selector for tenant network", logger)
+		}
+
+		if !reflect.DeepEqual(oldN.Spec.NamespaceSelector, newN.Spec.NamespaceSelector) {
+			if reason, err := checkTenantNamespaceSelectorOverlapped(ctx, handler.Client, newN); err != nil {
+				return webhookutils.AdmissionErroredWithLog(http.StatusInternalServerError, err, logger)
+			} else if len(reason) > 0 {
+				return webhookutils.AdmissionDeniedWithLog(reason, logger)
+			}
+		}
+	}
+
+	if _, err = networkingv1.IsNetworkVisibleToNamespace(newN, nil); err != nil {
+		return webhookutils.AdmissionDeniedWithLog(err.Error(), logger)
+	}
+
+	if newN.Spec.Config != nil {
+		if err = networkingv1.ValidateNoTrackFlows(newN.Spec.Config.NoTrackFlows); err != nil {
+			return webhookutils.AdmissionDeniedWithLog(err.Error(), logger)
+		}
+	}
+
 	switch networkingv1.GetNetworkMode(newN) {
 	case networkingv1.NetworkModeBGP:
 		if len(newN.Spec.Config.BGPPeers) == 0 {
@@ -287,6 +346,65 @@ func checkNetworkTypeExist(ctx context.Context, client client.Reader, networkTyp
 	return false, "", nil
 }
 
+// checkOverlayNetworkConflicted returns the reason why an overlay network conflicts with the existing ones,
+// every tenant has one overlay network at most, and overlay networks must be isolated by different net IDs
+func checkOverlayNetworkConflicted(ctx context.Context, c client.Reader, network *networkingv1.Network) (string, error) {
+	networks := &networkingv1.NetworkList{}
+	if err := c.List(ctx, networks); err != nil {
+		return "", err
+	}
+
+	tenant := networkingv1.GetNetworkTenant(network)
+	for i := range networks.Items {
+		existNetwork := &networks.Items[i]
+		if networkingv1.GetNetworkType(existNetwork) != networkingv1.NetworkTypeOverlay || existNetwork.Name == network.Name {
+			continue
+		}
+
+		if networkingv1.GetNetworkTenant(existNetwork) == tenant {
+			if len(tenant) == 0 {
+				return "must have one overlay network at most", nil
+			}
+			return fmt.Sprintf("must have one overlay network at most for tenant %s", tenant), nil
+		}
+
+		if network.Spec.NetID != nil && existNetwork.Spec.NetID != nil && *network.Spec.NetID == *existNetwork.Spec.NetID {
+			return fmt.Sprintf("net ID %d is already used by overlay network %s", *network.Spec.NetID, existNetwork.Name), nil
+		}
+	}
+	return "", nil
+}
+
+// checkTenantNamespaceSelectorOverlapped returns the reason why the namespace selector of a tenant network
+// may select the same namespaces as the networks of other tenants, which must be disjoint because ip instances
+// are named by their addresses in namespaces
+func checkTenantNamespaceSelectorOverlapped(ctx context.Context, c client.Reader, network *networkingv1.Network) (string, error) {
+	networks := &networkingv1.NetworkList{}
+	if err := c.List(ctx, networks); err != nil {
+		return "", err
+	}
+
+	tenant := networkingv1.GetNetworkTenant(network)
+	for i := range networks.Items {
+		existNetwork := &networks.Items[i]
+		existTenant := networkingv1.GetNetworkTenant(existNetwork)
+		if len(existTenant) == 0 || existTenant == tenant || existNetwork.Name == network.Name {
+			continue
+		}
+
+		disjoint, err := networkingv1.AreNamespaceSelectorsDisjoint(network.Spec.NamespaceSelector,
+			existNetwork.Spec.NamespaceSelector)
+		if err != nil {
+			return err.Error(), nil
+		}
+		if !disjoint {
+			return fmt.Sprintf("namespace selector must be disjoint with the one of network %s of tenant %s, "+
+				"e.g., different values of the same label", existNetwork.Name, existTenant), nil
+		}
+	}
+	return "", nil
+}
+
 func checkUnderlayNetworkOverlapped(ctx context.Context, c client.Reader, network *networkingv1.Network) (bool, string, error) {
 	networks := &networkingv1.NetworkList{}
 	if err := c.List(ctx, networks); err != nil {
@@ -0,0 +1,63 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package validating
+
+import (
+	"context"
+	"net/http"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	webhookutils "github.com/alibaba/hybridnet/pkg/webhook/utils"
+)
+
+var nodeNetworkConfigGVK = gvkConverter(networkingv1.GroupVersion.WithKind("NodeNetworkConfig"))
+
+func init() {
+	createHandlers[nodeNetworkConfigGVK] = NodeNetworkConfigCreateValidation
+	updateHandlers[nodeNetworkConfigGVK] = NodeNetworkConfigUpdateValidation
+}
+
+func NodeNetworkConfigCreateValidation(ctx context.Context, req *admission.Request, handler *Handler) admission.Response {
+	logger := log.FromContext(ctx)
+
+	config := &networkingv1.NodeNetworkConfig{}
+	if err := handler.Decoder.Decode(*req, config); err != nil {
+		return webhookutils.AdmissionErroredWithLog(http.StatusBadRequest, err, logger)
+	}
+
+	if err := networkingv1.ValidateNodeNetworkConfigSpec(&config.Spec); err != nil {
+		return webhookutils.AdmissionDeniedWithLog(err.Error(), logger)
+	}
+	return admission.Allowed("validation pass")
+}
+
+func NodeNetworkConfigUpdateValidation(ctx context.Context, req *admission.Request, handler *Handler) admission.Response {
+	logger := log.FromContext(ctx)
+
+	config := &networkingv1.NodeNetworkConfig{}
+	if err := handler.Decoder.DecodeRaw(req.Object, config); err != nil {
+		return webhookutils.AdmissionErroredWithLog(http.StatusBadRequest, err, logger)
+	}
+
+	if err := networkingv1.ValidateNodeNetworkConfigSpec(&config.Spec); err != nil {
+		return webhookutils.AdmissionDeniedWithLog(err.Error(), logger)
+	}
+	return admission.Allowed("validation pass")
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/alibaba/hybridnet/pkg/utils/transform"
@@ -35,7 +36,6 @@ import (
 	"github.com/alibaba/hybridnet/pkg/constants"
 	ipamtypes "github.com/alibaba/hybridnet/pkg/ipam/types"
 	"github.com/alibaba/hybridnet/pkg/utils"
-	macutils "github.com/alibaba/hybridnet/pkg/utils/mac"
 	webhookutils "github.com/alibaba/hybridnet/pkg/webhook/utils"
 )
 
@@ -73,7 +73,8 @@ func PodCreateValidation(ctx context.Context, req *admission.Request, handler *H
 		network := &networkingv1.Network{}
 		if err = handler.Cache.Get(ctx, types.NamespacedName{Name: specifiedNetwork}, network); err != nil {
 			if errors.IsNotFound(err) {
-				return webhookutils.AdmissionDeniedWithLog(fmt.Sprintf("specified network %s not found", specifiedNetwork), logger)
+				return webhookutils.AdmissionDeniedWithLog(webhookutils.AllocationFailureDenial(
+					ipamtypes.FailureNetworkNotFound, "specified network %s not found", specifiedNetwork), logger)
 			}
 			return webhookutils.AdmissionErroredWithLog(http.StatusInternalServerError, err, logger)
 		}
@@ -89,6 +90,15 @@ func PodCreateValidation(ctx context.Context, req *admission.Request, handler *H
 			networkType = ipamtypes.ParseNetworkTypeFromString(string(networkingv1.GetNetworkType(network)))
 		}
 
+		// Namespace visibility validation
+		var denyReason string
+		if denyReason, err = checkNamespaceVisibility(ctx, handler.Cache, pod.Namespace, network, specifiedSubnetStr); err != nil {
+			return webhookutils.AdmissionErroredWithLog(http.StatusInternalServerError, err, logger)
+		}
+		if len(denyReason) > 0 {
+			return webhookutils.AdmissionDeniedWithLog(denyReason, logger)
+		}
+
 		// Existing IP Instances Validation
 		ipList := &networkingv1.IPInstanceList{}
 		if err = handler.Client.List(
@@ -107,14 +117,14 @@ func PodCreateValidation(ctx context.Context, req *admission.Request, handler *H
 			if ipInstance.DeletionTimestamp == nil {
 				switch {
 				case ipInstance.Spec.Network != specifiedNetwork:
-					return webhookutils.AdmissionDeniedWithLog(fmt.Sprintf(
+					return webhookutils.AdmissionDeniedWithLog(webhookutils.AllocationFailureDenial(ipamtypes.FailureIPConflict,
 						"pod has assigned ip %s of network %s, cannot assign to another network %s",
 						ipInstance.Spec.Address.IP,
 						ipInstance.Spec.Network,
 						specifiedNetwork,
 					), logger)
 				case len(specifiedSubnetStr) > 0 && !webhookutils.SubnetNameBelongsToSpecifiedSubnets(ipInstance.Spec.Subnet, specifiedSubnetStr):
-					return webhookutils.AdmissionDeniedWithLog(fmt.Sprintf(
+					return webhookutils.AdmissionDeniedWithLog(webhookutils.AllocationFailureDenial(ipamtypes.FailureIPConflict,
 						"pod has assigend ip %s of subnet %s, cannot assign to another subnet by specified string %s",
 						ipInstance.Spec.Address.IP,
 						ipInstance.Spec.Subnet,
@@ -125,38 +135,39 @@ func PodCreateValidation(ctx context.Context, req *admission.Request, handler *H
 		}
 	}
 
-	// IP Pool Validation
-	var ipPool string
-	if ipPool = pod.Annotations[constants.AnnotationIPPool]; len(ipPool) > 0 {
-		if len(specifiedNetwork) == 0 {
-			return webhookutils.AdmissionDeniedWithLog("ip pool and network(subnet) must be specified at the same time", logger)
+	// Network intent validation, legacy annotations are translated into the typed spec of
+	// PodNetworkClaim and validated in the same way
+	claimSpec, err := networkingv1.PodNetworkClaimSpecFromAnnotations(pod.Annotations)
+	if err != nil {
+		return webhookutils.AdmissionDeniedWithLog(err.Error(), logger)
+	}
+	if len(claimSpec.IPPool) > 0 && len(specifiedNetwork) == 0 {
+		return webhookutils.AdmissionDeniedWithLog("ip pool and network(subnet) must be specified at the same time", logger)
+	}
+	if err = networkingv1.ValidatePodNetworkClaimSpec(claimSpec); err != nil {
+		return webhookutils.AdmissionDeniedWithLog(err.Error(), logger)
+	}
+	if len(claimSpec.IPPool) > 0 {
+		var denyReason string
+		if denyReason, err = checkIPPool(ctx, handler.Cache, pod.Namespace, specifiedNetwork, claimSpec.IPPool); err != nil {
+			return webhookutils.AdmissionErroredWithLog(http.StatusInternalServerError, err, logger)
 		}
-		ips := strings.Split(ipPool, ",")
-		for idx, ipSegment := range ips {
-			if len(ipSegment) == 0 {
-				return webhookutils.AdmissionDeniedWithLog(fmt.Sprintf("the %d ip in ip pool is empty", idx), logger)
-			}
+		if len(denyReason) > 0 {
+			return webhookutils.AdmissionDeniedWithLog(denyReason, logger)
+		}
+	}
 
-			// if dual stack IP family, more than one IP should be assigned
-			for _, ip := range strings.Split(ipPool, "/") {
-				if utils.NormalizedIP(ip) != ip {
-					return webhookutils.AdmissionDeniedWithLog(fmt.Sprintf("ip pool has an invalid ip %s", ip), logger)
-				}
-			}
+	// Egress allowlist validation
+	if egressAllowlist, exist := pod.Annotations[constants.AnnotationEgressAllowlist]; exist {
+		if _, err = utils.ParseEgressAllowlist(egressAllowlist); err != nil {
+			return webhookutils.AdmissionDeniedWithLog(fmt.Sprintf("invalid egress allowlist: %v", err), logger)
 		}
 	}
 
-	// MAC address pool validation
-	var macPool string
-	if macPool = pod.Annotations[constants.AnnotationMACPool]; len(macPool) > 0 {
-		macAddrSegments := strings.Split(macPool, ",")
-		for idx, macAddr := range macAddrSegments {
-			if len(macAddr) == 0 {
-				return webhookutils.AdmissionDeniedWithLog(fmt.Sprintf("the %d mac addr in mac pool is empty", idx), logger)
-			}
-			if len(macutils.NormalizeMAC(macAddr)) == 0 {
-				return webhookutils.AdmissionDeniedWithLog(fmt.Sprintf("the %d mac address %s is not valid", idx, macAddr), logger)
-			}
+	// IP source guard validation
+	if ipSourceGuard, exist := pod.Annotations[constants.AnnotationIPSourceGuard]; exist {
+		if _, err = strconv.ParseBool(ipSourceGuard); err != nil {
+			return webhookutils.AdmissionDeniedWithLog(fmt.Sprintf("invalid ip source guard %q, must be a bool", ipSourceGuard), logger)
 		}
 	}
 
@@ -165,6 +176,19 @@ func PodCreateValidation(ctx context.Context, req *admission.Request, handler *H
 		return webhookutils.AdmissionDeniedWithLog(fmt.Sprintf("unrecognized network type %s", networkType), logger)
 	}
 
+	// Node interface validation
+	if nodeInterface, exist := pod.Annotations[constants.AnnotationNodeInterface]; exist {
+		if err = networkingv1.ValidateNodeInterfaceName(nodeInterface); err != nil {
+			return webhookutils.AdmissionDeniedWithLog(err.Error(), logger)
+		}
+		if networkType != ipamtypes.Underlay {
+			return webhookutils.AdmissionDeniedWithLog("node interface can only be specified for underlay pods", logger)
+		}
+		if len(specifiedSubnetStr) > 0 {
+			return webhookutils.AdmissionDeniedWithLog("node interface and subnet must not be specified at the same time", logger)
+		}
+	}
+
 	// IP family validation
 	var ipFamily = ipamtypes.ParseIPFamilyFromString(pod.Annotations[constants.AnnotationIPFamily])
 	if !ipamtypes.IsValidFamilyMode(ipFamily) {
@@ -193,23 +217,35 @@ func PodCreateValidation(ctx context.Context, req *admission.Request, handler *H
 		}
 
 		if idx < 0 {
-			return webhookutils.AdmissionDeniedWithLog(fmt.Sprintf("no network found by type %s", networkTypeInSpec), logger)
+			return webhookutils.AdmissionDeniedWithLog(webhookutils.AllocationFailureDenial(
+				ipamtypes.FailureNetworkNotFound, "no network found by type %s", networkTypeInSpec), logger)
 		}
 
 		network := &networkList.Items[idx]
 
+		var denyReason string
+		if denyReason, err = checkNamespaceVisibility(ctx, handler.Cache, pod.Namespace, network, ""); err != nil {
+			return webhookutils.AdmissionErroredWithLog(http.StatusInternalServerError, err, logger)
+		}
+		if len(denyReason) > 0 {
+			return webhookutils.AdmissionDeniedWithLog(denyReason, logger)
+		}
+
 		switch ipFamily {
 		case ipamtypes.IPv4:
 			if !networkingv1.IsAvailable(network.Status.Statistics) {
-				return webhookutils.AdmissionDeniedWithLog(fmt.Sprintf("lacking ipv4 addresses by network type %s", networkTypeInSpec), logger)
+				return webhookutils.AdmissionDeniedWithLog(webhookutils.AllocationFailureDenial(
+					ipamtypes.FailureSubnetExhausted, "lacking ipv4 addresses by network type %s", networkTypeInSpec), logger)
 			}
 		case ipamtypes.IPv6:
 			if !networkingv1.IsAvailable(network.Status.IPv6Statistics) {
-				return webhookutils.AdmissionDeniedWithLog(fmt.Sprintf("lacking ipv6 addresses by network type %s", networkTypeInSpec), logger)
+				return webhookutils.AdmissionDeniedWithLog(webhookutils.AllocationFailureDenial(
+					ipamtypes.FailureSubnetExhausted, "lacking ipv6 addresses by network type %s", networkTypeInSpec), logger)
 			}
 		case ipamtypes.DualStack:
 			if !networkingv1.IsAvailable(network.Status.DualStackStatistics) {
-				return webhookutils.AdmissionDeniedWithLog(fmt.Sprintf("lacking dual stack addresses by network type %s", networkTypeInSpec), logger)
+				return webhookutils.AdmissionDeniedWithLog(webhookutils.AllocationFailureDenial(
+					ipamtypes.FailureSubnetExhausted, "lacking dual stack addresses by network type %s", networkTypeInSpec), logger)
 			}
 		}
 	}
@@ -217,6 +253,107 @@ func PodCreateValidation(ctx context.Context, req *admission.Request, handler *H
 	return admission.Allowed("validation pass")
 }
 
+// checkNamespaceVisibility returns a non-empty reason if network or any of specified subnets
+// is not visible to pods of namespace
+func checkNamespaceVisibility(ctx context.Context, c client.Reader, namespace string, network *networkingv1.Network,
+	specifiedSubnetStr string) (string, error) {
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return "", fmt.Errorf("failed to get namespace %s: %v", namespace, err)
+	}
+
+	visible, err := networkingv1.IsNetworkVisibleToNamespace(network, ns.Labels)
+	if err != nil {
+		return "", fmt.Errorf("failed to check visibility of network %s: %v", network.Name, err)
+	}
+	if !visible {
+		return webhookutils.AllocationFailureDenial(ipamtypes.FailureNetworkNotVisible,
+			"network %s is not visible to namespace %s", network.Name, namespace), nil
+	}
+
+	if len(specifiedSubnetStr) == 0 {
+		return "", nil
+	}
+
+	for _, subnetName := range strings.Split(specifiedSubnetStr, "/") {
+		if len(subnetName) == 0 {
+			continue
+		}
+
+		subnet := &networkingv1.Subnet{}
+		if err = c.Get(ctx, types.NamespacedName{Name: subnetName}, subnet); err != nil {
+			if errors.IsNotFound(err) {
+				return webhookutils.AllocationFailureDenial(ipamtypes.FailureSubnetNotFound,
+					"specified subnet %s not found", subnetName), nil
+			}
+			return "", fmt.Errorf("failed to get subnet %s: %v", subnetName, err)
+		}
+
+		if visible, err = networkingv1.IsSubnetVisibleToNamespace(subnet, ns.Labels); err != nil {
+			return "", fmt.Errorf("failed to check visibility of subnet %s: %v", subnetName, err)
+		}
+		if !visible {
+			return webhookutils.AllocationFailureDenial(ipamtypes.FailureNetworkNotVisible,
+				"subnet %s is not visible to namespace %s", subnetName, namespace), nil
+		}
+	}
+
+	return "", nil
+}
+
 func stringEqualCaseInsensitive(a, b string) bool {
 	return strings.EqualFold(a, b)
 }
+
+// checkIPPool returns a non-empty reason if any address of ip pool is in a reserved range, or any subnet
+// containing addresses of ip pool is not visible to pods of namespace, as those subnets are not specified
+// explicitly
+func checkIPPool(ctx context.Context, c client.Reader, namespace, networkName string, ipPool []string) (string, error) {
+	subnetList := &networkingv1.SubnetList{}
+	if err := c.List(ctx, subnetList); err != nil {
+		return "", fmt.Errorf("failed to list subnets: %v", err)
+	}
+
+	var subnetsOfNetwork []networkingv1.Subnet
+	for i := range subnetList.Items {
+		if subnetList.Items[i].Spec.Network == networkName {
+			subnetsOfNetwork = append(subnetsOfNetwork, subnetList.Items[i])
+		}
+	}
+
+	var ns *corev1.Namespace
+	for _, ips := range ipPool {
+		for _, ip := range strings.Split(ips, "/") {
+			subnet := networkingv1.GetSubnetOfAddress(subnetsOfNetwork, ip)
+			if subnet == nil {
+				continue
+			}
+
+			if networkingv1.IsInReservedRanges(&subnet.Spec.Range, ip) {
+				return webhookutils.AllocationFailureDenial(ipamtypes.FailureIPConflict,
+					"ip %s in ip pool is in a reserved range of subnet %s", ip, subnet.Name), nil
+			}
+
+			if subnet.Spec.NamespaceSelector == nil {
+				continue
+			}
+
+			if ns == nil {
+				ns = &corev1.Namespace{}
+				if err := c.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+					return "", fmt.Errorf("failed to get namespace %s: %v", namespace, err)
+				}
+			}
+
+			visible, err := networkingv1.IsSubnetVisibleToNamespace(subnet, ns.Labels)
+			if err != nil {
+				return "", fmt.Errorf("failed to check visibility of subnet %s: %v", subnet.Name, err)
+			}
+			if !visible {
+				return webhookutils.AllocationFailureDenial(ipamtypes.FailureNetworkNotVisible,
+					"subnet %s of ip %s in ip pool is not visible to namespace %s", subnet.Name, ip, namespace), nil
+			}
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,65 @@
+/*
+ Copyright 2021 The Hybridnet Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package validating
+
+import (
+	"context"
+	"net/http"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	networkingv1 "github.com/alibaba/hybridnet/pkg/apis/networking/v1"
+	webhookutils "github.com/alibaba/hybridnet/pkg/webhook/utils"
+)
+
+var podNetworkClaimGVK = gvkConverter(networkingv1.GroupVersion.WithKind("PodNetworkClaim"))
+
+func init() {
+	createHandlers[podNetworkClaimGVK] = PodNetworkClaimCreateValidation
+	updateHandlers[podNetworkClaimGVK] = PodNetworkClaimUpdateValidation
+}
+
+func PodNetworkClaimCreateValidation(ctx context.Context, req *admission.Request, handler *Handler) admission.Response {
+	logger := log.FromContext(ctx)
+
+	claim := &networkingv1.PodNetworkClaim{}
+	if err := handler.Decoder.Decode(*req, claim); err != nil {
+		return webhookutils.AdmissionErroredWithLog(http.StatusBadRequest, err, logger)
+	}
+
+	if err := networkingv1.ValidatePodNetworkClaimSpec(&claim.Spec); err != nil {
+		return webhookutils.AdmissionDeniedWithLog(err.Error(), logger)
+	}
+	return admission.Allowed("validation pass")
+}
+
+// PodNetworkClaimUpdateValidation only validates the new spec, claims are translated when pods are
+// created so updates never affect existing pods.
+func PodNetworkClaimUpdateValidation(ctx context.Context, req *admission.Request, handler *Handler) admission.Response {
+	logger := log.FromContext(ctx)
+
+	claim := &networkingv1.PodNetworkClaim{}
+	if err := handler.Decoder.DecodeRaw(req.Object, claim); err != nil {
+		return webhookutils.AdmissionErroredWithLog(http.StatusBadRequest, err, logger)
+	}
+
+	if err := networkingv1.ValidatePodNetworkClaimSpec(&claim.Spec); err != nil {
+		return webhookutils.AdmissionDeniedWithLog(err.Error(), logger)
+	}
+	return admission.Allowed("validation pass")
+}
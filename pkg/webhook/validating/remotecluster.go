@@ -76,7 +76,21 @@ func validate(ctx context.Context, rc *multiclusterv1.RemoteCluster, handler *Ha
 	if rc.Spec.APIEndpoint == "" {
 		return webhookutils.AdmissionDeniedWithLog("invalid empty endpoint", logger)
 	}
-	if len(rc.Spec.CAData) == 0 || len(rc.Spec.CertData) == 0 || len(rc.Spec.KeyData) == 0 {
+	if rc.Spec.ServiceAccountToken != nil {
+		if len(rc.Spec.CAData) == 0 {
+			return webhookutils.AdmissionDeniedWithLog("invalid empty ca data", logger)
+		}
+		if len(rc.Spec.CertData) > 0 || len(rc.Spec.KeyData) > 0 || rc.Spec.EncryptedKeyData != nil {
+			return webhookutils.AdmissionDeniedWithLog("client certificate must not be set with service account token", logger)
+		}
+		if len(rc.Spec.ServiceAccountToken.Audience) == 0 {
+			return webhookutils.AdmissionDeniedWithLog("invalid empty audience of service account token", logger)
+		}
+		if rc.Spec.ServiceAccountToken.ExpirationSeconds != nil && *rc.Spec.ServiceAccountToken.ExpirationSeconds < 600 {
+			return webhookutils.AdmissionDeniedWithLog("expiration seconds of service account token must be at least 600", logger)
+		}
+	} else if len(rc.Spec.CAData) == 0 || len(rc.Spec.CertData) == 0 ||
+		(len(rc.Spec.KeyData) == 0 && rc.Spec.EncryptedKeyData == nil) {
 		return webhookutils.AdmissionDeniedWithLog("invalid empty certificate info", logger)
 	}
 
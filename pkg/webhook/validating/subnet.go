@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"math/big"
+	"net"
 	"net/http"
 	"reflect"
 	"strings"
@@ -96,6 +97,12 @@ func SubnetCreateValidation(ctx context.Context, req *admission.Request, handler
 		if len(subnet.Spec.Range.Gateway) == 0 {
 			return admission.Denied("must assign gateway for a vlan subnet")
 		}
+
+		if nodeInterface := networkingv1.GetSubnetNodeInterface(&subnet.Spec); len(nodeInterface) > 0 {
+			if err = networkingv1.ValidateNodeInterfaceName(nodeInterface); err != nil {
+				return webhookutils.AdmissionDeniedWithLog(err.Error(), logger)
+			}
+		}
 	case networkingv1.NetworkModeBGP, networkingv1.NetworkModeGlobalBGP:
 		if subnet.Spec.NetID != nil {
 			return admission.Denied("must not assign net ID for (global) bgp subnet")
@@ -110,6 +117,25 @@ func SubnetCreateValidation(ctx context.Context, req *admission.Request, handler
 		}
 	}
 
+	if len(networkingv1.GetSubnetNodeInterface(&subnet.Spec)) > 0 && networkingv1.GetNetworkMode(network) != networkingv1.NetworkModeVlan {
+		return webhookutils.AdmissionDeniedWithLog("node interface can only be set for vlan subnet", logger)
+	}
+
+	// Egress routes validation
+	if err = validateSubnetEgressRoutes(&subnet.Spec, network); err != nil {
+		return webhookutils.AdmissionDeniedWithLog(err.Error(), logger)
+	}
+
+	// NoTrack flows validation
+	if err = validateSubnetNoTrackFlows(&subnet.Spec, network); err != nil {
+		return webhookutils.AdmissionDeniedWithLog(err.Error(), logger)
+	}
+
+	// Allocation freeze windows validation
+	if err = networkingv1.ValidateSubnetAllocationFreezeWindows(&subnet.Spec); err != nil {
+		return webhookutils.AdmissionDeniedWithLog(err.Error(), logger)
+	}
+
 	// Address Range validation
 	if err = networkingv1.ValidateAddressRange(&subnet.Spec.Range); err != nil {
 		return webhookutils.AdmissionDeniedWithLog(err.Error(), logger)
@@ -130,7 +156,24 @@ func SubnetCreateValidation(ctx context.Context, req *admission.Request, handler
 		return webhookutils.AdmissionErroredWithLog(http.StatusInternalServerError, err, logger)
 	}
 
+	networkList := &networkingv1.NetworkList{}
+	if err = handler.Client.List(ctx, networkList); err != nil {
+		return webhookutils.AdmissionErroredWithLog(http.StatusInternalServerError, err, logger)
+	}
+
+	networkTenants := map[string]string{}
+	for i := range networkList.Items {
+		networkTenants[networkList.Items[i].Name] = networkingv1.GetNetworkTenant(&networkList.Items[i])
+	}
+
+	tenant := networkingv1.GetNetworkTenant(network)
 	for i := range subnetList.Items {
+		// address spaces of different tenants are isolated from each other
+		if comparedTenant := networkTenants[subnetList.Items[i].Spec.Network]; len(tenant) > 0 &&
+			len(comparedTenant) > 0 && tenant != comparedTenant {
+			continue
+		}
+
 		if subnet.Spec.Range.CIDR != subnetList.Items[i].Spec.Range.CIDR &&
 			networkingv1.Intersect(&networkingv1.AddressRange{CIDR: subnet.Spec.Range.CIDR},
 				&networkingv1.AddressRange{CIDR: subnetList.Items[i].Spec.Range.CIDR}) {
@@ -151,6 +194,11 @@ func SubnetCreateValidation(ctx context.Context, req *admission.Request, handler
 		}
 	}
 
+	// Namespace selector validation
+	if _, err = networkingv1.IsSubnetVisibleToNamespace(subnet, nil); err != nil {
+		return webhookutils.AdmissionDeniedWithLog(err.Error(), logger)
+	}
+
 	if feature.MultiClusterEnabled() {
 		rcSubnetList := &multiclusterv1.RemoteSubnetList{}
 		if err = handler.Client.List(ctx, rcSubnetList); err != nil {
@@ -163,7 +211,7 @@ func SubnetCreateValidation(ctx context.Context, req *admission.Request, handler
 		}
 	}
 
-	return admission.Allowed("validation pass")
+	return admission.Allowed("validation pass").WithWarnings(subnetCapacityWarnings(&subnet.Spec.Range)...)
 }
 
 func SubnetUpdateValidation(ctx context.Context, req *admission.Request, handler *Handler) admission.Response {
@@ -214,6 +262,26 @@ func SubnetUpdateValidation(ctx context.Context, req *admission.Request, handler
 		}
 	}
 
+	// vlan sub-interfaces and routes of running pods are created on the node interface
+	if networkingv1.GetSubnetNodeInterface(&oldS.Spec) != networkingv1.GetSubnetNodeInterface(&newS.Spec) {
+		return webhookutils.AdmissionDeniedWithLog("must not change node interface", logger)
+	}
+
+	// Egress routes validation
+	if err = validateSubnetEgressRoutes(&newS.Spec, network); err != nil {
+		return webhookutils.AdmissionDeniedWithLog(err.Error(), logger)
+	}
+
+	// NoTrack flows validation
+	if err = validateSubnetNoTrackFlows(&newS.Spec, network); err != nil {
+		return webhookutils.AdmissionDeniedWithLog(err.Error(), logger)
+	}
+
+	// Allocation freeze windows validation
+	if err = networkingv1.ValidateSubnetAllocationFreezeWindows(&newS.Spec); err != nil {
+		return webhookutils.AdmissionDeniedWithLog(err.Error(), logger)
+	}
+
 	// Address Range validation
 	err = networkingv1.ValidateAddressRange(&newS.Spec.Range)
 	if err != nil {
@@ -235,6 +303,18 @@ func SubnetUpdateValidation(ctx context.Context, req *admission.Request, handler
 		return webhookutils.AdmissionDeniedWithLog("must not change excluded IPs", logger)
 	}
 
+	// Namespace selector validation
+	if _, err = networkingv1.IsSubnetVisibleToNamespace(newS, nil); err != nil {
+		return webhookutils.AdmissionDeniedWithLog(err.Error(), logger)
+	}
+
+	// reserved IPs and ranges are the only parts of address range which can be changed, so preview
+	// capacity again if they changed
+	if !utils.DeepEqualStringSlice(oldS.Spec.Range.ReservedIPs, newS.Spec.Range.ReservedIPs) ||
+		!reflect.DeepEqual(oldS.Spec.Range.ReservedRanges, newS.Spec.Range.ReservedRanges) {
+		return admission.Allowed("validation pass").WithWarnings(subnetCapacityWarnings(&newS.Spec.Range)...)
+	}
+
 	return admission.Allowed("validation pass")
 }
 
@@ -262,3 +342,62 @@ func SubnetDeleteValidation(ctx context.Context, req *admission.Request, handler
 
 	return admission.Allowed("validation pass")
 }
+
+// validateSubnetEgressRoutes checks egress routes of subnet, which are only supported by underlay
+// subnets because traffic of overlay pods always leaves through vxlan device or nat
+func validateSubnetEgressRoutes(subnetSpec *networkingv1.SubnetSpec, network *networkingv1.Network) error {
+	if len(networkingv1.GetSubnetEgressRoutes(subnetSpec)) == 0 {
+		return nil
+	}
+
+	if networkingv1.GetNetworkMode(network) == networkingv1.NetworkModeVxlan {
+		return fmt.Errorf("egress routes can only be set for underlay subnet")
+	}
+
+	return networkingv1.ValidateSubnetEgressRoutes(subnetSpec)
+}
+
+// validateSubnetNoTrackFlows checks notrack flows of subnet, which are refused for subnets depending on
+// nat outgoing because masquerade of connections is broken without connection tracking
+func validateSubnetNoTrackFlows(subnetSpec *networkingv1.SubnetSpec, network *networkingv1.Network) error {
+	if subnetSpec.Config == nil || len(subnetSpec.Config.NoTrackFlows) == 0 {
+		return nil
+	}
+
+	if networkingv1.IsSubnetNATDependent(subnetSpec, network) {
+		return fmt.Errorf("notrack flows can only be set for subnet not depending on nat outgoing")
+	}
+
+	isIPv6 := subnetSpec.Range.Version == networkingv1.IPv6
+	for _, flow := range subnetSpec.Config.NoTrackFlows {
+		if _, dst, err := net.ParseCIDR(flow.Destination); err == nil && (dst.IP.To4() == nil) != isIPv6 {
+			return fmt.Errorf("notrack flow destination %s is not of subnet family", flow.Destination)
+		}
+	}
+
+	return networkingv1.ValidateNoTrackFlows(subnetSpec.Config.NoTrackFlows)
+}
+
+// subnetCapacityWarnings previews the capacity of an address range as admission warnings,
+// so that mistakes of range like off-by-one CIDR or start/end can be found by a server-side
+// dry-run before subnet takes effect.
+func subnetCapacityWarnings(ar *networkingv1.AddressRange) []string {
+	usable, reserved := networkingv1.CalculateUsableCapacity(ar)
+
+	start, end := ar.Start, ar.End
+	if len(start) == 0 {
+		start = "<first of cidr>"
+	}
+	if len(end) == 0 {
+		end = "<last of cidr>"
+	}
+
+	warnings := []string{
+		fmt.Sprintf("subnet capacity preview: %s usable addresses and %s reserved addresses in range [%s, %s] of %s, "+
+			"excluded IPs and gateway are not counted", usable.String(), reserved.String(), start, end, ar.CIDR),
+	}
+	if usable.Sign() <= 0 {
+		warnings = append(warnings, "subnet has no usable address for allocation, please check range, excluded IPs and reserved IPs")
+	}
+	return warnings
+}
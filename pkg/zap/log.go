@@ -18,21 +18,34 @@ package zap
 
 import (
 	"flag"
+	"fmt"
 
 	"github.com/go-logr/logr"
+	uberzap "go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
 
+const levelFlagName = "zap-log-level"
+
 var zapOptions zap.Options
 
+// atomicLevel is shared by all loggers created by NewZapLogger, so that level can be changed at runtime
+var atomicLevel = uberzap.NewAtomicLevelAt(zapcore.InfoLevel)
+
 func init() {
 	zapOptions.BindFlags(flag.CommandLine)
 }
 
 // NewZapLogger should be called after parsing flags
 func NewZapLogger() logr.Logger {
+	if level, ok := zapOptions.Level.(uberzap.AtomicLevel); ok {
+		atomicLevel.SetLevel(level.Level())
+	}
+
 	var opts = []zap.Opts{
 		zap.UseFlagOptions(&zapOptions),
+		zap.Level(atomicLevel),
 	}
 
 	// default encoder to console mode
@@ -41,3 +54,30 @@ func NewZapLogger() logr.Logger {
 	}
 	return zap.New(opts...)
 }
+
+// SetLevel changes level of all loggers created by NewZapLogger, level is in the same
+// format of --zap-log-level flag, e.g., "debug", "info", "error" or a positive verbosity
+func SetLevel(level string) error {
+	var options zap.Options
+	flagSet := flag.NewFlagSet("", flag.ContinueOnError)
+	options.BindFlags(flagSet)
+
+	if err := flagSet.Set(levelFlagName, level); err != nil {
+		return err
+	}
+
+	parsed, ok := options.Level.(uberzap.AtomicLevel)
+	if !ok {
+		return fmt.Errorf("unsupported log level %q", level)
+	}
+	atomicLevel.SetLevel(parsed.Level())
+	return nil
+}
+
+// ValidateLevel checks whether level can be set by SetLevel
+func ValidateLevel(level string) error {
+	var options zap.Options
+	flagSet := flag.NewFlagSet("", flag.ContinueOnError)
+	options.BindFlags(flagSet)
+	return flagSet.Set(levelFlagName, level)
+}